@@ -0,0 +1,245 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- Adopting files ---
+//
+// Dropping reference material into a project (PDF notes, a style sheet, a
+// batch of research files) would otherwise cost a "New file added" version.
+// `gitnot adopt <paths>` registers such files at the current version
+// instead: they are copied into the snapshot and object store, added to
+// hashes.json, given a changelog that starts at the adoption, and logged in
+// .gitnot/adopted.json. Nothing is bumped; the next recorded version simply
+// includes them and lists them once as adopted. The adoption is journaled
+// like an update, so an interrupted one is rolled back by the next command.
+
+const opAdopt = "adopt"
+
+// Adoption is one run of gitnot adopt.
+type Adoption struct {
+	Files map[string]string `json:"files"` // path → hash
+	Base  float64           `json:"base"`  // the version the files were adopted at
+	At    time.Time         `json:"at"`
+}
+
+func loadAdoptions() []Adoption {
+	var adoptions []Adoption
+	_ = loadJSON(adoptedFile, &adoptions)
+	return adoptions
+}
+
+// pendingAdoptions returns the files adopted since version v was recorded.
+func pendingAdoptions(v float64) map[string]string {
+	out := map[string]string{}
+	for _, a := range loadAdoptions() {
+		if a.Base == v {
+			for rel, h := range a.Files {
+				out[rel] = h
+			}
+		}
+	}
+	return out
+}
+
+// withAdopted returns hashes plus the files adopted on top of version v.
+func withAdopted(hashes map[string]string, v float64) map[string]string {
+	adopted := pendingAdoptions(v)
+	if len(adopted) == 0 {
+		return hashes
+	}
+	out := make(map[string]string, len(hashes)+len(adopted))
+	for rel, h := range hashes {
+		out[rel] = h
+	}
+	for rel, h := range adopted {
+		out[rel] = h
+	}
+	return out
+}
+
+// adoptable picks the files under args that the settings track and that are
+// not tracked yet.
+func adoptable(args []string, files []string, tracked map[string]string) (picked, already []string, err error) {
+	seen := map[string]bool{}
+	for _, a := range args {
+		p := filepath.Clean(filepath.FromSlash(a))
+		if filepath.IsAbs(p) || within(p, "..") {
+			return nil, nil, fmt.Errorf("%s is outside the project", a)
+		}
+		if isUnderGitnot(p) {
+			return nil, nil, fmt.Errorf("%s is one of gitnot's own files", a)
+		}
+		n := 0
+		for _, rel := range files {
+			if p != "." && !within(rel, p) {
+				continue
+			}
+			n++
+			if seen[rel] {
+				continue
+			}
+			seen[rel] = true
+			if _, ok := tracked[rel]; ok {
+				already = append(already, rel)
+			} else {
+				picked = append(picked, rel)
+			}
+		}
+		if n == 0 {
+			if _, err := os.Stat(longPath(p)); err != nil {
+				return nil, nil, fmt.Errorf("%s does not exist", a)
+			}
+			return nil, nil, fmt.Errorf("%s is not tracked by your settings; see 'gitnot status --why'", a)
+		}
+	}
+	return picked, already, nil
+}
+
+// adoptFiles registers files at version v. The adoption log is written last:
+// it is the commit point recovery relies on.
+func adoptFiles(files []string, v float64) (Adoption, error) {
+	a := Adoption{Files: map[string]string{}, Base: v, At: repo.Now()}
+	var touched []string
+	for _, rel := range files {
+		touched = append(touched, filepath.Join(changelogDir, rel+".log"))
+	}
+	j, err := beginJournal(opAdopt, v, v, touched)
+	if err != nil {
+		return a, err
+	}
+	a.At = j.Started
+	cfg := loadConfig()
+	ts := a.At.Format("2006-01-02 15:04")
+	for _, rel := range files {
+		snap := filepath.Join(snapshotDir, rel)
+		if err := safeMkdirAllForFile(snap); err != nil {
+			return a, err
+		}
+		if err := copyFile(rel, snap); err != nil {
+			return a, fmt.Errorf("copying %s: %w", rel, err)
+		}
+		h := hashFile(snap)
+		if err := storeVersionObject(cfg, snap, h, ""); err != nil {
+			return a, err
+		}
+		a.Files[rel] = h
+		clPath := filepath.Join(changelogDir, rel+".log")
+		if err := safeMkdirAllForFile(clPath); err != nil {
+			return a, err
+		}
+		if err := appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n📥 Adopted (tracked from here on, no version recorded).\n", v, ts)); err != nil {
+			return a, err
+		}
+	}
+	if err := saveJSON(adoptedFile, append(loadAdoptions(), a)); err != nil {
+		return a, err
+	}
+	if err := commitAdoption(a); err != nil {
+		return a, err
+	}
+	return a, repo.FS.Remove(journalFile)
+}
+
+// commitAdoption adds the adopted files to hashes.json and the index.
+func commitAdoption(a Adoption) error {
+	hashes := map[string]string{}
+	if err := loadJSON(hashesFile, &hashes); err != nil {
+		return err
+	}
+	for rel, h := range a.Files {
+		hashes[rel] = h
+	}
+	if err := saveJSON(hashesFile, hashes); err != nil {
+		return err
+	}
+	return saveIndex(hashes)
+}
+
+// recoverAdoption finishes an interrupted adoption that reached the log, or
+// undoes one that did not.
+func recoverAdoption(j Journal) error {
+	for _, a := range loadAdoptions() {
+		if a.At.Equal(j.Started) {
+			if err := commitAdoption(a); err != nil {
+				return err
+			}
+			fmt.Printf("🩹 Finished interrupted adoption of %d files\n", len(a.Files))
+			return repo.FS.Remove(journalFile)
+		}
+	}
+	committed := loadCommittedHashes()
+	for p, size := range j.Changelogs {
+		if size < 0 {
+			_ = repo.FS.Remove(p)
+		} else {
+			_ = repo.FS.Truncate(p, size)
+		}
+		rel, err := filepath.Rel(changelogDir, strings.TrimSuffix(p, ".log"))
+		if err != nil {
+			continue
+		}
+		if _, ok := committed[rel]; !ok {
+			_ = repo.FS.Remove(filepath.Join(snapshotDir, rel))
+		}
+	}
+	fmt.Println("🩹 Rolled back interrupted adoption")
+	return repo.FS.Remove(journalFile)
+}
+
+func runAdopt(args []string) error {
+	fs := flag.NewFlagSet("adopt", flag.ExitOnError)
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return fmt.Errorf("usage: gitnot adopt <file|folder>...")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if err := recoverInterrupted(); err != nil {
+		return fmt.Errorf("recovering interrupted run: %w", err)
+	}
+	if err := checkNotArchived(); err != nil {
+		return err
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+	v, err := readVersion()
+	if err != nil {
+		return err
+	}
+	files, err := scanTextFiles(".", loadConfig().scanFilters())
+	if err != nil {
+		return err
+	}
+	picked, already, err := adoptable(rest, files, loadCommittedHashes())
+	if err != nil {
+		return err
+	}
+	if len(already) > 0 {
+		fmt.Printf("✅ Already tracked (%d): %s\n", len(already), strings.Join(preview(already, 3), ", "))
+	}
+	if len(picked) == 0 {
+		return nil
+	}
+	if _, err := adoptFiles(picked, v); err != nil {
+		return err
+	}
+	fmt.Printf("📥 Adopted %d files at v%.1f: %s\n", len(picked), v, strings.Join(preview(picked, 3), ", "))
+	if len(picked) > 3 {
+		fmt.Printf("    ... and %d more\n", len(picked)-3)
+	}
+	return nil
+}
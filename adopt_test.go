@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAdoptWithoutVersionBump(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "chapter.md", "One\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, filepath.Join("refs", "a.md"), "Reference A\n")
+	createTestFile(t, filepath.Join("refs", "b.md"), "Reference B\n")
+	createTestFile(t, "image.xyz", "not tracked\n")
+
+	if err := runAdopt([]string{"image.xyz"}); err == nil || !strings.Contains(err.Error(), "not tracked") {
+		t.Errorf("Expected adopting an untracked kind of file to fail, got %v", err)
+	}
+	if err := runAdopt([]string{"refs", "chapter.md"}); err != nil {
+		t.Fatalf("adopt failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.0 {
+		t.Errorf("Adopting should not bump the version, got v%.1f", v)
+	}
+	a := filepath.Join("refs", "a.md")
+	if _, ok := loadCommittedHashes()[a]; !ok {
+		t.Error("Adopted files should count as tracked")
+	}
+	if _, err := os.Stat(journalFile); err == nil {
+		t.Error("Adopting left its journal behind")
+	}
+	log, _ := os.ReadFile(filepath.Join(changelogDir, a+".log"))
+	if !strings.Contains(string(log), "Adopted") {
+		t.Errorf("Expected an adoption entry, got:\n%s", log)
+	}
+
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.0 {
+		t.Errorf("Adopted files alone should not record a version, got v%.1f", v)
+	}
+	createTestFile(t, "chapter.md", "Two\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	m, err := loadManifest(0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Changes) != 1 || m.Changes[0].Path != "chapter.md" {
+		t.Errorf("Adopted files should not show up as added, got %+v", m.Changes)
+	}
+	if _, ok := m.Files[a]; !ok || len(m.Adopted) != 2 {
+		t.Errorf("Expected both files in v0.1 and listed as adopted, got %v", m.Adopted)
+	}
+	if !strings.Contains(historySection(m), "📥 adopted 2 files") {
+		t.Errorf("History should mention the adoption:\n%s", historySection(m))
+	}
+}
+
+func TestAdoptRollsBackWhenInterrupted(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "chapter.md", "One\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "notes.md", "Notes\n")
+	cl := filepath.Join(changelogDir, "notes.md.log")
+	if _, err := beginJournal(opAdopt, 0, 0, []string{cl}); err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, cl, "\n## v0.0 – partial\n")
+	createTestFile(t, filepath.Join(snapshotDir, "notes.md"), "Notes\n")
+
+	if err := recoverInterrupted(); err != nil {
+		t.Fatalf("recoverInterrupted failed: %v", err)
+	}
+	if _, err := os.Stat(cl); err == nil {
+		t.Error("The partial changelog should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(snapshotDir, "notes.md")); err == nil {
+		t.Error("The partial snapshot copy should be removed")
+	}
+	if _, ok := loadCommittedHashes()["notes.md"]; ok {
+		t.Error("notes.md should not be tracked after the rollback")
+	}
+}
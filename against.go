@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/codinganovel/go-difflib/difflib"
+)
+
+// --- Diff against a folder ---
+//
+// `gitnot diff --against ../backup-2024-01/` compares the working tree with
+// any other folder, such as a copy brought back by another backup tool. Both
+// sides are scanned with this project's tracking rules, and every file that
+// differs gets the line and word summary a version records, followed by its
+// unified diff.
+
+// scanHashes hashes the files under root that filter tracks, keyed by their
+// path relative to root.
+func scanHashes(root string, filter scanFilter) (map[string]string, error) {
+	paths, err := scanTextFiles(root, filter)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string, len(paths))
+	for _, p := range paths {
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil, err
+		}
+		hashes[rel] = hashFile(p)
+	}
+	return hashes, nil
+}
+
+// compareFolder measures how the working tree differs from other: files only
+// here are "added", files only there "deleted". With files given, only those
+// are compared.
+func compareFolder(other string, files []string) ([]FileChange, error) {
+	if fi, err := os.Stat(other); err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a folder", other)
+	}
+	filter := loadConfig().scanFilters()
+	theirs, err := scanHashes(other, filter)
+	if err != nil {
+		return nil, err
+	}
+	ours, err := scanHashes(".", filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) > 0 {
+		keep := map[string]bool{}
+		for _, f := range files {
+			keep[f] = true
+		}
+		for _, m := range []map[string]string{theirs, ours} {
+			for rel := range m {
+				if !keep[rel] {
+					delete(m, rel)
+				}
+			}
+		}
+	}
+	added, changed, deleted := detectChanges(theirs, ours)
+	var out []FileChange
+	for _, rel := range added {
+		out = append(out, measureFiles(rel, "", rel, stateAdded))
+	}
+	for _, rel := range changed {
+		out = append(out, measureFiles(rel, filepath.Join(other, rel), rel, stateModified))
+	}
+	for _, rel := range deleted {
+		out = append(out, measureFiles(rel, filepath.Join(other, rel), "", stateDeleted))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out, nil
+}
+
+// folderSummary prints one line per differing file.
+func folderSummary(other string, changes []FileChange) string {
+	var b strings.Builder
+	for _, c := range changes {
+		rel := filepath.ToSlash(c.Path)
+		switch c.State {
+		case stateAdded:
+			fmt.Fprintf(&b, "+ %s (only here, %d lines)\n", rel, c.Lines)
+		case stateDeleted:
+			fmt.Fprintf(&b, "- %s (only in %s, %d lines)\n", rel, other, c.LinesRemoved)
+		default:
+			fmt.Fprintf(&b, "≠ %s (+%d −%d lines, +%d −%d words)\n",
+				rel, c.LinesAdded, c.LinesRemoved, c.WordsAdded, c.WordsRemoved)
+		}
+	}
+	return b.String()
+}
+
+// folderDiff renders the unified diff of one change.
+func folderDiff(other string, c FileChange) (string, error) {
+	var theirs, ours []byte
+	if c.State != stateAdded {
+		theirs, _ = os.ReadFile(longPath(filepath.Join(other, c.Path)))
+	}
+	if c.State != stateDeleted {
+		ours, _ = os.ReadFile(longPath(c.Path))
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A: difflib.SplitLines(string(theirs)), B: difflib.SplitLines(string(ours)),
+		FromFile: filepath.ToSlash(filepath.Join(other, c.Path)), ToFile: filepath.ToSlash(c.Path),
+		Context: 3,
+	})
+}
+
+// diffAgainst is `gitnot diff --against other`.
+func diffAgainst(other string, files []string, statOnly, copyOut bool) error {
+	changes, err := compareFolder(other, files)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Printf("✅ No differences from %s\n", other)
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString(folderSummary(other, changes))
+	if !statOnly {
+		for _, c := range changes {
+			d, err := folderDiff(other, c)
+			if err != nil {
+				return err
+			}
+			b.WriteString("\n")
+			b.WriteString(d)
+		}
+	}
+	return emit(b.String(), fmt.Sprintf("the diff of %d file(s) against %s", len(changes), other), copyOut)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareFolder(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, filepath.Join("chapters", "one.md"), "one\ntwo\n")
+	createTestFile(t, "new.md", "fresh\n")
+	createTestFile(t, "same.md", "same\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	backup := t.TempDir()
+	createTestFile(t, filepath.Join(backup, "chapters", "one.md"), "one\n")
+	createTestFile(t, filepath.Join(backup, "same.md"), "same\n")
+	createTestFile(t, filepath.Join(backup, "gone.md"), "old\nlines\n")
+	createTestFile(t, filepath.Join(backup, "image.png"), "not tracked")
+	createTestFile(t, filepath.Join(backup, ".gitnot", "hashes.json"), "{}")
+
+	changes, err := compareFolder(backup, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("Expected three differing files, got %+v", changes)
+	}
+	want := map[string]string{"chapters/one.md": stateModified, "gone.md": stateDeleted, "new.md": stateAdded}
+	for _, c := range changes {
+		if want[filepath.ToSlash(c.Path)] != c.State {
+			t.Errorf("Unexpected change %+v", c)
+		}
+	}
+	if c := changes[0]; c.LinesAdded != 1 || c.LinesRemoved != 0 {
+		t.Errorf("Expected one added line in the chapter, got %+v", c)
+	}
+	summary := folderSummary(backup, changes)
+	if !strings.Contains(summary, "≠ chapters/one.md (+1 −0 lines") || !strings.Contains(summary, "- gone.md (only in") {
+		t.Errorf("Unexpected summary:\n%s", summary)
+	}
+	d, _ := folderDiff(backup, changes[0])
+	if !strings.Contains(d, "+two") {
+		t.Errorf("Expected the added line in the diff:\n%s", d)
+	}
+
+	only, _ := compareFolder(backup, []string{"new.md"})
+	if len(only) != 1 || only[0].Path != "new.md" {
+		t.Errorf("Expected only the named file, got %+v", only)
+	}
+}
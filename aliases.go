@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// --- Aliases ---
+//
+// "aliases" in the user config name command lines, like git aliases:
+// with {"draft": "update -m \"drafting session\""}, `gitnot draft` runs
+// `gitnot update -m "drafting session"` and any further arguments are
+// appended. Aliases may refer to other aliases but can't replace built-in
+// commands. `update` is a name for the default action, so aliases can start
+// with it.
+
+const maxAliasDepth = 10
+
+// alias checks names against subcommands, so it can't be listed in that
+// map's initializer.
+func init() { subcommands["alias"] = runAlias }
+
+// splitCommandLine splits s into words the way a shell would for simple
+// cases: whitespace separates words, single and double quotes group them and
+// a backslash escapes the next character (except inside single quotes).
+func splitCommandLine(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// expandAliases replaces a leading alias in args with its command line.
+func expandAliases(args []string, aliases map[string]string) ([]string, error) {
+	for depth := 0; len(args) > 0; depth++ {
+		line, ok := aliases[args[0]]
+		if !ok || subcommands[args[0]] != nil || args[0] == "update" {
+			return args, nil
+		}
+		if depth == maxAliasDepth {
+			return nil, fmt.Errorf("alias %q expands too deeply (does it refer to itself?)", args[0])
+		}
+		words, err := splitCommandLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("alias %q: %w", args[0], err)
+		}
+		if len(words) == 0 {
+			return nil, fmt.Errorf("alias %q is empty", args[0])
+		}
+		args = append(words, args[1:]...)
+	}
+	return args, nil
+}
+
+func runAlias(args []string) error {
+	fs := flag.NewFlagSet("alias", flag.ExitOnError)
+	remove := fs.Bool("remove", false, "delete the alias")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	uc := loadUserConfig()
+	switch {
+	case len(rest) == 0:
+		if len(uc.Aliases) == 0 {
+			fmt.Println("🔖 No aliases (add one with 'gitnot alias <name> \"<command line>\"')")
+			return nil
+		}
+		for _, name := range sortedKeys(uc.Aliases) {
+			fmt.Printf("  %s = %s\n", name, uc.Aliases[name])
+		}
+		return nil
+	case len(rest) == 1 && *remove:
+		if _, ok := uc.Aliases[rest[0]]; !ok {
+			return fmt.Errorf("no alias named %q", rest[0])
+		}
+		delete(uc.Aliases, rest[0])
+		if err := saveUserConfig(uc); err != nil {
+			return err
+		}
+		fmt.Printf("🔖 Removed alias %s\n", rest[0])
+		return nil
+	case len(rest) != 2 || *remove:
+		return fmt.Errorf("usage: gitnot alias [<name> \"<command line>\" | --remove <name>]")
+	}
+	name, line := rest[0], rest[1]
+	if subcommands[name] != nil || name == "update" || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("%q is a gitnot command or flag and can't be an alias", name)
+	}
+	if _, err := splitCommandLine(line); err != nil {
+		return err
+	}
+	if uc.Aliases == nil {
+		uc.Aliases = map[string]string{}
+	}
+	uc.Aliases[name] = line
+	if err := saveUserConfig(uc); err != nil {
+		return err
+	}
+	fmt.Printf("🔖 gitnot %s = gitnot %s\n", name, line)
+	return nil
+}
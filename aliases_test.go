@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	for in, want := range map[string][]string{
+		`update -m "drafting session"`: {"update", "-m", "drafting session"},
+		`log  --daily`:                 {"log", "--daily"},
+		`show 'my file.md'@1.2`:        {"show", "my file.md@1.2"},
+		`diff a\ b.md ""`:              {"diff", "a b.md", ""},
+	} {
+		got, err := splitCommandLine(in)
+		if err != nil || !slices.Equal(got, want) {
+			t.Errorf("splitCommandLine(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := splitCommandLine(`-m "open`); err == nil {
+		t.Error("Expected an error for an unterminated quote")
+	}
+}
+
+func TestExpandAliases(t *testing.T) {
+	aliases := map[string]string{
+		"draft": `update -m "drafting session"`,
+		"d":     "draft --show-diff",
+		"loop":  "loop",
+		"log":   "status",
+	}
+	got, err := expandAliases([]string{"d", "--full"}, aliases)
+	if want := []string{"update", "-m", "drafting session", "--show-diff", "--full"}; err != nil || !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q, %v", want, got, err)
+	}
+	if got, _ := expandAliases([]string{"log"}, aliases); !slices.Equal(got, []string{"log"}) {
+		t.Errorf("Built-in commands can't be replaced, got %q", got)
+	}
+	if _, err := expandAliases([]string{"loop"}, aliases); err == nil {
+		t.Error("Expected an error for a self-referencing alias")
+	}
+}
+
+func TestAliasCommand(t *testing.T) {
+	setupTestDir(t)
+
+	if err := runAlias([]string{"draft", `update -m "drafting"`}); err != nil {
+		t.Fatalf("alias failed: %v", err)
+	}
+	if got := loadUserConfig().Aliases["draft"]; got != `update -m "drafting"` {
+		t.Errorf("Alias not saved, got %q", got)
+	}
+	if err := runAlias([]string{"status", "log"}); err == nil {
+		t.Error("Expected built-in names to be refused")
+	}
+	if err := runAlias([]string{"--remove", "draft"}); err != nil || len(loadUserConfig().Aliases) != 0 {
+		t.Errorf("Alias not removed: %v", err)
+	}
+}
+
+func TestAliasAfterRootFlag(t *testing.T) {
+	dir := setupTestDir(t)
+
+	createTestFile(t, "book/a.md", "one\n")
+	os.Chdir("book")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	os.Chdir(dir)
+	if err := runAlias([]string{"draft", "label a.md draft"}); err != nil {
+		t.Fatalf("alias failed: %v", err)
+	}
+	if err := runAlias([]string{"book-final", "--", "--root book label a.md final"}); err != nil {
+		t.Fatalf("alias failed: %v", err)
+	}
+
+	if code := run([]string{"--root", "book", "draft"}); code != 0 {
+		t.Fatalf("run failed with exit code %d", code)
+	}
+	if got := loadLabels()["a.md"]; !slices.Contains(got, "draft") {
+		t.Errorf("Expected the alias expanded after --root, got labels %q", got)
+	}
+
+	os.Chdir(dir)
+	if code := run([]string{"book-final"}); code != 0 {
+		t.Fatalf("run failed with exit code %d", code)
+	}
+	if got := loadLabels()["a.md"]; !slices.Contains(got, "final") {
+		t.Errorf("Expected --root inside an alias to be applied, got labels %q", got)
+	}
+}
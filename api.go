@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// --- Control API ---
+//
+// While `gitnot watch` runs it listens on .gitnot/watch.sock, so GUIs and
+// editor plugins can drive it and hear about new versions instead of
+// polling. Requests and responses are JSON objects, one per line:
+//
+//	{"method": "status"}                   → {"ok": true, "status": {...}}
+//	{"method": "update", "message": "..."} → {"ok": true, "version": 0.4}
+//	{"method": "subscribe"}                → {"ok": true}, then one event per line
+//
+// Events are {"event": "changed", "paths": [...]}, {"event": "reloaded"} and
+// {"event": "version", "version": 0.4, "id": "...", "paths": [...]}. Requests
+// are served by the watch loop itself, so an update asked for over the
+// socket never overlaps one the watcher starts.
+
+var apiSocket string // set by useStoreDir
+
+var errWatchRunning = errors.New("another gitnot watch is already running in this folder")
+
+type apiRequest struct {
+	Method  string `json:"method"`
+	Message string `json:"message,omitempty"` // for update
+}
+
+type apiResponse struct {
+	OK      bool       `json:"ok"`
+	Error   string     `json:"error,omitempty"`
+	Version *float64   `json:"version,omitempty"` // the version an update recorded, v0.0 included
+	Status  *apiStatus `json:"status,omitempty"`
+}
+
+// apiStatus is what an update would record right now.
+type apiStatus struct {
+	Version  float64  `json:"version"`
+	Tracked  int      `json:"tracked"`
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Deleted  []string `json:"deleted"`
+}
+
+// streamEvent is one pushed notification.
+type streamEvent struct {
+	Event   string    `json:"event"` // changed, reloaded or version
+	Time    time.Time `json:"time"`
+	Version *float64  `json:"version,omitempty"` // version events only, v0.0 included
+	ID      string    `json:"id,omitempty"`
+	Paths   []string  `json:"paths,omitempty"` // "/"-separated
+}
+
+// apiCall hands a request to the watch loop.
+type apiCall struct {
+	req   apiRequest
+	reply chan apiResponse
+}
+
+type apiServer struct {
+	ln     net.Listener
+	calls  chan apiCall
+	closed chan struct{}
+
+	mu   sync.Mutex
+	subs map[chan streamEvent]bool
+}
+
+// listenAPI opens the socket, clearing one left behind by a watcher that
+// crashed. A socket that still answers means a watcher is running.
+func listenAPI() (*apiServer, error) {
+	if conn, err := net.Dial("unix", apiSocket); err == nil {
+		conn.Close()
+		return nil, errWatchRunning
+	}
+	os.Remove(apiSocket)
+	ln, err := net.Listen("unix", apiSocket)
+	if err != nil {
+		return nil, err
+	}
+	s := &apiServer{
+		ln:     ln,
+		calls:  make(chan apiCall),
+		closed: make(chan struct{}),
+		subs:   map[chan streamEvent]bool{},
+	}
+	go s.serve()
+	return s, nil
+}
+
+func (s *apiServer) Close() {
+	close(s.closed)
+	s.ln.Close()
+	os.Remove(apiSocket)
+}
+
+func (s *apiServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *apiServer) handle(conn net.Conn) {
+	defer conn.Close()
+	dec, enc := json.NewDecoder(conn), json.NewEncoder(conn)
+	for {
+		var req apiRequest
+		if err := dec.Decode(&req); err != nil {
+			if !errors.Is(err, io.EOF) {
+				enc.Encode(apiResponse{Error: "bad request: " + err.Error()})
+			}
+			return
+		}
+		switch req.Method {
+		case "subscribe":
+			s.stream(conn, enc)
+			return
+		case "status", "update":
+			reply := make(chan apiResponse, 1)
+			select {
+			case s.calls <- apiCall{req, reply}:
+			case <-s.closed:
+				return
+			}
+			if enc.Encode(<-reply) != nil {
+				return
+			}
+		default:
+			enc.Encode(apiResponse{Error: fmt.Sprintf("unknown method %q", req.Method)})
+		}
+	}
+}
+
+// stream sends events to a subscriber until it hangs up. It is registered
+// before the reply goes out, so no event after the reply is missed.
+func (s *apiServer) stream(conn net.Conn, enc *json.Encoder) {
+	ch := make(chan streamEvent, 64)
+	s.mu.Lock()
+	s.subs[ch] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, ch)
+		s.mu.Unlock()
+	}()
+	if enc.Encode(apiResponse{OK: true}) != nil {
+		return
+	}
+	gone := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, conn)
+		close(gone)
+	}()
+	for {
+		select {
+		case ev := <-ch:
+			if enc.Encode(ev) != nil {
+				return
+			}
+		case <-gone:
+			return
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// publish sends ev to every subscriber. One that falls too far behind
+// misses events rather than stalling the watcher.
+func (s *apiServer) publish(ev streamEvent) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// pendingStatus reports what an update would record now.
+func pendingStatus() (apiStatus, error) {
+	cfg := loadConfig()
+	old := loadCommittedHashes()
+	rep, err := scanTreeReport(".", cfg.scanFilters())
+	if err != nil {
+		return apiStatus{}, err
+	}
+	files := rep.Files
+	files, deferred := deferPlaceholders(files, cfg)
+	takeUnreadable(nil)
+	current, _, _ := hashWithIndex(files, loadIndex(), false)
+	carryDeferred(current, old, deferred)
+	unreadable := takeUnreadable(current)
+	maps.Copy(unreadable, unscannedFiles(rep.Unreadable, old))
+	holdUnreadable(files, current, old, unreadable)
+	added, modified, deleted := detectChanges(old, current)
+	v, err := readVersion()
+	if err != nil {
+		return apiStatus{}, err
+	}
+	return apiStatus{
+		Version:  v,
+		Tracked:  len(current),
+		Added:    slashPaths(added),
+		Modified: slashPaths(modified),
+		Deleted:  slashPaths(deleted),
+	}, nil
+}
+
+func slashPaths(paths []string) []string {
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		out[i] = filepath.ToSlash(p)
+	}
+	return out
+}
+
+// versionEvent describes the version recorded since before, if any.
+func versionEvent(before float64) (streamEvent, bool) {
+	v, err := readVersion()
+	if err != nil || v == before {
+		return streamEvent{}, false
+	}
+	ev := streamEvent{Event: "version", Time: repo.Now(), Version: &v}
+	if m, err := loadManifest(v); err == nil {
+		ev.ID, ev.Time = m.ID, m.Timestamp
+		for _, c := range m.Changes {
+			ev.Paths = append(ev.Paths, filepath.ToSlash(c.Path))
+		}
+	}
+	return ev, true
+}
+
+// answerAPI serves a status or update request from the watch loop; record
+// runs an update the way the watcher does.
+func answerAPI(req apiRequest, record func(message string) (float64, error)) apiResponse {
+	switch req.Method {
+	case "status":
+		st, err := pendingStatus()
+		if err != nil {
+			return apiResponse{Error: err.Error()}
+		}
+		return apiResponse{OK: true, Status: &st}
+	case "update":
+		v, err := record(req.Message)
+		if err != nil {
+			return apiResponse{Error: err.Error()}
+		}
+		return apiResponse{OK: true, Version: &v}
+	}
+	return apiResponse{Error: fmt.Sprintf("unknown method %q", req.Method)}
+}
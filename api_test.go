@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+)
+
+func apiRoundTrip(t *testing.T, conn net.Conn, r *bufio.Reader, req apiRequest, out any) {
+	t.Helper()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		t.Fatal(err)
+	}
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(line, out); err != nil {
+		t.Fatalf("Bad reply %q: %v", line, err)
+	}
+}
+
+func TestControlAPI(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.md", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+
+	s, err := listenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := listenAPI(); !errors.Is(err, errWatchRunning) {
+		t.Fatalf("Expected a second watcher to be refused, got %v", err)
+	}
+	// Stand in for the watch loop.
+	go func() {
+		record := func(message string) (float64, error) {
+			before, _ := readVersion()
+			err := updateGitnotWith(updateOptions{Message: message})
+			if ev, ok := versionEvent(before); ok {
+				s.publish(ev)
+				return *ev.Version, err
+			}
+			return before, err
+		}
+		for {
+			select {
+			case call := <-s.calls:
+				call.reply <- answerAPI(call.req, record)
+			case <-s.closed:
+				return
+			}
+		}
+	}()
+
+	sub, err := net.Dial("unix", apiSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	subR := bufio.NewReader(sub)
+	var resp apiResponse
+	if apiRoundTrip(t, sub, subR, apiRequest{Method: "subscribe"}, &resp); !resp.OK {
+		t.Fatalf("Subscribe failed: %+v", resp)
+	}
+
+	conn, err := net.Dial("unix", apiSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	createTestFile(t, "a.md", "two\n")
+	resp = apiResponse{}
+	apiRoundTrip(t, conn, r, apiRequest{Method: "status"}, &resp)
+	if !resp.OK || resp.Status == nil || !slices.Equal(resp.Status.Modified, []string{"a.md"}) {
+		t.Fatalf("Unexpected status: %+v", resp)
+	}
+	resp = apiResponse{}
+	apiRoundTrip(t, conn, r, apiRequest{Method: "update", Message: "from the api"}, &resp)
+	if !resp.OK || resp.Version == nil || *resp.Version != 0.1 {
+		t.Fatalf("Unexpected update reply: %+v", resp)
+	}
+	resp = apiResponse{}
+	if apiRoundTrip(t, conn, r, apiRequest{Method: "rename"}, &resp); resp.OK || resp.Error == "" {
+		t.Errorf("Expected an unknown method to be refused, got %+v", resp)
+	}
+
+	line, err := subR.ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ev streamEvent
+	if err := json.Unmarshal(line, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Event != "version" || ev.Version == nil || *ev.Version != 0.1 || ev.ID == "" || !slices.Equal(ev.Paths, []string{"a.md"}) {
+		t.Errorf("Unexpected event: %s", line)
+	}
+	if m, _ := loadManifest(0.1); m.Message != "from the api" {
+		t.Errorf("Expected the message to be recorded, got %q", m.Message)
+	}
+}
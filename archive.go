@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+)
+
+// --- Archive mode ---
+//
+// An archived store is read-only: commands that would create versions refuse
+// to run, while status, info, stats and exports keep working.
+
+func checkNotArchived() error {
+	info, err := readStoreInfo()
+	if err != nil {
+		return err
+	}
+	if info.Archived {
+		return fmt.Errorf("🔒 this project is archived; run 'gitnot archive-mode off' to record new versions")
+	}
+	return nil
+}
+
+func setArchived(on bool) error {
+	info, err := readStoreInfo()
+	if err != nil {
+		return err
+	}
+	info.Archived = on
+	return writeStoreInfo(info)
+}
+
+func runArchiveMode(args []string) error {
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if len(args) == 0 || args[0] == "status" {
+		info, err := readStoreInfo()
+		if err != nil {
+			return err
+		}
+		if info.Archived {
+			fmt.Println("🔒 Archive mode is on (no new versions will be recorded)")
+		} else {
+			fmt.Println("🔓 Archive mode is off")
+		}
+		return nil
+	}
+	switch args[0] {
+	case "on":
+		if err := setArchived(true); err != nil {
+			return err
+		}
+		fmt.Println("🔒 Archive mode on — this project is now read-only for gitnot")
+	case "off":
+		if err := setArchived(false); err != nil {
+			return err
+		}
+		fmt.Println("🔓 Archive mode off — new versions can be recorded again")
+	default:
+		return fmt.Errorf("usage: gitnot archive-mode on|off|status")
+	}
+	return nil
+}
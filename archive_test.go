@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestArchiveModeBlocksUpdates(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.txt", "one")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := setArchived(true); err != nil {
+		t.Fatalf("setArchived failed: %v", err)
+	}
+	createTestFile(t, "a.txt", "two")
+	if err := updateGitnot(); err == nil || !strings.Contains(err.Error(), "archived") {
+		t.Errorf("Expected update to refuse in archive mode, got: %v", err)
+	}
+	if err := showStatus(); err != nil {
+		t.Errorf("Status should still work when archived: %v", err)
+	}
+
+	if err := setArchived(false); err != nil {
+		t.Fatalf("setArchived failed: %v", err)
+	}
+	if err := updateGitnot(); err != nil {
+		t.Errorf("Update should work once archive mode is off: %v", err)
+	}
+}
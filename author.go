@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// --- Authors ---
+//
+// When several people edit a synced folder, each sets an author once per
+// machine (`gitnot author set "Ana Bell"`). It is kept in the user config,
+// outside the shared folder, and stamped into every version's manifest and
+// changelog headers. GITNOT_AUTHOR overrides it; a project-wide "author" in
+// config.json is the fallback for single-person folders.
+
+type Author struct {
+	Name     string `json:"name,omitempty"`
+	Initials string `json:"initials,omitempty"`
+}
+
+// UserConfig holds per-user settings that don't belong to any one project.
+type UserConfig struct {
+	Author Author `json:"author,omitzero"`
+	Device Device `json:"device,omitzero"` // see versionid.go
+
+	SigningKey string `json:"signing_key,omitempty"` // private key that signs new versions
+
+	Aliases map[string]string `json:"aliases,omitempty"` // name → command line, see aliases.go
+}
+
+func userConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gitnot", "config.json"), nil
+}
+
+func loadUserConfig() UserConfig {
+	var uc UserConfig
+	if p, err := userConfigPath(); err == nil {
+		_ = loadJSON(p, &uc)
+	}
+	return uc
+}
+
+func saveUserConfig(uc UserConfig) error {
+	p, err := userConfigPath()
+	if err != nil {
+		return err
+	}
+	return saveJSON(p, uc)
+}
+
+// initialsOf derives initials from a name: "Ana Bell" → "AB".
+func initialsOf(name string) string {
+	var b strings.Builder
+	for _, w := range strings.Fields(name) {
+		b.WriteString(strings.ToUpper(string([]rune(w)[:1])))
+	}
+	return b.String()
+}
+
+// label is the short form used in changelog headers.
+func (a Author) label() string {
+	if a.Initials != "" {
+		return a.Initials
+	}
+	return a.Name
+}
+
+// currentAuthor resolves who is recording: GITNOT_AUTHOR, then the user
+// config, then the project config. It returns nil when nobody is set.
+func currentAuthor(cfg Config) *Author {
+	a := cfg.Author
+	if ua := loadUserConfig().Author; ua.Name != "" {
+		a = ua
+	}
+	if name := strings.TrimSpace(os.Getenv("GITNOT_AUTHOR")); name != "" {
+		a = Author{Name: name}
+	}
+	if a.Name == "" {
+		return nil
+	}
+	if a.Initials == "" {
+		a.Initials = initialsOf(a.Name)
+	}
+	return &a
+}
+
+// AuthorStats totals the versions and words recorded by one author.
+type AuthorStats struct {
+	Name         string
+	Versions     int
+	Files        int
+	WordsAdded   int
+	WordsRemoved int
+}
+
+// authorStats groups manifests by author; versions recorded without an
+// author are counted under "(unknown)".
+func authorStats(manifests []Manifest) []AuthorStats {
+	byName := map[string]*AuthorStats{}
+	files := map[string]map[string]bool{}
+	for _, m := range manifests {
+		name := "(unknown)"
+		if m.Author != nil && m.Author.Name != "" {
+			name = m.Author.Name
+		}
+		st := byName[name]
+		if st == nil {
+			st = &AuthorStats{Name: name}
+			byName[name] = st
+			files[name] = map[string]bool{}
+		}
+		st.Versions++
+		for _, c := range m.Changes {
+			files[name][c.Path] = true
+			st.WordsAdded += c.WordsAdded
+			st.WordsRemoved += c.WordsRemoved
+		}
+	}
+	var out []AuthorStats
+	for name, st := range byName {
+		st.Files = len(files[name])
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Versions != out[j].Versions {
+			return out[i].Versions > out[j].Versions
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func runAuthor(args []string) error {
+	fs := flag.NewFlagSet("author", flag.ExitOnError)
+	initials := fs.String("initials", "", "initials shown in changelog headers (default: derived from the name)")
+	project := fs.Bool("project", false, "store the author in this project's config instead of your user config")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) == 0 {
+		a := currentAuthor(loadConfig())
+		if a == nil {
+			fmt.Println("👤 No author set (use 'gitnot author set \"Your Name\"')")
+			return nil
+		}
+		fmt.Printf("👤 %s (%s)\n", a.Name, a.label())
+		return nil
+	}
+	if rest[0] != "set" || len(rest) != 2 {
+		return fmt.Errorf("usage: gitnot author [set \"Name\" [--initials AB] [--project]]")
+	}
+	a := Author{Name: strings.TrimSpace(rest[1]), Initials: *initials}
+	if a.Name == "" {
+		return fmt.Errorf("author name is empty")
+	}
+	if *project {
+		if err := ensureInitialized(); err != nil {
+			return err
+		}
+		cfg := loadConfig()
+		cfg.Author = a
+		if err := saveJSON(configFile, cfg); err != nil {
+			return err
+		}
+		fmt.Printf("👤 Project author set to %s\n", a.Name)
+		return nil
+	}
+	uc := loadUserConfig()
+	uc.Author = a
+	if err := saveUserConfig(uc); err != nil {
+		return err
+	}
+	fmt.Printf("👤 Author set to %s for this machine\n", a.Name)
+	return nil
+}
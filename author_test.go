@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestInitialsOf(t *testing.T) {
+	if got := initialsOf("ana maria bell"); got != "AMB" {
+		t.Errorf("Expected AMB, got %q", got)
+	}
+}
+
+func TestCurrentAuthor(t *testing.T) {
+	setupTestDir(t)
+	t.Setenv("GITNOT_AUTHOR", "")
+
+	if currentAuthor(Config{}) != nil {
+		t.Error("Expected no author by default")
+	}
+	if a := currentAuthor(Config{Author: Author{Name: "Project Person"}}); a == nil || a.Initials != "PP" {
+		t.Errorf("Expected project author, got %+v", a)
+	}
+	if err := saveUserConfig(UserConfig{Author: Author{Name: "Ana Bell", Initials: "AnB"}}); err != nil {
+		t.Fatalf("saveUserConfig failed: %v", err)
+	}
+	if a := currentAuthor(Config{Author: Author{Name: "Project Person"}}); a == nil || a.label() != "AnB" {
+		t.Errorf("User config should win over project config, got %+v", a)
+	}
+	t.Setenv("GITNOT_AUTHOR", "Cy Dee")
+	if a := currentAuthor(Config{}); a == nil || a.Name != "Cy Dee" {
+		t.Errorf("GITNOT_AUTHOR should win, got %+v", a)
+	}
+}
+
+func TestAuthorStamping(t *testing.T) {
+	setupTestDir(t)
+
+	t.Setenv("GITNOT_AUTHOR", "Ana Bell")
+	createTestFile(t, "story.md", "Once")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	t.Setenv("GITNOT_AUTHOR", "Ben Cole")
+	createTestFile(t, "story.md", "Once upon a time")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	b, _ := os.ReadFile(".gitnot/changelogs/story.md.log")
+	if !strings.Contains(string(b), " · BC\n") {
+		t.Errorf("Author initials missing from changelog header:\n%s", b)
+	}
+	manifests, _ := loadManifests()
+	stats := authorStats(manifests)
+	if len(stats) != 2 {
+		t.Fatalf("Expected two authors, got %+v", stats)
+	}
+	for _, a := range stats {
+		if a.Name == "Ben Cole" && (a.Versions != 1 || a.WordsAdded != 4) {
+			t.Errorf("Unexpected stats for Ben: %+v", a)
+		}
+	}
+}
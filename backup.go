@@ -0,0 +1,385 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// --- Metadata backups ---
+//
+// Destructive operations call backupMetadata first. A backup is a gzipped tar
+// of the version, hashes, index, config, store info and manifests (not file
+// content); `gitnot restore-meta` puts one back.
+
+const defaultBackupRetention = 10
+
+// metadataPaths lists the store files and directories captured in a backup.
+func metadataPaths() []string {
+	return []string{versionFile, hashesFile, indexFile, configFile, storeFile, labelsFile, pinsFile, tagsFile, remapsFile, adoptedFile, trackedListFile, untrackListFile, allowedSignersFile, manifestDir}
+}
+
+func backupMetadata(reason string) (string, error) {
+	if err := repo.FS.MkdirAll(backupDir, 0o755); err != nil {
+		return "", err
+	}
+	stamp := repo.Now().Format("20060102-150405.000")
+	out := filepath.Join(backupDir, stamp+"-"+reason+".tar.gz")
+	f, err := repo.FS.OpenFile(out, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	for n := 2; errors.Is(err, os.ErrExist); n++ {
+		out = filepath.Join(backupDir, fmt.Sprintf("%s-%s-%d.tar.gz", stamp, reason, n))
+		f, err = repo.FS.OpenFile(out, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	}
+	if err != nil {
+		return "", err
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, root := range metadataPaths() {
+		err := walkStore(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			return addFileToTar(tw, p)
+		})
+		if err != nil {
+			tw.Close()
+			gz.Close()
+			f.Close()
+			repo.FS.Remove(out)
+			return "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	pruneBackups(loadConfig().backupRetention())
+	return out, nil
+}
+
+func addFileToTar(tw *tar.Writer, p string) error {
+	fi, err := repo.FS.Stat(p)
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = filepath.ToSlash(p)
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	src, err := repo.FS.Open(p)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	_, err = io.Copy(tw, src)
+	return err
+}
+
+// listBackups returns backup file names, oldest first.
+func listBackups() ([]string, error) {
+	entries, err := repo.FS.ReadDir(backupDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".tar.gz") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func pruneBackups(keep int) {
+	names, err := listBackups()
+	if err != nil || keep <= 0 || len(names) <= keep {
+		return
+	}
+	for _, n := range names[:len(names)-keep] {
+		_ = repo.FS.Remove(filepath.Join(backupDir, n))
+	}
+}
+
+// restoreMetadata replaces the current metadata with the contents of a backup.
+// The backup is unpacked into a staging folder and checked, then the whole
+// metadata set is swapped in: files the backup doesn't have, such as the
+// manifests of later versions, go with the rest. The snapshot is rebuilt for
+// the restored version and changelog and history entries of later versions
+// are cut off, so the store agrees with itself again. When the restored
+// version's content can't be found, the previous metadata is put back.
+// The caller holds the store lock.
+func restoreMetadata(name string) error {
+	stage := filepath.Join(gitnotDir, "restore.tmp")
+	aside := filepath.Join(gitnotDir, "restore.old")
+	for _, d := range []string{stage, aside, snapshotTmpDir} {
+		if err := repo.FS.RemoveAll(d); err != nil {
+			return err
+		}
+	}
+	defer repo.FS.RemoveAll(stage)
+	if err := unpackBackup(name, stage); err != nil {
+		return err
+	}
+	b, err := repo.FS.ReadFile(filepath.Join(stage, filepath.Base(versionFile)))
+	if err != nil {
+		return fmt.Errorf("backup %s has no readable version: %w", filepath.Base(name), err)
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(string(b)), 64)
+	if err != nil {
+		return fmt.Errorf("backup %s has no readable version: %w", filepath.Base(name), err)
+	}
+
+	if err := swapMetadata(stage, aside); err != nil {
+		return err
+	}
+	if err := stageSnapshot(loadCommittedHashes(), v); err != nil {
+		_ = repo.FS.RemoveAll(snapshotTmpDir)
+		if rerr := swapMetadata(aside, stage); rerr != nil {
+			return fmt.Errorf("%w (and putting the previous metadata back failed: %v; it is in %s)", err, rerr, aside)
+		}
+		return fmt.Errorf("the backup's v%.1f can't be restored: %w", v, err)
+	}
+	_ = repo.FS.RemoveAll(snapshotOldDir)
+	if err := repo.FS.Rename(snapshotDir, snapshotOldDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := repo.FS.Rename(snapshotTmpDir, snapshotDir); err != nil {
+		return err
+	}
+	if err := trimChangelogs(v); err != nil {
+		return err
+	}
+	heads, err := loadManifestHeads()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(historyFile, []byte(renderHistory(heads)), 0o644); err != nil {
+		return err
+	}
+	_ = repo.FS.RemoveAll(snapshotOldDir)
+	return repo.FS.RemoveAll(aside)
+}
+
+// unpackBackup extracts backup name into dir, which gets the store's layout.
+func unpackBackup(name, dir string) error {
+	f, err := repo.FS.Open(filepath.Join(backupDir, filepath.Base(name)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	known := map[string]bool{}
+	for _, p := range metadataPaths() {
+		known[filepath.Base(p)] = true
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target := filepath.FromSlash(hdr.Name)
+		rel, rerr := filepath.Rel(gitnotDir, target)
+		if !isUnderGitnot(target) || strings.Contains(hdr.Name, "..") || rerr != nil || !known[strings.Split(filepath.ToSlash(rel), "/")[0]] {
+			return fmt.Errorf("backup contains unexpected path %q", hdr.Name)
+		}
+		staged := filepath.Join(dir, rel)
+		if err := safeMkdirAllForFile(staged); err != nil {
+			return err
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(staged, data, 0o644); err != nil {
+			return err
+		}
+	}
+}
+
+// swapMetadata moves the current metadata set into aside and the one in
+// from into its place.
+func swapMetadata(from, aside string) error {
+	if err := repo.FS.MkdirAll(aside, 0o755); err != nil {
+		return err
+	}
+	for _, p := range metadataPaths() {
+		base := filepath.Base(p)
+		if err := repo.FS.Rename(p, filepath.Join(aside, base)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err := repo.FS.Rename(filepath.Join(from, base), p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// stageSnapshot writes the content of version v's tracked files into
+// snapshot.tmp, from the object store or, where its content still matches,
+// the current snapshot or working file.
+func stageSnapshot(hashes map[string]string, v float64) error {
+	for _, rel := range sortedKeys(hashes) {
+		h := hashes[rel]
+		b, err := readObject(h)
+		if err != nil {
+			b = nil
+			for _, p := range []string{filepath.Join(snapshotOldDir, rel), filepath.Join(snapshotDir, rel), rel} {
+				if c, rerr := os.ReadFile(longPath(p)); rerr == nil && contentHash(c) == h {
+					b = c
+					break
+				}
+			}
+			if b == nil {
+				return fmt.Errorf("content of %s at v%.1f is not in the store", rel, v)
+			}
+		}
+		target := filepath.Join(snapshotTmpDir, rel)
+		if err := safeMkdirAllForFile(target); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(target, b, 0o644); err != nil {
+			return err
+		}
+	}
+	return repo.FS.MkdirAll(snapshotTmpDir, 0o755)
+}
+
+// trimChangelogs cuts every changelog off before its first entry for a
+// version after v; a log that only has such entries is removed.
+func trimChangelogs(v float64) error {
+	return walkStore(changelogDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, ".log") {
+			return nil
+		}
+		b, err := repo.FS.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		cut := -1
+		for i := 0; ; {
+			j := strings.Index(string(b[i:]), "\n## v")
+			if j < 0 {
+				break
+			}
+			i += j + 1
+			var ver float64
+			if _, err := fmt.Sscanf(string(b[i+len("## v"):]), "%f", &ver); err == nil && ver > v {
+				cut = i - 1
+				break
+			}
+		}
+		switch {
+		case cut < 0:
+			return nil
+		case strings.TrimSpace(string(b[:cut])) == "":
+			return repo.FS.Remove(p)
+		default:
+			return writeFileAtomic(p, b[:cut], 0o644)
+		}
+	})
+}
+
+func runRestoreMeta(args []string) error {
+	fs := flag.NewFlagSet("restore-meta", flag.ExitOnError)
+	latest := fs.Bool("latest", false, "restore the most recent backup")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	names, err := listBackups()
+	if err != nil {
+		return err
+	}
+	var name string
+	switch {
+	case len(rest) == 1:
+		name = rest[0]
+	case *latest && len(names) > 0:
+		name = names[len(names)-1]
+	default:
+		if len(names) == 0 {
+			fmt.Println("📦 No metadata backups yet")
+			return nil
+		}
+		fmt.Printf("📦 Metadata backups (%d):\n", len(names))
+		for _, n := range names {
+			fmt.Printf("  • %s\n", n)
+		}
+		fmt.Println("💡 Restore one with 'gitnot restore-meta <name>' or '--latest'")
+		return nil
+	}
+
+	if err := requirePassphrase("restore metadata"); err != nil {
+		return err
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+	saved, err := backupMetadata("restore-meta")
+	if err != nil {
+		return fmt.Errorf("backing up current metadata: %w", err)
+	}
+	gen := readGeneration()
+	if err := restoreMetadata(name); err != nil {
+		return err
+	}
+	// the restored store.json carries an older generation; move past the
+	// current one so updates that started before the restore notice it
+	if err := bumpGeneration(gen); err != nil {
+		return err
+	}
+	v, _ := readVersion()
+	fmt.Printf("⏪ Restored metadata from %s (now at v%.1f)\n", filepath.Base(name), v)
+	fmt.Printf("📦 Previous metadata saved as %s\n", filepath.Base(saved))
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupAndRestoreMetadata(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.txt", "one")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	name, err := backupMetadata("test")
+	if err != nil {
+		t.Fatalf("backupMetadata failed: %v", err)
+	}
+
+	createTestFile(t, "a.txt", "two")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.1 {
+		t.Fatalf("Expected v0.1, got v%.1f", v)
+	}
+
+	if err := restoreMetadata(name); err != nil {
+		t.Fatalf("restoreMetadata failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.0 {
+		t.Errorf("Expected v0.0 after restore, got v%.1f", v)
+	}
+	if _, err := os.Stat(manifestPath(0.1)); !os.IsNotExist(err) {
+		t.Error("Manifest newer than the backup should be gone")
+	}
+	if _, err := os.Stat(manifestPath(0.0)); err != nil {
+		t.Error("Manifest from the backup should be restored")
+	}
+	if b, _ := os.ReadFile(filepath.Join(snapshotDir, "a.txt")); string(b) != "one" {
+		t.Errorf("Expected the snapshot of v0.0, got %q", b)
+	}
+	if h := loadCommittedHashes(); h["a.txt"] != contentHash([]byte("one")) {
+		t.Errorf("Expected the hashes of v0.0, got %v", h)
+	}
+	if cl, _ := os.ReadFile(filepath.Join(changelogDir, "a.txt.log")); strings.Contains(string(cl), "v0.1") {
+		t.Errorf("Changelog entries after the backup should be cut off:\n%s", cl)
+	}
+	if h, _ := os.ReadFile(historyFile); strings.Contains(string(h), "v0.1") {
+		t.Errorf("History after the backup should be cut off:\n%s", h)
+	}
+
+	// the next update starts from the restored version
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.1 {
+		t.Errorf("Expected v0.1 again, got v%.1f", v)
+	}
+}
+
+func TestRestoreMetadataDropsNewerFiles(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.txt", "one")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	name, err := backupMetadata("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saveJSON(labelsFile, map[string]string{"a.txt": "draft"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := restoreMetadata(name); err != nil {
+		t.Fatalf("restoreMetadata failed: %v", err)
+	}
+	if _, err := os.Stat(labelsFile); !os.IsNotExist(err) {
+		t.Error("Metadata written after the backup should be gone")
+	}
+}
+
+func TestRestoreMetadataRefusesMissingContent(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.txt", "one")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	name, err := backupMetadata("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, "a.txt", "two")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	// v0.0's content is nowhere to be found any more
+	os.RemoveAll(objectsDir)
+	if err := restoreMetadata(name); err == nil {
+		t.Fatal("Expected the restore to be refused")
+	}
+	if v, _ := readVersion(); v != 0.1 {
+		t.Errorf("Expected to stay at v0.1, got v%.1f", v)
+	}
+	if _, err := os.Stat(manifestPath(0.1)); err != nil {
+		t.Error("Expected the current manifests to be put back")
+	}
+	if b, _ := os.ReadFile(filepath.Join(snapshotDir, "a.txt")); string(b) != "two" {
+		t.Errorf("Expected the snapshot to be left alone, got %q", b)
+	}
+}
+
+func TestBackupRetention(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.txt", "one")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.BackupRetention = 2
+	if err := saveJSON(configFile, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := backupMetadata("test"); err != nil {
+			t.Fatalf("backupMetadata failed: %v", err)
+		}
+	}
+	names, _ := listBackups()
+	if len(names) != 2 {
+		t.Errorf("Expected 2 retained backups, got %d", len(names))
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// --- Bench ---
+//
+// `gitnot bench` times the phases of an update on the current project
+// without recording anything: scanning, hashing (with and without the stat
+// index), diffing against the snapshot and copying. Each phase runs --runs
+// times and the fastest run is reported.
+//
+// `gitnot bench --synthetic N` instead builds a throwaway tree of N small
+// files, a thousand to a folder, and times a real init, an update with
+// nothing changed and one with a file in a thousand edited, reporting how
+// much memory that took. It is how operation on very large trees is checked.
+
+type benchPhase struct {
+	Name  string
+	Took  time.Duration
+	Files int
+	Bytes int64
+}
+
+func (p benchPhase) String() string {
+	s := fmt.Sprintf("%-12s %10s  %6d files", p.Name, p.Took.Round(time.Microsecond), p.Files)
+	if p.Bytes > 0 && p.Took > 0 {
+		s += fmt.Sprintf("  %s/s", formatBytes(int64(float64(p.Bytes)/p.Took.Seconds())))
+	}
+	return s
+}
+
+// timeBest runs f n times and returns the fastest duration.
+func timeBest(n int, f func() error) (time.Duration, error) {
+	var best time.Duration
+	for i := 0; i < n; i++ {
+		start := time.Now()
+		if err := f(); err != nil {
+			return 0, err
+		}
+		if d := time.Since(start); i == 0 || d < best {
+			best = d
+		}
+	}
+	return best, nil
+}
+
+func benchPhases(runs int) ([]benchPhase, error) {
+	var files []string
+	took, err := timeBest(runs, func() (err error) {
+		files, err = getAllTextFiles(".")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	size := fileSizes(files)
+	phases := []benchPhase{{"scan", took, len(files), 0}}
+
+	took, _ = timeBest(runs, func() error {
+		for _, f := range files {
+			hashFile(f)
+		}
+		return nil
+	})
+	phases = append(phases, benchPhase{"hash", took, len(files), size})
+
+	idx := loadIndex()
+	took, _ = timeBest(runs, func() error {
+		hashWithIndex(files, idx, false)
+		return nil
+	})
+	phases = append(phases, benchPhase{"index", took, len(files), 0})
+
+	// diff every file that has a snapshot; unchanged files still cost a diff
+	var diffed []string
+	for _, f := range files {
+		if _, err := repo.FS.Stat(filepath.Join(snapshotDir, f)); err == nil {
+			diffed = append(diffed, f)
+		}
+	}
+	took, _ = timeBest(runs, func() error {
+		for _, f := range diffed {
+			unifiedDiff(filepath.Join(snapshotDir, f), f)
+		}
+		return nil
+	})
+	phases = append(phases, benchPhase{"diff", took, len(diffed), fileSizes(diffed)})
+
+	tmp, err := os.MkdirTemp("", "gitnot-bench-*")
+	if err != nil {
+		return phases, err
+	}
+	defer os.RemoveAll(tmp)
+	took, err = timeBest(runs, func() error {
+		for _, f := range files {
+			if err := copyFile(f, filepath.Join(tmp, f)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return phases, err
+	}
+	phases = append(phases, benchPhase{"copy", took, len(files), size})
+	return phases, nil
+}
+
+// syntheticFile is the i-th file of a synthetic tree and its content.
+func syntheticFile(i int) (string, string) {
+	return filepath.Join(fmt.Sprintf("d%04d", i/1000), fmt.Sprintf("f%06d.md", i)), fmt.Sprintf("# Note %d\n\nSome text.\n", i)
+}
+
+// benchSynthetic times init and update on a generated tree of n files in a
+// temporary folder, which is removed afterwards. Their output is discarded.
+func benchSynthetic(n int) (phases []benchPhase, err error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp("", "gitnot-synthetic-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	store := gitnotDir
+	if err := os.Chdir(tmp); err != nil {
+		return nil, err
+	}
+	useStoreDir(defaultStoreDir)
+	defer func() {
+		useStoreDir(store)
+		if cerr := os.Chdir(wd); err == nil {
+			err = cerr
+		}
+	}()
+
+	var size int64
+	for i := 0; i < n; i++ {
+		rel, text := syntheticFile(i)
+		if i%1000 == 0 {
+			if err := os.MkdirAll(filepath.Dir(rel), 0o755); err != nil {
+				return nil, err
+			}
+		}
+		if err := os.WriteFile(rel, []byte(text), 0o644); err != nil {
+			return nil, err
+		}
+		size += int64(len(text))
+	}
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer devNull.Close()
+	stdout := os.Stdout
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	took, err := timeBest(1, initGitnot)
+	if err != nil {
+		return nil, fmt.Errorf("init: %w", err)
+	}
+	phases = append(phases, benchPhase{"init", took, n, size})
+	if took, err = timeBest(1, updateGitnot); err != nil {
+		return phases, fmt.Errorf("update: %w", err)
+	}
+	phases = append(phases, benchPhase{"unchanged", took, n, 0})
+	edited := 0
+	for i := 0; i < n; i += 1000 {
+		rel, text := syntheticFile(i)
+		if err := os.WriteFile(rel, []byte(text+"An edit.\n"), 0o644); err != nil {
+			return phases, err
+		}
+		edited++
+	}
+	if took, err = timeBest(1, updateGitnot); err != nil {
+		return phases, fmt.Errorf("update: %w", err)
+	}
+	phases = append(phases, benchPhase{"edited", took, edited, 0})
+	return phases, nil
+}
+
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	runs := fs.Int("runs", 3, "times to run each phase (the fastest counts)")
+	synthetic := fs.Int("synthetic", 0, "time init and update on a generated tree of this many files instead")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	var phases []benchPhase
+	var err error
+	if *synthetic != 0 {
+		if *synthetic < 1 {
+			return fmt.Errorf("--synthetic must be at least 1")
+		}
+		fmt.Printf("⏱️  Synthetic tree of %d files:\n", *synthetic)
+		phases, err = benchSynthetic(*synthetic)
+	} else {
+		if err := ensureInitialized(); err != nil {
+			return err
+		}
+		if *runs < 1 {
+			return fmt.Errorf("--runs must be at least 1")
+		}
+		fmt.Printf("⏱️  Best of %d runs:\n", *runs)
+		phases, err = benchPhases(*runs)
+	}
+	if err != nil {
+		return err
+	}
+	var total time.Duration
+	for _, p := range phases {
+		total += p.Took
+		fmt.Printf("  %s\n", p)
+	}
+	fmt.Printf("  %-12s %10s\n", "total", total.Round(time.Microsecond))
+	mem := memoryObtained()
+	fmt.Printf("🧠 %s of memory obtained from the system", formatBytes(int64(mem)))
+	if n := phases[0].Files; n > 0 {
+		fmt.Printf(" (%s per file)", formatBytes(int64(mem)/int64(n)))
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func evalPath(p string) string {
+	p, _ = filepath.EvalSymlinks(p)
+	return p
+}
+
+func TestBenchPhases(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one\ntwo\n")
+	createTestFile(t, "notes/b.txt", "three\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	phases, err := benchPhases(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range phases {
+		names = append(names, p.Name)
+		if p.Files != 2 {
+			t.Errorf("%s: expected 2 files, got %d", p.Name, p.Files)
+		}
+	}
+	if len(names) != 5 || names[0] != "scan" || names[4] != "copy" {
+		t.Errorf("Unexpected phases %v", names)
+	}
+	if v, _ := readVersion(); v != 0 {
+		t.Error("bench must not record a version")
+	}
+}
+
+func TestSplitProfileFlags(t *testing.T) {
+	rest, files, err := splitProfileFlags([]string{"status", "--cpuprofile", "cpu.out", "--full", "-trace=t.out", "--", "--memprofile"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files["cpuprofile"] != "cpu.out" || files["trace"] != "t.out" || files["memprofile"] != "" {
+		t.Errorf("Unexpected profile files %v", files)
+	}
+	if want := []string{"status", "--full", "--", "--memprofile"}; len(rest) != len(want) || rest[1] != "--full" || rest[3] != "--memprofile" {
+		t.Errorf("Expected %v, got %v", want, rest)
+	}
+	if _, _, err := splitProfileFlags([]string{"--memprofile"}); err == nil {
+		t.Error("A profiling flag without a file should fail")
+	}
+}
+
+func TestBenchSynthetic(t *testing.T) {
+	dir := setupTestDir(t)
+	phases, err := benchSynthetic(2500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(phases) != 3 || phases[0].Files != 2500 || phases[2].Files != 3 {
+		t.Errorf("Unexpected phases %v", phases)
+	}
+	wd, _ := os.Getwd()
+	if a, b := evalPath(wd), evalPath(dir); a != b {
+		t.Errorf("Expected to be back in %s, got %s", b, a)
+	}
+	if gitnotDir != defaultStoreDir {
+		t.Errorf("Expected the store to be restored, got %s", gitnotDir)
+	}
+	if _, err := os.Stat(gitnotDir); err == nil {
+		t.Error("The synthetic tree must not touch the current project")
+	}
+}
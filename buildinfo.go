@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// --- Build info ---
+//
+// Release builds set these with:
+//
+//	go build -ldflags "-X main.toolVersion=1.2.0 -X main.commit=abc123 -X main.buildDate=2024-05-01"
+//
+// Anything left empty is filled from the module build info when available.
+var (
+	toolVersion = ""
+	commit      = ""
+	buildDate   = ""
+)
+
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildDate string
+	GoVersion string
+	Modified  bool
+}
+
+func currentBuildInfo() BuildInfo {
+	info := BuildInfo{Version: toolVersion, Commit: commit, BuildDate: buildDate, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if len(info.Commit) > 12 {
+		info.Commit = info.Commit[:12]
+	}
+	return info
+}
+
+func showToolVersion() {
+	info := currentBuildInfo()
+	fmt.Printf("gitnot %s\n", info.Version)
+	c := info.Commit
+	if c == "" {
+		c = "unknown"
+	}
+	if info.Modified {
+		c += " (modified)"
+	}
+	fmt.Printf("  commit:     %s\n", c)
+	d := info.BuildDate
+	if d == "" {
+		d = "unknown"
+	}
+	fmt.Printf("  built:      %s\n", d)
+	fmt.Printf("  go:         %s\n", info.GoVersion)
+	fmt.Printf("  platform:   %s/%s\n", runtime.GOOS, runtime.GOARCH)
+}
+
+func runVersion(args []string) error {
+	showToolVersion()
+	return nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestBuildInfoLdflags(t *testing.T) {
+	oldV, oldC, oldD := toolVersion, commit, buildDate
+	t.Cleanup(func() { toolVersion, commit, buildDate = oldV, oldC, oldD })
+
+	toolVersion, commit, buildDate = "1.2.0", "0123456789abcdef", "2024-05-01"
+	info := currentBuildInfo()
+	if info.Version != "1.2.0" || info.BuildDate != "2024-05-01" {
+		t.Errorf("ldflags values not used: %+v", info)
+	}
+	if info.Commit != "0123456789ab" {
+		t.Errorf("Expected commit shortened to 12 chars, got %q", info.Commit)
+	}
+	if info.GoVersion == "" {
+		t.Error("Go version should always be reported")
+	}
+}
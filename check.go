@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// --- Prose checks ---
+//
+// check_command (e.g. "vale --output=line" or "proselint") runs once per new
+// or modified file during an update, with the file path appended. Every
+// non-empty output line counts as one finding; findings are appended to the
+// file's changelog entry and their count is kept in the manifest, so stats
+// can show whether a text is getting cleaner over time. Linters exit non-zero
+// when they find something, so the exit status itself is ignored.
+
+const (
+	checkTimeout     = time.Minute
+	maxCheckFindings = 20 // lines copied into the changelog per file
+)
+
+var errCheckUnavailable = errors.New("check_command could not be run")
+
+// runCheck runs command against rel and returns its findings.
+func runCheck(command, rel string) ([]string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, fields[0], append(fields[1:], rel)...).CombinedOutput()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("%w: %v", errCheckUnavailable, err)
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("%w: timed out after %s", errCheckUnavailable, checkTimeout)
+	}
+	var findings []string
+	for _, l := range strings.Split(string(out), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			findings = append(findings, l)
+		}
+	}
+	return findings, nil
+}
+
+// checkEntry renders findings as a changelog section.
+func checkEntry(findings []string) string {
+	if len(findings) == 0 {
+		return "### 🔎 Check: no issues\n\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "### 🔎 Check: %d issues\n", len(findings))
+	for _, f := range preview(findings, maxCheckFindings) {
+		b.WriteString(f)
+		b.WriteString("\n")
+	}
+	if len(findings) > maxCheckFindings {
+		fmt.Fprintf(&b, "... and %d more\n", len(findings)-maxCheckFindings)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// checkFile runs the configured check on rel, appends the findings to its
+// changelog and records their count on fc. A check that can't run is
+// reported once through warn and leaves fc untouched.
+func checkFile(command, rel, clPath string, fc *FileChange, warn func(error)) {
+	if command == "" {
+		return
+	}
+	findings, err := runCheck(command, rel)
+	if err != nil {
+		warn(err)
+		return
+	}
+	n := len(findings)
+	fc.Issues = &n
+	_ = appendToFile(clPath, checkEntry(findings))
+}
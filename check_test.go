@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheckCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses grep as the check command")
+	}
+	setupTestDir(t)
+
+	createTestFile(t, "draft.md", "Intro\nTODO: fix\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.CheckCommand = "grep -n TODO"
+	saveJSON(configFile, cfg)
+
+	createTestFile(t, "draft.md", "Intro\nTODO: fix\nTODO: more\n")
+	createTestFile(t, "clean.md", "Done\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	b, _ := os.ReadFile(".gitnot/changelogs/draft.md.log")
+	if !strings.Contains(string(b), "Check: 2 issues") || !strings.Contains(string(b), "3:TODO: more") {
+		t.Errorf("Findings missing from changelog:\n%s", b)
+	}
+	m, _ := loadManifest(0.1)
+	for _, c := range m.Changes {
+		want := map[string]int{"draft.md": 2, "clean.md": 0}[c.Path]
+		if c.Issues == nil || *c.Issues != want {
+			t.Errorf("Expected %d issues recorded for %s, got %v", want, c.Path, c.Issues)
+		}
+	}
+
+	manifests, _ := loadManifests()
+	if st, _ := computeFileStats("draft.md", manifests); len(st.Issues) != 1 || st.Issues[0] != 2 {
+		t.Errorf("Issues not counted in stats: %v", st.Issues)
+	}
+}
+
+func TestCheckCommandMissing(t *testing.T) {
+	if _, err := runCheck("gitnot-no-such-linter", "a.md"); err == nil {
+		t.Error("Expected an error for a missing check command")
+	}
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// --- Config validation ---
+//
+// config.json is parsed key by key so one bad value doesn't throw away the
+// rest: unknown keys, values of the wrong type, bad globs and unknown enum
+// values become warnings on load. `gitnot config lint` lists them and
+// explains what every rule currently matches.
+
+type configIssue struct {
+	Key     string
+	Message string
+}
+
+func (i configIssue) String() string {
+	if i.Key == "" {
+		return i.Message
+	}
+	return i.Key + ": " + i.Message
+}
+
+// parseConfig decodes config.json, skipping values it cannot use.
+func parseConfig(b []byte) (Config, []configIssue) {
+	var cfg Config
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return cfg, []configIssue{{Message: jsonErrorAt(b, err) + "; using the default configuration"}}
+	}
+	issues := decodeFields("", raw, reflect.ValueOf(&cfg).Elem())
+	issues = append(issues, validateConfig(cfg)...)
+	if _, ok := raw["extensions"]; ok && len(cfg.Extensions) == 0 && len(cfg.Rules) == 0 {
+		issues = append(issues, configIssue{"extensions", "no extensions listed; using the default configuration"})
+	}
+	return cfg, issues
+}
+
+// jsonErrorAt turns a decode error into a message with a line number.
+func jsonErrorAt(b []byte, err error) string {
+	var se *json.SyntaxError
+	if errors.As(err, &se) {
+		line := 1 + bytes.Count(b[:min(int(se.Offset), len(b))], []byte("\n"))
+		return fmt.Sprintf("not valid JSON (line %d: %v)", line, err)
+	}
+	return fmt.Sprintf("not a JSON object (%v)", err)
+}
+
+// jsonFields maps json keys to struct field indexes.
+func jsonFields(t reflect.Type) map[string]int {
+	out := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			out[name] = i
+		}
+	}
+	return out
+}
+
+// decodeFields fills the struct v from raw one key at a time, recursing into
+// nested objects so unknown keys are reported wherever they appear.
+func decodeFields(prefix string, raw map[string]json.RawMessage, v reflect.Value) []configIssue {
+	var issues []configIssue
+	fields := jsonFields(v.Type())
+	keys := sortedKeys(raw)
+	for _, k := range keys {
+		key := prefix + k
+		i, ok := fields[k]
+		if !ok {
+			msg := "unknown key, ignored"
+			if s := closestKey(k, fields); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", s)
+			}
+			issues = append(issues, configIssue{key, msg})
+			continue
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Struct {
+			var items []map[string]json.RawMessage
+			if err := json.Unmarshal(raw[k], &items); err != nil {
+				issues = append(issues, configIssue{key, "expected a list of objects, got " + describeJSON(raw[k])})
+				continue
+			}
+			list := reflect.MakeSlice(f.Type(), len(items), len(items))
+			for j, item := range items {
+				issues = append(issues, decodeFields(fmt.Sprintf("%s[%d].", key, j), item, list.Index(j))...)
+			}
+			f.Set(list)
+			continue
+		}
+		if f.Kind() == reflect.Struct {
+			var nested map[string]json.RawMessage
+			if err := json.Unmarshal(raw[k], &nested); err != nil {
+				issues = append(issues, configIssue{key, "expected " + describeType(f.Type()) + ", got " + describeJSON(raw[k])})
+				continue
+			}
+			issues = append(issues, decodeFields(key+".", nested, f)...)
+			continue
+		}
+		p := reflect.New(f.Type())
+		if err := json.Unmarshal(raw[k], p.Interface()); err != nil {
+			issues = append(issues, configIssue{key, "expected " + describeType(f.Type()) + ", got " + describeJSON(raw[k])})
+			continue
+		}
+		f.Set(p.Elem())
+	}
+	return issues
+}
+
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Slice:
+		return "a list of " + strings.TrimPrefix(describeType(t.Elem()), "a ") + "s"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int64:
+		return "a whole number"
+	case reflect.Struct:
+		return "an object"
+	}
+	return t.String()
+}
+
+func describeJSON(raw json.RawMessage) string {
+	s := strings.TrimSpace(string(raw))
+	if s == "" {
+		return "nothing"
+	}
+	switch s[0] {
+	case '[':
+		return "a list"
+	case '{':
+		return "an object"
+	case '"':
+		return "a string"
+	case 't', 'f':
+		return "true/false"
+	case 'n':
+		return "null"
+	}
+	return "the number " + s
+}
+
+// closestKey suggests a known key for a likely typo.
+func closestKey(k string, fields map[string]int) string {
+	norm := func(s string) string {
+		return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(s))
+	}
+	best, bestDist := "", 3
+	for f := range fields {
+		d := editDistance(norm(k), norm(f))
+		if d < bestDist || (d == bestDist && f < best) {
+			best, bestDist = f, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// validateConfig checks values that decoded fine but can't mean anything.
+func validateConfig(cfg Config) []configIssue {
+	var issues []configIssue
+	add := func(key, format string, args ...any) {
+		issues = append(issues, configIssue{key, fmt.Sprintf(format, args...)})
+	}
+	for _, e := range cfg.Extensions {
+		if !strings.HasPrefix(e, ".") {
+			add("extensions", "%q should start with a dot (%q)", e, "."+e)
+		}
+	}
+	checkGlobs := func(key string, pats []string) {
+		for _, p := range pats {
+			if p == "" {
+				add(key, "empty pattern")
+			} else if err := checkGlob(p); err != nil {
+				add(key, "%v", err)
+			} else if strings.Contains(p, `\`) {
+				add(key, "%q contains a backslash; use / in patterns", p)
+			}
+		}
+	}
+	checkGlobs("ignore_patterns", cfg.IgnorePatterns)
+	checkGlobs("include_patterns", cfg.IncludePatterns)
+	issues = append(issues, validateRules(cfg.Rules)...)
+	issues = append(issues, validateHooks(cfg.Hooks)...)
+	issues = append(issues, validatePublish(cfg.Publish)...)
+	issues = append(issues, validateRetry(cfg)...)
+	issues = append(issues, validateLockedFiles(cfg)...)
+	issues = append(issues, validateMemoryLimit(cfg)...)
+	issues = append(issues, validateCopy(cfg)...)
+	issues = append(issues, validatePostUpdate(cfg)...)
+	if cfg.Storage != "" {
+		if _, ok := lookupStorage(cfg.Storage); !ok {
+			add("storage", "%q is not registered, and no gitnot-%s is on PATH", cfg.Storage, cfg.Storage)
+		}
+	}
+	checkGlobs(ignoreFile, readIgnoreFile())
+	checkGlobs("order", cfg.Order)
+	if v := cfg.CloudPlaceholders; v != "" && v != placeholderSkip && v != placeholderHash {
+		add("cloud_placeholders", "%q is not one of %q, %q", v, placeholderSkip, placeholderHash)
+	}
+	switch cfg.Vault.Mode {
+	case "", vaultObsidian, vaultLogseq, vaultNone:
+	default:
+		add("vault.mode", "%q is not one of %q, %q, %q", cfg.Vault.Mode, vaultObsidian, vaultLogseq, vaultNone)
+	}
+	for _, f := range cfg.RecordEnvironment {
+		switch strings.ToLower(f) {
+		case envHost, envOS, envUser, envTool:
+		default:
+			add("record_environment", "%q is not one of host, os, user, tool", f)
+		}
+	}
+	issues = append(issues, validateTrackMetadata(cfg.TrackMetadata)...)
+	issues = append(issues, validatePinnedChanges(cfg.PinnedChanges)...)
+	issues = append(issues, validateMirrors(cfg.Mirrors)...)
+	issues = append(issues, validateAutoTags(cfg.AutoTags)...)
+	issues = append(issues, validateNarrative(cfg.Narrative)...)
+	issues = append(issues, validateHidden(cfg)...)
+	if cfg.ScrubEveryHours < 0 {
+		add("scrub_every_hours", "must not be negative")
+	}
+	if cfg.Throttle.IOMBPerSec < 0 {
+		add("throttle.io_mb_per_sec", "must not be negative")
+	}
+	if p := cfg.Throttle.CPUPercent; p < 0 || p > 99 {
+		add("throttle.cpu_percent", "must be between 1 and 99 (leave it out to use a whole core)")
+	}
+	if cfg.MaxDepth < 0 {
+		add("max_depth", "must not be negative")
+	}
+	for dir, n := range cfg.DepthOverrides {
+		if n < 0 {
+			add("depth_overrides", "%q: must not be negative", dir)
+		}
+	}
+	if cfg.BackupRetention < 0 {
+		add("backup_retention", "must not be negative")
+	}
+	if cfg.DeletedRetention.MaxAgeDays < 0 || cfg.DeletedRetention.MaxSizeMB < 0 {
+		add("deleted_retention", "must not be negative")
+	}
+	if cfg.Export.Template != "" {
+		if _, err := template.New("section").Parse(cfg.Export.Template); err != nil {
+			add("export.template", "%v", err)
+		}
+	}
+	return issues
+}
+
+// ignoreFile holds extra ignore patterns, one per line, next to the tracked
+// files so it can be edited (and synced) like any other text file.
+const ignoreFile = ".gitnotignore"
+
+func readIgnoreFile() []string {
+	pats, _ := readPatternFile(ignoreFile)
+	return pats
+}
+
+// readPatternFile reads one pattern per line, skipping blank lines and
+// # comments; ok is false when the file doesn't exist.
+func readPatternFile(p string) (pats []string, ok bool) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+	for _, l := range strings.Split(string(b), "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" && !strings.HasPrefix(l, "#") {
+			pats = append(pats, l)
+		}
+	}
+	return pats, true
+}
+
+// configWarned remembers which config contents were already warned about, so
+// commands that load the config repeatedly warn once. Warnings go to stderr,
+// as every command loads the config before writing its own output.
+var configWarned = map[string]bool{}
+
+func warnConfigIssues(b []byte, issues []configIssue) {
+	if len(issues) == 0 || configWarned[string(b)] {
+		return
+	}
+	configWarned[string(b)] = true
+	for _, i := range issues {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: %s: %s\n", configFile, i)
+	}
+	fmt.Fprintln(os.Stderr, "💡 Run 'gitnot config lint' for details")
+}
+
+// --- config command ---
+
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: gitnot config lint | export <file> | import <file> [--replace]")
+	}
+	fs := flag.NewFlagSet("config "+args[0], flag.ExitOnError)
+	replace := fs.Bool("replace", false, "with import, replace settings instead of merging")
+	rest, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "lint":
+	case "export":
+		return runConfigExport(rest)
+	case "import":
+		return runConfigImport(rest, *replace)
+	default:
+		return fmt.Errorf("usage: gitnot config lint | export <file> | import <file> [--replace]")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	b, err := repo.FS.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	cfg, issues := parseConfig(b)
+	configWarned[string(b)] = true // reported below instead
+	if len(cfg.Extensions) == 0 && len(cfg.Rules) == 0 {
+		cfg = defaultConfig
+	}
+	for _, i := range issues {
+		fmt.Printf("❌ %s\n", i)
+	}
+	lintRules(cfg)
+	if len(issues) > 0 {
+		return fmt.Errorf("%d problem(s) in %s", len(issues), configFile)
+	}
+	fmt.Printf("✅ %s is valid\n", configFile)
+	return nil
+}
+
+// workingFiles lists every file outside .gitnot and .git, unfiltered.
+func workingFiles() []string {
+	var files []string
+	_ = filepath.WalkDir(".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if isUnderGitnot(p) || d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		files = append(files, p)
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
+
+// matchList names up to three matches.
+func matchList(files []string) string {
+	if len(files) == 0 {
+		return "matches nothing"
+	}
+	shown := make([]string, 0, 3)
+	for _, f := range files[:min(3, len(files))] {
+		shown = append(shown, filepath.ToSlash(f))
+	}
+	noun := "files"
+	if len(files) == 1 {
+		noun = "file"
+	}
+	s := fmt.Sprintf("%d %s: %s", len(files), noun, strings.Join(shown, ", "))
+	if len(files) > 3 {
+		s += ", …"
+	}
+	return s
+}
+
+// lintRules explains each rule in cfg and what it matches right now.
+func lintRules(cfg Config) {
+	filter := cfg.scanFilters()
+	decided := make([][]string, len(filter.Rules))
+	tooLarge := make([]int, len(filter.Rules))
+	for _, f := range workingFiles() {
+		r, i := filter.ruleFor(f)
+		if i < 0 {
+			continue
+		}
+		if fi, err := os.Stat(longPath(f)); err == nil && !r.fits(fi.Size()) {
+			tooLarge[i]++
+			continue
+		}
+		decided[i] = append(decided[i], f)
+	}
+
+	fmt.Println("📏 rules (the first match decides):")
+	for i, r := range filter.Rules {
+		verb := "tracks"
+		if r.ignores() {
+			verb = "hides"
+		}
+		fmt.Printf("  %2d. %-30s %s %s  [%s]\n", i+1, r, verb, matchList(decided[i]), r.source)
+		if tooLarge[i] > 0 {
+			fmt.Printf("      %d files over the size limit are not tracked\n", tooLarge[i])
+		}
+	}
+	if _, ok := readTrackedList(); ok {
+		fmt.Printf("  %s lists the tracked files; rules, extensions and ignore patterns are unused\n", trackedListFile)
+	} else if len(cfg.Rules) > 0 && len(cfg.Extensions)+len(cfg.IncludePatterns)+len(cfg.IgnorePatterns) > 0 {
+		fmt.Println("  extensions, include_patterns and ignore_patterns are unused while rules are set")
+	}
+
+	if len(cfg.Order) > 0 {
+		tracked, _ := getAllTextFiles(".")
+		fmt.Println("📚 order: narrative order for export and stats")
+		for _, o := range cfg.Order {
+			var hits []string
+			for _, f := range tracked {
+				if orderMatches(o, f) {
+					hits = append(hits, f)
+				}
+			}
+			fmt.Printf("  %-12s %s\n", o, matchList(hits))
+		}
+	}
+
+	var settings []string
+	if m := cfg.vaultMode(); m != vaultNone {
+		settings = append(settings, fmt.Sprintf("vault: %s mode (rewrite_links %v)", m, cfg.Vault.RewriteLinks))
+	}
+	if cfg.CloudPlaceholders != "" {
+		settings = append(settings, "cloud_placeholders: "+cfg.CloudPlaceholders)
+	}
+	if cfg.DeletedRetention != (Retention{}) {
+		settings = append(settings, fmt.Sprintf("deleted_retention: %d days, %d MB (0 = no limit)", cfg.DeletedRetention.MaxAgeDays, cfg.DeletedRetention.MaxSizeMB))
+	}
+	settings = append(settings, fmt.Sprintf("backup_retention: %d backups", cfg.backupRetention()))
+	if cfg.CheckCommand != "" {
+		settings = append(settings, "check_command: "+cfg.CheckCommand)
+	}
+	if len(cfg.RecordEnvironment) > 0 {
+		settings = append(settings, "record_environment: "+strings.Join(cfg.RecordEnvironment, ", "))
+	}
+	if cfg.PinnedChanges == pinsWarn {
+		settings = append(settings, "pinned_changes: warn (changes to pinned files are recorded)")
+	}
+	if len(cfg.TrackMetadata) > 0 {
+		s := "track_metadata: " + strings.Join(cfg.TrackMetadata, ", ")
+		if cfg.SkipMetadataOnly {
+			s += " (metadata-only changes not recorded)"
+		}
+		settings = append(settings, s)
+	}
+	if cfg.Author.Name != "" {
+		settings = append(settings, "author: "+cfg.Author.Name+" (unless set per user)")
+	}
+	if cfg.DeltaStorage {
+		settings = append(settings, "delta_storage: new versions stored as deltas")
+	}
+	if cfg.AutoPack {
+		settings = append(settings, "auto_pack: small objects packed after updates")
+	}
+	fmt.Println("⚙️  settings")
+	for _, s := range settings {
+		fmt.Printf("  %s\n", s)
+	}
+}
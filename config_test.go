@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseConfigReportsIssues(t *testing.T) {
+	b := []byte(`{
+  "extensions": [".md", "txt"],
+  "ignore_patterns": "*.tmp",
+  "igonre_patterns": ["*.bak"],
+  "order": ["chapters/[a.md"],
+  "vault": {"mode": "notion", "rewrite_link": true},
+  "backup_retention": 5
+}`)
+	cfg, issues := parseConfig(b)
+	var msgs []string
+	for _, i := range issues {
+		msgs = append(msgs, i.String())
+	}
+	all := strings.Join(msgs, "\n")
+	for _, want := range []string{
+		`extensions: "txt" should start with a dot`,
+		"ignore_patterns: expected a list of strings, got a string",
+		`igonre_patterns: unknown key, ignored (did you mean "ignore_patterns"?)`,
+		`order: "chapters/[a.md" is not a valid glob`,
+		`vault.mode: "notion" is not one of`,
+		`vault.rewrite_link: unknown key, ignored (did you mean "rewrite_links"?)`,
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("Missing issue %q in:\n%s", want, all)
+		}
+	}
+	// good values survive a bad neighbour
+	if cfg.BackupRetention != 5 || len(cfg.Extensions) != 2 {
+		t.Errorf("Valid settings were dropped: %+v", cfg)
+	}
+
+	if _, issues := parseConfig([]byte("{\n  \"extensions\": [\".md\"],\n}")); len(issues) != 1 || !strings.Contains(issues[0].Message, "line 3") {
+		t.Errorf("Syntax error should point at its line, got %v", issues)
+	}
+	if _, issues := parseConfig([]byte(`{"extensions": [".md"]}`)); len(issues) != 0 {
+		t.Errorf("Clean config reported %v", issues)
+	}
+}
+
+func TestLoadConfigKeepsValidSettings(t *testing.T) {
+	setupTestDir(t)
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	os.WriteFile(configFile, []byte(`{"extensions": [".md"], "ignore_patterns": ["*.tmp"], "auto_pack": "yes"}`), 0o644)
+	cfg := loadConfig()
+	if len(cfg.Extensions) != 1 || cfg.Extensions[0] != ".md" || cfg.AutoPack {
+		t.Errorf("Unexpected config after a bad value: %+v", cfg)
+	}
+	if err := runConfig([]string{"lint"}); err == nil {
+		t.Error("config lint should fail on a bad value")
+	}
+	os.WriteFile(configFile, []byte(`{"extensions": [".md"], "ignore_patterns": ["*.tmp"]}`), 0o644)
+	if err := runConfig([]string{"lint"}); err != nil {
+		t.Errorf("config lint failed on a valid config: %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// --- Config profiles ---
+//
+// `gitnot config export profile.json` writes the reusable part of
+// config.json: extensions, patterns, rules, drivers and the like, without
+// settings that only make sense in this project (file order, per-folder
+// depths, the project author) or on one machine: commands run on updates
+// and paths to tools, folders and backends. A profile that carries them
+// anyway has them skipped on import, so importing a teammate's profile never
+// installs their commands. `gitnot config import profile.json` merges a
+// profile into this project's config: lists gain the entries they are
+// missing, objects are merged key by key and plain values are replaced. With
+// --replace the profile's settings replace the current ones outright.
+
+// projectKeys are config keys a profile never carries; a dot separates the
+// keys of nested objects.
+var projectKeys = []string{
+	"order", "depth_overrides", "author",
+	"hooks", "check_command", "export.pandoc", "publish", "mirrors", "storage",
+}
+
+// takeKey removes the dotted key from m and returns its value. An object
+// left empty goes too.
+func takeKey(m map[string]any, key string) (any, bool) {
+	first, rest, nested := strings.Cut(key, ".")
+	if !nested {
+		v, ok := m[key]
+		delete(m, key)
+		return v, ok
+	}
+	sub, ok := m[first].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	v, ok := takeKey(sub, rest)
+	if len(sub) == 0 {
+		delete(m, first)
+	}
+	return v, ok
+}
+
+// putKey sets the dotted key in m, creating objects on the way.
+func putKey(m map[string]any, key string, v any) {
+	first, rest, nested := strings.Cut(key, ".")
+	if !nested {
+		m[key] = v
+		return
+	}
+	sub, ok := m[first].(map[string]any)
+	if !ok {
+		sub = map[string]any{}
+		m[first] = sub
+	}
+	putKey(sub, rest, v)
+}
+
+// currentConfigJSON returns config.json as a JSON object, or the defaults
+// when there is none.
+func currentConfigJSON() (map[string]any, error) {
+	b, err := repo.FS.ReadFile(configFile)
+	if errors.Is(err, os.ErrNotExist) {
+		b, err = json.Marshal(defaultConfig)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("%s: %s", configFile, jsonErrorAt(b, err))
+	}
+	return m, nil
+}
+
+func exportProfile() ([]byte, error) {
+	m, err := currentConfigJSON()
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range projectKeys {
+		takeKey(m, k)
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	return append(b, '\n'), err
+}
+
+// mergeJSON merges src into dst: objects key by key, lists by appending the
+// entries dst lacks, anything else taken from src.
+func mergeJSON(dst, src any) any {
+	switch s := src.(type) {
+	case map[string]any:
+		d, ok := dst.(map[string]any)
+		if !ok {
+			return s
+		}
+		for k, v := range s {
+			d[k] = mergeJSON(d[k], v)
+		}
+		return d
+	case []any:
+		d, ok := dst.([]any)
+		if !ok {
+			return s
+		}
+		seen := map[string]bool{}
+		for _, v := range d {
+			b, _ := json.Marshal(v)
+			seen[string(b)] = true
+		}
+		for _, v := range s {
+			if b, _ := json.Marshal(v); !seen[string(b)] {
+				seen[string(b)] = true
+				d = append(d, v)
+			}
+		}
+		return d
+	}
+	return src
+}
+
+// importProfile returns this project's config with profile applied. Project
+// settings in the profile are skipped and returned in dropped.
+func importProfile(profile []byte, replace bool) (cfg Config, dropped []string, err error) {
+	var p map[string]any
+	if err := json.Unmarshal(profile, &p); err != nil {
+		return cfg, nil, fmt.Errorf("profile: %s", jsonErrorAt(profile, err))
+	}
+	for _, k := range projectKeys {
+		if _, ok := takeKey(p, k); ok {
+			dropped = append(dropped, k)
+		}
+	}
+	// skipped settings are not checked: they may name things that only
+	// exist on the machine the profile came from
+	kept, err := json.Marshal(p)
+	if err != nil {
+		return cfg, nil, err
+	}
+	if _, issues := parseConfig(kept); len(issues) > 0 {
+		msgs := make([]string, len(issues))
+		for i, is := range issues {
+			msgs[i] = "  " + is.String()
+		}
+		return cfg, nil, fmt.Errorf("profile has problems, nothing imported:\n%s", strings.Join(msgs, "\n"))
+	}
+	cur, err := currentConfigJSON()
+	if err != nil {
+		return cfg, nil, err
+	}
+	merged := cur
+	if replace {
+		merged = p
+		for _, k := range projectKeys {
+			if v, ok := takeKey(cur, k); ok {
+				putKey(merged, k, v)
+			}
+		}
+	} else {
+		mergeJSON(merged, p)
+	}
+	b, err := json.Marshal(merged)
+	if err != nil {
+		return cfg, nil, err
+	}
+	cfg, issues := parseConfig(b)
+	if len(issues) > 0 {
+		return cfg, nil, fmt.Errorf("merged config is invalid, nothing imported: %s", issues[0])
+	}
+	return cfg, dropped, nil
+}
+
+func runConfigExport(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: gitnot config export <profile.json>")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	b, err := exportProfile()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(args[0], b, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("📤 Exported config profile to %s\n", args[0])
+	return nil
+}
+
+func runConfigImport(args []string, replace bool) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: gitnot config import <profile.json> [--replace]")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	profile, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	cfg, dropped, err := importProfile(profile, replace)
+	if err != nil {
+		return err
+	}
+	if len(dropped) > 0 {
+		fmt.Printf("⚠️  Skipped settings that belong to one project or machine: %s\n", strings.Join(dropped, ", "))
+	}
+	if _, err := backupMetadata("config-import"); err != nil {
+		return fmt.Errorf("backing up before import: %w", err)
+	}
+	if err := saveJSON(configFile, cfg); err != nil {
+		return err
+	}
+	how := "Merged"
+	if replace {
+		how = "Applied"
+	}
+	fmt.Printf("📥 %s config profile %s into %s\n", how, args[0], configFile)
+	fmt.Println("💡 'gitnot config lint' shows what is tracked now")
+	return nil
+}
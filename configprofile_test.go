@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestConfigProfiles(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, configFile, `{
+  "extensions": [".md", ".txt"],
+  "ignore_patterns": ["*.tmp"],
+  "order": ["a.md"],
+  "vault": {"mode": "none"}
+}`)
+	if err := runConfig([]string{"export", "profile.json"}); err != nil {
+		t.Fatalf("config export failed: %v", err)
+	}
+	b, _ := os.ReadFile("profile.json")
+	if strings.Contains(string(b), "order") || !strings.Contains(string(b), ".txt") {
+		t.Errorf("Profile should keep extensions and drop order:\n%s", b)
+	}
+
+	createTestFile(t, configFile, `{"extensions": [".md", ".fountain"], "ignore_patterns": ["*.bak"], "order": ["b.md"]}`)
+	createTestFile(t, "team.json", `{"extensions": [".txt"], "vault": {"mode": "none"}, "order": ["x.md"], "auto_pack": true}`)
+
+	cfg, dropped, err := importProfile([]byte(`{"extensions": [".txt"], "vault": {"mode": "none"}, "order": ["x.md"], "auto_pack": true}`), false)
+	if err != nil {
+		t.Fatalf("importProfile failed: %v", err)
+	}
+	if !slices.Equal(cfg.Extensions, []string{".md", ".fountain", ".txt"}) || !cfg.AutoPack || cfg.Vault.Mode != "none" {
+		t.Errorf("Unexpected merge result: %+v", cfg)
+	}
+	if !slices.Equal(cfg.Order, []string{"b.md"}) || !slices.Equal(dropped, []string{"order"}) {
+		t.Errorf("Project order should be kept and the profile's skipped, got %v (dropped %v)", cfg.Order, dropped)
+	}
+
+	if err := runConfig([]string{"import", "team.json", "--replace"}); err != nil {
+		t.Fatalf("config import --replace failed: %v", err)
+	}
+	cfg = loadConfig()
+	if !slices.Equal(cfg.Extensions, []string{".txt"}) || len(cfg.IgnorePatterns) != 0 || !slices.Equal(cfg.Order, []string{"b.md"}) {
+		t.Errorf("Unexpected replace result: %+v", cfg)
+	}
+
+	createTestFile(t, "bad.json", `{"extensions": ["md"]}`)
+	if err := runConfig([]string{"import", "bad.json"}); err == nil || !strings.Contains(err.Error(), "nothing imported") {
+		t.Errorf("Invalid profile should be refused, got %v", err)
+	}
+}
+
+func TestProfilesLeaveMachineSettingsOut(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, configFile, `{
+  "extensions": [".md"],
+  "check_command": "vale",
+  "hooks": [{"match": "*.md", "run": "make"}],
+  "export": {"separator": "***", "pandoc": "/opt/pandoc"},
+  "mirrors": ["/mnt/backup"]
+}`)
+	b, err := exportProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"vale", "make", "/opt/pandoc", "/mnt/backup"} {
+		if strings.Contains(string(b), s) {
+			t.Errorf("Profile should not carry %q:\n%s", s, b)
+		}
+	}
+	if !strings.Contains(string(b), "***") {
+		t.Errorf("Profile should keep the export separator:\n%s", b)
+	}
+
+	team := `{"extensions": [".txt"], "hooks": [{"match": "*", "run": "curl evil"}],
+  "check_command": "rm -rf", "export": {"pandoc": "/home/them/pandoc"}, "storage": "s3", "publish": [{"match": "*.md", "to": "/srv"}]}`
+	cfg, dropped, err := importProfile([]byte(team), false)
+	if err != nil {
+		t.Fatalf("importProfile failed: %v", err)
+	}
+	if cfg.CheckCommand != "vale" || len(cfg.Hooks) != 1 || cfg.Hooks[0].Run != "make" ||
+		cfg.Export.Pandoc != "/opt/pandoc" || cfg.Storage != "" || len(cfg.Publish) != 0 {
+		t.Errorf("Machine settings should be kept from this project, got %+v", cfg)
+	}
+	slices.Sort(dropped)
+	if want := []string{"check_command", "export.pandoc", "hooks", "publish", "storage"}; !slices.Equal(dropped, want) {
+		t.Errorf("Expected %v reported as skipped, got %v", want, dropped)
+	}
+
+	cfg, _, err = importProfile([]byte(team), true)
+	if err != nil {
+		t.Fatalf("importProfile --replace failed: %v", err)
+	}
+	if cfg.CheckCommand != "vale" || cfg.Export.Pandoc != "/opt/pandoc" || !slices.Equal(cfg.Mirrors, []string{"/mnt/backup"}) {
+		t.Errorf("Replacing should keep this project's machine settings, got %+v", cfg)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// --- Copying files ---
+//
+// Snapshots, objects, deleted copies, mirrors and published files are all
+// made by copyFile. File to file, the OS is left to copy in the kernel
+// where it can; a throttled read goes through a pooled 256 KiB buffer
+// rather than a new one per file. A source with holes — a sparse disk image
+// or database — is copied hole for hole instead of being written out as
+// zeros.
+//
+// The "copy" config object adds what some setups need:
+//
+//   - "preserve_times" and "preserve_mode" give copies the source's
+//     modification time and permission bits, and make restore put back the
+//     ones a version recorded (with "track_metadata"). Copies always stay
+//     writable by their owner, so gitnot can replace them later.
+//   - "fsync" says when copies are forced to disk. "off" (the default)
+//     leaves it to the OS. "always" flushes every copy and metadata file as
+//     it is closed. "batch" flushes everything once just before a version is
+//     committed and once after, which on Linux is a single system call per
+//     version; elsewhere it flushes file by file like "always".
+
+const (
+	fsyncOff    = "off"
+	fsyncBatch  = "batch"
+	fsyncAlways = "always"
+
+	copyBufferSize = 256 << 10
+	sparseBlock    = 4096
+	sparseMinSize  = 64 << 10 // smaller files are copied as they are
+)
+
+// CopyConfig is the "copy" config object.
+type CopyConfig struct {
+	PreserveTimes bool   `json:"preserve_times,omitempty"` // copies keep the source's modification time
+	PreserveMode  bool   `json:"preserve_mode,omitempty"`  // copies keep the source's permission bits
+	Fsync         string `json:"fsync,omitempty"`          // "off" (default), "batch" or "always"
+}
+
+var (
+	copyMu    sync.Mutex
+	copyOpts  CopyConfig
+	copyDirty bool // batch mode: written since the last flush
+
+	copyBuffers = sync.Pool{New: func() any {
+		b := make([]byte, copyBufferSize)
+		return &b
+	}}
+	zeroBlock [sparseBlock]byte
+)
+
+// setCopy applies the copy config for the rest of the process.
+func setCopy(cc CopyConfig) {
+	copyMu.Lock()
+	defer copyMu.Unlock()
+	copyOpts = cc
+}
+
+func copyConfig() CopyConfig {
+	copyMu.Lock()
+	defer copyMu.Unlock()
+	return copyOpts
+}
+
+// flushOnClose reports whether a file just written must be synced before it
+// is closed; otherwise a batch flush is noted as due when one applies.
+func flushOnClose() bool {
+	copyMu.Lock()
+	defer copyMu.Unlock()
+	switch copyOpts.Fsync {
+	case fsyncAlways:
+		return true
+	case fsyncBatch:
+		if !batchSyncSupported {
+			return true
+		}
+		copyDirty = true
+	}
+	return false
+}
+
+// syncFile flushes f to disk when the file system it came from can.
+func syncFile(f File) error {
+	if s, ok := f.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// closeWritten closes a file that was just written, syncing it first when
+// the fsync policy asks for it.
+func closeWritten(f File) error {
+	if flushOnClose() {
+		if err := syncFile(f); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// flushCopies forces everything written since the last flush to disk in
+// batch mode. It runs before and after a version is committed, so a version
+// never refers to copies that a power cut could still take away.
+func flushCopies() {
+	copyMu.Lock()
+	dirty := copyDirty
+	copyDirty = false
+	copyMu.Unlock()
+	if dirty {
+		syncAll()
+	}
+}
+
+// allZero reports whether b holds nothing but zero bytes.
+func allZero(b []byte) bool {
+	return bytes.Equal(b, zeroBlock[:len(b)])
+}
+
+// copySparse copies r to w, seeking over zeroed blocks instead of writing
+// them so the copy keeps the holes of a sparse source. Without seeking it
+// writes the zeros.
+func copySparse(w File, r io.Reader, buf []byte) error {
+	sw, ok := w.(interface {
+		io.Seeker
+		Truncate(size int64) error
+	})
+	if !ok {
+		_, err := io.CopyBuffer(w, r, buf)
+		return err
+	}
+	var size int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		for i := 0; i < n; {
+			j := i
+			for j < n && !allZero(buf[j:min(j+sparseBlock, n)]) {
+				j = min(j+sparseBlock, n)
+			}
+			if j > i {
+				if _, werr := w.Write(buf[i:j]); werr != nil {
+					return werr
+				}
+			}
+			i = j
+			for j < n && allZero(buf[j:min(j+sparseBlock, n)]) {
+				j = min(j+sparseBlock, n)
+			}
+			if j > i {
+				if _, serr := sw.Seek(int64(j-i), io.SeekCurrent); serr != nil {
+					return serr
+				}
+			}
+			i = j
+		}
+		size += int64(n)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// a trailing hole is only there once the length is set
+	return sw.Truncate(size)
+}
+
+// applyCopyMeta gives dst the time and mode of src as configured.
+func applyCopyMeta(dst string, src fs.FileInfo, cc CopyConfig) error {
+	if cc.PreserveMode {
+		if err := repo.FS.Chmod(longPath(dst), src.Mode().Perm()|0o200); err != nil {
+			return err
+		}
+	}
+	if cc.PreserveTimes {
+		return os.Chtimes(longPath(dst), src.ModTime(), src.ModTime())
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	return withRetry(func() error {
+		srcF, err := openSource(src)
+		if err != nil {
+			return err
+		}
+		defer srcF.Close()
+		fi, err := srcF.Stat()
+		if err != nil {
+			return err
+		}
+		if err := safeMkdirAllForFile(dst); err != nil {
+			return err
+		}
+		dstF, err := repo.FS.OpenFile(longPath(dst), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+		if err != nil {
+			return err
+		}
+		buf := copyBuffers.Get().(*[]byte)
+		defer copyBuffers.Put(buf)
+		r := throttled(srcF)
+		_, plain := r.(*os.File)
+		switch {
+		case fi.Size() >= sparseMinSize && isSparse(fi):
+			err = copySparse(dstF, r, *buf)
+		case plain:
+			_, err = io.Copy(dstF, srcF) // in the kernel where the OS can
+		default:
+			_, err = io.CopyBuffer(struct{ io.Writer }{dstF}, r, *buf)
+		}
+		if err != nil {
+			dstF.Close()
+			return err
+		}
+		if err := closeWritten(dstF); err != nil {
+			return err
+		}
+		return applyCopyMeta(dst, fi, copyConfig())
+	})
+}
+
+// restoreMeta puts back the mode and modification time a version recorded
+// for a restored file, as far as the copy config asks to preserve them.
+func restoreMeta(p string, m FileMeta, cc CopyConfig) error {
+	if cc.PreserveMode && m.Mode != "" {
+		var mode uint32
+		if _, err := fmt.Sscanf(m.Mode, "%o", &mode); err == nil {
+			if err := os.Chmod(longPath(p), os.FileMode(mode).Perm()); err != nil {
+				return err
+			}
+		}
+	}
+	if cc.PreserveTimes && !m.MTime.IsZero() {
+		return os.Chtimes(longPath(p), m.MTime, m.MTime)
+	}
+	return nil
+}
+
+func validateCopy(c Config) []configIssue {
+	switch c.Copy.Fsync {
+	case "", fsyncOff, fsyncBatch, fsyncAlways:
+		return nil
+	}
+	return []configIssue{{"copy.fsync", fmt.Sprintf("unknown value %q; use \"off\", \"batch\" or \"always\"", c.Copy.Fsync)}}
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package main
+
+import "io/fs"
+
+const batchSyncSupported = false
+
+// isSparse is unknown here; files are copied as they are.
+func isSparse(fi fs.FileInfo) bool {
+	return false
+}
+
+func syncAll() {}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCopyFileKeepsHoles(t *testing.T) {
+	setupTestDir(t)
+	f, err := os.Create("disk.img")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("header"))
+	f.WriteAt([]byte("middle"), 1<<20)
+	f.Truncate(4 << 20) // ends in a hole
+	f.Close()
+
+	if err := copyFile("disk.img", filepath.Join("copies", "disk.img")); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := os.ReadFile("disk.img")
+	got, _ := os.ReadFile(filepath.Join("copies", "disk.img"))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("The copy differs: %d bytes, want %d", len(got), len(want))
+	}
+	src, _ := os.Stat("disk.img")
+	if !isSparse(src) {
+		t.Skip("this file system doesn't keep holes")
+	}
+	if dst, _ := os.Stat(filepath.Join("copies", "disk.img")); !isSparse(dst) {
+		t.Error("Expected the copy to keep the holes")
+	}
+}
+
+func TestCopySparseWithoutSeeking(t *testing.T) {
+	data := append(append([]byte("start"), make([]byte, 3*sparseBlock)...), "end"...)
+	var out bytes.Buffer
+	if err := copySparse(fileWriter{&out}, bytes.NewReader(data), make([]byte, sparseBlock*2)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Error("Expected the zeros to be written when the target can't seek")
+	}
+}
+
+// fileWriter is a File that can't seek.
+type fileWriter struct{ *bytes.Buffer }
+
+func (fileWriter) Close() error             { return nil }
+func (fileWriter) Name() string             { return "buffer" }
+func (fileWriter) Read([]byte) (int, error) { return 0, nil }
+
+func TestCopyFilePreservesMetadata(t *testing.T) {
+	setupTestDir(t)
+	t.Cleanup(func() { setCopy(CopyConfig{}) })
+	createTestFile(t, "script.sh", "echo hi\n")
+	old := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chmod("script.sh", 0o555)
+	os.Chtimes("script.sh", old, old)
+
+	if err := copyFile("script.sh", "plain.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := os.Stat("plain.sh"); fi.ModTime().Equal(old) {
+		t.Error("Expected a plain copy to get a new modification time")
+	}
+
+	setCopy(CopyConfig{PreserveTimes: true, PreserveMode: true})
+	if err := copyFile("script.sh", "kept.sh"); err != nil {
+		t.Fatal(err)
+	}
+	fi, _ := os.Stat("kept.sh")
+	if !fi.ModTime().Equal(old) {
+		t.Errorf("Expected the modification time %v, got %v", old, fi.ModTime())
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm() != 0o755 {
+		t.Errorf("Expected the mode to be kept, writable by its owner, got %v", fi.Mode().Perm())
+	}
+	// and the copy can be replaced
+	if err := copyFile("script.sh", "kept.sh"); err != nil {
+		t.Errorf("Expected to copy over a preserved copy: %v", err)
+	}
+}
+
+func TestFsyncPolicies(t *testing.T) {
+	setupTestDir(t)
+	t.Cleanup(func() { setCopy(CopyConfig{}) })
+	createTestFile(t, "a.md", "One\n")
+	for _, policy := range []string{fsyncOff, fsyncBatch, fsyncAlways} {
+		setCopy(CopyConfig{Fsync: policy})
+		if err := copyFile("a.md", "b.md"); err != nil {
+			t.Fatalf("%s: %v", policy, err)
+		}
+		if copyDirty != (policy == fsyncBatch && batchSyncSupported) {
+			t.Errorf("%s: unexpected pending flush %v", policy, copyDirty)
+		}
+		flushCopies()
+		if copyDirty {
+			t.Errorf("%s: expected the flush to clear what was pending", policy)
+		}
+	}
+
+	// a whole update flushes before and after committing
+	setCopy(CopyConfig{})
+	cfg := loadConfig()
+	cfg.Copy.Fsync = fsyncBatch
+	saveJSON(configFile, cfg)
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "a.md", "One, edited\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.1 || copyDirty {
+		t.Errorf("Expected v0.1 with nothing left to flush, got v%.1f (pending %v)", v, copyDirty)
+	}
+}
+
+func TestRestorePutsBackRecordedMetadata(t *testing.T) {
+	setupTestDir(t)
+	t.Cleanup(func() { setCopy(CopyConfig{}) })
+	cfg := loadConfig()
+	cfg.TrackMetadata = []string{"mode", "mtime"}
+	cfg.Copy = CopyConfig{PreserveTimes: true, PreserveMode: true}
+	saveJSON(configFile, cfg)
+	createTestFile(t, "ch1.md", "One\n")
+	old := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	os.Chmod("ch1.md", 0o600)
+	os.Chtimes("ch1.md", old, old)
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+
+	createTestFile(t, "ch1.md", "One, rewritten\n")
+	os.Chmod("ch1.md", 0o644)
+	m, _ := loadManifest(0.0)
+	if _, err := restoreFiles(m, []string{"ch1.md"}); err != nil {
+		t.Fatal(err)
+	}
+	fi, _ := os.Stat("ch1.md")
+	if !fi.ModTime().Equal(old) {
+		t.Errorf("Expected the recorded modification time %v, got %v", old, fi.ModTime())
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the recorded mode 0600, got %v", fi.Mode().Perm())
+	}
+}
+
+func TestValidateCopy(t *testing.T) {
+	if issues := validateCopy(Config{Copy: CopyConfig{Fsync: "batch"}}); len(issues) != 0 {
+		t.Errorf("Unexpected issues %v", issues)
+	}
+	if issues := validateCopy(Config{Copy: CopyConfig{Fsync: "sometimes"}}); len(issues) != 1 || issues[0].Key != "copy.fsync" {
+		t.Errorf("Expected an unknown fsync policy to be reported, got %v", issues)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"io/fs"
+	"runtime"
+	"syscall"
+)
+
+// batchSyncSupported: Linux's sync waits until everything is on disk; the
+// BSDs' only schedules the writes.
+const batchSyncSupported = runtime.GOOS == "linux"
+
+// isSparse reports whether the file takes up less space on disk than its
+// length, i.e. has holes.
+func isSparse(fi fs.FileInfo) bool {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	return ok && st.Blocks*512 < fi.Size()
+}
+
+// syncAll flushes every file system's pending writes to disk.
+func syncAll() {
+	syscall.Sync()
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package main
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+const (
+	batchSyncSupported = false // flushing a whole volume needs administrator rights
+
+	fileAttributeSparseFile = 0x00000200
+)
+
+// isSparse reports whether the file is marked sparse. Copies are written
+// with their holes skipped, which NTFS fills with zeros unless the copy is
+// marked sparse too; the content is the same either way.
+func isSparse(fi fs.FileInfo) bool {
+	d, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	return ok && d.FileAttributes&fileAttributeSparseFile != 0
+}
+
+func syncAll() {}
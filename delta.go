@@ -0,0 +1,266 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/codinganovel/go-difflib/difflib"
+)
+
+// --- Delta storage ---
+//
+// With delta_storage enabled, a new version of a file is stored as a
+// line-based delta against the object it replaced (objects/ab/cdef….delta)
+// instead of a full copy, as long as that is at least half the size. Every
+// maxDeltaChain-th version is stored in full so reads never replay long
+// chains. Readers don't care which form an object is in; `gitnot repack`
+// rewrites the whole store into delta form (or back with --full).
+
+const maxDeltaChain = 10
+
+// Delta rebuilds an object from its base: copy ranges of base lines and
+// insert literal text, in order.
+type Delta struct {
+	Base  string    `json:"base"`
+	Depth int       `json:"depth"` // deltas to replay before reaching a full object
+	Ops   []DeltaOp `json:"ops"`
+}
+
+type DeltaOp struct {
+	Copy   []int  `json:"c,omitempty"` // [from, to) base line range
+	Insert string `json:"i,omitempty"`
+}
+
+func deltaPath(hash string) string {
+	return objectPath(hash) + ".delta"
+}
+
+func contentHash(b []byte) string {
+	return fmt.Sprintf("%x", sha1.Sum(b))
+}
+
+func makeDelta(base, target []byte) []DeltaOp {
+	a := splitTextLines(string(base))
+	b := splitTextLines(string(target))
+	var ops []DeltaOp
+	for _, op := range difflib.NewMatcher(a, b).GetOpCodes() {
+		if op.Tag == 'e' {
+			ops = append(ops, DeltaOp{Copy: []int{op.I1, op.I2}})
+			continue
+		}
+		if op.J2 > op.J1 {
+			ops = append(ops, DeltaOp{Insert: strings.Join(b[op.J1:op.J2], "")})
+		}
+	}
+	return ops
+}
+
+func applyDelta(base []byte, ops []DeltaOp) ([]byte, error) {
+	lines := splitTextLines(string(base))
+	var out []byte
+	for _, op := range ops {
+		if op.Copy == nil {
+			out = append(out, op.Insert...)
+			continue
+		}
+		if len(op.Copy) != 2 || op.Copy[0] < 0 || op.Copy[1] > len(lines) || op.Copy[0] > op.Copy[1] {
+			return nil, fmt.Errorf("delta copies lines %v outside its base", op.Copy)
+		}
+		for _, l := range lines[op.Copy[0]:op.Copy[1]] {
+			out = append(out, l...)
+		}
+	}
+	return out, nil
+}
+
+func readDelta(hash string) (Delta, error) {
+	var d Delta
+	b, isDelta, err := storedObject(hash)
+	if err != nil {
+		return d, err
+	}
+	if !isDelta {
+		return d, fmt.Errorf("object %s is not a delta", hash)
+	}
+	return d, json.Unmarshal(b, &d)
+}
+
+// readDeltaObject resolves a delta-stored object through its chain.
+func readDeltaObject(hash string, hops int) ([]byte, error) {
+	if hops > 4*maxDeltaChain {
+		return nil, fmt.Errorf("delta chain for %s is too long or cyclic", hash)
+	}
+	d, err := readDelta(hash)
+	if err != nil {
+		return nil, err
+	}
+	base, err := readObjectHops(d.Base, hops+1)
+	if err != nil {
+		return nil, err
+	}
+	b, err := applyDelta(base, d.Ops)
+	if err != nil {
+		return nil, err
+	}
+	if got := contentHash(b); got != hash {
+		return nil, &corruptError{Hash: hash, Got: got}
+	}
+	return b, nil
+}
+
+// objectDepth is 0 for a full object and the chain length for a delta.
+func objectDepth(hash string) int {
+	if d, err := readDelta(hash); err == nil {
+		return d.Depth
+	}
+	return 0
+}
+
+// writeDeltaObject stores target as a delta against base if that pays off
+// and the chain stays short; it reports whether it did.
+func writeDeltaObject(hash string, target []byte, base string) (bool, error) {
+	if !validObjectHash(base) || base == hash || !hasObject(base) {
+		return false, nil
+	}
+	depth := objectDepth(base) + 1
+	if depth > maxDeltaChain {
+		return false, nil
+	}
+	baseB, err := readObject(base)
+	if err != nil {
+		return false, nil
+	}
+	ops := makeDelta(baseB, target)
+	enc, err := json.Marshal(Delta{Base: base, Depth: depth, Ops: ops})
+	if err != nil {
+		return false, err
+	}
+	if len(enc) > len(target)/2 {
+		return false, nil
+	}
+	if check, err := applyDelta(baseB, ops); err != nil || contentHash(check) != hash {
+		return false, nil // never store a delta that doesn't round-trip
+	}
+	if err := safeMkdirAllForFile(deltaPath(hash)); err != nil {
+		return false, err
+	}
+	return true, writeFileAtomic(deltaPath(hash), enc, 0o644)
+}
+
+// storeVersionObject stores the new content of a file, as a delta against
+// its previous object when delta storage is on.
+func storeVersionObject(cfg Config, src, hash, prev string) error {
+	if !cfg.DeltaStorage || !validObjectHash(hash) || hasObject(hash) {
+		return storeObject(src, hash)
+	}
+	b, err := os.ReadFile(longPath(src))
+	if err != nil {
+		return err
+	}
+	if contentHash(b) != hash {
+		return storeObject(src, hash)
+	}
+	if ok, err := writeDeltaObject(hash, b, prev); ok || err != nil {
+		return err
+	}
+	return storeObject(src, hash)
+}
+
+// writeFullObject turns hash into a full object, dropping any delta.
+func writeFullObject(hash string, b []byte) error {
+	p := objectPath(hash)
+	if _, err := os.Stat(p); errors.Is(err, os.ErrNotExist) {
+		if err := safeMkdirAllForFile(p); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(p, b, 0o644); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(deltaPath(hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// repackObjects rewrites every object referenced by a manifest, walking each
+// file's history in version order: the first version of a file is stored in
+// full, later ones as deltas against the previous, with a full copy every
+// maxDeltaChain versions. With full set, every object is expanded instead.
+func repackObjects(full bool) (deltas, fulls int, err error) {
+	done := map[string]bool{}
+	last := map[string]string{}
+	err = eachManifest(func(m Manifest) error {
+		for _, p := range sortedKeys(m.Files) {
+			h, prev := m.Files[p], last[p]
+			last[p] = h
+			if done[h] || !hasObject(h) {
+				continue
+			}
+			done[h] = true
+			b, err := readObject(h)
+			if err != nil {
+				return err
+			}
+			if !full && prev != "" {
+				if objectDepth(h) > 0 {
+					// drop the old delta so the new one is built from scratch
+					if err := writeFullObject(h, b); err != nil {
+						return err
+					}
+				}
+				ok, err := writeDeltaObject(h, b, prev)
+				if err != nil {
+					return err
+				}
+				if ok {
+					if err := repo.FS.Remove(objectPath(h)); err != nil && !errors.Is(err, os.ErrNotExist) {
+						return err
+					}
+					deltas++
+					continue
+				}
+			}
+			if err := writeFullObject(h, b); err != nil {
+				return err
+			}
+			fulls++
+		}
+		return nil
+	})
+	return deltas, fulls, err
+}
+
+func runRepack(args []string) error {
+	fs := flag.NewFlagSet("repack", flag.ExitOnError)
+	full := fs.Bool("full", false, "expand every object to a full copy instead of deltas")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if err := requirePassphrase("rewrite the object store"); err != nil {
+		return err
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	before, _ := dirSize(objectsDir)
+	deltas, fulls, err := repackObjects(*full)
+	if err != nil {
+		return err
+	}
+	after, _ := dirSize(objectsDir)
+	fmt.Printf("📦 Repacked %d objects: %d deltas, %d full copies\n", deltas+fulls, deltas, fulls)
+	fmt.Printf("💾 Object store: %s → %s\n", formatBytes(before), formatBytes(after))
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDeltaRoundTrip(t *testing.T) {
+	base := []byte("one\ntwo\nthree\nfour")
+	target := []byte("zero\none\nthree\nfour\nfive\n")
+	got, err := applyDelta(base, makeDelta(base, target))
+	if err != nil || string(got) != string(target) {
+		t.Errorf("Delta did not round-trip: %q, %v", got, err)
+	}
+	if _, err := applyDelta(base, []DeltaOp{{Copy: []int{2, 9}}}); err == nil {
+		t.Error("Expected an error for a copy outside the base")
+	}
+}
+
+// longText returns a document big enough for deltas to pay off.
+func longText(edit int) string {
+	var b strings.Builder
+	for i := 0; i < 200; i++ {
+		if i == edit {
+			fmt.Fprintf(&b, "line %d was edited\n", i)
+			continue
+		}
+		fmt.Fprintf(&b, "line %d of a long chapter that rarely changes\n", i)
+	}
+	return b.String()
+}
+
+func TestDeltaStorage(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "book.md", longText(-1))
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.DeltaStorage = true
+	saveJSON(configFile, cfg)
+
+	for v := 1; v <= maxDeltaChain+2; v++ {
+		createTestFile(t, "book.md", longText(v))
+		if err := updateGitnot(); err != nil {
+			t.Fatalf("updateGitnot failed: %v", err)
+		}
+	}
+
+	manifests, _ := loadManifests()
+	deltas := 0
+	for _, m := range manifests {
+		h := m.Files["book.md"]
+		if _, err := os.Stat(deltaPath(h)); err == nil {
+			deltas++
+		}
+		if objectDepth(h) > maxDeltaChain {
+			t.Errorf("v%.1f exceeds the maximum delta chain", m.Version)
+		}
+		b, err := contentAt("book.md", m)
+		if err != nil || contentHash(b) != h {
+			t.Errorf("v%.1f not readable: %v", m.Version, err)
+		}
+	}
+	if deltas < maxDeltaChain {
+		t.Errorf("Expected most versions stored as deltas, got %d", deltas)
+	}
+
+	if _, _, err := repackObjects(true); err != nil {
+		t.Fatalf("repack --full failed: %v", err)
+	}
+	for _, m := range manifests {
+		if _, err := os.Stat(objectPath(m.Files["book.md"])); err != nil {
+			t.Errorf("v%.1f not expanded by repack --full", m.Version)
+		}
+	}
+
+	d, f, err := repackObjects(false)
+	if err != nil {
+		t.Fatalf("repack failed: %v", err)
+	}
+	if d < maxDeltaChain || f < 2 {
+		t.Errorf("Expected deltas with periodic full copies, got %d deltas, %d full", d, f)
+	}
+	for _, m := range manifests {
+		if b, err := contentAt("book.md", m); err != nil || contentHash(b) != m.Files["book.md"] {
+			t.Errorf("v%.1f not readable after repack: %v", m.Version, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// --- Scan depth ---
+//
+// max_depth stops a scan from descending more than that many folders below
+// the project root, so a deeply nested generated tree can't blow up a scan.
+// depth_overrides sets a different limit below particular folders, counted
+// from that folder (0 keeps only its own files). Folders a limit prunes are
+// reported by status and update rather than dropped silently.
+
+func dirDepth(rel string) int {
+	rel = strings.Trim(filepath.ToSlash(rel), "/")
+	if rel == "" || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, "/") + 1
+}
+
+// pruneDir reports whether the folder rel (relative to the scan root) lies
+// beyond its depth limit. The deepest matching override wins.
+func (f scanFilter) pruneDir(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	base, limit, found := "", f.MaxDepth, false
+	for b, n := range f.Depths {
+		b = strings.Trim(filepath.ToSlash(b), "/")
+		if (rel == b || strings.HasPrefix(rel, b+"/")) && (!found || len(b) > len(base)) {
+			base, limit, found = b, n, true
+		}
+	}
+	if !found && limit <= 0 {
+		return false
+	}
+	return dirDepth(rel)-dirDepth(base) > limit
+}
+
+func warnPruned(pruned []string) {
+	if len(pruned) == 0 {
+		return
+	}
+	shown := make([]string, 0, 3)
+	for _, p := range preview(pruned, 3) {
+		shown = append(shown, filepath.ToSlash(p)+"/")
+	}
+	more := ""
+	if len(pruned) > 3 {
+		more = fmt.Sprintf(" and %d more", len(pruned)-3)
+	}
+	fmt.Printf("⚠️  Depth limit skipped %d folders: %s%s (see max_depth)\n", len(pruned), strings.Join(shown, ", "), more)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDepthLimits(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "top.md", "x")
+	createTestFile(t, "a/one.md", "x")
+	createTestFile(t, "a/b/two.md", "x")
+	createTestFile(t, "a/b/c/three.md", "x")
+	createTestFile(t, "gen/x/y/deep.md", "x")
+
+	cfg := defaultConfig
+	cfg.MaxDepth = 2
+	cfg.DepthOverrides = map[string]int{"gen": 0, "a/b": 5}
+	files, pruned, err := scanTree(".", cfg.scanFilters())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join("a", "b", "c", "three.md"),
+		filepath.Join("a", "b", "two.md"),
+		filepath.Join("a", "one.md"),
+		"top.md",
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("Expected %v, got %v", want, files)
+	}
+	if !reflect.DeepEqual(pruned, []string{filepath.Join("gen", "x")}) {
+		t.Errorf("Unexpected pruned folders %v", pruned)
+	}
+
+	cfg.DepthOverrides = nil
+	cfg.MaxDepth = 1
+	_, pruned, _ = scanTree(".", cfg.scanFilters())
+	if !reflect.DeepEqual(pruned, []string{filepath.Join("a", "b"), filepath.Join("gen", "x")}) {
+		t.Errorf("Unexpected pruned folders %v", pruned)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// --- devgen ---
+//
+// `gitnot devgen` is a hidden development command that writes a synthetic
+// project for performance work: nested folders of prose-like markdown and
+// text files around a target average size. With --edit it instead applies
+// a round of realistic edits to such a tree (rewrites, appended paragraphs,
+// new, renamed and deleted files). The same --seed always produces the same
+// tree and edits, so runs can be compared across releases.
+
+var devgenWords = strings.Fields(`the a of and to in is was that it he she they
+chapter draft scene morning river letter window quiet house garden storm city
+remember walked said looked because before after never always again almost
+light dark old new small long last first other every through between under`)
+
+// parseSize reads sizes like 512, 4k, 2m.
+func parseSize(s string) (int64, error) {
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(strings.ToLower(s), "k"):
+		mult, s = 1<<10, s[:len(s)-1]
+	case strings.HasSuffix(strings.ToLower(s), "m"):
+		mult, s = 1<<20, s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 512, 4k, 2m)", s)
+	}
+	return n * mult, nil
+}
+
+func devgenParagraph(rng *rand.Rand) string {
+	n := 20 + rng.Intn(80)
+	words := make([]string, n)
+	for i := range words {
+		words[i] = devgenWords[rng.Intn(len(devgenWords))]
+	}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ") + ".\n"
+}
+
+// devgenText returns roughly size bytes of paragraphs.
+func devgenText(rng *rand.Rand, size int64) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", devgenParagraph(rng)[:20])
+	for int64(b.Len()) < size {
+		b.WriteString(devgenParagraph(rng))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// devgenPath spreads files over nested folders, a few levels deep.
+func devgenPath(rng *rand.Rand, i int) string {
+	depth := rng.Intn(4)
+	parts := make([]string, 0, depth+1)
+	for d := 0; d < depth; d++ {
+		parts = append(parts, fmt.Sprintf("part%02d", rng.Intn(8)))
+	}
+	ext := ".md"
+	if rng.Intn(5) == 0 {
+		ext = ".txt"
+	}
+	parts = append(parts, fmt.Sprintf("file%06d%s", i, ext))
+	return filepath.Join(parts...)
+}
+
+// devgenTree writes n files averaging avg bytes (sizes vary from a tenth to
+// twice the average) under out.
+func devgenTree(out string, n int, avg int64, seed int64) error {
+	rng := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		p := filepath.Join(out, devgenPath(rng, i))
+		size := avg/10 + rng.Int63n(max(avg*19/10, 1))
+		if err := safeMkdirAllForFile(p); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, []byte(devgenText(rng, size)), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// devgenEdit changes about pct percent of the files under out the way a
+// writer would, and returns how many files it touched.
+func devgenEdit(out string, pct int, seed int64) (int, error) {
+	var files []string
+	err := filepath.WalkDir(out, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if isUnderGitnot(filepath.ToSlash(mustRel(out, p))) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		files = append(files, p)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(files)
+	rng := rand.New(rand.NewSource(seed))
+	touched := 0
+	for i, p := range files {
+		if rng.Intn(100) >= pct {
+			continue
+		}
+		touched++
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return touched, err
+		}
+		paras := strings.Split(string(b), "\n\n")
+		switch r := rng.Intn(10); {
+		case r < 5: // rewrite a paragraph
+			paras[rng.Intn(len(paras))] = strings.TrimSuffix(devgenParagraph(rng), "\n")
+			err = os.WriteFile(p, []byte(strings.Join(paras, "\n\n")), 0o644)
+		case r < 8: // keep writing
+			err = os.WriteFile(p, append(b, []byte("\n"+devgenParagraph(rng))...), 0o644)
+		case r < 9: // rename
+			err = os.Rename(p, strings.TrimSuffix(p, filepath.Ext(p))+"-renamed"+filepath.Ext(p))
+		default: // delete, and start something new
+			if err = os.Remove(p); err == nil {
+				np := filepath.Join(out, devgenPath(rng, len(files)+i))
+				if err = safeMkdirAllForFile(np); err == nil {
+					err = os.WriteFile(np, []byte(devgenText(rng, int64(len(b)))), 0o644)
+				}
+			}
+		}
+		if err != nil {
+			return touched, err
+		}
+	}
+	return touched, nil
+}
+
+func mustRel(base, p string) string {
+	rel, err := filepath.Rel(base, p)
+	if err != nil {
+		return p
+	}
+	return rel
+}
+
+func runDevgen(args []string) error {
+	fs := flag.NewFlagSet("devgen", flag.ExitOnError)
+	out := fs.String("out", "", "folder to generate into (required)")
+	files := fs.Int("files", 1000, "number of files to generate")
+	avgSize := fs.String("avg-size", "4k", "average file size")
+	seed := fs.Int64("seed", 1, "random seed; the same seed gives the same tree")
+	edit := fs.Int("edit", 0, "instead of generating, edit this percentage of the files")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("usage: gitnot devgen --out <dir> [--files n] [--avg-size 4k] [--seed s] [--edit pct]")
+	}
+	if *edit > 0 {
+		n, err := devgenEdit(*out, min(*edit, 100), *seed)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✏️  Edited %d files in %s\n", n, *out)
+		return nil
+	}
+	avg, err := parseSize(*avgSize)
+	if err != nil {
+		return err
+	}
+	if entries, err := os.ReadDir(*out); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s is not empty; generate into a new folder", *out)
+	}
+	if err := devgenTree(*out, *files, avg, *seed); err != nil {
+		return err
+	}
+	fmt.Printf("🧪 Generated %d files (about %s each) in %s\n", *files, formatBytes(avg), *out)
+	return nil
+}
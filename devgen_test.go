@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDevgenIsReproducible(t *testing.T) {
+	setupTestDir(t)
+
+	for _, out := range []string{"one", "two"} {
+		if err := devgenTree(out, 30, 2048, 42); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list := func(root string) map[string]string {
+		m := map[string]string{}
+		filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				b, _ := os.ReadFile(p)
+				m[mustRel(root, p)] = string(b)
+			}
+			return nil
+		})
+		return m
+	}
+	one, two := list("one"), list("two")
+	if len(one) != 30 {
+		t.Fatalf("Expected 30 files, got %d", len(one))
+	}
+	for p, text := range one {
+		if two[p] != text {
+			t.Fatalf("Same seed should give the same tree; %s differs", p)
+		}
+	}
+
+	n, err := devgenEdit("one", 50, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Error("Expected some files to be edited")
+	}
+	devgenEdit("two", 50, 7)
+	edited1, edited2 := list("one"), list("two")
+	if len(edited1) != len(edited2) {
+		t.Errorf("Same edit seed should give the same result")
+	}
+	for p, text := range edited1 {
+		if edited2[p] != text {
+			t.Fatalf("Same edit seed should give the same result; %s differs", p)
+		}
+	}
+
+	if s, err := parseSize("4k"); err != nil || s != 4096 {
+		t.Errorf("parseSize(4k) = %d, %v", s, err)
+	}
+	if _, err := parseSize("lots"); err == nil {
+		t.Error("parseSize should reject junk")
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// --- Skipping unchanged folders ---
+//
+// With "skip_unchanged_dirs": true, update and status remember what each
+// folder held (.gitnot/dirs.json) along with the folder's modification time.
+// Adding, removing or renaming an entry changes a folder's mtime, so a folder
+// whose mtime is unchanged is not listed again: its tracked files come from
+// the cache and only its subfolders are visited. Files are then answered
+// from the stat index as status does, so an unchanged part of the tree costs
+// a stat per folder and file instead of listing every folder and reading
+// every file. Editing a file in place doesn't touch its folder, which is why
+// files are still checked one by one.
+//
+// Folder mtimes can't be trusted everywhere (some network shares and sync
+// drives never update them), so whether they work is probed once in the
+// store, and where they don't, every folder is listed as before. A folder
+// changed within the racy window of the last scan is listed again, changing
+// the tracking rules drops the cache, and once a day, or with --full, the
+// scan lists every folder and hashes every file from scratch.
+
+// fullScanInterval is how long cached listings are trusted before a scan
+// starts over.
+const fullScanInterval = 24 * time.Hour
+
+// dirListing is what a scan found in one folder, by name.
+type dirListing struct {
+	ModTime     int64    `json:"mtime"`                  // unix nanoseconds
+	Files       []string `json:"files,omitempty"`        // tracked
+	Sized       []string `json:"sized,omitempty"`        // tracked or not by a size limit, checked every scan
+	Dirs        []string `json:"dirs,omitempty"`         // subfolders to visit
+	Pruned      []string `json:"pruned,omitempty"`       // subfolders below the depth limit
+	HiddenDirs  []string `json:"hidden_dirs,omitempty"`  // skipped by hidden_dirs
+	HiddenFiles []string `json:"hidden_files,omitempty"` // left out by hidden_files
+}
+
+// Kinds of entries noted in a listing.
+const (
+	listedFile = iota
+	listedSized
+	listedDir
+	listedPruned
+	listedHiddenDir
+	listedHiddenFile
+)
+
+type dirCache struct {
+	Filters  string                `json:"filters"`  // fingerprint of the tracking rules the listings follow
+	Reliable bool                  `json:"reliable"` // folder mtimes change with their entries here
+	Full     time.Time             `json:"full"`     // when every folder was last listed
+	Scanned  time.Time             `json:"scanned"`  // when these listings were made
+	Dirs     map[string]dirListing `json:"dirs"`     // slash-separated folder → listing
+
+	Fresh bool `json:"-"` // this scan starts over: list every folder, hash every file
+	Hits  int  `json:"-"` // folders not listed again
+
+	started time.Time
+	next    map[string]*dirListing // listings made or kept by this scan
+	kept    map[string]bool        // folders whose listing came from the cache
+}
+
+// loadDirCache reads the listings made with filter's rules. Starting over,
+// because of full, a day's age or new rules, probes again whether folder
+// mtimes can be relied on.
+func loadDirCache(filter scanFilter, full bool) *dirCache {
+	c := &dirCache{}
+	fp := contentHash(fmt.Appendf(nil, "%#v", filter))
+	if err := loadJSON(dirsFile, c); err != nil || c.Filters != fp {
+		c = &dirCache{Filters: fp}
+	}
+	c.started = time.Now()
+	if full || c.started.Sub(c.Full) > fullScanInterval {
+		c.Dirs, c.Full, c.Fresh = nil, c.started, true
+		c.Reliable = dirMtimesReliable()
+	}
+	c.next, c.kept = map[string]*dirListing{}, map[string]bool{}
+	return c
+}
+
+// lookup returns the cached listing of the folder at p when the folder has
+// not changed since it was made.
+func (c *dirCache) lookup(p string, d fs.DirEntry) (dirListing, bool) {
+	if c == nil || !c.Reliable {
+		return dirListing{}, false
+	}
+	fi, err := d.Info()
+	if err != nil {
+		return dirListing{}, false
+	}
+	mtime := fi.ModTime()
+	key := filepath.ToSlash(p)
+	l, ok := c.Dirs[key]
+	// a folder changed just before the last scan may have changed again in the same tick
+	if !ok || l.ModTime != mtime.UnixNano() || !mtime.Before(c.Scanned.Add(-racyWindow)) {
+		c.next[key] = &dirListing{ModTime: mtime.UnixNano()}
+		return dirListing{}, false
+	}
+	c.next[key], c.kept[key] = &l, true
+	c.Hits++
+	return l, true
+}
+
+// note records the entry at p in its folder's new listing, unless that
+// folder's listing came from the cache.
+func (c *dirCache) note(p string, kind int) {
+	if c == nil {
+		return
+	}
+	dir := filepath.ToSlash(filepath.Dir(p))
+	l, ok := c.next[dir]
+	if !ok || c.kept[dir] {
+		return
+	}
+	name := filepath.Base(p)
+	switch kind {
+	case listedFile:
+		l.Files = append(l.Files, name)
+	case listedSized:
+		l.Sized = append(l.Sized, name)
+	case listedDir:
+		l.Dirs = append(l.Dirs, name)
+	case listedPruned:
+		l.Pruned = append(l.Pruned, name)
+	case listedHiddenDir:
+		l.HiddenDirs = append(l.HiddenDirs, name)
+	case listedHiddenFile:
+		l.HiddenFiles = append(l.HiddenFiles, name)
+	}
+}
+
+// forget drops the listing of a folder that could not be read completely.
+func (c *dirCache) forget(p string) {
+	if c != nil {
+		delete(c.next, filepath.ToSlash(p))
+	}
+}
+
+// save keeps the listings this scan made for the next one.
+func (c *dirCache) save() error {
+	if c == nil {
+		return nil
+	}
+	c.Dirs = map[string]dirListing{}
+	if c.Reliable {
+		for dir, l := range c.next {
+			c.Dirs[dir] = *l
+		}
+	}
+	c.Scanned = c.started
+	return saveJSON(dirsFile, c)
+}
+
+// dirMtimesReliable reports whether adding a file to a folder in the store
+// changes the folder's modification time.
+func dirMtimesReliable() bool {
+	dir, err := os.MkdirTemp(gitnotDir, "probe-")
+	if err != nil {
+		return false
+	}
+	defer os.RemoveAll(dir)
+	// start from a time far from now, so coarse timestamps still move
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(dir, old, old); err != nil {
+		return false
+	}
+	if err := os.WriteFile(filepath.Join(dir, "probe"), nil, 0o644); err != nil {
+		return false
+	}
+	fi, err := os.Stat(dir)
+	return err == nil && !fi.ModTime().Equal(old)
+}
+
+// joinAll puts dir in front of each name.
+func joinAll(dir string, names []string) []string {
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = filepath.Join(dir, n)
+	}
+	return out
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ageFolders makes the folders' mtimes an hour old, outside the racy window.
+func ageFolders(t *testing.T, dirs ...string) {
+	t.Helper()
+	old := time.Now().Add(-time.Hour)
+	for _, d := range dirs {
+		if err := os.Chtimes(d, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDirCacheSkipsUnchangedFolders(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.md", "A\n")
+	createTestFile(t, "notes/b.md", "B\n")
+	createTestFile(t, "notes/deep/c.md", "C\n")
+	createTestFile(t, "notes/skip.tmp", "scratch\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	ageFolders(t, ".", "notes", filepath.Join("notes", "deep"))
+	filter := loadConfig().scanFilters()
+
+	scan := func() (scanReport, *dirCache) {
+		t.Helper()
+		c := loadDirCache(filter, false)
+		rep, err := scanTreeCached(".", filter, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.save(); err != nil {
+			t.Fatal(err)
+		}
+		return rep, c
+	}
+	first, c := scan()
+	if !c.Reliable {
+		t.Skip("folder mtimes are not reliable here")
+	}
+	if !c.Fresh || c.Hits != 0 {
+		t.Errorf("Expected the first scan to list everything, got fresh=%v hits=%d", c.Fresh, c.Hits)
+	}
+	second, c := scan()
+	if c.Fresh || c.Hits != 3 {
+		t.Errorf("Expected 3 folders from the cache, got fresh=%v hits=%d", c.Fresh, c.Hits)
+	}
+	if !slices.Equal(first.Files, second.Files) {
+		t.Errorf("Cached scan found %v, want %v", second.Files, first.Files)
+	}
+
+	// a new file changes its folder, which is listed again
+	createTestFile(t, "notes/deep/d.md", "D\n")
+	third, c := scan()
+	if c.Hits != 2 || !slices.Contains(third.Files, filepath.Join("notes", "deep", "d.md")) {
+		t.Errorf("Expected notes/deep to be listed again, got hits=%d files=%v", c.Hits, third.Files)
+	}
+	// and stays listed again while it is within the racy window of that scan
+	if _, c := scan(); c.Hits != 2 {
+		t.Errorf("Expected a recently changed folder to be listed again, got hits=%d", c.Hits)
+	}
+
+	// other rules mean other listings
+	c = loadDirCache(scanFilter{MaxDepth: 1}, false)
+	if !c.Fresh || len(c.Dirs) != 0 {
+		t.Error("Expected a change of rules to drop the cached listings")
+	}
+	if c := loadDirCache(filter, true); !c.Fresh {
+		t.Error("Expected --full to start over")
+	}
+}
+
+func TestDirCacheRechecksSizeLimits(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "notes/small.md", "x\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	filter := scanFilter{Rules: []Rule{{Match: "*.md", MaxSizeKB: 1}}}
+	ageFolders(t, ".", "notes")
+	c := loadDirCache(filter, false)
+	if _, err := scanTreeCached(".", filter, c); err != nil {
+		t.Fatal(err)
+	}
+	c.save()
+	if !c.Reliable {
+		t.Skip("folder mtimes are not reliable here")
+	}
+
+	// growing past the limit doesn't touch the folder
+	createTestFile(t, "notes/small.md", strings.Repeat("x", 2048))
+	ageFolders(t, "notes")
+	c = loadDirCache(filter, false)
+	rep, err := scanTreeCached(".", filter, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Hits == 0 || len(rep.Files) != 0 {
+		t.Errorf("Expected the grown file to be left out, got hits=%d files=%v", c.Hits, rep.Files)
+	}
+}
+
+func TestUpdateWithSkipUnchangedDirs(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "ch1.md", "One\n")
+	createTestFile(t, "notes/idea.md", "Idea\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.SkipUnchangedDirs = true
+	saveJSON(configFile, cfg)
+	ageFolders(t, ".", "notes")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if _, err := os.Stat(dirsFile); err != nil {
+		t.Fatalf("Expected the folder listings to be saved: %v", err)
+	}
+
+	// an edit in place leaves the folder alone but is still recorded
+	createTestFile(t, "notes/idea.md", "A better idea\n")
+	ageFolders(t, "notes")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.1 {
+		t.Errorf("Expected v0.1, got v%.1f", v)
+	}
+	if h := loadCommittedHashes()[filepath.Join("notes", "idea.md")]; h != hashFile(filepath.Join("notes", "idea.md")) {
+		t.Error("Expected the edit to be recorded")
+	}
+}
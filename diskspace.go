@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// --- Disk space check ---
+//
+// An update rebuilds the snapshot beside the old one and stores the new and
+// changed files as objects, so it needs about the size of every tracked file
+// plus the changed ones. It checks for that much room (plus a margin) before
+// writing anything rather than failing halfway through.
+
+const diskSpaceMargin = 16 << 20
+
+// freeSpaceFunc is swapped out in tests.
+var freeSpaceFunc = freeSpace
+
+func fileSizes(paths []string) int64 {
+	var total int64
+	for _, p := range paths {
+		if fi, err := os.Stat(longPath(p)); err == nil {
+			total += fi.Size()
+		}
+	}
+	return total
+}
+
+// requiredSpace estimates what an update writes: a new snapshot of files
+// and an object for each new or changed file (compression and deltas aside).
+func requiredSpace(files, newFiles, changedFiles []string) int64 {
+	return fileSizes(files) + fileSizes(newFiles) + fileSizes(changedFiles)
+}
+
+func checkDiskSpace(need int64) error {
+	free, ok := freeSpaceFunc(gitnotDir)
+	if !ok || uint64(need)+diskSpaceMargin <= free {
+		return nil
+	}
+	return fmt.Errorf("not enough disk space: this update needs about %s but only %s is free; nothing was written (see 'gitnot du' and 'gitnot gc' to reclaim space)",
+		formatBytes(need+diskSpaceMargin), formatBytes(int64(free)))
+}
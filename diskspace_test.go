@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestUpdateAbortsWithoutDiskSpace(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "first")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "a.md", "second")
+
+	saved := freeSpaceFunc
+	t.Cleanup(func() { freeSpaceFunc = saved })
+	freeSpaceFunc = func(string) (uint64, bool) { return 1 << 20, true }
+
+	err := updateGitnot()
+	if err == nil || !strings.Contains(err.Error(), "not enough disk space") {
+		t.Fatalf("Expected a disk space error, got %v", err)
+	}
+	if v, _ := readVersion(); v != 0 {
+		t.Errorf("Version should not change, got %.1f", v)
+	}
+	if _, err := os.Stat(snapshotTmpDir); !os.IsNotExist(err) {
+		t.Error("No temporary snapshot should be left behind")
+	}
+
+	freeSpaceFunc = func(string) (uint64, bool) { return 0, false } // unknown: don't block
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+}
+
+func TestLinksNotRewrittenWithoutDiskSpace(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, ".obsidian/app.json", "{}")
+	createTestFile(t, "Draft.md", "A note")
+	createTestFile(t, "index.md", "Start at [[Draft]]")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.Vault.RewriteLinks = true
+	saveJSON(configFile, cfg)
+	os.Rename("Draft.md", "Chapter One.md")
+
+	saved := freeSpaceFunc
+	t.Cleanup(func() { freeSpaceFunc = saved })
+	freeSpaceFunc = func(string) (uint64, bool) { return 1 << 20, true }
+	if err := updateGitnot(); err == nil || !strings.Contains(err.Error(), "not enough disk space") {
+		t.Fatalf("Expected a disk space error, got %v", err)
+	}
+	if b, _ := os.ReadFile("index.md"); string(b) != "Start at [[Draft]]" {
+		t.Errorf("Links should not be rewritten when the update can't be recorded, got %q", b)
+	}
+}
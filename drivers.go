@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Diff drivers ---
+//
+// A diff driver writes the changelog entry for a file type whose plain line
+// diff says little (LaTeX, canvas JSON, ...). Files without a driver, or
+// whose driver declines, get the usual line diff.
+
+// diffDriver renders a changelog entry from the old and new content; ok is
+// false when the line diff should be used instead.
+type diffDriver func(oldB, newB []byte) (md string, ok bool)
+
+// diffDrivers maps lower-case extensions to their driver.
+var diffDrivers = map[string]diffDriver{
+	".tex": latexDiff,
+	".cls": latexDiff,
+	".sty": latexDiff,
+	".bib": bibDiff,
+
+	".fountain": fountainDiff,
+}
+
+// namedDrivers are the drivers a tracking rule can pick with "diff".
+var namedDrivers = map[string]diffDriver{
+	"latex":    latexDiff,
+	"bibtex":   bibDiff,
+	"fountain": fountainDiff,
+	"json":     structuralDiff,
+}
+
+// driverNames lists the built-in and registered drivers; see plugins.go for
+// gitnot-<name> programs.
+func driverNames() []string {
+	names := append(sortedKeys(namedDrivers), sortedKeys(registeredDrivers)...)
+	return append(names, diffLines, diffNone)
+}
+
+// driverFor returns the driver for rel, if any. Structured JSON drivers only
+// apply inside a vault, where canvas and plugin files are common.
+func driverFor(rel string, vault bool) diffDriver {
+	if vault && structuredFile(rel) {
+		return structuralDiff
+	}
+	return diffDrivers[strings.ToLower(filepath.Ext(rel))]
+}
+
+// driverDiff runs rel's driver over the two files. A rule's diff setting
+// picks the driver by name instead, which may also be a registered driver or
+// a gitnot-<name> plugin; "lines" forces the line diff.
+func driverDiff(rel, oldPath, newPath string, vault bool, name string) (string, bool) {
+	var drv DiffDriver
+	if d := driverFor(rel, vault); d != nil {
+		drv = d
+	}
+	if name != "" {
+		drv, _ = lookupDiffDriver(name)
+	}
+	if drv == nil {
+		return "", false
+	}
+	oldB, err := os.ReadFile(longPath(oldPath))
+	if err != nil {
+		return "", false
+	}
+	newB, err := os.ReadFile(longPath(newPath))
+	if err != nil {
+		return "", false
+	}
+	return drv.Diff(oldB, newB)
+}
+
+// binaryEntry describes a change to a file tracked in binary mode.
+func binaryEntry(oldPath, newPath string) string {
+	size := func(p string) string {
+		if fi, err := os.Stat(longPath(p)); err == nil {
+			return formatBytes(fi.Size())
+		}
+		return "?"
+	}
+	return fmt.Sprintf("📦 Binary file changed (%s → %s)\n", size(oldPath), size(newPath))
+}
+
+// summaryEntry is the changelog entry of a file whose rule asks for
+// "changelog": "summary": the counts without the lines themselves.
+func summaryEntry(fc FileChange) string {
+	return fmt.Sprintf("📄 Changed (+%d/-%d lines)\n", fc.LinesAdded, fc.LinesRemoved)
+}
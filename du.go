@@ -0,0 +1,242 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// --- Disk usage ---
+//
+// `gitnot du` breaks the store down by area and by tracked file, and points
+// at what gc, repack and pack could reclaim.
+
+// duArea is one part of the store.
+type duArea struct {
+	Name  string
+	Bytes int64
+	Files int
+}
+
+var duAreaOrder = []string{"snapshot", "objects", "packs", "manifests", "changelogs", "deleted", "backups", "overwritten", "metadata"}
+
+// storeUsage sums .gitnot by area. Anything outside the known folders
+// (version, hashes, index, HISTORY.md, leftovers) counts as metadata.
+func storeUsage() []duArea {
+	areas := map[string]*duArea{}
+	for _, n := range duAreaOrder {
+		areas[n] = &duArea{Name: n}
+	}
+	_ = walkStore(gitnotDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(gitnotDir, p)
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		area := "metadata"
+		if len(parts) > 1 {
+			switch parts[0] {
+			case "snapshot", "manifests", "changelogs", "deleted", "backups", "overwritten":
+				area = parts[0]
+			case "objects":
+				area = "objects"
+				if parts[1] == "pack" {
+					area = "packs"
+				}
+			}
+		}
+		areas[area].Bytes += fi.Size()
+		areas[area].Files++
+		return nil
+	})
+	out := make([]duArea, 0, len(duAreaOrder))
+	for _, n := range duAreaOrder {
+		out = append(out, *areas[n])
+	}
+	return out
+}
+
+// storedSize is what an object occupies in the store, in whichever form.
+func storedSize(hash string) int64 {
+	if fi, err := repo.FS.Stat(objectPath(hash)); err == nil {
+		return fi.Size()
+	}
+	if fi, err := os.Stat(deltaPath(hash)); err == nil {
+		return fi.Size()
+	}
+	if loc, ok := packIndex()[hash]; ok {
+		return loc.Size
+	}
+	return 0
+}
+
+// FileUsage is the history one tracked path costs.
+type FileUsage struct {
+	Path      string
+	Versions  int
+	Objects   int64
+	Changelog int64
+	Deleted   int64
+}
+
+func (u FileUsage) Total() int64 { return u.Objects + u.Changelog + u.Deleted }
+
+// fileUsage attributes stored versions, changelogs and deleted copies to
+// every path in the history, largest first. An object shared by several
+// paths counts towards each of them.
+func fileUsage(manifests []Manifest) []FileUsage {
+	seen := map[string]map[string]bool{}
+	for _, m := range manifests {
+		for rel, h := range m.Files {
+			if seen[rel] == nil {
+				seen[rel] = map[string]bool{}
+			}
+			seen[rel][h] = true
+		}
+	}
+	var out []FileUsage
+	for rel, hashes := range seen {
+		u := FileUsage{Path: rel, Versions: len(hashes)}
+		for h := range hashes {
+			u.Objects += storedSize(h)
+		}
+		if fi, err := repo.FS.Stat(filepath.Join(changelogDir, rel+".log")); err == nil {
+			u.Changelog = fi.Size()
+		}
+		if fi, err := repo.FS.Stat(filepath.Join(deletedDir, rel)); err == nil {
+			u.Deleted = fi.Size()
+		}
+		out = append(out, u)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total() != out[j].Total() {
+			return out[i].Total() > out[j].Total()
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
+
+// duHints suggests commands that would reclaim space or files.
+func duHints(cfg Config, manifests []Manifest) []string {
+	var hints []string
+	if entries, err := listTrash(); err == nil && len(entries) > 0 {
+		if cfg.DeletedRetention == (Retention{}) {
+			var total int64
+			for _, e := range entries {
+				total += e.Size
+			}
+			hints = append(hints, fmt.Sprintf("deleted/ keeps %d files (%s) forever; set deleted_retention and run 'gitnot gc'", len(entries), formatBytes(total)))
+		} else if expired := expiredTrash(entries, cfg.DeletedRetention, repo.Now()); len(expired) > 0 {
+			var total int64
+			for _, e := range expired {
+				total += e.Size
+			}
+			hints = append(hints, fmt.Sprintf("'gitnot gc' would free %s (%d deleted files past deleted_retention)", formatBytes(total), len(expired)))
+		}
+	}
+
+	// full copies of versions that have a predecessor could be deltas
+	fullCopies, fullBytes := 0, int64(0)
+	seen := map[string]bool{}
+	last := map[string]string{}
+	for _, m := range manifests {
+		for rel, h := range m.Files {
+			prev := last[rel]
+			last[rel] = h
+			if prev == "" || prev == h || seen[h] {
+				continue
+			}
+			seen[h] = true
+			if fi, err := repo.FS.Stat(objectPath(h)); err == nil {
+				fullCopies++
+				fullBytes += fi.Size()
+			} else if loc, ok := packIndex()[h]; ok && !loc.Delta {
+				fullCopies++
+				fullBytes += loc.Size
+			}
+		}
+	}
+	if fullCopies > 0 {
+		hints = append(hints, fmt.Sprintf("%d later versions (%s) are stored as full copies; 'gitnot repack' stores them as deltas", fullCopies, formatBytes(fullBytes)))
+	}
+
+	if loose, err := listLooseObjects(); err == nil {
+		small := 0
+		for _, o := range loose {
+			if o.Size <= packObjectMax {
+				small++
+			}
+		}
+		if small >= 100 {
+			hints = append(hints, fmt.Sprintf("%d small loose objects; 'gitnot pack' combines them into pack files", small))
+		}
+	}
+	if _, err := repo.FS.Stat(snapshotOldDir); err == nil && !lockHolderAlive() {
+		size, _ := dirSize(snapshotOldDir)
+		hints = append(hints, fmt.Sprintf("a leftover snapshot.old holds %s; the next gitnot run cleans it up", formatBytes(size)))
+	}
+	return hints
+}
+
+func runDu(args []string) error {
+	fs := flag.NewFlagSet("du", flag.ExitOnError)
+	top := fs.Int("top", 10, "number of tracked files to list (0 lists all)")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	manifests, err := loadManifests()
+	if err != nil {
+		return err
+	}
+
+	areas := storeUsage()
+	var total int64
+	files := 0
+	for _, a := range areas {
+		total += a.Bytes
+		files += a.Files
+	}
+	fmt.Printf("💾 %s uses %s in %d files\n", gitnotDir, formatBytes(total), files)
+	for _, a := range areas {
+		if a.Files == 0 {
+			continue
+		}
+		fmt.Printf("  %-12s %10s  %6d files\n", a.Name, formatBytes(a.Bytes), a.Files)
+	}
+
+	usage := fileUsage(manifests)
+	if len(usage) > 0 {
+		n := len(usage)
+		if *top > 0 && *top < n {
+			n = *top
+		}
+		fmt.Printf("📄 Largest histories (%d of %d files):\n", n, len(usage))
+		for _, u := range usage[:n] {
+			fmt.Printf("  %-40s %10s  %3d versions  (stored %s, changelog %s", u.Path, formatBytes(u.Total()), u.Versions, formatBytes(u.Objects), formatBytes(u.Changelog))
+			if u.Deleted > 0 {
+				fmt.Printf(", deleted copy %s", formatBytes(u.Deleted))
+			}
+			fmt.Println(")")
+		}
+	}
+
+	if hints := duHints(loadConfig(), manifests); len(hints) > 0 {
+		fmt.Println("💡 Could reclaim:")
+		for _, h := range hints {
+			fmt.Printf("  • %s\n", h)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStoreUsage(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "big.md", longText(-1))
+	createTestFile(t, "small.md", "tiny\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "big.md", longText(1))
+	os.Remove("small.md")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	byName := map[string]duArea{}
+	for _, a := range storeUsage() {
+		byName[a.Name] = a
+	}
+	for _, area := range []string{"snapshot", "objects", "manifests", "changelogs", "deleted", "metadata"} {
+		if byName[area].Files == 0 {
+			t.Errorf("Expected files in %s, got %+v", area, byName[area])
+		}
+	}
+
+	manifests, _ := loadManifests()
+	usage := fileUsage(manifests)
+	if len(usage) != 2 || usage[0].Path != "big.md" || usage[0].Versions != 2 {
+		t.Fatalf("Unexpected file usage: %+v", usage)
+	}
+	if usage[1].Deleted == 0 {
+		t.Errorf("Deleted copy of small.md not counted: %+v", usage[1])
+	}
+
+	hints := strings.Join(duHints(loadConfig(), manifests), "\n")
+	if !strings.Contains(hints, "deleted_retention") || !strings.Contains(hints, "repack") {
+		t.Errorf("Expected gc and repack hints, got:\n%s", hints)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// disableEcho turns off terminal echo via stty and returns a function that
+// turns it back on. It does nothing when stdin is not a terminal.
+func disableEcho() func() {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return func() {}
+	}
+	cmd := exec.Command("stty", "-echo")
+	cmd.Stdin = os.Stdin
+	if err := cmd.Run(); err != nil {
+		return func() {}
+	}
+	return func() {
+		cmd := exec.Command("stty", "echo")
+		cmd.Stdin = os.Stdin
+		_ = cmd.Run()
+	}
+}
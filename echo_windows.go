@@ -0,0 +1,24 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+const enableEchoInput = 0x0004
+
+var procSetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// disableEcho clears ENABLE_ECHO_INPUT on the console and returns a function
+// restoring the previous mode.
+func disableEcho() func() {
+	h, err := syscall.GetStdHandle(syscall.STD_INPUT_HANDLE)
+	if err != nil {
+		return func() {}
+	}
+	var mode uint32
+	if err := syscall.GetConsoleMode(h, &mode); err != nil {
+		return func() {}
+	}
+	procSetConsoleMode.Call(uintptr(h), uintptr(mode&^enableEchoInput))
+	return func() { procSetConsoleMode.Call(uintptr(h), uintptr(mode)) }
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"os/user"
+	"runtime"
+	"strings"
+)
+
+// --- Environment ---
+//
+// With record_environment set, each manifest notes where the version was
+// made, which helps when one folder is synced across machines. Only the
+// listed fields are captured, so nothing identifying is stored by default.
+
+// Environment is the machine and tool a version was recorded with.
+type Environment struct {
+	Host string `json:"host,omitempty"`
+	OS   string `json:"os,omitempty"`
+	User string `json:"user,omitempty"`
+	Tool string `json:"tool,omitempty"` // gitnot version
+}
+
+const (
+	envHost = "host"
+	envOS   = "os"
+	envUser = "user"
+	envTool = "tool"
+)
+
+// captureEnvironment fills in the requested fields; it returns nil when
+// nothing is requested.
+func captureEnvironment(fields []string) *Environment {
+	var env Environment
+	for _, f := range fields {
+		switch strings.ToLower(f) {
+		case envHost:
+			env.Host, _ = os.Hostname()
+		case envOS:
+			env.OS = runtime.GOOS + "/" + runtime.GOARCH
+		case envUser:
+			env.User = currentUserName()
+		case envTool:
+			env.Tool = currentBuildInfo().Version
+		}
+	}
+	if env == (Environment{}) {
+		return nil
+	}
+	return &env
+}
+
+func currentUserName() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	for _, k := range []string{"USER", "USERNAME"} {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// String renders the captured fields for log output, e.g.
+// "alice@laptop, darwin/arm64, gitnot 1.4.0".
+func (e Environment) String() string {
+	var parts []string
+	switch {
+	case e.User != "" && e.Host != "":
+		parts = append(parts, e.User+"@"+e.Host)
+	case e.User != "":
+		parts = append(parts, e.User)
+	case e.Host != "":
+		parts = append(parts, e.Host)
+	}
+	if e.OS != "" {
+		parts = append(parts, e.OS)
+	}
+	if e.Tool != "" {
+		parts = append(parts, "gitnot "+e.Tool)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestCaptureEnvironment(t *testing.T) {
+	if captureEnvironment(nil) != nil {
+		t.Error("Nothing should be captured by default")
+	}
+	env := captureEnvironment([]string{"os", "tool"})
+	if env == nil || env.OS != runtime.GOOS+"/"+runtime.GOARCH || env.Tool == "" {
+		t.Fatalf("Unexpected environment: %+v", env)
+	}
+	if env.Host != "" || env.User != "" {
+		t.Errorf("Fields not asked for were captured: %+v", env)
+	}
+	if got := (Environment{User: "ana", Host: "laptop", OS: "linux/amd64"}).String(); got != "ana@laptop, linux/amd64" {
+		t.Errorf("Unexpected rendering: %q", got)
+	}
+}
+
+func TestEnvironmentInManifest(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if m, _ := loadManifest(0.0); m.Env != nil {
+		t.Errorf("Environment recorded without being configured: %+v", m.Env)
+	}
+	cfg := loadConfig()
+	cfg.RecordEnvironment = []string{"host", "os"}
+	saveJSON(configFile, cfg)
+	createTestFile(t, "a.md", "two")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if m, _ := loadManifest(0.1); m.Env == nil || m.Env.OS == "" {
+		t.Errorf("Expected environment in manifest, got %+v", m.Env)
+	}
+}
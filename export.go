@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// --- Export ---
+//
+// `gitnot export` materializes tracked files as of any version, either as a
+// directory tree or (--concat) as one document built from the markdown files
+// in narrative order.
+
+var markdownExts = []string{".md", ".markdown"}
+
+// ExportConfig shapes --concat output. Template is a text/template applied
+// to every section with .Path, .Title, .Content, .Index and .Version.
+type ExportConfig struct {
+	Separator string `json:"separator,omitempty"`
+	Template  string `json:"template,omitempty"`
+	Pandoc    string `json:"pandoc,omitempty"` // pandoc executable, default "pandoc" on PATH
+}
+
+// pandocFormats are the --format values handed to pandoc.
+var pandocFormats = map[string]bool{"epub": true, "pdf": true, "docx": true, "odt": true, "html": true}
+
+const defaultSeparator = "\n\n"
+
+// concatSection is the data available to an export template.
+type concatSection struct {
+	Path    string
+	Title   string
+	Content string
+	Index   int
+	Version string
+}
+
+// parseVersionArg accepts "1.2", "v1.2" or a version ID such as "3fa91c-12";
+// empty means the current version.
+func parseVersionArg(s string) (float64, error) {
+	if s == "" {
+		return readVersion()
+	}
+	v, err := strconv.ParseFloat(strings.TrimPrefix(s, "v"), 64)
+	if err != nil {
+		if v, ok := loadTags()[s]; ok {
+			return v, nil
+		}
+		if v, ok := versionByID(s); ok {
+			return v, nil
+		}
+		return 0, fmt.Errorf("invalid version %q", s)
+	}
+	return v, nil
+}
+
+// exportSelection lists the files of m to export, filtered by label and, for
+// concatenation, restricted to markdown (or to the order list when set).
+func exportSelection(m Manifest, cfg Config, label string, concat bool) []string {
+	var files []string
+	for rel := range m.Files {
+		files = append(files, rel)
+	}
+	if label != "" {
+		files = filterByLabel(files, loadLabels(), label)
+	}
+	if !concat {
+		return orderPaths(files, cfg.Order)
+	}
+	if len(cfg.Order) > 0 {
+		return selectOrdered(files, cfg.Order)
+	}
+	var md []string
+	for _, f := range files {
+		if hasAnySuffix(f, markdownExts) {
+			md = append(md, f)
+		}
+	}
+	return orderPaths(md, nil)
+}
+
+func concatDocument(m Manifest, files []string, ec ExportConfig) ([]byte, error) {
+	sep := ec.Separator
+	if sep == "" {
+		sep = defaultSeparator
+	}
+	var tmpl *template.Template
+	if ec.Template != "" {
+		var err error
+		if tmpl, err = template.New("section").Parse(ec.Template); err != nil {
+			return nil, fmt.Errorf("export template: %w", err)
+		}
+	}
+	var b strings.Builder
+	for i, rel := range files {
+		content, err := contentAt(rel, m)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		text := strings.TrimRight(string(content), "\n")
+		if tmpl == nil {
+			b.WriteString(text)
+			continue
+		}
+		base := filepath.Base(rel)
+		sec := concatSection{
+			Path: filepath.ToSlash(rel), Title: strings.TrimSuffix(base, filepath.Ext(base)),
+			Content: text, Index: i + 1, Version: fmt.Sprintf("%.1f", m.Version),
+		}
+		if err := tmpl.Execute(&b, sec); err != nil {
+			return nil, fmt.Errorf("export template: %w", err)
+		}
+	}
+	b.WriteString("\n")
+	return []byte(b.String()), nil
+}
+
+// runPandoc converts a markdown document with pandoc, stamping the source
+// version into the output metadata.
+func runPandoc(ec ExportConfig, doc []byte, format, out string, v float64) error {
+	bin := ec.Pandoc
+	if bin == "" {
+		bin = "pandoc"
+	}
+	args := []string{"-f", "markdown", "-o", out, "--metadata", fmt.Sprintf("gitnot-version=v%.1f", v)}
+	if format != "pdf" { // pandoc picks the PDF engine from the output name
+		args = append(args, "-t", format)
+	}
+	cmd := exec.Command(bin, args...)
+	cmd.Stdin = bytes.NewReader(doc)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("pandoc not found (install it or set export.pandoc in config.json)")
+		}
+		return fmt.Errorf("pandoc failed: %v\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func exportTree(m Manifest, files []string, outDir string) error {
+	vfs := newVersionFS(m)
+	for _, rel := range files {
+		content, err := fs.ReadFile(vfs, filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(outDir, rel)
+		if err := safeMkdirAllForFile(dst); err != nil {
+			return err
+		}
+		if err := os.WriteFile(longPath(dst), content, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	concat := fs.Bool("concat", false, "merge markdown files into a single document")
+	version := fs.String("version", "", "export as of this version (default: current)")
+	out := fs.String("out", "", "output file (--concat) or directory")
+	label := fs.String("label", "", "only export files with this label")
+	separator := fs.String("separator", "", "text placed between files with --concat (overrides config)")
+	format := fs.String("format", "", "convert the concatenated document with pandoc: epub, pdf, docx, odt, html")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if *out == "" && len(rest) == 1 {
+		*out = rest[0]
+	}
+	if *format != "" {
+		if !pandocFormats[*format] {
+			return fmt.Errorf("unsupported format %q (epub, pdf, docx, odt, html)", *format)
+		}
+		*concat = true
+	}
+	if *out == "" || len(rest) > 1 {
+		return fmt.Errorf("usage: gitnot export [--concat] [--version v] [--label l] --out <file|dir>")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	v, err := parseVersionArg(*version)
+	if err != nil {
+		return err
+	}
+	m, err := loadManifest(v)
+	if err != nil {
+		return err
+	}
+	cfg := loadConfig()
+	if *separator != "" {
+		cfg.Export.Separator = strings.ReplaceAll(*separator, `\n`, "\n")
+	}
+	files := exportSelection(m, cfg, *label, *concat)
+	if len(files) == 0 {
+		return fmt.Errorf("nothing to export at v%.1f", v)
+	}
+
+	if *concat {
+		doc, err := concatDocument(m, files, cfg.Export)
+		if err != nil {
+			return err
+		}
+		if err := safeMkdirAllForFile(*out); err != nil {
+			return err
+		}
+		if *format != "" {
+			if err := runPandoc(cfg.Export, doc, *format, *out, v); err != nil {
+				return err
+			}
+			fmt.Printf("📚 Built %s (%s) from %d files at v%.1f\n", *out, *format, len(files), v)
+			return nil
+		}
+		if err := os.WriteFile(*out, doc, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("📚 Wrote %s from %d files at v%.1f\n", *out, len(files), v)
+		return nil
+	}
+	if err := exportTree(m, files, *out); err != nil {
+		return err
+	}
+	fmt.Printf("📤 Exported %d files at v%.1f to %s\n", len(files), v, *out)
+	return nil
+}
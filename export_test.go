@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestOrderPaths(t *testing.T) {
+	paths := []string{"notes.md", "chapters/02.md", "epilogue.md", "chapters/01.md", "outline.md"}
+	order := []string{"outline.md", "chapters/*.md", "epilogue.md"}
+
+	got := orderPaths(paths, order)
+	want := []string{"outline.md", "chapters/01.md", "chapters/02.md", "epilogue.md", "notes.md"}
+	for i := range want {
+		if filepath.ToSlash(got[i]) != want[i] {
+			t.Fatalf("orderPaths = %v, expected %v", got, want)
+		}
+	}
+	if sel := selectOrdered(paths, order); len(sel) != 4 {
+		t.Errorf("selectOrdered should drop unlisted files, got %v", sel)
+	}
+}
+
+func TestConcatExportAtVersion(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "chapters/01.md", "# One\nfirst draft\n")
+	createTestFile(t, "chapters/02.md", "# Two\n")
+	createTestFile(t, "outline.md", "# Outline\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.Order = []string{"outline.md", "chapters/*.md"}
+	saveJSON(configFile, cfg)
+
+	createTestFile(t, "chapters/01.md", "# One\nsecond draft\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	if err := runExport([]string{"--concat", "--version", "0.0", "out/book.md"}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	b, _ := os.ReadFile("out/book.md")
+	want := "# Outline\n\n# One\nfirst draft\n\n# Two\n"
+	if string(b) != want {
+		t.Errorf("Unexpected v0.0 book:\n%q\nexpected\n%q", b, want)
+	}
+
+	if err := runExport([]string{"--out", "tree"}); err != nil {
+		t.Fatalf("tree export failed: %v", err)
+	}
+	b, _ = os.ReadFile("tree/chapters/01.md")
+	if string(b) != "# One\nsecond draft\n" {
+		t.Errorf("Tree export should contain the current version, got %q", b)
+	}
+}
+
+func TestConcatTemplate(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a-chapter.md", "Alpha\n")
+	createTestFile(t, "b-chapter.md", "Beta\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	m, err := loadManifest(0.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec := ExportConfig{Separator: "\n\n---\n\n", Template: "## {{.Index}}. {{.Title}} (v{{.Version}})\n{{.Content}}"}
+	doc, err := concatDocument(m, []string{"a-chapter.md", "b-chapter.md"}, ec)
+	if err != nil {
+		t.Fatalf("concatDocument failed: %v", err)
+	}
+	want := "## 1. a-chapter (v0.0)\nAlpha\n\n---\n\n## 2. b-chapter (v0.0)\nBeta\n"
+	if string(doc) != want {
+		t.Errorf("Unexpected document:\n%q\nexpected\n%q", doc, want)
+	}
+
+	if _, err := concatDocument(m, []string{"a-chapter.md"}, ExportConfig{Template: "{{.Nope"}); err == nil {
+		t.Error("Invalid template should be reported")
+	}
+}
+
+func TestPandocExport(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a stand-in for pandoc")
+	}
+	dir := setupTestDir(t)
+
+	// Fake pandoc: record its arguments and stdin in the output file
+	fake := filepath.Join(dir, "fake-pandoc.sh")
+	createTestFile(t, fake, "#!/bin/sh\nout=\"\"\nprev=\"\"\nfor a in \"$@\"; do [ \"$prev\" = \"-o\" ] && out=\"$a\"; prev=\"$a\"; done\n{ echo \"$@\"; cat; } > \"$out\"\n")
+	os.Chmod(fake, 0o755)
+
+	createTestFile(t, "chapter.md", "Hello\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.Export.Pandoc = fake
+	saveJSON(configFile, cfg)
+
+	if err := runExport([]string{"--format", "epub", "--out", "book.epub"}); err != nil {
+		t.Fatalf("export --format failed: %v", err)
+	}
+	b, _ := os.ReadFile("book.epub")
+	if !strings.Contains(string(b), "gitnot-version=v0.0") || !strings.Contains(string(b), "-t epub") {
+		t.Errorf("Version metadata or format not passed to pandoc:\n%s", b)
+	}
+	if !strings.Contains(string(b), "Hello") {
+		t.Errorf("Document not piped to pandoc:\n%s", b)
+	}
+
+	if err := runExport([]string{"--format", "mobi", "--out", "x"}); err == nil {
+		t.Error("Unsupported format should be rejected")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+)
+
+// --- Fast pre-filter ---
+//
+// Hashing a file computes its SHA-1, which is what gets recorded, and in the
+// same pass its XXH64, which is many times cheaper. The pair is remembered
+// and kept in the stat index. When a file is expected to still hold content
+// whose pair is known — an update re-reading a recorded file, or status
+// re-reading one whose size or mtime changed — only the XXH64 is computed,
+// and the SHA-1 is only worked out when that differs. On a mostly unchanged
+// tree that leaves the cryptographic hash for the files that changed.
+// Metadata always holds SHA-1s; `--full` skips the pre-filter.
+
+// fastHash pairs a content's XXH64 with its SHA-1.
+type fastHash struct {
+	XXH64 string `json:"xxh64"`
+	SHA1  string `json:"sha1"`
+}
+
+var (
+	fastMu     sync.Mutex
+	fastHashes = map[string]string{} // SHA-1 → XXH64 of the same content
+)
+
+// rememberFast notes the XXH64 of the content hashing to sum.
+func rememberFast(sum, fast string) {
+	if sum == "" || fast == "" {
+		return
+	}
+	fastMu.Lock()
+	fastHashes[sum] = fast
+	fastMu.Unlock()
+}
+
+// knownFast returns the pair for the content hashing to sum, if known.
+func knownFast(sum string) *fastHash {
+	fastMu.Lock()
+	defer fastMu.Unlock()
+	if fast, ok := fastHashes[sum]; ok {
+		return &fastHash{XXH64: fast, SHA1: sum}
+	}
+	return nil
+}
+
+// digestFile feeds the content of p to each of hs, retrying transient read
+// errors.
+func digestFile(p string, hs ...hash.Hash) error {
+	return withRetry(func() error {
+		ws := make([]io.Writer, len(hs))
+		for i, h := range hs {
+			h.Reset()
+			ws[i] = h
+		}
+		f, err := openSource(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		buf := copyBuffers.Get().(*[]byte)
+		defer copyBuffers.Put(buf)
+		// hidden behind a plain Reader, the file can't bring its own buffer
+		_, err = io.CopyBuffer(io.MultiWriter(ws...), struct{ io.Reader }{throttled(f)}, *buf)
+		return err
+	})
+}
+
+// hashFileExpecting is hashFile for a file expected to still hold the
+// content hashing to want: when its XXH64 matches want's, want is returned
+// without computing the SHA-1.
+func hashFileExpecting(p, want string) string {
+	known := knownFast(want)
+	if known == nil {
+		return hashFile(p)
+	}
+	fi, _ := os.Stat(longPath(p))
+	x := newXXH64()
+	if err := digestFile(p, x); err == nil && fmt.Sprintf("%016x", x.Sum64()) == known.XXH64 {
+		noteHashed(p, want, fi)
+		return want
+	}
+	return hashFile(p)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestXXH64(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"abc", 0x44bc2cf5ad770999},
+		{"asdf", 0x415872f599cea71e},
+		{"Call me Ishmael. Some years ago--never mind how long precisely-", 0x02a2e85470d6fd96},
+	} {
+		x := newXXH64()
+		x.Write([]byte(c.in))
+		if got := x.Sum64(); got != c.want {
+			t.Errorf("XXH64(%q) = %#x, want %#x", c.in, got, c.want)
+		}
+	}
+
+	// written in pieces of any size, the result is the same
+	text := []byte(strings.Repeat("It was a dark and stormy night. ", 40))
+	whole := newXXH64()
+	whole.Write(text)
+	for _, step := range []int{1, 3, 31, 32, 33, 100} {
+		x := newXXH64()
+		for i := 0; i < len(text); i += step {
+			x.Write(text[i:min(i+step, len(text))])
+		}
+		if x.Sum64() != whole.Sum64() {
+			t.Errorf("Writing %d bytes at a time gave %#x, want %#x", step, x.Sum64(), whole.Sum64())
+		}
+	}
+}
+
+func TestHashFileExpecting(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.md", "Once upon a time\n")
+	sum := hashFile("a.md")
+	known := knownFast(sum)
+	if known == nil {
+		t.Fatal("Expected hashing to remember the fast hash")
+	}
+
+	// a matching fast hash answers without computing the SHA-1
+	rememberFast("not-a-real-sha1", known.XXH64)
+	if got := hashFileExpecting("a.md", "not-a-real-sha1"); got != "not-a-real-sha1" {
+		t.Errorf("Expected the expected hash back, got %s", got)
+	}
+	createTestFile(t, "a.md", "Once upon a time, again\n")
+	if got := hashFileExpecting("a.md", sum); got == sum || got != contentHash([]byte("Once upon a time, again\n")) {
+		t.Errorf("Expected the new content's SHA-1, got %s", got)
+	}
+	if got := hashFileExpecting("a.md", "never-seen"); got != contentHash([]byte("Once upon a time, again\n")) {
+		t.Errorf("Expected a full hash without a known fast hash, got %s", got)
+	}
+}
+
+func TestIndexKeepsFastHashes(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.md", "One\n")
+	createTestFile(t, "b.md", "Two\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	for rel, e := range loadIndex() {
+		if e.Fast == nil || e.Fast.SHA1 != hashFile(rel) {
+			t.Errorf("Expected %s to carry its fast hash, got %+v", rel, e.Fast)
+		}
+	}
+
+	createTestFile(t, "b.md", "Two, edited\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.1 {
+		t.Errorf("Expected the edit to be recorded as v0.1, got v%.1f", v)
+	}
+	if got, want := loadCommittedHashes()["b.md"], contentHash([]byte("Two, edited\n")); got != want {
+		t.Errorf("Recorded %s for b.md, want %s", got, want)
+	}
+	e := loadIndex()["b.md"]
+	if e.Fast == nil || e.Fast.SHA1 != contentHash([]byte("Two, edited\n")) {
+		t.Errorf("Expected the index to pair the new content, got %+v", e.Fast)
+	}
+	x := newXXH64()
+	x.Write([]byte("Two, edited\n"))
+	if e.Fast != nil && e.Fast.XXH64 != fmt.Sprintf("%016x", x.Sum64()) {
+		t.Errorf("Unexpected fast hash %s", e.Fast.XXH64)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// --- Store format & migrations ---
+//
+// store.json carries the on-disk format version. Older stores are upgraded
+// in place, one step at a time, after a metadata backup; stores written by a
+// newer gitnot are refused rather than risk misreading them. A store the
+// Python gitnot created is only converted by `gitnot migrate --from-python`.
+
+const currentFormat = 4
+
+type StoreInfo struct {
+	FormatVersion int   `json:"format_version"`
+	Archived      bool  `json:"archived,omitempty"`
+	Generation    int64 `json:"generation,omitempty"` // bumped by every committed update
+}
+
+type migration struct {
+	from int
+	desc string
+	run  func() error
+}
+
+var migrations = []migration{
+	{0, "record manifests and the stat index", migrateAddManifests},
+	{1, "store file content objects", migrateSeedObjects},
+	// objects may be stored as .delta files (delta.go); nothing to convert,
+	// but older builds can't read them and must refuse the store
+	{2, "allow delta objects", func() error { return nil }},
+	// objects may sit in pack files (pack.go), where older builds don't look
+	{3, "allow packed objects", func() error { return nil }},
+}
+
+// readStoreInfo returns format 0 for stores that predate store.json.
+func readStoreInfo() (StoreInfo, error) {
+	var info StoreInfo
+	err := loadJSON(storeFile, &info)
+	if errors.Is(err, os.ErrNotExist) {
+		return StoreInfo{}, nil
+	}
+	return info, err
+}
+
+func writeStoreInfo(info StoreInfo) error {
+	return saveJSON(storeFile, info)
+}
+
+func migrateStore() error {
+	if _, err := repo.FS.Stat(gitnotDir); err != nil {
+		return nil
+	}
+	info, err := readStoreInfo()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", storeFile, err)
+	}
+	if info.FormatVersion > currentFormat {
+		return fmt.Errorf("this store uses format %d, written by a newer gitnot (this build understands up to %d); please upgrade gitnot",
+			info.FormatVersion, currentFormat)
+	}
+	if info.FormatVersion == currentFormat {
+		return nil
+	}
+	if info.FormatVersion == 0 && isPythonStore() {
+		return fmt.Errorf("this store was created by the Python gitnot; run 'gitnot migrate --from-python' to convert it")
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return fmt.Errorf("upgrading the store from format %d: %w", info.FormatVersion, err)
+	}
+	defer release()
+	// another run may have upgraded it while we waited for the lock
+	if info, err = readStoreInfo(); err != nil || info.FormatVersion == currentFormat {
+		return err
+	}
+	if _, err := backupMetadata("migrate"); err != nil {
+		return fmt.Errorf("backing up before migration: %w", err)
+	}
+	for _, m := range migrations {
+		if m.from != info.FormatVersion {
+			continue
+		}
+		if err := m.run(); err != nil {
+			return fmt.Errorf("migrating store from format %d: %w", m.from, err)
+		}
+		info.FormatVersion = m.from + 1
+		if err := writeStoreInfo(info); err != nil {
+			return err
+		}
+		fmt.Printf("🔄 Upgraded store to format %d (%s)\n", info.FormatVersion, m.desc)
+	}
+	if info.FormatVersion != currentFormat {
+		return fmt.Errorf("no migration path from store format %d", info.FormatVersion)
+	}
+	return nil
+}
+
+// migrateAddManifests seeds a manifest for the current version and builds the
+// stat index from the working tree's real hashes.
+func migrateAddManifests() error {
+	v, err := readVersion()
+	if err != nil {
+		return err
+	}
+	hashes := map[string]string{}
+	if err := loadJSON(hashesFile, &hashes); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if _, err := repo.FS.Stat(manifestPath(v)); errors.Is(err, os.ErrNotExist) {
+		m := Manifest{Version: v, Files: hashes}
+		if fi, err := repo.FS.Stat(versionFile); err == nil {
+			m.Timestamp = fi.ModTime()
+		}
+		if err := writeManifest(m); err != nil {
+			return err
+		}
+	}
+	current := map[string]string{}
+	for rel := range hashes {
+		if _, err := os.Stat(rel); err == nil {
+			current[rel] = hashFile(rel)
+		}
+	}
+	return saveIndex(current)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMigrateLegacyStore(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.txt", "one")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	// Strip everything a pre-manifest gitnot would not have written
+	for _, p := range []string{storeFile, indexFile, manifestDir} {
+		os.RemoveAll(p)
+	}
+
+	if err := migrateStore(); err != nil {
+		t.Fatalf("migrateStore failed: %v", err)
+	}
+	info, _ := readStoreInfo()
+	if info.FormatVersion != currentFormat {
+		t.Errorf("Expected format %d, got %d", currentFormat, info.FormatVersion)
+	}
+	var m Manifest
+	if err := loadJSON(manifestPath(0.0), &m); err != nil || m.Files["a.txt"] == "" {
+		t.Errorf("Expected seeded manifest for v0.0, got %+v (%v)", m, err)
+	}
+	if _, ok := loadIndex()["a.txt"]; !ok {
+		t.Error("Expected index entry for a.txt")
+	}
+	if names, _ := listBackups(); len(names) != 1 {
+		t.Errorf("Expected a pre-migration backup, got %v", names)
+	}
+}
+
+func TestRefuseNewerStoreFormat(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.txt", "one")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := writeStoreInfo(StoreInfo{FormatVersion: currentFormat + 1}); err != nil {
+		t.Fatal(err)
+	}
+	err := migrateStore()
+	if err == nil || !strings.Contains(err.Error(), "newer gitnot") {
+		t.Errorf("Expected refusal for newer format, got: %v", err)
+	}
+}
+
+func TestMigrationTakesTheLock(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.txt", "one")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	os.RemoveAll(storeFile)
+	release, err := acquireLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateStore(); err == nil || !strings.Contains(err.Error(), "in progress") {
+		t.Errorf("Expected the migration to wait for the running update, got %v", err)
+	}
+	if info, _ := readStoreInfo(); info.FormatVersion != 0 {
+		t.Errorf("Expected the store to be left alone, got format %d", info.FormatVersion)
+	}
+	release()
+	if err := migrateStore(); err != nil {
+		t.Fatalf("migrateStore failed: %v", err)
+	}
+	if _, err := os.Stat(lockFile); err == nil {
+		t.Error("Expected the migration to let go of the lock")
+	}
+}
+
+func TestDeltaAndPackStoresAreNewFormats(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.txt", "one")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	// a build from before delta objects wrote format 2
+	if err := writeStoreInfo(StoreInfo{FormatVersion: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateStore(); err != nil {
+		t.Fatalf("migrateStore failed: %v", err)
+	}
+	if info, _ := readStoreInfo(); info.FormatVersion != currentFormat || currentFormat < 4 {
+		t.Errorf("Expected the store moved to format %d, got %d", currentFormat, info.FormatVersion)
+	}
+	if b, err := readObject(contentHash([]byte("one"))); err != nil || string(b) != "one" {
+		t.Errorf("Expected objects left as they were, got %q, %v", b, err)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/codinganovel/go-difflib/difflib"
+)
+
+// --- Fountain ---
+//
+// The Fountain driver reads screenplays as scenes rather than lines: its
+// changelog entries say which scenes were added, cut, moved or rewritten and
+// how many dialogue and action lines changed, followed by the line diff.
+
+var (
+	sceneHeadingRe = regexp.MustCompile(`(?i)^(int|ext|est|int\.?/ext|i/e)[. ]`)
+	sceneNumberRe  = regexp.MustCompile(`\s*#[^#]*#\s*$`)
+	characterRe    = regexp.MustCompile(`^[^a-z]*[A-Z][^a-z]*$`)
+)
+
+const (
+	fountainAction   = "action"
+	fountainDialogue = "dialogue"
+	fountainOther    = "" // headings, characters, transitions, blanks, notes
+)
+
+// fountainScene is one scene: its heading key and body text.
+type fountainScene struct {
+	Key  string
+	Body string
+}
+
+// parseFountain classifies every line and splits the script into scenes.
+// Text before the first heading is its own unnamed scene.
+func parseFountain(lines []string) (kinds []string, scenes []fountainScene) {
+	seen := map[string]int{}
+	var body strings.Builder
+	key := ""
+	flush := func() {
+		if key != "" || strings.TrimSpace(body.String()) != "" {
+			scenes = append(scenes, fountainScene{Key: key, Body: body.String()})
+		}
+		body.Reset()
+	}
+	inDialogue := false
+	for i, raw := range lines {
+		l := strings.TrimRight(raw, "\r\n")
+		t := strings.TrimSpace(l)
+		prevBlank := i == 0 || strings.TrimSpace(lines[i-1]) == ""
+		nextText := i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != ""
+		kind := fountainOther
+		switch {
+		case t == "":
+			inDialogue = false
+		case prevBlank && (sceneHeadingRe.MatchString(t) || strings.HasPrefix(t, ".") && !strings.HasPrefix(t, "..")):
+			flush()
+			heading := strings.ToUpper(sceneNumberRe.ReplaceAllString(strings.TrimPrefix(t, "."), ""))
+			seen[heading]++
+			key = heading
+			if n := seen[heading]; n > 1 {
+				key = fmt.Sprintf("%s (%d)", heading, n)
+			}
+			inDialogue = false
+		case strings.HasPrefix(t, "=") || strings.HasPrefix(t, "#") || strings.HasPrefix(t, "[[") || strings.HasPrefix(t, ">"):
+			// synopses, sections, notes, transitions and centred text
+		case prevBlank && nextText && (strings.HasPrefix(t, "@") || characterRe.MatchString(t) && !strings.HasSuffix(t, "TO:")):
+			inDialogue = true
+		case inDialogue:
+			kind = fountainDialogue
+		case prevBlank && characterRe.MatchString(t) && strings.HasSuffix(t, "TO:"):
+			// transition
+		default:
+			kind = fountainAction
+		}
+		kinds = append(kinds, kind)
+		body.WriteString(l)
+		body.WriteString("\n")
+	}
+	flush()
+	return kinds, scenes
+}
+
+// fountainDiff summarises a screenplay change by scene and line type.
+func fountainDiff(oldB, newB []byte) (string, bool) {
+	a := difflib.SplitLines(string(oldB))
+	b := difflib.SplitLines(string(newB))
+	ka, sa := parseFountain(a)
+	kb, sb := parseFountain(b)
+
+	oldBody := map[string]string{}
+	for _, s := range sa {
+		oldBody[s.Key] = s.Body
+	}
+	newBody := map[string]string{}
+	for _, s := range sb {
+		newBody[s.Key] = s.Body
+	}
+	var added, cut, moved, rewritten []string
+	for _, s := range sb {
+		if s.Key == "" {
+			continue
+		}
+		if old, ok := oldBody[s.Key]; !ok {
+			added = append(added, s.Key)
+		} else if old != s.Body {
+			rewritten = append(rewritten, s.Key)
+		}
+	}
+	for _, s := range sa {
+		if _, ok := newBody[s.Key]; !ok && s.Key != "" {
+			cut = append(cut, s.Key)
+		}
+	}
+	// scenes present on both sides but outside the longest common ordering moved
+	var oldOrder, newOrder []string
+	for _, s := range sa {
+		if _, ok := newBody[s.Key]; ok && s.Key != "" {
+			oldOrder = append(oldOrder, s.Key)
+		}
+	}
+	for _, s := range sb {
+		if _, ok := oldBody[s.Key]; ok && s.Key != "" {
+			newOrder = append(newOrder, s.Key)
+		}
+	}
+	inPlace := map[string]bool{}
+	for _, op := range difflib.NewMatcher(oldOrder, newOrder).GetOpCodes() {
+		if op.Tag == 'e' {
+			for _, k := range newOrder[op.J1:op.J2] {
+				inPlace[k] = true
+			}
+		}
+	}
+	for _, k := range newOrder {
+		if !inPlace[k] {
+			moved = append(moved, k)
+		}
+	}
+
+	counts := map[string][2]int{} // kind → {added, removed}
+	for _, op := range difflib.NewMatcher(a, b).GetOpCodes() {
+		if op.Tag == 'e' {
+			continue
+		}
+		for i := op.I1; i < op.I2; i++ {
+			c := counts[ka[i]]
+			c[1]++
+			counts[ka[i]] = c
+		}
+		for j := op.J1; j < op.J2; j++ {
+			c := counts[kb[j]]
+			c[0]++
+			counts[kb[j]] = c
+		}
+	}
+
+	var out strings.Builder
+	out.WriteString("### 🎬 Scenes\n")
+	for _, sec := range []struct {
+		label  string
+		scenes []string
+	}{{"Added", added}, {"Cut", cut}, {"Moved", moved}, {"Rewritten", rewritten}} {
+		if len(sec.scenes) > 0 {
+			fmt.Fprintf(&out, "%s: %s\n", sec.label, strings.Join(sec.scenes, "; "))
+		}
+	}
+	d, ac := counts[fountainDialogue], counts[fountainAction]
+	fmt.Fprintf(&out, "Dialogue lines: +%d/-%d · Action lines: +%d/-%d\n\n", d[0], d[1], ac[0], ac[1])
+	out.WriteString(formatDiffAsMarkdown(unifiedDiffLines(a, b)))
+	return out.String(), true
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codinganovel/go-difflib/difflib"
+)
+
+const testScript = `Title: Test
+
+INT. KITCHEN - DAY
+
+Anna pours coffee.
+
+ANNA
+Morning.
+
+EXT. GARDEN - NIGHT
+
+Rain falls.
+
+INT. HALLWAY - DAY #3#
+
+BEN
+(quietly)
+Who's there?
+
+CUT TO:
+`
+
+func TestParseFountain(t *testing.T) {
+	lines := difflib.SplitLines(testScript)
+	kinds, scenes := parseFountain(lines)
+	var keys []string
+	for _, s := range scenes {
+		keys = append(keys, s.Key)
+	}
+	if strings.Join(keys, "|") != "|INT. KITCHEN - DAY|EXT. GARDEN - NIGHT|INT. HALLWAY - DAY" {
+		t.Errorf("Unexpected scenes: %q", keys)
+	}
+	count := map[string]int{}
+	for _, k := range kinds {
+		count[k]++
+	}
+	if count[fountainDialogue] != 3 || count[fountainAction] != 3 {
+		t.Errorf("Expected 3 dialogue and 3 action lines, got %v", count)
+	}
+}
+
+func TestFountainDiff(t *testing.T) {
+	kitchen := "INT. KITCHEN - DAY\n\nAnna pours coffee.\n\nANNA\nMorning.\n\n"
+	newScript := strings.Replace(testScript, kitchen, "", 1)
+	newScript += "\n" + strings.Replace(kitchen, "Morning.", "Morning, Ben.", 1) + "EXT. ROOF - DAY\n\nWind.\n"
+
+	md, ok := fountainDiff([]byte(testScript), []byte(newScript))
+	if !ok {
+		t.Fatal("Expected a fountain summary")
+	}
+	for _, want := range []string{
+		"Added: EXT. ROOF - DAY",
+		"Moved: INT. KITCHEN - DAY",
+		"Rewritten: INT. KITCHEN - DAY",
+		"Dialogue lines: +1/-1",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected %q in:\n%s", want, md)
+		}
+	}
+
+	cut := strings.Replace(testScript, "EXT. GARDEN - NIGHT\n\nRain falls.\n\n", "", 1)
+	md, _ = fountainDiff([]byte(testScript), []byte(cut))
+	if !strings.Contains(md, "Cut: EXT. GARDEN - NIGHT") || strings.Contains(md, "Moved") {
+		t.Errorf("Expected only a cut scene:\n%s", md)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/codinganovel/go-difflib/difflib"
+)
+
+// Fuzz targets for the code that takes text from users and files. Run one
+// with, for example:
+//
+//	go test -run '^$' -fuzz FuzzShouldIgnore -fuzztime 30s
+//
+// Without -fuzz the seeds below run as ordinary tests.
+
+func FuzzShouldIgnore(f *testing.F) {
+	for _, s := range [][2]string{
+		{"notes/draft.tmp", "*.tmp"},
+		{"node_modules/x/index.js", "node_modules/*"},
+		{"build/out/a.md", "build/**"},
+		{"_minted-thesis/x.pyg", "_minted*/*"},
+		{"a.md", "[a-"},
+		{"a.md", "\\"},
+		{"dir/a.md", "dir/[/*"},
+		{"", ""},
+		{"a/b", "/*"},
+	} {
+		f.Add(s[0], s[1])
+	}
+	f.Fuzz(func(t *testing.T, p, pat string) {
+		got := shouldIgnore(p, []string{pat})
+		if err := checkGlob(pat); err != nil && !strings.HasSuffix(pat, "/*") && !strings.HasSuffix(pat, "/**") {
+			if base := path.Base(p); got && base != pat && p != pat {
+				t.Errorf("malformed glob %q matched %q", pat, p)
+			}
+		}
+		if pat != "" && !strings.ContainsAny(pat, "*?[\\/") && path.Base(p) == pat && !got {
+			t.Errorf("name %q did not match itself in %q", pat, p)
+		}
+	})
+}
+
+func FuzzFormatDiffAsMarkdown(f *testing.F) {
+	f.Add("one\ntwo\n", "one\n2\nthree\n")
+	f.Add("", "new\n")
+	f.Add("gone\n", "")
+	f.Add("@@ -x,y +z @@\n", "@@\n")
+	f.Add("a\r\nb\r\n", "a\nb\n")
+	f.Fuzz(func(t *testing.T, a, b string) {
+		// whatever shape the text is in, formatting never panics
+		_ = formatDiffAsMarkdown(a)
+		_ = formatDiffAsMarkdown(b)
+		diff := unifiedDiffLines(difflib.SplitLines(a), difflib.SplitLines(b))
+		for _, l := range strings.Split(formatDiffAsMarkdown(diff), "\n") {
+			if l != "" && !strings.HasPrefix(l, "### ") && !strings.HasPrefix(l, "L") && !strings.HasPrefix(l, "📄") {
+				t.Errorf("unexpected line %q in the formatted diff of\n%s", l, diff)
+			}
+		}
+	})
+}
+
+func FuzzParseConfig(f *testing.F) {
+	defaults, _ := json.Marshal(defaultConfig)
+	f.Add(defaults)
+	f.Add([]byte(`{"extensions": [".md"], "ignore_patterns": ["[", "a/**", ""]}`))
+	f.Add([]byte(`{"rules": [{"match": "*.md"}, null, 3], "hooks": {}}`))
+	f.Add([]byte(`{"retry": {"attempts": -1}, "locked_files": "sometimes"}`))
+	f.Add([]byte(`{"extensions": [".md"`))
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`null`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		cfg, _ := parseConfig(b)
+		cfg.scanFilters().ruleFor("notes/a.md")
+	})
+}
+
+func FuzzParseManifest(f *testing.F) {
+	f.Add([]byte(`{"version": 0.1, "timestamp": "2024-05-01T10:00:00Z", "files": {"a.md": "x"}, "changes": [{"path": "a.md", "state": "modified"}]}`))
+	f.Add([]byte(`{"version": -3, "changes": [null, {"state": "revived", "revived_from": 1e308}], "remaps": [{}], "adopted": ["a"]}`))
+	f.Add([]byte(`{"version": 1e400}`))
+	f.Add([]byte(`{"files": null, "clock": {"": -1}}`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		m, err := parseManifest(b)
+		if err != nil {
+			return
+		}
+		_ = historySection(m)
+	})
+}
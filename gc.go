@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- Garbage collection ---
+//
+// The deleted store keeps the last snapshot of every removed file. gc purges
+// entries past the configured retention (by age, then oldest-first until the
+// store fits the size cap) and appends each permanent removal to gc.log.
+// Nothing is removed without --confirm or a yes at the prompt; --dry-run
+// only lists what would go.
+
+type Retention struct {
+	MaxAgeDays int `json:"max_age_days,omitempty"`
+	MaxSizeMB  int `json:"max_size_mb,omitempty"`
+}
+
+type trashEntry struct {
+	Path    string // relative to deletedDir
+	Size    int64
+	Deleted time.Time
+}
+
+// listTrash lists the deleted store, oldest first. A deleted copy's mtime is
+// set to when its file was deleted.
+func listTrash() ([]trashEntry, error) {
+	var out []trashEntry
+	err := walkStore(deletedDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(deletedDir, p)
+		out = append(out, trashEntry{Path: rel, Size: fi.Size(), Deleted: fi.ModTime()})
+		return nil
+	})
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Deleted.Before(out[j].Deleted) }) // ties stay in path order
+	return out, err
+}
+
+// expiredTrash picks the entries the retention policy says to purge.
+func expiredTrash(entries []trashEntry, r Retention, now time.Time) []trashEntry {
+	var expired []trashEntry
+	var kept []trashEntry
+	for _, e := range entries {
+		if r.MaxAgeDays > 0 && now.Sub(e.Deleted) > time.Duration(r.MaxAgeDays)*24*time.Hour {
+			expired = append(expired, e)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+	if r.MaxSizeMB > 0 {
+		limit := int64(r.MaxSizeMB) << 20
+		var total int64
+		for _, e := range kept {
+			total += e.Size
+		}
+		for len(kept) > 0 && total > limit { // kept is oldest first
+			expired = append(expired, kept[0])
+			total -= kept[0].Size
+			kept = kept[1:]
+		}
+	}
+	return expired
+}
+
+// removeEmptyParents deletes now-empty directories between p and stop.
+func removeEmptyParents(p, stop string) {
+	for d := filepath.Dir(p); d != stop && d != "." && d != string(filepath.Separator); d = filepath.Dir(d) {
+		if err := os.Remove(d); err != nil {
+			return
+		}
+	}
+}
+
+func purgeTrash(entries []trashEntry) (int64, error) {
+	var freed int64
+	now := repo.Now().Format("2006-01-02 15:04")
+	for _, e := range entries {
+		p := filepath.Join(deletedDir, e.Path)
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return freed, err
+		}
+		removeEmptyParents(p, deletedDir)
+		freed += e.Size
+		_ = appendToFile(gcLogFile, fmt.Sprintf("%s removed deleted/%s (%s, deleted %s)\n",
+			now, filepath.ToSlash(e.Path), formatBytes(e.Size), e.Deleted.Format("2006-01-02")))
+	}
+	return freed, nil
+}
+
+// confirmAction asks a yes/no question on the terminal. Without one (scripts,
+// pipes) the answer is no, so destructive commands need an explicit flag.
+func confirmAction(question string) bool {
+	if !isTerminal() {
+		return false
+	}
+	fmt.Printf("%s [y/N] ", question)
+	line, _ := stdin.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+func runGC(args []string) error {
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "list what would be removed without removing anything")
+	confirm := fs.Bool("confirm", false, "remove without asking")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	cfg := loadConfig()
+	if cfg.DeletedRetention == (Retention{}) {
+		fmt.Println("✅ No deleted_retention configured; nothing to purge")
+		return nil
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+	entries, err := listTrash()
+	if err != nil {
+		return err
+	}
+	expired := expiredTrash(entries, cfg.DeletedRetention, repo.Now())
+	if len(expired) == 0 {
+		fmt.Println("✅ Deleted store is within retention; nothing to purge")
+		return nil
+	}
+	var reclaim int64
+	for _, e := range expired {
+		reclaim += e.Size
+		fmt.Printf("  🗑️  deleted/%s (%s, deleted %s)\n", filepath.ToSlash(e.Path), formatBytes(e.Size), e.Deleted.Format("2006-01-02"))
+	}
+	fmt.Printf("💾 %d deleted files, %s would be reclaimed\n", len(expired), formatBytes(reclaim))
+	if *dryRun {
+		fmt.Println("🔍 Dry run; nothing was removed")
+		return nil
+	}
+	if !*confirm && !confirmAction("Permanently remove these files?") {
+		return fmt.Errorf("gc aborted; re-run with --confirm to remove these files")
+	}
+	if err := requirePassphrase("permanently remove deleted files"); err != nil {
+		return err
+	}
+	saved, err := backupMetadata("gc")
+	if err != nil {
+		return fmt.Errorf("backing up metadata: %w", err)
+	}
+	freed, err := purgeTrash(expired)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🧹 Permanently removed %d deleted files, freed %s (logged to %s, metadata backup: %s)\n", len(expired), formatBytes(freed), gcLogFile, saved)
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpiredTrash(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	entries := []trashEntry{
+		{Path: "ancient.md", Size: 1 << 20, Deleted: now.AddDate(0, 0, -100)},
+		{Path: "old.md", Size: 2 << 20, Deleted: now.AddDate(0, 0, -20)},
+		{Path: "recent.md", Size: 2 << 20, Deleted: now.AddDate(0, 0, -1)},
+	}
+	expired := expiredTrash(entries, Retention{MaxAgeDays: 30}, now)
+	if len(expired) != 1 || expired[0].Path != "ancient.md" {
+		t.Errorf("Age policy: unexpected %v", expired)
+	}
+	expired = expiredTrash(entries, Retention{MaxAgeDays: 30, MaxSizeMB: 3}, now)
+	if len(expired) != 2 || expired[1].Path != "old.md" {
+		t.Errorf("Size policy should also drop the oldest remaining entry, got %v", expired)
+	}
+}
+
+func TestGCPurgesDeletedStore(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "keep.md", "keep")
+	createTestFile(t, "notes/gone.md", "gone")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	os.Remove("notes/gone.md")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	trashed := filepath.Join(deletedDir, "notes", "gone.md")
+	past := time.Now().AddDate(0, 0, -10)
+	if err := os.Chtimes(trashed, past, past); err != nil {
+		t.Fatal(err)
+	}
+	cfg := loadConfig()
+	cfg.DeletedRetention = Retention{MaxAgeDays: 7}
+	saveJSON(configFile, cfg)
+
+	if err := runGC([]string{"--dry-run"}); err != nil {
+		t.Fatalf("gc --dry-run failed: %v", err)
+	}
+	if _, err := os.Stat(trashed); err != nil {
+		t.Fatal("Dry run must not remove anything")
+	}
+	if err := runGC(nil); err == nil {
+		t.Fatal("gc without --confirm and no terminal should refuse")
+	}
+	if _, err := os.Stat(trashed); err != nil {
+		t.Fatal("Unconfirmed gc must not remove anything")
+	}
+
+	if err := setPassphrase("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(passphraseEnv, "wrong")
+	if err := runGC([]string{"--confirm"}); err == nil {
+		t.Fatal("gc with a wrong passphrase should refuse")
+	}
+	if _, err := os.Stat(trashed); err != nil {
+		t.Fatal("Refused gc must not remove anything")
+	}
+	t.Setenv(passphraseEnv, "correct horse")
+	if err := runGC([]string{"--confirm"}); err != nil {
+		t.Fatalf("gc failed: %v", err)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(backupDir, "*-gc.tar.gz")); len(backups) != 1 {
+		t.Errorf("Expected a metadata backup before purging, got %v", backups)
+	}
+	if _, err := os.Stat(trashed); !os.IsNotExist(err) {
+		t.Error("Expired deleted file should be purged")
+	}
+	if _, err := os.Stat(filepath.Dir(trashed)); !os.IsNotExist(err) {
+		t.Error("Empty directories should be cleaned up")
+	}
+	log, _ := os.ReadFile(gcLogFile)
+	if !strings.Contains(string(log), "deleted/notes/gone.md") {
+		t.Errorf("Removal not logged: %q", log)
+	}
+}
+
+func TestDeletedCopyAgesFromDeletion(t *testing.T) {
+	setupTestDir(t)
+	t.Cleanup(func() { setCopy(CopyConfig{}) })
+	cfg := loadConfig()
+	cfg.Copy.PreserveTimes = true
+	saveJSON(configFile, cfg)
+	createTestFile(t, "old.md", "written long ago")
+	past := time.Now().AddDate(-1, 0, 0)
+	os.Chtimes("old.md", past, past)
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	os.Remove("old.md")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	entries, _ := listTrash()
+	if len(entries) != 1 || time.Since(entries[0].Deleted) > time.Hour {
+		t.Fatalf("Expected one entry deleted just now, got %v", entries)
+	}
+	if expired := expiredTrash(entries, Retention{MaxAgeDays: 30}, time.Now()); len(expired) != 0 {
+		t.Errorf("Expected a note deleted just now to be kept, got %v", expired)
+	}
+}
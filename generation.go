@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// --- Store generation ---
+//
+// store.json carries a generation counter that every committed update bumps.
+// An update notes the generation when it starts and checks it again before
+// its journal begins and before it writes any metadata: if a sync service
+// delivered another machine's update in the meantime, the run is abandoned
+// instead of overwriting that version (last writer wins).
+
+var errStoreChanged = errors.New("the store changed while this update was running " +
+	"(another gitnot run, or a sync service delivering an update from another machine); nothing was recorded.\n" +
+	"💡 Let the sync finish, check 'gitnot log' to see the new version, then run gitnot again")
+
+func readGeneration() int64 {
+	info, _ := readStoreInfo()
+	return info.Generation
+}
+
+// checkGeneration fails with errStoreChanged unless the store is still at
+// generation gen and version prev.
+func checkGeneration(gen int64, prev float64) error {
+	if v, err := readVersion(); err != nil || v != prev || readGeneration() != gen {
+		return errStoreChanged
+	}
+	return nil
+}
+
+// bumpGeneration moves the store past generation gen.
+func bumpGeneration(gen int64) error {
+	info, err := readStoreInfo()
+	if err != nil {
+		return err
+	}
+	info.Generation = max(info.Generation, gen) + 1
+	return writeStoreInfo(info)
+}
+
+// abandonUpdate undoes a journaled update that found the store changed
+// before it wrote any metadata: changelog entries are cut off again and the
+// previous snapshot is put back. Hashes and manifests are left alone; they
+// belong to whoever changed the store.
+func abandonUpdate(j *Journal) error {
+	rollBackChangelogs(j)
+	if _, err := repo.FS.Stat(snapshotOldDir); err == nil {
+		if err := repo.FS.RemoveAll(snapshotDir); err != nil {
+			return err
+		}
+		if err := repo.FS.Rename(snapshotOldDir, snapshotDir); err != nil {
+			return err
+		}
+	}
+	_ = repo.FS.RemoveAll(snapshotTmpDir)
+	if err := repo.FS.Remove(journalFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("clearing journal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// racingFS lets another machine's update land once this one has written
+// its journal, halfway through the update.
+type racingFS struct {
+	osFS
+	raced bool
+}
+
+func (f *racingFS) Rename(oldpath, newpath string) error {
+	if err := f.osFS.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+	if filepath.Clean(newpath) == filepath.Clean(journalFile) && !f.raced {
+		f.raced = true
+		return bumpGeneration(readGeneration())
+	}
+	return nil
+}
+
+func TestUpdateAbortsWhenStoreChanges(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "a.md", "two\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	gen := readGeneration()
+	if gen != 1 {
+		t.Fatalf("Expected generation 1 after one update, got %d", gen)
+	}
+
+	// Another machine's update lands while this one is running.
+	useRepo(t, Repo{Clock: systemClock{}, FS: &racingFS{}})
+	createTestFile(t, "a.md", "three\n")
+	if err := updateGitnot(); !errors.Is(err, errStoreChanged) {
+		t.Fatalf("Expected errStoreChanged, got %v", err)
+	}
+	if v, _ := readVersion(); v != 0.1 {
+		t.Errorf("Nothing should have been recorded, version is v%.1f", v)
+	}
+	if _, err := os.Stat(journalFile); err == nil {
+		t.Error("Abandoned update left its journal behind")
+	}
+}
+
+func TestAbandonUpdate(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cl := filepath.Join(changelogDir, "a.md.log")
+	before, _ := os.ReadFile(cl)
+	j, err := beginJournal("update", 0, 0.1, []string{cl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendToFile(cl, "\n## v0.1 – entry that must go\n")
+	os.Rename(snapshotDir, snapshotOldDir)
+	os.MkdirAll(snapshotDir, 0o755)
+
+	if err := abandonUpdate(j); err != nil {
+		t.Fatalf("abandonUpdate failed: %v", err)
+	}
+	if after, _ := os.ReadFile(cl); string(after) != string(before) {
+		t.Errorf("Changelog not cut back:\n%s", after)
+	}
+	if got, err := os.ReadFile(filepath.Join(snapshotDir, "a.md")); err != nil || string(got) != "one\n" {
+		t.Errorf("Previous snapshot not put back: %q, %v", got, err)
+	}
+}
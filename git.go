@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// --- Git coexistence ---
+//
+// gitnot never tracks anything inside .git/, records the git HEAD commit in
+// each manifest when the folder is also a git repository, and can install
+// hooks so a version is recorded alongside every commit.
+
+const (
+	hookBegin = "# >>> gitnot >>>"
+	hookEnd   = "# <<< gitnot <<<"
+)
+
+// gitDir returns the repository's git directory, following the "gitdir:"
+// file used by worktrees and submodules. ok is false outside a repository.
+func gitDir() (string, bool) {
+	fi, err := os.Stat(".git")
+	if err != nil {
+		return "", false
+	}
+	if fi.IsDir() {
+		return ".git", true
+	}
+	b, err := os.ReadFile(".git")
+	if err != nil {
+		return "", false
+	}
+	line := strings.TrimSpace(string(b))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, "gitdir:")), true
+}
+
+// gitHead returns the commit HEAD points at, or "" when there is none.
+func gitHead() string {
+	dir, ok := gitDir()
+	if !ok {
+		return ""
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	head := strings.TrimSpace(string(b))
+	ref, isRef := strings.CutPrefix(head, "ref: ")
+	if !isRef {
+		return head // detached
+	}
+	// worktrees keep branch refs in the common dir
+	common := gitCommonDir(dir)
+	for _, d := range []string{dir, common} {
+		if b, err := os.ReadFile(filepath.Join(d, filepath.FromSlash(ref))); err == nil {
+			return strings.TrimSpace(string(b))
+		}
+	}
+	return packedRef(filepath.Join(common, "packed-refs"), ref)
+}
+
+// gitCommonDir returns the folder a worktree's git directory shares with
+// the main repository; for the main repository it is dir itself.
+func gitCommonDir(dir string) string {
+	if c, err := os.ReadFile(filepath.Join(dir, "commondir")); err == nil {
+		return filepath.Join(dir, strings.TrimSpace(string(c)))
+	}
+	return dir
+}
+
+// gitHooksDir returns the folder git runs hooks from for the git directory
+// dir. git knows best: `git rev-parse --git-path hooks` follows
+// core.hooksPath and sends a worktree to the hooks of the main repository.
+// Without a git binary, the default hooks/ in the common dir is used.
+func gitHooksDir(dir string) string {
+	if out, err := exec.Command("git", "--git-dir="+dir, "rev-parse", "--git-path", "hooks").Output(); err == nil {
+		if p := strings.TrimSpace(string(out)); p != "" {
+			return filepath.FromSlash(p)
+		}
+	}
+	return filepath.Join(gitCommonDir(dir), "hooks")
+}
+
+func packedRef(p, ref string) string {
+	f, err := os.Open(p)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if hash, name, ok := strings.Cut(sc.Text(), " "); ok && name == ref {
+			return hash
+		}
+	}
+	return ""
+}
+
+func shortHash(h string) string {
+	if len(h) > 7 {
+		return h[:7]
+	}
+	return h
+}
+
+// hookScript is the block gitnot adds to a hook; it never fails the commit.
+func hookScript(hook string) string {
+	return fmt.Sprintf("%s\n# recorded by 'gitnot git-hooks install' (%s)\ngitnot || true\n%s\n", hookBegin, hook, hookEnd)
+}
+
+// installHook adds the gitnot block to a hook, creating the hook if needed.
+// It reports false when the block was already there.
+func installHook(hooksDir, hook string) (bool, error) {
+	p := filepath.Join(hooksDir, hook)
+	b, err := os.ReadFile(p)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	text := string(b)
+	if strings.Contains(text, hookBegin) {
+		return false, nil
+	}
+	if text == "" {
+		text = "#!/bin/sh\n"
+	} else if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	text += hookScript(hook)
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		return false, err
+	}
+	if err := writeFileAtomic(p, []byte(text), 0o755); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// uninstallHook removes the gitnot block, and the hook itself when nothing
+// else is left in it. It reports false when there was no block.
+func uninstallHook(hooksDir, hook string) (bool, error) {
+	p := filepath.Join(hooksDir, hook)
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	text := string(b)
+	start := strings.Index(text, hookBegin)
+	end := strings.Index(text, hookEnd)
+	if start < 0 || end < start {
+		return false, nil
+	}
+	text = text[:start] + strings.TrimPrefix(text[end+len(hookEnd):], "\n")
+	if strings.TrimSpace(strings.TrimPrefix(text, "#!/bin/sh")) == "" {
+		return true, os.Remove(p)
+	}
+	return true, writeFileAtomic(p, []byte(text), 0o755)
+}
+
+func hookInstalled(hooksDir, hook string) bool {
+	b, err := os.ReadFile(filepath.Join(hooksDir, hook))
+	return err == nil && strings.Contains(string(b), hookBegin)
+}
+
+func runGitHooks(args []string) error {
+	fs := flag.NewFlagSet("git-hooks", flag.ExitOnError)
+	preCommit := fs.Bool("pre-commit", false, "also record a version before each commit")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: gitnot git-hooks install|uninstall|status [--pre-commit]")
+	}
+	dir, ok := gitDir()
+	if !ok {
+		return fmt.Errorf("not a git repository (no .git here)")
+	}
+	hooksDir := gitHooksDir(dir)
+	hooks := []string{"post-commit"}
+	if *preCommit {
+		hooks = append(hooks, "pre-commit")
+	}
+
+	switch rest[0] {
+	case "install":
+		if err := ensureInitialized(); err != nil {
+			return err
+		}
+		for _, h := range hooks {
+			added, err := installHook(hooksDir, h)
+			if err != nil {
+				return err
+			}
+			if added {
+				fmt.Printf("🪝 Installed %s hook\n", h)
+			} else {
+				fmt.Printf("🪝 %s hook already runs gitnot\n", h)
+			}
+		}
+	case "uninstall":
+		for _, h := range []string{"post-commit", "pre-commit"} {
+			removed, err := uninstallHook(hooksDir, h)
+			if err != nil {
+				return err
+			}
+			if removed {
+				fmt.Printf("🪝 Removed gitnot from %s hook\n", h)
+			}
+		}
+	case "status":
+		for _, h := range []string{"post-commit", "pre-commit"} {
+			state := "not installed"
+			if hookInstalled(hooksDir, h) {
+				state = "installed"
+			}
+			fmt.Printf("🪝 %s: %s\n", h, state)
+		}
+		if head := gitHead(); head != "" {
+			fmt.Printf("🔗 git HEAD: %s\n", shortHash(head))
+		}
+	default:
+		return fmt.Errorf("unknown git-hooks action %q (use install, uninstall or status)", rest[0])
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitHead(t *testing.T) {
+	setupTestDir(t)
+
+	if gitHead() != "" {
+		t.Error("Expected no HEAD outside a repository")
+	}
+	createTestFile(t, ".git/HEAD", "ref: refs/heads/main\n")
+	createTestFile(t, ".git/packed-refs", "# pack-refs with: peeled\n1111111111111111111111111111111111111111 refs/heads/main\n")
+	if got := gitHead(); got != "1111111111111111111111111111111111111111" {
+		t.Errorf("Expected packed ref, got %q", got)
+	}
+	createTestFile(t, ".git/refs/heads/main", "2222222222222222222222222222222222222222\n")
+	if got := gitHead(); !strings.HasPrefix(got, "2222") {
+		t.Errorf("Expected loose ref to win, got %q", got)
+	}
+}
+
+func TestGitCoexistence(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, ".git/HEAD", "3333333333333333333333333333333333333333\n")
+	createTestFile(t, ".git/description.txt", "git internals")
+	createTestFile(t, "notes.md", "hello")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	m, _ := loadManifest(0.0)
+	if _, ok := m.Files[filepath.Join(".git", "description.txt")]; ok || len(m.Files) != 1 {
+		t.Errorf("Files inside .git should not be tracked: %v", m.Files)
+	}
+	if m.GitHead != "3333333333333333333333333333333333333333" {
+		t.Errorf("Expected git HEAD in manifest, got %q", m.GitHead)
+	}
+}
+
+func TestInstallHook(t *testing.T) {
+	dir := setupTestDir(t)
+	hooks := filepath.Join(dir, "hooks")
+
+	createTestFile(t, filepath.Join(hooks, "post-commit"), "#!/bin/sh\necho existing\n")
+	if added, err := installHook(hooks, "post-commit"); err != nil || !added {
+		t.Fatalf("installHook failed: %v", err)
+	}
+	if added, _ := installHook(hooks, "post-commit"); added {
+		t.Error("Second install should be a no-op")
+	}
+	b, _ := os.ReadFile(filepath.Join(hooks, "post-commit"))
+	if !strings.Contains(string(b), "echo existing") || strings.Count(string(b), hookBegin) != 1 {
+		t.Errorf("Unexpected hook:\n%s", b)
+	}
+
+	if removed, err := uninstallHook(hooks, "post-commit"); err != nil || !removed {
+		t.Fatalf("uninstallHook failed: %v", err)
+	}
+	b, _ = os.ReadFile(filepath.Join(hooks, "post-commit"))
+	if string(b) != "#!/bin/sh\necho existing\n" {
+		t.Errorf("Existing hook not restored:\n%q", b)
+	}
+
+	installHook(hooks, "pre-commit")
+	uninstallHook(hooks, "pre-commit")
+	if _, err := os.Stat(filepath.Join(hooks, "pre-commit")); !os.IsNotExist(err) {
+		t.Error("Hook created by gitnot should be removed entirely")
+	}
+}
+
+func TestGitHooksDir(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := setupTestDir(t)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	if got := gitHooksDir(".git"); got != filepath.Join(".git", "hooks") {
+		t.Errorf("Expected .git/hooks, got %q", got)
+	}
+	git("config", "core.hooksPath", "shared-hooks")
+	if got := gitHooksDir(".git"); got != "shared-hooks" {
+		t.Errorf("Expected core.hooksPath to be followed, got %q", got)
+	}
+
+	// a worktree runs the main repository's hooks
+	git("config", "--unset", "core.hooksPath")
+	git("commit", "-q", "--allow-empty", "-m", "start")
+	git("worktree", "add", "-q", "wt")
+	if err := os.Chdir("wt"); err != nil {
+		t.Fatal(err)
+	}
+	wtDir, ok := gitDir()
+	if !ok {
+		t.Fatal("Expected the worktree's .git file to be followed")
+	}
+	os.MkdirAll(filepath.Join(dir, ".git", "hooks"), 0o755)
+	want, _ := filepath.EvalSymlinks(filepath.Join(dir, ".git", "hooks"))
+	if got, _ := filepath.EvalSymlinks(gitHooksDir(wtDir)); got != want {
+		t.Errorf("Expected the main repository's hooks, got %q", gitHooksDir(wtDir))
+	}
+}
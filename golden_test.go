@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// --- Golden files ---
+//
+// A fixture is a canned project under testdata/fixtures/<name>: numbered
+// folders (0, 1, 2, ...) hold the project as it looked at each version, and
+// an optional "pending" folder holds edits not recorded yet. loadFixture
+// replays it into a fresh test directory, running --init on the first step
+// and an update on each later one, on a fixed clock an hour apart. The
+// changelogs, manifests, HISTORY.md, diffs and porcelain output it produces
+// are compared against testdata/golden/<name>; run
+//
+//	go test -run Golden -update
+//
+// after an intended formatting change and review the rewritten files.
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files under testdata/golden")
+
+// testdataDir is found before any test changes directory.
+var testdataDir, _ = filepath.Abs("testdata")
+
+// goldenEpoch is when a fixture's first version is recorded.
+var goldenEpoch = time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+// loadFixture builds the named fixture in a new test directory and returns
+// the folder holding its golden files.
+func loadFixture(t *testing.T, name string) string {
+	t.Helper()
+	src := filepath.Join(testdataDir, "fixtures", name)
+	golden := filepath.Join(testdataDir, "golden", name)
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		t.Fatalf("Reading fixture %s: %v", name, err)
+	}
+	var steps []string
+	pending := false
+	for _, e := range entries {
+		if e.Name() == "pending" {
+			pending = true
+		} else if e.IsDir() {
+			steps = append(steps, e.Name())
+		}
+	}
+	sort.Slice(steps, func(i, j int) bool {
+		return len(steps[i]) < len(steps[j]) || len(steps[i]) == len(steps[j]) && steps[i] < steps[j]
+	})
+
+	setupTestDir(t)
+	saved, savedLocal := repo, time.Local
+	t.Cleanup(func() { repo, time.Local = saved, savedLocal })
+	time.Local = time.UTC
+	t.Setenv("GITNOT_AUTHOR", "Ada Lovelace")
+	if err := saveUserConfig(UserConfig{Device: Device{ID: "f1x7e5", Name: "fixture"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, step := range steps {
+		at := goldenEpoch.Add(time.Duration(i) * time.Hour)
+		repo = Repo{Clock: fixedClock{at}, FS: osFS{}}
+		replaceWorkingTree(t, filepath.Join(src, step), at)
+		if i == 0 {
+			err = initGitnot()
+		} else {
+			err = updateGitnot()
+		}
+		if err != nil {
+			t.Fatalf("Fixture %s step %s: %v", name, step, err)
+		}
+	}
+	if pending {
+		replaceWorkingTree(t, filepath.Join(src, "pending"), goldenEpoch.Add(time.Duration(len(steps))*time.Hour))
+	}
+	return golden
+}
+
+// replaceWorkingTree makes the project's files (everything but the store)
+// exactly those under dir, modified at mtime.
+func replaceWorkingTree(t *testing.T, dir string, mtime time.Time) {
+	t.Helper()
+	entries, _ := os.ReadDir(".")
+	for _, e := range entries {
+		if e.Name() != gitnotDir {
+			os.RemoveAll(e.Name())
+		}
+	}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		createTestFile(t, rel, string(b))
+		return os.Chtimes(rel, mtime, mtime)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkGolden compares got with the golden file at p, or rewrites it with
+// -update.
+func checkGolden(t *testing.T, p string, got []byte) {
+	t.Helper()
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatalf("Reading golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from its golden file (run with -update if the change is intended)\n%s",
+			filepath.Base(p), unifiedDiffLines(strings.SplitAfter(string(want), "\n"), strings.SplitAfter(string(got), "\n")))
+	}
+}
+
+// stableManifest blanks the fields that differ between machines and runs.
+func stableManifest(t *testing.T, v float64) []byte {
+	t.Helper()
+	m, err := loadManifest(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ID, m.Clock, m.Env, m.GitHead, m.Signature = "", nil, nil, "", nil
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(b, '\n')
+}
+
+func TestGoldenChangelogs(t *testing.T) {
+	golden := loadFixture(t, "draft")
+	err := filepath.WalkDir(changelogDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		got, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(changelogDir, p)
+		checkGolden(t, filepath.Join(golden, "changelogs", rel), got)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGoldenManifests(t *testing.T) {
+	golden := loadFixture(t, "draft")
+	for _, v := range []float64{0.0, 0.1, 0.2} {
+		checkGolden(t, filepath.Join(golden, "manifests", filepath.Base(manifestPath(v))), stableManifest(t, v))
+	}
+	history, err := os.ReadFile(historyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, filepath.Join(golden, "HISTORY.md"), history)
+}
+
+func TestGoldenDiff(t *testing.T) {
+	golden := loadFixture(t, "draft")
+	diff, err := unifiedDiff(filepath.Join(snapshotDir, "chapter2.md"), "chapter2.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, filepath.Join(golden, "chapter2.diff"), []byte(diff))
+	checkGolden(t, filepath.Join(golden, "chapter2.diff.md"), []byte(formatDiffAsMarkdown(diff)))
+}
+
+func TestGoldenPorcelain(t *testing.T) {
+	golden := loadFixture(t, "draft")
+	old := loadCommittedHashes()
+	files, err := scanTextFiles(".", loadConfig().scanFilters())
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := map[string]string{}
+	for _, f := range files {
+		current[f] = hashFile(f)
+	}
+	added, modified, deleted := detectChanges(old, current)
+	records := porcelainRecords(added, modified, deleted, old, current)
+	for _, nul := range []bool{false, true} {
+		var b bytes.Buffer
+		if err := writePorcelain(&b, records, nul); err != nil {
+			t.Fatal(err)
+		}
+		name := "porcelain-v1.txt"
+		if nul {
+			name = "porcelain-v1-z.txt"
+		}
+		checkGolden(t, filepath.Join(golden, name), b.Bytes())
+	}
+}
+
+// TestStoreIsDeterministic replays a fixture several times and expects the
+// same bytes in every store file; map iteration order differs on each run,
+// so anything written in map order shows up here.
+func TestStoreIsDeterministic(t *testing.T) {
+	var first map[string]string
+	for run := range 4 {
+		loadFixture(t, "draft")
+		store := map[string]string{}
+		err := filepath.WalkDir(gitnotDir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			b, err := os.ReadFile(p)
+			store[filepath.ToSlash(p)] = string(b)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run == 0 {
+			first = store
+			continue
+		}
+		for _, p := range sortedKeys(first) {
+			if got, ok := store[p]; !ok {
+				t.Errorf("Run %d did not write %s", run, p)
+			} else if got != first[p] {
+				t.Errorf("Run %d wrote a different %s:\n%s", run, p,
+					unifiedDiffLines(strings.SplitAfter(first[p], "\n"), strings.SplitAfter(got, "\n")))
+			}
+		}
+		for p := range store {
+			if _, ok := first[p]; !ok {
+				t.Errorf("Run %d wrote an extra %s", run, p)
+			}
+		}
+	}
+}
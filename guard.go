@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Project root ---
+//
+// Any command takes --root <folder> to run on a project other than the
+// current folder. Whichever folder that ends up being, it must not be a
+// store or lie inside one: run from inside .gitnot, gitnot would start
+// versioning its own snapshot and changelogs, so commands refuse and point
+// at the project folder instead.
+
+// splitRootFlag removes --root <dir> (or --root=dir) from args.
+func splitRootFlag(args []string) (rest []string, root string, err error) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if !strings.HasPrefix(a, "-") || name != "root" {
+			rest = append(rest, a)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, "", fmt.Errorf("--root needs a folder")
+			}
+			i++
+			value = args[i]
+		}
+		root = value
+	}
+	return rest, root, nil
+}
+
+// enterRoot switches to the project folder given with --root.
+func enterRoot(root string) error {
+	if root == "" {
+		return nil
+	}
+	if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+		return fmt.Errorf("--root %s is not a folder", root)
+	}
+	return os.Chdir(root)
+}
+
+// checkOutsideStore refuses to run in a store folder or anywhere inside one.
+func checkOutsideStore() error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	for d := wd; ; d = filepath.Dir(d) {
+		if isStore(d) {
+			where := "inside gitnot's store " + d
+			if d == wd {
+				where = "gitnot's store folder"
+			}
+			return fmt.Errorf("this is %s, not a project; run gitnot from %s (or pass --root %s)", where, filepath.Dir(d), filepath.Dir(d))
+		}
+		if filepath.Dir(d) == d {
+			return nil
+		}
+	}
+}
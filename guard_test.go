@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRefuseInsideStore(t *testing.T) {
+	dir := setupTestDir(t)
+
+	createTestFile(t, "chapter.md", "One\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := checkOutsideStore(); err != nil {
+		t.Errorf("The project folder should be fine: %v", err)
+	}
+	if err := os.Chdir(filepath.Join(defaultStoreDir, "snapshot")); err != nil {
+		t.Fatal(err)
+	}
+	err := checkOutsideStore()
+	if err == nil || !strings.Contains(err.Error(), "inside gitnot's store") {
+		t.Fatalf("Expected a refusal inside the store, got %v", err)
+	}
+	if code := run([]string{"status"}); code == 0 {
+		t.Error("status inside the store should fail")
+	}
+	if _, err := os.Stat(defaultStoreDir); err == nil {
+		t.Error("No store should be created inside the store")
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if code := run([]string{"--root", defaultStoreDir, "status"}); code == 0 {
+		t.Error("--root pointing at the store should fail")
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOtherStoreNotTracked(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "chapter.md", "One\n")
+	createTestFile(t, filepath.Join("old-store", "version.txt"), "0.3")
+	createTestFile(t, filepath.Join("old-store", "config.json"), "{}")
+	createTestFile(t, filepath.Join("old-store", "snapshot", "chapter.md"), "Old\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	for rel := range loadCommittedHashes() {
+		if within(rel, "old-store") {
+			t.Errorf("A store folder should never be tracked: %s", rel)
+		}
+	}
+}
+
+func TestSplitRootFlag(t *testing.T) {
+	rest, root, err := splitRootFlag([]string{"status", "--root=../novel", "--full", "--", "--root"})
+	if err != nil || root != "../novel" || strings.Join(rest, " ") != "status --full -- --root" {
+		t.Errorf("Unexpected split: %v %q %v", rest, root, err)
+	}
+	if _, _, err := splitRootFlag([]string{"--root"}); err == nil {
+		t.Error("Expected an error for --root without a folder")
+	}
+}
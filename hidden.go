@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// --- Hidden files and folders ---
+//
+// Dotfiles are easy to miss: `.editorconfig` or `.env.example` have no
+// extension from the tracked list, while `.venv/` or `.cache/` can hold
+// thousands of files nobody wants versioned. Two settings make this
+// explicit:
+//
+//   - "hidden_files": "match" (default) treats dotfiles like any other file,
+//     "track" tracks them whatever their extension (ignore patterns still
+//     apply) and "skip" never tracks them.
+//   - "hidden_dirs": "scan" (default) looks inside dot-folders, "skip" leaves
+//     them out entirely.
+//
+// `gitnot status` says what was left out this way. Neither applies when
+// .gitnot/tracked.txt lists the files, and Finder clutter such as .DS_Store
+// is never tracked or reported by them.
+
+const (
+	hiddenMatch = "match"
+	hiddenTrack = "track"
+	hiddenSkip  = "skip"
+	hiddenScan  = "scan"
+)
+
+var hiddenNoise = []string{".DS_Store", "._*"}
+
+// isHidden reports whether a file or folder name starts with a dot.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// skipsHiddenDir reports whether the scan leaves the folder rel out.
+func (f scanFilter) skipsHiddenDir(rel string) bool {
+	return f.HiddenDirs == hiddenSkip && isHidden(filepath.Base(rel))
+}
+
+// hiddenFile applies hidden_files to a dotfile the rules did or did not
+// track. skipped is set when the file is left out and worth reporting; files
+// an ignore pattern or size limit leaves out are not.
+func (f scanFilter) hiddenFile(rel string, tracked bool) (track, skipped bool) {
+	if f.listed {
+		return tracked, false
+	}
+	if matchesAny(rel, hiddenNoise) {
+		return tracked && f.HiddenFiles != hiddenSkip, false
+	}
+	// files no rule matched are the ones extensions miss
+	_, i := f.ruleFor(rel)
+	switch f.HiddenFiles {
+	case hiddenSkip:
+		return false, tracked
+	case hiddenTrack:
+		return tracked || i < 0, false
+	default:
+		return tracked, !tracked && i < 0
+	}
+}
+
+// reportHidden tells status what the hidden settings left out.
+func reportHidden(dirs, files []string) {
+	show := func(ps []string, suffix string) string {
+		var out []string
+		for _, p := range preview(ps, 3) {
+			out = append(out, filepath.ToSlash(p)+suffix)
+		}
+		if len(ps) > 3 {
+			out = append(out, fmt.Sprintf("and %d more", len(ps)-3))
+		}
+		return strings.Join(out, ", ")
+	}
+	if len(dirs) > 0 {
+		fmt.Printf("🙈 Hidden folders skipped (%d): %s (see hidden_dirs)\n", len(dirs), show(dirs, "/"))
+	}
+	if len(files) > 0 {
+		fmt.Printf("🙈 Hidden files not tracked (%d): %s (see hidden_files)\n", len(files), show(files, ""))
+	}
+}
+
+func validateHidden(c Config) []configIssue {
+	var issues []configIssue
+	switch c.HiddenFiles {
+	case "", hiddenMatch, hiddenTrack, hiddenSkip:
+	default:
+		issues = append(issues, configIssue{"hidden_files", fmt.Sprintf("%q is not one of %q, %q, %q", c.HiddenFiles, hiddenMatch, hiddenTrack, hiddenSkip)})
+	}
+	switch c.HiddenDirs {
+	case "", hiddenScan, hiddenSkip:
+	default:
+		issues = append(issues, configIssue{"hidden_dirs", fmt.Sprintf("%q is not one of %q, %q", c.HiddenDirs, hiddenScan, hiddenSkip)})
+	}
+	return issues
+}
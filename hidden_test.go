@@ -0,0 +1,69 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestHiddenSettings(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "chapter.md", "Text\n")
+	createTestFile(t, ".editorconfig", "root = true\n")
+	createTestFile(t, ".notes.md", "Hidden notes\n")
+	createTestFile(t, ".DS_Store", "\x00\x01")
+	createTestFile(t, filepath.Join(".venv", "lib.py"), "import os\n")
+	createTestFile(t, filepath.Join(".venv", ".cfg"), "x\n")
+
+	scan := func(files, dirs string) scanReport {
+		t.Helper()
+		cfg := defaultConfig
+		cfg.HiddenFiles, cfg.HiddenDirs = files, dirs
+		rep, err := scanTreeReport(".", cfg.scanFilters())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rep
+	}
+	has := func(ps []string, p string) bool {
+		for _, q := range ps {
+			if filepath.Clean(q) == filepath.Clean(p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	rep := scan("", "")
+	if !has(rep.Files, ".notes.md") || has(rep.Files, ".editorconfig") || !has(rep.Files, filepath.Join(".venv", "lib.py")) {
+		t.Errorf("Default should track matching dotfiles and scan dot-folders, got %v", rep.Files)
+	}
+	if !has(rep.HiddenFiles, ".editorconfig") || has(rep.HiddenFiles, ".DS_Store") {
+		t.Errorf("Expected .editorconfig reported as skipped and .DS_Store not, got %v", rep.HiddenFiles)
+	}
+
+	rep = scan(hiddenTrack, hiddenSkip)
+	if !has(rep.Files, ".editorconfig") || has(rep.Files, ".DS_Store") {
+		t.Errorf("track should take dotfiles whatever their extension, except clutter, got %v", rep.Files)
+	}
+	if has(rep.Files, filepath.Join(".venv", "lib.py")) || !has(rep.HiddenDirs, ".venv") {
+		t.Errorf("skip should leave .venv out and report it, got files %v, dirs %v", rep.Files, rep.HiddenDirs)
+	}
+
+	rep = scan(hiddenSkip, "")
+	if has(rep.Files, ".notes.md") || !has(rep.HiddenFiles, ".notes.md") {
+		t.Errorf("skip should leave out even matching dotfiles, got %v", rep.Files)
+	}
+	if !has(rep.Files, "chapter.md") {
+		t.Errorf("Ordinary files should stay tracked, got %v", rep.Files)
+	}
+}
+
+func TestValidateHidden(t *testing.T) {
+	if issues := validateHidden(Config{HiddenFiles: "all", HiddenDirs: "ignore"}); len(issues) != 2 {
+		t.Errorf("Expected two issues, got %v", issues)
+	}
+	if issues := validateHidden(Config{HiddenFiles: hiddenTrack, HiddenDirs: hiddenSkip}); len(issues) != 0 {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+}
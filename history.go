@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- History ---
+//
+// .gitnot/HISTORY.md collects every version in one place: its message, its
+// narrative if enabled, and a one-line summary per file, so "what changed in
+// v1.2" doesn't mean opening dozens of per-file logs. Updates append to it;
+// `gitnot history` renders the same document from the manifests on demand.
+
+const historyHeader = "# History\n"
+
+// historySection renders one version's entry.
+func historySection(m Manifest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n## v%.1f – %s", m.Version, m.Timestamp.Local().Format("2006-01-02 15:04"))
+	if m.Author != nil {
+		b.WriteString(" · " + m.Author.label())
+	}
+	b.WriteString("\n")
+	if m.Message != "" {
+		for _, l := range strings.Split(strings.TrimSpace(m.Message), "\n") {
+			b.WriteString("> " + l + "\n")
+		}
+	}
+	b.WriteString("\n")
+	if m.Narrative != "" {
+		b.WriteString("*" + m.Narrative + "*\n\n")
+	}
+	for _, r := range m.Remaps {
+		fmt.Fprintf(&b, "- 🔀 moved `%s` → `%s` (%d files)\n", filepath.ToSlash(r.From), filepath.ToSlash(r.To), r.Files)
+	}
+	if len(m.Adopted) > 0 {
+		fmt.Fprintf(&b, "- 📥 adopted %d files: %s", len(m.Adopted), strings.Join(preview(m.Adopted, 3), ", "))
+		if len(m.Adopted) > 3 {
+			fmt.Fprintf(&b, " and %d more", len(m.Adopted)-3)
+		}
+		b.WriteString("\n")
+	}
+	for _, c := range m.Changes {
+		b.WriteString(changeSummary(c))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func changeSummary(c FileChange) string {
+	switch c.State {
+	case stateAdded:
+		if c.RevivedFrom != nil {
+			return fmt.Sprintf("- ♻️ `%s` is back, last tracked in v%.1f (%d lines, %d words)", c.Path, *c.RevivedFrom, c.Lines, c.Words)
+		}
+		return fmt.Sprintf("- 📄 added `%s` (%d lines, %d words)", c.Path, c.Lines, c.Words)
+	case stateDeleted:
+		return fmt.Sprintf("- 🔻 deleted `%s`", c.Path)
+	case stateMetadata:
+		return fmt.Sprintf("- 🔧 metadata of `%s`", c.Path)
+	case stateUntracked:
+		return fmt.Sprintf("- 🚫 stopped tracking `%s`", c.Path)
+	default:
+		return fmt.Sprintf("- 📝 modified `%s` (+%d/-%d lines, +%d/-%d words)",
+			c.Path, c.LinesAdded, c.LinesRemoved, c.WordsAdded, c.WordsRemoved)
+	}
+}
+
+func renderHistory(manifests []Manifest) string {
+	var b strings.Builder
+	b.WriteString(historyHeader)
+	for _, m := range manifests {
+		b.WriteString(historySection(m))
+	}
+	return b.String()
+}
+
+// appendHistory adds m to HISTORY.md. A store without one (created before it
+// existed) gets the whole history rendered from its manifests instead.
+func appendHistory(m Manifest) error {
+	if _, err := repo.FS.Stat(historyFile); errors.Is(err, os.ErrNotExist) {
+		manifests, err := loadManifestHeads()
+		if err != nil {
+			return err
+		}
+		return appendToFile(historyFile, renderHistory(manifests))
+	}
+	return appendToFile(historyFile, historySection(m))
+}
+
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	out := fs.String("out", "", "write the rendered history to this file instead of printing it")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	manifests, err := loadManifestHeads()
+	if err != nil {
+		return err
+	}
+	doc := renderHistory(manifests)
+	if *out == "" {
+		fmt.Print(doc)
+		return nil
+	}
+	if err := safeMkdirAllForFile(*out); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, []byte(doc), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("📜 Wrote history of %d versions to %s\n", len(manifests), *out)
+	return nil
+}
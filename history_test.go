@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHistoryFile(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one\n")
+	createTestFile(t, "b.md", "gone\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "a.md", "one\ntwo words\n")
+	os.Remove("b.md")
+	if err := updateGitnotWith(updateOptions{Message: "Second draft"}); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	b, err := os.ReadFile(historyFile)
+	if err != nil {
+		t.Fatalf("HISTORY.md not written: %v", err)
+	}
+	history := string(b)
+	for _, want := range []string{
+		"## v0.0", "- 📄 added `a.md` (1 lines, 1 words)",
+		"## v0.1", "> Second draft", "- 📝 modified `a.md` (+1/-0 lines, +2/-0 words)", "- 🔻 deleted `b.md`",
+	} {
+		if !strings.Contains(history, want) {
+			t.Errorf("Expected %q in HISTORY.md:\n%s", want, history)
+		}
+	}
+
+	manifests, _ := loadManifests()
+	if rendered := renderHistory(manifests); rendered != history {
+		t.Errorf("Rendered history differs from HISTORY.md:\n%s\n---\n%s", rendered, history)
+	}
+}
+
+func TestHistoryCreatedForOldStores(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	os.Remove(historyFile)
+	createTestFile(t, "a.md", "two\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	b, _ := os.ReadFile(historyFile)
+	if !strings.Contains(string(b), "## v0.0") || strings.Count(string(b), "## v0.1") != 1 {
+		t.Errorf("Expected full history rebuilt once:\n%s", b)
+	}
+}
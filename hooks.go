@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+)
+
+// --- Hooks ---
+//
+// "hooks" run commands after an update, each only when the update changed
+// files matching its pattern ("blog/**" below the root, "*.tex" anywhere).
+// The matching paths are appended to the command as arguments, and the new
+// version is passed in GITNOT_VERSION and GITNOT_VERSION_ID. Like
+// check_command, commands run without a shell. A failing hook is reported;
+// the version stays recorded.
+
+// Hook is one entry of the "hooks" config list.
+type Hook struct {
+	Match string   `json:"match"`
+	Run   string   `json:"run"`
+	On    []string `json:"on,omitempty"` // any of added, modified, deleted, metadata; default all
+}
+
+const hookTimeout = 10 * time.Minute
+
+// paths returns the changes of m this hook fires for, in manifest order.
+func (h Hook) paths(changes []FileChange) []string {
+	var out []string
+	for _, c := range changes {
+		if len(h.On) > 0 && !containsFold(h.On, c.State) {
+			continue
+		}
+		if matchesChange(c.Path, h.Match) {
+			out = append(out, c.Path)
+		}
+	}
+	return out
+}
+
+// matchesChange is how hooks and publish targets pick changes: a pattern
+// with a "/" is taken from the project root (see matchesRooted), one
+// without matches names at any depth, like "*.tex".
+func matchesChange(p, pat string) bool {
+	if strings.Contains(pat, "/") {
+		return matchesRooted(p, pat)
+	}
+	return matchesAny(p, []string{pat})
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func runHook(h Hook, m Manifest, paths []string) error {
+	fields := strings.Fields(h.Run)
+	if len(fields) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, fields[0], append(fields[1:], paths...)...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("GITNOT_VERSION=%.1f", m.Version),
+		"GITNOT_VERSION_ID="+m.ID)
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return fmt.Errorf("timed out after %s", hookTimeout)
+	}
+	return err
+}
+
+// runHooks fires every hook whose pattern matches a change in m and reports
+// how many failed.
+func runHooks(hooks []Hook, m Manifest) error {
+	failed := 0
+	for _, h := range hooks {
+		paths := h.paths(m.Changes)
+		if len(paths) == 0 {
+			continue
+		}
+		fmt.Printf("🪝 %s: %s (%d changed)\n", h.Match, h.Run, len(paths))
+		if err := runHook(h, m, paths); err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				err = fmt.Errorf("exit status %d", exitErr.ExitCode())
+			}
+			fmt.Printf("⚠️  Warning: hook for %s failed: %v\n", h.Match, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d hooks failed", failed, len(hooks))
+	}
+	return nil
+}
+
+func validateHooks(hooks []Hook) []configIssue {
+	var issues []configIssue
+	for i, h := range hooks {
+		key := fmt.Sprintf("hooks[%d]", i)
+		add := func(format string, args ...any) {
+			issues = append(issues, configIssue{key, fmt.Sprintf(format, args...)})
+		}
+		if h.Match == "" {
+			add("needs a match pattern")
+		} else if _, err := path.Match(h.Match, ""); err != nil {
+			add("%q is not a valid glob", h.Match)
+		}
+		if strings.TrimSpace(h.Run) == "" {
+			add("needs a command to run")
+		}
+		for _, s := range h.On {
+			switch strings.ToLower(s) {
+			case stateAdded, stateModified, stateDeleted, stateMetadata, stateUntracked:
+			default:
+				add("on: %q is not one of %s, %s, %s, %s, %s", s, stateAdded, stateModified, stateDeleted, stateMetadata, stateUntracked)
+			}
+		}
+	}
+	return issues
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHookPaths(t *testing.T) {
+	changes := []FileChange{
+		{Path: filepath.Join("blog", "post.md"), State: stateModified},
+		{Path: filepath.Join("blog", "2024", "old.md"), State: stateDeleted},
+		{Path: "notes.md", State: stateAdded},
+		{Path: filepath.Join("drafts", "blog", "idea.md"), State: stateAdded},
+	}
+	h := Hook{Match: "blog/**", Run: "publish"}
+	if got := h.paths(changes); len(got) != 2 {
+		t.Errorf("Expected both blog changes and nothing from drafts/blog, got %v", got)
+	}
+	if got := (Hook{Match: "*.md"}).paths(changes); len(got) != 4 {
+		t.Errorf("Expected a pattern without a folder to match at any depth, got %v", got)
+	}
+	h.On = []string{"Modified"}
+	if got := h.paths(changes); !slices.Equal(got, []string{filepath.Join("blog", "post.md")}) {
+		t.Errorf("Expected only the modified post, got %v", got)
+	}
+	if issues := validateHooks([]Hook{{Match: "[", On: []string{"renamed"}}}); len(issues) != 3 {
+		t.Errorf("Expected glob, command and state problems, got %v", issues)
+	}
+}
+
+func TestHooksRunAfterUpdate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh as the hook command")
+	}
+	setupTestDir(t)
+
+	createTestFile(t, filepath.Join("blog", "post.md"), "draft\n")
+	createTestFile(t, "notes.md", "todo\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "hook.sh", "echo \"$GITNOT_VERSION $*\" >> hook.out\n")
+	createTestFile(t, configFile, `{"extensions": [".md"], "hooks": [{"match": "blog/**", "run": "sh hook.sh"}]}`)
+
+	createTestFile(t, "notes.md", "done\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if _, err := os.Stat("hook.out"); err == nil {
+		t.Fatal("Hook ran although no blog file changed")
+	}
+	createTestFile(t, filepath.Join("blog", "post.md"), "published\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	b, _ := os.ReadFile("hook.out")
+	if strings.TrimSpace(string(b)) != "0.2 blog/post.md" {
+		t.Errorf("Unexpected hook invocation: %q", b)
+	}
+}
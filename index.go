@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// --- Stat index ---
+//
+// index.json caches size and mtime per tracked file next to its hash, so
+// read-only commands can skip re-hashing files whose metadata is unchanged.
+
+type IndexEntry struct {
+	Size    int64     `json:"size"`
+	ModTime int64     `json:"mtime"` // unix nanoseconds
+	Hash    string    `json:"hash,omitempty"`
+	Fast    *fastHash `json:"fast,omitempty"` // of the recorded content, see fasthash.go
+}
+
+// racyWindow guards against files written in the same timestamp tick as the
+// index itself: such entries are stored without a hash and always re-hashed.
+const racyWindow = 2 * time.Second
+
+var (
+	hashedMu sync.Mutex
+	hashed   = map[string]IndexEntry{} // path → size and mtime from just before it was hashed
+)
+
+// forgetHashed drops the stats noted so far. Each scan starts afresh and
+// saving the index ends it, so watch and the API server don't collect an
+// entry for every path they have ever hashed.
+func forgetHashed() {
+	hashedMu.Lock()
+	hashed = map[string]IndexEntry{}
+	hashedMu.Unlock()
+}
+
+// noteHashed remembers the stat fi of p taken before its content was read
+// and hashed to sum. The index is built from these rather than from a stat
+// at save time, which would pair an edit made during a long update with the
+// hash of what was there before.
+func noteHashed(p, sum string, fi os.FileInfo) {
+	if sum == "" || fi == nil {
+		return
+	}
+	hashedMu.Lock()
+	hashed[p] = IndexEntry{Size: fi.Size(), ModTime: fi.ModTime().UnixNano(), Hash: sum}
+	hashedMu.Unlock()
+}
+
+// statWhenHashed returns the stat noted when p was hashed to sum.
+func statWhenHashed(p, sum string) (IndexEntry, bool) {
+	hashedMu.Lock()
+	defer hashedMu.Unlock()
+	e, ok := hashed[p]
+	return e, ok && e.Hash == sum
+}
+
+// loadIndex reads the stat index and remembers the fast hashes it holds.
+func loadIndex() map[string]IndexEntry {
+	idx := map[string]IndexEntry{}
+	if err := loadJSON(indexFile, &idx); err != nil {
+		return map[string]IndexEntry{}
+	}
+	for _, e := range idx {
+		if e.Fast != nil {
+			rememberFast(e.Fast.SHA1, e.Fast.XXH64)
+		}
+	}
+	return idx
+}
+
+// buildIndex indexes the files of hashes this process hashed; the others
+// are left out and hashed again next time.
+func buildIndex(hashes map[string]string) map[string]IndexEntry {
+	idx := map[string]IndexEntry{}
+	cutoff := repo.Now().Add(-racyWindow).UnixNano()
+	for rel, h := range hashes {
+		e, ok := statWhenHashed(rel, h)
+		if !ok {
+			continue
+		}
+		e.Fast = knownFast(h)
+		if e.ModTime >= cutoff {
+			e.Hash = ""
+		}
+		idx[rel] = e
+	}
+	return idx
+}
+
+func saveIndex(hashes map[string]string) error {
+	idx := buildIndex(hashes)
+	forgetHashed()
+	return saveJSONStreamed(indexFile, func(w io.Writer) error { return writeJSONMap(w, idx, 0) })
+}
+
+// hashWithIndex returns hashes for files, trusting the index when size and
+// mtime match unless full is set; otherwise the fast pre-filter checks them
+// against the content last recorded. It also reports how many files were
+// answered from the cache and how many were actually hashed.
+func hashWithIndex(files []string, idx map[string]IndexEntry, full bool) (current map[string]string, cached, hashed int) {
+	forgetHashed()
+	current = map[string]string{}
+	for _, f := range files {
+		if !full {
+			if e, ok := idx[f]; ok && e.Hash != "" {
+				if fi, err := os.Stat(f); err == nil && fi.Size() == e.Size && fi.ModTime().UnixNano() == e.ModTime {
+					noteHashed(f, e.Hash, fi)
+					current[f] = e.Hash
+					cached++
+					continue
+				}
+			}
+		}
+		if e, ok := idx[f]; ok && e.Fast != nil && !full {
+			current[f] = hashFileExpecting(f, e.Fast.SHA1)
+		} else {
+			current[f] = hashFile(f)
+		}
+		hashed++
+	}
+	return current, cached, hashed
+}
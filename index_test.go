@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHashWithIndex(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "old.txt", "settled content")
+	createTestFile(t, "fresh.txt", "just written")
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes("old.txt", past, past); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+
+	files := []string{"fresh.txt", "old.txt"}
+	current, cached, hashed := hashWithIndex(files, loadIndex(), false)
+	if cached != 1 || hashed != 1 {
+		t.Errorf("Expected 1 cached (old) and 1 hashed (racy fresh), got %d/%d", cached, hashed)
+	}
+	if current["old.txt"] != hashFile("old.txt") {
+		t.Error("Cached hash does not match file content")
+	}
+
+	// A size change invalidates the cache entry
+	createTestFile(t, "old.txt", "settled content, now edited")
+	if err := os.Chtimes("old.txt", past, past); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+	current, cached, _ = hashWithIndex(files, loadIndex(), false)
+	if cached != 0 || current["old.txt"] != hashFile("old.txt") {
+		t.Errorf("Edited file should be re-hashed, cached=%d", cached)
+	}
+
+	// --full never trusts the index
+	if _, cached, hashed := hashWithIndex(files, loadIndex(), true); cached != 0 || hashed != 2 {
+		t.Errorf("Full scan should hash everything, got cached=%d hashed=%d", cached, hashed)
+	}
+}
+
+func TestIndexKeepsStatFromHashing(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "ch1.md", "first")
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes("ch1.md", past, past)
+	h := hashFile("ch1.md")
+
+	// edited after hashing but long before the index is saved
+	createTestFile(t, "ch1.md", "first, edited during the update")
+	os.Chtimes("ch1.md", past.Add(time.Minute), past.Add(time.Minute))
+	if err := saveIndex(map[string]string{"ch1.md": h}); err != nil {
+		t.Fatal(err)
+	}
+	current, cached, _ := hashWithIndex([]string{"ch1.md"}, loadIndex(), false)
+	if cached != 0 || current["ch1.md"] == h {
+		t.Errorf("Expected the edit to be seen, got cached=%d", cached)
+	}
+}
+
+func TestHashedStatsLastOneScan(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "ch1.md", "first")
+	createTestFile(t, "gone.md", "soon removed")
+	hashWithIndex([]string{"ch1.md", "gone.md"}, nil, true)
+	os.Remove("gone.md")
+
+	// the next scan doesn't carry the removed file along
+	current, _, _ := hashWithIndex([]string{"ch1.md"}, nil, true)
+	if _, ok := hashed["gone.md"]; ok || len(hashed) != 1 {
+		t.Errorf("Expected only this scan's files noted, got %v", hashed)
+	}
+	if err := saveIndex(current); err != nil {
+		t.Fatal(err)
+	}
+	if len(hashed) != 0 {
+		t.Errorf("Expected the noted stats dropped once the index is saved, got %v", hashed)
+	}
+	if idx := loadIndex(); len(idx) != 1 {
+		t.Errorf("Expected ch1.md indexed, got %v", idx)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"time"
+)
+
+// --- Info ---
+
+func dirSize(root string) (int64, int) {
+	var total int64
+	count := 0
+	_ = walkStore(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil {
+			total += fi.Size()
+			count++
+		}
+		return nil
+	})
+	return total, count
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// lastUpdateTime is the timestamp of the committed version's manifest, or the
+// version file's mtime for stores without one.
+func lastUpdateTime(v float64) (time.Time, bool) {
+	var m Manifest
+	if err := loadJSON(manifestPath(v), &m); err == nil && !m.Timestamp.IsZero() {
+		return m.Timestamp, true
+	}
+	if fi, err := repo.FS.Stat(versionFile); err == nil {
+		return fi.ModTime(), true
+	}
+	return time.Time{}, false
+}
+
+func showInfo(listFiles bool) error {
+	if _, err := repo.FS.Stat(gitnotDir); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("gitnot not initialized; run --init")
+	}
+	v, err := readVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("📌 Current version: v%.1f\n", v)
+
+	hashes, err := committedHashes(v)
+	if err != nil {
+		fmt.Printf("⚠️ Could not load tracked files: %v\n", err)
+		hashes = map[string]string{}
+	}
+	fmt.Printf("📁 Tracked files: %d\n", len(hashes))
+
+	if t, ok := lastUpdateTime(v); ok {
+		fmt.Printf("🕒 Last update: %s (%s ago)\n", t.Format("2006-01-02 15:04"), formatDuration(repo.Now().Sub(t)))
+	}
+	size, count := dirSize(gitnotDir)
+	fmt.Printf("💾 Store size: %s in %d files\n", formatBytes(size), count)
+	if trashSize, trashCount := dirSize(deletedDir); trashCount > 0 {
+		fmt.Printf("🗑️  Deleted store: %s in %d files\n", formatBytes(trashSize), trashCount)
+	}
+
+	cfg := loadConfig()
+	if len(cfg.Rules) > 0 {
+		fmt.Printf("⚙️  Config: %d tracking rules\n", len(cfg.Rules))
+	} else {
+		fmt.Printf("⚙️  Config: %d extensions, %d ignore patterns\n", len(cfg.Extensions), len(cfg.IgnorePatterns))
+	}
+	if info, err := readStoreInfo(); err == nil {
+		fmt.Printf("🗄️  Store format: %d\n", info.FormatVersion)
+		if info.Archived {
+			fmt.Println("🔒 Archive mode: on (read-only)")
+		}
+	}
+	fmt.Println("🌐 Remotes: none configured")
+
+	if listFiles && len(hashes) > 0 {
+		fmt.Printf("📁 Tracked files (%d):\n", len(hashes))
+		// Sort file names for consistent output
+		var files []string
+		for file := range hashes {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			fmt.Printf("  • %s\n", file)
+		}
+	}
+	return nil
+}
+
+func runInfo(args []string) error {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	files := fs.Bool("files", false, "also list every tracked file")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	return showInfo(*files)
+}
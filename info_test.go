@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:           "0 B",
+		1023:        "1023 B",
+		1536:        "1.5 KiB",
+		5 * 1 << 20: "5.0 MiB",
+	}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, expected %q", n, got, want)
+		}
+	}
+}
+
+func TestInfo(t *testing.T) {
+	setupTestDir(t)
+
+	if err := showInfo(false); err == nil {
+		t.Error("showInfo should fail when not initialized")
+	}
+	createTestFile(t, "a.txt", "hello")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := showInfo(true); err != nil {
+		t.Errorf("showInfo failed: %v", err)
+	}
+	if size, count := dirSize(gitnotDir); size == 0 || count == 0 {
+		t.Errorf("Expected non-empty store, got %d bytes in %d files", size, count)
+	}
+}
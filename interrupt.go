@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// --- Ctrl-C during an update ---
+//
+// An update catches Ctrl-C (and SIGTERM) instead of dying wherever it happens
+// to be. Until it starts writing metadata it stops at the next checkpoint
+// (between files, and before hashes.json is saved), rolls back the
+// changelog entries and snapshot through the journal and says that nothing
+// was recorded. Once metadata is being written the few remaining steps are
+// finished, the version is committed and only the optional follow-ups
+// (storage, mirrors, publishing, hooks) are skipped. A second Ctrl-C exits
+// at once; the next gitnot then rolls back from the journal.
+
+var interruptFlag atomic.Bool
+
+// errInterrupted is wrapped by the error an update returns when it stopped
+// for Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+// catchInterrupts starts catching Ctrl-C for one update; the returned
+// function stops it.
+func catchInterrupts() (stop func()) {
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ch:
+				if interruptFlag.Swap(true) {
+					fmt.Println("\n⏹  Stopping now; the next gitnot rolls back what was not recorded")
+					os.Exit(130)
+				}
+				fmt.Println("\n⏹  Stopping at the next safe point (Ctrl-C again to quit at once)")
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+		interruptFlag.Store(false)
+	}
+}
+
+func interruptRequested() bool {
+	return interruptFlag.Load()
+}
+
+// stopIfInterrupted is an update checkpoint: after Ctrl-C it undoes the
+// journaled update j (nil before the journal begins) and reports that
+// nothing past version prev was recorded.
+func stopIfInterrupted(j *Journal, prev float64) error {
+	if !interruptRequested() {
+		return nil
+	}
+	if j != nil {
+		if err := abandonUpdate(j); err != nil {
+			return fmt.Errorf("%w, and rolling back failed (the next gitnot retries): %v", errInterrupted, err)
+		}
+		return fmt.Errorf("%w: v%.1f was not recorded, changelogs and snapshot rolled back; still at v%.1f", errInterrupted, j.Version, prev)
+	}
+	return fmt.Errorf("%w: nothing was recorded; still at v%.1f", errInterrupted, prev)
+}
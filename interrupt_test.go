@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInterruptBeforeJournalRecordsNothing(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "chapter.md", "One\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "chapter.md", "Two\n")
+	interruptFlag.Store(true)
+	err := updateGitnot()
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("Expected an interrupted update, got %v", err)
+	}
+	if interruptRequested() {
+		t.Error("The interrupt should be cleared once the update returns")
+	}
+	if v, _ := readVersion(); v != 0.0 {
+		t.Errorf("Nothing should be recorded, got v%.1f", v)
+	}
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.1 {
+		t.Errorf("The next update should record the change, got v%.1f", v)
+	}
+}
+
+func TestInterruptRollsBackJournaledWork(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "chapter.md", "One\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cl := filepath.Join(changelogDir, "chapter.md.log")
+	before, _ := os.ReadFile(cl)
+	j, err := beginJournal("update", 0, 0.1, []string{cl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := appendToFile(cl, "\n## v0.1 – partial\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := stopIfInterrupted(j, 0); err != nil {
+		t.Fatalf("No interrupt pending, got %v", err)
+	}
+
+	interruptFlag.Store(true)
+	defer interruptFlag.Store(false)
+	if err := stopIfInterrupted(j, 0); !errors.Is(err, errInterrupted) {
+		t.Fatalf("Expected an interrupted error, got %v", err)
+	}
+	if after, _ := os.ReadFile(cl); string(after) != string(before) {
+		t.Errorf("Changelog not rolled back:\n%s", after)
+	}
+	if _, err := os.Stat(journalFile); err == nil {
+		t.Error("Rolled-back update left its journal behind")
+	}
+}
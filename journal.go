@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// --- Journal & crash recovery ---
+//
+// An update records what it is about to touch in journal.json before
+// touching it. If the process dies, the next gitnot invocation finds the
+// journal (with no live lock holder) and either finishes the cleanup of a
+// committed update or rolls an uncommitted one back to the previous version.
+
+type Journal struct {
+	Op          string            `json:"op"`
+	PID         int               `json:"pid"`
+	Started     time.Time         `json:"started"`
+	PrevVersion float64           `json:"prev_version"`
+	Version     float64           `json:"version"`
+	Changelogs  map[string]int64  `json:"changelogs"`      // size before the run, -1 if new
+	Moved       map[string]string `json:"moved,omitempty"` // changelogs a folder rename moved, new path → old
+}
+
+func beginJournal(op string, prev, ver float64, changelogs []string) (*Journal, error) {
+	j := &Journal{
+		Op: op, PID: os.Getpid(), Started: repo.Now(),
+		PrevVersion: prev, Version: ver,
+		Changelogs: map[string]int64{},
+	}
+	for _, p := range changelogs {
+		if fi, err := repo.FS.Stat(p); err == nil {
+			j.Changelogs[p] = fi.Size()
+		} else {
+			j.Changelogs[p] = -1
+		}
+	}
+	return j, saveJSON(journalFile, j)
+}
+
+// journalMoves moves the changelogs of files a folder rename moved (new
+// path → old path), noting each in j first so a rollback puts it back and
+// trims it at its old path.
+func journalMoves(j *Journal, moved map[string]string) error {
+	if len(moved) == 0 {
+		return nil
+	}
+	j.Moved = map[string]string{}
+	for to, from := range moved {
+		toLog, fromLog := filepath.Join(changelogDir, to+".log"), filepath.Join(changelogDir, from+".log")
+		fi, err := repo.FS.Stat(fromLog)
+		if err != nil {
+			continue
+		}
+		j.Moved[toLog] = fromLog
+		j.Changelogs[fromLog] = fi.Size()
+		delete(j.Changelogs, toLog)
+	}
+	if err := saveJSON(journalFile, j); err != nil {
+		return err
+	}
+	for _, toLog := range sortedKeys(j.Moved) {
+		if err := moveStoreFile(j.Moved[toLog], toLog); err != nil {
+			return err
+		}
+		removeEmptyParents(j.Moved[toLog], changelogDir)
+	}
+	return nil
+}
+
+// rollBackChangelogs moves back the changelogs j moved and trims every
+// changelog it touched to its size before the run.
+func rollBackChangelogs(j *Journal) {
+	for toLog, fromLog := range j.Moved {
+		if _, err := repo.FS.Stat(toLog); err != nil {
+			continue
+		}
+		if err := safeMkdirAllForFile(fromLog); err == nil && repo.FS.Rename(toLog, fromLog) == nil {
+			removeEmptyParents(toLog, changelogDir)
+		}
+	}
+	for p, size := range j.Changelogs {
+		if size < 0 {
+			_ = repo.FS.Remove(p)
+		} else {
+			_ = repo.FS.Truncate(p, size)
+		}
+	}
+}
+
+func endJournal() error {
+	if err := repo.FS.RemoveAll(snapshotOldDir); err != nil {
+		return err
+	}
+	return repo.FS.Remove(journalFile)
+}
+
+// lockHolderAlive reports whether the lock file belongs to a running process.
+// A young lock that doesn't name its holder yet counts as held.
+func lockHolderAlive() bool {
+	b, fi, err := readLock()
+	return err == nil && holderAlive(b, fi)
+}
+
+func readLock() ([]byte, fs.FileInfo, error) {
+	fi, err := repo.FS.Stat(lockFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := repo.FS.ReadFile(lockFile)
+	return b, fi, err
+}
+
+// holderAlive reports whether lock content b, of a file with info fi, names
+// a running process.
+func holderAlive(b []byte, fi fs.FileInfo) bool {
+	var pid int
+	if _, err := fmt.Sscan(string(b), &pid); err != nil {
+		return time.Since(fi.ModTime()) < lockGrace
+	}
+	return processAlive(pid)
+}
+
+// removeStaleLock removes the lock if it is still the stale one read as b.
+// Commands that find the same stale lock at once could otherwise each take
+// it over, the later one deleting the lock the earlier one had just taken:
+// the check and the removal happen while holding lockFile+".takeover",
+// which is created exclusively, so a command coming second finds the new
+// holder's lock and leaves it alone. A takeover file left by a crash is
+// cleared once it is older than lockGrace.
+func removeStaleLock(b []byte) (bool, error) {
+	guard := lockFile + ".takeover"
+	f, err := repo.FS.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		if fi, err := repo.FS.Stat(guard); err == nil && time.Since(fi.ModTime()) > lockGrace {
+			_ = repo.FS.Remove(guard)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	f.Close()
+	defer repo.FS.Remove(guard)
+	now, fi, err := readLock()
+	if err != nil || !bytes.Equal(now, b) || holderAlive(now, fi) {
+		return false, nil
+	}
+	return true, repo.FS.Remove(lockFile)
+}
+
+// recoverInterrupted repairs the store after a crashed update. It is a no-op
+// when nothing was interrupted or another gitnot process is still running;
+// otherwise it takes the lock for the repair, so two commands starting at
+// once don't both repair.
+func recoverInterrupted() error {
+	if _, err := repo.FS.Stat(gitnotDir); err != nil {
+		return nil
+	}
+	if !leftBehind() {
+		return nil
+	}
+	if b, fi, err := readLock(); err == nil {
+		if holderAlive(b, fi) {
+			return nil
+		}
+		removed, err := removeStaleLock(b)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return nil // another run is taking over
+		}
+		fmt.Println("🩹 Removed stale lock from an interrupted run")
+	}
+	release, err := acquireLock()
+	if errors.Is(err, errLockHeld) {
+		return nil // another run got there first
+	}
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var j Journal
+	switch err := loadJSON(journalFile, &j); {
+	case err == nil:
+		if err := recoverFromJournal(j); err != nil {
+			return err
+		}
+	case errors.Is(err, os.ErrNotExist):
+		// no journal: only leftovers from a crash before journaling started
+	default:
+		fmt.Printf("⚠️  Ignoring unreadable journal: %v\n", err)
+		_ = repo.FS.Remove(journalFile)
+	}
+
+	_ = repo.FS.RemoveAll(snapshotTmpDir)
+	if _, err := repo.FS.Stat(snapshotDir); errors.Is(err, os.ErrNotExist) {
+		if _, err := repo.FS.Stat(snapshotOldDir); err == nil {
+			if err := repo.FS.Rename(snapshotOldDir, snapshotDir); err != nil {
+				return err
+			}
+			fmt.Println("🩹 Restored snapshot left behind by an interrupted run")
+		} else {
+			return rebuildSnapshot()
+		}
+	}
+	return nil
+}
+
+// leftBehind reports whether an earlier run left anything to repair, so
+// commands on an intact store don't need the lock.
+func leftBehind() bool {
+	for _, p := range []string{journalFile, lockFile, snapshotTmpDir} {
+		if _, err := repo.FS.Stat(p); err == nil {
+			return true
+		}
+	}
+	_, err := repo.FS.Stat(snapshotDir)
+	return errors.Is(err, os.ErrNotExist)
+}
+
+func recoverFromJournal(j Journal) error {
+	if j.Op == opAdopt {
+		return recoverAdoption(j)
+	}
+	v, err := readVersion()
+	if err != nil {
+		return err
+	}
+	if v == j.Version {
+		// committed; only the cleanup was interrupted
+		if err := endJournal(); err != nil {
+			return err
+		}
+		fmt.Printf("🩹 Finished cleanup of interrupted %s to v%.1f\n", j.Op, j.Version)
+		return nil
+	}
+
+	rollBackChangelogs(&j)
+	_ = repo.FS.Remove(manifestPath(j.Version))
+	if prev, err := committedHashes(j.PrevVersion); err == nil {
+		// adoptions and remaps made before the update still apply
+		if err := saveJSON(hashesFile, remapKeys(withAdopted(prev, j.PrevVersion), pendingRemaps(j.PrevVersion))); err != nil {
+			return err
+		}
+	}
+	if _, err := repo.FS.Stat(snapshotOldDir); err == nil {
+		if err := repo.FS.RemoveAll(snapshotDir); err != nil {
+			return err
+		}
+		if err := repo.FS.Rename(snapshotOldDir, snapshotDir); err != nil {
+			return err
+		}
+	}
+	_ = repo.FS.RemoveAll(snapshotTmpDir)
+	if err := repo.FS.Remove(journalFile); err != nil {
+		return err
+	}
+	fmt.Printf("🩹 Rolled back interrupted %s (v%.1f was not committed; still at v%.1f)\n", j.Op, j.Version, v)
+	return nil
+}
+
+// rebuildSnapshot recreates a missing snapshot from working files whose
+// content still matches the committed hashes.
+func rebuildSnapshot() error {
+	hashes := loadCommittedHashes()
+	if err := repo.FS.MkdirAll(snapshotDir, 0o755); err != nil {
+		return err
+	}
+	restored, missing := 0, 0
+	for rel, h := range hashes {
+		if hashFile(rel) != h {
+			missing++
+			continue
+		}
+		if err := copyFile(rel, filepath.Join(snapshotDir, rel)); err != nil {
+			missing++
+			continue
+		}
+		restored++
+	}
+	fmt.Printf("🩹 Snapshot folder was missing; rebuilt %d files from the working tree\n", restored)
+	if missing > 0 {
+		fmt.Printf("⚠️  %d files changed since the last version; their next diff will be skipped\n", missing)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecoverRollsBackUncommittedUpdate(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.txt", "original\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	clPath := filepath.Join(changelogDir, "a.txt.log")
+	before, _ := os.ReadFile(clPath)
+
+	// Simulate an update to v0.1 that died after swapping the snapshot
+	createTestFile(t, "a.txt", "edited\n")
+	if _, err := beginJournal("update", 0.0, 0.1, []string{clPath}); err != nil {
+		t.Fatalf("beginJournal failed: %v", err)
+	}
+	if err := appendToFile(clPath, "\n## v0.1 – partial\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(snapshotDir, snapshotOldDir); err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, filepath.Join(snapshotDir, "a.txt"), "edited\n")
+	if err := writeManifest(Manifest{Version: 0.1, Files: map[string]string{"a.txt": "x"}}); err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, lockFile, "0 stale\n")
+
+	if err := recoverInterrupted(); err != nil {
+		t.Fatalf("recoverInterrupted failed: %v", err)
+	}
+
+	after, _ := os.ReadFile(clPath)
+	if string(after) != string(before) {
+		t.Errorf("Changelog not rolled back: %q", after)
+	}
+	snap, _ := os.ReadFile(filepath.Join(snapshotDir, "a.txt"))
+	if string(snap) != "original\n" {
+		t.Errorf("Snapshot not restored, got %q", snap)
+	}
+	for _, p := range []string{journalFile, lockFile, snapshotOldDir, manifestPath(0.1)} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", p)
+		}
+	}
+
+	// The store is usable again
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot after recovery failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.1 {
+		t.Errorf("Expected v0.1 after update, got v%.1f", v)
+	}
+}
+
+func TestRecoverRebuildsMissingSnapshot(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.txt", "same\n")
+	createTestFile(t, "b.txt", "before\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	os.RemoveAll(snapshotDir)
+	createTestFile(t, "b.txt", "after\n")
+
+	if err := recoverInterrupted(); err != nil {
+		t.Fatalf("recoverInterrupted failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(snapshotDir, "a.txt")); err != nil {
+		t.Error("Unchanged file should be restored into the snapshot")
+	}
+	if _, err := os.Stat(filepath.Join(snapshotDir, "b.txt")); !os.IsNotExist(err) {
+		t.Error("Changed file must not be snapshotted with its new content")
+	}
+}
+
+func TestRecoverLeavesALiveUpdateAlone(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.txt", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	release, err := acquireLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	// an update of this process is midway: journal and new snapshot written
+	if _, err := beginJournal("update", 0.0, 0.1, nil); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(snapshotTmpDir, 0o755)
+	if err := recoverInterrupted(); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{lockFile, journalFile, snapshotTmpDir} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("Expected %s to be left for the running update", p)
+		}
+	}
+}
+
+func TestStaleLockIsTakenOverOnce(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.txt", "a\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	stale := []byte("0 stale\n")
+
+	// a second command read the same stale lock, but the first one has
+	// already replaced it with its own
+	release, err := acquireLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed, err := removeStaleLock(stale); removed || err != nil {
+		t.Errorf("Expected the new holder's lock to be left alone, got %v, %v", removed, err)
+	}
+	if !lockHolderAlive() {
+		t.Error("Expected the lock to still name its live holder")
+	}
+	release()
+
+	// while another command is taking over, this one steps back
+	createTestFile(t, lockFile, string(stale))
+	createTestFile(t, lockFile+".takeover", "")
+	if removed, _ := removeStaleLock(stale); removed {
+		t.Error("Expected no takeover while another is under way")
+	}
+	os.Remove(lockFile + ".takeover")
+
+	if removed, err := removeStaleLock(stale); !removed || err != nil {
+		t.Errorf("Expected the stale lock to be removed, got %v, %v", removed, err)
+	}
+	for _, p := range []string{lockFile, lockFile + ".takeover"} {
+		if _, err := os.Stat(p); err == nil {
+			t.Errorf("Expected %s to be gone", p)
+		}
+	}
+}
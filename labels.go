@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// --- Labels ---
+//
+// labels.json maps tracked paths to free-form labels ("draft", "final") that
+// filter commands such as `status --label`. Labels follow a file when an
+// update sees it renamed (same content under a new path).
+
+func loadLabels() map[string][]string {
+	labels := map[string][]string{}
+	_ = loadJSON(labelsFile, &labels)
+	return labels
+}
+
+func saveLabels(labels map[string][]string) error {
+	for p, ls := range labels {
+		if len(ls) == 0 {
+			delete(labels, p)
+		}
+	}
+	return saveJSON(labelsFile, labels)
+}
+
+func hasLabel(labels map[string][]string, p, label string) bool {
+	for _, l := range labels[p] {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// filterByLabel keeps only paths carrying label.
+func filterByLabel(paths []string, labels map[string][]string, label string) []string {
+	var out []string
+	for _, p := range paths {
+		if hasLabel(labels, p, label) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// carryLabelsAcrossRenames moves labels from deleted paths to new paths with
+// identical content and reports whether anything moved.
+func carryLabelsAcrossRenames(labels map[string][]string, newFiles, deletedFiles []string, current, old map[string]string) bool {
+	moved := false
+	for d, n := range detectRenames(newFiles, deletedFiles, current, old) {
+		if ls, ok := labels[d]; ok {
+			labels[n] = mergeLabels(labels[n], ls)
+			delete(labels, d)
+			moved = true
+		}
+	}
+	return moved
+}
+
+func mergeLabels(a, b []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, l := range append(append([]string{}, a...), b...) {
+		if !seen[l] {
+			seen[l] = true
+			out = append(out, l)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func runLabel(args []string) error {
+	fs := flag.NewFlagSet("label", flag.ExitOnError)
+	remove := fs.Bool("remove", false, "remove the given labels instead of adding them")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	labels := loadLabels()
+
+	switch len(rest) {
+	case 0:
+		if len(labels) == 0 {
+			fmt.Println("🏷️  No labels yet")
+			return nil
+		}
+		var paths []string
+		for p := range labels {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			fmt.Printf("  • %s: %s\n", p, strings.Join(labels[p], ", "))
+		}
+		return nil
+	case 1:
+		rel := filepath.Clean(rest[0])
+		fmt.Printf("🏷️  %s: %s\n", rel, strings.Join(labels[rel], ", "))
+		return nil
+	}
+
+	rel := filepath.Clean(rest[0])
+	if *remove {
+		drop := map[string]bool{}
+		for _, l := range rest[1:] {
+			drop[l] = true
+		}
+		var kept []string
+		for _, l := range labels[rel] {
+			if !drop[l] {
+				kept = append(kept, l)
+			}
+		}
+		labels[rel] = kept
+	} else {
+		labels[rel] = mergeLabels(labels[rel], rest[1:])
+	}
+	if err := saveLabels(labels); err != nil {
+		return err
+	}
+	fmt.Printf("🏷️  %s: %s\n", rel, strings.Join(labels[rel], ", "))
+	return nil
+}
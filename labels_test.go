@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLabelsFilterAndFollowRenames(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "chapter1.md", "once upon a time")
+	createTestFile(t, "notes.md", "todo")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := runLabel([]string{"chapter1.md", "draft", "fiction"}); err != nil {
+		t.Fatalf("label failed: %v", err)
+	}
+	labels := loadLabels()
+	if !hasLabel(labels, "chapter1.md", "draft") || !hasLabel(labels, "chapter1.md", "fiction") {
+		t.Fatalf("Labels not stored: %v", labels)
+	}
+
+	got := filterByLabel([]string{"chapter1.md", "notes.md"}, labels, "draft")
+	if len(got) != 1 || got[0] != "chapter1.md" {
+		t.Errorf("Expected only chapter1.md, got %v", got)
+	}
+
+	// Rename without content change: labels move with the file
+	if err := os.Rename("chapter1.md", "chapter-one.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	labels = loadLabels()
+	if !hasLabel(labels, "chapter-one.md", "draft") {
+		t.Errorf("Labels should follow the rename, got %v", labels)
+	}
+	if _, ok := labels["chapter1.md"]; ok {
+		t.Error("Old path should no longer carry labels")
+	}
+
+	if err := runLabel([]string{"chapter-one.md", "fiction", "--remove"}); err != nil {
+		t.Fatalf("label --remove failed: %v", err)
+	}
+	if hasLabel(loadLabels(), "chapter-one.md", "fiction") {
+		t.Error("Label should have been removed")
+	}
+}
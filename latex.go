@@ -0,0 +1,239 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/codinganovel/go-difflib/difflib"
+)
+
+// --- LaTeX ---
+//
+// The LaTeX driver diffs documents with comments blanked out, so commenting
+// a paragraph in or out of a draft note doesn't read as a rewrite, and heads
+// the entry with the sections and environments the change touched. BibTeX
+// files are compared entry by entry.
+
+var (
+	latexSectionRe = regexp.MustCompile(`\\(part|chapter|section|subsection|subsubsection)\*?\s*(?:\[[^\]]*\])?\s*\{([^}]*)\}`)
+	latexBeginRe   = regexp.MustCompile(`\\begin\{([^}]+)\}`)
+	latexEndRe     = regexp.MustCompile(`\\end\{([^}]+)\}`)
+	bibEntryRe     = regexp.MustCompile(`(?m)^\s*@(\w+)\s*[{(]\s*([^,\s]+)\s*,`)
+)
+
+// stripLatexComment removes an unescaped % comment from a line, keeping the
+// line ending.
+func stripLatexComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++ // skip the escaped character, e.g. \%
+		case '%':
+			if strings.HasSuffix(line, "\n") {
+				return line[:i] + "\n"
+			}
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// latexOutline records, for every line, the section and innermost
+// environment it sits in.
+type latexOutline struct {
+	sections []string // titles in document order
+	section  []string // per line
+	env      []string // per line, "" outside environments (document excluded)
+}
+
+func outlineLatex(lines []string) latexOutline {
+	var o latexOutline
+	cur := ""
+	var stack []string
+	for _, l := range lines {
+		if m := latexSectionRe.FindStringSubmatch(l); m != nil {
+			cur = strings.TrimSpace(m[2])
+			o.sections = append(o.sections, cur)
+		}
+		for _, m := range latexBeginRe.FindAllStringSubmatch(l, -1) {
+			if m[1] != "document" {
+				stack = append(stack, m[1])
+			}
+		}
+		env := ""
+		if len(stack) > 0 {
+			env = stack[len(stack)-1]
+		}
+		o.section = append(o.section, cur)
+		o.env = append(o.env, env)
+		for _, m := range latexEndRe.FindAllStringSubmatch(l, -1) {
+			if len(stack) > 0 && stack[len(stack)-1] == m[1] {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return o
+}
+
+// latexDiff summarises a LaTeX change by section and environment and
+// appends the comment-free line diff.
+func latexDiff(oldB, newB []byte) (string, bool) {
+	a := difflib.SplitLines(string(oldB))
+	b := difflib.SplitLines(string(newB))
+	for i := range a {
+		a[i] = stripLatexComment(a[i])
+	}
+	for i := range b {
+		b[i] = stripLatexComment(b[i])
+	}
+	if strings.Join(a, "") == strings.Join(b, "") {
+		return "📄 Only comments changed\n", true
+	}
+
+	oa, ob := outlineLatex(a), outlineLatex(b)
+	oldSecs, newSecs := countSet(oa.sections), countSet(ob.sections)
+	var added, removed []string
+	for _, s := range ob.sections {
+		if oldSecs[s] == 0 {
+			added = append(added, s)
+		}
+	}
+	for _, s := range oa.sections {
+		if newSecs[s] == 0 {
+			removed = append(removed, s)
+		}
+	}
+	changed := map[string]bool{}
+	envs := map[string]bool{}
+	touch := func(o latexOutline, i int) {
+		if sec := o.section[i]; sec != "" && oldSecs[sec] > 0 && newSecs[sec] > 0 {
+			changed[sec] = true
+		}
+		if env := o.env[i]; env != "" {
+			envs[env] = true
+		}
+	}
+	for _, op := range difflib.NewMatcher(a, b).GetOpCodes() {
+		if op.Tag == 'e' {
+			continue
+		}
+		for i := op.I1; i < op.I2; i++ {
+			if strings.TrimSpace(a[i]) != "" {
+				touch(oa, i)
+			}
+		}
+		for j := op.J1; j < op.J2; j++ {
+			if strings.TrimSpace(b[j]) != "" {
+				touch(ob, j)
+			}
+		}
+	}
+
+	var sb strings.Builder
+	if len(added)+len(removed)+len(changed)+len(envs) > 0 {
+		sb.WriteString("### 📑 Structure\n")
+		if len(added) > 0 {
+			sb.WriteString("Sections added: " + strings.Join(added, ", ") + "\n")
+		}
+		if len(removed) > 0 {
+			sb.WriteString("Sections removed: " + strings.Join(removed, ", ") + "\n")
+		}
+		if len(changed) > 0 {
+			sb.WriteString("Sections changed: " + strings.Join(inOrder(changed, ob.sections), ", ") + "\n")
+		}
+		if len(envs) > 0 {
+			sb.WriteString("Environments changed: " + strings.Join(sortedSet(envs), ", ") + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(formatDiffAsMarkdown(unifiedDiffLines(a, b)))
+	return sb.String(), true
+}
+
+// bibDiff reports BibTeX entries added, removed or changed, by citation key.
+func bibDiff(oldB, newB []byte) (string, bool) {
+	a, b := bibEntries(string(oldB)), bibEntries(string(newB))
+	var added, removed, changed []string
+	for k, v := range b {
+		if old, ok := a[k]; !ok {
+			added = append(added, k)
+		} else if old != v {
+			changed = append(changed, k)
+		}
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	if len(added)+len(removed)+len(changed) == 0 {
+		return "📄 Only comments or formatting changed\n", true
+	}
+	var sb strings.Builder
+	for _, sec := range []struct {
+		title string
+		keys  []string
+	}{{"### ➕ Entries added\n", added}, {"### ➖ Entries removed\n", removed}, {"### ✏️ Entries changed\n", changed}} {
+		if len(sec.keys) == 0 {
+			continue
+		}
+		sort.Strings(sec.keys)
+		sb.WriteString(sec.title)
+		sb.WriteString(strings.Join(sec.keys, ", "))
+		sb.WriteString("\n\n")
+	}
+	return sb.String(), true
+}
+
+// bibEntries maps citation keys to their whitespace-normalised entry text.
+func bibEntries(text string) map[string]string {
+	var kept []string
+	for _, l := range strings.Split(text, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(l), "%") {
+			kept = append(kept, l)
+		}
+	}
+	text = strings.Join(kept, "\n")
+	out := map[string]string{}
+	locs := bibEntryRe.FindAllStringSubmatchIndex(text, -1)
+	for i, loc := range locs {
+		end := len(text)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		key := text[loc[4]:loc[5]]
+		out[key] = strings.Join(strings.Fields(text[loc[0]:end]), " ")
+	}
+	return out
+}
+
+func countSet(ss []string) map[string]int {
+	m := map[string]int{}
+	for _, s := range ss {
+		m[s]++
+	}
+	return m
+}
+
+func sortedSet(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// inOrder lists the members of set in the order they appear in order.
+func inOrder(set map[string]bool, order []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, s := range order {
+		if set[s] && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripLatexComment(t *testing.T) {
+	for in, want := range map[string]string{
+		"text % note\n":     "text \n",
+		"50\\% of it\n":     "50\\% of it\n",
+		"% whole line":      "",
+		"a \\\\% comment\n": "a \\\\\n",
+	} {
+		if got := stripLatexComment(in); got != want {
+			t.Errorf("stripLatexComment(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLatexDiff(t *testing.T) {
+	oldDoc := "\\section{Intro}\nHello.\n% TODO expand\n\\section{Method}\n\\begin{equation}\na = b\n\\end{equation}\n\\section{Old}\nGone.\n"
+
+	md, ok := latexDiff([]byte(oldDoc), []byte(strings.Replace(oldDoc, "% TODO expand", "% done", 1)))
+	if !ok || !strings.Contains(md, "Only comments changed") {
+		t.Errorf("Comment-only change not recognised:\n%s", md)
+	}
+
+	newDoc := "\\section{Intro}\nHello.\n\\section{Method}\n\\begin{equation}\na = c\n\\end{equation}\n\\section{Results}\nNew.\n"
+	md, _ = latexDiff([]byte(oldDoc), []byte(newDoc))
+	for _, want := range []string{"Sections added: Results", "Sections removed: Old", "Sections changed: Method", "Environments changed: equation", "a = c"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected %q in:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "TODO") {
+		t.Errorf("Comments should not appear in the diff:\n%s", md)
+	}
+}
+
+func TestBibDiff(t *testing.T) {
+	oldBib := "@article{knuth84,\n  title={Literate Programming}\n}\n% note\n@book{lamport94, title={LaTeX}}\n"
+	newBib := "@article{knuth84,\n  title = {Literate Programming},\n  year={1984}\n}\n@misc{web, title={Site}}\n"
+	md, _ := bibDiff([]byte(oldBib), []byte(newBib))
+	for _, want := range []string{"Entries added\nweb", "Entries removed\nlamport94", "Entries changed\nknuth84"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected %q in:\n%s", want, md)
+		}
+	}
+	if md, _ := bibDiff([]byte(oldBib), []byte(strings.Replace(oldBib, "% note", "% other", 1))); !strings.Contains(md, "Only comments") {
+		t.Errorf("Comment-only change not recognised:\n%s", md)
+	}
+}
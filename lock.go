@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// --- Locking & consistent reads ---
+//
+// Writers (update) hold an exclusive lock file for the whole run and publish
+// version.txt last. Readers never take the lock: they read the committed
+// version and the manifest written for it, both of which are replaced
+// atomically, so a concurrent update can't hand them a half-written tree.
+// Only when a reader finds a crashed run to repair, or a store format to
+// upgrade, does it take the lock for that.
+
+// lockGrace is how long a lock file that doesn't name its holder yet is
+// taken as held: one written in place, where hard links aren't supported,
+// is empty for a moment after it is created.
+const lockGrace = 10 * time.Second
+
+var errLockHeld = errors.New("another gitnot update is in progress")
+
+// acquireLock takes the store lock. The holder's PID is written to a
+// temporary file that is then linked into place, so the lock never exists
+// without it.
+func acquireLock() (release func(), err error) {
+	tmp, err := repo.FS.CreateTemp(filepath.Dir(lockFile), "."+filepath.Base(lockFile)+".tmp*")
+	if err != nil {
+		return nil, err
+	}
+	defer repo.FS.Remove(tmp.Name())
+	fmt.Fprintf(tmp, "%d %s\n", os.Getpid(), repo.Now().Format(time.RFC3339))
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	err = repo.FS.Link(tmp.Name(), lockFile)
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		// no hard links here: create it in place and write the PID after
+		var f File
+		if f, err = repo.FS.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644); err == nil {
+			fmt.Fprintf(f, "%d %s\n", os.Getpid(), repo.Now().Format(time.RFC3339))
+			f.Close()
+		}
+	}
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%w (remove %s if it is stale)", errLockHeld, lockFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func() { _ = repo.FS.Remove(lockFile) }, nil
+}
+
+// committedHashes returns the tracked tree recorded for version v, falling
+// back to hashes.json for stores that predate manifests.
+func committedHashes(v float64) (map[string]string, error) {
+	var m Manifest
+	if err := loadJSON(manifestPath(v), &m); err == nil && m.Files != nil {
+		return m.Files, nil
+	}
+	hashes := map[string]string{}
+	if err := loadJSON(hashesFile, &hashes); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+func loadCommittedHashes() map[string]string {
+	v, err := readVersion()
+	if err != nil {
+		return map[string]string{}
+	}
+	hashes, err := committedHashes(v)
+	if err != nil {
+		return map[string]string{}
+	}
+	return remapKeys(withAdopted(hashes, v), pendingRemaps(v))
+}
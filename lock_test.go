@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdateLock(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.txt", "a")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	release, err := acquireLock()
+	if err != nil {
+		t.Fatalf("acquireLock failed: %v", err)
+	}
+	createTestFile(t, "a.txt", "changed")
+	if err := updateGitnot(); err == nil || !strings.Contains(err.Error(), "in progress") {
+		t.Errorf("Expected update to refuse while locked, got: %v", err)
+	}
+	release()
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed after release: %v", err)
+	}
+}
+
+func TestReadersUseCommittedVersion(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.txt", "a")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	// Simulate an update that has rewritten hashes.json but not yet committed
+	if err := saveJSON(hashesFile, map[string]string{"half.txt": "x"}); err != nil {
+		t.Fatalf("saveJSON failed: %v", err)
+	}
+	hashes := loadCommittedHashes()
+	if _, ok := hashes["a.txt"]; !ok || len(hashes) != 1 {
+		t.Errorf("Expected committed tree {a.txt}, got %v", hashes)
+	}
+}
+
+func TestLockNamesItsHolder(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.txt", "a")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	release, err := acquireLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(lockFile)
+	if !strings.HasPrefix(string(b), fmt.Sprintf("%d ", os.Getpid())) {
+		t.Errorf("Expected the lock to hold our PID from the start, got %q", b)
+	}
+	release()
+
+	// a lock created in place that isn't written yet is held while young
+	os.WriteFile(lockFile, nil, 0o644)
+	if err := recoverInterrupted(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(lockFile); err != nil {
+		t.Fatal("Expected a young empty lock to be left alone")
+	}
+	old := time.Now().Add(-2 * lockGrace)
+	os.Chtimes(lockFile, old, old)
+	recoverInterrupted()
+	if _, err := os.Stat(lockFile); err == nil {
+		t.Error("Expected an old empty lock to be removed as stale")
+	}
+}
+
+// hidingFS reports one path as missing.
+type hidingFS struct {
+	osFS
+	target string
+}
+
+func (f hidingFS) Stat(name string) (fs.FileInfo, error) {
+	if filepath.Clean(name) == filepath.Clean(f.target) {
+		return nil, os.ErrNotExist
+	}
+	return f.osFS.Stat(name)
+}
+
+func TestUpdateFailsWithoutSnapshot(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.txt", "a")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	// the snapshot goes missing once the update is under way
+	useRepo(t, Repo{Clock: systemClock{}, FS: hidingFS{target: snapshotDir}})
+	createTestFile(t, "a.txt", "changed")
+	if err := updateGitnot(); err == nil || !strings.Contains(err.Error(), "snapshot folder missing") {
+		t.Fatalf("Expected the update to fail, got %v", err)
+	}
+	for _, p := range []string{journalFile, lockFile} {
+		if _, err := os.Stat(p); err == nil {
+			t.Errorf("Expected %s to be cleaned up", p)
+		}
+	}
+	if v, _ := readVersion(); v != 0.0 {
+		t.Errorf("Expected to stay at v0.0, got v%.1f", v)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// --- Files in use ---
+//
+// On Windows, Word, Excel and friends can hold a document open so that
+// nobody else may read it. Such a file is retried like any transient error
+// and, if it is still locked, skipped for this run: status lists it as "in
+// use" instead of modified, and an update carries its recorded content
+// forward rather than storing an unreadable stand-in. The "locked_files"
+// setting picks how hard gitnot tries first:
+//
+//   - "skip" (default): open the file normally.
+//   - "share": open it with every sharing mode allowed and backup semantics,
+//     which gets past editors that only deny deletion or renaming.
+//   - "shadow": like "share", then read a locked file from a volume shadow
+//     copy made for the run (needs administrator rights).
+//
+// Elsewhere files are not locked this way and the setting has no effect.
+
+const (
+	lockedSkip   = "skip"
+	lockedShare  = "share"
+	lockedShadow = "shadow"
+)
+
+var (
+	lockedPolicy = lockedSkip
+)
+
+// useLockedFiles applies the locked_files setting for the rest of the
+// process.
+func useLockedFiles(policy string) {
+	retryMu.Lock()
+	defer retryMu.Unlock()
+	lockedPolicy = policy
+	if policy == "" {
+		lockedPolicy = lockedSkip
+	}
+}
+
+// isInUse reports whether err means another program holds the file.
+func isInUse(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	for _, e := range inUseErrnos {
+		if errno == e {
+			return true
+		}
+	}
+	return false
+}
+
+// openSource opens a working file for reading the way locked_files asks.
+func openSource(p string) (*os.File, error) {
+	retryMu.Lock()
+	policy := lockedPolicy
+	retryMu.Unlock()
+	if policy == lockedSkip {
+		return os.Open(longPath(p))
+	}
+	f, err := openShared(longPath(p))
+	if err != nil && policy == lockedShadow && isInUse(err) {
+		sf, serr := openFromShadow(p)
+		if serr == nil {
+			return sf, nil
+		}
+		fmt.Printf("⚠️  Warning: Could not read %s from a shadow copy: %v\n", filepath.ToSlash(p), serr)
+	}
+	return f, err
+}
+
+func validateLockedFiles(c Config) []configIssue {
+	switch c.LockedFiles {
+	case "", lockedSkip, lockedShare:
+	case lockedShadow:
+		if !shadowSupported {
+			return []configIssue{{"locked_files", "shadow copies only exist on Windows; files are opened as with \"share\""}}
+		}
+	default:
+		return []configIssue{{"locked_files", fmt.Sprintf("%q is not one of %q, %q, %q", c.LockedFiles, lockedSkip, lockedShare, lockedShadow)}}
+	}
+	return nil
+}
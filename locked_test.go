@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestIsInUse(t *testing.T) {
+	saved := inUseErrnos
+	t.Cleanup(func() { inUseErrnos = saved })
+	inUseErrnos = []syscall.Errno{syscall.EBUSY}
+
+	if !isInUse(&os.PathError{Op: "open", Path: "report.docx", Err: syscall.EBUSY}) {
+		t.Error("EBUSY should count as in use here")
+	}
+	if isInUse(&os.PathError{Op: "open", Path: "report.docx", Err: syscall.EIO}) {
+		t.Error("EIO is not a lock")
+	}
+}
+
+func TestValidateLockedFiles(t *testing.T) {
+	for _, v := range []string{"", lockedSkip, lockedShare} {
+		if issues := validateLockedFiles(Config{LockedFiles: v}); len(issues) != 0 {
+			t.Errorf("%q: unexpected issues %v", v, issues)
+		}
+	}
+	if issues := validateLockedFiles(Config{LockedFiles: "wait"}); len(issues) != 1 {
+		t.Errorf("Expected an issue for an unknown value, got %v", issues)
+	}
+}
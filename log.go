@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- Log ---
+//
+// `gitnot log` lists versions newest first from the manifests. With --daily
+// it groups changes by day instead: edits to a daily note (journals/2024_05_01.md,
+// 2024-05-01.md) count towards the date in its name, everything else towards
+// the day it was recorded.
+
+var journalNameRe = regexp.MustCompile(`^(\d{4})[-_.](\d{2})[-_.](\d{2})\.(md|org|txt)$`)
+
+// journalDate returns the date a daily-note path refers to.
+func journalDate(rel string) (time.Time, bool) {
+	m := journalNameRe.FindStringSubmatch(strings.ToLower(filepath.Base(rel)))
+	if m == nil {
+		return time.Time{}, false
+	}
+	d, err := time.ParseInLocation("2006-01-02", m[1]+"-"+m[2]+"-"+m[3], time.Local)
+	return d, err == nil
+}
+
+// DayFile is one file's activity within a day of the daily log.
+type DayFile struct {
+	Path         string
+	Journal      bool
+	Versions     []float64
+	WordsAdded   int
+	WordsRemoved int
+}
+
+// DayLog collects everything attributed to one calendar day.
+type DayLog struct {
+	Date  string // YYYY-MM-DD
+	Files []DayFile
+}
+
+// dailyLog groups manifest changes by day, newest day first. Within a day
+// the journal comes first, then other files by path.
+func dailyLog(manifests []Manifest) []DayLog {
+	days := map[string]map[string]*DayFile{}
+	for _, m := range manifests {
+		for _, c := range m.Changes {
+			day := m.Timestamp.Local().Format("2006-01-02")
+			jd, journal := journalDate(c.Path)
+			if journal {
+				day = jd.Format("2006-01-02")
+			}
+			if days[day] == nil {
+				days[day] = map[string]*DayFile{}
+			}
+			f := days[day][c.Path]
+			if f == nil {
+				f = &DayFile{Path: c.Path, Journal: journal}
+				days[day][c.Path] = f
+			}
+			f.Versions = append(f.Versions, m.Version)
+			f.WordsAdded += c.WordsAdded
+			f.WordsRemoved += c.WordsRemoved
+		}
+	}
+	var out []DayLog
+	for day, files := range days {
+		dl := DayLog{Date: day}
+		for _, f := range files {
+			dl.Files = append(dl.Files, *f)
+		}
+		sort.Slice(dl.Files, func(i, j int) bool {
+			a, b := dl.Files[i], dl.Files[j]
+			if a.Journal != b.Journal {
+				return a.Journal
+			}
+			return a.Path < b.Path
+		})
+		out = append(out, dl)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date > out[j].Date })
+	return out
+}
+
+func versionRange(vs []float64) string {
+	if len(vs) == 1 {
+		return fmt.Sprintf("v%.1f", vs[0])
+	}
+	return fmt.Sprintf("v%.1f–v%.1f", vs[0], vs[len(vs)-1])
+}
+
+func runLog(args []string) error {
+	fs := flag.NewFlagSet("log", flag.ExitOnError)
+	daily := fs.Bool("daily", false, "group changes by journal date instead of by version")
+	pick := fs.Bool("pick", false, "choose the file from a list")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 1 {
+		return fmt.Errorf("usage: gitnot log [file | --pick] [--daily]")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if *pick && len(rest) == 0 {
+		rel, err := pickFile()
+		if err != nil {
+			return err
+		}
+		rest = []string{rel}
+	}
+	manifests, err := loadManifestHeads()
+	if err != nil {
+		return err
+	}
+	if len(rest) == 1 {
+		manifests = onlyPath(manifests, filepath.Clean(rest[0]))
+	}
+	if len(manifests) == 0 {
+		fmt.Println("📭 No recorded history yet")
+		return nil
+	}
+
+	if *daily {
+		for _, day := range dailyLog(manifests) {
+			fmt.Printf("📅 %s\n", day.Date)
+			for _, f := range day.Files {
+				icon := "📝"
+				if f.Journal {
+					icon = "📓"
+				}
+				fmt.Printf("  %s %s (%s) +%d/-%d words\n", icon, f.Path, versionRange(f.Versions), f.WordsAdded, f.WordsRemoved)
+			}
+		}
+		return nil
+	}
+
+	tags := loadTags()
+	for i := len(manifests) - 1; i >= 0; i-- {
+		m := manifests[i]
+		extra := ""
+		if m.ID != "" {
+			extra += ", id " + m.ID
+		}
+		if names := tagsOf(tags, m.Version); len(names) > 0 {
+			extra += ", tags " + strings.Join(names, " ")
+		}
+		if m.GitHead != "" {
+			extra += ", git " + shortHash(m.GitHead)
+		}
+		if m.Author != nil {
+			extra += ", by " + m.Author.Name
+		}
+		if m.Env != nil {
+			extra += ", " + m.Env.String()
+		}
+		fmt.Printf("🏷️  v%.1f – %s (%d files changed%s)\n", m.Version, m.Timestamp.Local().Format("2006-01-02 15:04"), len(m.Changes), extra)
+		if m.Message != "" {
+			fmt.Printf("  💬 %s\n", m.Message)
+		}
+		for _, c := range m.Changes {
+			fmt.Printf("  %-8s %s +%d/-%d words\n", c.State, c.Path, c.WordsAdded, c.WordsRemoved)
+		}
+	}
+	return nil
+}
+
+// onlyPath trims each manifest's changes to rel and drops versions that
+// didn't touch it.
+func onlyPath(manifests []Manifest, rel string) []Manifest {
+	var out []Manifest
+	for _, m := range manifests {
+		var cs []FileChange
+		for _, c := range m.Changes {
+			if c.Path == rel {
+				cs = append(cs, c)
+			}
+		}
+		if len(cs) > 0 {
+			m.Changes = cs
+			out = append(out, m)
+		}
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournalDate(t *testing.T) {
+	for rel, want := range map[string]string{
+		"journals/2024_05_01.md": "2024-05-01",
+		"daily/2024-05-02.md":    "2024-05-02",
+		"2024.05.03.org":         "2024-05-03",
+	} {
+		d, ok := journalDate(rel)
+		if !ok || d.Format("2006-01-02") != want {
+			t.Errorf("journalDate(%q) = %v, %v; want %s", rel, d, ok, want)
+		}
+	}
+	if _, ok := journalDate("pages/Project.md"); ok {
+		t.Error("Regular page detected as a daily note")
+	}
+}
+
+func TestDailyLog(t *testing.T) {
+	recorded := time.Date(2024, 5, 2, 9, 0, 0, 0, time.Local)
+	manifests := []Manifest{
+		{Version: 0.1, Timestamp: recorded, Changes: []FileChange{
+			{Path: "journals/2024_05_01.md", WordsAdded: 10},
+			{Path: "pages/Project.md", WordsAdded: 3},
+		}},
+		{Version: 0.2, Timestamp: recorded.Add(time.Hour), Changes: []FileChange{
+			{Path: "journals/2024_05_01.md", WordsAdded: 5, WordsRemoved: 1},
+		}},
+	}
+	days := dailyLog(manifests)
+	if len(days) != 2 || days[0].Date != "2024-05-02" || days[1].Date != "2024-05-01" {
+		t.Fatalf("Unexpected days: %+v", days)
+	}
+	j := days[1].Files[0]
+	if !j.Journal || j.WordsAdded != 15 || j.WordsRemoved != 1 || versionRange(j.Versions) != "v0.1–v0.2" {
+		t.Errorf("Journal edits not grouped under their date: %+v", j)
+	}
+	if days[0].Files[0].Path != "pages/Project.md" {
+		t.Errorf("Page edits should be filed under the recording day: %+v", days[0])
+	}
+}
+
+func TestLogseqIgnoredProperties(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "logseq/config.edn", "{}")
+	createTestFile(t, "logseq/bak/pages/Old.md", "backup")
+	createTestFile(t, "pages/Project.md", "- Plan\n  collapsed:: false\n- Write\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	files, _ := getAllTextFiles(".")
+	if strings.Contains(strings.Join(files, " "), "bak") {
+		t.Errorf("Logseq backups should be ignored, got %v", files)
+	}
+
+	createTestFile(t, "pages/Project.md", "- Plan\n  collapsed:: true\n- Write\n  id:: 6650a1b2-0000\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	b, _ := os.ReadFile(".gitnot/changelogs/pages/Project.md.log")
+	log := string(b)
+	if strings.Contains(log, "collapsed::") || strings.Contains(log, "id::") {
+		t.Errorf("Ignored properties leaked into the changelog:\n%s", log)
+	}
+	if !strings.Contains(log, "Only ignored properties") {
+		t.Errorf("Expected a property-only note in the changelog:\n%s", log)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import "strings"
+
+// --- Long paths ---
+//
+// Windows limits classic paths to MAX_PATH (260) characters, which long note
+// titles nested under .gitnot/changelogs/ can easily exceed. File operations
+// go through longPath, which on Windows rewrites long paths into the \\?\
+// extended-length form; elsewhere it returns the path unchanged.
+
+// maxShortPath mirrors the os package: MAX_PATH minus room for an 8.3 name.
+const maxShortPath = 248
+
+// extendedLengthPath converts an absolute Windows path (drive or UNC) of at
+// least maxShortPath characters to \\?\ form. The prefix disables Windows'
+// own normalization, so separators and . / .. segments are cleaned here.
+func extendedLengthPath(p string) string {
+	if len(p) < maxShortPath || strings.HasPrefix(p, `\\?\`) {
+		return p
+	}
+	p = strings.ReplaceAll(p, "/", `\`)
+	switch {
+	case strings.HasPrefix(p, `\\`):
+		return `\\?\UNC\` + cleanWindowsSegments(p[2:])
+	case len(p) >= 3 && p[1] == ':' && p[2] == '\\':
+		return `\\?\` + p[:2] + `\` + cleanWindowsSegments(p[3:])
+	}
+	return p // relative paths must be made absolute by the caller
+}
+
+func cleanWindowsSegments(p string) string {
+	var out []string
+	for _, seg := range strings.Split(p, `\`) {
+		switch seg {
+		case "", ".":
+		case "..":
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		default:
+			out = append(out, seg)
+		}
+	}
+	return strings.Join(out, `\`)
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package main
+
+func longPath(p string) string {
+	return p
+}
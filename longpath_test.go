@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtendedLengthPath(t *testing.T) {
+	long := strings.Repeat("chapter-", 40) // 320 chars
+	tests := []struct {
+		in, want string
+	}{
+		{`C:\notes\short.md`, `C:\notes\short.md`},
+		{`C:\notes\` + long + `.md`, `\\?\C:\notes\` + long + `.md`},
+		{`C:/notes/./drafts/../` + long + `.md`, `\\?\C:\notes\` + long + `.md`},
+		{`\\nas\share\vault\` + long + `.md`, `\\?\UNC\nas\share\vault\` + long + `.md`},
+		{`\\?\C:\already\` + long, `\\?\C:\already\` + long},
+		{`relative\` + long, `relative\` + long},
+	}
+	for _, tt := range tests {
+		if got := extendedLengthPath(tt.in); got != tt.want {
+			t.Errorf("extendedLengthPath(%.40q...) = %.60q..., expected %.60q...", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLongAndUnicodeNames(t *testing.T) {
+	setupTestDir(t)
+
+	// Note titles often become long, non-ASCII file names
+	dir := strings.Repeat("very long folder name ", 5)
+	name := filepath.Join(dir, "Ideen für das 📚 Buch — "+strings.Repeat("x", 120)+".md")
+	createTestFile(t, name, "hallo\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, name, "hallo welt\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	log, err := os.ReadFile(longPath(filepath.Join(changelogDir, name+".log")))
+	if err != nil {
+		t.Fatalf("Changelog for long unicode name missing: %v", err)
+	}
+	if !strings.Contains(string(log), "welt") {
+		t.Errorf("Expected diff in changelog, got:\n%s", log)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package main
+
+import "path/filepath"
+
+func longPath(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil || len(abs) < maxShortPath {
+		return p
+	}
+	return extendedLengthPath(abs)
+}
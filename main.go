@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/sha1"
 	"encoding/json"
 	"errors"
@@ -8,35 +10,169 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"io/ioutil"
+	"maps"
 	"os"
 	"path"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/codinganovel/go-difflib/difflib"
 )
 
 // --- Constants & paths ---
-const (
-	gitnotDir    = ".gitnot"
-	snapshotDir  = ".gitnot/snapshot"
-	changelogDir = ".gitnot/changelogs"
-	deletedDir   = ".gitnot/deleted"
-	hashesFile   = ".gitnot/hashes.json"
-	versionFile  = ".gitnot/version.txt"
-	configFile   = ".gitnot/config.json"
+
+const defaultStoreDir = ".gitnot"
+
+// The store lives in gitnotDir, ".gitnot" unless another name is chosen (see
+// storedir.go); useStoreDir points every path below at it.
+var (
+	gitnotDir string
+
+	snapshotDir        string
+	changelogDir       string
+	deletedDir         string
+	hashesFile         string
+	versionFile        string
+	configFile         string
+	manifestDir        string
+	indexFile          string
+	dirsFile           string
+	tasksDir           string
+	lockFile           string
+	journalFile        string
+	backupDir          string
+	storeFile          string
+	protectFile        string
+	gcLogFile          string
+	labelsFile         string
+	objectsDir         string
+	historyFile        string
+	pinsFile           string
+	scrubFile          string
+	tagsFile           string
+	remapsFile         string
+	adoptedFile        string
+	trackedListFile    string
+	untrackListFile    string
+	overwrittenDir     string
+	quarantineDir      string
+	untrackedLogDir    string
+	snapshotTmpDir     string
+	snapshotOldDir     string
+	packDir            string
+	allowedSignersFile string
+	storageFile        string
+	devicesDir         string
 )
 
+func init() { useStoreDir(defaultStoreDir) }
+
+func useStoreDir(dir string) {
+	in := func(name string) string { return dir + "/" + name }
+	gitnotDir = dir
+	snapshotDir = in("snapshot")
+	changelogDir = in("changelogs")
+	deletedDir = in("deleted")
+	hashesFile = in("hashes.json")
+	versionFile = in("version.txt")
+	configFile = in("config.json")
+	manifestDir = in("manifests")
+	indexFile = in("index.json")
+	dirsFile = in("dirs.json")
+	tasksDir = in("tasks")
+	lockFile = in("lock")
+	journalFile = in("journal.json")
+	backupDir = in("backups")
+	storeFile = in("store.json")
+	protectFile = in("protect.json")
+	gcLogFile = in("gc.log")
+	labelsFile = in("labels.json")
+	objectsDir = in("objects")
+	historyFile = in("HISTORY.md")
+	pinsFile = in("pins.json")
+	scrubFile = in("scrub.json")
+	tagsFile = in("tags.json")
+	remapsFile = in("remaps.json")
+	adoptedFile = in("adopted.json")
+	trackedListFile = in("tracked.txt")
+	untrackListFile = in("untracked.txt")
+	overwrittenDir = in("overwritten")
+	quarantineDir = in("quarantine")
+	untrackedLogDir = in("untracked")
+	snapshotTmpDir = in("snapshot.tmp")
+	snapshotOldDir = in("snapshot.old")
+	packDir = in("objects/pack")
+	allowedSignersFile = in("allowed_signers")
+	storageFile = in("storage.json")
+	devicesDir = in("devices")
+	apiSocket = filepath.Join(dir, "watch.sock")
+}
+
 // --- Config ---
 
 type Config struct {
-	Extensions     []string `json:"extensions"`
-	IgnorePatterns []string `json:"ignore_patterns"`
+	Extensions      []string `json:"extensions"`
+	IgnorePatterns  []string `json:"ignore_patterns"`
+	IncludePatterns []string `json:"include_patterns,omitempty"` // always tracked, whatever the extension
+	Rules           []Rule   `json:"rules,omitempty"`            // ordered tracking rules; replace the three lists above
+
+	MaxDepth        int            `json:"max_depth,omitempty"`        // folders scanned below the root, 0 = no limit
+	DepthOverrides  map[string]int `json:"depth_overrides,omitempty"`  // folder → depth limit below it
+	BackupRetention int            `json:"backup_retention,omitempty"` // metadata backups to keep
+
+	HiddenFiles string `json:"hidden_files,omitempty"` // "match" (default), "track" or "skip"; see hidden.go
+	HiddenDirs  string `json:"hidden_dirs,omitempty"`  // "scan" (default) or "skip"
+
+	CloudPlaceholders string `json:"cloud_placeholders,omitempty"` // "skip" (default) or "hash"
+
+	DeletedRetention Retention `json:"deleted_retention,omitzero"` // purge policy for gc
+
+	Order  []string     `json:"order,omitempty"` // narrative order for export and stats
+	Export ExportConfig `json:"export,omitzero"`
+
+	Vault VaultConfig `json:"vault,omitzero"` // Obsidian vault handling
+
+	CheckCommand string          `json:"check_command,omitempty"` // linter run on changed files, e.g. "vale"
+	Hooks        []Hook          `json:"hooks,omitempty"`         // commands run when matching files change
+	Publish      []PublishTarget `json:"publish,omitempty"`       // where changed files are copied after an update
+	Storage      string          `json:"storage,omitempty"`       // backend that also keeps every object, see plugins.go
+	Mirrors      []string        `json:"mirrors,omitempty"`       // folders that get a copy of the store after each update
+
+	RecordEnvironment []string `json:"record_environment,omitempty"` // any of host, os, user, tool
+
+	TrackMetadata    []string `json:"track_metadata,omitempty"`     // any of mode, mtime; see metadata.go
+	SkipMetadataOnly bool     `json:"skip_metadata_only,omitempty"` // don't record versions that only change metadata
+
+	PinnedChanges string `json:"pinned_changes,omitempty"` // refuse (default) or warn; see pins.go
+
+	AutoTags []string `json:"auto_tags,omitempty"` // any of daily, weekly, monthly; see tags.go
+
+	Narrative NarrativeConfig `json:"narrative,omitzero"` // a sentence describing each version
+
+	Author Author `json:"author,omitzero"` // fallback when no per-user author is set
+
+	DeltaStorage bool `json:"delta_storage,omitempty"` // store new versions as deltas against the previous one
+	AutoPack     bool `json:"auto_pack,omitempty"`     // pack small objects once many pile up
+
+	Throttle        ThrottleConfig `json:"throttle,omitzero"`           // pace background work
+	Retry           RetryConfig    `json:"retry,omitzero"`              // transient read errors on network drives, see retry.go
+	Copy            CopyConfig     `json:"copy,omitzero"`               // kept metadata and fsync policy of copies, see copy.go
+	LockedFiles     string         `json:"locked_files,omitempty"`      // "skip" (default), "share" or "shadow"; see locked.go
+	ScrubEveryHours int            `json:"scrub_every_hours,omitempty"` // let watch re-verify the store this often
+
+	SkipUnchangedDirs bool   `json:"skip_unchanged_dirs,omitempty"` // reuse listings of unchanged folders, see dirscan.go
+	MemoryLimitMB     int    `json:"memory_limit_mb,omitempty"`     // soft limit for the Go runtime, see memory.go
+	PostUpdate        string `json:"post_update,omitempty"`         // "inline" (default), "background" or "queue", see tasks.go
+}
+
+func (c Config) backupRetention() int {
+	if c.BackupRetention > 0 {
+		return c.BackupRetention
+	}
+	return defaultBackupRetention
 }
 
 var defaultConfig = Config{
@@ -44,8 +180,9 @@ var defaultConfig = Config{
 		".txt", ".md", ".csv", ".log", ".py", ".js", ".sh",
 		".html", ".css", ".c", ".java", ".json", ".yaml",
 		".yml", ".ini", ".toml", ".xml", ".rtf", ".go",
+		".tex", ".bib", ".cls", ".sty", ".fountain",
 	},
-	IgnorePatterns: []string{"*.tmp", "*.bak"},
+	IgnorePatterns: []string{"*.tmp", "*.bak", "*.aux", "*.synctex.gz", "_minted*/*"},
 }
 
 // --- Utilities ---
@@ -55,17 +192,22 @@ func safeMkdirAllForFile(p string) error {
 	if d == "." || d == "" {
 		return nil
 	}
-	return os.MkdirAll(d, 0o755)
+	return repo.FS.MkdirAll(longPath(d), 0o755)
 }
 
 func loadJSON[T any](p string, out *T) error {
-	b, err := os.ReadFile(p)
+	b, err := repo.FS.ReadFile(p)
 	if err != nil {
 		return err
 	}
 	return json.Unmarshal(b, out)
 }
 
+// saveJSON writes v as indented JSON. The bytes depend only on the value:
+// encoding/json sorts map keys and every slice the store saves is built in a
+// defined order, so recording the same state twice gives the same files. A
+// file whose content would not change is left alone, so sync tools and
+// backups don't see a rewrite on every run.
 func saveJSON(p string, v any) error {
 	if err := safeMkdirAllForFile(p); err != nil {
 		return err
@@ -74,11 +216,126 @@ func saveJSON(p string, v any) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(p, b, 0o644)
+	if old, err := repo.FS.ReadFile(p); err == nil && bytes.Equal(old, b) {
+		return nil
+	}
+	return writeFileAtomic(p, b, 0o644)
+}
+
+// saveJSONStreamed is saveJSON for the files that list every tracked file
+// (hashes.json, the stat index, manifests): encode writes the JSON straight
+// into the temp file, so the text of a million entries is never held in
+// memory. The bytes are the ones saveJSON would write, and an unchanged file
+// is again left alone, found by comparing digests.
+func saveJSONStreamed(p string, encode func(w io.Writer) error) error {
+	if err := safeMkdirAllForFile(p); err != nil {
+		return err
+	}
+	return writeFileAtomicWith(p, 0o644, func(w io.Writer) error {
+		sum := sha1.New()
+		bw := bufio.NewWriter(io.MultiWriter(w, sum))
+		if err := encode(bw); err != nil {
+			return err
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if old, err := storeFileDigest(p); err == nil && bytes.Equal(old, sum.Sum(nil)) {
+			return errUnchanged
+		}
+		return nil
+	})
+}
+
+// writeJSONMap writes m as json.MarshalIndent does when m sits depth levels
+// down, one entry at a time.
+func writeJSONMap[V any](w io.Writer, m map[string]V, depth int) error {
+	switch {
+	case m == nil:
+		_, err := io.WriteString(w, "null")
+		return err
+	case len(m) == 0:
+		_, err := io.WriteString(w, "{}")
+		return err
+	}
+	indent := strings.Repeat("  ", depth+1)
+	sep := "{"
+	for _, k := range sortedKeys(m) {
+		key, err := json.Marshal(k)
+		if err != nil {
+			return err
+		}
+		value, err := json.MarshalIndent(m[k], indent, "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s\n%s%s: %s", sep, indent, key, value); err != nil {
+			return err
+		}
+		sep = ","
+	}
+	_, err := fmt.Fprintf(w, "\n%s}", indent[2:])
+	return err
+}
+
+// storeFileDigest is the SHA-1 of a store file, read in pieces.
+func storeFileDigest(p string) ([]byte, error) {
+	f, err := repo.FS.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sum := sha1.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return nil, err
+	}
+	return sum.Sum(nil), nil
+}
+
+// errUnchanged, returned by the write function of writeFileAtomicWith,
+// leaves the file as it was.
+var errUnchanged = errors.New("unchanged")
+
+// writeFileAtomic writes to a temp file in the same directory and renames it
+// into place, so concurrent readers see either the old or the new content.
+func writeFileAtomic(p string, data []byte, perm os.FileMode) error {
+	return writeFileAtomicWith(p, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeFileAtomicWith is writeFileAtomic for content that write produces.
+func writeFileAtomicWith(p string, perm os.FileMode, write func(w io.Writer) error) error {
+	tmp, err := repo.FS.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		repo.FS.Remove(tmp.Name())
+		if errors.Is(err, errUnchanged) {
+			return nil
+		}
+		return err
+	}
+	if err := closeWritten(tmp); err != nil {
+		repo.FS.Remove(tmp.Name())
+		return err
+	}
+	if err := repo.FS.Chmod(tmp.Name(), perm); err != nil {
+		repo.FS.Remove(tmp.Name())
+		return err
+	}
+	if err := repo.FS.Rename(tmp.Name(), p); err != nil {
+		repo.FS.Remove(tmp.Name())
+		return err
+	}
+	return nil
 }
 
 func readVersion() (float64, error) {
-	b, err := os.ReadFile(versionFile)
+	b, err := repo.FS.ReadFile(versionFile)
 	if errors.Is(err, os.ErrNotExist) {
 		return 0.0, nil
 	}
@@ -94,10 +351,19 @@ func readVersion() (float64, error) {
 }
 
 func writeVersion(v float64) error {
-	if err := os.MkdirAll(gitnotDir, 0o755); err != nil {
+	if err := repo.FS.MkdirAll(gitnotDir, 0o755); err != nil {
 		return err
 	}
-	return os.WriteFile(versionFile, []byte(fmt.Sprintf("%.1f", v)), 0o644)
+	flushCopies()
+	if err := writeFileAtomic(versionFile, []byte(fmt.Sprintf("%.1f", v)), 0o644); err != nil {
+		return err
+	}
+	flushCopies()
+	return nil
+}
+
+func nextVersion(v float64) float64 {
+	return float64(int((v+0.1)*10+0.5)) / 10.0 // keep one decimal, avoid fp drift
 }
 
 func bumpVersion() (float64, error) {
@@ -105,7 +371,7 @@ func bumpVersion() (float64, error) {
 	if err != nil {
 		return 0, err
 	}
-	v = float64(int((v+0.1)*10+0.5)) / 10.0 // keep one decimal, avoid fp drift
+	v = nextVersion(v)
 	if err := writeVersion(v); err != nil {
 		return 0, err
 	}
@@ -114,9 +380,15 @@ func bumpVersion() (float64, error) {
 
 // --- Config & filters ---
 
+// loadConfig reads config.json, warning about anything it had to skip.
 func loadConfig() Config {
-	var cfg Config
-	if err := loadJSON(configFile, &cfg); err != nil || len(cfg.Extensions) == 0 {
+	b, err := repo.FS.ReadFile(configFile)
+	if err != nil {
+		return defaultConfig
+	}
+	cfg, issues := parseConfig(b)
+	warnConfigIssues(b, issues)
+	if len(cfg.Extensions) == 0 && len(cfg.Rules) == 0 {
 		return defaultConfig
 	}
 	return cfg
@@ -133,19 +405,38 @@ func hasAnySuffix(name string, exts []string) bool {
 }
 
 func shouldIgnore(p string, patterns []string) bool {
+	return matchesAny(p, patterns)
+}
+
+// matchesAny reports whether p matches a file name, path, glob or
+// directory pattern ("dir/*").
+func matchesAny(p string, patterns []string) bool {
 	pp := filepath.ToSlash(p)
 	base := path.Base(pp)
 	for _, pat := range patterns {
 		if strings.HasSuffix(pat, "/*") { // directory pattern
 			d := strings.TrimSuffix(pat, "/*")
-			// match whole path segments (e.g., node_modules)
-			segRe := regexp.MustCompile(`/` + regexp.QuoteMeta(d) + `(/|$)`)
-			if strings.Contains(pp, "/"+d+"/") || strings.HasPrefix(pp, d+"/") || segRe.MatchString("/"+pp) {
+			if strings.ContainsAny(d, "*?[") && !strings.Contains(d, "/") && checkGlob(d) == nil {
+				// a glob names folders (e.g. _minted*/*), wherever they are
+				segs := strings.Split(pp, "/")
+				for _, seg := range segs[:len(segs)-1] {
+					if ok, _ := path.Match(d, seg); ok {
+						return true
+					}
+				}
+				continue
+			}
+			// match whole path segments (e.g., node_modules); plain string
+			// checks, as d may be anything a user typed
+			if strings.Contains("/"+pp+"/", "/"+d+"/") {
 				return true
 			}
 			continue
 		}
 		if strings.ContainsAny(pat, "*?") { // glob
+			if checkGlob(pat) != nil {
+				continue // malformed, matches nothing; config validation reports it
+			}
 			if ok, _ := path.Match(pat, base); ok {
 				return true
 			}
@@ -154,84 +445,239 @@ func shouldIgnore(p string, patterns []string) bool {
 			}
 			continue
 		}
-		// exact filename
-		if base == pat {
+		// exact filename or path
+		if base == pat || pp == pat {
 			return true
 		}
 	}
 	return false
 }
 
-// --- File scanning & hashing ---
-
-func hashFile(p string) string {
-	f, err := os.Open(p)
-	if err != nil {
-		return fmt.Sprintf("unreadable-%s", filepath.Base(p))
-	}
-	defer f.Close()
+// matchesRooted reports whether p matches pat taken from the project root,
+// one path segment at a time: "*" stays within a segment and a "**" segment
+// stands for any number of them, so "blog/**" is everything below the root's
+// blog/ folder and nothing in drafts/blog/.
+func matchesRooted(p, pat string) bool {
+	segs := strings.Split(path.Clean(filepath.ToSlash(p)), "/")
+	pat = strings.TrimPrefix(path.Clean(filepath.ToSlash(pat)), "/")
+	return matchSegments(segs, strings.Split(pat, "/"))
+}
 
-	h := sha1.New()
-	buf := make([]byte, 8192)
-	for {
-		n, err := f.Read(buf)
-		if n > 0 {
-			h.Write(buf[:n])
+func matchSegments(segs, pats []string) bool {
+	for len(pats) > 0 {
+		if pats[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(segs[i:], pats[1:]) {
+					return true
+				}
+			}
+			return false
 		}
-		if err != nil {
-			break
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pats[0], segs[0]); !ok {
+			return false
 		}
+		segs, pats = segs[1:], pats[1:]
 	}
-	return fmt.Sprintf("%x", h.Sum(nil))
+	return len(segs) == 0
+}
+
+// checkGlob reports a pattern path.Match can't use. path.Match only says so
+// when it gets far enough into the pattern, so the whole pattern is checked
+// up front rather than trusting a false from a particular name.
+func checkGlob(pat string) error {
+	if _, err := path.Match(pat, ""); err != nil {
+		return fmt.Errorf("%q is not a valid glob", pat)
+	}
+	return nil
+}
+
+// --- File scanning & hashing ---
+
+func hashFile(p string) string {
+	fi, _ := os.Stat(longPath(p)) // before reading, so a later edit shows in the index
+	h, x := sha1.New(), newXXH64()
+	if err := digestFile(p, h, x); err != nil {
+		noteUnreadable(p, err)
+		return ""
+	}
+	sum := fmt.Sprintf("%x", h.Sum(nil))
+	rememberFast(sum, fmt.Sprintf("%016x", x.Sum64()))
+	noteHashed(p, sum, fi)
+	return sum
 }
 
 func isUnderGitnot(p string) bool {
-	return strings.HasPrefix(filepath.ToSlash(p), gitnotDir)
+	return within(p, gitnotDir)
 }
 
 func getAllTextFiles(root string) ([]string, error) {
-	cfg := loadConfig()
-	var files []string
-	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+	return scanTextFiles(root, loadConfig().scanFilters())
+}
+
+// scanTextFiles lists the files under root that filter tracks.
+func scanTextFiles(root string, filter scanFilter) ([]string, error) {
+	files, _, err := scanTree(root, filter)
+	return files, err
+}
+
+// scanTree is scanTextFiles that also returns the folders the depth limit
+// pruned. Filters see paths relative to root; the paths returned include it.
+func scanTree(root string, filter scanFilter) (files, pruned []string, err error) {
+	rep, err := scanTreeReport(root, filter)
+	return rep.Files, rep.Pruned, err
+}
+
+// scanReport is what a scan found and what it left out.
+type scanReport struct {
+	Files       []string
+	Pruned      []string         // folders below the depth limit
+	HiddenDirs  []string         // dot-folders hidden_dirs skipped
+	HiddenFiles []string         // dotfiles hidden_files left out
+	Unreadable  map[string]error // folders that could not be listed
+}
+
+func scanTreeReport(root string, filter scanFilter) (rep scanReport, err error) {
+	return scanTreeCached(root, filter, nil)
+}
+
+// scanTreeCached is scanTreeReport reusing the listings of folders that
+// haven't changed since cache was made (see dirscan.go); a nil cache lists
+// every folder.
+func scanTreeCached(root string, filter scanFilter, cache *dirCache) (rep scanReport, err error) {
+	addFile := func(p, rel string, size func() int64) (tracked, hidden bool) {
+		tracked = filter.tracks(rel, size)
+		if isHidden(filepath.Base(p)) {
+			if tracked, hidden = filter.hiddenFile(rel, tracked); hidden {
+				rep.HiddenFiles = append(rep.HiddenFiles, p)
+			}
+		}
+		if tracked {
+			rep.Files = append(rep.Files, p)
+		}
+		return tracked, hidden
+	}
+	var walk fs.WalkDirFunc
+	walk = func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			cache.forget(p)
+			// skip what can't be read, remembering folders that exist
+			if !errors.Is(err, fs.ErrNotExist) {
+				if rep.Unreadable == nil {
+					rep.Unreadable = map[string]error{}
+				}
+				rep.Unreadable[p] = err
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
 		if err != nil {
-			return nil // skip unreadable
+			return nil
 		}
 		if d.IsDir() {
-			if isUnderGitnot(p) {
+			if isUnderGitnot(rel) || d.Name() == ".git" {
 				return filepath.SkipDir
 			}
-			return nil
+			if filepath.Dir(rel) == "." && rel != "." && isStore(p) {
+				return filepath.SkipDir // a store under another name is never tracked
+			}
+			if filter.skipsHiddenDir(rel) {
+				rep.HiddenDirs = append(rep.HiddenDirs, p)
+				cache.note(p, listedHiddenDir)
+				return filepath.SkipDir
+			}
+			if filter.pruneDir(rel) {
+				rep.Pruned = append(rep.Pruned, p)
+				cache.note(p, listedPruned)
+				return filepath.SkipDir
+			}
+			if p != root {
+				cache.note(p, listedDir)
+			}
+			l, ok := cache.lookup(p, d)
+			if !ok {
+				return nil
+			}
+			// unchanged: what it holds is known, only its subfolders are visited
+			rep.Files = append(rep.Files, joinAll(p, l.Files)...)
+			rep.HiddenFiles = append(rep.HiddenFiles, joinAll(p, l.HiddenFiles)...)
+			rep.HiddenDirs = append(rep.HiddenDirs, joinAll(p, l.HiddenDirs)...)
+			rep.Pruned = append(rep.Pruned, joinAll(p, l.Pruned)...)
+			for _, f := range joinAll(p, l.Sized) {
+				if fi, err := os.Lstat(f); err == nil {
+					rel, _ := filepath.Rel(root, f)
+					addFile(f, rel, fi.Size)
+				}
+			}
+			for _, sub := range joinAll(p, l.Dirs) {
+				if err := filepath.WalkDir(sub, walk); err != nil {
+					return err
+				}
+			}
+			return filepath.SkipDir
 		}
-		if !hasAnySuffix(d.Name(), cfg.Extensions) {
-			return nil
+		size := func() int64 {
+			if fi, err := d.Info(); err == nil {
+				return fi.Size()
+			}
+			return 0
 		}
-		if shouldIgnore(p, cfg.IgnorePatterns) {
-			return nil
+		sized := filter.sizeLimited(rel)
+		switch tracked, hidden := addFile(p, rel, size); {
+		case sized:
+			cache.note(p, listedSized) // checked again every scan: growing doesn't touch the folder
+		case hidden:
+			cache.note(p, listedHiddenFile)
+		case tracked:
+			cache.note(p, listedFile)
 		}
-		files = append(files, p)
 		return nil
-	})
+	}
+	err = filepath.WalkDir(root, walk)
 	if err != nil {
-		return nil, err
+		return scanReport{}, err
 	}
-	sort.Strings(files)
-	return files, nil
+	sort.Strings(rep.Files)
+	return rep, nil
 }
 
 // --- Diff helpers ---
 
 func unifiedDiff(oldPath, newPath string) (string, error) {
-	oldB, _ := os.ReadFile(oldPath) // tolerate missing/encoding issues
-	newB, _ := os.ReadFile(newPath)
-	ud := difflib.UnifiedDiff{
-		A:        difflib.SplitLines(string(oldB)),
-		B:        difflib.SplitLines(string(newB)),
-		FromFile: "before",
-		ToFile:   "after",
-		Context:  3,
+	return unifiedDiffMasked(oldPath, newPath, nil)
+}
+
+// unifiedDiffLines diffs two already-split texts.
+func unifiedDiffLines(a, b []string) string {
+	text, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A: a, B: b, FromFile: "before", ToFile: "after", Context: 3,
+	})
+	return text
+}
+
+// unifiedDiffMasked blanks lines matched by mask on both sides before
+// diffing, so they never show up as changes but line numbers stay intact.
+func unifiedDiffMasked(oldPath, newPath string, mask func(string) bool) (string, error) {
+	oldB, _ := os.ReadFile(longPath(oldPath)) // tolerate missing/encoding issues
+	newB, _ := os.ReadFile(longPath(newPath))
+	return unifiedDiffLines(
+		maskLines(difflib.SplitLines(string(oldB)), mask),
+		maskLines(difflib.SplitLines(string(newB)), mask),
+	), nil
+}
+
+func maskLines(lines []string, mask func(string) bool) []string {
+	if mask == nil {
+		return lines
 	}
-	text, err := difflib.GetUnifiedDiffString(ud)
-	return text, err
+	for i, l := range lines {
+		if mask(l) {
+			lines[i] = "\n"
+		}
+	}
+	return lines
 }
 
 func formatDiffAsMarkdown(diffText string) string {
@@ -320,35 +766,54 @@ func formatDiffAsMarkdown(diffText string) string {
 
 // --- Core ops ---
 
+// initGitnot initializes a store, refusing when one already exists.
 func initGitnot() error {
+	return initGitnotWith(initOptions{})
+}
+
+// createStore sets up a new store and records v0.0.
+func createStore() error {
 	// Create dirs
-	for _, d := range []string{snapshotDir, changelogDir, deletedDir} {
-		if err := os.MkdirAll(d, 0o755); err != nil {
+	for _, d := range []string{snapshotDir, changelogDir, deletedDir, manifestDir, objectsDir} {
+		if err := repo.FS.MkdirAll(d, 0o755); err != nil {
 			return err
 		}
 	}
 	// Save default config if missing
-	if _, err := os.Stat(configFile); errors.Is(err, os.ErrNotExist) {
+	if _, err := repo.FS.Stat(configFile); errors.Is(err, os.ErrNotExist) {
 		if err := saveJSON(configFile, defaultConfig); err != nil {
 			return err
 		}
 	}
 
+	setRetry(loadConfig().Retry)
+	setCopy(loadConfig().Copy)
+	useLockedFiles(loadConfig().LockedFiles)
+	defer releaseShadows()
+	takeUnreadable(nil)
 	files, err := getAllTextFiles(".")
 	if err != nil {
 		return err
 	}
 	hashes := map[string]string{}
+	var changes []FileChange
 	for _, f := range files {
 		rel := f
 		snap := filepath.Join(snapshotDir, rel)
 		if err := safeMkdirAllForFile(snap); err != nil {
 			return err
 		}
+		fi, _ := os.Stat(longPath(f)) // the index gets the stat from before the copy
 		if err := copyFile(f, snap); err != nil {
+			noteUnreadable(f, err)
 			continue
 		}
-		hashes[rel] = hashFile(f)
+		hashes[rel] = hashFile(snap)
+		noteHashed(rel, hashes[rel], fi)
+		if err := storeObject(snap, hashes[rel]); err != nil {
+			return err
+		}
+		changes = append(changes, measureFiles(rel, "", f, stateAdded))
 
 		// create initial changelog entry
 		clPath := filepath.Join(changelogDir, rel+".log")
@@ -361,78 +826,312 @@ func initGitnot() error {
 	if err := writeVersion(0.0); err != nil {
 		return err
 	}
+	cfg := loadConfig()
+	unreadable := takeUnreadable(nil)
+	manifest := Manifest{Version: 0.0, Timestamp: repo.Now(), Files: hashes, Changes: changes, GitHead: gitHead(),
+		Env: captureEnvironment(cfg.RecordEnvironment), Author: currentAuthor(cfg), Meta: readMeta(cfg, files),
+		Unreadable: unreadableErrors(unreadable)}
+	manifest.ID, manifest.Clock = nextVersionID(currentDevice().ID, nil)
+	maybeSignManifest(&manifest)
+	if err := writeManifest(manifest); err != nil {
+		return err
+	}
+	if err := appendHistory(manifest); err != nil {
+		return err
+	}
+	if err := saveIndex(hashes); err != nil {
+		return err
+	}
+	if err := writeStoreInfo(StoreInfo{FormatVersion: currentFormat}); err != nil {
+		return err
+	}
+	if err := recordDeviceHead(currentDevice().ID, manifest); err != nil {
+		return err
+	}
 	fmt.Printf("✨ Initialized gitnot at version 0.0\n")
 	fmt.Printf("📁 Tracking %d files\n", len(hashes))
+	if err := storeRemote(cfg.Storage, manifest); err != nil {
+		fmt.Printf("⚠️  Warning: copying objects to storage %s failed: %v\n", cfg.Storage, err)
+	}
+	reportUnreadable(unreadable)
+	recordAutoTags(cfg, 0.0, manifest.Timestamp)
 	return nil
 }
 
+// updateOptions tunes updateGitnotWith; the zero value is a plain update.
+type updateOptions struct {
+	Message  string   // recorded with the version
+	ShowDiff bool     // print the new changelog entries afterwards
+	Force    bool     // record changes to pinned files
+	Paths    []string // record only these files and folders, see pathsfrom.go
+	Full     bool     // hash every file in full, listing every folder with skip_unchanged_dirs
+
+	KeepTasks bool // queue post-update work without starting a runner; watch runs it itself
+}
+
 func updateGitnot() error {
-	if _, err := os.Stat(gitnotDir); errors.Is(err, os.ErrNotExist) {
+	return updateGitnotWith(updateOptions{})
+}
+
+func updateGitnotWith(opts updateOptions) error {
+	if _, err := repo.FS.Stat(gitnotDir); errors.Is(err, os.ErrNotExist) {
 		return fmt.Errorf("gitnot not initialized; run --init")
 	}
+	if err := recoverInterrupted(); err != nil {
+		return fmt.Errorf("recovering interrupted run: %w", err)
+	}
+	if err := checkNotArchived(); err != nil {
+		return err
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer catchInterrupts()()
+	gen := readGeneration()
 	var oldHashes map[string]string
 	if err := loadJSON(hashesFile, &oldHashes); err != nil {
 		oldHashes = map[string]string{}
 	}
-	files, err := getAllTextFiles(".")
+	cfg := loadConfig()
+	setRetry(cfg.Retry)
+	setCopy(cfg.Copy)
+	useLockedFiles(cfg.LockedFiles)
+	defer releaseShadows()
+	filter := cfg.scanFilters()
+	var cache *dirCache
+	if cfg.SkipUnchangedDirs {
+		cache = loadDirCache(filter, opts.Full)
+	}
+	rep, err := scanTreeCached(".", filter, cache)
 	if err != nil {
 		return err
 	}
+	if err := cache.save(); err != nil {
+		return err
+	}
+	files := rep.Files
+	warnPruned(rep.Pruned)
+	vault := cfg.vaultMode() != vaultNone
+	mask := cfg.propertyMask()
+	files, deferred := deferPlaceholders(files, cfg)
 	current := map[string]string{}
-	for _, f := range files {
-		current[f] = hashFile(f)
+	takeUnreadable(nil)
+	idx := loadIndex()
+	if cache != nil {
+		current, _, _ = hashWithIndex(files, idx, opts.Full || cache.Fresh)
+	} else {
+		for _, f := range files {
+			if opts.Full {
+				current[f] = hashFile(f)
+			} else {
+				current[f] = hashFileExpecting(f, oldHashes[f])
+			}
+		}
 	}
-	// detect changes
-	var newFiles, changedFiles, deletedFiles []string
-	for f := range current {
-		if _, ok := oldHashes[f]; !ok {
-			newFiles = append(newFiles, f)
+	carryDeferred(current, oldHashes, deferred)
+	unreadable := takeUnreadable(current)
+	maps.Copy(unreadable, unscannedFiles(rep.Unreadable, oldHashes))
+	files = holdUnreadable(files, current, oldHashes, unreadable)
+	defer reportUnreadable(unreadable)
+	var held []string
+	if opts.Paths != nil {
+		files, held = limitToPaths(files, current, oldHashes, opts.Paths)
+	}
+	newFiles, changedFiles, deletedFiles := detectChanges(oldHashes, current)
+	if vault {
+		if renames := detectRenames(newFiles, deletedFiles, current, oldHashes); len(renames) > 0 {
+			if cfg.Vault.RewriteLinks {
+				// rewriting edits working files, so the room for recording
+				// them is checked first
+				linking := filesLinkingTo(files, renames)
+				if err := checkDiskSpace(requiredSpace(files, newFiles, append(slices.Clone(changedFiles), linking...))); err != nil {
+					return err
+				}
+				rewritten, err := applyLinkRewrites(linking, renames)
+				if err != nil {
+					return fmt.Errorf("rewriting links: %w", err)
+				}
+				for _, f := range rewritten {
+					current[f] = hashFile(f)
+				}
+				if len(rewritten) > 0 {
+					fmt.Printf("🔗 Updated links to %d renamed notes in %d files\n", len(renames), len(rewritten))
+					newFiles, changedFiles, deletedFiles = detectChanges(oldHashes, current)
+				}
+			} else if n := staleLinkCount(files, renames); n > 0 {
+				fmt.Printf("🔗 %d links still point at renamed notes (set vault.rewrite_links to update them)\n", n)
+			}
 		}
 	}
-	for f, h := range current {
-		if oh, ok := oldHashes[f]; ok && oh != h {
-			changedFiles = append(changedFiles, f)
+	metaFiles, prevMeta, curMeta := pendingMetaChanges(cfg, files, oldHashes, current)
+	untrackedFiles, deletedFiles := splitUntracked(deletedFiles)
+	base, _ := readVersion()
+	remaps := pendingRemaps(base)
+	adopted := pendingAdoptions(base)
+	if len(newFiles)+len(changedFiles)+len(deletedFiles)+len(untrackedFiles)+len(remaps) == 0 {
+		if len(metaFiles) == 0 && len(held) > 0 {
+			fmt.Printf("✅ No changes among the listed paths (%d changed files outside them)\n", len(held))
+			return nil
+		}
+		if len(metaFiles) == 0 {
+			fmt.Println("✅ No changes detected")
+			return nil
+		}
+		if cfg.SkipMetadataOnly {
+			fmt.Printf("✅ No content changes (%d files changed only metadata, not recorded: skip_metadata_only)\n", len(metaFiles))
+			return nil
 		}
 	}
-	for f := range oldHashes {
-		if _, ok := current[f]; !ok {
-			deletedFiles = append(deletedFiles, f)
+	if err := checkPins(cfg, opts.Force, changedFiles, deletedFiles); err != nil {
+		return err
+	}
+	if err := checkDiskSpace(requiredSpace(files, newFiles, changedFiles)); err != nil {
+		return err
+	}
+	// folder renames only change what is recorded here; their changelogs
+	// move once the journal can move them back
+	dirRenames := detectDirRenames(newFiles, deletedFiles, current, oldHashes)
+	renamed := map[string]string{}
+	for i := range dirRenames {
+		r := &dirRenames[i]
+		r.Base, r.At = base, repo.Now()
+		moved := movedFiles(oldHashes, []Remap{*r})
+		maps.Copy(renamed, moved)
+		newFiles, deletedFiles = dropMoved(newFiles, deletedFiles, moved)
+		oldHashes = remapKeys(oldHashes, []Remap{*r})
+		remaps = append(remaps, *r)
+		fmt.Printf("🔀 Folder renamed: %s → %s (%d files)\n", filepath.ToSlash(r.From), filepath.ToSlash(r.To), r.Files)
+	}
+	// version.txt is written last: it is the commit point readers rely on
+	prev, err := readVersion()
+	if err != nil {
+		return err
+	}
+	if err := checkGeneration(gen, prev); err != nil {
+		return err
+	}
+	ver := nextVersion(prev)
+	if err := checkVersionFree(ver); err != nil {
+		return err
+	}
+	prevManifest, _ := loadManifest(prev)
+	clock := mergeDiverged(prevManifest.Clock, prev)
+	now := repo.Now()
+	ts := now.Format("2006-01-02 15:04")
+	author := currentAuthor(cfg)
+	if author != nil {
+		ts += " · " + author.label()
+	}
+	var changes []FileChange
+	// the previous manifest is already read; committedHashes would read it
+	// a second time
+	prevFiles := prevManifest.Files
+	if prevFiles == nil {
+		prevFiles, _ = committedHashes(prev)
+	}
+	moved := movedFiles(prevFiles, remaps)
+
+	var touched []string
+	for _, group := range [][]string{newFiles, changedFiles, deletedFiles, untrackedFiles, metaFiles, sortedKeys(moved)} {
+		for _, rel := range group {
+			touched = append(touched, filepath.Join(changelogDir, rel+".log"))
 		}
 	}
-	if len(newFiles)+len(changedFiles)+len(deletedFiles) == 0 {
-		fmt.Println("✅ No changes detected")
-		return nil
+	touched = append(touched, historyFile)
+	if err := stopIfInterrupted(nil, prev); err != nil {
+		return err
 	}
-	ver, err := bumpVersion()
+	journal, err := beginJournal("update", prev, ver, touched)
 	if err != nil {
 		return err
 	}
-	ts := time.Now().Format("2006-01-02 15:04")
+	if err := journalMoves(journal, renamed); err != nil {
+		if rerr := abandonUpdate(journal); rerr != nil {
+			fmt.Printf("⚠️  Warning: Could not undo the abandoned update: %v\n", rerr)
+		}
+		return fmt.Errorf("recording folder rename: %w", err)
+	}
+
+	checkWarned := false
+	warnCheck := func(err error) {
+		if !checkWarned {
+			checkWarned = true
+			fmt.Printf("⚠️  %v\n", err)
+		}
+	}
 
 	// handle new and modified files - update changelogs first
+	revived := findRevived(newFiles)
 	for _, rel := range newFiles {
+		if err := stopIfInterrupted(journal, prev); err != nil {
+			return err
+		}
 		clPath := filepath.Join(changelogDir, rel+".log")
 		_ = safeMkdirAllForFile(clPath)
+		rule, _ := filter.ruleFor(rel)
+		if last, ok := revived[rel]; ok {
+			md, fc := reviveEntry(rel, last, rule.binary(), mask)
+			_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n%s", ver, ts, md))
+			fc.RevivedFrom = &last
+			if !rule.binary() {
+				checkFile(cfg.CheckCommand, rel, clPath, &fc, warnCheck)
+			}
+			changes = append(changes, fc)
+			continue
+		}
 		_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n📄 New file added.\n", ver, ts))
+		if rule.binary() {
+			changes = append(changes, FileChange{Path: rel, State: stateAdded})
+			continue
+		}
+		fc := measureFiles(rel, "", rel, stateAdded)
+		checkFile(cfg.CheckCommand, rel, clPath, &fc, warnCheck)
+		changes = append(changes, fc)
 	}
 
 	for _, rel := range changedFiles {
+		if err := stopIfInterrupted(journal, prev); err != nil {
+			return err
+		}
 		oldP := filepath.Join(snapshotDir, rel)
 		newP := rel
 		clPath := filepath.Join(changelogDir, rel+".log")
 		_ = safeMkdirAllForFile(clPath)
+		rule, _ := filter.ruleFor(rel)
+		if rule.binary() {
+			_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n%s", ver, ts, binaryEntry(oldP, newP)))
+			changes = append(changes, FileChange{Path: rel, State: stateModified})
+			continue
+		}
+		fc := measureFiles(rel, oldP, newP, stateModified)
 
 		// Try to read files and generate diff
-		if _, err := os.Stat(oldP); err == nil {
-			diffText, _ := unifiedDiff(oldP, newP)
-			if diffText != "" {
-				_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n%s", ver, ts, formatDiffAsMarkdown(diffText)))
+		if rule.Changelog == changelogSummary {
+			_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n%s", ver, ts, summaryEntry(fc)))
+		} else if rule.Diff == diffNone {
+			_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n📄 File changed (diff turned off by rule)\n", ver, ts))
+		} else if md, ok := driverDiff(rel, oldP, newP, vault, rule.Diff); ok {
+			_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n%s", ver, ts, md))
+		} else if md, efc, ok := extractDiff(rule.Extract, rel, oldP, newP); ok {
+			efc.Path, efc.State = rel, stateModified
+			fc = efc
+			_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n%s", ver, ts, md))
+		} else if _, err := repo.FS.Stat(oldP); err == nil {
+			diffText, _ := unifiedDiffMasked(oldP, newP, mask)
+			if md := formatDiffAsMarkdown(diffText); mask != nil && diffText != "" && md == "" {
+				_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n📄 Only ignored properties or whitespace changed\n", ver, ts))
+			} else if diffText != "" {
+				_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n%s", ver, ts, md))
 			} else {
 				_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n📄 File changed (no readable diff)\n", ver, ts))
 			}
 		} else {
 			_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n📄 File changed (encoding issues, diff skipped)\n", ver, ts))
 		}
+		checkFile(cfg.CheckCommand, rel, clPath, &fc, warnCheck)
+		changes = append(changes, fc)
 	}
 	// handle deleted files
 	for _, rel := range deletedFiles {
@@ -442,95 +1141,308 @@ func updateGitnot() error {
 
 		// move snapshot to deleted store
 		from := filepath.Join(snapshotDir, rel)
+		changes = append(changes, measureFiles(rel, from, "", stateDeleted))
 		to := filepath.Join(deletedDir, rel)
-		if _, err := os.Stat(from); err == nil {
+		if _, err := repo.FS.Stat(from); err == nil {
 			_ = safeMkdirAllForFile(to)
-			_ = copyFile(from, to) // Use copy instead of move for safety
-			_ = os.Remove(from)
+			if err := copyFile(from, to); err == nil { // Use copy instead of move for safety
+				// gc ages deleted copies by their mtime, which
+				// copy.preserve_times would set to the note's own
+				_ = repo.FS.Chtimes(longPath(to), now, now)
+			}
 		}
 	}
+	// untracked files are still on disk: no deleted copy, and the changelog
+	// is archived once the version is committed
+	for _, rel := range untrackedFiles {
+		clPath := filepath.Join(changelogDir, rel+".log")
+		_ = safeMkdirAllForFile(clPath)
+		_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n🚫 No longer tracked (file kept).\n", ver, ts))
+		changes = append(changes, FileChange{Path: rel, State: stateUntracked})
+	}
+	recordRemaps(moved, current, ver, ts)
+	changes = append(changes, recordMetaChanges(metaFiles, prevMeta, curMeta, ver, ts)...)
 
-	// Atomic snapshot replacement using temporary directory
-	if _, err := os.Stat(snapshotDir); err == nil {
-		tempDir, err := ioutil.TempDir("", "gitnot_snapshot_")
-		if err != nil {
+	var unstable []string
+	// Atomic snapshot replacement using a temporary directory inside the
+	// store; the previous snapshot is kept as snapshot.old until the version
+	// is committed so an interrupted run can be rolled back.
+	if _, err := repo.FS.Stat(snapshotDir); err == nil {
+		tempDir := snapshotTmpDir
+		_ = repo.FS.RemoveAll(tempDir)
+		if err := repo.FS.MkdirAll(tempDir, 0o755); err != nil {
 			fmt.Printf("⚠️  Warning: Could not create temp directory: %v\n", err)
 		} else {
 			// Copy current files to temp location
 			allOk := true
 			for _, file := range files {
+				if interruptRequested() {
+					allOk = false
+					break
+				}
 				rel := file
 				target := filepath.Join(tempDir, rel)
 				if err := safeMkdirAllForFile(target); err != nil {
 					allOk = false
 					break
 				}
-				if err := copyFile(file, target); err != nil {
+				h, moved, err := copyStable(file, target, current[file])
+				if err != nil {
+					allOk = false
+					break
+				}
+				if moved {
+					// the changelog entry was written from an earlier read
+					unstable = append(unstable, file)
+					if changes, err = holdUnstable(journal, file, tempDir, oldHashes, current, changes); err != nil {
+						allOk = false
+						break
+					}
+					continue
+				}
+				if err := storeVersionObject(cfg, target, h, oldHashes[file]); err != nil {
 					allOk = false
 					break
 				}
 			}
+			// online-only placeholders, unreadable files and files
+			// held back by --paths-from keep their previous snapshot
+			for _, rel := range slices.Concat(deferred, sortedKeys(unreadable), held) {
+				if _, ok := current[rel]; !ok || !allOk {
+					continue
+				}
+				if err := copyFile(filepath.Join(snapshotDir, rel), filepath.Join(tempDir, rel)); err != nil {
+					allOk = false
+				}
+			}
 
 			if allOk {
 				// Atomic replacement
-				if err := os.RemoveAll(snapshotDir); err != nil {
-					fmt.Printf("⚠️  Warning: Could not remove old snapshot: %v\n", err)
-				} else if err := os.Rename(tempDir, snapshotDir); err != nil {
+				_ = repo.FS.RemoveAll(snapshotOldDir)
+				if err := repo.FS.Rename(snapshotDir, snapshotOldDir); err != nil {
+					fmt.Printf("⚠️  Warning: Could not set aside old snapshot: %v\n", err)
+					_ = repo.FS.RemoveAll(tempDir)
+				} else if err := repo.FS.Rename(tempDir, snapshotDir); err != nil {
 					fmt.Printf("⚠️  Warning: Could not move new snapshot: %v\n", err)
+					_ = repo.FS.Rename(snapshotOldDir, snapshotDir)
 				}
 			} else {
-				fmt.Printf("⚠️  Warning: Could not update snapshot\n")
-				_ = os.RemoveAll(tempDir) // cleanup
+				if !interruptRequested() {
+					fmt.Printf("⚠️  Warning: Could not update snapshot\n")
+				}
+				_ = repo.FS.RemoveAll(tempDir) // cleanup
 			}
 		}
 	} else {
-		fmt.Println("⚠️  Snapshot folder missing. Please reinitialize with 'gitnot --init'")
-		return nil
+		if err := abandonUpdate(journal); err != nil {
+			fmt.Printf("⚠️  Warning: Could not undo the abandoned update: %v\n", err)
+		}
+		return fmt.Errorf("snapshot folder missing; please reinitialize with 'gitnot --init'")
 	}
 
-	// save hashes
-	if err := saveJSON(hashesFile, current); err != nil {
+	// last checkpoint: from here on the version is committed
+	if err := stopIfInterrupted(journal, prev); err != nil {
 		return err
 	}
-	fmt.Printf("⬆ Version bumped → v%.1f\n", ver)
-	fmt.Printf("📝 %d files tracked\n", len(files))
-	return nil
-}
-
-func showStatus() error {
-	if _, err := os.Stat(gitnotDir); errors.Is(err, os.ErrNotExist) {
-		return fmt.Errorf("❌ gitnot not initialized")
+	err = checkGeneration(gen, prev)
+	if err == nil {
+		err = checkVersionFree(ver)
 	}
-	var oldHashes map[string]string
-	_ = loadJSON(hashesFile, &oldHashes)
-	files, err := getAllTextFiles(".")
 	if err != nil {
+		if rerr := abandonUpdate(journal); rerr != nil {
+			fmt.Printf("⚠️  Warning: Could not undo the abandoned update: %v\n", rerr)
+		}
 		return err
 	}
-	current := map[string]string{}
-	for _, f := range files {
-		current[f] = hashFile(f)
+	// save hashes
+	if err := saveJSONStreamed(hashesFile, func(w io.Writer) error { return writeJSONMap(w, current, 0) }); err != nil {
+		return err
 	}
-	var newFiles, changedFiles, deletedFiles []string
-	for f := range current {
-		if _, ok := oldHashes[f]; !ok {
-			newFiles = append(newFiles, f)
+	manifest := Manifest{Version: ver, Timestamp: now, Files: current, Changes: changes, GitHead: gitHead(),
+		Env: captureEnvironment(cfg.RecordEnvironment), Author: author, Message: opts.Message, Meta: curMeta, Remaps: remaps,
+		Unreadable: unreadableErrors(unreadable)}
+	for _, rel := range sortedKeys(adopted) {
+		if _, ok := current[rel]; ok {
+			manifest.Adopted = append(manifest.Adopted, rel)
+		}
+	}
+	if cfg.Narrative.Enabled {
+		if manifest.Narrative, err = narrate(cfg.Narrative, ver, changes); err != nil {
+			fmt.Printf("⚠️  Warning: %v\n", err)
+		}
+	}
+	manifest.ID, manifest.Clock = nextVersionID(currentDevice().ID, clock)
+	maybeSignManifest(&manifest)
+	if err := writeManifest(manifest); err != nil {
+		return err
+	}
+	if err := appendHistory(manifest); err != nil {
+		return err
+	}
+	labels := loadLabels()
+	if carried := carryLabelsAcrossRenames(labels, newFiles, deletedFiles, current, oldHashes); carried || len(dirRenames) > 0 && len(labels) > 0 {
+		if err := saveLabels(remapKeys(labels, dirRenames)); err != nil {
+			return err
 		}
 	}
+	if err := saveIndex(current); err != nil {
+		return err
+	}
+	if err := bumpGeneration(gen); err != nil {
+		return err
+	}
+	if err := recordDeviceHead(currentDevice().ID, manifest); err != nil {
+		return err
+	}
+	if err := writeVersion(ver); err != nil {
+		return err
+	}
+	if err := endJournal(); err != nil {
+		fmt.Printf("⚠️  Warning: Could not clear journal: %v\n", err)
+	}
+	inline := cfg.postUpdate() == postUpdateInline
+	if inline {
+		maybeAutoPack(cfg)
+	}
+	fmt.Printf("⬆ Version bumped → v%.1f\n", ver)
+	fmt.Printf("📝 %d files tracked\n", len(current))
+	if manifest.Narrative != "" {
+		fmt.Printf("📖 %s\n", manifest.Narrative)
+	}
+	archiveUntracked(untrackedFiles)
+	clearRevived(revived)
+	recordAutoTags(cfg, ver, now)
+	if len(deferred) > 0 {
+		fmt.Printf("☁️  %d online-only files skipped (not downloaded)\n", len(deferred))
+	}
+	if len(held) > 0 {
+		fmt.Printf("🎯 %d changed files outside --paths-from left for a later update\n", len(held))
+	}
+	if len(unstable) > 0 {
+		fmt.Printf("⚠️  %d files changed while being recorded (left for the next update): %s\n",
+			len(unstable), strings.Join(preview(unstable, 3), ", "))
+	}
+	if opts.ShowDiff {
+		printEntries(changes, ver)
+	}
+	if interruptRequested() {
+		fmt.Printf("⏹  Interrupted after v%.1f was recorded; skipped storage, mirrors, publishing and hooks\n", ver)
+		return nil
+	}
+	if !inline {
+		queuePostUpdate(cfg, manifest, opts.KeepTasks)
+		return nil
+	}
+	if err := storeRemote(cfg.Storage, manifest); err != nil {
+		fmt.Printf("⚠️  Warning: copying objects to storage %s failed: %v\n", cfg.Storage, err)
+	}
+	runMirrors(cfg.Mirrors)
+	runPublish(cfg.Publish, manifest)
+	runHooks(cfg.Hooks, manifest)
+	return nil
+}
+
+// statusOptions tunes showStatusWith; the zero value is the default status.
+type statusOptions struct {
+	Full  bool   // ignore the stat index and hash every file
+	Label string // only report files carrying this label
+	Why   bool   // list untracked files with the reason instead
+
+	Porcelain string // machine-readable format version ("v1"), "" for human output
+	NulTerm   bool   // with Porcelain, end records with NUL and never quote paths
+}
+
+// detectChanges compares the recorded and current trees and returns the
+// added, modified and deleted paths, each sorted.
+func detectChanges(old, current map[string]string) (newFiles, changedFiles, deletedFiles []string) {
 	for f, h := range current {
-		if oh, ok := oldHashes[f]; ok && oh != h {
+		if oh, ok := old[f]; !ok {
+			newFiles = append(newFiles, f)
+		} else if oh != h {
 			changedFiles = append(changedFiles, f)
 		}
 	}
-	for f := range oldHashes {
+	for f := range old {
 		if _, ok := current[f]; !ok {
 			deletedFiles = append(deletedFiles, f)
 		}
 	}
-	if len(newFiles)+len(changedFiles)+len(deletedFiles) == 0 {
+	sort.Strings(newFiles)
+	sort.Strings(changedFiles)
+	sort.Strings(deletedFiles)
+	return newFiles, changedFiles, deletedFiles
+}
+
+func showStatus() error {
+	return showStatusWith(statusOptions{})
+}
+
+func showStatusWith(opts statusOptions) error {
+	if _, err := repo.FS.Stat(gitnotDir); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("gitnot not initialized")
+	}
+	if opts.Why {
+		return showUntracked()
+	}
+	oldHashes := loadCommittedHashes()
+	setRetry(loadConfig().Retry)
+	useLockedFiles(loadConfig().LockedFiles)
+	defer releaseShadows()
+	filter := loadConfig().scanFilters()
+	var cache *dirCache
+	if loadConfig().SkipUnchangedDirs {
+		cache = loadDirCache(filter, opts.Full)
+	}
+	rep, err := scanTreeCached(".", filter, cache)
+	if err != nil {
+		return err
+	}
+	files, pruned := rep.Files, rep.Pruned
+	files, deferred := deferPlaceholders(files, loadConfig())
+	takeUnreadable(nil)
+	current, cached, hashed := hashWithIndex(files, loadIndex(), opts.Full || cache != nil && cache.Fresh)
+	carryDeferred(current, oldHashes, deferred)
+	unreadable := takeUnreadable(current)
+	maps.Copy(unreadable, unscannedFiles(rep.Unreadable, oldHashes))
+	files = holdUnreadable(files, current, oldHashes, unreadable)
+	newFiles, changedFiles, deletedFiles := detectChanges(oldHashes, current)
+	metaFiles, _, _ := pendingMetaChanges(loadConfig(), files, oldHashes, current)
+	if opts.Label != "" {
+		labels := loadLabels()
+		newFiles = filterByLabel(newFiles, labels, opts.Label)
+		changedFiles = filterByLabel(changedFiles, labels, opts.Label)
+		deletedFiles = filterByLabel(deletedFiles, labels, opts.Label)
+		metaFiles = filterByLabel(metaFiles, labels, opts.Label)
+	}
+	if opts.Porcelain != "" {
+		records := porcelainRecords(newFiles, changedFiles, deletedFiles, oldHashes, current)
+		return writePorcelain(recordsOutput(), records, opts.NulTerm)
+	}
+	untrackedFiles, deletedFiles := splitUntracked(deletedFiles)
+	base, _ := readVersion()
+	remaps := pendingRemaps(base)
+	dirRenames := detectDirRenames(newFiles, deletedFiles, current, oldHashes)
+	newFiles, deletedFiles = dropMoved(newFiles, deletedFiles, movedFiles(oldHashes, dirRenames))
+	if cache != nil && cache.Hits > 0 {
+		defer fmt.Printf("📂 %d unchanged folders not listed again\n", cache.Hits)
+	}
+	defer fmt.Printf("🔍 %d verified by cache, %d hashed\n", cached, hashed)
+	defer warnPruned(pruned)
+	defer reportHidden(rep.HiddenDirs, rep.HiddenFiles)
+	defer reportUnreadable(unreadable)
+	if len(deferred) > 0 {
+		defer fmt.Printf("☁️  %d online-only files skipped (set cloud_placeholders to \"hash\" to read them)\n", len(deferred))
+	}
+	if len(newFiles)+len(changedFiles)+len(deletedFiles)+len(untrackedFiles)+len(metaFiles)+len(remaps)+len(dirRenames) == 0 {
 		fmt.Println("✅ No changes detected")
 		return nil
 	}
+	for _, r := range remaps {
+		fmt.Printf("🔀 Remapped %s → %s (%d files)\n", filepath.ToSlash(r.From), filepath.ToSlash(r.To), r.Files)
+	}
+	for _, r := range dirRenames {
+		fmt.Printf("🔀 Folder renamed: %s → %s (%d files)\n", filepath.ToSlash(r.From), filepath.ToSlash(r.To), r.Files)
+	}
 	if len(newFiles) > 0 {
 		fmt.Printf("📄 New files (%d): %s\n", len(newFiles), strings.Join(preview(newFiles, 3), ", "))
 		if len(newFiles) > 3 {
@@ -549,6 +1461,21 @@ func showStatus() error {
 			fmt.Printf("    ... and %d more\n", len(deletedFiles)-3)
 		}
 	}
+	if len(untrackedFiles) > 0 {
+		fmt.Printf("🚫 Untracked (%d): %s\n", len(untrackedFiles), strings.Join(preview(untrackedFiles, 3), ", "))
+		if len(untrackedFiles) > 3 {
+			fmt.Printf("    ... and %d more\n", len(untrackedFiles)-3)
+		}
+	}
+	if pinned := pinnedIn(loadPins(), changedFiles, deletedFiles); len(pinned) > 0 {
+		fmt.Printf("📌 Pinned files changed (%d): %s\n", len(pinned), strings.Join(preview(pinned, 3), ", "))
+	}
+	if len(metaFiles) > 0 {
+		fmt.Printf("🔧 Metadata only (%d): %s\n", len(metaFiles), strings.Join(preview(metaFiles, 3), ", "))
+		if len(metaFiles) > 3 {
+			fmt.Printf("    ... and %d more\n", len(metaFiles)-3)
+		}
+	}
 	return nil
 }
 
@@ -559,48 +1486,128 @@ func preview(ss []string, n int) []string {
 	return ss[:n]
 }
 
-func showVersion() error {
-	v, err := readVersion()
-	if err != nil {
-		return err
-	}
-	fmt.Printf("📌 Current version: v%.1f\n", v)
-
-	// Display actually tracked files from hashes.json
-	var hashes map[string]string
-	if err := loadJSON(hashesFile, &hashes); err != nil {
-		fmt.Printf("⚠️ Could not load tracked files: %v\n", err)
-		return nil
-	}
-
-	if len(hashes) == 0 {
-		fmt.Printf("📁 No files are currently being tracked\n")
-	} else {
-		fmt.Printf("📁 Tracked files (%d):\n", len(hashes))
-		// Sort file names for consistent output
-		var files []string
-		for file := range hashes {
-			files = append(files, file)
-		}
-		sort.Strings(files)
-		for _, file := range files {
-			fmt.Printf("  • %s\n", file)
-		}
-	}
-
-	return nil
-}
-
 func showHelp() {
 	fmt.Print(`
 🔧 gitnot - Simple version control for personal projects
 
 Usage:
   gitnot          Track changes and bump version
+  gitnot -m "msg" Same, recording a message with the version
+  gitnot --show-diff
+                  Same, then print the changelog entries just written
+  gitnot --full   Same, computing every file's hash in full (no fast
+                  pre-filter) and listing every folder even with
+                  skip_unchanged_dirs
+  gitnot --paths-from <file|->
+                  Same, recording only the listed paths (one per line or
+                  NUL-separated; - reads stdin)
   gitnot --init   Initialize gitnot in current folder  
-  gitnot --show   Display current version
+                  on an existing store: --rescan adopts newly tracked files,
+                  --force starts over at v0.0 (history dropped, backup taken)
+  gitnot new <folder> [--template writing] [--profile file.json]
+                  Create a project with starter files and record v0.0
+  gitnot --show   Display current version (deprecated: use 'gitnot info')
   gitnot --status Show pending changes (without committing)
+                  add --full to re-hash every file instead of using the index
+  gitnot status --why
+                  List files that are not tracked, and why
+  gitnot status --porcelain=v1 [-z]
+                  Stable machine-readable status for scripts
   gitnot --help   Show this help message
+  gitnot --version
+                  Show gitnot's own version, commit and build info
+  --cpuprofile f, --memprofile f, --trace f
+                  Profile any command (for go tool pprof / go tool trace)
+  --root <folder> Run any command on the project in <folder>
+
+Commands:
+  gitnot info [--files] Version, tracked files, store size and configuration
+  gitnot history [--out file]
+                        All versions with their messages and file summaries
+  gitnot log [file | --pick] [--daily]
+                        List versions, or changes grouped by (journal) day
+  gitnot query '[since v] [until v] [in glob] [by name] [where field op value]'
+                        File changes as JSON, e.g. where words_added > 100
+  gitnot stats [file]   History statistics for a file (or all files, in order)
+  gitnot stats --export metrics.csv
+                        Export per-version metrics as CSV or JSON
+  gitnot show <file>[@version] [--copy]
+                        Print a file as it was at a version
+  gitnot diff [file... | --pick] [--version v] [--copy]
+                        Show how working files differ from a version
+  gitnot diff --against <folder> [file...] [--stat]
+                        Compare working files with another folder
+  gitnot restore <file|glob>... [--version v] [--dry-run] [--yes] | --all
+                        Put files back as of a version (unrecorded changes
+                        are saved to .gitnot/overwritten/ first);
+                        --paths-from <file|-> adds a list of paths
+  gitnot restore-meta [name|--latest]
+                        List or restore metadata backups
+  gitnot seal [--out file] [--remove] | unseal [file]
+                        Pack the whole history into one checked archive, and back
+  gitnot archive-mode on|off
+                        Make the project read-only (no new versions)
+  gitnot export --out <dir> [--version v] [--label l]
+                        Write tracked files as of a version to a folder
+  gitnot export --concat --out book.md [--version v] [--separator s]
+                        Merge markdown files (in 'order') into one document
+  gitnot export --format epub|pdf|docx --out book.epub
+                        Build the merged document with pandoc
+  gitnot alias [<name> "<command line>" | --remove <name>]
+                        Define shortcuts, e.g. gitnot alias draft "update -m drafting"
+  gitnot <name> ...      Run the gitnot-<name> plugin from your PATH
+  gitnot author set "Name" [--initials AB]
+                        Stamp your name on the versions you record
+  gitnot signing on <key> | off
+                        Sign new versions with an SSH ed25519 key
+  gitnot verify --signatures [--allowed-signers f] [--require]
+                        Check every version's signature
+  gitnot label <file> <label>... [--remove]
+                        Tag files; filter with 'gitnot status --label <label>'
+  gitnot pin <file|glob>... [--remove]
+                        Freeze reference files; updates refuse to record changes
+                        to them unless run with --force
+  gitnot untrack <file|glob>... [--undo] [--paths-from <file|->]
+                        Stop tracking files without deleting them
+  gitnot remap <old/prefix> <new/prefix>
+                        After moving a folder, carry its files' history along
+  gitnot adopt <file|folder>...
+                        Start tracking files at the current version, without
+                        recording a new one
+  gitnot today [--since 2024-05-01]
+                        What changed since the day began: files, lines, words
+  gitnot compare <version> [<version>]
+                        Files added, changed and deleted between two versions
+  gitnot tag [<name>... [--version v] | --remove <name>...]
+                        Name versions; tags work wherever a version is expected
+  gitnot git-hooks install [--pre-commit]
+                        Record a version after every git commit
+  gitnot repack [--full] Rewrite stored versions as deltas (or full copies)
+  gitnot pack            Move small stored objects into pack files
+  gitnot du [--top n]    Disk usage of the store, by area and by file
+  gitnot mirror          Bring the configured mirrors of the store up to date
+  gitnot storage sync    Copy every stored object to the storage backend
+  gitnot tasks [flush|clear]
+                        List post-update work queued with post_update, run it
+                        now or drop it
+  gitnot verify [--paths dir]... [--jobs n]
+                        Re-check stored versions against their hashes without
+                        changing anything
+  gitnot scrub [--repair] [--paths dir]... [--jobs n]
+                        Re-check every stored version against its hash and
+                        quarantine damaged copies
+  gitnot config lint     Check config.json and show what each rule matches
+  gitnot config export <file> | import <file> [--replace]
+                        Share extension, pattern and rule settings between projects
+  gitnot watch [--events]
+                        Record a version whenever tracked files change
+  gitnot bench [--runs n] [--synthetic n]
+                         Time the scan, hash, diff and copy phases
+  gitnot gc [--dry-run]  Purge deleted files past deleted_retention
+  gitnot protect set|clear
+                        Require a passphrase for destructive commands
+  gitnot migrate --from-python
+                        Convert a store created by the Python gitnot
 
 Examples:
   gitnot --init   # Start tracking this folder
@@ -621,78 +1628,349 @@ Features:
 
 // --- Small file helpers ---
 
-func copyFile(src, dst string) error {
-	srcF, err := os.Open(src)
-	if err != nil {
-		return err
+// copyRetries bounds how often copyStable re-copies a file that keeps
+// changing underneath it.
+const copyRetries = 3
+
+// copyStable copies src to dst and verifies the copy against the expected
+// hash. If the file changed after it was hashed, it re-copies until the copy
+// and the source agree (or retries run out) and returns the copy's hash with
+// moved set, so the caller can record what was actually stored.
+func copyStable(src, dst, expected string) (hash string, moved bool, err error) {
+	for attempt := 0; ; attempt++ {
+		if err := copyFile(src, dst); err != nil {
+			return "", false, err
+		}
+		got := hashFile(dst)
+		if got == expected {
+			return got, attempt > 0, nil
+		}
+		if attempt == copyRetries {
+			return got, true, nil
+		}
+		expected = hashFile(src)
 	}
-	defer srcF.Close()
-	if err := safeMkdirAllForFile(dst); err != nil {
-		return err
+}
+
+// holdUnstable takes back what an update wrote for rel, a file that changed
+// while it was being copied: its changelog entry and change are dropped and
+// its previous hash and snapshot kept (a new file is left out altogether),
+// so the next update records it in one consistent read.
+func holdUnstable(j *Journal, rel, tempDir string, old, current map[string]string, changes []FileChange) ([]FileChange, error) {
+	clPath := filepath.Join(changelogDir, rel+".log")
+	if size, ok := j.Changelogs[clPath]; ok {
+		if size < 0 {
+			_ = repo.FS.Remove(longPath(clPath))
+		} else if err := repo.FS.Truncate(longPath(clPath), size); err != nil {
+			return changes, err
+		}
 	}
-	dstF, err := os.Create(dst)
-	if err != nil {
-		return err
+	changes = slices.DeleteFunc(changes, func(fc FileChange) bool { return fc.Path == rel })
+	target := filepath.Join(tempDir, rel)
+	h, ok := old[rel]
+	if !ok {
+		delete(current, rel)
+		return changes, repo.FS.Remove(longPath(target))
 	}
-	defer dstF.Close()
-	_, err = io.Copy(dstF, srcF)
-	return err
+	current[rel] = h
+	return changes, copyFile(filepath.Join(snapshotDir, rel), target)
 }
 
 func appendToFile(p, text string) error {
 	if err := safeMkdirAllForFile(p); err != nil {
 		return err
 	}
-	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	f, err := repo.FS.OpenFile(longPath(p), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	_, err = f.WriteString(text)
+	_, err = io.WriteString(f, text)
 	return err
 }
 
+// --- Commands ---
+
+// subcommands are positional commands (e.g. `gitnot stats file.md`), each
+// parsing its own flags.
+var subcommands = map[string]func(args []string) error{
+	"stats":        runStats,
+	"status":       runStatus,
+	"restore":      runRestore,
+	"show":         runShow,
+	"query":        runQuery,
+	"diff":         runDiff,
+	"restore-meta": runRestoreMeta,
+	"new":          runNew,
+	"seal":         runSeal,
+	"unseal":       runUnseal,
+	"migrate":      runMigrate,
+	"version":      runVersion,
+	"info":         runInfo,
+	"versions":     runInfo,
+	"archive-mode": runArchiveMode,
+	"protect":      runProtect,
+	"gc":           runGC,
+	"label":        runLabel,
+	"pin":          runPin,
+	"untrack":      runUntrack,
+	"remap":        runRemap,
+	"adopt":        runAdopt,
+	"tag":          runTag,
+	"today":        runToday,
+	"compare":      runCompare,
+	"export":       runExport,
+	"log":          runLog,
+	"git-hooks":    runGitHooks,
+	"author":       runAuthor,
+	"signing":      runSigning,
+	"verify":       runVerify,
+	"history":      runHistory,
+	"repack":       runRepack,
+	"pack":         runPack,
+	"watch":        runWatch,
+	"du":           runDu,
+	"scrub":        runScrub,
+	"mirror":       runMirror,
+	"config":       runConfig,
+	"bench":        runBench,
+	"tasks":        runTasks,
+	"storage":      runStorage,
+	"devgen":       runDevgen, // hidden: synthetic trees for performance work
+}
+
+func runStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	full := fs.Bool("full", false, "hash every file instead of trusting the stat index")
+	label := fs.String("label", "", "only show files with this label")
+	why := fs.Bool("why", false, "list files that are not tracked and why")
+	var porcelain porcelainFlag
+	fs.Var(&porcelain, "porcelain", "stable machine-readable output (=v1)")
+	nul := fs.Bool("z", false, "with --porcelain, end records with NUL instead of newline")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if *nul && porcelain == "" {
+		porcelain = porcelainV1
+	}
+	return showStatusWith(statusOptions{Full: *full, Label: *label, Why: *why, Porcelain: string(porcelain), NulTerm: *nul})
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// positional arguments and returns the positionals in order.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func ensureInitialized() error {
+	if _, err := repo.FS.Stat(gitnotDir); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("gitnot not initialized; run --init")
+	}
+	return nil
+}
+
 // --- main ---
 
+// needsStore reports whether an invocation reads or writes .gitnot, so that
+// help and tool version output work even on a store we can't open.
+func needsStore(args []string) bool {
+	if len(args) == 0 {
+		return true
+	}
+	switch args[0] {
+	case "version", "--version", "-version", "help", "--help", "-help", "-h", "new", "unseal":
+		return false
+	}
+	return true
+}
+
+// initializes reports whether args run --init.
+func initializes(args []string) bool {
+	if len(args) == 0 || !strings.HasPrefix(args[0], "-") {
+		return false
+	}
+	for _, a := range args {
+		if a == "--" {
+			break
+		}
+		name, value, _ := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if strings.HasPrefix(a, "-") && name == "init" && value != "false" {
+			return true
+		}
+	}
+	return false
+}
+
+// recordedVersion reports whether the store has a version.txt.
+func recordedVersion() bool {
+	_, err := repo.FS.Stat(versionFile)
+	return err == nil
+}
+
 func main() {
+	args, profiles, err := splitProfileFlags(os.Args[1:])
+	if err != nil {
+		fmt.Println("❌", err)
+		os.Exit(2)
+	}
+	stop, err := startProfiling(profiles)
+	if err != nil {
+		stop()
+		fmt.Println("❌", err)
+		os.Exit(1)
+	}
+	code := run(args)
+	stop()
+	os.Exit(code)
+}
+
+// run executes one invocation and returns the process exit code.
+func run(args []string) int {
+	// --root may come before an alias, or inside one; the command line wins
+	args, root, err := splitRootFlag(args)
+	if err == nil {
+		args, err = expandAliases(args, loadUserConfig().Aliases)
+	}
+	if err == nil {
+		var aliasRoot string
+		args, aliasRoot, err = splitRootFlag(args)
+		if root == "" {
+			root = aliasRoot
+		}
+	}
+	if err == nil {
+		err = enterRoot(root)
+	}
+	if err != nil {
+		fmt.Println("❌", err)
+		return 2
+	}
+	if len(args) > 0 && args[0] == "update" {
+		args = args[1:]
+	}
+	if machineReadable(args) {
+		defer reserveStdout()()
+	}
+	if needsStore(args) {
+		if err := checkOutsideStore(); err != nil {
+			fmt.Println("❌", err)
+			return 1
+		}
+	}
+	if err := locateStore(); err != nil {
+		fmt.Println("❌", err)
+		return 2
+	}
+	applyMemoryLimit(loadConfig())
+	// --init makes the store, and a store that never recorded a version
+	// (a config.json written ahead of --init, say) has nothing to recover
+	// or upgrade
+	if needsStore(args) && !initializes(args) && recordedVersion() {
+		if err := recoverInterrupted(); err != nil {
+			fmt.Printf("⚠️  Could not recover from an interrupted run: %v\n", err)
+		}
+		// migrate upgrades the store itself, after converting a Python one
+		if len(args) == 0 || args[0] != "migrate" {
+			if err := migrateStore(); err != nil {
+				fmt.Println("❌", err)
+				return 1
+			}
+		}
+	}
+	if len(args) > 0 {
+		if cmd, ok := subcommands[args[0]]; ok {
+			if err := cmd(args[1:]); err != nil {
+				fmt.Println("❌", err)
+				return 1
+			}
+			return 0
+		}
+		if p, ok := findPlugin(args[0]); ok {
+			return runPluginCommand(p, args[1:])
+		}
+	}
+
 	// allow either flags or positional args like python version
 	initFlag := flag.Bool("init", false, "initialize gitnot")
-	showFlag := flag.Bool("show", false, "show version")
+	showFlag := flag.Bool("show", false, "show version (deprecated: use 'gitnot info')")
 	statusFlag := flag.Bool("status", false, "status only")
+	fullFlag := flag.Bool("full", false, "hash every file in full, without the index or the fast pre-filter")
 	helpFlag := flag.Bool("help", false, "help")
-	flag.Parse()
+	versionFlag := flag.Bool("version", false, "print gitnot build info")
+	showDiffFlag := flag.Bool("show-diff", false, "print the changelog entries this update writes")
+	forceFlag := flag.Bool("force", false, "record changes to pinned files; with --init, start over")
+	rescanFlag := flag.Bool("rescan", false, "with --init, adopt newly tracked files and keep history")
+	pathsFrom := flag.String("paths-from", "", "record only the paths listed in this file (- for stdin)")
+	var message string
+	flag.StringVar(&message, "m", "", "message recorded with the new version")
+	flag.StringVar(&message, "message", "", "message recorded with the new version")
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return 2
+	}
 
 	switch {
 	case *helpFlag:
 		showHelp()
-		return
+		return 0
+	case *versionFlag:
+		showToolVersion()
+		return 0
 	case *initFlag:
-		if err := initGitnot(); err != nil {
+		if err := initGitnotWith(initOptions{Force: *forceFlag, Rescan: *rescanFlag}); err != nil {
 			fmt.Println("❌", err)
-			os.Exit(1)
+			return 1
 		}
-		return
+		return 0
 	case *showFlag:
-		if err := showVersion(); err != nil {
+		fmt.Println("⚠️  --show is deprecated; use 'gitnot info' (add --files to list tracked files)")
+		if err := showInfo(true); err != nil {
 			fmt.Println("❌", err)
-			os.Exit(1)
+			return 1
 		}
-		return
+		return 0
 	case *statusFlag:
-		if err := showStatus(); err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+		if err := showStatusWith(statusOptions{Full: *fullFlag}); err != nil {
+			fmt.Println("❌", err)
+			return 1
 		}
-		return
+		return 0
 	default:
-		if err := updateGitnot(); err != nil {
+		opts := updateOptions{Message: message, ShowDiff: *showDiffFlag, Force: *forceFlag, Full: *fullFlag}
+		if *pathsFrom != "" {
+			paths, err := readPathList(*pathsFrom)
+			if err != nil {
+				fmt.Println("❌", err)
+				return 2
+			}
+			opts.Paths = paths
+		}
+		if err := updateGitnotWith(opts); err != nil {
+			if errors.Is(err, errInterrupted) {
+				fmt.Println("⏹ ", err)
+				return 130
+			}
 			if os.IsPermission(err) {
 				fmt.Println("❌ Permission denied. Check file/folder permissions.")
 			} else {
 				fmt.Printf("❌ Error: %v\n", err)
-				fmt.Println("💡 Try 'gitnot --init' to reset if needed.")
+				fmt.Println("💡 'gitnot --init --force' starts over if the store can't be repaired.")
 			}
-			os.Exit(1)
+			return 1
 		}
 	}
+	return 0
 }
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Test helper to create a temporary directory for testing
@@ -25,9 +28,34 @@ func setupTestDir(t *testing.T) string {
 		os.RemoveAll(tempDir)
 	})
 
+	// Keep per-user settings (author, device ID) out of the real config.
+	userDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", userDir)
+	t.Setenv("HOME", userDir)
+	t.Setenv("AppData", userDir)
+
 	return tempDir
 }
 
+// captureStdout runs f and returns what it wrote to standard output.
+func captureStdout(t *testing.T, f func()) []byte {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	out := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- b
+	}()
+	defer func() { os.Stdout = saved }()
+	f()
+	w.Close()
+	return <-out
+}
+
 // Helper to create a test file
 func createTestFile(t *testing.T, path, content string) {
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil && !os.IsExist(err) {
@@ -161,10 +189,13 @@ func TestFileHashing(t *testing.T) {
 		t.Errorf("Different content should produce different hash")
 	}
 
-	// Non-existent file should return special hash
-	nonExistentHash := hashFile("nonexistent.txt")
-	if !strings.HasPrefix(nonExistentHash, "unreadable-") {
-		t.Errorf("Non-existent file should return unreadable hash, got: %s", nonExistentHash)
+	// Non-existent file has no hash, and the error is noted
+	takeUnreadable(nil)
+	if nonExistentHash := hashFile("nonexistent.txt"); nonExistentHash != "" {
+		t.Errorf("Non-existent file should have no hash, got: %s", nonExistentHash)
+	}
+	if _, ok := takeUnreadable(nil)["nonexistent.txt"]; !ok {
+		t.Error("The read error should be noted")
 	}
 }
 
@@ -300,6 +331,12 @@ func TestIgnorePatterns(t *testing.T) {
 		{"file.js", []string{"node_modules/*"}, false},
 		{"exact.txt", []string{"exact.txt"}, true},
 		{"other.txt", []string{"exact.txt"}, false},
+		{"_minted-main/code.tex", []string{"_minted*/*"}, true},
+		{"paper/_minted-paper/code.tex", []string{"_minted*/*"}, true},
+		{"minted/code.tex", []string{"_minted*/*"}, false},
+		{"_minted-notes.txt", []string{"_minted*/*"}, false},
+		{"drafts/ch1.md", []string{"draft*"}, false},
+		{"Docker-assets/logo.png", []string{"Docker*"}, false},
 	}
 
 	for _, test := range tests {
@@ -377,6 +414,32 @@ func TestFileExtensionFiltering(t *testing.T) {
 	}
 }
 
+func TestIncludePatterns(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "Makefile", "all:")
+	createTestFile(t, "docker/Dockerfile", "FROM scratch")
+	createTestFile(t, "LICENSE", "MIT")
+	createTestFile(t, "vendor/Makefile", "all:")
+	createTestFile(t, "notes.md", "notes")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.IncludePatterns = []string{"Makefile", "Docker*", "docs/LICENSE"}
+	cfg.IgnorePatterns = append(cfg.IgnorePatterns, "vendor/*")
+	saveJSON(configFile, cfg)
+
+	files, err := getAllTextFiles(".")
+	if err != nil {
+		t.Fatalf("getAllTextFiles failed: %v", err)
+	}
+	want := []string{"Makefile", filepath.Join("docker", "Dockerfile"), "notes.md"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("Expected %v, got %v", want, files)
+	}
+}
+
 func TestUtilityFunctions(t *testing.T) {
 	setupTestDir(t)
 
@@ -438,7 +501,8 @@ func TestIsUnderGitnot(t *testing.T) {
 		{".gitnot/subdir/file.txt", true},
 		{"file.txt", false},
 		{"subdir/file.txt", false},
-		{".gitnothing/file.txt", true},    // This will match due to HasPrefix behavior
+		{".gitnot", true},
+		{".gitnothing/file.txt", false},   // a different folder that shares the prefix
 		{"other/.gitnot/file.txt", false}, // doesn't start with .gitnot
 	}
 
@@ -485,3 +549,109 @@ func TestJSONUtilities(t *testing.T) {
 		t.Error("loadJSON should fail for non-existent file")
 	}
 }
+
+func TestSaveJSONLeavesUnchangedFile(t *testing.T) {
+	setupTestDir(t)
+
+	if err := saveJSON("h.json", map[string]string{"b": "2", "a": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := os.ReadFile("h.json")
+	if want := "{\n  \"a\": \"1\",\n  \"b\": \"2\"\n}"; string(first) != want {
+		t.Errorf("Expected sorted keys, got %q", first)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes("h.json", old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saveJSON("h.json", map[string]string{"a": "1", "b": "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := os.Stat("h.json"); !fi.ModTime().Equal(old) {
+		t.Error("Saving the same content should not rewrite the file")
+	}
+	if err := saveJSON("h.json", map[string]string{"a": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile("h.json"); string(b) == string(first) {
+		t.Error("Changed content was not written")
+	}
+}
+
+func TestCopyStable(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "live.txt", "current content")
+
+	// Hash matches: plain copy
+	h, moved, err := copyStable("live.txt", "copy1.txt", hashFile("live.txt"))
+	if err != nil || moved || h != hashFile("live.txt") {
+		t.Errorf("Stable copy: hash=%s moved=%t err=%v", h, moved, err)
+	}
+
+	// File changed after hashing: the copy's real hash is returned and flagged
+	h, moved, err = copyStable("live.txt", "copy2.txt", "stale-hash")
+	if err != nil {
+		t.Fatalf("copyStable failed: %v", err)
+	}
+	if !moved {
+		t.Error("Expected the change to be flagged")
+	}
+	if h != hashFile("copy2.txt") {
+		t.Error("Returned hash must describe the stored copy")
+	}
+}
+
+func TestHoldUnstable(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.md", "first\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	old := loadCommittedHashes()
+	clPath := filepath.Join(changelogDir, "a.md.log")
+	newLog := filepath.Join(changelogDir, "b.md.log")
+	before, _ := os.ReadFile(clPath)
+	j, err := beginJournal("update", 0.0, 0.1, []string{clPath, newLog})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendToFile(clPath, "\n## v0.1\nsecond\n")
+	appendToFile(newLog, "\n## v0.1\n📄 New file added.\n")
+	tempDir := snapshotTmpDir
+	createTestFile(t, filepath.Join(tempDir, "a.md"), "third\n")
+	createTestFile(t, filepath.Join(tempDir, "b.md"), "new\n")
+	current := map[string]string{"a.md": "changed", "b.md": "added"}
+	changes := []FileChange{{Path: "a.md", State: stateModified}, {Path: "b.md", State: stateAdded}}
+
+	changes, err = holdUnstable(j, "a.md", tempDir, old, current, changes)
+	if err != nil {
+		t.Fatalf("holdUnstable failed: %v", err)
+	}
+	changes, err = holdUnstable(j, "b.md", tempDir, old, current, changes)
+	if err != nil {
+		t.Fatalf("holdUnstable failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Expected the held files' changes dropped, got %v", changes)
+	}
+	if current["a.md"] != old["a.md"] {
+		t.Error("A held file should keep its previous hash")
+	}
+	if _, ok := current["b.md"]; ok {
+		t.Error("A held new file should be left out of the version")
+	}
+	if b, _ := os.ReadFile(filepath.Join(tempDir, "a.md")); string(b) != "first\n" {
+		t.Errorf("A held file should keep its previous snapshot, got %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "b.md")); err == nil {
+		t.Error("A held new file should not be in the snapshot")
+	}
+	if b, _ := os.ReadFile(clPath); string(b) != string(before) {
+		t.Errorf("The held file's changelog entry should be taken back, got %q", b)
+	}
+	if _, err := os.Stat(newLog); err == nil {
+		t.Error("A held new file's changelog should be removed")
+	}
+}
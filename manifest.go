@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codinganovel/go-difflib/difflib"
+)
+
+// --- Manifests ---
+//
+// Every version writes a manifest to .gitnot/manifests/v<version>.json. It
+// records the full tracked tree (path → hash) plus per-file change metrics,
+// so history questions can be answered without re-parsing changelogs, and a
+// hash per folder (see merkle.go) for comparing versions quickly.
+
+// FileChange records what happened to one file in one version.
+type FileChange struct {
+	Path         string `json:"path"`
+	State        string `json:"state"` // added, modified, deleted, metadata, untracked
+	LinesAdded   int    `json:"lines_added"`
+	LinesRemoved int    `json:"lines_removed"`
+	WordsAdded   int    `json:"words_added"`
+	WordsRemoved int    `json:"words_removed"`
+	Lines        int    `json:"lines"` // length after the change
+	Words        int    `json:"words"`
+	Issues       *int   `json:"issues,omitempty"` // check_command findings, nil when not checked
+
+	RevivedFrom *float64 `json:"revived_from,omitempty"` // a file back after deletion: the version that last had it
+}
+
+type Manifest struct {
+	Version    float64             `json:"version"`
+	Timestamp  time.Time           `json:"timestamp"`
+	Files      map[string]string   `json:"files"`
+	Changes    []FileChange        `json:"changes"`
+	GitHead    string              `json:"git_head,omitempty"` // commit checked out when recorded
+	Env        *Environment        `json:"env,omitempty"`      // where it was recorded, see record_environment
+	Author     *Author             `json:"author,omitempty"`
+	Message    string              `json:"message,omitempty"`   // from gitnot -m
+	Narrative  string              `json:"narrative,omitempty"` // one-sentence summary, see narrative.go
+	ID         string              `json:"id,omitempty"`        // device-sequence, unique across machines
+	Clock      map[string]int      `json:"clock,omitempty"`     // latest sequence seen per device
+	Signature  *Signature          `json:"signature,omitempty"`
+	Meta       map[string]FileMeta `json:"meta,omitempty"`       // see track_metadata
+	Remaps     []Remap             `json:"remaps,omitempty"`     // prefix moves made with gitnot remap
+	Adopted    []string            `json:"adopted,omitempty"`    // files registered with gitnot adopt since the previous version
+	Unreadable map[string]string   `json:"unreadable,omitempty"` // path → read error; the file's earlier content was kept
+	Tree       map[string]string   `json:"tree,omitempty"`       // folder → rollup hash, see merkle.go
+}
+
+const (
+	stateAdded    = "added"
+	stateModified = "modified"
+	stateDeleted  = "deleted"
+)
+
+// parseManifest decodes a manifest file.
+func parseManifest(b []byte) (Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+func manifestPath(v float64) string {
+	return filepath.Join(manifestDir, fmt.Sprintf("v%.1f.json", v))
+}
+
+func writeManifest(m Manifest) error {
+	sort.Slice(m.Changes, func(i, j int) bool { return m.Changes[i].Path < m.Changes[j].Path })
+	if m.Tree == nil {
+		m.Tree = treeHashes(m.Files)
+	}
+	return saveJSONStreamed(manifestPath(m.Version), m.encode)
+}
+
+// encode writes m as saveJSON would, streaming the file list and the tree
+// entry by entry. The rest is encoded as usual around them: "files" comes
+// third, after two fields that can't contain it, and "tree" comes last.
+func (m Manifest) encode(w io.Writer) error {
+	files, tree := m.Files, m.Tree
+	m.Files, m.Tree = nil, nil
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	head, rest, ok := bytes.Cut(b, []byte(`"files": null`))
+	if !ok {
+		return fmt.Errorf("encoding manifest v%.1f: no file list", m.Version)
+	}
+	if _, err := fmt.Fprintf(w, `%s"files": `, head); err != nil {
+		return err
+	}
+	if err := writeJSONMap(w, files, 1); err != nil {
+		return err
+	}
+	if _, err := w.Write(bytes.TrimSuffix(rest, []byte("\n}"))); err != nil {
+		return err
+	}
+	if len(tree) > 0 {
+		if _, err := io.WriteString(w, ",\n  \"tree\": "); err != nil {
+			return err
+		}
+		if err := writeJSONMap(w, tree, 1); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "\n}")
+	return err
+}
+
+// errStopManifests ends eachManifest early without an error.
+var errStopManifests = errors.New("stop")
+
+// manifestNames lists the manifest files in version order. A store created
+// before manifests existed has none.
+func manifestNames() ([]string, error) {
+	entries, err := repo.FS.ReadDir(manifestDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	versions := map[string]float64{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, "v") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSuffix(name[1:], ".json"), 64)
+		if err != nil {
+			continue
+		}
+		names = append(names, name)
+		versions[name] = v
+	}
+	sort.Slice(names, func(i, j int) bool { return versions[names[i]] < versions[names[j]] })
+	return names, nil
+}
+
+// eachManifest calls fn with every recorded manifest in version order. They
+// are read one at a time, so only one version's file list is in memory
+// however long the history; fn returning errStopManifests ends the walk.
+func eachManifest(fn func(Manifest) error) error {
+	names, err := manifestNames()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		var m Manifest
+		if err := loadJSON(filepath.Join(manifestDir, name), &m); err != nil {
+			return fmt.Errorf("reading manifest %s: %w", name, err)
+		}
+		if err := fn(m); errors.Is(err, errStopManifests) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadManifests returns every recorded manifest ordered by version. A store
+// created before manifests existed simply yields an empty slice. On a large
+// tree with a long history this is a lot of memory; prefer eachManifest, or
+// loadManifestHeads when the file lists aren't needed.
+func loadManifests() ([]Manifest, error) {
+	var out []Manifest
+	err := eachManifest(func(m Manifest) error {
+		out = append(out, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// loadManifestHeads is loadManifests without each version's file list, tree
+// hashes and file metadata: enough for history, logs and statistics, which
+// go by the recorded changes.
+func loadManifestHeads() ([]Manifest, error) {
+	var out []Manifest
+	err := eachManifest(func(m Manifest) error {
+		m.Files, m.Tree, m.Meta = nil, nil, nil
+		out = append(out, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// withFiles returns m with its file list, reading it back for a head.
+func withFiles(m Manifest) (Manifest, error) {
+	if m.Files != nil {
+		return m, nil
+	}
+	return loadManifest(m.Version)
+}
+
+// --- Text metrics ---
+
+func splitTextLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func countWords(s string) int {
+	return len(strings.Fields(s))
+}
+
+// measureChange compares two file contents line by line and returns a
+// FileChange with added/removed line and word counts filled in.
+func measureChange(oldText, newText string) FileChange {
+	a, b := splitTextLines(oldText), splitTextLines(newText)
+	fc := FileChange{Lines: len(b), Words: countWords(newText)}
+	for _, op := range difflib.NewMatcher(a, b).GetOpCodes() {
+		if op.Tag == 'e' {
+			continue
+		}
+		for _, l := range a[op.I1:op.I2] {
+			fc.LinesRemoved++
+			fc.WordsRemoved += countWords(l)
+		}
+		for _, l := range b[op.J1:op.J2] {
+			fc.LinesAdded++
+			fc.WordsAdded += countWords(l)
+		}
+	}
+	return fc
+}
+
+// measureFiles reads both sides (either may be "" for a missing file) and
+// returns the change metrics tagged with path and state.
+func measureFiles(rel, oldPath, newPath, state string) FileChange {
+	var oldB, newB []byte
+	if oldPath != "" {
+		oldB, _ = os.ReadFile(longPath(oldPath))
+	}
+	if newPath != "" {
+		newB, _ = os.ReadFile(longPath(newPath))
+	}
+	fc := measureChange(string(oldB), string(newB))
+	fc.Path = rel
+	fc.State = state
+	return fc
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// --- Memory ---
+//
+// Memory grows with the number of tracked files, not with the length of the
+// history. Commands that walk the history (log, stats, today, verify, scrub,
+// repack, signature checks) read manifests one at a time, and those that
+// only need the recorded changes drop the file lists as they go (see
+// eachManifest and loadManifestHeads); changelog diffs are written file by
+// file as they are made. An update streams what it writes: hashes.json,
+// the stat index and the manifest go to disk entry by entry (see
+// saveJSONStreamed) rather than as one text built in memory, and the
+// previous version's file list is read once. Finding the changes still
+// compares whole maps, so the scanned file list, the current and previous
+// hashes, the stat index and, with "skip_unchanged_dirs", the folder cache
+// are held at once; peak memory is a fixed amount per tracked file.
+// `gitnot bench --synthetic N` measures it for a tree of N files on this
+// machine, and TestLargeTree checks it stays under 3 KB.
+//
+// "memory_limit_mb" sets a soft limit for the Go runtime: as the heap nears
+// it, garbage is collected sooner, instead of letting the heap grow to twice
+// what is live. It is not a cap; gitnot goes past it when it needs more, so
+// too low a limit only makes gitnot slower.
+
+// applyMemoryLimit sets the soft memory limit from cfg, if any; GOMEMLIMIT
+// in the environment applies otherwise.
+func applyMemoryLimit(cfg Config) {
+	if cfg.MemoryLimitMB > 0 {
+		debug.SetMemoryLimit(int64(cfg.MemoryLimitMB) << 20)
+	}
+}
+
+func validateMemoryLimit(cfg Config) []configIssue {
+	switch {
+	case cfg.MemoryLimitMB < 0:
+		return []configIssue{{"memory_limit_mb", "must not be negative"}}
+	case cfg.MemoryLimitMB > 0 && cfg.MemoryLimitMB < 64:
+		return []configIssue{{"memory_limit_mb", fmt.Sprintf("%d MB leaves too little room to work in; use at least 64", cfg.MemoryLimitMB)}}
+	}
+	return nil
+}
+
+// memoryObtained is how much memory the process has taken from the system
+// so far, which is about its peak.
+func memoryObtained() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.Sys
+}
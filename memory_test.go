@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestEachManifestStreams(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.md", "One\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	for i := 1; i <= 10; i++ {
+		createTestFile(t, "a.md", fmt.Sprintf("One, edit %d\n", i))
+		if err := updateGitnot(); err != nil {
+			t.Fatalf("updateGitnot failed: %v", err)
+		}
+	}
+
+	// version order is numeric: v0.9 comes before v1.0
+	var seen []float64
+	if err := eachManifest(func(m Manifest) error {
+		seen = append(seen, m.Version)
+		if m.Version == 0.9 {
+			return errStopManifests
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 10 || seen[9] != 0.9 {
+		t.Errorf("Expected v0.0 to v0.9 before stopping, got %v", seen)
+	}
+
+	heads, err := loadManifestHeads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(heads) != 11 {
+		t.Fatalf("Expected 11 versions, got %d", len(heads))
+	}
+	last := heads[len(heads)-1]
+	if last.Files != nil || last.Tree != nil || len(last.Changes) != 1 {
+		t.Errorf("Expected a head with changes and no file list, got %+v", last)
+	}
+	full, err := withFiles(last)
+	if err != nil || full.Files["a.md"] != hashFile("a.md") {
+		t.Errorf("Expected withFiles to read the file list back, got %v (%v)", full.Files, err)
+	}
+}
+
+func TestStreamedJSONMatchesSaveJSON(t *testing.T) {
+	setupTestDir(t)
+	files := map[string]string{"a.md": "h1", "b & <c>.md": "h2", "z/é.md": "h3"}
+	manifests := []Manifest{
+		{Version: 1.2, Files: files, Changes: []FileChange{{Path: "a.md", State: stateAdded}},
+			Message: `"files": null`, Clock: map[string]int{"d1": 3}, Tree: map[string]string{"": "t0", "z": "t1"}},
+		{Version: 0.1, Files: map[string]string{}},
+		{Version: 0.2},
+	}
+	for _, m := range manifests {
+		if err := saveJSON("want.json", m); err != nil {
+			t.Fatal(err)
+		}
+		if err := saveJSONStreamed("got.json", m.encode); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := os.ReadFile("want.json")
+		got, _ := os.ReadFile("got.json")
+		if string(got) != string(want) {
+			t.Errorf("Manifest v%.1f streamed as\n%s\nwant\n%s", m.Version, got, want)
+		}
+	}
+
+	idx := map[string]IndexEntry{"a.md": {Size: 3, ModTime: 7, Hash: "h1", Fast: &fastHash{XXH64: "x", SHA1: "s"}}, "b.md": {Size: 1}}
+	if err := saveJSON("want.json", idx); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveJSONStreamed("got.json", func(w io.Writer) error { return writeJSONMap(w, idx, 0) }); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := os.ReadFile("want.json")
+	got, _ := os.ReadFile("got.json")
+	if string(got) != string(want) {
+		t.Errorf("Index streamed as\n%s\nwant\n%s", got, want)
+	}
+
+	// rewriting the same content leaves the file alone
+	before, _ := os.Stat("got.json")
+	if err := saveJSONStreamed("got.json", func(w io.Writer) error { return writeJSONMap(w, idx, 0) }); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.Stat("got.json"); !os.SameFile(before, after) {
+		t.Error("Expected unchanged content not to replace the file")
+	}
+}
+
+func TestValidateMemoryLimit(t *testing.T) {
+	for mb, ok := range map[int]bool{0: true, 512: true, 16: false, -1: false} {
+		if issues := validateMemoryLimit(Config{MemoryLimitMB: mb}); (len(issues) == 0) != ok {
+			t.Errorf("memory_limit_mb %d: got issues %v", mb, issues)
+		}
+	}
+}
+
+// TestLargeTree records a tree of GITNOT_LARGE_TREE files (a million, say)
+// and checks that memory stays in proportion to it. It is skipped unless
+// that is set, as it takes minutes and gigabytes of disk.
+func TestLargeTree(t *testing.T) {
+	n, _ := strconv.Atoi(os.Getenv("GITNOT_LARGE_TREE"))
+	if n <= 0 {
+		t.Skip("set GITNOT_LARGE_TREE to the number of files to try")
+	}
+	setupTestDir(t)
+	phases, err := benchSynthetic(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range phases {
+		t.Log(p)
+	}
+	perFile := memoryObtained() / uint64(n)
+	t.Logf("%s of memory per file", formatBytes(int64(perFile)))
+	if perFile > 3<<10 {
+		t.Errorf("Expected at most 3 KB of memory per file, took %s", formatBytes(int64(perFile)))
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// --- Tree hashes ---
+//
+// Each manifest also records a rollup hash for every folder ("tree"): the
+// hash of its sorted entries, where a file contributes its content hash and
+// a subfolder its own rollup. Two versions whose root hashes match hold the
+// same files, whichever machine recorded them, and when they differ only the
+// folders whose hashes differ need looking into. Folders are keyed by their
+// slash-separated path, with "." for the project root. Manifests written
+// before tree hashes existed get them computed when they are compared.
+
+const treeRoot = "."
+
+// treeHashes computes the rollup hash of every folder holding tracked files.
+func treeHashes(files map[string]string) map[string]string {
+	entries := map[string]map[string]string{treeRoot: {}} // folder → entry → hash, "" for a subfolder
+	for rel, h := range files {
+		p := filepath.ToSlash(rel)
+		dir := path.Dir(p)
+		if entries[dir] == nil {
+			entries[dir] = map[string]string{}
+		}
+		entries[dir]["file "+path.Base(p)] = h
+		for dir != treeRoot {
+			parent := path.Dir(dir)
+			if entries[parent] == nil {
+				entries[parent] = map[string]string{}
+			}
+			if _, ok := entries[parent]["tree "+path.Base(dir)]; ok {
+				break
+			}
+			entries[parent]["tree "+path.Base(dir)] = ""
+			dir = parent
+		}
+	}
+
+	dirs := sortedKeys(entries)
+	// deepest folders first, so every subfolder is hashed before its parent
+	sort.SliceStable(dirs, func(i, j int) bool { return treeDepth(dirs[i]) > treeDepth(dirs[j]) })
+	tree := make(map[string]string, len(dirs))
+	for _, dir := range dirs {
+		var b strings.Builder
+		for _, e := range sortedKeys(entries[dir]) {
+			h := entries[dir][e]
+			if kind, name, _ := strings.Cut(e, " "); kind == "tree" {
+				h = tree[path.Join(dir, name)]
+			}
+			fmt.Fprintf(&b, "%s\x00%s\n", e, h)
+		}
+		tree[dir] = contentHash([]byte(b.String()))
+	}
+	return tree
+}
+
+func treeDepth(dir string) int {
+	if dir == treeRoot {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
+
+// manifestTree returns the folder hashes of m, computing them for manifests
+// recorded without.
+func manifestTree(m Manifest) map[string]string {
+	if m.Tree != nil {
+		return m.Tree
+	}
+	return treeHashes(m.Files)
+}
+
+// diffTrees lists the files added, changed and deleted going from version a
+// to version b, like detectChanges, but only looks inside folders whose
+// hashes differ: files in unchanged folders are never compared.
+func diffTrees(a, b Manifest) (added, changed, deleted []string) {
+	ta, tb := manifestTree(a), manifestTree(b)
+	if ta[treeRoot] == tb[treeRoot] {
+		return nil, nil, nil
+	}
+	filesA, filesB := filesByFolder(a.Files), filesByFolder(b.Files)
+	subdirs := map[string][]string{}
+	for _, t := range []map[string]string{ta, tb} {
+		for dir := range t {
+			if dir != treeRoot {
+				subdirs[path.Dir(dir)] = append(subdirs[path.Dir(dir)], dir)
+			}
+		}
+	}
+
+	queue := []string{treeRoot}
+	seen := map[string]bool{treeRoot: true}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		for _, rel := range filesB[dir] {
+			if h, ok := a.Files[rel]; !ok {
+				added = append(added, rel)
+			} else if h != b.Files[rel] {
+				changed = append(changed, rel)
+			}
+		}
+		for _, rel := range filesA[dir] {
+			if _, ok := b.Files[rel]; !ok {
+				deleted = append(deleted, rel)
+			}
+		}
+		for _, sub := range subdirs[dir] {
+			if !seen[sub] && ta[sub] != tb[sub] {
+				seen[sub] = true
+				queue = append(queue, sub)
+			}
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(deleted)
+	return added, changed, deleted
+}
+
+// filesByFolder groups the tracked paths by the folder holding them.
+func filesByFolder(files map[string]string) map[string][]string {
+	out := map[string][]string{}
+	for rel := range files {
+		dir := path.Dir(filepath.ToSlash(rel))
+		out[dir] = append(out[dir], rel)
+	}
+	return out
+}
+
+// compareVersions is `gitnot compare a b`.
+func compareVersions(a, b Manifest) string {
+	added, changed, deleted := diffTrees(a, b)
+	if len(added)+len(changed)+len(deleted) == 0 {
+		return fmt.Sprintf("✅ v%.1f and v%.1f hold the same files\n", a.Version, b.Version)
+	}
+	var s strings.Builder
+	fmt.Fprintf(&s, "🌳 v%.1f → v%.1f: %d added, %d changed, %d deleted\n",
+		a.Version, b.Version, len(added), len(changed), len(deleted))
+	for _, group := range []struct {
+		mark  string
+		paths []string
+	}{{"+", added}, {"≠", changed}, {"-", deleted}} {
+		for _, rel := range group.paths {
+			fmt.Fprintf(&s, "%s %s\n", group.mark, filepath.ToSlash(rel))
+		}
+	}
+	return s.String()
+}
+
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 || len(rest) > 2 {
+		return fmt.Errorf("usage: gitnot compare <version> [<version>]")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	var ms [2]Manifest
+	for i := range ms {
+		arg := ""
+		if i < len(rest) {
+			arg = rest[i]
+		}
+		v, err := parseVersionArg(arg)
+		if err != nil {
+			return err
+		}
+		if ms[i], err = loadManifest(v); err != nil {
+			return err
+		}
+	}
+	fmt.Print(compareVersions(ms[0], ms[1]))
+	return nil
+}
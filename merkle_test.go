@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTreeHashes(t *testing.T) {
+	files := map[string]string{
+		"a.md":                              "1",
+		filepath.Join("notes", "b.md"):      "2",
+		filepath.Join("notes", "x", "c.md"): "3",
+		filepath.Join("drafts", "d.md"):     "4",
+	}
+	before := treeHashes(files)
+	for _, dir := range []string{".", "notes", "notes/x", "drafts"} {
+		if before[dir] == "" {
+			t.Errorf("No hash for %s in %v", dir, before)
+		}
+	}
+	if len(before) != 4 {
+		t.Errorf("Expected 4 folders, got %v", before)
+	}
+
+	files[filepath.Join("notes", "x", "c.md")] = "3b"
+	after := treeHashes(files)
+	for _, dir := range []string{".", "notes", "notes/x"} {
+		if before[dir] == after[dir] {
+			t.Errorf("Expected %s to change", dir)
+		}
+	}
+	if before["drafts"] != after["drafts"] {
+		t.Error("Expected drafts to keep its hash")
+	}
+
+	// a file and a folder of the same name are told apart
+	if treeHashes(map[string]string{"x": "h"})["."] == treeHashes(map[string]string{"x/y": "h"})["."] {
+		t.Error("Expected a file and a folder to hash differently")
+	}
+	if treeHashes(nil)["."] == "" {
+		t.Error("Expected an empty tree to have a root hash")
+	}
+}
+
+func TestDiffTreesMatchesDetectChanges(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() map[string]string {
+		m := map[string]string{}
+		for range 40 {
+			p := filepath.Join(fmt.Sprint("d", r.Intn(3)), fmt.Sprint("s", r.Intn(3)), fmt.Sprint("f", r.Intn(4), ".md"))
+			if r.Intn(4) == 0 {
+				p = fmt.Sprint("f", r.Intn(4), ".md")
+			}
+			m[p] = fmt.Sprint(r.Intn(3))
+		}
+		return m
+	}
+	for i := range 200 {
+		a, b := Manifest{Files: random()}, Manifest{Files: random()}
+		if i%2 == 0 {
+			a.Tree = treeHashes(a.Files) // recorded; otherwise computed
+		}
+		wantA, wantC, wantD := detectChanges(a.Files, b.Files)
+		gotA, gotC, gotD := diffTrees(a, b)
+		for _, s := range [][]string{wantA, wantC, wantD} {
+			slices.Sort(s)
+		}
+		if !slices.Equal(gotA, wantA) || !slices.Equal(gotC, wantC) || !slices.Equal(gotD, wantD) {
+			t.Fatalf("diffTrees(%v, %v) = %v %v %v, want %v %v %v", a.Files, b.Files, gotA, gotC, gotD, wantA, wantC, wantD)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "ch1.md", "One\n")
+	createTestFile(t, "notes/idea.md", "Idea\n")
+	createTestFile(t, "notes/old.md", "Old\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "ch1.md", "One, revised\n")
+	createTestFile(t, "ch2.md", "Two\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	v0, _ := loadManifest(0.0)
+	v1, _ := loadManifest(0.1)
+	if v0.Tree["notes"] == "" || v0.Tree["notes"] != v1.Tree["notes"] {
+		t.Errorf("Expected notes to keep its tree hash: %v, %v", v0.Tree, v1.Tree)
+	}
+	out := compareVersions(v0, v1)
+	for _, want := range []string{"v0.0 → v0.1: 1 added, 1 changed, 0 deleted", "+ ch2.md", "≠ ch1.md"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "notes") {
+		t.Errorf("Expected notes to be left out:\n%s", out)
+	}
+	if out := compareVersions(v1, v1); !strings.Contains(out, "hold the same files") {
+		t.Errorf("Expected identical versions, got:\n%s", out)
+	}
+
+	// an old manifest without tree hashes compares the same way
+	v0.Tree = nil
+	if got := compareVersions(v0, v1); got != out {
+		t.Errorf("Unexpected comparison without tree hashes:\n%s", got)
+	}
+}
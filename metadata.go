@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- File metadata ---
+//
+// With "track_metadata": ["mode", "mtime"] each version also records the
+// permissions and/or modification time of every tracked file. A file whose
+// content is unchanged but whose metadata differs is reported apart from
+// content changes — "metadata" in status, manifests and changelogs — and
+// "skip_metadata_only" stops such changes from creating a version on their
+// own. Files are compared from the first version that recorded metadata.
+
+const (
+	metaMode  = "mode"
+	metaMTime = "mtime"
+
+	stateMetadata = "metadata"
+)
+
+// FileMeta is the recorded metadata of one file; fields not tracked stay
+// empty.
+type FileMeta struct {
+	Mode  string    `json:"mode,omitempty"` // permission bits in octal, e.g. "0755"
+	MTime time.Time `json:"mtime,omitzero"` // to the second
+}
+
+func (c Config) tracksMeta(kind string) bool {
+	for _, k := range c.TrackMetadata {
+		if strings.EqualFold(k, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// readMeta collects the tracked metadata of files; nil when none is tracked.
+func readMeta(cfg Config, files []string) map[string]FileMeta {
+	if len(cfg.TrackMetadata) == 0 {
+		return nil
+	}
+	out := make(map[string]FileMeta, len(files))
+	for _, f := range files {
+		fi, err := os.Stat(longPath(f))
+		if err != nil {
+			continue
+		}
+		var m FileMeta
+		if cfg.tracksMeta(metaMode) {
+			m.Mode = fmt.Sprintf("%04o", fi.Mode().Perm())
+		}
+		if cfg.tracksMeta(metaMTime) {
+			m.MTime = fi.ModTime().UTC().Truncate(time.Second)
+		}
+		out[f] = m
+	}
+	return out
+}
+
+// metaOnlyChanges lists files whose content is the same in old and current
+// but whose metadata differs from what the last version recorded.
+func metaOnlyChanges(prev, cur map[string]FileMeta, old, current map[string]string) []string {
+	var out []string
+	for rel, m := range cur {
+		p, ok := prev[rel]
+		if !ok || old[rel] == "" || old[rel] != current[rel] {
+			continue
+		}
+		if !p.MTime.Equal(m.MTime) || p.Mode != m.Mode {
+			out = append(out, rel)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// describeMeta says what changed between two recordings of a file.
+func describeMeta(before, after FileMeta) string {
+	var parts []string
+	if before.Mode != after.Mode {
+		parts = append(parts, fmt.Sprintf("mode %s → %s", orDash(before.Mode), orDash(after.Mode)))
+	}
+	if !before.MTime.Equal(after.MTime) {
+		stamp := func(t time.Time) string {
+			if t.IsZero() {
+				return "-"
+			}
+			return t.Local().Format("2006-01-02 15:04:05")
+		}
+		parts = append(parts, fmt.Sprintf("modified time %s → %s", stamp(before.MTime), stamp(after.MTime)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// pendingMetaChanges is metaOnlyChanges against the last recorded version.
+func pendingMetaChanges(cfg Config, files []string, old, current map[string]string) (changed []string, prev, cur map[string]FileMeta) {
+	cur = readMeta(cfg, files)
+	if cur == nil {
+		return nil, nil, nil
+	}
+	if v, err := readVersion(); err == nil {
+		if m, err := loadManifest(v); err == nil {
+			prev = m.Meta
+		}
+	}
+	return metaOnlyChanges(prev, cur, old, current), prev, cur
+}
+
+// recordMetaChanges writes the changelog entries for metadata-only changes
+// and returns their FileChanges.
+func recordMetaChanges(files []string, prev, cur map[string]FileMeta, ver float64, ts string) []FileChange {
+	var out []FileChange
+	for _, rel := range files {
+		clPath := filepath.Join(changelogDir, rel+".log")
+		_ = safeMkdirAllForFile(clPath)
+		_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n🔧 Metadata changed: %s\n", ver, ts, describeMeta(prev[rel], cur[rel])))
+		out = append(out, FileChange{Path: rel, State: stateMetadata})
+	}
+	return out
+}
+
+func validateTrackMetadata(kinds []string) []configIssue {
+	var issues []configIssue
+	for _, k := range kinds {
+		switch strings.ToLower(k) {
+		case metaMode, metaMTime:
+		default:
+			issues = append(issues, configIssue{"track_metadata", fmt.Sprintf("%q is not one of %q, %q", k, metaMode, metaMTime)})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestMetadataOnlyChanges(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not kept on Windows")
+	}
+	setupTestDir(t)
+
+	cfg := loadConfig()
+	cfg.TrackMetadata = []string{"mode"}
+	saveJSON(configFile, cfg)
+	createTestFile(t, "build.sh", "echo hi\n")
+	createTestFile(t, "notes.md", "notes\n")
+	os.Chmod("build.sh", 0o644)
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if m, _ := loadManifest(0.0); m.Meta["build.sh"].Mode != "0644" {
+		t.Fatalf("Expected the mode in the first manifest, got %+v", m.Meta)
+	}
+
+	os.Chmod("build.sh", 0o755)
+	old := loadCommittedHashes()
+	current := map[string]string{"build.sh": hashFile("build.sh"), "notes.md": hashFile("notes.md")}
+	if changed, _, _ := pendingMetaChanges(loadConfig(), []string{"build.sh", "notes.md"}, old, current); len(changed) != 1 || changed[0] != "build.sh" {
+		t.Errorf("Expected build.sh to have a pending metadata change, got %v", changed)
+	}
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	m, err := loadManifest(0.1)
+	if err != nil {
+		t.Fatalf("Expected a version for the mode change: %v", err)
+	}
+	if len(m.Changes) != 1 || m.Changes[0].State != stateMetadata || m.Changes[0].Path != "build.sh" {
+		t.Errorf("Expected one metadata change, got %+v", m.Changes)
+	}
+	log, _ := os.ReadFile(filepath.Join(changelogDir, "build.sh.log"))
+	if !strings.Contains(string(log), "Metadata changed: mode 0644 → 0755") {
+		t.Errorf("Expected the mode change in the changelog:\n%s", log)
+	}
+
+	cfg = loadConfig()
+	cfg.SkipMetadataOnly = true
+	saveJSON(configFile, cfg)
+	os.Chmod("build.sh", 0o700)
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.1 {
+		t.Errorf("Expected no version for a skipped metadata change, at v%.1f", v)
+	}
+	createTestFile(t, "notes.md", "more notes\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if m, _ := loadManifest(0.2); m.Meta["build.sh"].Mode != "0700" {
+		t.Errorf("Expected the mode recorded with the next content change, got %+v", m.Meta)
+	}
+}
+
+func TestValidateTrackMetadata(t *testing.T) {
+	if issues := validateTrackMetadata([]string{"mode", "MTime"}); len(issues) != 0 {
+		t.Errorf("Unexpected issues: %v", issues)
+	}
+	if issues := validateTrackMetadata([]string{"owner"}); len(issues) != 1 {
+		t.Errorf("Expected an issue for an unknown kind, got %v", issues)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// --- Mirrors ---
+//
+// "mirrors": ["/Volumes/Backup/novel"] keeps a second copy of the whole
+// store on another disk. After every update each mirror receives the store
+// files that are new or changed since the last run — stored versions never
+// change, so this is usually a handful of files — and loses the ones the
+// store no longer has. version.txt is copied last, as in the store itself,
+// so an interrupted mirror still reads as the previous version. A mirror
+// whose parent folder is missing (an unplugged drive) is skipped with a
+// warning; `gitnot mirror` catches it up later.
+//
+// A folder becomes a mirror when it is empty or missing: gitnot then writes
+// mirrorMarker into it, listing every file it has copied there. A folder
+// with files but no marker is refused, and only files the marker lists are
+// ever removed, so pointing mirrors at the wrong folder can't delete
+// anything of the user's.
+
+// mirrorMarker names the file in a mirror that lists what gitnot copied.
+const mirrorMarker = ".gitnot-mirror.json"
+
+// mirrorState is the content of mirrorMarker.
+type mirrorState struct {
+	Files []string `json:"files"` // slash-separated, relative to the mirror
+}
+
+// mirrorResult says what syncing one mirror did.
+type mirrorResult struct {
+	Copied  int
+	Removed int
+	Bytes   int64
+}
+
+// sameFile reports whether dst already holds src, judged by size and
+// modification time, which mirroring carries over.
+func sameFile(src, dst fs.FileInfo) bool {
+	return dst.Size() == src.Size() && dst.ModTime().Equal(src.ModTime())
+}
+
+// mirrorStore brings the mirror at dir up to date with the store.
+func mirrorStore(dir string) (mirrorResult, error) {
+	var res mirrorResult
+	if _, err := os.Stat(filepath.Dir(filepath.Clean(dir))); err != nil {
+		return res, fmt.Errorf("%s is not reachable (is the drive connected?)", dir)
+	}
+	owned, err := claimMirror(dir)
+	if err != nil {
+		return res, err
+	}
+	files, err := sealedFiles()
+	if err != nil {
+		return res, err
+	}
+	// everything about to be copied is listed before it is, so a run cut
+	// short still owns what it left behind
+	keep := map[string]bool{}
+	for _, p := range files {
+		if rel, err := filepath.Rel(gitnotDir, p); err == nil && rel != mirrorMarker {
+			keep[filepath.ToSlash(rel)] = true
+		}
+	}
+	if err := saveMirrorState(dir, owned, keep); err != nil {
+		return res, err
+	}
+	copyOne := func(p string) error {
+		rel, err := filepath.Rel(gitnotDir, p)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dir, rel)
+		src, err := os.Stat(longPath(p))
+		if err != nil {
+			return err
+		}
+		if have, err := os.Stat(longPath(dst)); err == nil && sameFile(src, have) {
+			return nil
+		}
+		tmp := dst + ".tmp"
+		if err := copyFile(p, tmp); err != nil {
+			os.Remove(longPath(tmp))
+			return err
+		}
+		if err := os.Chtimes(longPath(tmp), src.ModTime(), src.ModTime()); err != nil {
+			return err
+		}
+		if err := os.Rename(longPath(tmp), longPath(dst)); err != nil {
+			return err
+		}
+		res.Copied++
+		res.Bytes += src.Size()
+		return nil
+	}
+	version := filepath.FromSlash(versionFile)
+	for _, p := range files {
+		if p == version || p == filepath.Join(gitnotDir, mirrorMarker) {
+			continue
+		}
+		if err := copyOne(p); err != nil {
+			return res, err
+		}
+	}
+	// only files an earlier run copied are removed
+	for rel := range owned {
+		if keep[rel] {
+			continue
+		}
+		err := os.Remove(longPath(filepath.Join(dir, filepath.FromSlash(rel))))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return res, err
+		}
+		if err == nil {
+			res.Removed++
+		}
+	}
+	if err := saveMirrorState(dir, nil, keep); err != nil {
+		return res, err
+	}
+	flushCopies()
+	return res, copyOne(version)
+}
+
+// claimMirror returns the files gitnot has copied into dir, making dir a
+// mirror if it is missing or empty. A folder that holds anything but has no
+// marker is refused: it isn't gitnot's to fill or clean up.
+func claimMirror(dir string) (map[string]bool, error) {
+	b, err := os.ReadFile(filepath.Join(dir, mirrorMarker))
+	if err == nil {
+		var st mirrorState
+		if err := json.Unmarshal(b, &st); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Join(dir, mirrorMarker), err)
+		}
+		owned := map[string]bool{}
+		for _, f := range st.Files {
+			owned[f] = true
+		}
+		return owned, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty and not a gitnot mirror; choose an empty folder", dir)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return map[string]bool{}, nil
+}
+
+// saveMirrorState records in dir's marker that gitnot owns the files in
+// both sets.
+func saveMirrorState(dir string, a, b map[string]bool) error {
+	st := mirrorState{Files: []string{}}
+	for _, set := range []map[string]bool{a, b} {
+		for rel := range set {
+			st.Files = append(st.Files, rel)
+		}
+	}
+	sort.Strings(st.Files)
+	st.Files = slices.Compact(st.Files)
+	return saveJSON(filepath.Join(dir, mirrorMarker), st)
+}
+
+// runMirrors syncs every configured mirror after an update. A mirror that
+// fails only warns, as the version itself is already recorded; the error
+// says how many did.
+func runMirrors(mirrors []string) error {
+	failed := 0
+	for _, dir := range mirrors {
+		res, err := mirrorStore(dir)
+		if err != nil {
+			fmt.Printf("⚠️  Warning: Mirror %s not updated: %v\n", dir, err)
+			failed++
+			continue
+		}
+		if res.Copied+res.Removed > 0 {
+			fmt.Printf("🪞 Mirrored to %s (%d files, %s)\n", dir, res.Copied, formatBytes(res.Bytes))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d mirrors not updated", failed, len(mirrors))
+	}
+	return nil
+}
+
+// validateMirrors rejects mirrors inside the project, which would end up
+// scanning (and mirroring) themselves.
+func validateMirrors(mirrors []string) []configIssue {
+	var issues []configIssue
+	wd, _ := os.Getwd()
+	for _, m := range mirrors {
+		if strings.TrimSpace(m) == "" {
+			issues = append(issues, configIssue{"mirrors", "empty path"})
+			continue
+		}
+		abs, err := filepath.Abs(m)
+		if err != nil || wd == "" {
+			continue
+		}
+		if rel, err := filepath.Rel(wd, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			issues = append(issues, configIssue{"mirrors", fmt.Sprintf("%q is inside the project; use a folder elsewhere, such as another drive", m)})
+		}
+	}
+	return issues
+}
+
+func runMirror(args []string) error {
+	fs := flag.NewFlagSet("mirror", flag.ExitOnError)
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	mirrors := loadConfig().Mirrors
+	if len(mirrors) == 0 {
+		return fmt.Errorf("no mirrors configured; add \"mirrors\": [\"/path/on/another/drive\"] to %s", configFile)
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+	failed := 0
+	for _, dir := range mirrors {
+		res, err := mirrorStore(dir)
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", dir, err)
+			failed++
+			continue
+		}
+		if res.Copied+res.Removed == 0 {
+			fmt.Printf("✅ %s is up to date\n", dir)
+		} else {
+			fmt.Printf("🪞 %s: copied %d files (%s), removed %d\n", dir, res.Copied, formatBytes(res.Bytes), res.Removed)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d mirrors could not be updated", failed, len(mirrors))
+	}
+	return nil
+}
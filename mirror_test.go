@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMirrorStore(t *testing.T) {
+	setupTestDir(t)
+	drive := t.TempDir()
+	mirror := filepath.Join(drive, "novel")
+
+	createTestFile(t, "ch1.md", "first\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.Mirrors = []string{mirror}
+	saveJSON(configFile, cfg)
+	createTestFile(t, "ch1.md", "second\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(mirror, "version.txt")); err != nil || strings.TrimSpace(string(b)) != "0.1" {
+		t.Fatalf("Expected the mirror at v0.1, got %q, %v", b, err)
+	}
+	h := contentHash([]byte("second\n"))
+	if _, err := os.Stat(filepath.Join(mirror, "objects", h[:2], h[2:])); err != nil {
+		t.Errorf("Expected the new object in the mirror: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(mirror, "lock")); err == nil {
+		t.Error("The lock should not be mirrored")
+	}
+
+	res, err := mirrorStore(mirror)
+	if err != nil || res.Copied != 0 || res.Removed != 0 {
+		t.Errorf("Expected nothing to do on an up-to-date mirror, got %+v, %v", res, err)
+	}
+	createTestFile(t, filepath.Join(mirror, "stray.txt"), "x")
+	createTestFile(t, filepath.Join(gitnotDir, "extra.txt"), "x")
+	if _, err := mirrorStore(mirror); err != nil {
+		t.Fatalf("mirrorStore failed: %v", err)
+	}
+	os.Remove(filepath.Join(gitnotDir, "extra.txt"))
+	createTestFile(t, "ch1.md", "third\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(mirror, "extra.txt")); err == nil {
+		t.Error("Files the store no longer has should be removed from the mirror")
+	}
+	if _, err := os.Stat(filepath.Join(mirror, "stray.txt")); err != nil {
+		t.Error("Files gitnot never copied should be left in the mirror")
+	}
+
+	if _, err := mirrorStore(filepath.Join(drive, "unplugged", "novel")); err == nil {
+		t.Error("Expected an error for a mirror whose drive is missing")
+	}
+}
+
+func TestMirrorStoreRefusesForeignFolder(t *testing.T) {
+	setupTestDir(t)
+	drive := t.TempDir()
+	createTestFile(t, filepath.Join(drive, "photos", "wedding.jpg"), "jpeg")
+
+	createTestFile(t, "ch1.md", "first\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if _, err := mirrorStore(drive); err == nil || !strings.Contains(err.Error(), "not a gitnot mirror") {
+		t.Errorf("Expected a folder with other files to be refused, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(drive, "photos", "wedding.jpg")); err != nil {
+		t.Errorf("The folder's own files should be untouched: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(drive, mirrorMarker)); err == nil {
+		t.Error("A refused folder should not become a mirror")
+	}
+}
+
+func TestValidateMirrors(t *testing.T) {
+	setupTestDir(t)
+	if issues := validateMirrors([]string{"backup", t.TempDir()}); len(issues) != 1 || !strings.Contains(issues[0].String(), "inside the project") {
+		t.Errorf("Expected only the mirror inside the project to be rejected, got %v", issues)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// --- Narrative ---
+//
+// With "narrative": {"enabled": true} each version also gets one sentence
+// describing it as a whole — "Edited 3 chapters, added 1 new scene, deleted
+// outline-old.md" — kept in the manifest and shown in HISTORY.md above the
+// per-file lines. Files matching a group ({"match": "chapters/*", "name":
+// "chapter"}) are counted under its name; other files are named, or counted
+// per folder when several in one folder changed. A template can reshape the
+// sentence.
+
+// NarrativeConfig is the "narrative" config object.
+type NarrativeConfig struct {
+	Enabled  bool             `json:"enabled,omitempty"`
+	Groups   []NarrativeGroup `json:"groups,omitempty"`
+	Template string           `json:"template,omitempty"` // text/template over narrativeData
+}
+
+// NarrativeGroup counts matching files under one name.
+type NarrativeGroup struct {
+	Match  string `json:"match"`
+	Name   string `json:"name"`             // singular, e.g. "chapter"
+	Plural string `json:"plural,omitempty"` // default: name + "s"
+}
+
+// narrativeData is what a narrative template sees.
+type narrativeData struct {
+	Version      float64
+	Summary      string   // the default sentence, without a final period
+	Edited       []string // phrases such as "3 chapters" or "notes.md"
+	Added        []string
+	Deleted      []string
+	WordsAdded   int
+	WordsRemoved int
+}
+
+func (g NarrativeGroup) count(n int) string {
+	if n == 1 {
+		return "1 " + g.Name
+	}
+	plural := g.Plural
+	if plural == "" {
+		plural = g.Name + "s"
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
+
+// narrativePhrases describes paths: group counts first, in config order,
+// then folders with several files, then single files by name.
+func narrativePhrases(paths []string, groups []NarrativeGroup, added bool) []string {
+	byGroup := make([]int, len(groups))
+	byDir := map[string][]string{}
+	for _, p := range paths {
+		rel := filepath.ToSlash(p)
+		matched := false
+		for i, g := range groups {
+			if matchesAny(rel, []string{g.Match}) {
+				byGroup[i]++
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			byDir[path.Dir(rel)] = append(byDir[path.Dir(rel)], rel)
+		}
+	}
+	var out []string
+	for i, n := range byGroup {
+		if n == 0 {
+			continue
+		}
+		s := groups[i].count(n)
+		if added {
+			s = strings.Replace(s, " ", " new ", 1)
+		}
+		out = append(out, s)
+	}
+	var singles []string
+	for _, dir := range sortedKeys(byDir) {
+		files := byDir[dir]
+		if len(files) > 1 && dir != "." {
+			out = append(out, fmt.Sprintf("%d files in %s/", len(files), dir))
+			continue
+		}
+		singles = append(singles, files...)
+	}
+	return append(out, singles...)
+}
+
+// joinPhrases makes "a", "a and b" or "a, b and c".
+func joinPhrases(ps []string) string {
+	if len(ps) < 2 {
+		return strings.Join(ps, "")
+	}
+	return strings.Join(ps[:len(ps)-1], ", ") + " and " + ps[len(ps)-1]
+}
+
+// narrate builds the narrative of a version from its changes.
+func narrate(nc NarrativeConfig, v float64, changes []FileChange) (string, error) {
+	var edited, added, deleted []string
+	d := narrativeData{Version: v}
+	for _, c := range changes {
+		switch c.State {
+		case stateAdded:
+			added = append(added, c.Path)
+		case stateModified:
+			edited = append(edited, c.Path)
+		case stateDeleted:
+			deleted = append(deleted, c.Path)
+		default:
+			continue
+		}
+		d.WordsAdded += c.WordsAdded
+		d.WordsRemoved += c.WordsRemoved
+	}
+	d.Edited = narrativePhrases(edited, nc.Groups, false)
+	d.Added = narrativePhrases(added, nc.Groups, true)
+	d.Deleted = narrativePhrases(deleted, nc.Groups, false)
+	var clauses []string
+	for _, c := range []struct {
+		verb    string
+		phrases []string
+	}{{"edited", d.Edited}, {"added", d.Added}, {"deleted", d.Deleted}} {
+		if len(c.phrases) > 0 {
+			clauses = append(clauses, c.verb+" "+joinPhrases(c.phrases))
+		}
+	}
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	d.Summary = capitalize(strings.Join(clauses, ", "))
+	if nc.Template == "" {
+		return d.Summary + ".", nil
+	}
+	tmpl, err := template.New("narrative").Parse(nc.Template)
+	if err != nil {
+		return "", fmt.Errorf("narrative template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, d); err != nil {
+		return "", fmt.Errorf("narrative template: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+func capitalize(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
+
+func validateNarrative(nc NarrativeConfig) []configIssue {
+	var issues []configIssue
+	for i, g := range nc.Groups {
+		key := fmt.Sprintf("narrative.groups[%d]", i)
+		if g.Match == "" || g.Name == "" {
+			issues = append(issues, configIssue{key, "needs a match pattern and a name"})
+		} else if _, err := path.Match(g.Match, ""); err != nil {
+			issues = append(issues, configIssue{key, fmt.Sprintf("%q is not a valid glob", g.Match)})
+		}
+	}
+	if nc.Template != "" {
+		if _, err := template.New("narrative").Parse(nc.Template); err != nil {
+			issues = append(issues, configIssue{"narrative.template", err.Error()})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNarrate(t *testing.T) {
+	nc := NarrativeConfig{Groups: []NarrativeGroup{
+		{Match: "chapters/*", Name: "chapter"},
+		{Match: "scenes/*", Name: "scene file"},
+	}}
+	changes := []FileChange{
+		{Path: filepath.Join("chapters", "01.md"), State: stateModified, WordsAdded: 10},
+		{Path: filepath.Join("chapters", "02.md"), State: stateModified, WordsRemoved: 3},
+		{Path: filepath.Join("chapters", "03.md"), State: stateModified},
+		{Path: "notes.md", State: stateModified},
+		{Path: filepath.Join("scenes", "storm.md"), State: stateAdded, WordsAdded: 5},
+		{Path: "outline-old.md", State: stateDeleted},
+		{Path: "build.sh", State: stateMetadata},
+	}
+	got, err := narrate(nc, 0.3, changes)
+	if want := "Edited 3 chapters and notes.md, added 1 new scene file, deleted outline-old.md."; err != nil || got != want {
+		t.Errorf("narrate = %q, %v; want %q", got, err, want)
+	}
+
+	nc.Template = "v{{.Version}}: {{.Summary}} (+{{.WordsAdded}}/-{{.WordsRemoved}} words)"
+	if got, _ := narrate(nc, 0.3, changes); !strings.HasPrefix(got, "v0.3: Edited 3 chapters") || !strings.HasSuffix(got, "(+15/-3 words)") {
+		t.Errorf("Unexpected templated narrative %q", got)
+	}
+
+	folder := narrativePhrases([]string{"notes/a.md", "notes/b.md", "x.md"}, nil, false)
+	if strings.Join(folder, "|") != "2 files in notes/|x.md" {
+		t.Errorf("Unexpected phrases %v", folder)
+	}
+}
+
+func TestNarrativeInHistory(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.md", "one\n")
+	createTestFile(t, "b.md", "two\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.Narrative.Enabled = true
+	saveJSON(configFile, cfg)
+	createTestFile(t, "a.md", "one more\n")
+	os.Remove("b.md")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if m, _ := loadManifest(0.1); m.Narrative != "Edited a.md, deleted b.md." {
+		t.Errorf("Unexpected narrative %q", m.Narrative)
+	}
+	b, _ := os.ReadFile(historyFile)
+	if !strings.Contains(string(b), "*Edited a.md, deleted b.md.*") {
+		t.Errorf("Expected the narrative in HISTORY.md:\n%s", b)
+	}
+	if issues := validateNarrative(NarrativeConfig{Groups: []NarrativeGroup{{Match: "x"}}, Template: "{{"}); len(issues) != 2 {
+		t.Errorf("Expected a group and a template issue, got %v", issues)
+	}
+}
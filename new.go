@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- New projects ---
+//
+// `gitnot new mynovel --template writing` creates the folder, seeds it with
+// starter files, writes config.json from the template's settings and records
+// v0.0, so a writing project starts in one step. Besides the built-in
+// templates, any folder in <user config>/gitnot/templates/<name> is a
+// template: its files are copied and a profile.json in it, in the format of
+// `gitnot config export`, is applied to the config.
+
+// projectTemplate is a set of starter files plus config settings merged into
+// the defaults.
+type projectTemplate struct {
+	About   string
+	Files   map[string]string
+	Profile string
+}
+
+var projectTemplates = map[string]projectTemplate{
+	"writing": {
+		About: "a novel or long piece: outline, chapters and notes",
+		Files: map[string]string{
+			"outline.md":             "# Outline\n\n",
+			"chapters/01-chapter.md": "# Chapter 1\n\n",
+			"notes/characters.md":    "# Characters\n\n",
+			"notes/research.md":      "# Research\n\n",
+		},
+		Profile: `{"order": ["outline.md", "chapters/*.md"]}`,
+	},
+	"screenplay": {
+		About: "a Fountain screenplay",
+		Files: map[string]string{
+			"script.fountain":     "Title: Untitled\nAuthor: \n\n",
+			"notes/characters.md": "# Characters\n\n",
+		},
+		Profile: `{"order": ["script.fountain"]}`,
+	},
+	"latex": {
+		About: "a LaTeX paper with a bibliography",
+		Files: map[string]string{
+			"main.tex":       "\\documentclass{article}\n\n\\begin{document}\n\n\\bibliography{references}\n\\end{document}\n",
+			"references.bib": "",
+		},
+		Profile: `{"order": ["main.tex"]}`,
+	},
+	"blog": {
+		About: "posts and drafts for a static site",
+		Files: map[string]string{
+			"posts/hello.md":  "# Hello\n\n",
+			"drafts/ideas.md": "# Ideas\n\n",
+		},
+		Profile: `{"ignore_patterns": ["public/*"]}`,
+	},
+}
+
+// userTemplateDir is where a user's own template called name lives.
+func userTemplateDir(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gitnot", "templates", name), nil
+}
+
+// loadTemplate finds name among the user's templates, then the built-in
+// ones. A user template is read from disk into the same shape.
+func loadTemplate(name string) (projectTemplate, error) {
+	if dir, err := userTemplateDir(name); err == nil && name != "" && !strings.ContainsAny(name, `/\`) {
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return readTemplateDir(dir)
+		}
+	}
+	if t, ok := projectTemplates[name]; ok {
+		return t, nil
+	}
+	return projectTemplate{}, fmt.Errorf("no template %q (built in: %s)", name, strings.Join(sortedKeys(projectTemplates), ", "))
+}
+
+func readTemplateDir(dir string) (projectTemplate, error) {
+	t := projectTemplate{Files: map[string]string{}}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if rel == "profile.json" {
+			t.Profile = string(b)
+		} else {
+			t.Files[filepath.ToSlash(rel)] = string(b)
+		}
+		return nil
+	})
+	return t, err
+}
+
+// templateConfig merges the template's settings into the default config.
+// Unlike `config import`, a template may set project keys such as "order".
+func templateConfig(t projectTemplate) (Config, error) {
+	if t.Profile == "" {
+		return defaultConfig, nil
+	}
+	cur, err := currentConfigJSON()
+	if err != nil {
+		return Config{}, err
+	}
+	var p map[string]any
+	if err := json.Unmarshal([]byte(t.Profile), &p); err != nil {
+		return Config{}, fmt.Errorf("template settings: %s", jsonErrorAt([]byte(t.Profile), err))
+	}
+	b, err := json.Marshal(mergeJSON(cur, p))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg, issues := parseConfig(b)
+	if len(issues) > 0 {
+		return Config{}, fmt.Errorf("template settings are invalid: %s", issues[0])
+	}
+	return cfg, nil
+}
+
+// newProject sets up dir from the template and a profile file, if given,
+// and records the first version.
+func newProject(dir, template, profile string) error {
+	var t projectTemplate
+	if template != "" {
+		var err error
+		if t, err = loadTemplate(template); err != nil {
+			return err
+		}
+	}
+	var profileB []byte
+	if profile != "" {
+		var err error
+		if profileB, err = os.ReadFile(profile); err != nil {
+			return err
+		}
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s already exists and is not empty", dir)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	back, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	defer os.Chdir(back)
+	// the store folder found where gitnot was started belongs to some other
+	// project; the new one gets GITNOT_DIR or the default
+	if err := locateStore(); err != nil {
+		return err
+	}
+
+	for _, rel := range sortedKeys(t.Files) {
+		p := filepath.FromSlash(rel)
+		if err := safeMkdirAllForFile(p); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, []byte(t.Files[rel]), 0o644); err != nil {
+			return err
+		}
+	}
+	cfg, err := templateConfig(t)
+	if err != nil {
+		return err
+	}
+	if err := saveJSON(configFile, cfg); err != nil {
+		return err
+	}
+	if profileB != nil {
+		cfg, dropped, err := importProfile(profileB, false)
+		if err != nil {
+			return err
+		}
+		if len(dropped) > 0 {
+			fmt.Printf("⚠️  Skipped project-specific settings in %s: %s\n", profile, strings.Join(dropped, ", "))
+		}
+		if err := saveJSON(configFile, cfg); err != nil {
+			return err
+		}
+	}
+	return initGitnot()
+}
+
+func runNew(args []string) error {
+	fs := flag.NewFlagSet("new", flag.ExitOnError)
+	template := fs.String("template", "", "starter files and settings: "+strings.Join(sortedKeys(projectTemplates), ", ")+", or one of your own")
+	profile := fs.String("profile", "", "config profile (from 'gitnot config export') to apply")
+	list := fs.Bool("list", false, "list the available templates")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if *list {
+		for _, name := range sortedKeys(projectTemplates) {
+			fmt.Printf("  %-12s %s\n", name, projectTemplates[name].About)
+		}
+		if dir, err := userTemplateDir(""); err == nil {
+			fmt.Printf("Your own templates go in %s\n", dir)
+		}
+		return nil
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: gitnot new <folder> [--template name] [--profile file.json]")
+	}
+	if err := newProject(rest[0], *template, *profile); err != nil {
+		return err
+	}
+	fmt.Printf("🆕 Created %s", rest[0])
+	if *template != "" {
+		fmt.Printf(" from the %s template", *template)
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestNewProjectFromTemplate(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "profile.json", `{"extensions": [".org"], "order": ["ignored.md"]}`)
+
+	if err := newProject("mynovel", "writing", "profile.json"); err != nil {
+		t.Fatalf("newProject failed: %v", err)
+	}
+	if wd, _ := os.Getwd(); filepath.Base(wd) == "mynovel" {
+		t.Fatal("Expected to be back in the starting folder")
+	}
+	if err := os.Chdir("mynovel"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join("chapters", "01-chapter.md")); err != nil {
+		t.Errorf("Expected the starter chapter: %v", err)
+	}
+	cfg := loadConfig()
+	if !slices.Equal(cfg.Order, []string{"outline.md", "chapters/*.md"}) {
+		t.Errorf("Expected the template's order, got %v", cfg.Order)
+	}
+	if !slices.Contains(cfg.Extensions, ".org") || !slices.Contains(cfg.Extensions, ".md") {
+		t.Errorf("Expected the profile merged into the defaults, got %v", cfg.Extensions)
+	}
+	if hashes := loadCommittedHashes(); len(hashes) != 4 {
+		t.Errorf("Expected the starter files recorded as v0.0, got %v", hashes)
+	}
+	os.Chdir("..")
+
+	if err := newProject("mynovel", "", ""); err == nil {
+		t.Error("Expected a non-empty folder to be refused")
+	}
+	if err := newProject("other", "nope", ""); err == nil {
+		t.Error("Expected an unknown template to be refused")
+	}
+}
+
+func TestNewProjectUserTemplate(t *testing.T) {
+	setupTestDir(t)
+	dir, err := userTemplateDir("zine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, filepath.Join(dir, "pages", "cover.md"), "# Cover\n")
+	createTestFile(t, filepath.Join(dir, "profile.json"), `{"order": ["pages/cover.md"]}`)
+
+	if err := newProject("issue1", "zine", ""); err != nil {
+		t.Fatalf("newProject failed: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join("issue1", "pages", "cover.md"))
+	if err != nil || string(b) != "# Cover\n" {
+		t.Errorf("Expected the template's file, got %q, %v", b, err)
+	}
+	if _, err := os.Stat(filepath.Join("issue1", "profile.json")); err == nil {
+		t.Error("profile.json should configure the project, not be copied into it")
+	}
+}
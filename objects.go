@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// --- Object store ---
+//
+// File content is kept content-addressed under .gitnot/objects/ab/cdef…,
+// keyed by the same SHA1 recorded in hashes and manifests. Only new and
+// changed content is written, so together with the manifests any tracked
+// file can be read back as of any version. Content is checked against its
+// hash every time it is read back, so a damaged object is reported instead
+// of being handed out as history.
+
+func objectPath(hash string) string {
+	return filepath.Join(objectsDir, hash[:2], hash[2:])
+}
+
+func validObjectHash(hash string) bool {
+	if len(hash) != 40 {
+		return false
+	}
+	for _, c := range hash {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func hasObject(hash string) bool {
+	if !validObjectHash(hash) {
+		return false
+	}
+	if _, err := repo.FS.Stat(objectPath(hash)); err == nil {
+		return true
+	}
+	if _, err := repo.FS.Stat(deltaPath(hash)); err == nil {
+		return true
+	}
+	_, ok := packIndex()[hash]
+	return ok
+}
+
+// storeObject copies src into the object store under hash unless an object
+// with that hash already exists.
+func storeObject(src, hash string) error {
+	if !validObjectHash(hash) || hasObject(hash) {
+		return nil
+	}
+	dst := objectPath(hash)
+	tmp := dst + ".tmp"
+	if err := copyFile(src, tmp); err != nil {
+		repo.FS.Remove(tmp)
+		return err
+	}
+	return repo.FS.Rename(tmp, dst)
+}
+
+// corruptError reports stored content that no longer matches the hash it
+// was recorded under.
+type corruptError struct {
+	Hash string
+	Got  string
+}
+
+func (e *corruptError) Error() string {
+	return fmt.Sprintf("stored object %s is damaged: its content now hashes to %s", e.Hash, e.Got)
+}
+
+func readObject(hash string) ([]byte, error) {
+	return readObjectHops(hash, 0)
+}
+
+func readObjectHops(hash string, hops int) ([]byte, error) {
+	if !validObjectHash(hash) {
+		return nil, fmt.Errorf("invalid object id %q", hash)
+	}
+	b, isDelta, err := storedObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	if isDelta {
+		return readDeltaObject(hash, hops)
+	}
+	if got := contentHash(b); got != hash {
+		return nil, &corruptError{Hash: hash, Got: got}
+	}
+	return b, nil
+}
+
+func loadManifest(v float64) (Manifest, error) {
+	b, err := repo.FS.ReadFile(manifestPath(v))
+	if errors.Is(err, os.ErrNotExist) {
+		return Manifest{}, fmt.Errorf("no manifest recorded for v%.1f", v)
+	}
+	if err != nil {
+		return Manifest{}, err
+	}
+	m, err := parseManifest(b)
+	if err != nil {
+		return m, fmt.Errorf("manifest of v%.1f: %w", v, err)
+	}
+	return m, nil
+}
+
+// contentAt returns the content of rel as of version v. Stores created before
+// the object store existed can still serve the latest version from the
+// snapshot when its content matches. Whatever the source, the content
+// returned matches the hash the manifest recorded; when the stored copy is
+// damaged and no intact copy is found, that is the error.
+func contentAt(rel string, m Manifest) ([]byte, error) {
+	hash, ok := m.Files[rel]
+	if !ok {
+		return nil, fmt.Errorf("%s is not tracked at v%.1f", rel, m.Version)
+	}
+	b, err := readObject(hash)
+	if err == nil {
+		return b, nil
+	}
+	var damaged *corruptError
+	errors.As(err, &damaged)
+	if b, err := repo.FS.ReadFile(filepath.Join(snapshotDir, rel)); err == nil && contentHash(b) == hash {
+		warnDamaged(rel, m.Version, damaged, "the snapshot")
+		return b, nil
+	}
+	if b, err := fetchRemote(hash); err == nil {
+		warnDamaged(rel, m.Version, damaged, "remote storage")
+		return b, nil
+	}
+	if damaged != nil {
+		return nil, fmt.Errorf("content of %s at v%.1f: %w", rel, m.Version, damaged)
+	}
+	return nil, fmt.Errorf("content of %s at v%.1f is not in the store", rel, m.Version)
+}
+
+// warnDamaged notes on stderr that an intact copy stood in for a damaged
+// object; stdout may be the content itself.
+func warnDamaged(rel string, v float64, damaged *corruptError, from string) {
+	if damaged != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: %s (%s at v%.1f); read it from %s instead\n", damaged, rel, v, from)
+	}
+}
+
+// migrateSeedObjects fills the object store from the snapshot, so stores that
+// predate it can still export their current version.
+func migrateSeedObjects() error {
+	hashes := loadCommittedHashes()
+	for rel, h := range hashes {
+		snap := filepath.Join(snapshotDir, rel)
+		if hashFile(snap) == h {
+			if err := storeObject(snap, h); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// --- Narrative order ---
+//
+// The optional "order" config lists files (or globs) in reading order, e.g.
+// ["outline.md", "chapters/*.md", "epilogue.md"]. Exports and stats present
+// files in that order; glob matches are sorted by path among themselves.
+
+func orderMatches(entry, rel string) bool {
+	entry, rel = filepath.ToSlash(entry), filepath.ToSlash(rel)
+	if entry == rel {
+		return true
+	}
+	ok, _ := path.Match(entry, rel)
+	return ok
+}
+
+// orderRank returns the index of the first order entry matching rel.
+func orderRank(rel string, order []string) (int, bool) {
+	for i, e := range order {
+		if orderMatches(e, rel) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// orderPaths returns paths in narrative order: files named by the order list
+// first, in list order, then everything else alphabetically.
+func orderPaths(paths []string, order []string) []string {
+	out := append([]string(nil), paths...)
+	sort.SliceStable(out, func(i, j int) bool {
+		ri, oki := orderRank(out[i], order)
+		rj, okj := orderRank(out[j], order)
+		switch {
+		case oki && okj && ri != rj:
+			return ri < rj
+		case oki != okj:
+			return oki
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+// selectOrdered keeps only the paths named by the order list, in order.
+func selectOrdered(paths []string, order []string) []string {
+	var picked []string
+	for _, p := range paths {
+		if _, ok := orderRank(p, order); ok {
+			picked = append(picked, p)
+		}
+	}
+	return orderPaths(picked, order)
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// --- Pack files ---
+//
+// Thousands of small loose objects are slow on Windows and in synced
+// folders, so `gitnot pack` (or auto_pack after an update) appends small
+// loose objects to .gitnot/objects/pack/pack-NNNN.pack and records where
+// each one lives in the matching .idx (JSON, replaced atomically after the
+// data is synced). Packs are append-only; a crash mid-pack leaves at most an
+// unreferenced tail. Loose objects always take precedence over packed ones,
+// so readers never need to know whether something was packed.
+
+const (
+	packMaxBytes      = 64 << 20 // start a new pack beyond this size
+	packObjectMax     = 64 << 10 // larger objects stay loose
+	autoPackLooseSize = 500      // auto_pack kicks in above this many loose objects
+)
+
+// PackEntry locates one object inside a pack.
+type PackEntry struct {
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+	Delta  bool  `json:"delta,omitempty"` // stored form is a Delta
+}
+
+type packLoc struct {
+	Pack string
+	PackEntry
+}
+
+// packCache is shared by the workers of verify and scrub.
+var packCache struct {
+	mu    sync.Mutex
+	key   string
+	index map[string]packLoc
+}
+
+// packIndex returns every packed object, later packs overriding earlier
+// ones. It is cached until the set of index files changes.
+func packIndex() map[string]packLoc {
+	entries, err := repo.FS.ReadDir(packDir)
+	if err != nil {
+		return nil
+	}
+	wd, _ := os.Getwd()
+	key := wd
+	var idxs []string
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".idx") {
+			if fi, err := e.Info(); err == nil {
+				key += fmt.Sprintf("|%s:%d:%d", e.Name(), fi.Size(), fi.ModTime().UnixNano())
+			}
+			idxs = append(idxs, e.Name())
+		}
+	}
+	packCache.mu.Lock()
+	defer packCache.mu.Unlock()
+	if packCache.index != nil && packCache.key == key {
+		return packCache.index
+	}
+	sort.Strings(idxs)
+	index := map[string]packLoc{}
+	for _, name := range idxs {
+		var m map[string]PackEntry
+		if err := loadJSON(filepath.Join(packDir, name), &m); err != nil {
+			continue
+		}
+		pack := strings.TrimSuffix(name, ".idx") + ".pack"
+		for h, e := range m {
+			index[h] = packLoc{Pack: pack, PackEntry: e}
+		}
+	}
+	packCache.key, packCache.index = key, index
+	return index
+}
+
+func readPacked(loc packLoc) ([]byte, error) {
+	f, err := repo.FS.Open(filepath.Join(packDir, loc.Pack))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b := make([]byte, loc.Size)
+	if err := readAt(f, b, loc.Offset); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", loc.Pack, err)
+	}
+	return b, nil
+}
+
+// readAt fills b from offset off of f, seeking by reading when f has no
+// ReadAt.
+func readAt(f File, b []byte, off int64) error {
+	if ra, ok := f.(io.ReaderAt); ok {
+		_, err := ra.ReadAt(b, off)
+		return err
+	}
+	if _, err := io.CopyN(io.Discard, f, off); err != nil {
+		return err
+	}
+	_, err := io.ReadFull(f, b)
+	return err
+}
+
+// storedObject returns an object as stored: its content, or (with isDelta
+// set) its encoded delta. Loose objects win over packed ones.
+func storedObject(hash string) (data []byte, isDelta bool, err error) {
+	b, err := repo.FS.ReadFile(objectPath(hash))
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return b, false, err
+	}
+	if b, err := repo.FS.ReadFile(deltaPath(hash)); err == nil || !errors.Is(err, os.ErrNotExist) {
+		return b, true, err
+	}
+	if loc, ok := packIndex()[hash]; ok {
+		b, err := readPacked(loc)
+		return b, loc.Delta, err
+	}
+	return nil, false, fmt.Errorf("object %s: %w", hash, os.ErrNotExist)
+}
+
+// looseObject is a loose file that may be packed.
+type looseObject struct {
+	Hash  string
+	Path  string
+	Delta bool
+	Size  int64
+}
+
+func listLooseObjects() ([]looseObject, error) {
+	var out []looseObject
+	err := walkStore(objectsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if filepath.Clean(p) == filepath.Clean(packDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(objectsDir, p)
+		name := strings.ReplaceAll(filepath.ToSlash(rel), "/", "")
+		delta := strings.HasSuffix(name, ".delta")
+		hash := strings.TrimSuffix(name, ".delta")
+		if !validObjectHash(hash) {
+			return nil // temp files and strays
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		out = append(out, looseObject{Hash: hash, Path: p, Delta: delta, Size: fi.Size()})
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out, err
+}
+
+// packWriter appends objects to the newest pack, rolling over to a new one
+// once it grows past packMaxBytes.
+type packWriter struct {
+	name  string
+	f     File
+	off   int64
+	index map[string]PackEntry
+}
+
+func nextPackName() (string, int64, error) {
+	entries, err := repo.FS.ReadDir(packDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", 0, err
+	}
+	n := 0
+	var last string
+	for _, e := range entries {
+		var k int
+		if _, err := fmt.Sscanf(e.Name(), "pack-%04d.pack", &k); err == nil && k >= n {
+			n, last = k, e.Name()
+		}
+	}
+	if last != "" {
+		if fi, err := repo.FS.Stat(filepath.Join(packDir, last)); err == nil && fi.Size() < packMaxBytes {
+			return last, fi.Size(), nil
+		}
+	}
+	return fmt.Sprintf("pack-%04d.pack", n+1), 0, nil
+}
+
+func (w *packWriter) open() error {
+	name, off, err := nextPackName()
+	if err != nil {
+		return err
+	}
+	if err := repo.FS.MkdirAll(packDir, 0o755); err != nil {
+		return err
+	}
+	f, err := repo.FS.OpenFile(filepath.Join(packDir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	w.name, w.f, w.off = name, f, off
+	w.index = map[string]PackEntry{}
+	_ = loadJSON(w.idxPath(), &w.index)
+	return nil
+}
+
+func (w *packWriter) idxPath() string {
+	return filepath.Join(packDir, strings.TrimSuffix(w.name, ".pack")+".idx")
+}
+
+func (w *packWriter) add(hash string, data []byte, delta bool) error {
+	if w.f == nil || (w.off > 0 && w.off+int64(len(data)) > packMaxBytes) {
+		if err := w.close(); err != nil {
+			return err
+		}
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	if _, err := w.f.Write(data); err != nil {
+		return err
+	}
+	w.index[hash] = PackEntry{Offset: w.off, Size: int64(len(data)), Delta: delta}
+	w.off += int64(len(data))
+	return nil
+}
+
+// close syncs the pack data and only then publishes its index.
+func (w *packWriter) close() error {
+	if w.f == nil {
+		return nil
+	}
+	f := w.f
+	w.f = nil
+	if err := syncFile(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	packCache.mu.Lock()
+	packCache.index = nil
+	packCache.mu.Unlock()
+	return saveJSON(w.idxPath(), w.index)
+}
+
+// packLooseObjects moves loose objects up to packObjectMax bytes into packs
+// and returns how many it packed.
+func packLooseObjects() (int, error) {
+	loose, err := listLooseObjects()
+	if err != nil {
+		return 0, err
+	}
+	w := &packWriter{}
+	var packed []looseObject
+	for _, o := range loose {
+		if o.Size > packObjectMax {
+			continue
+		}
+		b, err := repo.FS.ReadFile(o.Path)
+		if err != nil {
+			w.close()
+			return 0, err
+		}
+		if !o.Delta && contentHash(b) != o.Hash {
+			continue // damaged; leave it where verify can find it
+		}
+		if err := w.add(o.Hash, b, o.Delta); err != nil {
+			w.close()
+			return 0, err
+		}
+		packed = append(packed, o)
+	}
+	if err := w.close(); err != nil {
+		return 0, err
+	}
+	// the index is durable now; the loose copies can go
+	for _, o := range packed {
+		if err := repo.FS.Remove(o.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return len(packed), err
+		}
+		removeEmptyParents(o.Path, filepath.FromSlash(objectsDir))
+	}
+	return len(packed), nil
+}
+
+// maybeAutoPack packs after an update once enough loose objects pile up.
+func maybeAutoPack(cfg Config) error {
+	if !cfg.AutoPack {
+		return nil
+	}
+	loose, err := listLooseObjects()
+	if err != nil || len(loose) < autoPackLooseSize {
+		return nil
+	}
+	n, err := packLooseObjects()
+	if err != nil {
+		fmt.Printf("⚠️  Warning: Could not pack objects: %v\n", err)
+		return err
+	}
+	if n > 0 {
+		fmt.Printf("📦 Packed %d small objects\n", n)
+	}
+	return nil
+}
+
+func runPack(args []string) error {
+	fs := flag.NewFlagSet("pack", flag.ExitOnError)
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+	n, err := packLooseObjects()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("📦 Packed %d objects (%d in packs in total)\n", n, len(packIndex()))
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestPackObjects(t *testing.T) {
+	setupTestDir(t)
+
+	for i := 0; i < 5; i++ {
+		createTestFile(t, fmt.Sprintf("note%d.md", i), fmt.Sprintf("note %d\n", i))
+	}
+	createTestFile(t, "big.md", longText(-1))
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.DeltaStorage = true
+	saveJSON(configFile, cfg)
+	createTestFile(t, "big.md", longText(3))
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	n, err := packLooseObjects()
+	if err != nil {
+		t.Fatalf("packLooseObjects failed: %v", err)
+	}
+	if n != 7 {
+		t.Errorf("Expected 7 objects packed (5 notes, big.md and its delta), got %d", n)
+	}
+	if loose, _ := listLooseObjects(); len(loose) != 0 {
+		t.Errorf("Expected no loose objects left, got %d", len(loose))
+	}
+
+	manifests, _ := loadManifests()
+	for _, m := range manifests {
+		for rel, h := range m.Files {
+			if !hasObject(h) {
+				t.Errorf("%s@v%.1f missing after packing", rel, m.Version)
+			}
+			if b, err := contentAt(rel, m); err != nil || contentHash(b) != h {
+				t.Errorf("%s@v%.1f not readable from pack: %v", rel, m.Version, err)
+			}
+		}
+	}
+
+	// a second run appends to the same pack
+	createTestFile(t, "note0.md", "changed\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if n, _ := packLooseObjects(); n != 1 {
+		t.Errorf("Expected 1 new object packed, got %d", n)
+	}
+	entries, _ := os.ReadDir(packDir)
+	if len(entries) != 2 {
+		t.Errorf("Expected a single pack and index, got %d files", len(entries))
+	}
+	if len(packIndex()) != 8 {
+		t.Errorf("Expected 8 packed objects, got %d", len(packIndex()))
+	}
+}
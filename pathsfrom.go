@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Path lists ---
+//
+// `--paths-from <file>` (or `-` for stdin) lets find, fzf or a script decide
+// exactly which files an update records, which files restore puts back or
+// which files untrack drops: `find . -name '*.md' -newer x | gitnot update
+// --paths-from -`. The list holds one path per line, or NUL-separated paths
+// (find -print0, fd -0) when it contains a NUL byte. Paths are literal,
+// never globs; "./" prefixes and absolute paths inside the project are
+// accepted.
+
+// readPathList reads the paths listed in src ("-" for stdin).
+func readPathList(src string) ([]string, error) {
+	var data []byte
+	var err error
+	if src == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(src)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading --paths-from %s: %w", src, err)
+	}
+	paths, err := parsePathList(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("--paths-from %s lists no paths", src)
+	}
+	return paths, nil
+}
+
+// parsePathList splits a path list and makes each path relative to the
+// project.
+func parsePathList(data []byte) ([]string, error) {
+	sep := []byte("\n")
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = []byte{0}
+	}
+	wd, _ := os.Getwd()
+	seen := map[string]bool{}
+	var out []string
+	for _, raw := range bytes.Split(data, sep) {
+		p := string(raw)
+		if len(sep) == 1 && sep[0] == '\n' {
+			p = strings.TrimSuffix(p, "\r")
+		}
+		if p == "" {
+			continue
+		}
+		if filepath.IsAbs(p) {
+			rel, err := filepath.Rel(wd, p)
+			if err != nil {
+				return nil, fmt.Errorf("%s is outside the project", p)
+			}
+			p = rel
+		}
+		p = filepath.Clean(filepath.FromSlash(p))
+		if within(p, "..") {
+			return nil, fmt.Errorf("%s is outside the project", string(raw))
+		}
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// limitToPaths narrows an update to the listed files and folders. Files
+// outside the list keep their recorded hash (changed or deleted ones are
+// held back for a later update, with their previous snapshot) and untracked
+// ones are left out.
+func limitToPaths(files []string, current, old map[string]string, paths []string) (keep, held []string) {
+	listed := func(rel string) bool {
+		for _, p := range paths {
+			if p == "." || within(rel, p) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, f := range files {
+		if listed(f) {
+			keep = append(keep, f)
+			continue
+		}
+		h, ok := old[f]
+		switch {
+		case ok && current[f] != h:
+			current[f] = h
+			held = append(held, f)
+		case ok:
+			keep = append(keep, f)
+		default:
+			delete(current, f)
+		}
+	}
+	for _, rel := range sortedKeys(old) {
+		if _, ok := current[rel]; !ok && !listed(rel) {
+			current[rel] = old[rel]
+			held = append(held, rel)
+		}
+	}
+	return keep, held
+}
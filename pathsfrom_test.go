@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePathList(t *testing.T) {
+	setupTestDir(t)
+	wd, _ := os.Getwd()
+
+	got, err := parsePathList([]byte("./a.md\r\nnotes/b.md\n\n" + filepath.Join(wd, "c.md") + "\na.md\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a.md", filepath.Join("notes", "b.md"), "c.md"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got, err = parsePathList([]byte("with\nnewline.md\x00b [1].md\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"with\nnewline.md", "b [1].md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NUL-separated: got %q, want %q", got, want)
+	}
+
+	if _, err := parsePathList([]byte("../outside.md\n")); err == nil {
+		t.Error("Expected paths outside the project to be refused")
+	}
+}
+
+func TestUpdateLimitedToPaths(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, filepath.Join("scenes", "one.md"), "One\n")
+	createTestFile(t, "outline.md", "Outline\n")
+	createTestFile(t, "gone.md", "Gone\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, filepath.Join("scenes", "one.md"), "One, edited\n")
+	createTestFile(t, filepath.Join("scenes", "two.md"), "Two\n")
+	createTestFile(t, "outline.md", "Outline, edited\n")
+	createTestFile(t, "extra.md", "Extra\n")
+	if err := os.Remove("gone.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := updateGitnotWith(updateOptions{Paths: []string{"scenes"}}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	m, err := loadManifest(0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changed []string
+	for _, c := range m.Changes {
+		changed = append(changed, c.Path)
+	}
+	want := []string{filepath.Join("scenes", "one.md"), filepath.Join("scenes", "two.md")}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("Recorded %q, want only %q", changed, want)
+	}
+	if _, ok := m.Files["extra.md"]; ok {
+		t.Error("A new file outside the list should not be tracked yet")
+	}
+	if _, ok := m.Files["gone.md"]; !ok {
+		t.Error("A deleted file outside the list should still be tracked")
+	}
+	snap, _ := os.ReadFile(filepath.Join(snapshotDir, "outline.md"))
+	if string(snap) != "Outline\n" {
+		t.Errorf("The snapshot of a held-back file should keep its old content, got %q", snap)
+	}
+
+	// the rest is recorded by the next plain update
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	m, _ = loadManifest(0.2)
+	if len(m.Changes) != 3 {
+		t.Errorf("Expected outline, extra and gone in v0.2, got %+v", m.Changes)
+	}
+	log, _ := os.ReadFile(filepath.Join(changelogDir, "outline.md.log"))
+	if !strings.Contains(string(log), "L1: Outline, edited") {
+		t.Errorf("The held-back change should diff against the old content:\n%s", log)
+	}
+}
+
+func TestRestorePathsFrom(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, filepath.Join("scenes", "one.md"), "One\n")
+	createTestFile(t, filepath.Join("scenes", "two.md"), "Two\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, filepath.Join("scenes", "one.md"), "Changed\n")
+	createTestFile(t, filepath.Join("scenes", "two.md"), "Changed\n")
+	createTestFile(t, "list.txt", "scenes\n")
+
+	if err := runRestore([]string{"--paths-from", "list.txt"}); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	for name, want := range map[string]string{"one.md": "One\n", "two.md": "Two\n"} {
+		if got, _ := os.ReadFile(filepath.Join("scenes", name)); string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
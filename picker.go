@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// --- Picker ---
+//
+// show and restore, run without a file on a terminal, ask for one instead of
+// failing; log and diff do the same with --pick. The picker is line based:
+// type a few letters to filter the list fuzzily, a number to choose, or just
+// Enter to take the best match. Where a version applies, a second list
+// offers the versions that changed the chosen file.
+
+const pickerShown = 10
+
+var errPickCancelled = errors.New("nothing picked")
+
+// stdin is shared by every prompt, so input typed ahead isn't lost to a
+// discarded buffer.
+var stdin = bufio.NewReader(os.Stdin)
+
+// isTerminal reports whether stdin is interactive.
+func isTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// fuzzyScore reports whether the letters of query appear in s in order
+// (ignoring case) and scores the match: consecutive letters and letters
+// starting a word or path segment count extra, so "ch1" prefers
+// "chapters/ch1.md" over "chapters/outline-h1.md".
+func fuzzyScore(query, s string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0, true
+	}
+	score, qi, prevMatch := 0, 0, -2
+	runes := []rune(s)
+	for i, r := range runes {
+		if qi == len(q) {
+			break
+		}
+		if unicode.ToLower(r) != q[qi] {
+			continue
+		}
+		score++
+		if i == prevMatch+1 {
+			score += 3
+		}
+		if i == 0 || strings.ContainsRune("/\\-_. ", runes[i-1]) {
+			score += 2
+		}
+		prevMatch = i
+		qi++
+	}
+	return score - len(runes)/10, qi == len(q)
+}
+
+// fuzzyFilter returns the items matching query, best first.
+func fuzzyFilter(query string, items []string) []string {
+	type scored struct {
+		s     string
+		score int
+	}
+	var matches []scored
+	for _, it := range items {
+		if sc, ok := fuzzyScore(query, it); ok {
+			matches = append(matches, scored{it, sc})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	out := make([]string, len(matches))
+	for i, m := range matches {
+		out[i] = m.s
+	}
+	return out
+}
+
+// pickFrom runs the picker over items, reading from r and writing to w.
+func pickFrom(r *bufio.Reader, w io.Writer, prompt string, items []string) (string, error) {
+	if len(items) == 0 {
+		return "", errPickCancelled
+	}
+	query := ""
+	for {
+		matches := fuzzyFilter(query, items)
+		fmt.Fprintf(w, "🔎 %s (%d of %d; type to filter, a number to choose, Enter for the first):\n", prompt, len(matches), len(items))
+		shown := matches[:min(len(matches), pickerShown)]
+		for i, m := range shown {
+			fmt.Fprintf(w, "  %2d  %s\n", i+1, m)
+		}
+		fmt.Fprint(w, "> ")
+		line, err := r.ReadString('\n')
+		line = strings.TrimSpace(line)
+		switch n, nerr := strconv.Atoi(line); {
+		case nerr == nil && n >= 1 && n <= len(shown):
+			return shown[n-1], nil
+		case line == "" && err == nil && len(shown) > 0:
+			return shown[0], nil
+		case err != nil:
+			return "", errPickCancelled
+		}
+		query = line
+	}
+}
+
+// pickFile asks for one of the files tracked at the current version.
+func pickFile() (string, error) {
+	return pickFrom(stdin, os.Stdout, "File", sortedKeys(loadCommittedHashes()))
+}
+
+// pickVersion asks for one of the versions that changed rel and returns it
+// as a --version argument.
+func pickVersion(rel string) (string, error) {
+	manifests, err := loadManifestHeads()
+	if err != nil {
+		return "", err
+	}
+	manifests = onlyPath(manifests, rel)
+	items := make([]string, 0, len(manifests))
+	for i := len(manifests) - 1; i >= 0; i-- {
+		m := manifests[i]
+		item := fmt.Sprintf("v%.1f  %s", m.Version, m.Timestamp.Local().Format("2006-01-02 15:04"))
+		if m.Message != "" {
+			item += "  " + m.Message
+		}
+		items = append(items, item)
+	}
+	picked, err := pickFrom(stdin, os.Stdout, "Version of "+rel, items)
+	if err != nil {
+		return "", err
+	}
+	v, _, _ := strings.Cut(picked, " ")
+	return v, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFuzzyFilter(t *testing.T) {
+	items := []string{"chapters/outline-h1.md", "chapters/ch1.md", "chapters/ch2.md", "readme.md"}
+	got := fuzzyFilter("ch1", items)
+	if len(got) != 2 || got[0] != "chapters/ch1.md" {
+		t.Errorf("Expected chapters/ch1.md first of two matches, got %v", got)
+	}
+	if got := fuzzyFilter("", items); !slices.Equal(got, items) {
+		t.Errorf("Empty query should keep every item in order, got %v", got)
+	}
+	if got := fuzzyFilter("xyz", items); len(got) != 0 {
+		t.Errorf("Expected no matches, got %v", got)
+	}
+}
+
+func TestPickFrom(t *testing.T) {
+	items := []string{"a.md", "chapters/ch1.md", "chapters/ch2.md"}
+	for input, want := range map[string]string{
+		"2\n":         "chapters/ch1.md",
+		"ch2\n\n":     "chapters/ch2.md",
+		"ch\n2\n":     "chapters/ch2.md",
+		"zzz\na\n1\n": "a.md",
+	} {
+		got, err := pickFrom(bufio.NewReader(strings.NewReader(input)), io.Discard, "File", items)
+		if err != nil || got != want {
+			t.Errorf("Input %q: got %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := pickFrom(bufio.NewReader(strings.NewReader("ch")), io.Discard, "File", items); !errors.Is(err, errPickCancelled) {
+		t.Errorf("End of input should cancel, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// --- Pins ---
+//
+// `gitnot pin style-guide.md` marks a file, or every file matching a glob,
+// as frozen reference material. An update that finds a pinned file changed
+// or deleted refuses to record a version until the edit is undone or
+// `gitnot --force` is run; with "pinned_changes": "warn" it records the
+// version and only warns. Pins are kept in .gitnot/pins.json.
+
+const (
+	pinsRefuse = "refuse"
+	pinsWarn   = "warn"
+)
+
+func loadPins() []string {
+	var pins []string
+	_ = loadJSON(pinsFile, &pins)
+	return pins
+}
+
+// pinnedIn lists the paths among groups that a pin covers.
+func pinnedIn(pins []string, groups ...[]string) []string {
+	if len(pins) == 0 {
+		return nil
+	}
+	var out []string
+	for _, g := range groups {
+		for _, rel := range g {
+			if matchesAny(filepath.ToSlash(rel), pins) {
+				out = append(out, rel)
+			}
+		}
+	}
+	return out
+}
+
+// checkPins stops an update that would record changes to pinned files,
+// unless forced or configured to warn.
+func checkPins(cfg Config, force bool, changed, deleted []string) error {
+	hit := pinnedIn(loadPins(), changed, deleted)
+	if len(hit) == 0 {
+		return nil
+	}
+	list := strings.Join(preview(hit, 3), ", ")
+	if len(hit) > 3 {
+		list += fmt.Sprintf(" and %d more", len(hit)-3)
+	}
+	if force || cfg.PinnedChanges == pinsWarn {
+		fmt.Printf("⚠️  Warning: recording changes to pinned files: %s\n", list)
+		return nil
+	}
+	return fmt.Errorf("pinned files changed: %s; undo the edits, unpin them, or run 'gitnot --force' to record anyway", list)
+}
+
+func validatePinnedChanges(mode string) []configIssue {
+	switch mode {
+	case "", pinsRefuse, pinsWarn:
+		return nil
+	}
+	return []configIssue{{"pinned_changes", fmt.Sprintf("%q is not one of %q, %q", mode, pinsRefuse, pinsWarn)}}
+}
+
+func runPin(args []string) error {
+	fs := flag.NewFlagSet("pin", flag.ExitOnError)
+	remove := fs.Bool("remove", false, "unpin the given files or globs")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	pins := loadPins()
+	if len(rest) == 0 {
+		if *remove {
+			return fmt.Errorf("usage: gitnot pin --remove <file|glob>...")
+		}
+		if len(pins) == 0 {
+			fmt.Println("📌 No pinned files")
+			return nil
+		}
+		for _, p := range pins {
+			fmt.Printf("  📌 %s\n", p)
+		}
+		return nil
+	}
+
+	has := map[string]bool{}
+	for _, p := range pins {
+		has[p] = true
+	}
+	for _, a := range rest {
+		p := filepath.ToSlash(filepath.Clean(a))
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("%q is not a valid glob", a)
+		}
+		if *remove {
+			if !has[p] {
+				return fmt.Errorf("%s is not pinned", p)
+			}
+			delete(has, p)
+		} else {
+			has[p] = true
+		}
+	}
+	if err := saveJSON(pinsFile, sortedKeys(has)); err != nil {
+		return err
+	}
+	if *remove {
+		fmt.Printf("📌 Unpinned %s\n", strings.Join(rest, ", "))
+	} else {
+		fmt.Printf("📌 Pinned %s; updates refuse to record changes to it\n", strings.Join(rest, ", "))
+	}
+	return nil
+}
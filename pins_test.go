@@ -0,0 +1,65 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPinnedFilesRefuseUpdate(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "style-guide.md", "Use the Oxford comma.\n")
+	createTestFile(t, filepath.Join("reference", "names.md"), "Ana\n")
+	createTestFile(t, "draft.md", "One\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := runPin([]string{"style-guide.md", "reference/*"}); err != nil {
+		t.Fatalf("pin failed: %v", err)
+	}
+	if pins := loadPins(); len(pins) != 2 {
+		t.Fatalf("Expected two pins, got %v", pins)
+	}
+
+	createTestFile(t, "draft.md", "Two\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("Unpinned changes should be recorded: %v", err)
+	}
+	createTestFile(t, "style-guide.md", "Skip the Oxford comma.\n")
+	createTestFile(t, "draft.md", "Three\n")
+	err := updateGitnot()
+	if err == nil || !strings.Contains(err.Error(), "style-guide.md") {
+		t.Fatalf("Expected the update to refuse, got %v", err)
+	}
+	if v, _ := readVersion(); v != 0.1 {
+		t.Errorf("No version should be recorded while refusing, at v%.1f", v)
+	}
+	if err := updateGitnotWith(updateOptions{Force: true}); err != nil {
+		t.Fatalf("--force should record the change: %v", err)
+	}
+
+	cfg := loadConfig()
+	cfg.PinnedChanges = pinsWarn
+	saveJSON(configFile, cfg)
+	createTestFile(t, filepath.Join("reference", "names.md"), "Ana\nBo\n")
+	if err := updateGitnot(); err != nil {
+		t.Errorf("With pinned_changes warn the update should go through: %v", err)
+	}
+
+	if err := runPin([]string{"--remove", "reference/*"}); err != nil {
+		t.Fatalf("unpin failed: %v", err)
+	}
+	if got := pinnedIn(loadPins(), []string{"reference/names.md", "style-guide.md"}); len(got) != 1 || got[0] != "style-guide.md" {
+		t.Errorf("Expected only style-guide.md still pinned, got %v", got)
+	}
+	if err := runPin([]string{"--remove", "nothing.md"}); err == nil {
+		t.Error("Expected an error unpinning a file that is not pinned")
+	}
+}
+
+func TestValidatePinnedChanges(t *testing.T) {
+	if issues := validatePinnedChanges("ask"); len(issues) != 1 {
+		t.Errorf("Expected an issue for an unknown mode, got %v", issues)
+	}
+}
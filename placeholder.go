@@ -0,0 +1,50 @@
+package main
+
+import "os"
+
+// --- Cloud-sync placeholders ---
+//
+// OneDrive, Dropbox and iCloud can leave "online-only" placeholders whose
+// content is fetched on first read. Hashing or copying them would download
+// the file (or fail), so by default they are deferred: a tracked placeholder
+// keeps its last recorded hash and snapshot, and an untracked one waits until
+// it is available locally. Set "cloud_placeholders": "hash" to read them
+// anyway.
+
+const (
+	placeholderSkip = "skip"
+	placeholderHash = "hash"
+)
+
+func (c Config) placeholderPolicy() string {
+	if c.CloudPlaceholders == placeholderHash {
+		return placeholderHash
+	}
+	return placeholderSkip
+}
+
+// deferPlaceholders splits files into those to read now and online-only
+// placeholders to leave alone under the configured policy.
+func deferPlaceholders(files []string, cfg Config) (keep, deferred []string) {
+	if cfg.placeholderPolicy() == placeholderHash {
+		return files, nil
+	}
+	for _, f := range files {
+		if fi, err := os.Lstat(f); err == nil && isPlaceholder(fi) {
+			deferred = append(deferred, f)
+			continue
+		}
+		keep = append(keep, f)
+	}
+	return keep, deferred
+}
+
+// carryDeferred gives deferred placeholders their previously recorded hash so
+// they count as unchanged; untracked ones are left out entirely.
+func carryDeferred(current, old map[string]string, deferred []string) {
+	for _, f := range deferred {
+		if h, ok := old[f]; ok {
+			current[f] = h
+		}
+	}
+}
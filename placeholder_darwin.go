@@ -0,0 +1,20 @@
+//go:build darwin
+
+package main
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// sfDataless marks APFS "dataless" files whose content lives in iCloud or a
+// File Provider extension until it is materialized.
+const sfDataless = 0x40000000
+
+func isPlaceholder(fi fs.FileInfo) bool {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return st.Flags&sfDataless != 0
+}
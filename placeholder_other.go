@@ -0,0 +1,11 @@
+//go:build !windows && !darwin
+
+package main
+
+import "io/fs"
+
+// Other platforms have no standard placeholder marker; sync clients there
+// keep real files on disk.
+func isPlaceholder(fi fs.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestCarryDeferred(t *testing.T) {
+	old := map[string]string{"tracked.md": "h1"}
+	current := map[string]string{"other.md": "h2"}
+	carryDeferred(current, old, []string{"tracked.md", "new.md"})
+
+	if current["tracked.md"] != "h1" {
+		t.Error("Tracked placeholder should keep its previous hash")
+	}
+	if _, ok := current["new.md"]; ok {
+		t.Error("Untracked placeholder should not be adopted")
+	}
+}
+
+func TestPlaceholderPolicy(t *testing.T) {
+	files := []string{"a.md", "b.md"}
+	keep, deferred := deferPlaceholders(files, Config{CloudPlaceholders: placeholderHash})
+	if len(keep) != 2 || len(deferred) != 0 {
+		t.Errorf("\"hash\" policy must read every file, got keep=%v deferred=%v", keep, deferred)
+	}
+	if (Config{}).placeholderPolicy() != placeholderSkip {
+		t.Error("Placeholders should be skipped by default")
+	}
+}
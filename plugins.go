@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- Plugins ---
+//
+// Niche formats and remotes are supported out of tree through four
+// extension points: diff drivers (changelog entries), content extractors
+// (text out of .docx, .pdf, ...), remote transports (publish targets) and
+// storage backends (a second home for objects). Code built into gitnot
+// registers implementations by name; anything else is looked up as a
+// gitnot-<name> program on PATH, like git does with git-<name>. The same
+// programs also work as subcommands: `gitnot foo` runs gitnot-foo.
+//
+// An exec plugin is called with a verb and reads or writes stdio:
+//
+//	gitnot-<name> diff <old> <new>   markdown on stdout; exit 1 declines
+//	gitnot-<name> extract <path>     content on stdin, text on stdout
+//	gitnot-<name> publish <dest>     "source<TAB>target" lines on stdin
+//	gitnot-<name> get <hash>         object content on stdout
+//	gitnot-<name> put <hash>         object content on stdin
+
+// DiffDriver renders a changelog entry from the old and new content; ok is
+// false when the line diff should be used instead.
+type DiffDriver interface {
+	Diff(oldB, newB []byte) (md string, ok bool)
+}
+
+// ContentExtractor turns a file into plain text, which is then diffed and
+// measured like any text file.
+type ContentExtractor interface {
+	Extract(rel string, b []byte) (string, error)
+}
+
+// RemoteTransport copies files to a publish target. files maps each local
+// path to its path below dest.
+type RemoteTransport interface {
+	Publish(dest string, files map[string]string) error
+}
+
+// StorageBackend keeps copies of objects outside the project. Get is only
+// consulted for objects missing from the local store.
+type StorageBackend interface {
+	Get(hash string) ([]byte, error)
+	Put(hash string, b []byte) error
+}
+
+// Diff lets the built-in driver functions serve as DiffDrivers.
+func (d diffDriver) Diff(oldB, newB []byte) (string, bool) { return d(oldB, newB) }
+
+var (
+	registeredDrivers    = map[string]DiffDriver{}
+	registeredExtractors = map[string]ContentExtractor{}
+	registeredTransports = map[string]RemoteTransport{} // by URL scheme
+	registeredBackends   = map[string]StorageBackend{}
+)
+
+// register adds impl under name, panicking on duplicates like
+// database/sql.Register: two registrations are a programming error.
+func register[T any](m map[string]T, kind, name string, impl T) {
+	if _, dup := m[name]; dup {
+		panic(fmt.Sprintf("gitnot: %s %q registered twice", kind, name))
+	}
+	m[name] = impl
+}
+
+func registerDiffDriver(name string, d DiffDriver) {
+	if _, ok := namedDrivers[name]; ok || name == diffLines || name == diffNone {
+		panic(fmt.Sprintf("gitnot: diff driver %q is built in", name))
+	}
+	register(registeredDrivers, "diff driver", name, d)
+}
+
+func registerExtractor(name string, e ContentExtractor) {
+	register(registeredExtractors, "extractor", name, e)
+}
+
+func registerTransport(scheme string, t RemoteTransport) {
+	register(registeredTransports, "transport", scheme, t)
+}
+
+func registerStorage(name string, s StorageBackend) {
+	register(registeredBackends, "storage backend", name, s)
+}
+
+// lookup finds name among the registered implementations, then on PATH.
+func lookup[T any](m map[string]T, name string, fromPlugin func(execPlugin) T) (T, bool) {
+	if impl, ok := m[name]; ok {
+		return impl, true
+	}
+	if p, ok := findPlugin(name); ok {
+		return fromPlugin(p), true
+	}
+	var zero T
+	return zero, false
+}
+
+func lookupDiffDriver(name string) (DiffDriver, bool) {
+	if d, ok := namedDrivers[name]; ok {
+		return d, true
+	}
+	return lookup(registeredDrivers, name, func(p execPlugin) DiffDriver { return p })
+}
+
+func lookupExtractor(name string) (ContentExtractor, bool) {
+	return lookup(registeredExtractors, name, func(p execPlugin) ContentExtractor { return p })
+}
+
+func lookupTransport(scheme string) (RemoteTransport, bool) {
+	return lookup(registeredTransports, scheme, func(p execPlugin) RemoteTransport { return p })
+}
+
+func lookupStorage(name string) (StorageBackend, bool) {
+	return lookup(registeredBackends, name, func(p execPlugin) StorageBackend { return p })
+}
+
+// --- Exec plugins ---
+
+// pluginTimeout bounds one plugin call made during an update.
+const pluginTimeout = 2 * time.Minute
+
+// execPlugin is a gitnot-<name> program found on PATH.
+type execPlugin struct {
+	name string
+	path string
+}
+
+// validPluginName keeps names to what is safe to put into a program name.
+func validPluginName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func findPlugin(name string) (execPlugin, bool) {
+	if !validPluginName(name) {
+		return execPlugin{}, false
+	}
+	p, err := exec.LookPath("gitnot-" + name)
+	if err != nil {
+		return execPlugin{}, false
+	}
+	return execPlugin{name: name, path: p}, true
+}
+
+// call runs the plugin with args, feeding it stdin, and returns its stdout.
+func (p execPlugin) call(stdin io.Reader, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.path, args...)
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("gitnot-%s %s: timed out after %s", p.name, args[0], pluginTimeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("gitnot-%s %s: %v: %s", p.name, args[0], err, msg)
+		}
+		return nil, fmt.Errorf("gitnot-%s %s: %v", p.name, args[0], err)
+	}
+	return out, nil
+}
+
+func (p execPlugin) Diff(oldB, newB []byte) (string, bool) {
+	dir, err := os.MkdirTemp("", "gitnot-diff-")
+	if err != nil {
+		return "", false
+	}
+	defer os.RemoveAll(dir)
+	oldP, newP := filepath.Join(dir, "old"), filepath.Join(dir, "new")
+	if os.WriteFile(oldP, oldB, 0o600) != nil || os.WriteFile(newP, newB, 0o600) != nil {
+		return "", false
+	}
+	out, err := p.call(nil, "diff", oldP, newP)
+	if err != nil || len(bytes.TrimSpace(out)) == 0 {
+		return "", false
+	}
+	md := string(out)
+	if !strings.HasSuffix(md, "\n") {
+		md += "\n"
+	}
+	return md, true
+}
+
+func (p execPlugin) Extract(rel string, b []byte) (string, error) {
+	out, err := p.call(bytes.NewReader(b), "extract", rel)
+	return string(out), err
+}
+
+func (p execPlugin) Publish(dest string, files map[string]string) error {
+	var list bytes.Buffer
+	for _, src := range sortedKeys(files) {
+		fmt.Fprintf(&list, "%s\t%s\n", src, files[src])
+	}
+	_, err := p.call(&list, "publish", dest)
+	return err
+}
+
+func (p execPlugin) Get(hash string) ([]byte, error) {
+	return p.call(nil, "get", hash)
+}
+
+func (p execPlugin) Put(hash string, b []byte) error {
+	_, err := p.call(bytes.NewReader(b), "put", hash)
+	return err
+}
+
+// runPluginCommand runs `gitnot <name> args...` as gitnot-<name>, handing it
+// the terminal, and returns its exit code.
+func runPluginCommand(p execPlugin, args []string) int {
+	cmd := exec.Command(p.path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if dir, err := filepath.Abs(gitnotDir); err == nil {
+		cmd.Env = append(os.Environ(), "GITNOT_DIR="+dir)
+	}
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		fmt.Println("❌", err)
+		return 1
+	}
+	return 0
+}
+
+// --- Using plugins ---
+
+// extractDiff diffs the text a rule's extractor pulls out of both sides and
+// measures the change on that text.
+func extractDiff(name, rel, oldPath, newPath string) (md string, fc FileChange, ok bool) {
+	ex, found := lookupExtractor(name)
+	if !found {
+		return "", fc, false
+	}
+	text := func(p string) (string, error) {
+		b, err := os.ReadFile(longPath(p))
+		if err != nil {
+			return "", err
+		}
+		return ex.Extract(rel, b)
+	}
+	oldT, err := text(oldPath)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: extracting text from %s failed: %v\n", rel, err)
+		return "", fc, false
+	}
+	newT, err := text(newPath)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: extracting text from %s failed: %v\n", rel, err)
+		return "", fc, false
+	}
+	fc = measureChange(oldT, newT)
+	diffText := unifiedDiffLines(splitTextLines(oldT), splitTextLines(newT))
+	return formatDiffAsMarkdown(diffText), fc, true
+}
+
+// publishTransport returns the transport for a "scheme://..." target.
+func (t PublishTarget) publishTransport() (RemoteTransport, bool) {
+	scheme, _, ok := strings.Cut(t.To, "://")
+	if !ok {
+		return nil, false
+	}
+	return lookupTransport(scheme)
+}
+
+// storeRemote copies the content of every file m added or changed to the
+// configured storage backend. A backend that hasn't been filled yet gets
+// every object the history holds instead (see syncStorage), so versions
+// recorded before storage was configured reach it too.
+func storeRemote(name string, m Manifest) error {
+	if name == "" {
+		return nil
+	}
+	if storageSynced() != name {
+		n, err := syncStorage(name)
+		if n > 0 {
+			fmt.Printf("☁️  Copied %d stored objects to storage %s\n", n, name)
+		}
+		return err
+	}
+	backend, ok := lookupStorage(name)
+	if !ok {
+		return fmt.Errorf("no storage backend %q (and no gitnot-%s on PATH)", name, name)
+	}
+	for _, c := range m.Changes {
+		if c.State == stateDeleted {
+			continue
+		}
+		h := m.Files[c.Path]
+		b, err := readObject(h)
+		if err != nil {
+			continue // not in the local store either, nothing to copy
+		}
+		if err := backend.Put(h, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storageState records in storage.json which backend holds every object.
+type storageState struct {
+	Synced string `json:"synced"`
+}
+
+func storageSynced() string {
+	var st storageState
+	_ = loadJSON(storageFile, &st)
+	return st.Synced
+}
+
+// syncStorage copies every object any version refers to into the backend
+// called name and notes that it is complete. It returns how many objects
+// it copied.
+func syncStorage(name string) (int, error) {
+	backend, ok := lookupStorage(name)
+	if !ok {
+		return 0, fmt.Errorf("no storage backend %q (and no gitnot-%s on PATH)", name, name)
+	}
+	sent := map[string]bool{}
+	err := eachManifest(func(m Manifest) error {
+		for _, h := range m.Files {
+			if sent[h] {
+				continue
+			}
+			b, err := readObject(h)
+			if err != nil {
+				continue // not in the local store either, nothing to copy
+			}
+			if err := backend.Put(h, b); err != nil {
+				return err
+			}
+			sent[h] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return len(sent), err
+	}
+	return len(sent), saveJSON(storageFile, storageState{Synced: name})
+}
+
+func runStorage(args []string) error {
+	fs := flag.NewFlagSet("storage", flag.ExitOnError)
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 || rest[0] != "sync" {
+		return fmt.Errorf("usage: gitnot storage sync")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	name := loadConfig().Storage
+	if name == "" {
+		return fmt.Errorf("no storage configured; add \"storage\": \"<name>\" to %s", configFile)
+	}
+	n, err := syncStorage(name)
+	if err != nil {
+		return fmt.Errorf("copying objects to storage %s: %w", name, err)
+	}
+	fmt.Printf("☁️  Storage %s holds all %d stored objects\n", name, n)
+	return nil
+}
+
+// fetchRemote reads an object back from the configured storage backend,
+// refusing content that doesn't match its hash.
+func fetchRemote(hash string) ([]byte, error) {
+	name := loadConfig().Storage
+	if name == "" {
+		return nil, fmt.Errorf("object %s: %w", hash, os.ErrNotExist)
+	}
+	backend, ok := lookupStorage(name)
+	if !ok {
+		return nil, fmt.Errorf("no storage backend %q", name)
+	}
+	b, err := backend.Get(hash)
+	if err != nil {
+		return nil, err
+	}
+	if contentHash(b) != hash {
+		return nil, fmt.Errorf("storage %s returned corrupt content for %s", name, hash)
+	}
+	return b, nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type upperExtractor struct{}
+
+func (upperExtractor) Extract(rel string, b []byte) (string, error) {
+	return strings.ToUpper(string(b)), nil
+}
+
+type memStorage map[string][]byte
+
+func (m memStorage) Get(hash string) ([]byte, error) {
+	if b, ok := m[hash]; ok {
+		return b, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func (m memStorage) Put(hash string, b []byte) error {
+	m[hash] = b
+	return nil
+}
+
+func TestRegisterPlugins(t *testing.T) {
+	setupTestDir(t)
+	t.Cleanup(func() {
+		delete(registeredDrivers, "shout")
+		delete(registeredExtractors, "upper")
+	})
+
+	registerDiffDriver("shout", diffDriver(func(oldB, newB []byte) (string, bool) {
+		return "📣 " + string(newB), true
+	}))
+	registerExtractor("upper", upperExtractor{})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected registering a built-in driver name to panic")
+			}
+		}()
+		registerDiffDriver("latex", diffDriver(latexDiff))
+	}()
+
+	createTestFile(t, "old.txt", "a\n")
+	createTestFile(t, "new.txt", "b\n")
+	if md, ok := driverDiff("new.txt", "old.txt", "new.txt", false, "shout"); !ok || md != "📣 b\n" {
+		t.Errorf("Expected the registered driver to render, got %q %v", md, ok)
+	}
+	md, fc, ok := extractDiff("upper", "new.txt", "old.txt", "new.txt")
+	if !ok || !strings.Contains(md, "B") || fc.LinesAdded != 1 || fc.LinesRemoved != 1 {
+		t.Errorf("Expected a diff of the extracted text, got %q %+v %v", md, fc, ok)
+	}
+
+	issues := validateRules([]Rule{{Match: "*.txt", Diff: "shout", Extract: "upper"}, {Match: "*.x", Diff: "nope", Extract: "nope"}})
+	if len(issues) != 2 {
+		t.Errorf("Expected only the unknown driver and extractor to be reported, got %v", issues)
+	}
+}
+
+func TestStorageBackend(t *testing.T) {
+	setupTestDir(t)
+	mem := memStorage{}
+	registerStorage("mem", mem)
+	t.Cleanup(func() { delete(registeredBackends, "mem") })
+
+	createTestFile(t, "notes.txt", "first\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, configFile, `{"extensions": [".txt"], "storage": "mem"}`)
+	createTestFile(t, "notes.txt", "second\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	h := contentHash([]byte("second\n"))
+	if string(mem[h]) != "second\n" {
+		t.Fatalf("Expected the new content in the backend, got %v", mem)
+	}
+	if string(mem[contentHash([]byte("first\n"))]) != "first\n" {
+		t.Errorf("Expected versions from before storage was configured to be copied too, got %v", mem)
+	}
+
+	// Lose the local copy; reads fall back to the backend.
+	if err := os.RemoveAll(objectsDir); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(snapshotDir, "notes.txt"))
+	m, err := loadManifest(0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := contentAt("notes.txt", m); err != nil || string(b) != "second\n" {
+		t.Errorf("Expected the content from the backend, got %q, %v", b, err)
+	}
+
+	mem[h] = []byte("rotten\n")
+	if _, err := contentAt("notes.txt", m); err == nil {
+		t.Error("Expected corrupt backend content to be refused")
+	}
+}
+
+func TestExecPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin is a shell script")
+	}
+	setupTestDir(t)
+	bin := t.TempDir()
+	script := `#!/bin/sh
+case "$1" in
+diff) echo "demo: $(cat "$3")" ;;
+extract) tr a-z A-Z ;;
+get) cat "$STORE/$2" ;;
+put) cat > "$STORE/$2" ;;
+publish) cat > "$STORE/published" ;;
+*) echo "$@" > args.out; exit 3 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(bin, "gitnot-demo"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("STORE", t.TempDir())
+
+	if _, ok := findPlugin("../demo"); ok {
+		t.Error("Expected names with path separators to be refused")
+	}
+	d, ok := lookupDiffDriver("demo")
+	if !ok {
+		t.Fatal("Expected gitnot-demo to be found on PATH")
+	}
+	if md, ok := d.Diff([]byte("a"), []byte("b")); !ok || md != "demo: b\n" {
+		t.Errorf("Unexpected plugin diff %q %v", md, ok)
+	}
+	ex, _ := lookupExtractor("demo")
+	if text, err := ex.Extract("x.docx", []byte("hello")); err != nil || text != "HELLO" {
+		t.Errorf("Unexpected extracted text %q, %v", text, err)
+	}
+	st, _ := lookupStorage("demo")
+	if err := st.Put("abc", []byte("blob")); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := st.Get("abc"); err != nil || string(b) != "blob" {
+		t.Errorf("Expected the stored blob back, got %q, %v", b, err)
+	}
+	tr, _ := lookupTransport("demo")
+	if err := tr.Publish("demo://site", map[string]string{"blog/a.md": "a.md"}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(os.Getenv("STORE"), "published")); string(b) != "blog/a.md\ta.md\n" {
+		t.Errorf("Unexpected publish list %q", b)
+	}
+
+	if code := run([]string{"demo", "hello", "world"}); code != 3 {
+		t.Errorf("Expected the plugin's exit code, got %d", code)
+	}
+	if b, _ := os.ReadFile("args.out"); strings.TrimSpace(string(b)) != "hello world" {
+		t.Errorf("Expected the arguments to be passed on, got %q", b)
+	}
+}
+
+func TestStorageAtInitAndSync(t *testing.T) {
+	setupTestDir(t)
+	mem, other := memStorage{}, memStorage{}
+	registerStorage("mem", mem)
+	registerStorage("other", other)
+	t.Cleanup(func() {
+		delete(registeredBackends, "mem")
+		delete(registeredBackends, "other")
+	})
+
+	createTestFile(t, "notes.txt", "first\n")
+	createTestFile(t, configFile, `{"extensions": [".txt"], "storage": "mem"}`)
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	h := contentHash([]byte("first\n"))
+	if string(mem[h]) != "first\n" {
+		t.Fatalf("Expected v0.0 in the backend after init, got %v", mem)
+	}
+
+	createTestFile(t, configFile, `{"extensions": [".txt"], "storage": "other"}`)
+	if err := runStorage([]string{"sync"}); err != nil {
+		t.Fatalf("storage sync failed: %v", err)
+	}
+	if string(other[h]) != "first\n" || storageSynced() != "other" {
+		t.Errorf("Expected storage sync to fill the new backend, got %v (%q)", other, storageSynced())
+	}
+	if err := runStorage(nil); err == nil {
+		t.Error("Expected a usage error without sync")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// --- Worker pool ---
+//
+// Work that is mostly reading and hashing stored files (verify, scrub) is
+// spread over a few goroutines: disks and SSDs serve several reads at once,
+// and hashing keeps a core busy per file. Results are collected by index, so
+// what is reported stays in the same order however the work was scheduled.
+
+// defaultJobs is how many files are read at once unless --jobs says
+// otherwise: one per core, but not so many that a spinning disk thrashes.
+func defaultJobs() int {
+	return min(runtime.NumCPU(), 8)
+}
+
+// parallelEach calls fn for every index below n on up to workers goroutines
+// and returns once all calls have. fn must be safe to call concurrently.
+func parallelEach(n, workers int, fn func(i int)) {
+	workers = max(1, min(workers, n))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallelEach(t *testing.T) {
+	for _, workers := range []int{0, 1, 3, 50} {
+		var mu sync.Mutex
+		seen := map[int]int{}
+		var running, peak atomic.Int32
+		parallelEach(20, workers, func(i int) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			mu.Lock()
+			seen[i]++
+			mu.Unlock()
+		})
+		if len(seen) != 20 {
+			t.Errorf("workers=%d: visited %d of 20 indexes", workers, len(seen))
+		}
+		for i, n := range seen {
+			if n != 1 {
+				t.Errorf("workers=%d: index %d visited %d times", workers, i, n)
+			}
+		}
+		if limit := int32(max(1, workers)); peak.Load() > limit {
+			t.Errorf("workers=%d: %d calls ran at once", workers, peak.Load())
+		}
+	}
+	parallelEach(0, 4, func(int) { t.Error("Nothing to do should call nothing") })
+}
+
+func TestProgressLine(t *testing.T) {
+	tests := []struct {
+		done, total int
+		elapsed     time.Duration
+		want        string
+	}{
+		{0, 10, 0, "🔎 Checking 0/10 (0%)"},
+		{25, 100, 10 * time.Second, "🔎 Checking 25/100 (25%), about 30s left"},
+		{99, 100, time.Second, "🔎 Checking 99/100 (99%), about 1s left"},
+		{10, 10, time.Minute, "🔎 Checking 10/10 (100%)"},
+	}
+	for _, tt := range tests {
+		if got := progressLine("Checking", tt.done, tt.total, tt.elapsed); got != tt.want {
+			t.Errorf("progressLine(%d, %d, %v) = %q, want %q", tt.done, tt.total, tt.elapsed, got, tt.want)
+		}
+	}
+}
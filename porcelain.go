@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// --- Porcelain status ---
+//
+// `gitnot status --porcelain=v1` prints one record per changed file for
+// scripts and editor plugins. The v1 format is frozen: new information only
+// ever appears in a new version (v2, ...), never as a change to v1.
+//
+//	<state> SP <old-hash> SP <new-hash> SP <size> SP <path> LF
+//
+// state is A (added), M (modified) or D (deleted); hashes are the 40-digit
+// SHA-1 of the content or "-" when there is none; size is the current size
+// in bytes, "-" for deleted files; path is relative to the project root with
+// "/" separators, last so it may contain spaces. Paths containing a quote,
+// backslash or control character are written as a Go/C-style quoted string.
+// With -z records end in NUL and paths are never quoted. Records are sorted
+// by path; no changes means no output. Only the records go to stdout:
+// anything else gitnot has to say, such as config warnings, goes to stderr.
+
+const porcelainV1 = "v1"
+
+// recordsOut is the real standard output while a machine-readable command
+// runs; see reserveStdout.
+var recordsOut *os.File
+
+// machineReadable reports whether args ask for output scripts parse:
+// status --porcelain or -z, and query.
+func machineReadable(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "query":
+		return true
+	case "status":
+		for _, a := range args[1:] {
+			if a == "--" {
+				break
+			}
+			name, _, _ := strings.Cut(strings.TrimLeft(a, "-"), "=")
+			if strings.HasPrefix(a, "-") && (name == "porcelain" || name == "z") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reserveStdout keeps standard output for a command's records: everything
+// else printed until the returned func runs (recovery notices, warnings,
+// errors) goes to stderr instead.
+func reserveStdout() (restore func()) {
+	recordsOut, os.Stdout = os.Stdout, os.Stderr
+	return func() { os.Stdout, recordsOut = recordsOut, nil }
+}
+
+// recordsOutput is where machine-readable records are written.
+func recordsOutput() io.Writer {
+	if recordsOut != nil {
+		return recordsOut
+	}
+	return os.Stdout
+}
+
+// porcelainFlag accepts --porcelain (meaning v1) and --porcelain=<version>.
+type porcelainFlag string
+
+func (p *porcelainFlag) String() string { return string(*p) }
+
+func (p *porcelainFlag) IsBoolFlag() bool { return true }
+
+func (p *porcelainFlag) Set(v string) error {
+	switch v {
+	case "true", porcelainV1:
+		*p = porcelainV1
+	case "false":
+		*p = ""
+	default:
+		return fmt.Errorf("unsupported porcelain format %q (supported: %s)", v, porcelainV1)
+	}
+	return nil
+}
+
+type porcelainRecord struct {
+	State   byte
+	OldHash string
+	NewHash string
+	Size    int64 // -1 when the file is gone
+	Path    string
+}
+
+func porcelainRecords(newFiles, changedFiles, deletedFiles []string, old, current map[string]string) []porcelainRecord {
+	var out []porcelainRecord
+	size := func(p string) int64 {
+		if fi, err := os.Stat(longPath(p)); err == nil {
+			return fi.Size()
+		}
+		return -1
+	}
+	for _, p := range newFiles {
+		out = append(out, porcelainRecord{'A', "", current[p], size(p), p})
+	}
+	for _, p := range changedFiles {
+		out = append(out, porcelainRecord{'M', old[p], current[p], size(p), p})
+	}
+	for _, p := range deletedFiles {
+		out = append(out, porcelainRecord{'D', old[p], "", -1, p})
+	}
+	sort.Slice(out, func(i, j int) bool { return filepath.ToSlash(out[i].Path) < filepath.ToSlash(out[j].Path) })
+	return out
+}
+
+// porcelainPath quotes a path only when it needs it.
+func porcelainPath(p string, nul bool) string {
+	p = filepath.ToSlash(p)
+	if nul {
+		return p
+	}
+	if strings.ContainsFunc(p, func(r rune) bool { return r < 0x20 || r == 0x7f || r == '"' || r == '\\' }) {
+		return strconv.Quote(p)
+	}
+	return p
+}
+
+func writePorcelain(w io.Writer, records []porcelainRecord, nul bool) error {
+	end := "\n"
+	if nul {
+		end = "\x00"
+	}
+	dash := func(s string) string {
+		if s == "" || !validObjectHash(s) {
+			return "-"
+		}
+		return s
+	}
+	for _, r := range records {
+		size := "-"
+		if r.Size >= 0 {
+			size = strconv.FormatInt(r.Size, 10)
+		}
+		if _, err := fmt.Fprintf(w, "%c %s %s %s %s%s", r.State, dash(r.OldHash), dash(r.NewHash), size, porcelainPath(r.Path, nul), end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPorcelainV1(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "keep.md", "same")
+	createTestFile(t, "edit.md", "before")
+	createTestFile(t, "gone.md", "bye")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	old := loadCommittedHashes()
+	createTestFile(t, "edit.md", "after!")
+	createTestFile(t, "new file.md", "hi")
+	os.Remove("gone.md")
+
+	files, _ := getAllTextFiles(".")
+	current := map[string]string{}
+	for _, f := range files {
+		current[f] = hashFile(f)
+	}
+	n, c, d := detectChanges(old, current)
+	var buf bytes.Buffer
+	if err := writePorcelain(&buf, porcelainRecords(n, c, d, old, current), false); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"M " + old["edit.md"] + " " + current["edit.md"] + " 6 edit.md",
+		"D " + old["gone.md"] + " - - gone.md",
+		"A - " + current["new file.md"] + " 2 new file.md",
+	}, "\n") + "\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected porcelain output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	writePorcelain(&buf, porcelainRecords(n, nil, nil, old, current), true)
+	if !strings.HasSuffix(buf.String(), " new file.md\x00") {
+		t.Errorf("-z output should end records in NUL: %q", buf.String())
+	}
+
+	if got := porcelainPath("a\"b\tc.md", false); got != `"a\"b\tc.md"` {
+		t.Errorf("Quoted path: got %s", got)
+	}
+	if got := porcelainPath("a\"b.md", true); got != `a"b.md` {
+		t.Errorf("-z must not quote: got %s", got)
+	}
+}
+
+func TestPorcelainFlag(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want string
+		ok   bool
+	}{
+		{[]string{"--porcelain"}, "v1", true},
+		{[]string{"--porcelain=v1"}, "v1", true},
+		{[]string{"--porcelain=v9"}, "", false},
+	} {
+		fs := flag.NewFlagSet("status", flag.ContinueOnError)
+		fs.SetOutput(&bytes.Buffer{})
+		var p porcelainFlag
+		fs.Var(&p, "porcelain", "")
+		err := fs.Parse(tc.args)
+		if (err == nil) != tc.ok || string(p) != tc.want {
+			t.Errorf("%v: got %q, %v", tc.args, p, err)
+		}
+	}
+}
+
+func TestPorcelainStdoutHoldsOnlyRecords(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	b, _ := os.ReadFile(configFile)
+	createTestFile(t, configFile, strings.Replace(string(b), "{", `{"bogus": true,`, 1))
+	configWarned = map[string]bool{}
+	createTestFile(t, lockFile, "999999") // a stale lock: recovery has something to say
+	createTestFile(t, "a.md", "two")
+
+	var code int
+	out := captureStdout(t, func() { code = run([]string{"status", "--porcelain"}) })
+	if code != 0 {
+		t.Fatalf("status exited with %d", code)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "M ") || !strings.HasSuffix(lines[0], " a.md") {
+		t.Errorf("Expected only the porcelain record on stdout, got %q", out)
+	}
+	if recordsOut != nil {
+		t.Error("Standard output should be restored after the command")
+	}
+}
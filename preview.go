@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Changelog preview ---
+//
+// With --show-diff, an update ends by printing the changelog entries it just
+// wrote, so there's no need to open the .log files to see what was recorded.
+
+// latestEntry returns the entry for version ver from a changelog.
+func latestEntry(clPath string, ver float64) string {
+	b, err := os.ReadFile(longPath(clPath))
+	if err != nil {
+		return ""
+	}
+	text := string(b)
+	i := strings.LastIndex(text, fmt.Sprintf("\n## v%.1f – ", ver))
+	if i < 0 {
+		return ""
+	}
+	text = text[i+1:]
+	if j := strings.Index(text, "\n## v"); j >= 0 {
+		text = text[:j]
+	}
+	return strings.TrimSpace(text)
+}
+
+func printEntries(changes []FileChange, ver float64) {
+	fmt.Printf("📰 Changelog entries for v%.1f:\n", ver)
+	for _, c := range changes {
+		entry := latestEntry(filepath.Join(changelogDir, c.Path+".log"), ver)
+		if entry == "" {
+			continue
+		}
+		fmt.Printf("\n── %s ──\n%s\n", filepath.ToSlash(c.Path), entry)
+	}
+}
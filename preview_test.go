@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLatestEntry(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one\ntwo\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "a.md", "one\nthree\n")
+	if err := updateGitnotWith(updateOptions{ShowDiff: true}); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	createTestFile(t, "a.md", "one\nfour\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	cl := filepath.Join(changelogDir, "a.md.log")
+	first := latestEntry(cl, 0.1)
+	if !strings.HasPrefix(first, "## v0.1 – ") || !strings.Contains(first, "three") {
+		t.Errorf("Unexpected v0.1 entry:\n%s", first)
+	}
+	if strings.Contains(first, "four") {
+		t.Errorf("v0.1 entry must stop at its own section, got:\n%s", first)
+	}
+	if e := latestEntry(cl, 0.2); !strings.Contains(e, "four") {
+		t.Errorf("Unexpected v0.2 entry:\n%s", e)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+)
+
+// --- Profiling ---
+//
+// --cpuprofile, --memprofile and --trace work with any command. They are
+// taken off the command line before anything else parses it, and the files
+// are finished when the command ends, including when it fails.
+
+var profileFlags = []string{"cpuprofile", "memprofile", "trace"}
+
+// splitProfileFlags removes the profiling flags from args and returns their
+// values by name.
+func splitProfileFlags(args []string) (rest []string, files map[string]string, err error) {
+	files = map[string]string{}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		known := strings.HasPrefix(a, "-")
+		if known {
+			known = false
+			for _, f := range profileFlags {
+				known = known || name == f
+			}
+		}
+		if !known {
+			rest = append(rest, a)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("--%s needs a file name", name)
+			}
+			i++
+			value = args[i]
+		}
+		files[name] = value
+	}
+	return rest, files, nil
+}
+
+// startProfiling starts the requested profiles; the returned stop function
+// writes them out.
+func startProfiling(files map[string]string) (stop func(), err error) {
+	var stops []func()
+	stop = func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+	if p := files["cpuprofile"]; p != "" {
+		f, err := os.Create(p)
+		if err != nil {
+			return stop, err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return stop, err
+		}
+		stops = append(stops, func() { pprof.StopCPUProfile(); f.Close() })
+	}
+	if p := files["trace"]; p != "" {
+		f, err := os.Create(p)
+		if err != nil {
+			return stop, err
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			return stop, err
+		}
+		stops = append(stops, func() { trace.Stop(); f.Close() })
+	}
+	if p := files["memprofile"]; p != "" {
+		stops = append(stops, func() {
+			f, err := os.Create(p)
+			if err != nil {
+				fmt.Printf("⚠️  Warning: Could not write memory profile: %v\n", err)
+				return
+			}
+			defer f.Close()
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				fmt.Printf("⚠️  Warning: Could not write memory profile: %v\n", err)
+			}
+		})
+	}
+	return stop, nil
+}
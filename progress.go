@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// --- Progress ---
+//
+// Long checks show a line that rewrites itself: how far along they are and
+// roughly how long is left. It only appears on a terminal, and only once the
+// work has run for a moment, so quick runs and logs stay clean. Elapsed time
+// is measured on the real clock.
+
+const (
+	progressDelay    = 500 * time.Millisecond // quiet before the first line
+	progressInterval = 200 * time.Millisecond // between redraws
+)
+
+type progress struct {
+	mu    sync.Mutex
+	label string
+	total int
+	done  int
+	start time.Time
+	last  time.Time
+	shown bool
+	quiet bool
+}
+
+// newProgress starts counting total items of work described by label.
+func newProgress(label string, total int) *progress {
+	return &progress{label: label, total: total, start: time.Now(), quiet: !stdoutIsTerminal()}
+}
+
+// stdoutIsTerminal reports whether output goes to a terminal rather than a
+// file or pipe.
+func stdoutIsTerminal() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// add counts n more items done and redraws the line when it is due.
+func (p *progress) add(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	now := time.Now()
+	if p.quiet || now.Sub(p.start) < progressDelay || now.Sub(p.last) < progressInterval {
+		return
+	}
+	p.last, p.shown = now, true
+	fmt.Printf("\r\033[K%s", progressLine(p.label, p.done, p.total, now.Sub(p.start)))
+}
+
+// finish clears the line, if one was drawn.
+func (p *progress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.shown {
+		fmt.Print("\r\033[K")
+		p.shown = false
+	}
+}
+
+// progressLine is e.g. "🔎 Checking stored copies 1200/5000 (24%), about 38s left".
+func progressLine(label string, done, total int, elapsed time.Duration) string {
+	s := fmt.Sprintf("🔎 %s %d/%d", label, done, total)
+	if total > 0 {
+		s += fmt.Sprintf(" (%d%%)", done*100/total)
+	}
+	if done > 0 && done < total {
+		left := time.Duration(float64(elapsed) / float64(done) * float64(total-done))
+		s += ", about " + max(left, time.Second).Round(time.Second).String() + " left"
+	}
+	return s
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// --- Passphrase protection ---
+//
+// A project can require a passphrase before destructive commands run. Only a
+// salted PBKDF2 hash is stored (.gitnot/protect.json). The passphrase is read
+// from GITNOT_PASSPHRASE when set, otherwise prompted for on the terminal.
+
+const (
+	protectIterations = 200_000
+	passphraseEnv     = "GITNOT_PASSPHRASE"
+)
+
+type Protection struct {
+	Salt       string `json:"salt"`
+	Hash       string `json:"hash"`
+	Iterations int    `json:"iterations"`
+}
+
+func deriveKey(pass string, salt []byte, iter int) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, pass, salt, iter, 32)
+}
+
+func loadProtection() (*Protection, error) {
+	var p Protection
+	err := loadJSON(protectFile, &p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func setPassphrase(pass string) error {
+	if pass == "" {
+		return fmt.Errorf("passphrase must not be empty")
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := deriveKey(pass, salt, protectIterations)
+	if err != nil {
+		return err
+	}
+	return saveJSON(protectFile, Protection{
+		Salt: hex.EncodeToString(salt), Hash: hex.EncodeToString(key), Iterations: protectIterations,
+	})
+}
+
+func (p *Protection) matches(pass string) bool {
+	salt, err := hex.DecodeString(p.Salt)
+	if err != nil {
+		return false
+	}
+	want, err := hex.DecodeString(p.Hash)
+	if err != nil {
+		return false
+	}
+	got, err := deriveKey(pass, salt, p.Iterations)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// readPassphrase takes the passphrase from the environment or prompts for it
+// with terminal echo turned off where possible.
+func readPassphrase(prompt string) (string, error) {
+	if v, ok := os.LookupEnv(passphraseEnv); ok {
+		return v, nil
+	}
+	fmt.Print(prompt)
+	restore := disableEcho()
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	restore()
+	fmt.Println()
+	if err != nil && line == "" {
+		return "", fmt.Errorf("no passphrase given")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// requirePassphrase guards a destructive action. It is a no-op for projects
+// without protection.
+func requirePassphrase(action string) error {
+	p, err := loadProtection()
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return nil
+	}
+	pass, err := readPassphrase(fmt.Sprintf("🔐 Passphrase required to %s: ", action))
+	if err != nil {
+		return err
+	}
+	if !p.matches(pass) {
+		return fmt.Errorf("wrong passphrase; %s aborted", action)
+	}
+	return nil
+}
+
+// passphraseAvailable reports whether requirePassphrase can run without
+// prompting: the project is unprotected or GITNOT_PASSPHRASE is set.
+func passphraseAvailable() bool {
+	if _, ok := os.LookupEnv(passphraseEnv); ok {
+		return true
+	}
+	p, err := loadProtection()
+	return err == nil && p == nil
+}
+
+func runProtect(args []string) error {
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	sub := "status"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	switch sub {
+	case "status":
+		p, err := loadProtection()
+		if err != nil {
+			return err
+		}
+		if p != nil {
+			fmt.Println("🔐 Destructive commands require a passphrase")
+		} else {
+			fmt.Println("🔓 No passphrase protection")
+		}
+	case "set":
+		if err := requirePassphrase("change the passphrase"); err != nil {
+			return err
+		}
+		pass, err := readPassphrase("New passphrase: ")
+		if err != nil {
+			return err
+		}
+		if _, fromEnv := os.LookupEnv(passphraseEnv); !fromEnv {
+			again, err := readPassphrase("Repeat passphrase: ")
+			if err != nil {
+				return err
+			}
+			if again != pass {
+				return fmt.Errorf("passphrases do not match")
+			}
+		}
+		if err := setPassphrase(pass); err != nil {
+			return err
+		}
+		fmt.Println("🔐 Passphrase protection enabled")
+	case "clear":
+		if err := requirePassphrase("remove protection"); err != nil {
+			return err
+		}
+		if err := repo.FS.Remove(protectFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		fmt.Println("🔓 Passphrase protection removed")
+	default:
+		return fmt.Errorf("usage: gitnot protect set|clear|status")
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPassphraseProtection(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.txt", "one")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := requirePassphrase("test"); err != nil {
+		t.Errorf("Unprotected project should not ask: %v", err)
+	}
+
+	if err := setPassphrase("correct horse"); err != nil {
+		t.Fatalf("setPassphrase failed: %v", err)
+	}
+	t.Setenv(passphraseEnv, "wrong")
+	if err := requirePassphrase("test"); err == nil {
+		t.Error("Wrong passphrase should be rejected")
+	}
+	t.Setenv(passphraseEnv, "correct horse")
+	if err := requirePassphrase("test"); err != nil {
+		t.Errorf("Correct passphrase rejected: %v", err)
+	}
+}
+
+func TestDestructiveCommandsRequirePassphrase(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.txt", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "a.txt", "two\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if err := setPassphrase("correct horse"); err != nil {
+		t.Fatalf("setPassphrase failed: %v", err)
+	}
+	t.Setenv(passphraseEnv, "wrong")
+
+	if err := runRestore([]string{"a.txt", "--version", "0.0"}); err == nil {
+		t.Error("restore over a changed file should ask for the passphrase")
+	}
+	if b, _ := os.ReadFile("a.txt"); string(b) != "two\n" {
+		t.Errorf("Expected a.txt left alone, got %q", b)
+	}
+	os.Remove("a.txt")
+	if err := runRestore([]string{"a.txt", "--version", "0.0"}); err != nil {
+		t.Errorf("Restoring a missing file overwrites nothing and should not ask: %v", err)
+	}
+	createTestFile(t, "a.txt", "two\n")
+
+	if err := runUntrack([]string{"a.txt"}); err == nil {
+		t.Error("untrack should ask for the passphrase")
+	}
+	if pats := loadUntrackList(); len(pats) != 0 {
+		t.Errorf("Expected nothing untracked, got %v", pats)
+	}
+	if err := runRepack(nil); err == nil {
+		t.Error("repack should ask for the passphrase")
+	}
+
+	one := contentHash([]byte("one\n"))
+	os.WriteFile(objectPath(one), []byte("rot\n"), 0o644)
+	if _, err := scrubStore(scrubOptions{}); err == nil {
+		t.Error("Quarantining during scrub should ask for the passphrase")
+	}
+	if _, err := os.Stat(objectPath(one)); err != nil {
+		t.Errorf("The damaged object should stay in place: %v", err)
+	}
+
+	// watch can't ask, so its scrub only reports
+	os.Unsetenv(passphraseEnv)
+	backgroundScrub()
+	if _, err := os.Stat(objectPath(one)); err != nil {
+		t.Errorf("The background scrub should not quarantine: %v", err)
+	}
+	if _, err := os.Stat(scrubFile); err != nil {
+		t.Errorf("Expected the background check recorded: %v", err)
+	}
+
+	t.Setenv(passphraseEnv, "correct horse")
+	if res, err := scrubStore(scrubOptions{}); err != nil || len(res.Damaged) != 1 || !res.Damaged[0].Moved {
+		t.Errorf("Expected the scrub to quarantine with the passphrase, got %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// --- Publishing ---
+//
+// "publish" copies files that an update added or changed to another place,
+// such as a Hugo content folder, so recording a post also stages it: with
+// {"match": "blog/**", "to": "../site/content/posts", "strip": "blog"},
+// blog/2024/hello.md lands in ../site/content/posts/2024/hello.md. A "to"
+// like "me@host:www/posts" is handed to rsync instead. With "delete", files
+// deleted from the project are removed from a local target as well.
+
+// PublishTarget is one entry of the "publish" config list.
+type PublishTarget struct {
+	Match  string `json:"match"`
+	To     string `json:"to"`
+	Strip  string `json:"strip,omitempty"`  // leading folder left out at the target
+	Delete bool   `json:"delete,omitempty"` // mirror deletions (local targets only)
+}
+
+// remote reports whether To names an rsync destination (host:path). Windows
+// drive letters don't count.
+func (t PublishTarget) remote() bool {
+	host, _, ok := strings.Cut(t.To, ":")
+	return ok && len(host) > 1 && !strings.ContainsAny(host, `/\`)
+}
+
+// targetPath is where rel goes below To.
+func (t PublishTarget) targetPath(rel string) string {
+	p := filepath.ToSlash(rel)
+	if s := strings.Trim(filepath.ToSlash(t.Strip), "/"); s != "" {
+		p = strings.TrimPrefix(p, s+"/")
+	}
+	return p
+}
+
+// publishChanges splits the changes t applies to into files to copy and
+// files to remove.
+func (t PublishTarget) publishChanges(changes []FileChange) (copied, removed []string) {
+	for _, c := range changes {
+		if !matchesChange(c.Path, t.Match) {
+			continue
+		}
+		if c.State == stateDeleted {
+			removed = append(removed, c.Path)
+		} else {
+			copied = append(copied, c.Path)
+		}
+	}
+	return copied, removed
+}
+
+func publishLocal(t PublishTarget, copied, removed []string) error {
+	for _, rel := range copied {
+		dst := filepath.Join(t.To, filepath.FromSlash(t.targetPath(rel)))
+		if err := safeMkdirAllForFile(dst); err != nil {
+			return err
+		}
+		if err := copyFile(rel, dst); err != nil {
+			return err
+		}
+	}
+	if !t.Delete {
+		return nil
+	}
+	for _, rel := range removed {
+		dst := filepath.Join(t.To, filepath.FromSlash(t.targetPath(rel)))
+		if err := os.Remove(longPath(dst)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishRsync sends the files with rsync, keeping their paths below Strip.
+func publishRsync(t PublishTarget, copied []string) error {
+	src := "."
+	if s := strings.Trim(filepath.ToSlash(t.Strip), "/"); s != "" {
+		src = filepath.FromSlash(s)
+	}
+	var list bytes.Buffer
+	for _, rel := range copied {
+		list.WriteString(t.targetPath(rel))
+		list.WriteString("\n")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "rsync", "-a", "--files-from=-", src+string(filepath.Separator), t.To)
+	cmd.Stdin = &list
+	out, err := cmd.CombinedOutput()
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("rsync not found (install it, or publish to a local folder)")
+	}
+	if err != nil {
+		return fmt.Errorf("rsync failed: %v\n%s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// runPublish publishes the changes in m to every matching target and
+// reports how many failed.
+func runPublish(targets []PublishTarget, m Manifest) error {
+	failed := 0
+	for _, t := range targets {
+		copied, removed := t.publishChanges(m.Changes)
+		if len(copied) == 0 && (len(removed) == 0 || !t.Delete || t.remote()) {
+			continue
+		}
+		var err error
+		if tr, ok := t.publishTransport(); ok {
+			files := map[string]string{}
+			for _, rel := range copied {
+				files[rel] = t.targetPath(rel)
+			}
+			if len(files) > 0 {
+				err = tr.Publish(t.To, files)
+			}
+		} else if t.remote() {
+			if len(copied) > 0 {
+				err = publishRsync(t, copied)
+			}
+		} else {
+			err = publishLocal(t, copied, removed)
+		}
+		if err != nil {
+			fmt.Printf("⚠️  Warning: publishing to %s failed: %v\n", t.To, err)
+			failed++
+			continue
+		}
+		fmt.Printf("📰 Published %d files to %s\n", len(copied), t.To)
+		if t.Delete && !t.remote() && len(removed) > 0 {
+			fmt.Printf("📰 Removed %d deleted files from %s\n", len(removed), t.To)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("publishing to %d of %d targets failed", failed, len(targets))
+	}
+	return nil
+}
+
+func validatePublish(targets []PublishTarget) []configIssue {
+	var issues []configIssue
+	for i, t := range targets {
+		key := fmt.Sprintf("publish[%d]", i)
+		add := func(format string, args ...any) {
+			issues = append(issues, configIssue{key, fmt.Sprintf(format, args...)})
+		}
+		if t.Match == "" {
+			add("needs a match pattern")
+		} else if _, err := path.Match(t.Match, ""); err != nil {
+			add("%q is not a valid glob", t.Match)
+		}
+		if t.To == "" {
+			add("needs a target (\"to\")")
+		}
+		if t.Delete && t.remote() {
+			add("delete only works for local targets")
+		}
+		if scheme, _, ok := strings.Cut(t.To, "://"); ok {
+			if _, found := t.publishTransport(); !found {
+				add("no transport for %s:// (and no gitnot-%s on PATH)", scheme, scheme)
+			}
+		}
+	}
+	return issues
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPublishTarget(t *testing.T) {
+	for to, remote := range map[string]bool{
+		"me@host:www/posts":  true,
+		"host:posts":         true,
+		`C:\site\content`:    false,
+		"../site/content":    false,
+		"./odd:name/content": false,
+	} {
+		if got := (PublishTarget{To: to}).remote(); got != remote {
+			t.Errorf("remote(%q) = %v, want %v", to, got, remote)
+		}
+	}
+	pt := PublishTarget{Strip: "blog/"}
+	if got := pt.targetPath(filepath.Join("blog", "2024", "hello.md")); got != "2024/hello.md" {
+		t.Errorf("Expected the strip folder removed, got %q", got)
+	}
+	pt.Match = "blog/**"
+	copied, _ := pt.publishChanges([]FileChange{
+		{Path: filepath.Join("blog", "hello.md"), State: stateAdded},
+		{Path: filepath.Join("drafts", "blog", "idea.md"), State: stateAdded},
+	})
+	if len(copied) != 1 || copied[0] != filepath.Join("blog", "hello.md") {
+		t.Errorf("Expected only the post below blog/ at the root, got %v", copied)
+	}
+}
+
+func TestPublishAfterUpdate(t *testing.T) {
+	setupTestDir(t)
+	site := t.TempDir()
+
+	createTestFile(t, filepath.Join("blog", "hello.md"), "hi\n")
+	createTestFile(t, filepath.Join("blog", "bye.md"), "bye\n")
+	createTestFile(t, "notes.md", "private\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, configFile, `{"extensions": [".md"], "publish": [{"match": "blog/**", "to": "`+filepath.ToSlash(site)+`", "strip": "blog", "delete": true}]}`)
+
+	createTestFile(t, filepath.Join("blog", "hello.md"), "hello, world\n")
+	createTestFile(t, "notes.md", "still private\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(site, "hello.md")); err != nil || string(b) != "hello, world\n" {
+		t.Errorf("Changed post not published: %q, %v", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(site, "bye.md")); err == nil {
+		t.Error("Unchanged post should not be published")
+	}
+	if _, err := os.Stat(filepath.Join(site, "notes.md")); err == nil {
+		t.Error("File outside the pattern was published")
+	}
+
+	os.Remove(filepath.Join("blog", "hello.md"))
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(site, "hello.md")); err == nil {
+		t.Error("Deleted post should be removed from the target")
+	}
+}
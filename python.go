@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// --- Python gitnot import ---
+//
+// The original Python gitnot uses the same .gitnot/ layout with small
+// differences: hashes keyed by "./"-prefixed (or backslash) paths, versions
+// written as "v0.3" or with float noise ("0.30000000000000004"), and
+// changelog headers using plain hyphens. Conversion only rewrites those
+// spellings; every changelog line is kept.
+
+type pythonImport struct {
+	Version    bool
+	Hashes     int
+	Changelogs int
+	Config     bool
+}
+
+var (
+	pyEntryHeader  = regexp.MustCompile(`^##\s*v?(\d+(?:\.\d+)?)\s*(?:-|–|—)\s*(.*)$`)
+	pyOriginHeader = regexp.MustCompile(`^#\s*(.+?)\s*(?:-|–|—)\s*original\s*v?(\d+(?:\.\d+)?)\s*$`)
+)
+
+func normalizeStorePath(p string) string {
+	p = strings.ReplaceAll(p, `\`, "/")
+	for strings.HasPrefix(p, "./") {
+		p = p[2:]
+	}
+	return filepath.FromSlash(p)
+}
+
+func normalizeChangelogLine(line string) string {
+	if m := pyEntryHeader.FindStringSubmatch(line); m != nil {
+		v, _ := strconv.ParseFloat(m[1], 64)
+		return fmt.Sprintf("## v%.1f – %s", v, m[2])
+	}
+	if m := pyOriginHeader.FindStringSubmatch(line); m != nil {
+		v, _ := strconv.ParseFloat(m[2], 64)
+		return fmt.Sprintf("# %s — original v%.1f", filepath.ToSlash(normalizeStorePath(m[1])), v)
+	}
+	return line
+}
+
+// isPythonStore reports whether the store still has the Python gitnot's
+// spelling of its version or of its hash keys.
+func isPythonStore() bool {
+	if b, err := repo.FS.ReadFile(versionFile); err == nil {
+		raw := strings.TrimSpace(string(b))
+		if v, err := strconv.ParseFloat(strings.TrimPrefix(raw, "v"), 64); err == nil && fmt.Sprintf("%.1f", v) != raw {
+			return true
+		}
+	}
+	hashes := map[string]string{}
+	_ = loadJSON(hashesFile, &hashes)
+	for k := range hashes {
+		if normalizeStorePath(k) != k {
+			return true
+		}
+	}
+	return false
+}
+
+// convertPythonStore rewrites a Python-created store in place. It is
+// idempotent, so it is also safe to run on stores this tool created.
+func convertPythonStore() (pythonImport, error) {
+	var rep pythonImport
+
+	if b, err := repo.FS.ReadFile(versionFile); err == nil {
+		raw := strings.TrimSpace(string(b))
+		if v, err := strconv.ParseFloat(strings.TrimPrefix(raw, "v"), 64); err == nil {
+			if canonical := fmt.Sprintf("%.1f", v); canonical != raw {
+				if err := writeVersion(float64(int(v*10+0.5)) / 10.0); err != nil {
+					return rep, err
+				}
+				rep.Version = true
+			}
+		}
+	}
+
+	hashes := map[string]string{}
+	if err := loadJSON(hashesFile, &hashes); err == nil {
+		fixed := map[string]string{}
+		for k, h := range hashes {
+			nk := normalizeStorePath(k)
+			if nk != k {
+				rep.Hashes++
+			}
+			fixed[nk] = h
+		}
+		if rep.Hashes > 0 {
+			if err := saveJSON(hashesFile, fixed); err != nil {
+				return rep, err
+			}
+		}
+	}
+
+	err := walkStore(changelogDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, ".log") {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(string(b), "\n")
+		changed := false
+		for i, l := range lines {
+			if nl := normalizeChangelogLine(l); nl != l {
+				lines[i] = nl
+				changed = true
+			}
+		}
+		if changed {
+			rep.Changelogs++
+			return writeFileAtomic(p, []byte(strings.Join(lines, "\n")), 0o644)
+		}
+		return nil
+	})
+	if err != nil {
+		return rep, err
+	}
+
+	if _, err := repo.FS.Stat(configFile); os.IsNotExist(err) {
+		if err := saveJSON(configFile, defaultConfig); err != nil {
+			return rep, err
+		}
+		rep.Config = true
+	}
+	return rep, nil
+}
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fromPython := fs.Bool("from-python", false, "convert a store created by the Python gitnot")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if !*fromPython {
+		if err := migrateStore(); err != nil {
+			return err
+		}
+		info, _ := readStoreInfo()
+		fmt.Printf("✅ Store is at format %d\n", info.FormatVersion)
+		return nil
+	}
+	if err := requirePassphrase("rewrite the store"); err != nil {
+		return err
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	if _, err := backupMetadata("from-python"); err != nil {
+		release()
+		return fmt.Errorf("backing up before import: %w", err)
+	}
+	rep, err := convertPythonStore()
+	release()
+	if err != nil {
+		return err
+	}
+	fmt.Println("🐍 Imported Python gitnot store")
+	if rep.Version {
+		fmt.Println("  • version normalized")
+	}
+	fmt.Printf("  • %d hash entries renamed\n", rep.Hashes)
+	fmt.Printf("  • %d changelogs rewritten\n", rep.Changelogs)
+	if rep.Config {
+		fmt.Println("  • default config.json written")
+	}
+	return migrateStore()
+}
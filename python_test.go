@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertPythonStore(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "notes/a.md", "hello\n")
+	createTestFile(t, versionFile, "v0.30000000000000004\n")
+	createTestFile(t, hashesFile, `{"./notes/a.md": "abc"}`)
+	createTestFile(t, filepath.Join(changelogDir, "notes/a.md.log"),
+		"# ./notes/a.md - original v0.0\n\n## v0.1 - 2024-05-01 10:00\n📄 New file added.\n")
+
+	rep, err := convertPythonStore()
+	if err != nil {
+		t.Fatalf("convertPythonStore failed: %v", err)
+	}
+	if !rep.Version || rep.Hashes != 1 || rep.Changelogs != 1 || !rep.Config {
+		t.Errorf("Unexpected report: %+v", rep)
+	}
+	if v, _ := readVersion(); v != 0.3 {
+		t.Errorf("Expected v0.3, got v%.1f", v)
+	}
+	var hashes map[string]string
+	loadJSON(hashesFile, &hashes)
+	if hashes[filepath.Join("notes", "a.md")] != "abc" {
+		t.Errorf("Hash keys not normalized: %v", hashes)
+	}
+	b, _ := os.ReadFile(filepath.Join(changelogDir, "notes/a.md.log"))
+	want := "# notes/a.md — original v0.0\n\n## v0.1 – 2024-05-01 10:00\n📄 New file added.\n"
+	if string(b) != want {
+		t.Errorf("Changelog not converted:\n%s", b)
+	}
+
+	// Second run is a no-op
+	rep, _ = convertPythonStore()
+	if rep.Version || rep.Hashes != 0 || rep.Changelogs != 0 {
+		t.Errorf("Conversion should be idempotent, got %+v", rep)
+	}
+	if !strings.HasPrefix(normalizeChangelogLine("### ➕ Added"), "###") {
+		t.Error("Non-header lines must be untouched")
+	}
+}
+
+func TestPythonStoreNeedsExplicitMigrate(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "notes/a.md", "hello\n")
+	createTestFile(t, versionFile, "v0.3\n")
+	createTestFile(t, hashesFile, `{"./notes/a.md": "`+contentHash([]byte("hello\n"))+`"}`)
+
+	if err := migrateStore(); err == nil || !strings.Contains(err.Error(), "--from-python") {
+		t.Fatalf("Expected the automatic migration to refuse a Python store, got %v", err)
+	}
+	if b, _ := os.ReadFile(versionFile); string(b) != "v0.3\n" {
+		t.Errorf("Expected the store to be left alone, got version %q", b)
+	}
+
+	if err := setPassphrase("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(passphraseEnv, "wrong")
+	if err := runMigrate([]string{"--from-python"}); err == nil {
+		t.Fatal("Expected a wrong passphrase to be refused")
+	}
+	t.Setenv(passphraseEnv, "correct horse")
+	if err := runMigrate([]string{"--from-python"}); err != nil {
+		t.Fatalf("migrate --from-python failed: %v", err)
+	}
+	if names, _ := listBackups(); len(names) == 0 || !strings.Contains(names[0], "from-python") {
+		t.Errorf("Expected a backup before the conversion, got %v", names)
+	}
+	if info, _ := readStoreInfo(); info.FormatVersion != currentFormat {
+		t.Errorf("Expected the store upgraded to format %d, got %d", currentFormat, info.FormatVersion)
+	}
+	if m, err := loadManifest(0.3); err != nil || m.Files[filepath.Join("notes", "a.md")] == "" {
+		t.Errorf("Expected a manifest with normalized paths, got %+v (%v)", m, err)
+	}
+}
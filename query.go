@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// --- query ---
+//
+// `gitnot query` prints recorded file changes as JSON, one object per file
+// per version, for reports that shouldn't have to parse changelogs. Filters
+// come as flags or as a small query string:
+//
+//	gitnot query 'files changed since v1.0 in chapters/* where words_added > 100'
+//	gitnot query --since 1.0 --where 'words_added > 100' --state modified
+//
+// A condition compares any output field (words_added, state, path, author…)
+// with =, !=, <, <=, >, >= or ~ (glob match), and conditions are joined
+// with "and".
+
+// queryRow is one file change in one version.
+type queryRow struct {
+	Version float64   `json:"version"`
+	ID      string    `json:"id,omitempty"`
+	Time    time.Time `json:"time"`
+	Author  string    `json:"author,omitempty"`
+	Message string    `json:"message,omitempty"`
+	FileChange
+}
+
+type queryCond struct {
+	Field, Op, Value string
+}
+
+type queryFilter struct {
+	Since, Until *float64 // exclusive, inclusive
+	Path         string   // glob
+	Author       string
+	Conds        []queryCond
+}
+
+var queryOps = []string{">=", "<=", "!=", "=", ">", "<", "~"}
+
+// queryTokens splits a query into words, quoted strings and operators.
+func queryTokens(s string) ([]string, error) {
+	var out []string
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		switch r := rs[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			j := i + 1
+			for j < len(rs) && rs[j] != r {
+				j++
+			}
+			if j == len(rs) {
+				return nil, fmt.Errorf("unterminated quote in query")
+			}
+			out = append(out, string(rs[i+1:j]))
+			i = j + 1
+		case strings.ContainsRune("<>=!~", r):
+			op := string(r)
+			if i+1 < len(rs) && rs[i+1] == '=' {
+				op += "="
+			}
+			out = append(out, op)
+			i += len(op)
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && !strings.ContainsRune("<>=!~\"'", rs[j]) {
+				j++
+			}
+			out = append(out, string(rs[i:j]))
+			i = j
+		}
+	}
+	return out, nil
+}
+
+func isQueryOp(s string) bool {
+	for _, op := range queryOps {
+		if s == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQuery reads "[files changed] [since v] [until v] [in glob] [by name]
+// [where cond and cond…]" into f.
+func parseQuery(s string, f *queryFilter) error {
+	toks, err := queryTokens(s)
+	if err != nil {
+		return err
+	}
+	next := func(i int, what string) (string, error) {
+		if i+1 >= len(toks) {
+			return "", fmt.Errorf("query: %q needs %s", toks[i], what)
+		}
+		return toks[i+1], nil
+	}
+	for i := 0; i < len(toks); i++ {
+		switch kw := strings.ToLower(toks[i]); kw {
+		case "files", "changed", "changes":
+		case "since", "after", "until", "before":
+			arg, err := next(i, "a version")
+			if err != nil {
+				return err
+			}
+			v, err := parseVersionArg(arg)
+			if err != nil {
+				return err
+			}
+			if kw == "since" || kw == "after" {
+				f.Since = &v
+			} else {
+				f.Until = &v
+			}
+			i++
+		case "in", "by":
+			arg, err := next(i, "a value")
+			if err != nil {
+				return err
+			}
+			if kw == "in" {
+				if strings.ContainsAny(arg, "*?") {
+					if err := checkGlob(arg); err != nil {
+						return fmt.Errorf("query: %w", err)
+					}
+				}
+				f.Path = arg
+			} else {
+				f.Author = arg
+			}
+			i++
+		case "where", "and":
+			if i+3 >= len(toks) || !isQueryOp(toks[i+2]) {
+				return fmt.Errorf("query: expected \"field op value\" after %q", toks[i])
+			}
+			f.Conds = append(f.Conds, queryCond{toks[i+1], toks[i+2], toks[i+3]})
+			i += 3
+		default:
+			return fmt.Errorf("query: unexpected %q (expected since, until, in, by or where)", toks[i])
+		}
+	}
+	return nil
+}
+
+// fields returns a row as its JSON fields, which conditions refer to.
+func (r queryRow) fields() map[string]any {
+	b, _ := json.Marshal(r)
+	var m map[string]any
+	_ = json.Unmarshal(b, &m)
+	return m
+}
+
+// match evaluates c against a row's fields.
+func (c queryCond) match(fields map[string]any) (bool, error) {
+	v, ok := fields[c.Field]
+	if !ok { // e.g. issues on a version that wasn't checked
+		return false, nil
+	}
+	if n, isNum := v.(float64); isNum {
+		want, err := strconv.ParseFloat(strings.TrimPrefix(c.Value, "v"), 64)
+		if err != nil {
+			return false, fmt.Errorf("query: %s is a number, not %q", c.Field, c.Value)
+		}
+		switch c.Op {
+		case "=":
+			return n == want, nil
+		case "!=":
+			return n != want, nil
+		case "<":
+			return n < want, nil
+		case "<=":
+			return n <= want, nil
+		case ">":
+			return n > want, nil
+		case ">=":
+			return n >= want, nil
+		}
+		return false, fmt.Errorf("query: %s can't be used with a number", c.Op)
+	}
+	s := fmt.Sprint(v)
+	switch c.Op {
+	case "=":
+		return s == c.Value, nil
+	case "!=":
+		return s != c.Value, nil
+	case "~":
+		if strings.ContainsAny(c.Value, "*?") {
+			if err := checkGlob(c.Value); err != nil {
+				return false, fmt.Errorf("query: %w", err)
+			}
+		}
+		return matchesAny(s, []string{c.Value}), nil
+	}
+	return false, fmt.Errorf("query: %s can't be used with text (use =, != or ~)", c.Op)
+}
+
+// queryFields lists the fields conditions can use.
+func queryFields() map[string]any {
+	issues := 0
+	return queryRow{FileChange: FileChange{Issues: &issues}}.fields()
+}
+
+// runQueryFilter returns the rows of manifests that pass f.
+func runQueryFilter(manifests []Manifest, f queryFilter) ([]queryRow, error) {
+	known := queryFields()
+	for _, c := range f.Conds {
+		if _, ok := known[c.Field]; !ok {
+			return nil, fmt.Errorf("query: unknown field %q (try one of %s)", c.Field, strings.Join(sortedKeys(known), ", "))
+		}
+	}
+	rows := []queryRow{}
+	for _, m := range manifests {
+		if f.Since != nil && m.Version <= *f.Since || f.Until != nil && m.Version > *f.Until {
+			continue
+		}
+		author := ""
+		if m.Author != nil {
+			author = m.Author.Name
+		}
+		if f.Author != "" && !strings.EqualFold(author, f.Author) {
+			continue
+		}
+	changes:
+		for _, c := range m.Changes {
+			if f.Path != "" && !matchesAny(c.Path, []string{f.Path}) {
+				continue
+			}
+			row := queryRow{Version: m.Version, ID: m.ID, Time: m.Timestamp, Author: author, Message: m.Message, FileChange: c}
+			row.Path = strings.ReplaceAll(row.Path, "\\", "/")
+			fields := row.fields()
+			for _, cond := range f.Conds {
+				ok, err := cond.match(fields)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					continue changes
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// listFlag collects a repeatable string flag.
+type listFlag []string
+
+func (l *listFlag) String() string     { return strings.Join(*l, ", ") }
+func (l *listFlag) Set(s string) error { *l = append(*l, s); return nil }
+
+func runQuery(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	since := fs.String("since", "", "only versions after this one")
+	until := fs.String("until", "", "only versions up to this one")
+	pathGlob := fs.String("path", "", "only files matching this pattern")
+	state := fs.String("state", "", "only added, modified, deleted, metadata or untracked changes")
+	author := fs.String("author", "", "only versions by this author")
+	var where listFlag
+	fs.Var(&where, "where", "condition such as 'words_added > 100' (repeatable)")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	var f queryFilter
+	if err := parseQuery(strings.Join(rest, " "), &f); err != nil {
+		return err
+	}
+	for _, w := range where {
+		if err := parseQuery("where "+w, &f); err != nil {
+			return err
+		}
+	}
+	if *since != "" {
+		v, err := parseVersionArg(*since)
+		if err != nil {
+			return err
+		}
+		f.Since = &v
+	}
+	if *until != "" {
+		v, err := parseVersionArg(*until)
+		if err != nil {
+			return err
+		}
+		f.Until = &v
+	}
+	if *pathGlob != "" {
+		f.Path = *pathGlob
+	}
+	if *author != "" {
+		f.Author = *author
+	}
+	if *state != "" {
+		f.Conds = append(f.Conds, queryCond{"state", "=", *state})
+	}
+	manifests, err := loadManifestHeads()
+	if err != nil {
+		return err
+	}
+	rows, err := runQueryFilter(manifests, f)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(recordsOutput())
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
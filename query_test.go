@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "ch1.md", "one two\n")
+	createTestFile(t, "notes.md", "n\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "ch1.md", "one two "+strings.Repeat("word ", 150)+"\n")
+	createTestFile(t, "notes.md", "n m\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	var f queryFilter
+	if err := parseQuery("files changed since v0.0 where words_added > 100", &f); err != nil {
+		t.Fatalf("parseQuery failed: %v", err)
+	}
+	if f.Since == nil || *f.Since != 0 || len(f.Conds) != 1 || f.Conds[0] != (queryCond{"words_added", ">", "100"}) {
+		t.Fatalf("Unexpected filter: %+v", f)
+	}
+	manifests, _ := loadManifests()
+	rows, err := runQueryFilter(manifests, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Path != "ch1.md" || rows[0].Version != 0.1 || rows[0].WordsAdded < 150 {
+		t.Errorf("Expected only ch1.md at v0.1, got %+v", rows)
+	}
+
+	f = queryFilter{}
+	if err := parseQuery(`in '*.md' where state="added" and path~"n*"`, &f); err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ := runQueryFilter(manifests, f); len(rows) != 1 || rows[0].Path != "notes.md" || rows[0].Version != 0 {
+		t.Errorf("Expected notes.md added at v0.0, got %+v", rows)
+	}
+
+	for _, bad := range []string{"where nope > 1", "where words_added > many", "where path > 3", "since", `where path~"*["`, "in [*"} {
+		f = queryFilter{}
+		err := parseQuery(bad, &f)
+		if err == nil {
+			_, err = runQueryFilter(manifests, f)
+		}
+		if err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestQueryOutputIsJSONDespiteConfigWarnings(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "ch1.md", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	b, _ := os.ReadFile(configFile)
+	createTestFile(t, configFile, strings.Replace(string(b), "{", `{"bogus": true,`, 1))
+	configWarned = map[string]bool{}
+
+	var code int
+	out := captureStdout(t, func() { code = run([]string{"query"}) })
+	if code != 0 {
+		t.Fatalf("query exited with %d", code)
+	}
+	var rows []queryRow
+	if err := json.Unmarshal(out, &rows); err != nil {
+		t.Fatalf("query output is not JSON: %v\n%s", err, out)
+	}
+	if len(rows) != 1 || rows[0].Path != "ch1.md" {
+		t.Errorf("Expected ch1.md added at v0.0, got %+v", rows)
+	}
+}
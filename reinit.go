@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// --- Re-initializing ---
+//
+// `gitnot --init` in a folder that already has a store used to re-snapshot
+// everything and reset the version to 0.0, throwing the history away. It now
+// refuses and explains the two ways forward: --rescan records a version that
+// adopts the files the current settings newly track, leaving history alone,
+// and --force starts over at v0.0 after a metadata backup. Starting over keeps
+// the settings (config, ignore and tracking lists, pins, labels, signing keys,
+// the passphrase) and the backups, and drops every recorded version.
+
+type initOptions struct {
+	Force  bool // start over, dropping history
+	Rescan bool // adopt newly tracked files without touching history
+}
+
+// historyPaths lists what starting over clears.
+func historyPaths() []string {
+	return []string{snapshotDir, snapshotOldDir, snapshotTmpDir, changelogDir, deletedDir, manifestDir, objectsDir,
+		hashesFile, versionFile, indexFile, dirsFile, historyFile, tagsFile, remapsFile, adoptedFile, scrubFile, gcLogFile, journalFile,
+		untrackedLogDir, tasksDir}
+}
+
+func initGitnotWith(opts initOptions) error {
+	if _, err := repo.FS.Stat(versionFile); errors.Is(err, os.ErrNotExist) {
+		return createStore()
+	}
+	v, err := readVersion()
+	if err != nil {
+		return err
+	}
+	switch {
+	case opts.Rescan:
+		fmt.Printf("🔎 Already initialized at v%.1f; recording newly tracked files\n", v)
+		return updateGitnotWith(updateOptions{Message: "Adopted newly tracked files (--init --rescan)"})
+	case opts.Force:
+		return startOver(v)
+	}
+	return fmt.Errorf("gitnot is already initialized here (v%.1f). Run 'gitnot' to record changes, "+
+		"'gitnot --init --rescan' to pick up files your settings now track, or 'gitnot --init --force' "+
+		"to start over at v0.0 (every recorded version is dropped)", v)
+}
+
+// startOver clears the history of the store at version v and initializes
+// it again.
+func startOver(v float64) error {
+	if err := requirePassphrase("start over and drop the history"); err != nil {
+		return err
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	saved, err := backupMetadata("reinit")
+	if err != nil {
+		release()
+		return fmt.Errorf("backing up metadata: %w", err)
+	}
+	for _, p := range historyPaths() {
+		if err := os.RemoveAll(p); err != nil {
+			release()
+			return err
+		}
+	}
+	release()
+	fmt.Printf("🗑  Dropped the history up to v%.1f (metadata backup: %s)\n", v, saved)
+	return createStore()
+}
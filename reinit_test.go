@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReinitRefusesWithoutFlag(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "chapter.md", "One\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "chapter.md", "Two\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	err := initGitnot()
+	if err == nil || !strings.Contains(err.Error(), "already initialized") {
+		t.Fatalf("Expected init to refuse, got %v", err)
+	}
+	if v, _ := readVersion(); v != 0.1 {
+		t.Errorf("The history should be untouched, at v%.1f", v)
+	}
+}
+
+func TestReinitRescanAdoptsNewFiles(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "chapter.md", "One\n")
+	createTestFile(t, "notes.org", "* Notes\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.Extensions = append(cfg.Extensions, ".org")
+	saveJSON(configFile, cfg)
+	if err := initGitnotWith(initOptions{Rescan: true}); err != nil {
+		t.Fatalf("rescan failed: %v", err)
+	}
+	m, err := loadManifest(0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Files["notes.org"]; !ok || len(m.Changes) != 1 {
+		t.Errorf("Expected notes.org adopted in v0.1, got %+v", m.Changes)
+	}
+	if _, err := loadManifest(0.0); err != nil {
+		t.Errorf("v0.0 should still be there: %v", err)
+	}
+}
+
+func TestReinitForceStartsOver(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "chapter.md", "One\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := runPin([]string{"chapter.md"}); err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, "other.md", "Other\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if err := initGitnotWith(initOptions{Force: true}); err != nil {
+		t.Fatalf("init --force failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.0 {
+		t.Errorf("Expected v0.0, got v%.1f", v)
+	}
+	if _, err := loadManifest(0.1); err == nil {
+		t.Error("Old versions should be dropped")
+	}
+	if len(loadPins()) != 1 {
+		t.Error("Settings such as pins should be kept")
+	}
+	backups, _ := filepath.Glob(filepath.Join(backupDir, "*-reinit.tar.gz"))
+	if len(backups) != 1 {
+		t.Errorf("Expected a metadata backup, got %v", backups)
+	}
+	log, _ := os.ReadFile(filepath.Join(changelogDir, "other.md.log"))
+	if strings.Count(string(log), "## v") != 0 {
+		t.Errorf("Changelogs should start over, got:\n%s", log)
+	}
+}
+
+func TestPreparedConfigIsNotRecoveredOrMigrated(t *testing.T) {
+	for args, want := range map[string]bool{"--init": true, "-init --rescan": true, "--init=false": false, "status --init": false, "": false} {
+		if got := initializes(strings.Fields(args)); got != want {
+			t.Errorf("initializes(%q) = %v, want %v", args, got, want)
+		}
+	}
+
+	setupTestDir(t)
+	createTestFile(t, "chapter.md", "One\n")
+	createTestFile(t, filepath.Join(defaultStoreDir, "config.json"), `{"extensions": [".md"]}`)
+	out := string(captureStdout(t, func() { run([]string{"status"}) }))
+	if strings.Contains(out, "🩹") || strings.Contains(out, "Upgraded store") {
+		t.Errorf("Expected a store without versions left alone, got:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(defaultStoreDir, "store.json")); err == nil {
+		t.Error("Expected no format upgrade to be written")
+	}
+}
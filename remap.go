@@ -0,0 +1,324 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- Remapping ---
+//
+// Rename detection only pairs files whose content did not change, so a big
+// reshuffle that also edits files reads as a pile of deletions and new
+// files. `gitnot remap drafts/ book/chapters/`, run after moving the files
+// and before recording them, tells gitnot what happened instead: it moves the
+// tracked paths under the old prefix to the new one in hashes.json, the
+// snapshot, the changelogs and labels, and logs the mapping in
+// .gitnot/remaps.json. The next update then records the files as the same
+// files at their new paths (diffed against their old content), notes the
+// move in each changelog and keeps the mapping in the version's manifest.
+//
+// A plain folder rename needs no remap: when every tracked file under a
+// folder disappears and reappears unchanged under another folder that
+// tracked nothing before, the update records one rename instead of a
+// deletion and an addition per file. It moves the changelogs under the
+// journal, so a rolled-back update leaves them where they were.
+
+// Remap is one prefix move.
+type Remap struct {
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+	Base  float64   `json:"base"` // the version it was applied on top of
+	Files int       `json:"files"`
+	At    time.Time `json:"at"`
+}
+
+func loadRemaps() []Remap {
+	var remaps []Remap
+	_ = loadJSON(remapsFile, &remaps)
+	return remaps
+}
+
+// pendingRemaps returns the remaps made since version v was recorded, in
+// the order they were made.
+func pendingRemaps(v float64) []Remap {
+	var out []Remap
+	for _, r := range loadRemaps() {
+		if r.Base == v {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// remapPath moves rel from one prefix to another; ok is false when rel is
+// not under from. Prefixes only match whole path components.
+func remapPath(rel, from, to string) (string, bool) {
+	if rel == from {
+		return to, true
+	}
+	if rest, ok := strings.CutPrefix(rel, from+string(filepath.Separator)); ok {
+		return filepath.Join(to, rest), true
+	}
+	return rel, false
+}
+
+// applyRemaps returns the path rel ends up at after remaps.
+func applyRemaps(rel string, remaps []Remap) string {
+	for _, r := range remaps {
+		rel, _ = remapPath(rel, r.From, r.To)
+	}
+	return rel
+}
+
+// remapKeys returns m with its keys moved by remaps.
+func remapKeys[V any](m map[string]V, remaps []Remap) map[string]V {
+	if len(remaps) == 0 {
+		return m
+	}
+	out := make(map[string]V, len(m))
+	for k, v := range m {
+		out[applyRemaps(k, remaps)] = v
+	}
+	return out
+}
+
+// movedFiles pairs the paths of old that remaps moved with their new paths.
+func movedFiles(old map[string]string, remaps []Remap) map[string]string {
+	moved := map[string]string{}
+	for rel := range old {
+		if to := applyRemaps(rel, remaps); to != rel {
+			moved[to] = rel
+		}
+	}
+	return moved
+}
+
+// recordRemaps notes each move in the changelog of the file that moved, for
+// the update recording version ver.
+func recordRemaps(moved map[string]string, current map[string]string, ver float64, ts string) {
+	for _, to := range sortedKeys(moved) {
+		if _, ok := current[to]; !ok {
+			continue
+		}
+		clPath := filepath.Join(changelogDir, to+".log")
+		_ = safeMkdirAllForFile(clPath)
+		_ = appendToFile(clPath, fmt.Sprintf("\n## v%.1f – %s\n🔀 Moved from %s\n", ver, ts, filepath.ToSlash(moved[to])))
+	}
+}
+
+// moveStoreFile renames a snapshot file or changelog, if there is one.
+func moveStoreFile(from, to string) error {
+	if _, err := os.Stat(from); err != nil {
+		return nil
+	}
+	if err := safeMkdirAllForFile(to); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
+
+// applyRemap moves the tracked paths r covers in hashes, the snapshot, the
+// changelogs and labels, saves hashes.json and logs r as pending for the next
+// update. It returns the remapped hashes.
+func applyRemap(r *Remap, hashes map[string]string) (map[string]string, error) {
+	moved := movedFiles(hashes, []Remap{*r})
+	r.Files = len(moved)
+	for _, newRel := range sortedKeys(moved) {
+		oldRel := moved[newRel]
+		if err := moveStoreFile(filepath.Join(snapshotDir, oldRel), filepath.Join(snapshotDir, newRel)); err != nil {
+			return nil, err
+		}
+		if err := moveStoreFile(filepath.Join(changelogDir, oldRel+".log"), filepath.Join(changelogDir, newRel+".log")); err != nil {
+			return nil, err
+		}
+		removeEmptyParents(filepath.Join(snapshotDir, oldRel), snapshotDir)
+		removeEmptyParents(filepath.Join(changelogDir, oldRel+".log"), changelogDir)
+	}
+	hashes = remapKeys(hashes, []Remap{*r})
+	if err := saveJSON(hashesFile, hashes); err != nil {
+		return nil, err
+	}
+	if err := saveIndex(hashes); err != nil {
+		return nil, err
+	}
+	if labels := loadLabels(); len(labels) > 0 {
+		if err := saveLabels(remapKeys(labels, []Remap{*r})); err != nil {
+			return nil, err
+		}
+	}
+	return hashes, saveJSON(remapsFile, append(loadRemaps(), *r))
+}
+
+// dropMoved removes the files that moved from the added and deleted lists.
+func dropMoved(newFiles, deletedFiles []string, moved map[string]string) (added, deleted []string) {
+	from := map[string]bool{}
+	for _, old := range moved {
+		from[old] = true
+	}
+	for _, n := range newFiles {
+		if _, ok := moved[n]; !ok {
+			added = append(added, n)
+		}
+	}
+	for _, d := range deletedFiles {
+		if !from[d] {
+			deleted = append(deleted, d)
+		}
+	}
+	return added, deleted
+}
+
+// detectDirRenames finds folders whose tracked files all disappeared and
+// reappeared, unchanged and under the same names, in a folder that tracked
+// nothing before. Each is returned as a remap, outermost folders first.
+func detectDirRenames(newFiles, deletedFiles []string, current, old map[string]string) []Remap {
+	if len(newFiles) == 0 || len(deletedFiles) == 0 {
+		return nil
+	}
+	added, gone := map[string]bool{}, map[string]bool{}
+	for _, n := range newFiles {
+		added[n] = true
+	}
+	dirs := map[string]bool{}
+	for _, d := range deletedFiles {
+		gone[d] = true
+		for p := filepath.Dir(d); p != "."; p = filepath.Dir(p) {
+			dirs[p] = true
+		}
+	}
+	candidates := sortedKeys(dirs)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return strings.Count(candidates[i], string(filepath.Separator)) < strings.Count(candidates[j], string(filepath.Separator))
+	})
+	under := func(dir string) []string {
+		var out []string
+		for rel := range old {
+			if strings.HasPrefix(rel, dir+string(filepath.Separator)) {
+				out = append(out, rel)
+			}
+		}
+		sort.Strings(out)
+		return out
+	}
+	var out []Remap
+	claimed := map[string]bool{}
+	covered := func(dir string) bool {
+		for _, r := range out {
+			if _, ok := remapPath(dir, r.From, r.To); ok {
+				return true
+			}
+		}
+		return false
+	}
+	for _, dir := range candidates {
+		if covered(dir) {
+			continue
+		}
+		files := under(dir)
+		allGone := true
+		for _, f := range files {
+			allGone = allGone && gone[f]
+		}
+		if !allGone {
+			continue
+		}
+		fits := func(to string) bool {
+			if to == dir || len(under(to)) > 0 {
+				return false
+			}
+			for _, f := range files {
+				n, _ := remapPath(f, dir, to)
+				if !added[n] || claimed[n] || current[n] != old[f] {
+					return false
+				}
+			}
+			return true
+		}
+		suffix := string(filepath.Separator) + strings.TrimPrefix(files[0], dir+string(filepath.Separator))
+		for _, n := range newFiles {
+			if to, ok := strings.CutSuffix(n, suffix); ok && current[n] == old[files[0]] && fits(to) {
+				r := Remap{From: dir, To: to, Files: len(files)}
+				for _, f := range files {
+					moved, _ := remapPath(f, dir, to)
+					claimed[moved] = true
+				}
+				out = append(out, r)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func runRemap(args []string) error {
+	fs := flag.NewFlagSet("remap", flag.ExitOnError)
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: gitnot remap <old/prefix> <new/prefix>")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if err := recoverInterrupted(); err != nil {
+		return fmt.Errorf("recovering interrupted run: %w", err)
+	}
+	from := filepath.Clean(filepath.FromSlash(rest[0]))
+	to := filepath.Clean(filepath.FromSlash(rest[1]))
+	if from == to || from == "." || to == "." || filepath.IsAbs(from) || filepath.IsAbs(to) ||
+		within(from, "..") || within(to, "..") {
+		return fmt.Errorf("remap needs two different paths inside the project")
+	}
+	if isUnderGitnot(from) || isUnderGitnot(to) {
+		return fmt.Errorf("remap can't move gitnot's own files")
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+	v, err := readVersion()
+	if err != nil {
+		return err
+	}
+
+	var hashes map[string]string
+	if err := loadJSON(hashesFile, &hashes); err != nil {
+		return err
+	}
+	r := Remap{From: from, To: to, Base: v, At: repo.Now()}
+	moved := movedFiles(hashes, []Remap{r})
+	if len(moved) == 0 {
+		return fmt.Errorf("no tracked files under %s", rest[0])
+	}
+	for newRel := range moved {
+		if _, taken := hashes[newRel]; taken && applyRemaps(newRel, []Remap{r}) == newRel {
+			return fmt.Errorf("%s is already tracked; remap would overwrite it", filepath.ToSlash(newRel))
+		}
+	}
+	if _, err := backupMetadata("remap"); err != nil {
+		return fmt.Errorf("backing up metadata: %w", err)
+	}
+	if _, err := applyRemap(&r, hashes); err != nil {
+		return err
+	}
+
+	fmt.Printf("🔀 Remapped %d files from %s to %s; the next gitnot records the move\n", r.Files, filepath.ToSlash(from), filepath.ToSlash(to))
+	var missing []string
+	for _, newRel := range sortedKeys(moved) {
+		if _, err := os.Stat(longPath(newRel)); err != nil {
+			missing = append(missing, filepath.ToSlash(newRel))
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Printf("⚠️  %d of them are not at their new path yet: %s\n", len(missing), strings.Join(preview(missing, 3), ", "))
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemapCarriesHistory(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, filepath.Join("drafts", "one.md"), "First line\n")
+	createTestFile(t, filepath.Join("drafts", "two.md"), "Second\n")
+	createTestFile(t, filepath.Join("draftsmore", "three.md"), "Third\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := runLabel([]string{filepath.Join("drafts", "one.md"), "draft"}); err != nil {
+		t.Fatalf("label failed: %v", err)
+	}
+
+	// move the folder and edit a file on the way
+	if err := os.MkdirAll("book", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename("drafts", filepath.Join("book", "chapters")); err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, filepath.Join("book", "chapters", "one.md"), "First line\nAnd more\n")
+
+	if err := runRemap([]string{"nothing", "else"}); err == nil {
+		t.Error("Expected an error remapping a prefix with no tracked files")
+	}
+	if err := runRemap([]string{"drafts", "book/chapters"}); err != nil {
+		t.Fatalf("remap failed: %v", err)
+	}
+	newOne := filepath.Join("book", "chapters", "one.md")
+	if _, ok := loadCommittedHashes()[newOne]; !ok {
+		t.Error("Committed hashes should show the pending remap")
+	}
+	if _, ok := loadCommittedHashes()[filepath.Join("draftsmore", "three.md")]; !ok {
+		t.Error("A sibling sharing the prefix as a string should not move")
+	}
+	if _, err := os.Stat(filepath.Join(snapshotDir, newOne)); err != nil {
+		t.Errorf("Expected the snapshot to move: %v", err)
+	}
+	if labels := loadLabels(); !hasLabel(labels, newOne, "draft") {
+		t.Errorf("Expected the label to follow, got %v", labels)
+	}
+
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	m, err := loadManifest(0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Remaps) != 1 || m.Remaps[0].Files != 2 {
+		t.Errorf("Expected the remap in the manifest, got %+v", m.Remaps)
+	}
+	if len(m.Changes) != 1 || m.Changes[0].State != stateModified || m.Changes[0].Path != newOne {
+		t.Errorf("Expected only the edited file as modified, got %+v", m.Changes)
+	}
+	log, _ := os.ReadFile(filepath.Join(changelogDir, newOne+".log"))
+	if !strings.Contains(string(log), "Moved from drafts/one.md") || !strings.Contains(string(log), "And more") {
+		t.Errorf("Expected the old changelog to continue at the new path, got:\n%s", log)
+	}
+	if _, err := os.Stat(filepath.Join(changelogDir, "drafts")); err == nil {
+		t.Error("The old changelog folder should be gone")
+	}
+	if pendingRemaps(0.1) != nil {
+		t.Error("Nothing should be pending after the update")
+	}
+	if s := historySection(m); !strings.Contains(s, "moved `drafts` → `book/chapters` (2 files)") {
+		t.Errorf("Expected the move in the history, got:\n%s", s)
+	}
+}
+
+func TestRemapPath(t *testing.T) {
+	cases := []struct{ rel, from, to, want string }{
+		{"a/b.md", "a", "c", "c/b.md"},
+		{"a", "a", "c", "c"},
+		{"ab/b.md", "a", "c", "ab/b.md"},
+		{"a/x/b.md", "a/x", "y", "y/b.md"},
+	}
+	for _, c := range cases {
+		got, _ := remapPath(filepath.FromSlash(c.rel), filepath.FromSlash(c.from), filepath.FromSlash(c.to))
+		if got != filepath.FromSlash(c.want) {
+			t.Errorf("remapPath(%q, %q, %q) = %q, want %q", c.rel, c.from, c.to, got, c.want)
+		}
+	}
+}
+
+func TestFolderRenameRecordedOnce(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, filepath.Join("notes", "a.md"), "Alpha\n")
+	createTestFile(t, filepath.Join("notes", "deep", "b.md"), "Beta\n")
+	createTestFile(t, filepath.Join("other", "c.md"), "Gamma\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := os.Rename("notes", "journal"); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	m, err := loadManifest(0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Changes) != 0 {
+		t.Errorf("A folder rename should not record per-file changes, got %+v", m.Changes)
+	}
+	if len(m.Remaps) != 1 || m.Remaps[0].From != "notes" || m.Remaps[0].To != "journal" || m.Remaps[0].Files != 2 {
+		t.Errorf("Expected one folder rename, got %+v", m.Remaps)
+	}
+	log, err := os.ReadFile(filepath.Join(changelogDir, "journal", "deep", "b.md.log"))
+	if err != nil || !strings.Contains(string(log), "Moved from notes/deep/b.md") {
+		t.Errorf("Expected the changelog subtree to move, got %q, %v", log, err)
+	}
+	if _, err := os.Stat(filepath.Join(changelogDir, "notes")); err == nil {
+		t.Error("The old changelog folder should be gone")
+	}
+	if _, err := os.Stat(filepath.Join(deletedDir, "notes")); err == nil {
+		t.Error("Renamed files should not land in the deleted store")
+	}
+}
+
+func TestDetectDirRenames(t *testing.T) {
+	j := filepath.Join
+	old := map[string]string{j("a", "x.md"): "1", j("a", "y.md"): "2", j("b", "z.md"): "3", j("c", "w.md"): "4"}
+	current := map[string]string{j("n", "x.md"): "1", j("n", "y.md"): "2", j("b", "z.md"): "3", j("c", "w.md"): "4"}
+	newFiles, _, deleted := detectChanges(old, current)
+	if got := detectDirRenames(newFiles, deleted, current, old); len(got) != 1 || got[0].From != "a" || got[0].To != "n" {
+		t.Errorf("Expected a → n, got %+v", got)
+	}
+
+	// one file edited on the way: not a plain rename
+	current[j("n", "y.md")] = "changed"
+	newFiles, _, deleted = detectChanges(old, current)
+	if got := detectDirRenames(newFiles, deleted, current, old); len(got) != 0 {
+		t.Errorf("Expected no rename when content changed, got %+v", got)
+	}
+
+	// moving into a folder that already tracks files is not a folder rename
+	current = map[string]string{j("c", "x.md"): "1", j("c", "y.md"): "2", j("b", "z.md"): "3", j("c", "w.md"): "4"}
+	newFiles, _, deleted = detectChanges(old, current)
+	if got := detectDirRenames(newFiles, deleted, current, old); len(got) != 0 {
+		t.Errorf("Expected no rename into a tracked folder, got %+v", got)
+	}
+}
+
+func TestFolderRenameNotAppliedWhenUpdateStops(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, filepath.Join("notes", "a.md"), "Alpha\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := os.Rename("notes", "journal"); err != nil {
+		t.Fatal(err)
+	}
+	interruptFlag.Store(true)
+	if err := updateGitnot(); !errors.Is(err, errInterrupted) {
+		t.Fatalf("Expected an interrupted update, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(changelogDir, "notes", "a.md.log")); err != nil {
+		t.Errorf("The changelog should stay put when nothing is recorded: %v", err)
+	}
+	if _, ok := loadCommittedHashes()[filepath.Join("notes", "a.md")]; !ok {
+		t.Error("hashes.json should still have the old path")
+	}
+	if _, err := os.Stat(remapsFile); err == nil {
+		t.Error("A folder rename should not be logged as a pending remap")
+	}
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(changelogDir, "journal", "a.md.log")); err != nil {
+		t.Errorf("The next update should move the changelog: %v", err)
+	}
+}
+
+func TestAbandonUpdateMovesChangelogsBack(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, filepath.Join("notes", "a.md"), "Alpha\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	from := filepath.Join(changelogDir, "notes", "a.md.log")
+	to := filepath.Join(changelogDir, "journal", "a.md.log")
+	before, _ := os.ReadFile(from)
+	j, err := beginJournal("update", 0, 0.1, []string{to})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := journalMoves(j, map[string]string{filepath.Join("journal", "a.md"): filepath.Join("notes", "a.md")}); err != nil {
+		t.Fatalf("journalMoves failed: %v", err)
+	}
+	appendToFile(to, "\n## v0.1 – Moved from notes/a.md\n")
+	if err := abandonUpdate(j); err != nil {
+		t.Fatalf("abandonUpdate failed: %v", err)
+	}
+	if after, err := os.ReadFile(from); err != nil || string(after) != string(before) {
+		t.Errorf("Changelog not moved back and cut: %q, %v", after, err)
+	}
+	if _, err := os.Stat(to); err == nil {
+		t.Error("The moved changelog should be gone from its new path")
+	}
+}
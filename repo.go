@@ -0,0 +1,188 @@
+package main
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// --- Clock and file system ---
+//
+// The store code reaches the outside world through repo, a package-level
+// Repo that tests swap for their own (see useRepo). Its Clock stamps
+// versions, changelog entries, journals, locks, backups, the stat index's
+// racy-mtime cutoff and the ages status and stats show. Its FS carries the
+// reads and writes of the store: its metadata and config, manifests,
+// objects and packs, changelogs and the snapshot, the lock, journal, task
+// queue and backups, and walks over its folders (walkStore). Store paths
+// are relative and FS resolves them: the default takes them from the
+// current folder, which --root switches to the project, while dirFS takes
+// them from a given folder, so store code can run on a project without
+// changing into it. Tests swap in a fixed clock, or an FS that fails a
+// chosen operation, to check timestamps and crash safety deterministically.
+// Working files, mirrors, keys and other files outside the store go through
+// the os package, and so do diffs, which read a snapshot copy the way they
+// read the working file it is compared with. Measuring elapsed time (bench,
+// throttling, progress, task waits) and the folder cache's scan stamps stay
+// on the real clock.
+
+// Clock tells the time.
+type Clock interface {
+	Now() time.Time
+}
+
+// File is an open file as FS hands it out.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+	Name() string
+}
+
+// FS is the file system the store is read and written through. Names are
+// as the os package takes them.
+type FS interface {
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	CreateTemp(dir, pattern string) (File, error)
+	ReadFile(name string) ([]byte, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+	Chmod(name string, mode fs.FileMode) error
+	Chtimes(name string, atime, mtime time.Time) error
+	Rename(oldpath, newpath string) error
+	Link(oldname, newname string) error
+	Truncate(name string, size int64) error
+	Remove(name string) error
+	RemoveAll(path string) error
+	MkdirAll(path string, perm fs.FileMode) error
+}
+
+// Repo bundles what the store code depends on.
+type Repo struct {
+	Clock Clock
+	FS    FS
+}
+
+var repo = Repo{Clock: systemClock{}, FS: osFS{}}
+
+// Now is the repo's current time.
+func (r Repo) Now() time.Time {
+	return r.Clock.Now()
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// osFS is FS on the real file system. Relative names are taken from Dir,
+// which is absolute, or from the current folder when Dir is empty.
+type osFS struct {
+	Dir string
+}
+
+// dirFS is the real file system with relative names taken from dir rather
+// than the current folder. Names longPath has already made absolute (long
+// ones, on Windows) are taken as they are.
+func dirFS(dir string) (osFS, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return osFS{}, err
+	}
+	return osFS{Dir: abs}, nil
+}
+
+func (f osFS) path(name string) string {
+	if f.Dir == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(f.Dir, name)
+}
+
+func (f osFS) Open(name string) (File, error) {
+	file, err := os.Open(f.path(name))
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (f osFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	file, err := os.OpenFile(f.path(name), flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// CreateTemp names the file it returns with an absolute path when Dir is
+// set, so the name can be handed back as is.
+func (f osFS) CreateTemp(dir, pattern string) (File, error) {
+	file, err := os.CreateTemp(f.path(dir), pattern)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (f osFS) ReadFile(name string) ([]byte, error)       { return os.ReadFile(f.path(name)) }
+func (f osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(f.path(name)) }
+func (f osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(f.path(name)) }
+func (f osFS) Chmod(name string, mode fs.FileMode) error  { return os.Chmod(f.path(name), mode) }
+func (f osFS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(f.path(name), atime, mtime)
+}
+func (f osFS) Rename(oldpath, newpath string) error {
+	return os.Rename(f.path(oldpath), f.path(newpath))
+}
+func (f osFS) Link(oldname, newname string) error     { return os.Link(f.path(oldname), f.path(newname)) }
+func (f osFS) Truncate(name string, size int64) error { return os.Truncate(f.path(name), size) }
+func (f osFS) Remove(name string) error               { return os.Remove(f.path(name)) }
+func (f osFS) RemoveAll(path string) error            { return os.RemoveAll(f.path(path)) }
+func (f osFS) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(f.path(path), perm)
+}
+
+// walkStore is filepath.WalkDir for the store: it lists folders through
+// repo.FS.
+func walkStore(root string, fn fs.WalkDirFunc) error {
+	fi, err := repo.FS.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkStoreDir(root, fs.FileInfoToDirEntry(fi), fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walkStoreDir(p string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(p, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+	entries, err := repo.FS.ReadDir(p)
+	if err != nil {
+		if err = fn(p, d, err); err != nil {
+			if err == filepath.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, e := range entries {
+		if err := walkStoreDir(filepath.Join(p, e.Name()), e, fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fixedClock struct{ t time.Time }
+
+func (c fixedClock) Now() time.Time { return c.t }
+
+// failingFS fails renames onto one path and passes everything else through.
+type failingFS struct {
+	osFS
+	target string
+}
+
+var errInjected = errors.New("injected failure")
+
+func (f failingFS) Rename(oldpath, newpath string) error {
+	if filepath.Clean(newpath) == filepath.Clean(f.target) {
+		return errInjected
+	}
+	return f.osFS.Rename(oldpath, newpath)
+}
+
+// useRepo swaps the package's Repo for the rest of the test.
+func useRepo(t *testing.T, r Repo) {
+	saved := repo
+	repo = r
+	t.Cleanup(func() { repo = saved })
+}
+
+func TestFixedClockStampsVersions(t *testing.T) {
+	setupTestDir(t)
+	at := time.Date(2024, 3, 9, 14, 30, 0, 0, time.UTC)
+	useRepo(t, Repo{Clock: fixedClock{at}, FS: osFS{}})
+
+	createTestFile(t, "a.txt", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "a.txt", "two\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	for _, v := range []float64{0.0, 0.1} {
+		m, err := loadManifest(v)
+		if err != nil {
+			t.Fatalf("loadManifest(%.1f) failed: %v", v, err)
+		}
+		if !m.Timestamp.Equal(at) {
+			t.Errorf("v%.1f stamped %v, want %v", v, m.Timestamp, at)
+		}
+	}
+	cl, _ := os.ReadFile(filepath.Join(changelogDir, "a.txt.log"))
+	if !strings.Contains(string(cl), "## v0.1 – "+at.Local().Format("2006-01-02 15:04")) {
+		t.Errorf("Changelog entry not stamped with the fixed time:\n%s", cl)
+	}
+}
+
+func TestFailedVersionWriteRollsBack(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.txt", "original\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	clPath := filepath.Join(changelogDir, "a.txt.log")
+	before, _ := os.ReadFile(clPath)
+
+	// The update gets as far as its last write, version.txt, and dies there
+	createTestFile(t, "a.txt", "edited\n")
+	saved := repo
+	repo = Repo{Clock: systemClock{}, FS: failingFS{target: versionFile}}
+	err := updateGitnot()
+	repo = saved
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("Expected the injected failure, got %v", err)
+	}
+	if _, err := os.Stat(journalFile); err != nil {
+		t.Fatal("The journal should survive the failed update")
+	}
+
+	if err := recoverInterrupted(); err != nil {
+		t.Fatalf("recoverInterrupted failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.0 {
+		t.Errorf("Expected v0.0 after rollback, got v%.1f", v)
+	}
+	if _, err := os.Stat(manifestPath(0.1)); !os.IsNotExist(err) {
+		t.Error("The manifest of the failed version should be removed")
+	}
+	after, _ := os.ReadFile(clPath)
+	if string(after) != string(before) {
+		t.Errorf("Changelog not rolled back: %q", after)
+	}
+	snap, _ := os.ReadFile(filepath.Join(snapshotDir, "a.txt"))
+	if string(snap) != "original\n" {
+		t.Errorf("Snapshot not restored, got %q", snap)
+	}
+}
+
+// noLinkFS is a file system without hard links.
+type noLinkFS struct{ osFS }
+
+func (noLinkFS) Link(oldname, newname string) error {
+	return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: errors.ErrUnsupported}
+}
+
+func TestLockWithoutHardLinks(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.txt", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	useRepo(t, Repo{Clock: systemClock{}, FS: noLinkFS{}})
+	release, err := acquireLock()
+	if err != nil {
+		t.Fatalf("Expected the lock to be created in place: %v", err)
+	}
+	if !lockHolderAlive() {
+		t.Error("Expected the lock to name this process")
+	}
+	if _, err := acquireLock(); err == nil {
+		t.Error("Expected a second lock to be refused")
+	}
+	release()
+	if entries, _ := filepath.Glob(filepath.Join(gitnotDir, ".lock.tmp*")); len(entries) != 0 {
+		t.Errorf("Expected no temporary lock files left, got %v", entries)
+	}
+}
+
+func TestDirFSReachesTheStoreFromElsewhere(t *testing.T) {
+	project := setupTestDir(t)
+	createTestFile(t, "a.txt", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	fsys, err := dirFS(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(t.TempDir())
+	useRepo(t, Repo{Clock: systemClock{}, FS: fsys})
+
+	v, err := readVersion()
+	if err != nil {
+		t.Fatalf("Expected version.txt to be read from the project: %v", err)
+	}
+	m, err := loadManifest(v)
+	if err != nil || len(m.Files) != 1 {
+		t.Errorf("Expected the manifest with one file, got %v (%v)", m.Files, err)
+	}
+	if _, count := dirSize(gitnotDir); count == 0 {
+		t.Error("Expected walkStore to list the store's files")
+	}
+	if err := saveJSON(labelsFile, map[string]string{"draft": "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(project, labelsFile)); err != nil {
+		t.Errorf("Expected the write to land in the project's store: %v", err)
+	}
+	if _, err := os.Stat(gitnotDir); err == nil {
+		t.Error("Expected nothing written to the current folder")
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// --- Restore ---
+//
+// `gitnot restore` writes tracked files back as they were at a version.
+// Before a working file with unrecorded changes is overwritten, its content
+// is saved under .gitnot/overwritten/<timestamp>/, so a restore never loses
+// work that no version holds. Arguments may be globs ('chapters/**'), taken
+// from the project root; such a restore lists what it would do and asks
+// before writing anything. Overwriting a file that differs from the version
+// asks for the passphrase of a protected project.
+
+// restoreResult says what a restore did.
+type restoreResult struct {
+	Restored  []string
+	Saved     []string // working files copied aside before being overwritten
+	BackupDir string   // where Saved went, "" if nothing needed saving
+}
+
+// unrecorded reports whether the working file at rel holds content that
+// would be lost by writing want over it: it exists, differs from want and
+// no version stored it.
+func unrecorded(rel string, want []byte, committed map[string]string) bool {
+	have, err := os.ReadFile(longPath(rel))
+	if err != nil || bytes.Equal(have, want) {
+		return false
+	}
+	h := hashFile(rel)
+	return h != committed[rel] && !hasObject(h)
+}
+
+// overwrites reports whether restoring files as of m would replace a
+// working file holding something else.
+func overwrites(m Manifest, files []string) bool {
+	for _, rel := range files {
+		if _, err := os.Stat(longPath(rel)); err == nil && hashFileExpecting(rel, m.Files[rel]) != m.Files[rel] {
+			return true
+		}
+	}
+	return false
+}
+
+// saveOverwritten copies rel into dir, keeping its relative path.
+func saveOverwritten(dir, rel string) error {
+	dst := filepath.Join(dir, rel)
+	if err := safeMkdirAllForFile(dst); err != nil {
+		return err
+	}
+	return copyFile(rel, dst)
+}
+
+// restoreStep is what restoring one file would do.
+type restoreStep struct {
+	Path   string
+	Action string // unchanged, create, overwrite or save+overwrite
+}
+
+// planRestore previews restoreFiles without writing anything.
+func planRestore(m Manifest, files []string) ([]restoreStep, error) {
+	committed := loadCommittedHashes()
+	var plan []restoreStep
+	for _, rel := range files {
+		content, err := contentAt(rel, m)
+		if err != nil {
+			return nil, err
+		}
+		step := restoreStep{Path: rel, Action: "overwrite"}
+		if have, err := os.ReadFile(longPath(rel)); err != nil {
+			step.Action = "create"
+		} else if bytes.Equal(have, content) {
+			step.Action = "unchanged"
+		} else if unrecorded(rel, content, committed) {
+			step.Action = "save+overwrite"
+		}
+		plan = append(plan, step)
+	}
+	return plan, nil
+}
+
+func printRestorePlan(plan []restoreStep, v float64) {
+	icons := map[string]string{"unchanged": "  ", "create": "➕", "overwrite": "✏️ ", "save+overwrite": "🛟"}
+	fmt.Printf("Restoring from v%.1f:\n", v)
+	for _, s := range plan {
+		fmt.Printf("  %s %-15s %s\n", icons[s.Action], s.Action, s.Path)
+	}
+}
+
+// expandRestoreArgs turns file arguments and globs into the files tracked
+// at m. A glob that matches nothing is an error rather than a no-op.
+func expandRestoreArgs(m Manifest, args []string) (files []string, globbed bool, err error) {
+	seen := map[string]bool{}
+	for _, a := range args {
+		if !strings.ContainsAny(a, "*?[") {
+			if f := filepath.Clean(a); !seen[f] {
+				seen[f] = true
+				files = append(files, f)
+			}
+			continue
+		}
+		if _, err := path.Match(filepath.ToSlash(a), ""); err != nil {
+			return nil, false, fmt.Errorf("%q is not a valid glob", a)
+		}
+		globbed = true
+		n := 0
+		for _, rel := range sortedKeys(m.Files) {
+			if matchesRooted(rel, a) {
+				n++
+				if !seen[rel] {
+					seen[rel] = true
+					files = append(files, rel)
+				}
+			}
+		}
+		if n == 0 {
+			return nil, false, fmt.Errorf("%s matches no file tracked at v%.1f", a, m.Version)
+		}
+	}
+	return files, globbed, nil
+}
+
+// restoreFiles writes files as of m into the working tree.
+func restoreFiles(m Manifest, files []string) (restoreResult, error) {
+	var res restoreResult
+	if overwrites(m, files) {
+		if err := requirePassphrase("overwrite files with restore"); err != nil {
+			return res, err
+		}
+	}
+	committed := loadCommittedHashes()
+	cc := loadConfig().Copy
+	stamp := repo.Now().Format("20060102-150405.000")
+	for _, rel := range files {
+		content, err := contentAt(rel, m)
+		if err != nil {
+			return res, err
+		}
+		if unrecorded(rel, content, committed) {
+			if res.BackupDir == "" {
+				res.BackupDir = filepath.Join(overwrittenDir, stamp)
+			}
+			if err := saveOverwritten(res.BackupDir, rel); err != nil {
+				return res, fmt.Errorf("could not save %s before restoring it, nothing overwritten: %w", rel, err)
+			}
+			res.Saved = append(res.Saved, rel)
+		}
+		perm := os.FileMode(0o644)
+		if fi, err := os.Stat(longPath(rel)); err == nil {
+			perm = fi.Mode().Perm()
+		}
+		if err := safeMkdirAllForFile(rel); err != nil {
+			return res, err
+		}
+		if err := writeFileAtomic(longPath(rel), content, perm); err != nil {
+			return res, err
+		}
+		if err := restoreMeta(rel, m.Meta[rel], cc); err != nil {
+			return res, err
+		}
+		res.Restored = append(res.Restored, rel)
+	}
+	return res, nil
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	version := fs.String("version", "", "restore as of this version (default: current)")
+	all := fs.Bool("all", false, "restore every file tracked at that version")
+	dryRun := fs.Bool("dry-run", false, "list what would be restored without writing anything")
+	yes := fs.Bool("yes", false, "restore files matched by a glob without asking")
+	pathsFrom := fs.String("paths-from", "", "also restore the paths listed in this file (- for stdin)")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	var listed []string
+	if *pathsFrom != "" {
+		if listed, err = readPathList(*pathsFrom); err != nil {
+			return err
+		}
+	}
+	if len(rest)+len(listed) == 0 && !*all && !isTerminal() {
+		return fmt.Errorf("usage: gitnot restore <file>... [--version v] | gitnot restore --all [--version v]")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if len(rest)+len(listed) == 0 && !*all {
+		rel, err := pickFile()
+		if err != nil {
+			return err
+		}
+		if *version == "" {
+			if *version, err = pickVersion(rel); err != nil {
+				return err
+			}
+		}
+		rest = []string{rel}
+	}
+	v, err := parseVersionArg(*version)
+	if err != nil {
+		return err
+	}
+	m, err := loadManifest(v)
+	if err != nil {
+		return err
+	}
+	files, globbed, err := expandRestoreArgs(m, rest)
+	if err != nil {
+		return err
+	}
+	// listed paths are literal, whatever characters they contain; a folder
+	// stands for the files under it
+	for _, p := range listed {
+		n := 0
+		for _, rel := range sortedKeys(m.Files) {
+			if within(rel, p) {
+				n++
+				if !slices.Contains(files, rel) {
+					files = append(files, rel)
+				}
+			}
+		}
+		if n == 0 {
+			return fmt.Errorf("%s is not tracked at v%.1f", filepath.ToSlash(p), m.Version)
+		}
+	}
+	if *all {
+		files = sortedKeys(m.Files)
+	}
+	if globbed || *dryRun {
+		plan, err := planRestore(m, files)
+		if err != nil {
+			return err
+		}
+		printRestorePlan(plan, v)
+		if *dryRun {
+			return nil
+		}
+		files = files[:0]
+		for _, s := range plan {
+			if s.Action != "unchanged" {
+				files = append(files, s.Path)
+			}
+		}
+		if len(files) == 0 {
+			fmt.Println("✅ Nothing to restore; the files already match")
+			return nil
+		}
+		if !*yes && !confirmAction(fmt.Sprintf("Restore these %d files?", len(files))) {
+			if !isTerminal() {
+				return fmt.Errorf("not restoring without confirmation; rerun with --yes")
+			}
+			fmt.Println("Nothing restored")
+			return nil
+		}
+	}
+
+	res, err := restoreFiles(m, files)
+	if len(res.Saved) > 0 {
+		fmt.Printf("🛟 Saved unrecorded changes to %d file(s) in %s before overwriting them\n", len(res.Saved), res.BackupDir)
+	}
+	if err != nil {
+		if len(res.Restored) > 0 {
+			fmt.Printf("⚠️  Warning: only %d of %d files were restored\n", len(res.Restored), len(files))
+		}
+		return err
+	}
+	if len(res.Restored) == 1 {
+		fmt.Printf("↩️  Restored %s from v%.1f\n", res.Restored[0], v)
+	} else {
+		fmt.Printf("↩️  Restored %d files from v%.1f\n", len(res.Restored), v)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRestoreSavesUnrecordedWork(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "ch1.md", "first draft\n")
+	createTestFile(t, "ch2.md", "untouched\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "ch1.md", "second draft\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	createTestFile(t, "ch1.md", "third draft, not recorded\n")
+
+	m, err := loadManifest(0.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := restoreFiles(m, []string{"ch1.md", "ch2.md"})
+	if err != nil {
+		t.Fatalf("restoreFiles failed: %v", err)
+	}
+	if got, _ := os.ReadFile("ch1.md"); string(got) != "first draft\n" {
+		t.Errorf("ch1.md not restored, got %q", got)
+	}
+	if len(res.Saved) != 1 || res.Saved[0] != "ch1.md" {
+		t.Fatalf("Expected only ch1.md to be saved, got %v", res.Saved)
+	}
+	saved, err := os.ReadFile(filepath.Join(res.BackupDir, "ch1.md"))
+	if err != nil || string(saved) != "third draft, not recorded\n" {
+		t.Errorf("Unrecorded content not saved: %q, %v", saved, err)
+	}
+
+	// Content a version already holds is not lost, so it isn't copied.
+	m2, _ := loadManifest(0.1)
+	res, err = restoreFiles(m2, []string{"ch1.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Saved) != 0 {
+		t.Errorf("Restoring over recorded content should not save it, saved %v", res.Saved)
+	}
+	if got, _ := os.ReadFile("ch1.md"); string(got) != "second draft\n" {
+		t.Errorf("ch1.md not restored to v0.1, got %q", got)
+	}
+}
+
+func TestRestoreGlob(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, filepath.Join("chapters", "one.md"), "one\n")
+	createTestFile(t, filepath.Join("chapters", "two.md"), "two\n")
+	createTestFile(t, filepath.Join("old", "chapters", "two.md"), "old two\n")
+	createTestFile(t, "notes.md", "notes\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, filepath.Join("chapters", "one.md"), "one, revised\n")
+	createTestFile(t, filepath.Join("old", "chapters", "two.md"), "old two, revised\n")
+	createTestFile(t, "notes.md", "notes, revised\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	m, _ := loadManifest(0.0)
+	files, globbed, err := expandRestoreArgs(m, []string{"chapters/**", "chapters/one.md"})
+	if err != nil || !globbed || len(files) != 2 {
+		t.Fatalf("Expected both chapters once, got %v %v %v", files, globbed, err)
+	}
+	plan, _ := planRestore(m, files)
+	if plan[0].Action != "overwrite" || plan[1].Action != "unchanged" {
+		t.Errorf("Unexpected plan %+v", plan)
+	}
+	if _, _, err := expandRestoreArgs(m, []string{"drafts/*.md"}); err == nil {
+		t.Error("Expected a glob matching nothing to be an error")
+	}
+
+	if err := runRestore([]string{"chapters/**", "--version", "0.0", "--dry-run"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = runRestore([]string{"chapters/**", "--version", "0.0"}) // no one answers the prompt
+	if b, _ := os.ReadFile(filepath.Join("chapters", "one.md")); string(b) != "one, revised\n" {
+		t.Fatalf("Nothing should be written without confirmation, got %q", b)
+	}
+	if err := runRestore([]string{"chapters/**", "--version", "0.0", "--yes"}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join("chapters", "one.md")); string(b) != "one\n" {
+		t.Errorf("Expected the chapter restored, got %q", b)
+	}
+	if b, _ := os.ReadFile("notes.md"); string(b) != "notes, revised\n" {
+		t.Errorf("Files outside the glob must be left alone, got %q", b)
+	}
+	if b, _ := os.ReadFile(filepath.Join("old", "chapters", "two.md")); string(b) != "old two, revised\n" {
+		t.Errorf("Globs are taken from the project root; old/chapters must be left alone, got %q", b)
+	}
+	if files, _, _ := expandRestoreArgs(m, []string{"*.md"}); len(files) != 1 || files[0] != "notes.md" {
+		t.Errorf("Expected *.md to match only files at the root, got %v", files)
+	}
+}
+
+func TestRestoreRefusesDamagedContent(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "ch1.md", "first draft\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "ch1.md", "second draft\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	m0, _ := loadManifest(0.0)
+	obj := objectPath(m0.Files["ch1.md"])
+	os.Chmod(obj, 0o644)
+	if err := os.WriteFile(obj, []byte("first drafT\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := restoreFiles(m0, []string{"ch1.md"})
+	var damaged *corruptError
+	if !errors.As(err, &damaged) {
+		t.Fatalf("Expected a damaged-object error, got %v", err)
+	}
+	if b, _ := os.ReadFile("ch1.md"); string(b) != "second draft\n" {
+		t.Errorf("Working file was overwritten with damaged content: %q", b)
+	}
+
+	// The current version is still served from the intact snapshot.
+	m1, _ := loadManifest(0.1)
+	os.Chmod(objectPath(m1.Files["ch1.md"]), 0o644)
+	os.WriteFile(objectPath(m1.Files["ch1.md"]), []byte("garbage"), 0o644)
+	if b, err := contentAt("ch1.md", m1); err != nil || string(b) != "second draft\n" {
+		t.Errorf("Expected the snapshot copy, got %q, %v", b, err)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// --- Retrying transient read errors ---
+//
+// SMB and NFS shares now and then fail a read with EIO or EBUSY that works
+// a moment later, and a file that failed that way used to drop out of the
+// version it was being recorded in. Opening, reading and copying working
+// files is now retried with a doubling delay when the error looks transient
+// ("retry": {"attempts": 3, "delay_ms": 100} by default; "attempts": 1 turns
+// it off). Files that still fail are reported as unreadable at the end of
+// the run (see unreadable.go), so nothing goes missing without a word.
+
+// RetryConfig is the "retry" config object.
+type RetryConfig struct {
+	Attempts int `json:"attempts,omitempty"` // tries per read or copy, default 3
+	DelayMS  int `json:"delay_ms,omitempty"` // wait before the first retry, doubled after each; default 100
+}
+
+const (
+	defaultRetryAttempts = 3
+	defaultRetryDelay    = 100 * time.Millisecond
+)
+
+var (
+	retryMu       sync.Mutex
+	retryAttempts = defaultRetryAttempts
+	retryDelay    = defaultRetryDelay
+)
+
+// setRetry applies the retry config for the rest of the process.
+func setRetry(rc RetryConfig) {
+	retryMu.Lock()
+	defer retryMu.Unlock()
+	retryAttempts, retryDelay = defaultRetryAttempts, defaultRetryDelay
+	if rc.Attempts > 0 {
+		retryAttempts = rc.Attempts
+	}
+	if rc.DelayMS > 0 {
+		retryDelay = time.Duration(rc.DelayMS) * time.Millisecond
+	}
+}
+
+// isTransient reports whether err may go away when the operation is retried.
+func isTransient(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	for _, e := range transientErrnos {
+		if errno == e {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry runs op until it succeeds, fails with an error that is not
+// transient, or runs out of attempts.
+func withRetry(op func() error) error {
+	retryMu.Lock()
+	attempts, delay := retryAttempts, retryDelay
+	retryMu.Unlock()
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = op(); err == nil || !isTransient(err) {
+			break
+		}
+	}
+	return err
+}
+
+func validateRetry(c Config) []configIssue {
+	var issues []configIssue
+	if c.Retry.Attempts < 0 {
+		issues = append(issues, configIssue{"retry.attempts", "must be at least 1"})
+	}
+	if c.Retry.DelayMS < 0 {
+		issues = append(issues, configIssue{"retry.delay_ms", "must not be negative"})
+	}
+	if c.Retry.Attempts > 10 {
+		issues = append(issues, configIssue{"retry.attempts", fmt.Sprintf("%d attempts with a doubling delay can stall an update for hours; use 10 or fewer", c.Retry.Attempts)})
+	}
+	return issues
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestWithRetryRecoversFromTransientErrors(t *testing.T) {
+	t.Cleanup(func() { setRetry(RetryConfig{}) })
+	setRetry(RetryConfig{Attempts: 3, DelayMS: 1})
+	transient := &os.PathError{Op: "read", Path: "a.md", Err: transientErrnos[0]}
+
+	calls := 0
+	err := withRetry(func() error {
+		calls++
+		if calls < 3 {
+			return transient
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Expected success on the third try, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	if err := withRetry(func() error { calls++; return transient }); !errors.Is(err, transientErrnos[0]) || calls != 3 {
+		t.Errorf("Expected the error after 3 calls, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	if err := withRetry(func() error { calls++; return fmt.Errorf("wrap: %w", syscall.ENOENT) }); err == nil || calls != 1 {
+		t.Errorf("A missing file should not be retried, got %d calls", calls)
+	}
+}
+
+func TestValidateRetry(t *testing.T) {
+	if issues := validateRetry(Config{Retry: RetryConfig{Attempts: 3, DelayMS: 100}}); len(issues) != 0 {
+		t.Errorf("Unexpected issues: %v", issues)
+	}
+	if issues := validateRetry(Config{Retry: RetryConfig{Attempts: 50, DelayMS: -1}}); len(issues) != 2 {
+		t.Errorf("Expected two issues, got %v", issues)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// --- Revived files ---
+//
+// A file that comes back after being deleted — restored from an old
+// version, or simply recreated under the same name — continues its old
+// history instead of starting over as a new file. Its changelog gets a
+// "back" entry that says which version last had it and diffs it against the
+// copy kept in the deleted store, its change is measured against that copy,
+// and the version records where it came back from. Once the version is
+// committed the copy leaves the deleted store, since the file is no longer
+// deleted.
+
+// lastTrackedIn returns the newest version in manifests that has rel.
+func lastTrackedIn(manifests []Manifest, rel string) (float64, bool) {
+	for i := len(manifests) - 1; i >= 0; i-- {
+		if _, ok := manifests[i].Files[rel]; ok {
+			return manifests[i].Version, true
+		}
+	}
+	return 0, false
+}
+
+// findRevived picks the new files that were tracked before, keyed by the
+// version that last had them. Only files that kept a changelog can be, so
+// the manifests are read only when one did.
+func findRevived(newFiles []string) map[string]float64 {
+	var candidates []string
+	for _, rel := range newFiles {
+		if _, err := repo.FS.Stat(filepath.Join(changelogDir, rel+".log")); err == nil {
+			candidates = append(candidates, rel)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	revived := map[string]float64{}
+	err := eachManifest(func(m Manifest) error {
+		// later versions overwrite earlier ones, leaving the last that had it
+		for _, rel := range candidates {
+			if v, ok := lastTrackedIn([]Manifest{m}, rel); ok {
+				revived[rel] = v
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+	return revived
+}
+
+// reviveEntry is the changelog entry for a file that is back, diffed against
+// its deleted copy when there is one.
+func reviveEntry(rel string, last float64, binary bool, mask func(string) bool) (string, FileChange) {
+	head := fmt.Sprintf("♻️ File is back (last tracked in v%.1f).\n", last)
+	if binary {
+		return head, FileChange{Path: rel, State: stateAdded}
+	}
+	old := filepath.Join(deletedDir, rel)
+	if _, err := os.Stat(old); err != nil {
+		return head, measureFiles(rel, "", rel, stateAdded)
+	}
+	fc := measureFiles(rel, old, rel, stateAdded)
+	diffText, _ := unifiedDiffMasked(old, rel, mask)
+	if diffText == "" {
+		return head + "📄 Same content as when it was deleted\n", fc
+	}
+	return head + formatDiffAsMarkdown(diffText), fc
+}
+
+// clearRevived drops the deleted copies of files that are back, once the
+// version recording them is committed.
+func clearRevived(revived map[string]float64) {
+	for rel := range revived {
+		p := filepath.Join(deletedDir, rel)
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("⚠️  Warning: Could not clear the deleted copy of %s: %v\n", rel, err)
+			continue
+		}
+		removeEmptyParents(p, deletedDir)
+	}
+}
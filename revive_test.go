@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRevivedFileContinuesHistory(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "chapter1.md", "One\nTwo\n")
+	createTestFile(t, "keep.md", "Keep\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	os.Remove("chapter1.md")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(deletedDir, "chapter1.md")); err != nil {
+		t.Fatalf("Expected a deleted copy: %v", err)
+	}
+
+	createTestFile(t, "chapter1.md", "One\nTwo\nThree\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	m, err := loadManifest(0.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Changes) != 1 {
+		t.Fatalf("Expected one change, got %+v", m.Changes)
+	}
+	c := m.Changes[0]
+	if c.RevivedFrom == nil || *c.RevivedFrom != 0.0 {
+		t.Errorf("Expected the file to be back from v0.0, got %v", c.RevivedFrom)
+	}
+	if c.LinesAdded != 1 || c.LinesRemoved != 0 {
+		t.Errorf("Expected the change measured against the deleted copy, got +%d/-%d", c.LinesAdded, c.LinesRemoved)
+	}
+	log, _ := os.ReadFile(filepath.Join(changelogDir, "chapter1.md.log"))
+	if !strings.Contains(string(log), "File was deleted") || !strings.Contains(string(log), "File is back (last tracked in v0.0)") {
+		t.Errorf("Expected the old changelog to continue, got:\n%s", log)
+	}
+	if strings.Contains(string(log), "New file added") {
+		t.Errorf("A revived file should not be added again, got:\n%s", log)
+	}
+	if !strings.Contains(string(log), "Three") {
+		t.Errorf("Expected a diff against the deleted copy, got:\n%s", log)
+	}
+	if _, err := os.Stat(filepath.Join(deletedDir, "chapter1.md")); err == nil {
+		t.Error("The deleted copy should go once the file is back")
+	}
+	if s := changeSummary(c); !strings.Contains(s, "is back") {
+		t.Errorf("Unexpected history line %q", s)
+	}
+}
+
+func TestLastTrackedIn(t *testing.T) {
+	manifests := []Manifest{
+		{Version: 0.0, Files: map[string]string{"a.md": "1"}},
+		{Version: 0.1, Files: map[string]string{"a.md": "2"}},
+		{Version: 0.2, Files: map[string]string{}},
+	}
+	if v, ok := lastTrackedIn(manifests, "a.md"); !ok || v != 0.1 {
+		t.Errorf("Expected v0.1, got %v %v", v, ok)
+	}
+	if _, ok := lastTrackedIn(manifests, "b.md"); ok {
+		t.Error("b.md was never tracked")
+	}
+}
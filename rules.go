@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// --- Tracking rules ---
+//
+// "rules" is an ordered list: the first rule whose pattern matches a path
+// decides whether it is tracked and how — as text or binary, with which diff
+// driver, whether its changelog gets full diffs, and up to what size.
+// Configs without rules get them translated from ignore_patterns,
+// include_patterns and extensions, in that order. Patterns from
+// .gitnotignore and the vault defaults apply either way, unless
+// .gitnot/tracked.txt exists: then it alone lists what is tracked.
+
+// Rule is one entry of the "rules" config list.
+type Rule struct {
+	Match     string `json:"match"`
+	Action    string `json:"action,omitempty"`      // "track" (default) or "ignore"
+	Mode      string `json:"mode,omitempty"`        // "text" (default) or "binary"
+	Diff      string `json:"diff,omitempty"`        // driver name, "lines" or "none"; default by extension
+	Extract   string `json:"extract,omitempty"`     // extractor whose text is diffed, see plugins.go
+	MaxSizeKB int    `json:"max_size_kb,omitempty"` // larger files are not tracked
+	Changelog string `json:"changelog,omitempty"`   // "full" (default) or "summary": one count line per change
+
+	source string // where a translated or implied rule came from
+}
+
+const (
+	ruleTrack  = "track"
+	ruleIgnore = "ignore"
+	modeText   = "text"
+	modeBinary = "binary"
+	diffLines  = "lines"
+	diffNone   = "none"
+
+	changelogFull    = "full"
+	changelogSummary = "summary"
+)
+
+func (r Rule) ignores() bool { return r.Action == ruleIgnore }
+
+func (r Rule) binary() bool { return r.Mode == modeBinary }
+
+// extPattern reports whether a track rule is a plain "*.ext" pattern, which
+// matches case-insensitively like the extensions list.
+func (r Rule) extPattern() (string, bool) {
+	ext, ok := strings.CutPrefix(r.Match, "*")
+	if !ok || r.ignores() || !strings.HasPrefix(ext, ".") || strings.ContainsAny(ext, "*?[/") {
+		return "", false
+	}
+	return ext, true
+}
+
+func (r Rule) matches(p string) bool {
+	if ext, ok := r.extPattern(); ok {
+		return hasAnySuffix(filepath.Base(p), []string{ext})
+	}
+	return matchesAny(p, []string{r.Match})
+}
+
+// fits reports whether a file of size bytes is within the rule's limit.
+func (r Rule) fits(size int64) bool {
+	return r.MaxSizeKB <= 0 || size <= int64(r.MaxSizeKB)<<10
+}
+
+func (r Rule) String() string {
+	action := ruleTrack
+	if r.ignores() {
+		action = ruleIgnore
+	}
+	s := fmt.Sprintf("%-6s %s", action, r.Match)
+	var opts []string
+	if r.binary() {
+		opts = append(opts, modeBinary)
+	}
+	if r.Diff != "" {
+		opts = append(opts, "diff "+r.Diff)
+	}
+	if r.Extract != "" {
+		opts = append(opts, "extract "+r.Extract)
+	}
+	if r.Changelog == changelogSummary {
+		opts = append(opts, "changelog summary")
+	}
+	if r.MaxSizeKB > 0 {
+		opts = append(opts, "max "+formatBytes(int64(r.MaxSizeKB)<<10))
+	}
+	if len(opts) > 0 {
+		s += " (" + strings.Join(opts, ", ") + ")"
+	}
+	return s
+}
+
+// readTrackedList reads .gitnot/tracked.txt, the curated list of paths and
+// globs to track; ok is false when there is none.
+func readTrackedList() ([]string, bool) {
+	return readPatternFile(trackedListFile)
+}
+
+// scanFilters returns the effective rules for a scan.
+func (c Config) scanFilters() scanFilter {
+	var rules []Rule
+	add := func(source, action string, pats ...string) {
+		for _, p := range pats {
+			rules = append(rules, Rule{Match: p, Action: action, source: source})
+		}
+	}
+	add(untrackListFile, ruleIgnore, loadUntrackList()...)
+	if pats, ok := readTrackedList(); ok {
+		add(trackedListFile, ruleTrack, pats...)
+		return scanFilter{Rules: rules, MaxDepth: c.MaxDepth, Depths: c.DepthOverrides, listed: true}
+	}
+	var vaultExts, vaultIgnores []string
+	switch c.vaultMode() {
+	case vaultObsidian:
+		vaultExts, vaultIgnores = obsidianExtensions, obsidianIgnores
+	case vaultLogseq:
+		vaultExts, vaultIgnores = logseqExtensions, logseqIgnores
+	}
+	add(ignoreFile, ruleIgnore, readIgnoreFile()...)
+	add("vault", ruleIgnore, vaultIgnores...)
+	if len(c.Rules) > 0 {
+		for _, r := range c.Rules {
+			r.source = "rules"
+			rules = append(rules, r)
+		}
+	} else {
+		add("ignore_patterns", ruleIgnore, c.IgnorePatterns...)
+		add("include_patterns", ruleTrack, c.IncludePatterns...)
+		for _, e := range c.Extensions {
+			add("extensions", ruleTrack, "*"+e)
+		}
+	}
+	for _, e := range vaultExts {
+		add("vault", ruleTrack, "*"+e)
+	}
+	return scanFilter{Rules: rules, MaxDepth: c.MaxDepth, Depths: c.DepthOverrides,
+		HiddenFiles: c.HiddenFiles, HiddenDirs: c.HiddenDirs}
+}
+
+// scanFilter decides which files a scan tracks: the first matching rule
+// wins, and files no rule matches are left alone.
+type scanFilter struct {
+	Rules    []Rule
+	MaxDepth int
+	Depths   map[string]int
+
+	HiddenFiles, HiddenDirs string // see hidden.go
+	listed                  bool   // rules come from tracked.txt
+}
+
+// ruleFor returns the deciding rule for p and its index.
+func (f scanFilter) ruleFor(p string) (Rule, int) {
+	for i, r := range f.Rules {
+		if r.matches(p) {
+			return r, i
+		}
+	}
+	return Rule{}, -1
+}
+
+// tracks reports whether p is tracked; size is only called when the
+// deciding rule has a size limit.
+func (f scanFilter) tracks(p string, size func() int64) bool {
+	r, i := f.ruleFor(p)
+	if i < 0 || r.ignores() {
+		return false
+	}
+	return r.MaxSizeKB <= 0 || r.fits(size())
+}
+
+// sizeLimited reports whether a size limit decides whether p is tracked.
+func (f scanFilter) sizeLimited(p string) bool {
+	r, i := f.ruleFor(p)
+	return i >= 0 && !r.ignores() && r.MaxSizeKB > 0
+}
+
+// validateRules checks the explicit rules list.
+func validateRules(rules []Rule) []configIssue {
+	var issues []configIssue
+	for i, r := range rules {
+		key := fmt.Sprintf("rules[%d]", i)
+		add := func(format string, args ...any) {
+			issues = append(issues, configIssue{key, fmt.Sprintf(format, args...)})
+		}
+		if r.Match == "" {
+			add("needs a match pattern")
+		} else if _, err := path.Match(r.Match, ""); err != nil {
+			add("%q is not a valid glob", r.Match)
+		}
+		switch r.Action {
+		case "", ruleTrack, ruleIgnore:
+		default:
+			add("action %q is not one of %q, %q", r.Action, ruleTrack, ruleIgnore)
+		}
+		switch r.Mode {
+		case "", modeText, modeBinary:
+		default:
+			add("mode %q is not one of %q, %q", r.Mode, modeText, modeBinary)
+		}
+		if _, ok := lookupDiffDriver(r.Diff); !ok && r.Diff != "" && r.Diff != diffLines && r.Diff != diffNone {
+			add("diff %q is not one of %s, and no gitnot-%s is on PATH", r.Diff, strings.Join(driverNames(), ", "), r.Diff)
+		}
+		if _, ok := lookupExtractor(r.Extract); !ok && r.Extract != "" {
+			add("extract %q is not registered, and no gitnot-%s is on PATH", r.Extract, r.Extract)
+		}
+		if r.MaxSizeKB < 0 {
+			add("max_size_kb must not be negative")
+		}
+		switch r.Changelog {
+		case "", changelogFull, changelogSummary:
+		default:
+			add("changelog %q is not one of %q, %q", r.Changelog, changelogFull, changelogSummary)
+		}
+	}
+	return issues
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTranslatedRulesMatchOldShape(t *testing.T) {
+	setupTestDir(t)
+
+	cfg := Config{
+		Extensions:      []string{".md", ".txt"},
+		IncludePatterns: []string{"Makefile"},
+		IgnorePatterns:  []string{"*.tmp", "build/*"},
+	}
+	filter := cfg.scanFilters()
+	size := func() int64 { return 0 }
+	cases := map[string]bool{
+		"notes.md":       true,
+		"NOTES.MD":       true,
+		"Makefile":       true,
+		"draft.tmp":      false,
+		"build/out.md":   false,
+		"picture.png":    false,
+		"sub/readme.txt": true,
+	}
+	for p, want := range cases {
+		if got := filter.tracks(p, size); got != want {
+			t.Errorf("tracks(%q) = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestExplicitRules(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "text")
+	createTestFile(t, "big.md", strings.Repeat("x", 3000))
+	createTestFile(t, "cover.svg", "<svg/>")
+	createTestFile(t, "data.csv", "a,b\n1,2\n")
+	createTestFile(t, "private/secret.md", "shh")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.Rules = []Rule{
+		{Match: "private/*", Action: ruleIgnore},
+		{Match: "*.svg", Mode: modeBinary},
+		{Match: "*.csv", Diff: diffNone},
+		{Match: "*.md", MaxSizeKB: 2},
+	}
+	saveJSON(configFile, cfg)
+
+	files, err := getAllTextFiles(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.md", "cover.svg", "data.csv"}; !reflect.DeepEqual(files, want) {
+		t.Fatalf("Expected %v, got %v", want, files)
+	}
+
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	createTestFile(t, "cover.svg", "<svg><circle/></svg>")
+	createTestFile(t, "data.csv", "a,b\n1,3\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	svgLog, _ := os.ReadFile(filepath.Join(changelogDir, "cover.svg.log"))
+	if !strings.Contains(string(svgLog), "Binary file changed") || strings.Contains(string(svgLog), "circle") {
+		t.Errorf("Binary rule should not diff content:\n%s", svgLog)
+	}
+	csvLog, _ := os.ReadFile(filepath.Join(changelogDir, "data.csv.log"))
+	if !strings.Contains(string(csvLog), "diff turned off") {
+		t.Errorf("diff none rule ignored:\n%s", csvLog)
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	_, issues := parseConfig([]byte(`{"rules": [
+		{"match": "*.md"},
+		{"match": "*.bin", "mode": "blob", "diff": "word"},
+		{"action": "skip", "max_size": 3},
+		{"match": "todo.md", "changelog": "brief"}
+	]}`))
+	var msgs []string
+	for _, i := range issues {
+		msgs = append(msgs, i.String())
+	}
+	all := strings.Join(msgs, "\n")
+	for _, want := range []string{
+		`rules[1]: mode "blob"`,
+		`rules[1]: diff "word"`,
+		`rules[2]: needs a match pattern`,
+		`rules[2]: action "skip"`,
+		`rules[2].max_size: unknown key, ignored (did you mean "max_size_kb"?)`,
+		`rules[3]: changelog "brief"`,
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("Missing issue %q in:\n%s", want, all)
+		}
+	}
+	if strings.Contains(all, "rules[0]") || strings.Contains(all, "no extensions") {
+		t.Errorf("Unexpected issues:\n%s", all)
+	}
+}
+
+func TestSummaryChangelog(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "todo.md", "- buy milk\n- call mum\n")
+	createTestFile(t, "ch1.md", "Once upon a time.\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, configFile, `{"rules": [{"match": "todo.md", "changelog": "summary"}, {"match": "*.md"}]}`)
+	createTestFile(t, "todo.md", "- call mum\n- write\n- edit\n")
+	createTestFile(t, "ch1.md", "Once upon a midnight.\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	todo, _ := os.ReadFile(filepath.Join(changelogDir, "todo.md.log"))
+	if !strings.Contains(string(todo), "📄 Changed (+2/-1 lines)") || strings.Contains(string(todo), "buy milk") {
+		t.Errorf("Expected only a summary line for todo.md:\n%s", todo)
+	}
+	ch1, _ := os.ReadFile(filepath.Join(changelogDir, "ch1.md.log"))
+	if !strings.Contains(string(ch1), "midnight") {
+		t.Errorf("Other files should keep their full diff:\n%s", ch1)
+	}
+	if m, _ := loadManifest(0.1); len(m.Changes) != 2 {
+		t.Errorf("Summarized files should still record their metrics, got %+v", m.Changes)
+	}
+}
+
+func TestTrackedList(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "outline.md", "outline\n")
+	createTestFile(t, filepath.Join("chapters", "01.md"), "one\n")
+	createTestFile(t, filepath.Join("chapters", "cover.png"), "png")
+	createTestFile(t, "scratch.md", "not curated\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, trackedListFile, "# curated\noutline.md\nchapters/*\n")
+
+	files, err := scanTextFiles(".", loadConfig().scanFilters())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(slashPaths(files), " ")
+	if got != "chapters/01.md chapters/cover.png outline.md" {
+		t.Errorf("Expected exactly the listed files, whatever their extension, got %q", got)
+	}
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- Scrub ---
+//
+// `gitnot scrub` reads back every stored object and every snapshot file and
+// checks it against the hash it was recorded under, so bit rot is found
+// before a restore needs the damaged copy. Damaged loose objects and
+// snapshot files are moved to .gitnot/quarantine/<timestamp>/. Each side
+// is recovered from the other: a snapshot file is rewritten from its intact
+// object, and a loose object is stored again from an intact snapshot file.
+// With a storage backend configured, --repair fetches intact copies of
+// damaged objects. Deltas left without a readable base are listed as
+// unrecoverable. `gitnot watch` runs a scrub while idle once every
+// scrub_every_hours.
+
+// scrubDamage is one damaged copy found by a scrub.
+type scrubDamage struct {
+	Hash   string
+	Where  string   // the file holding it, or "pack <name>"
+	Files  []string // "path@vX.Y" that recorded this content
+	Moved  bool     // quarantined
+	Base   bool     // intact itself, but its delta base can't be read
+	Reason string
+}
+
+// scrubResult is what a scrub found; its summary is kept in scrub.json.
+type scrubResult struct {
+	Time     time.Time     `json:"time"`
+	Objects  int           `json:"objects"`
+	Snapshot int           `json:"snapshot"`
+	Damaged  []scrubDamage `json:"damaged,omitempty"`
+	Rebuilt  []string      `json:"rebuilt,omitempty"`  // snapshot files rewritten from objects
+	Restored []string      `json:"restored,omitempty"` // objects stored again from the snapshot
+	Repaired []string      `json:"repaired,omitempty"` // objects fetched from storage
+}
+
+// recovered is how many damaged copies the scrub put back.
+func (r scrubResult) recovered() int {
+	return len(r.Rebuilt) + len(r.Restored) + len(r.Repaired)
+}
+
+// recordedAt maps each content hash to the files and versions that hold it.
+func recordedAt() map[string][]string {
+	out := map[string][]string{}
+	_ = eachManifest(func(m Manifest) error {
+		for _, rel := range sortedKeys(m.Files) {
+			h := m.Files[rel]
+			out[h] = append(out[h], fmt.Sprintf("%s@v%.1f", filepath.ToSlash(rel), m.Version))
+		}
+		return nil
+	})
+	return out
+}
+
+// storedCopy is one stored copy of some content: a loose or packed object,
+// or a snapshot file.
+type storedCopy struct {
+	Hash  string
+	Path  string // the file, or "pack <name>"
+	Rel   string // the tracked file a snapshot copy belongs to; "" for objects
+	Delta bool
+}
+
+func (c storedCopy) packed() bool {
+	return strings.HasPrefix(c.Path, "pack ")
+}
+
+// storedCopies lists the stored objects, then the snapshot files. With
+// paths, only content recorded for files under one of them is listed.
+func storedCopies(paths []string) ([]storedCopy, error) {
+	wanted := func(rel string) bool {
+		if len(paths) == 0 {
+			return true
+		}
+		for _, p := range paths {
+			if p == "." || within(rel, p) {
+				return true
+			}
+		}
+		return false
+	}
+	var keep map[string]bool // hashes recorded for wanted files; nil keeps all
+	if len(paths) > 0 {
+		keep = map[string]bool{}
+		seen := map[string]bool{} // folder and tree hash already gone through
+		err := eachManifest(func(m Manifest) error {
+			// a folder whose tree hash an earlier version had holds no new hashes
+			tree, fresh := manifestTree(m), false
+			for _, p := range paths {
+				h, ok := tree[filepath.ToSlash(p)]
+				key := filepath.ToSlash(p) + "\x00" + h
+				if !ok || !seen[key] {
+					fresh = true
+				}
+				seen[key] = seen[key] || ok
+			}
+			if !fresh {
+				return nil
+			}
+			for rel, h := range m.Files {
+				if wanted(rel) {
+					keep[h] = true
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var out []storedCopy
+	loose, err := listLooseObjects()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	isLoose := map[string]bool{}
+	for _, o := range loose {
+		isLoose[o.Hash] = true
+		if keep == nil || keep[o.Hash] {
+			out = append(out, storedCopy{Hash: o.Hash, Path: o.Path, Delta: o.Delta})
+		}
+	}
+	packed := packIndex()
+	for _, h := range sortedKeys(packed) {
+		if !isLoose[h] && (keep == nil || keep[h]) {
+			out = append(out, storedCopy{Hash: h, Path: "pack " + packed[h].Pack, Delta: packed[h].Delta})
+		}
+	}
+
+	committed := loadCommittedHashes()
+	for _, rel := range sortedKeys(committed) {
+		h := committed[rel]
+		snap := filepath.Join(snapshotDir, rel)
+		if !validObjectHash(h) || !wanted(rel) {
+			continue
+		}
+		if _, err := repo.FS.Stat(longPath(snap)); err != nil {
+			continue
+		}
+		out = append(out, storedCopy{Hash: h, Path: snap, Rel: rel})
+	}
+	return out, nil
+}
+
+// checkCopy reads c back and compares it with its hash. A delta whose base
+// is damaged is not blamed for it: the base is checked, and reported, on its
+// own.
+func checkCopy(c storedCopy) error {
+	if c.Rel != "" || !c.Delta && !c.packed() {
+		if got := hashFile(c.Path); got != c.Hash {
+			return &corruptError{Hash: c.Hash, Got: got}
+		}
+		return nil
+	}
+	_, err := readObject(c.Hash)
+	var ce *corruptError
+	if errors.As(err, &ce) && ce.Hash != c.Hash {
+		return nil
+	}
+	return err
+}
+
+// checkCopies checks copies on jobs workers (0 for the default), showing
+// progress, and returns what is wrong with each, nil when intact.
+func checkCopies(copies []storedCopy, jobs int) []error {
+	if jobs <= 0 {
+		jobs = defaultJobs()
+	}
+	errs := make([]error, len(copies))
+	p := newProgress("Checking stored copies", len(copies))
+	parallelEach(len(copies), jobs, func(i int) {
+		errs[i] = checkCopy(copies[i])
+		p.add(1)
+	})
+	p.finish()
+	return errs
+}
+
+// quarantine moves p below dir, keeping its path inside the store.
+func quarantine(dir, p string) error {
+	rel, err := filepath.Rel(gitnotDir, p)
+	if err != nil {
+		return err
+	}
+	dst := filepath.Join(dir, rel)
+	if err := safeMkdirAllForFile(dst); err != nil {
+		return err
+	}
+	return repo.FS.Rename(p, dst)
+}
+
+type scrubOptions struct {
+	Repair bool     // fetch intact objects from storage
+	Paths  []string // only content recorded for files under these
+	Jobs   int      // files read at once; 0 for the default
+}
+
+// scrubStore checks every object and snapshot file, quarantining what is
+// damaged, and with Repair fetches intact objects from storage. A scrub
+// limited to some paths is not saved: it doesn't stand in for the full one
+// scrub_every_hours asks for.
+func scrubStore(opts scrubOptions) (scrubResult, error) {
+	res := scrubResult{Time: repo.Now()}
+	qdir := filepath.Join(quarantineDir, res.Time.Format("20060102-150405.000"))
+	where := recordedAt()
+
+	copies, err := storedCopies(opts.Paths)
+	if err != nil {
+		return res, err
+	}
+	snapshotOf := map[string]string{} // hash -> a snapshot file recorded with it
+	for _, c := range copies {
+		if c.Rel != "" && snapshotOf[c.Hash] == "" {
+			snapshotOf[c.Hash] = c.Path
+		}
+	}
+	var deltas []storedCopy // checked again once damaged bases are gone
+	asked := false
+	// moving copies aside is destructive: a protected project asks for its
+	// passphrase once, before the first one
+	quarantineCopy := func(p string) error {
+		if !asked {
+			if err := requirePassphrase("quarantine damaged copies"); err != nil {
+				return err
+			}
+			asked = true
+		}
+		if err := quarantine(qdir, p); err != nil {
+			return fmt.Errorf("quarantining %s: %w", p, err)
+		}
+		return nil
+	}
+	// objects come first, so a damaged object is quarantined before a
+	// snapshot file could be rebuilt from it
+	for i, err := range checkCopies(copies, opts.Jobs) {
+		c := copies[i]
+		if c.Rel != "" {
+			res.Snapshot++
+		} else {
+			res.Objects++
+		}
+		if err == nil {
+			if c.Delta {
+				deltas = append(deltas, c)
+			}
+			continue
+		}
+		if c.Rel == "" {
+			d := scrubDamage{Hash: c.Hash, Where: c.Path, Files: where[c.Hash], Reason: err.Error()}
+			if !c.packed() {
+				if err := quarantineCopy(c.Path); err != nil {
+					return res, err
+				}
+				d.Moved = true
+				if snap := snapshotOf[c.Hash]; snap != "" && hashFile(snap) == c.Hash {
+					if err := storeObject(snap, c.Hash); err != nil {
+						return res, fmt.Errorf("storing %s again from %s: %w", c.Hash, snap, err)
+					}
+					res.Restored = append(res.Restored, c.Hash)
+				}
+			}
+			res.Damaged = append(res.Damaged, d)
+			continue
+		}
+		if err := quarantineCopy(c.Path); err != nil {
+			return res, err
+		}
+		res.Damaged = append(res.Damaged, scrubDamage{Hash: c.Hash, Where: c.Path, Files: []string{filepath.ToSlash(c.Rel)},
+			Moved: true, Reason: err.Error()})
+		if b, err := readObject(c.Hash); err == nil {
+			if err := writeFileAtomic(longPath(c.Path), b, 0o644); err != nil {
+				return res, err
+			}
+			res.Rebuilt = append(res.Rebuilt, c.Rel)
+		}
+	}
+
+	// a delta is only as good as its base chain; once a damaged base is
+	// quarantined and nothing put it back, the delta can't be read either.
+	// An intact snapshot file stores it whole; --repair may still fetch it.
+	if len(res.Damaged) > res.recovered() {
+		for _, c := range deltas {
+			_, err := readObject(c.Hash)
+			if err == nil {
+				continue
+			}
+			res.Damaged = append(res.Damaged, scrubDamage{Hash: c.Hash, Where: c.Path, Files: where[c.Hash],
+				Base: true, Reason: err.Error()})
+			if snap := snapshotOf[c.Hash]; snap != "" {
+				if b, err := repo.FS.ReadFile(longPath(snap)); err == nil && contentHash(b) == c.Hash {
+					if err := writeFullObject(c.Hash, b); err != nil {
+						return res, err
+					}
+					res.Restored = append(res.Restored, c.Hash)
+				}
+			}
+		}
+	}
+
+	if opts.Repair {
+		for _, d := range res.Damaged {
+			if within(d.Where, snapshotDir) {
+				continue
+			}
+			if _, err := readObject(d.Hash); err == nil {
+				continue // already stored again from the snapshot
+			}
+			b, err := fetchRemote(d.Hash)
+			if err != nil {
+				continue
+			}
+			dst := objectPath(d.Hash)
+			if err := safeMkdirAllForFile(dst); err != nil {
+				return res, err
+			}
+			if err := writeFileAtomic(dst, b, 0o644); err != nil {
+				return res, err
+			}
+			res.Repaired = append(res.Repaired, d.Hash)
+		}
+	}
+	if len(opts.Paths) > 0 {
+		return res, nil
+	}
+	return res, saveJSON(scrubFile, res)
+}
+
+func printScrub(res scrubResult, repair bool) {
+	fmt.Printf("🧽 Checked %d objects and %d snapshot files\n", res.Objects, res.Snapshot)
+	if len(res.Damaged) == 0 {
+		fmt.Println("✅ Everything matches its recorded hash")
+		return
+	}
+	fmt.Printf("❌ %d damaged:\n", len(res.Damaged))
+	for _, d := range res.Damaged {
+		fmt.Printf("  • %s (%s)\n", d.Where, d.Reason)
+		if len(d.Files) > 0 {
+			fmt.Printf("    holds %s\n", strings.Join(preview(d.Files, 4), ", "))
+		}
+		switch {
+		case d.Base:
+			fmt.Println("    a delta whose base is damaged; restores fail until the base is recovered")
+		case !d.Moved:
+			fmt.Println("    still in its pack; restores fail until an intact copy is stored")
+		}
+	}
+	if moved := countMoved(res.Damaged); moved > 0 {
+		fmt.Printf("📦 Moved %d damaged files to %s\n", moved, quarantineDir)
+	}
+	if len(res.Rebuilt) > 0 {
+		fmt.Printf("🔧 Rebuilt %d snapshot files from their stored objects\n", len(res.Rebuilt))
+	}
+	if len(res.Restored) > 0 {
+		fmt.Printf("🔧 Stored %d objects again from their snapshot files\n", len(res.Restored))
+	}
+	if len(res.Repaired) > 0 {
+		fmt.Printf("🛟 Fetched %d intact objects from storage\n", len(res.Repaired))
+	}
+	if storage := loadConfig().Storage; storage == "" {
+		fmt.Println("💡 Set \"storage\" to a backend that keeps every object to be able to recover damaged ones")
+	} else if !repair {
+		fmt.Printf("💡 Run 'gitnot scrub --repair' to fetch intact copies from %s\n", storage)
+	}
+}
+
+func countMoved(ds []scrubDamage) int {
+	n := 0
+	for _, d := range ds {
+		if d.Moved {
+			n++
+		}
+	}
+	return n
+}
+
+// scrubDue reports whether watch should scrub: the interval is set and the
+// last scrub is older than it.
+func scrubDue(cfg Config, now time.Time) bool {
+	if cfg.ScrubEveryHours <= 0 {
+		return false
+	}
+	var last scrubResult
+	if err := loadJSON(scrubFile, &last); err != nil {
+		return true
+	}
+	return now.Sub(last.Time) >= time.Duration(cfg.ScrubEveryHours)*time.Hour
+}
+
+// backgroundScrub is the scrub watch runs; it only speaks up about damage.
+func backgroundScrub() {
+	release, err := acquireLock()
+	if err != nil {
+		return // an update is running; try again on the next quiet tick
+	}
+	defer release()
+	if !passphraseAvailable() {
+		// nobody is there to give the passphrase quarantining needs, so
+		// only check, and leave the rest to `gitnot scrub`
+		_, damaged, err := verifyStore(nil, 0)
+		if err != nil {
+			fmt.Printf("⚠️  Warning: Scrub failed: %v\n", err)
+			return
+		}
+		if len(damaged) > 0 {
+			fmt.Printf("⚠️  Warning: %d damaged stored copies; run 'gitnot scrub' to quarantine them\n", len(damaged))
+		}
+		_ = saveJSON(scrubFile, scrubResult{Time: repo.Now(), Damaged: damaged})
+		return
+	}
+	res, err := scrubStore(scrubOptions{})
+	if err != nil {
+		fmt.Printf("⚠️  Warning: Scrub failed: %v\n", err)
+		return
+	}
+	if len(res.Damaged) > 0 {
+		printScrub(res, false)
+	}
+}
+
+func runScrub(args []string) error {
+	fs := flag.NewFlagSet("scrub", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "fetch intact copies of damaged objects from the storage backend")
+	var paths listFlag
+	fs.Var(&paths, "paths", "only check content recorded for files under this path (repeatable)")
+	jobs := fs.Int("jobs", defaultJobs(), "how many files to read at once")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	limit, err := checkPaths(paths)
+	if err != nil {
+		return err
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+	res, err := scrubStore(scrubOptions{Repair: *repair, Paths: limit, Jobs: *jobs})
+	if err != nil {
+		return err
+	}
+	printScrub(res, *repair)
+	if n := len(res.Damaged) - res.recovered(); n > 0 {
+		return fmt.Errorf("%d damaged copies could not be recovered", n)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestScrubQuarantinesDamage(t *testing.T) {
+	setupTestDir(t)
+	mem := memStorage{}
+	registerStorage("scrubmem", mem)
+	t.Cleanup(func() { delete(registeredBackends, "scrubmem") })
+
+	createTestFile(t, "ch1.md", "first\n")
+	createTestFile(t, "ch2.md", "other\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.Storage = "scrubmem"
+	saveJSON(configFile, cfg)
+	createTestFile(t, "ch1.md", "second\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	res, err := scrubStore(scrubOptions{})
+	if err != nil || len(res.Damaged) != 0 || res.Objects == 0 || res.Snapshot != 2 {
+		t.Fatalf("Expected a clean scrub, got %+v, %v", res, err)
+	}
+
+	second := contentHash([]byte("second\n"))
+	os.WriteFile(objectPath(second), []byte("secoNd\n"), 0o644)
+	os.WriteFile(filepath.Join(snapshotDir, "ch2.md"), []byte("rot\n"), 0o644)
+	res, err = scrubStore(scrubOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Damaged) != 2 {
+		t.Fatalf("Expected the object and the snapshot file to be reported, got %+v", res.Damaged)
+	}
+	if d := res.Damaged[0]; d.Hash != second || !d.Moved || len(d.Files) != 1 || d.Files[0] != "ch1.md@v0.1" {
+		t.Errorf("Unexpected object damage %+v", d)
+	}
+	if !slices.Equal(res.Restored, []string{second}) {
+		t.Errorf("Expected the object stored again from the snapshot, got %v", res.Restored)
+	}
+	if b, err := readObject(second); err != nil || string(b) != "second\n" {
+		t.Errorf("Expected the object stored again from the snapshot, got %q, %v", b, err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(snapshotDir, "ch2.md")); string(b) != "other\n" {
+		t.Errorf("Expected the snapshot file rebuilt from its object, got %q", b)
+	}
+	entries, _ := os.ReadDir(quarantineDir)
+	if len(entries) != 1 {
+		t.Errorf("Expected one quarantine folder, got %v", entries)
+	}
+
+	// Damage the object and its snapshot file; --repair brings it back
+	// from storage.
+	os.WriteFile(objectPath(second), []byte("garbage"), 0o644)
+	os.WriteFile(filepath.Join(snapshotDir, "ch1.md"), []byte("rot\n"), 0o644)
+	res, err = scrubStore(scrubOptions{Repair: true})
+	if err != nil || len(res.Repaired) != 1 {
+		t.Fatalf("Expected the object fetched from storage, got %+v, %v", res, err)
+	}
+	if b, err := readObject(second); err != nil || string(b) != "second\n" {
+		t.Errorf("Expected the repaired object, got %q, %v", b, err)
+	}
+}
+
+func TestScrubListsDeltasWithDamagedBase(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "book.md", longText(-1))
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.DeltaStorage = true
+	saveJSON(configFile, cfg)
+	var hashes []string
+	for v := 1; v <= 2; v++ {
+		createTestFile(t, "book.md", longText(v))
+		if err := updateGitnot(); err != nil {
+			t.Fatalf("updateGitnot failed: %v", err)
+		}
+		hashes = append(hashes, contentHash([]byte(longText(v))))
+	}
+	for _, h := range hashes {
+		if _, err := os.Stat(deltaPath(h)); err != nil {
+			t.Fatalf("Expected %s stored as a delta: %v", h, err)
+		}
+	}
+
+	base := contentHash([]byte(longText(-1)))
+	os.WriteFile(objectPath(base), []byte("rot\n"), 0o644)
+	res, err := scrubStore(scrubOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var orphaned []string
+	for _, d := range res.Damaged {
+		if d.Base {
+			orphaned = append(orphaned, d.Hash)
+		}
+	}
+	slices.Sort(orphaned)
+	if !slices.Equal(orphaned, slices.Sorted(slices.Values(hashes))) {
+		t.Errorf("Expected both deltas listed as unreadable, got %+v", res.Damaged)
+	}
+	// the newest delta is in the snapshot, so it is stored whole again; the
+	// one in between is lost with its base
+	if !slices.Equal(res.Restored, hashes[1:]) {
+		t.Errorf("Expected only the snapshot's content stored again, got %v", res.Restored)
+	}
+	if n := len(res.Damaged) - res.recovered(); n != 2 {
+		t.Errorf("Expected the base and one delta unrecovered, got %d", n)
+	}
+	if b, err := readObject(hashes[1]); err != nil || string(b) != longText(2) {
+		t.Errorf("Expected the newest version readable, got %v", err)
+	}
+}
+
+func TestScrubDue(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.md", "a\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := Config{ScrubEveryHours: 24}
+	if scrubDue(Config{}, time.Now()) {
+		t.Error("Scrub should not be due without scrub_every_hours")
+	}
+	if !scrubDue(cfg, time.Now()) {
+		t.Error("Scrub should be due when none has run")
+	}
+	saveJSON(scrubFile, scrubResult{Time: time.Now().Add(-time.Hour)})
+	if scrubDue(cfg, time.Now()) {
+		t.Error("Scrub should not be due an hour after the last one")
+	}
+	if !scrubDue(cfg, time.Now().Add(24*time.Hour)) {
+		t.Error("Scrub should be due a day later")
+	}
+}
+
+func TestRecordedAtIsOrdered(t *testing.T) {
+	setupTestDir(t)
+
+	for _, name := range []string{"c.txt", "a.txt", "b.txt", "d/e.txt"} {
+		createTestFile(t, name, "same\n")
+	}
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	want := []string{"a.txt@v0.0", "b.txt@v0.0", "c.txt@v0.0", "d/e.txt@v0.0"}
+	for range 5 {
+		if got := recordedAt()[contentHash([]byte("same\n"))]; !slices.Equal(got, want) {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- Sealing ---
+//
+// `gitnot seal` packs the whole store of a finished project — every stored
+// version, changelog and manifest — into one gzipped tar next to the files.
+// The archive starts with gitnot-seal.json, listing the SHA-256 of every
+// file in it, and is read back and checked before seal reports success;
+// with --remove the live .gitnot folder is deleted afterwards. `gitnot
+// unseal` checks the archive again and only then puts the store back, under
+// the folder name it was sealed from (see GITNOT_DIR).
+
+const sealIndexName = "gitnot-seal.json"
+
+// sealIndex is the first entry of a sealed archive.
+type sealIndex struct {
+	Format  int               `json:"format"`
+	Version float64           `json:"version"`
+	Sealed  time.Time         `json:"sealed"`
+	Store   string            `json:"store,omitempty"` // store folder name; .gitnot when empty
+	Files   map[string]string `json:"files"`           // archive path → SHA-256
+}
+
+// storeDir is the folder the archive's files live under.
+func (idx sealIndex) storeDir() (string, error) {
+	if idx.Store == "" {
+		return defaultStoreDir, nil
+	}
+	if idx.Store == "." || idx.Store == ".." || strings.ContainsAny(idx.Store, `/\`) {
+		return "", fmt.Errorf("archive names an invalid store folder %q", idx.Store)
+	}
+	return idx.Store, nil
+}
+
+// defaultSealPath names the archive after the project folder.
+func defaultSealPath() string {
+	name := "project"
+	if wd, err := os.Getwd(); err == nil && filepath.Base(wd) != string(filepath.Separator) {
+		name = filepath.Base(wd)
+	}
+	return name + ".gitnot.tar.gz"
+}
+
+// sealedFiles lists the store files to archive, leaving out the lock, the
+// watch socket and the task queue, which only mean something while gitnot
+// runs.
+func sealedFiles() ([]string, error) {
+	var files []string
+	err := walkStore(gitnotDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p == filepath.FromSlash(tasksDir) {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if p == filepath.FromSlash(lockFile) || p == apiSocket {
+			return nil
+		}
+		files = append(files, p)
+		return nil
+	})
+	return files, err
+}
+
+func sha256File(p string) (string, error) {
+	f, err := os.Open(longPath(p))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// sealStore writes the archive to out.
+func sealStore(out string) (sealIndex, error) {
+	v, err := readVersion()
+	if err != nil {
+		return sealIndex{}, err
+	}
+	files, err := sealedFiles()
+	if err != nil {
+		return sealIndex{}, err
+	}
+	idx := sealIndex{Format: 1, Version: v, Sealed: repo.Now().UTC(), Store: gitnotDir, Files: map[string]string{}}
+	for _, p := range files {
+		sum, err := sha256File(p)
+		if err != nil {
+			return idx, err
+		}
+		idx.Files[filepath.ToSlash(p)] = sum
+	}
+	indexB, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return idx, err
+	}
+
+	tmp := out + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return idx, err
+	}
+	err = writeSealArchive(f, indexB, files)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return idx, err
+	}
+	return idx, os.Rename(tmp, out)
+}
+
+func writeSealArchive(w io.Writer, indexB []byte, files []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	hdr := &tar.Header{Name: sealIndexName, Mode: 0o644, Size: int64(len(indexB)), ModTime: repo.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := tw.Write(indexB); err != nil {
+		return err
+	}
+	for _, p := range files {
+		if err := addFileToTar(tw, p); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readSeal checks every file in the archive against the index. With dest
+// set, files are also written below dest.
+func readSeal(archive, dest string) (sealIndex, error) {
+	var idx sealIndex
+	f, err := os.Open(archive)
+	if err != nil {
+		return idx, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return idx, fmt.Errorf("%s is not a sealed gitnot archive: %w", archive, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != sealIndexName {
+		return idx, fmt.Errorf("%s is not a sealed gitnot archive (no %s)", archive, sealIndexName)
+	}
+	if err := json.NewDecoder(tr).Decode(&idx); err != nil {
+		return idx, fmt.Errorf("reading %s: %w", sealIndexName, err)
+	}
+	store, err := idx.storeDir()
+	if err != nil {
+		return idx, err
+	}
+	seen := map[string]bool{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return idx, err
+		}
+		name := hdr.Name
+		want, ok := idx.Files[name]
+		if !ok || seen[name] || path.Clean(name) != name || !strings.HasPrefix(name, store+"/") {
+			return idx, fmt.Errorf("archive contains unexpected file %q", name)
+		}
+		seen[name] = true
+		h := sha256.New()
+		var w io.Writer = h
+		var out *os.File
+		if dest != "" {
+			target := filepath.Join(dest, filepath.FromSlash(name))
+			if err := safeMkdirAllForFile(target); err != nil {
+				return idx, err
+			}
+			if out, err = os.Create(longPath(target)); err != nil {
+				return idx, err
+			}
+			w = io.MultiWriter(h, out)
+		}
+		_, err = io.Copy(w, tr)
+		if out != nil {
+			if cerr := out.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			return idx, err
+		}
+		if hex.EncodeToString(h.Sum(nil)) != want {
+			return idx, fmt.Errorf("%s is damaged: %s does not match its checksum", archive, name)
+		}
+	}
+	if len(seen) != len(idx.Files) {
+		return idx, fmt.Errorf("%s is incomplete: %d of %d files present", archive, len(seen), len(idx.Files))
+	}
+	return idx, nil
+}
+
+// unsealStore restores the store from archive, under the folder name it was
+// sealed from, and switches to it. Files are unpacked next to the project
+// first and only moved into place once all of them check out.
+func unsealStore(archive string) (sealIndex, error) {
+	if _, err := repo.FS.Stat(gitnotDir); err == nil {
+		return sealIndex{}, fmt.Errorf("%s already exists; this project is not sealed", gitnotDir)
+	}
+	staging := gitnotDir + ".unseal"
+	if err := os.RemoveAll(staging); err != nil {
+		return sealIndex{}, err
+	}
+	idx, err := readSeal(archive, staging)
+	if err != nil {
+		os.RemoveAll(staging)
+		return idx, err
+	}
+	store, _ := idx.storeDir() // checked by readSeal
+	if _, err := os.Stat(store); err == nil {
+		os.RemoveAll(staging)
+		return idx, fmt.Errorf("%s already exists; this project is not sealed", store)
+	}
+	if err := os.Rename(filepath.Join(staging, store), store); err != nil {
+		os.RemoveAll(staging)
+		return idx, err
+	}
+	useStoreDir(store)
+	return idx, os.RemoveAll(staging)
+}
+
+func runSeal(args []string) error {
+	fs := flag.NewFlagSet("seal", flag.ExitOnError)
+	out := fs.String("out", "", "archive to write (default: <folder>.gitnot.tar.gz)")
+	remove := fs.Bool("remove", false, "delete .gitnot once the archive is written and checked")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if *out == "" {
+		*out = defaultSealPath()
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+	if *remove {
+		if err := requirePassphrase("seal --remove"); err != nil {
+			return err
+		}
+		st, err := pendingStatus()
+		if err != nil {
+			return err
+		}
+		if n := len(st.Added) + len(st.Modified) + len(st.Deleted); n > 0 {
+			return fmt.Errorf("%d files have changes that are not recorded yet; run gitnot first, or seal without --remove", n)
+		}
+	}
+	idx, err := sealStore(*out)
+	if err != nil {
+		return err
+	}
+	if _, err := readSeal(*out, ""); err != nil {
+		return fmt.Errorf("checking the archive: %w", err)
+	}
+	fi, _ := os.Stat(*out)
+	fmt.Printf("🧊 Sealed v%.1f (%d files) into %s (%s)\n", idx.Version, len(idx.Files), *out, formatBytes(fi.Size()))
+	if !*remove {
+		return nil
+	}
+	if err := repo.FS.RemoveAll(gitnotDir); err != nil {
+		return err
+	}
+	fmt.Printf("🗑  Removed %s; run 'gitnot unseal %s' to reopen the project\n", gitnotDir, *out)
+	return nil
+}
+
+func runUnseal(args []string) error {
+	fs := flag.NewFlagSet("unseal", flag.ExitOnError)
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	archive := defaultSealPath()
+	switch len(rest) {
+	case 0:
+	case 1:
+		archive = rest[0]
+	default:
+		return fmt.Errorf("usage: gitnot unseal [archive]")
+	}
+	idx, err := unsealStore(archive)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🔓 Unsealed v%.1f (%d files, sealed %s)\n", idx.Version, len(idx.Files), idx.Sealed.Local().Format("2006-01-02"))
+	if checkNotArchived() != nil {
+		fmt.Println("🔒 Archive mode is still on; run 'gitnot archive-mode off' to record new versions")
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSealAndUnseal(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "book.md", "draft\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "book.md", "final\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	createTestFile(t, "book.md", "unrecorded\n")
+	if err := runSeal([]string{"--remove", "--out", "book.tgz"}); err == nil {
+		t.Fatal("Expected --remove to refuse while changes are unrecorded")
+	}
+	createTestFile(t, "book.md", "final\n")
+	if err := runSeal([]string{"--remove", "--out", "book.tgz"}); err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+	if _, err := os.Stat(gitnotDir); !os.IsNotExist(err) {
+		t.Fatal("Expected .gitnot to be removed")
+	}
+
+	idx, err := unsealStore("book.tgz")
+	if err != nil {
+		t.Fatalf("unseal failed: %v", err)
+	}
+	if idx.Version != 0.1 {
+		t.Errorf("Expected v0.1 in the index, got %v", idx.Version)
+	}
+	m, err := loadManifest(0.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := contentAt("book.md", m); err != nil || string(b) != "draft\n" {
+		t.Errorf("Expected the old version back, got %q, %v", b, err)
+	}
+	if _, err := unsealStore("book.tgz"); err == nil {
+		t.Error("Expected unseal to refuse over an existing store")
+	}
+}
+
+func TestUnsealIntoCustomStoreDir(t *testing.T) {
+	setupTestDir(t)
+	t.Cleanup(func() { useStoreDir(defaultStoreDir) })
+	t.Setenv(storeDirEnv, ".history")
+	if err := locateStore(); err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, "book.md", "draft\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := runSeal([]string{"--remove", "--out", "book.tgz"}); err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+
+	// with the store gone, nothing names .history any more
+	os.Unsetenv(storeDirEnv)
+	if err := locateStore(); err != nil || gitnotDir != defaultStoreDir {
+		t.Fatalf("Expected the default store, got %s, %v", gitnotDir, err)
+	}
+	if err := runUnseal([]string{"book.tgz"}); err != nil {
+		t.Fatalf("unseal failed: %v", err)
+	}
+	if gitnotDir != ".history" {
+		t.Errorf("Expected the store unsealed as .history, got %s", gitnotDir)
+	}
+	if _, err := os.Stat(filepath.Join(".history", "version.txt")); err != nil {
+		t.Errorf("Expected .history back: %v", err)
+	}
+	if _, err := os.Stat(defaultStoreDir); err == nil {
+		t.Error("No .gitnot folder should be created")
+	}
+}
+
+func TestUnsealRejectsDamage(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, filepath.Join(gitnotDir, "version.txt"), "0.0")
+
+	write := func(name string, idx sealIndex) {
+		t.Helper()
+		b, _ := json.Marshal(idx)
+		var buf bytes.Buffer
+		if err := writeSealArchive(&buf, b, []string{versionFile}); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sum, _ := sha256File(versionFile)
+	write("good.tgz", sealIndex{Format: 1, Files: map[string]string{versionFile: sum}})
+	write("bad.tgz", sealIndex{Format: 1, Files: map[string]string{versionFile: strings.Repeat("0", 64)}})
+	write("short.tgz", sealIndex{Format: 1, Files: map[string]string{versionFile: sum, manifestDir + "/v0.0.json": sum}})
+
+	if _, err := readSeal("good.tgz", ""); err != nil {
+		t.Errorf("Expected the intact archive to check out: %v", err)
+	}
+	if _, err := readSeal("bad.tgz", ""); err == nil || !strings.Contains(err.Error(), "damaged") {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+	if _, err := readSeal("short.tgz", ""); err == nil || !strings.Contains(err.Error(), "incomplete") {
+		t.Errorf("Expected a missing file to be reported, got %v", err)
+	}
+
+	os.RemoveAll(gitnotDir)
+	if _, err := unsealStore("bad.tgz"); err == nil {
+		t.Fatal("Expected a damaged archive to be refused")
+	}
+	if _, err := os.Stat(gitnotDir); !os.IsNotExist(err) {
+		t.Error("A damaged archive must not leave a store behind")
+	}
+}
+
+func TestSealRemoveNeedsPassphrase(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "ch1.md", "One\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := setPassphrase("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(passphraseEnv, "wrong")
+	if err := runSeal([]string{"--remove", "--out", "book.tgz"}); err == nil {
+		t.Fatal("Expected a wrong passphrase to be refused")
+	}
+	if _, err := os.Stat(gitnotDir); err != nil {
+		t.Fatal("Expected the store to be kept")
+	}
+	t.Setenv(passphraseEnv, "correct horse")
+	if err := runSeal([]string{"--remove", "--out", "book.tgz"}); err != nil {
+		t.Fatalf("seal --remove failed: %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+const shadowSupported = false
+
+// openShared is a plain open: nothing needs sharing modes here.
+func openShared(p string) (*os.File, error) {
+	return os.Open(p)
+}
+
+func openFromShadow(string) (*os.File, error) {
+	return nil, errors.New("volume shadow copies are only available on Windows")
+}
+
+func releaseShadows() {}
@@ -0,0 +1,103 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const shadowSupported = true
+
+// openShared opens p for reading while allowing others to read, write,
+// delete and rename it, with backup semantics.
+func openShared(p string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(p)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: p, Err: err}
+	}
+	h, err := syscall.CreateFile(name, syscall.GENERIC_READ,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, syscall.FILE_ATTRIBUTE_NORMAL|syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: p, Err: err}
+	}
+	return os.NewFile(uintptr(h), p), nil
+}
+
+// shadowCopy is a volume shadow copy made for this run, or the reason none
+// could be made.
+type shadowCopy struct {
+	ID     string
+	Device string // \\?\GLOBALROOT\Device\HarddiskVolumeShadowCopyN
+	Err    error
+}
+
+var (
+	shadowMu sync.Mutex
+	shadows  = map[string]*shadowCopy{} // by volume, e.g. "C:"
+)
+
+// openFromShadow reads p from a shadow copy of its volume, making one the
+// first time a file on that volume is needed.
+func openFromShadow(p string) (*os.File, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return nil, err
+	}
+	vol := filepath.VolumeName(abs)
+	if vol == "" || strings.HasPrefix(vol, `\\`) {
+		return nil, errors.New("shadow copies need a local drive")
+	}
+	shadowMu.Lock()
+	s, ok := shadows[vol]
+	if !ok {
+		s = createShadow(vol)
+		shadows[vol] = s
+		if s.Err == nil {
+			fmt.Printf("📸 Made a shadow copy of %s to read files in use\n", vol)
+		}
+	}
+	shadowMu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return os.Open(s.Device + abs[len(vol):])
+}
+
+func createShadow(vol string) *shadowCopy {
+	script := fmt.Sprintf(`$r = (Get-WmiObject -List Win32_ShadowCopy).Create('%s\', 'ClientAccessible'); `+
+		`if ($r.ReturnValue -ne 0) { throw "Win32_ShadowCopy.Create returned $($r.ReturnValue)" }; `+
+		`$s = Get-WmiObject Win32_ShadowCopy | Where-Object { $_.ID -eq $r.ShadowID }; $s.ID; $s.DeviceObject`, vol)
+	out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).CombinedOutput()
+	if err != nil {
+		return &shadowCopy{Err: fmt.Errorf("making a shadow copy of %s (administrator rights needed): %s", vol, strings.TrimSpace(string(out)))}
+	}
+	lines := strings.Fields(string(out))
+	if len(lines) != 2 {
+		return &shadowCopy{Err: fmt.Errorf("making a shadow copy of %s: unexpected output %q", vol, out)}
+	}
+	return &shadowCopy{ID: lines[0], Device: lines[1]}
+}
+
+// releaseShadows deletes the shadow copies this run made.
+func releaseShadows() {
+	shadowMu.Lock()
+	defer shadowMu.Unlock()
+	for vol, s := range shadows {
+		delete(shadows, vol)
+		if s.Err != nil {
+			continue
+		}
+		script := fmt.Sprintf(`Get-WmiObject Win32_ShadowCopy | Where-Object { $_.ID -eq '%s' } | ForEach-Object { $_.Delete() }`, s.ID)
+		if out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).CombinedOutput(); err != nil {
+			fmt.Printf("⚠️  Warning: Could not delete the shadow copy of %s: %s\n", vol, strings.TrimSpace(string(out)))
+		}
+	}
+}
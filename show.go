@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/codinganovel/go-difflib/difflib"
+)
+
+// --- show & diff ---
+//
+// `gitnot show file@v1.2` prints a file as it was at a version, and
+// `gitnot diff [file...]` shows how working files differ from a version
+// (the current one by default). Both take --copy to put the output on the
+// clipboard instead, for pasting an old paragraph back into an editor.
+
+// clipboardFunc is swapped out in tests.
+var clipboardFunc = writeClipboard
+
+// splitFileVersion splits "file@v1.2" (or "file@<version id>") into path and
+// version. A trailing "@…" that isn't a version is part of the file name.
+func splitFileVersion(arg string) (rel, version string) {
+	i := strings.LastIndex(arg, "@")
+	if i <= 0 {
+		return arg, ""
+	}
+	if _, err := parseVersionArg(arg[i+1:]); err != nil {
+		return arg, ""
+	}
+	return arg[:i], arg[i+1:]
+}
+
+// emit prints out, or copies it to the clipboard when copyOut is set.
+func emit(out, what string, copyOut bool) error {
+	if !copyOut {
+		fmt.Print(out)
+		return nil
+	}
+	if err := clipboardFunc(out); err != nil {
+		return fmt.Errorf("copying to the clipboard: %w", err)
+	}
+	fmt.Printf("📋 Copied %s to the clipboard\n", what)
+	return nil
+}
+
+func runShow(args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	copyOut := fs.Bool("copy", false, "put the content on the clipboard instead of printing it")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 1 || len(rest) == 0 && !isTerminal() {
+		return fmt.Errorf("usage: gitnot show <file>[@version] [--copy]")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	var rel, version string
+	if len(rest) == 0 {
+		if rel, err = pickFile(); err != nil {
+			return err
+		}
+		if version, err = pickVersion(rel); err != nil {
+			return err
+		}
+	} else {
+		rel, version = splitFileVersion(rest[0])
+	}
+	v, err := parseVersionArg(version)
+	if err != nil {
+		return err
+	}
+	m, err := loadManifest(v)
+	if err != nil {
+		return err
+	}
+	content, err := contentAt(filepath.Clean(rel), m)
+	if err != nil {
+		return err
+	}
+	return emit(string(content), fmt.Sprintf("%s at v%.1f", rel, v), *copyOut)
+}
+
+// fileDiff renders a unified diff of rel between version m and the working
+// tree, or "" when they are the same.
+func fileDiff(rel string, m Manifest) (string, error) {
+	var old []byte
+	if _, ok := m.Files[rel]; ok {
+		var err error
+		if old, err = contentAt(rel, m); err != nil {
+			return "", err
+		}
+	}
+	cur, _ := os.ReadFile(longPath(rel)) // a deleted file diffs as empty
+	if bytes.Equal(old, cur) {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A: difflib.SplitLines(string(old)), B: difflib.SplitLines(string(cur)),
+		FromFile: fmt.Sprintf("%s@v%.1f", filepath.ToSlash(rel), m.Version), ToFile: filepath.ToSlash(rel),
+		Context: 3,
+	})
+}
+
+// diffAll diffs files (every file tracked at m or now, when empty) against m.
+func diffAll(m Manifest, files []string) (string, int, error) {
+	if len(files) == 0 {
+		seen := map[string]bool{}
+		for rel := range m.Files {
+			seen[rel] = true
+		}
+		current, err := getAllTextFiles(".")
+		if err != nil {
+			return "", 0, err
+		}
+		for _, rel := range current {
+			seen[rel] = true
+		}
+		files = sortedKeys(seen)
+	}
+	sort.Strings(files)
+	var b strings.Builder
+	n := 0
+	for _, rel := range files {
+		d, err := fileDiff(rel, m)
+		if err != nil {
+			return "", 0, err
+		}
+		if d != "" {
+			b.WriteString(d)
+			n++
+		}
+	}
+	return b.String(), n, nil
+}
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	version := fs.String("version", "", "compare with this version (default: current)")
+	copyOut := fs.Bool("copy", false, "put the diff on the clipboard instead of printing it")
+	pick := fs.Bool("pick", false, "choose the file from a list")
+	against := fs.String("against", "", "compare with another folder instead of a version")
+	stat := fs.Bool("stat", false, "with --against, only list the files that differ")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if *against != "" {
+		if *version != "" {
+			return fmt.Errorf("use either --version or --against")
+		}
+		files := make([]string, len(rest))
+		for i, f := range rest {
+			files[i] = filepath.Clean(f)
+		}
+		return diffAgainst(*against, files, *stat, *copyOut)
+	}
+	if *pick && len(rest) == 0 {
+		rel, err := pickFile()
+		if err != nil {
+			return err
+		}
+		rest = []string{rel}
+	}
+	v, err := parseVersionArg(*version)
+	if err != nil {
+		return err
+	}
+	m, err := loadManifest(v)
+	if err != nil {
+		return err
+	}
+	files := make([]string, len(rest))
+	for i, f := range rest {
+		files[i] = filepath.Clean(f)
+	}
+	out, n, err := diffAll(m, files)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Printf("✅ No differences from v%.1f\n", v)
+		return nil
+	}
+	return emit(out, fmt.Sprintf("the diff of %d file(s) against v%.1f", n, v), *copyOut)
+}
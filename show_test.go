@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestShowAndDiff(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "ch1.md", "The first paragraph.\n")
+	createTestFile(t, "me@home.md", "mail\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "ch1.md", "The rewritten paragraph.\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	createTestFile(t, "ch1.md", "The rewritten paragraph.\nAnd more.\n")
+
+	for arg, want := range map[string][2]string{
+		"ch1.md@v0.0":    {"ch1.md", "v0.0"},
+		"ch1.md@0.1":     {"ch1.md", "0.1"},
+		"ch1.md":         {"ch1.md", ""},
+		"me@home.md":     {"me@home.md", ""},
+		"me@home.md@0.0": {"me@home.md", "0.0"},
+	} {
+		if rel, v := splitFileVersion(arg); rel != want[0] || v != want[1] {
+			t.Errorf("splitFileVersion(%q) = %q, %q; want %q, %q", arg, rel, v, want[0], want[1])
+		}
+	}
+
+	var copied string
+	saved := clipboardFunc
+	t.Cleanup(func() { clipboardFunc = saved })
+	clipboardFunc = func(s string) error { copied = s; return nil }
+
+	if err := runShow([]string{"ch1.md@0.0", "--copy"}); err != nil {
+		t.Fatalf("show failed: %v", err)
+	}
+	if copied != "The first paragraph.\n" {
+		t.Errorf("Expected v0.0 content on the clipboard, got %q", copied)
+	}
+
+	if err := runDiff([]string{"--copy"}); err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	if !strings.Contains(copied, "--- ch1.md@v0.1") || !strings.Contains(copied, "+And more.") || strings.Contains(copied, "me@home") {
+		t.Errorf("Unexpected diff:\n%s", copied)
+	}
+
+	os.Remove("me@home.md")
+	m, _ := loadManifest(0.0)
+	out, n, err := diffAll(m, nil)
+	if err != nil || n != 2 || !strings.Contains(out, "-The first paragraph.") || !strings.Contains(out, "-mail") {
+		t.Errorf("Expected ch1.md and the deleted file against v0.0, got %d:\n%s", n, out)
+	}
+}
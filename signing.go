@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// --- Signed versions ---
+//
+// With a signing key set (`gitnot signing on ~/.ssh/id_ed25519`), every
+// manifest is signed with that SSH key when it is recorded. Public keys that
+// may sign live in .gitnot/allowed_signers, one "name ssh-ed25519 AAAA…" per
+// line, and `gitnot verify --signatures` checks every version against them.
+// The key path is per machine and kept in the user config. Only unencrypted
+// ed25519 keys in OpenSSH format are supported.
+
+// signatureNamespace is prepended to what gets signed, so a manifest
+// signature can't be replayed as a signature over anything else.
+const signatureNamespace = "gitnot-manifest-v1\n"
+
+// Signature is a manifest's signature and the fingerprint of the key that
+// made it.
+type Signature struct {
+	Key string `json:"key"` // SHA256:… as printed by ssh-keygen -l
+	Sig string `json:"sig"` // base64 ed25519 signature
+}
+
+const sshEd25519 = "ssh-ed25519"
+
+// sshString reads one length-prefixed field of the SSH wire format.
+func sshString(b []byte) (field, rest []byte, err error) {
+	if len(b) < 4 {
+		return nil, nil, errors.New("truncated key")
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint64(len(b)-4) < uint64(n) {
+		return nil, nil, errors.New("truncated key")
+	}
+	return b[4 : 4+n], b[4+n:], nil
+}
+
+func appendSSHString(b, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// sshPublicBlob is the wire encoding of an ed25519 public key.
+func sshPublicBlob(pub ed25519.PublicKey) []byte {
+	return appendSSHString(appendSSHString(nil, []byte(sshEd25519)), pub)
+}
+
+func fingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(sshPublicBlob(pub))
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// authorizedKey formats pub as an authorized_keys / allowed_signers key.
+func authorizedKey(pub ed25519.PublicKey) string {
+	return sshEd25519 + " " + base64.StdEncoding.EncodeToString(sshPublicBlob(pub))
+}
+
+// parsePublicKey reads "ssh-ed25519 AAAA…" (anything after the key is ignored).
+func parsePublicKey(fields []string) (ed25519.PublicKey, error) {
+	if len(fields) < 2 || fields[0] != sshEd25519 {
+		return nil, fmt.Errorf("only %s keys are supported", sshEd25519)
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	typ, rest, err := sshString(blob)
+	if err != nil || string(typ) != sshEd25519 {
+		return nil, errors.New("invalid key")
+	}
+	pub, _, err := sshString(rest)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid key")
+	}
+	return ed25519.PublicKey(pub), nil
+}
+
+// parsePrivateKey reads an unencrypted OpenSSH ed25519 private key.
+func parsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "OPENSSH PRIVATE KEY" {
+		return nil, errors.New("not an OpenSSH private key")
+	}
+	const magic = "openssh-key-v1\x00"
+	b, ok := bytes.CutPrefix(block.Bytes, []byte(magic))
+	if !ok {
+		return nil, errors.New("not an OpenSSH private key")
+	}
+	var cipher, priv []byte
+	var err error
+	if cipher, b, err = sshString(b); err != nil {
+		return nil, err
+	}
+	if string(cipher) != "none" {
+		return nil, errors.New("passphrase-protected keys are not supported; use a separate unencrypted key for signing")
+	}
+	for range 2 { // kdf name and options
+		if _, b, err = sshString(b); err != nil {
+			return nil, err
+		}
+	}
+	if len(b) < 4 || binary.BigEndian.Uint32(b) != 1 {
+		return nil, errors.New("expected exactly one key")
+	}
+	if _, b, err = sshString(b[4:]); err != nil { // public key
+		return nil, err
+	}
+	if priv, _, err = sshString(b); err != nil {
+		return nil, err
+	}
+	if len(priv) < 8 || !bytes.Equal(priv[:4], priv[4:8]) {
+		return nil, errors.New("corrupt private key")
+	}
+	typ, rest, err := sshString(priv[8:])
+	if err != nil || string(typ) != sshEd25519 {
+		return nil, fmt.Errorf("only %s keys are supported", sshEd25519)
+	}
+	if _, rest, err = sshString(rest); err != nil {
+		return nil, err
+	}
+	key, _, err := sshString(rest)
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("corrupt private key")
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+func loadSigningKey(p string) (ed25519.PrivateKey, error) {
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, rest)
+		}
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parsePrivateKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	return key, nil
+}
+
+// manifestPayload is what a signature covers: the manifest as written,
+// without its signature.
+func manifestPayload(m Manifest) []byte {
+	m.Signature = nil
+	m.Changes = slices.Clone(m.Changes)
+	sort.Slice(m.Changes, func(i, j int) bool { return m.Changes[i].Path < m.Changes[j].Path })
+	b, _ := json.Marshal(m)
+	return append([]byte(signatureNamespace), b...)
+}
+
+func signManifest(m *Manifest, key ed25519.PrivateKey) {
+	if m.Tree == nil {
+		m.Tree = treeHashes(m.Files) // signed along with the files
+	}
+	sig := ed25519.Sign(key, manifestPayload(*m))
+	m.Signature = &Signature{
+		Key: fingerprint(key.Public().(ed25519.PublicKey)),
+		Sig: base64.StdEncoding.EncodeToString(sig),
+	}
+}
+
+// maybeSignManifest signs m with this machine's signing key, if one is set.
+// A key that can't be used is reported and the version recorded unsigned.
+func maybeSignManifest(m *Manifest) {
+	p := loadUserConfig().SigningKey
+	if p == "" {
+		return
+	}
+	key, err := loadSigningKey(p)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: v%.1f recorded unsigned, signing key unusable: %v\n", m.Version, err)
+		return
+	}
+	signManifest(m, key)
+}
+
+// allowedSigner is one line of allowed_signers.
+type allowedSigner struct {
+	Name string
+	Key  ed25519.PublicKey
+}
+
+// loadAllowedSigners reads "name ssh-ed25519 AAAA…" lines; blank lines and
+// # comments are skipped.
+func loadAllowedSigners(p string) (map[string]allowedSigner, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	out := map[string]allowedSigner{}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%s:%d: expected \"name ssh-ed25519 key\"", p, n)
+		}
+		pub, err := parsePublicKey(fields[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", p, n, err)
+		}
+		out[fingerprint(pub)] = allowedSigner{Name: fields[0], Key: pub}
+	}
+	return out, sc.Err()
+}
+
+// checkSignature returns who signed m, or why the signature doesn't hold.
+func checkSignature(m Manifest, signers map[string]allowedSigner) (string, error) {
+	if m.Signature == nil {
+		return "", errors.New("not signed")
+	}
+	s, ok := signers[m.Signature.Key]
+	if !ok {
+		return "", fmt.Errorf("signed by a key not in allowed_signers (%s)", m.Signature.Key)
+	}
+	sig, err := base64.StdEncoding.DecodeString(m.Signature.Sig)
+	if err != nil || !ed25519.Verify(s.Key, manifestPayload(m), sig) {
+		return "", fmt.Errorf("signature by %s does not match; the manifest was changed after signing", s.Name)
+	}
+	return s.Name, nil
+}
+
+// signatureCheck tallies signature checks one manifest at a time.
+type signatureCheck struct {
+	signers              map[string]allowedSigner
+	total, unsigned, bad int
+	by                   map[string]int // versions per signer
+}
+
+// add checks m and prints a line if its signature is bad.
+func (c *signatureCheck) add(m Manifest) {
+	if c.by == nil {
+		c.by = map[string]int{}
+	}
+	c.total++
+	name, err := checkSignature(m, c.signers)
+	switch {
+	case m.Signature == nil:
+		c.unsigned++
+	case err != nil:
+		c.bad++
+		fmt.Printf("❌ v%.1f: %v\n", m.Version, err)
+	default:
+		c.by[name]++
+	}
+}
+
+// report prints how many versions each signer signed.
+func (c *signatureCheck) report() {
+	for _, name := range sortedKeys(c.by) {
+		fmt.Printf("🔏 %d versions signed by %s\n", c.by[name], name)
+	}
+}
+
+// verifySignatures checks every manifest and prints one line per problem.
+// It returns how many versions were unsigned and how many failed.
+func verifySignatures(manifests []Manifest, signers map[string]allowedSigner) (unsigned, bad int) {
+	c := signatureCheck{signers: signers}
+	for _, m := range manifests {
+		c.add(m)
+	}
+	c.report()
+	return c.unsigned, c.bad
+}
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	signatures := fs.Bool("signatures", false, "check every version's signature")
+	signersPath := fs.String("allowed-signers", allowedSignersFile, "trusted keys (a copy kept outside the store can't be tampered with alongside it)")
+	requireAll := fs.Bool("require", false, "with --signatures, treat unsigned versions as failures")
+	var paths listFlag
+	fs.Var(&paths, "paths", "only check content recorded for files under this path (repeatable)")
+	jobs := fs.Int("jobs", defaultJobs(), "how many files to read at once")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("usage: gitnot verify [--paths dir]... [--jobs n] | --signatures [--allowed-signers file] [--require]")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if !*signatures {
+		limit, err := checkPaths(paths)
+		if err != nil {
+			return err
+		}
+		return verifyContent(limit, *jobs)
+	}
+	signers, err := loadAllowedSigners(*signersPath)
+	if err != nil {
+		return fmt.Errorf("reading allowed signers: %w", err)
+	}
+	// one version at a time: each is checked with its whole file list
+	c := signatureCheck{signers: signers}
+	err = eachManifest(func(m Manifest) error {
+		c.add(m)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.report()
+	unsigned, bad := c.unsigned, c.bad
+	if unsigned > 0 {
+		fmt.Printf("⚠️  %d of %d versions are not signed\n", unsigned, c.total)
+	}
+	if *requireAll {
+		bad += unsigned
+	}
+	if bad > 0 {
+		return fmt.Errorf("%d versions failed signature verification", bad)
+	}
+	fmt.Println("✅ Signatures verified")
+	return nil
+}
+
+// addAllowedSigner appends pub to allowed_signers unless it is already there.
+func addAllowedSigner(name string, pub ed25519.PublicKey) (bool, error) {
+	if signers, err := loadAllowedSigners(allowedSignersFile); err == nil {
+		if _, ok := signers[fingerprint(pub)]; ok {
+			return false, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	name = strings.Join(strings.Fields(name), "_")
+	if name == "" {
+		name = "unnamed"
+	}
+	return true, appendToFile(allowedSignersFile, name+" "+authorizedKey(pub)+"\n")
+}
+
+func runSigning(args []string) error {
+	fs := flag.NewFlagSet("signing", flag.ExitOnError)
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	uc := loadUserConfig()
+	switch {
+	case len(rest) == 0:
+		if uc.SigningKey == "" {
+			fmt.Println("🔓 Versions are not signed on this machine (use 'gitnot signing on <key>')")
+			return nil
+		}
+		fmt.Printf("🔏 Signing versions with %s\n", uc.SigningKey)
+		return nil
+	case rest[0] == "off" && len(rest) == 1:
+		uc.SigningKey = ""
+		if err := saveUserConfig(uc); err != nil {
+			return err
+		}
+		fmt.Println("🔓 Signing turned off for this machine")
+		return nil
+	case rest[0] == "on" && len(rest) == 2:
+	default:
+		return fmt.Errorf("usage: gitnot signing [on <ssh-ed25519-private-key> | off]")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	p, err := filepath.Abs(rest[1])
+	if err != nil {
+		return err
+	}
+	key, err := loadSigningKey(p)
+	if err != nil {
+		return err
+	}
+	name := currentDevice().Name
+	if a := currentAuthor(loadConfig()); a != nil {
+		name = a.Name
+	}
+	pub := key.Public().(ed25519.PublicKey)
+	added, err := addAllowedSigner(name, pub)
+	if err != nil {
+		return err
+	}
+	uc = loadUserConfig()
+	uc.SigningKey = p
+	if err := saveUserConfig(uc); err != nil {
+		return err
+	}
+	fmt.Printf("🔏 New versions will be signed with %s (%s)\n", p, fingerprint(pub))
+	if added {
+		fmt.Printf("📝 Added the public key to %s\n", allowedSignersFile)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testSSHKey writes an unencrypted OpenSSH ed25519 private key, in the
+// layout ssh-keygen produces, and returns its path.
+func testSSHKey(t *testing.T, seed byte) (string, ed25519.PrivateKey) {
+	s := make([]byte, ed25519.SeedSize)
+	s[0] = seed
+	key := ed25519.NewKeyFromSeed(s)
+	pub := sshPublicBlob(key.Public().(ed25519.PublicKey))
+
+	var priv []byte
+	priv = binary.BigEndian.AppendUint32(priv, 0x01020304)
+	priv = binary.BigEndian.AppendUint32(priv, 0x01020304)
+	priv = appendSSHString(priv, []byte(sshEd25519))
+	priv = appendSSHString(priv, key.Public().(ed25519.PublicKey))
+	priv = appendSSHString(priv, key)
+	priv = appendSSHString(priv, []byte("test@host"))
+	for i := byte(1); len(priv)%8 != 0; i++ {
+		priv = append(priv, i)
+	}
+
+	b := []byte("openssh-key-v1\x00")
+	b = appendSSHString(b, []byte("none"))
+	b = appendSSHString(b, []byte("none"))
+	b = appendSSHString(b, nil)
+	b = binary.BigEndian.AppendUint32(b, 1)
+	b = appendSSHString(b, pub)
+	b = appendSSHString(b, priv)
+
+	p := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(p, pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: b}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p, key
+}
+
+func TestSignedVersions(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	keyPath, key := testSSHKey(t, 1)
+	if err := runSigning([]string{"on", keyPath}); err != nil {
+		t.Fatalf("signing on failed: %v", err)
+	}
+	createTestFile(t, "a.md", "two\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	signers, err := loadAllowedSigners(allowedSignersFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := loadManifest(0.1)
+	if m.Signature == nil || m.Signature.Key != fingerprint(key.Public().(ed25519.PublicKey)) {
+		t.Fatalf("Expected v0.1 to be signed by the test key, got %+v", m.Signature)
+	}
+	if _, err := checkSignature(m, signers); err != nil {
+		t.Errorf("Valid signature rejected: %v", err)
+	}
+	manifests, _ := loadManifests()
+	if unsigned, bad := verifySignatures(manifests, signers); unsigned != 1 || bad != 0 {
+		t.Errorf("Expected v0.0 unsigned and nothing bad, got %d unsigned, %d bad", unsigned, bad)
+	}
+
+	// Tampering with the recorded tree breaks the signature.
+	m.Files["a.md"] = strings.Repeat("0", 40)
+	if _, err := checkSignature(m, signers); err == nil || !strings.Contains(err.Error(), "changed after signing") {
+		t.Errorf("Tampered manifest accepted: %v", err)
+	}
+	// So does a key nobody allowed.
+	_, other := testSSHKey(t, 2)
+	m, _ = loadManifest(0.1)
+	signManifest(&m, other)
+	if _, err := checkSignature(m, signers); err == nil || !strings.Contains(err.Error(), "not in allowed_signers") {
+		t.Errorf("Unknown signer accepted: %v", err)
+	}
+
+	if err := runVerify([]string{"--signatures", "--require"}); err == nil {
+		t.Error("verify --require should fail with an unsigned version")
+	}
+}
+
+func TestParsePrivateKeyRejectsEncrypted(t *testing.T) {
+	b := []byte("openssh-key-v1\x00")
+	b = appendSSHString(b, []byte("aes256-ctr"))
+	data := pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: b})
+	if _, err := parsePrivateKey(data); err == nil || !strings.Contains(err.Error(), "passphrase") {
+		t.Errorf("Expected passphrase error, got %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Stats ---
+
+// FileStats summarises the recorded history of a single file.
+type FileStats struct {
+	Path         string
+	Versions     int
+	LinesAdded   int
+	LinesRemoved int
+	WordsAdded   int
+	WordsRemoved int
+	Lines        int
+	Words        int
+	FirstSeen    time.Time
+	FirstVersion float64
+	LongestGap   time.Duration
+	GapFrom      float64
+	GapTo        float64
+	Issues       []int // check_command findings per checked version, oldest first
+}
+
+func computeFileStats(rel string, manifests []Manifest) (FileStats, bool) {
+	st := FileStats{Path: rel}
+	var last time.Time
+	var lastVer float64
+	for _, m := range manifests {
+		for _, c := range m.Changes {
+			if c.Path != rel {
+				continue
+			}
+			if st.Versions == 0 {
+				st.FirstSeen = m.Timestamp
+				st.FirstVersion = m.Version
+			} else if gap := m.Timestamp.Sub(last); gap > st.LongestGap {
+				st.LongestGap = gap
+				st.GapFrom, st.GapTo = lastVer, m.Version
+			}
+			st.Versions++
+			st.LinesAdded += c.LinesAdded
+			st.LinesRemoved += c.LinesRemoved
+			st.WordsAdded += c.WordsAdded
+			st.WordsRemoved += c.WordsRemoved
+			st.Lines, st.Words = c.Lines, c.Words
+			if c.Issues != nil {
+				st.Issues = append(st.Issues, *c.Issues)
+			}
+			last, lastVer = m.Timestamp, m.Version
+		}
+	}
+	return st, st.Versions > 0
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%d days", int(d.Hours()/24))
+	case d >= 2*time.Hour:
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	case d >= 2*time.Minute:
+		return fmt.Sprintf("%d minutes", int(d.Minutes()))
+	default:
+		return "moments"
+	}
+}
+
+// MetricRow is one (version, file) record of the metrics export.
+type MetricRow struct {
+	Version      float64   `json:"version"`
+	Timestamp    time.Time `json:"timestamp"`
+	Path         string    `json:"path"`
+	State        string    `json:"state"`
+	LinesAdded   int       `json:"lines_added"`
+	LinesRemoved int       `json:"lines_removed"`
+	WordsAdded   int       `json:"words_added"`
+	WordsRemoved int       `json:"words_removed"`
+	Lines        int       `json:"lines"`
+	Words        int       `json:"words"`
+	Issues       *int      `json:"issues,omitempty"`
+	Author       string    `json:"author,omitempty"`
+}
+
+func metricRows(manifests []Manifest, only string) []MetricRow {
+	var rows []MetricRow
+	for _, m := range manifests {
+		author := ""
+		if m.Author != nil {
+			author = m.Author.Name
+		}
+		for _, c := range m.Changes {
+			if only != "" && c.Path != only {
+				continue
+			}
+			rows = append(rows, MetricRow{
+				Version: m.Version, Timestamp: m.Timestamp, Path: c.Path, State: c.State,
+				LinesAdded: c.LinesAdded, LinesRemoved: c.LinesRemoved,
+				WordsAdded: c.WordsAdded, WordsRemoved: c.WordsRemoved,
+				Lines: c.Lines, Words: c.Words, Issues: c.Issues, Author: author,
+			})
+		}
+	}
+	return rows
+}
+
+// exportMetrics writes rows as JSON when out ends in .json, CSV otherwise.
+func exportMetrics(out string, rows []MetricRow) error {
+	if strings.EqualFold(filepath.Ext(out), ".json") {
+		return saveJSON(out, rows)
+	}
+	if err := safeMkdirAllForFile(out); err != nil {
+		return err
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"version", "timestamp", "path", "state",
+		"lines_added", "lines_removed", "words_added", "words_removed", "lines", "words", "issues", "author"})
+	for _, r := range rows {
+		issues := ""
+		if r.Issues != nil {
+			issues = strconv.Itoa(*r.Issues)
+		}
+		_ = w.Write([]string{
+			fmt.Sprintf("%.1f", r.Version), r.Timestamp.Format(time.RFC3339), r.Path, r.State,
+			strconv.Itoa(r.LinesAdded), strconv.Itoa(r.LinesRemoved),
+			strconv.Itoa(r.WordsAdded), strconv.Itoa(r.WordsRemoved),
+			strconv.Itoa(r.Lines), strconv.Itoa(r.Words), issues, r.Author,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	exportPath := fs.String("export", "", "write per-version metrics to a .csv or .json file")
+	byAuthor := fs.Bool("by-author", false, "summarise versions and words per author")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 1 {
+		return fmt.Errorf("usage: gitnot stats [file] [--export metrics.csv]")
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	manifests, err := loadManifestHeads()
+	if err != nil {
+		return err
+	}
+	if *exportPath != "" {
+		only := ""
+		if len(rest) == 1 {
+			only = filepath.Clean(rest[0])
+		}
+		rows := metricRows(manifests, only)
+		if err := exportMetrics(*exportPath, rows); err != nil {
+			return err
+		}
+		fmt.Printf("📤 Exported %d rows to %s\n", len(rows), *exportPath)
+		return nil
+	}
+	if *byAuthor {
+		fmt.Println("👥 Stats by author")
+		for _, a := range authorStats(manifests) {
+			fmt.Printf("  %-24s %3d versions  %4d files  +%d/-%d words\n", a.Name, a.Versions, a.Files, a.WordsAdded, a.WordsRemoved)
+		}
+		return nil
+	}
+	if len(rest) == 0 {
+		return showStatsOverview(manifests, loadConfig())
+	}
+	rel := filepath.Clean(rest[0])
+	st, ok := computeFileStats(rel, manifests)
+	if !ok {
+		return fmt.Errorf("no recorded history for %s", rel)
+	}
+	// prefer the working copy for current length when it still exists
+	if b, err := os.ReadFile(rel); err == nil {
+		st.Lines = len(splitTextLines(string(b)))
+		st.Words = countWords(string(b))
+	}
+
+	fmt.Printf("📊 Stats for %s\n", st.Path)
+	fmt.Printf("  Versions touching file: %d\n", st.Versions)
+	fmt.Printf("  Lines: +%d / -%d\n", st.LinesAdded, st.LinesRemoved)
+	fmt.Printf("  Words: +%d / -%d\n", st.WordsAdded, st.WordsRemoved)
+	fmt.Printf("  Current length: %d lines, %d words\n", st.Lines, st.Words)
+	fmt.Printf("  Age: %s (first seen v%.1f)\n", formatDuration(repo.Now().Sub(st.FirstSeen)), st.FirstVersion)
+	if n := len(st.Issues); n > 0 {
+		trend := make([]string, 0, 5)
+		for _, i := range st.Issues[max(0, n-5):] {
+			trend = append(trend, strconv.Itoa(i))
+		}
+		fmt.Printf("  Check issues: %d (recent: %s)\n", st.Issues[n-1], strings.Join(trend, " → "))
+	}
+	if st.Versions > 1 {
+		fmt.Printf("  Longest gap between edits: %s (v%.1f → v%.1f)\n", formatDuration(st.LongestGap), st.GapFrom, st.GapTo)
+	}
+	return nil
+}
+
+// showStatsOverview prints one line per tracked file, in narrative order.
+func showStatsOverview(manifests []Manifest, cfg Config) error {
+	if len(manifests) == 0 {
+		return fmt.Errorf("no recorded history yet")
+	}
+	latest, err := withFiles(manifests[len(manifests)-1])
+	if err != nil {
+		return err
+	}
+	var files []string
+	for rel := range latest.Files {
+		files = append(files, rel)
+	}
+	files = orderPaths(files, cfg.Order)
+	fmt.Printf("📊 %d files at v%.1f\n", len(files), latest.Version)
+	totalWords := 0
+	for _, rel := range files {
+		st, _ := computeFileStats(rel, manifests)
+		totalWords += st.Words
+		fmt.Printf("  %-40s %3d versions  %6d lines  %7d words\n", rel, st.Versions, st.Lines, st.Words)
+	}
+	fmt.Printf("  Total words: %d\n", totalWords)
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMeasureChange(t *testing.T) {
+	fc := measureChange("one two\nthree\n", "one two\nfour five six\nseven\n")
+	if fc.LinesAdded != 2 || fc.LinesRemoved != 1 {
+		t.Errorf("Expected +2/-1 lines, got +%d/-%d", fc.LinesAdded, fc.LinesRemoved)
+	}
+	if fc.WordsAdded != 4 || fc.WordsRemoved != 1 {
+		t.Errorf("Expected +4/-1 words, got +%d/-%d", fc.WordsAdded, fc.WordsRemoved)
+	}
+	if fc.Lines != 3 || fc.Words != 6 {
+		t.Errorf("Expected length 3 lines/6 words, got %d/%d", fc.Lines, fc.Words)
+	}
+}
+
+func TestManifestsRecordHistory(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "notes.md", "first line\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "notes.md", "first line\nsecond line here\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	manifests, err := loadManifests()
+	if err != nil {
+		t.Fatalf("loadManifests failed: %v", err)
+	}
+	if len(manifests) != 2 {
+		t.Fatalf("Expected 2 manifests, got %d", len(manifests))
+	}
+	if manifests[1].Version != 0.1 || manifests[1].Files["notes.md"] == "" {
+		t.Errorf("Unexpected second manifest: %+v", manifests[1])
+	}
+
+	st, ok := computeFileStats("notes.md", manifests)
+	if !ok {
+		t.Fatal("Expected stats for notes.md")
+	}
+	if st.Versions != 2 || st.LinesAdded != 2 || st.WordsAdded != 5 {
+		t.Errorf("Unexpected stats: %+v", st)
+	}
+}
+
+func TestLongestGap(t *testing.T) {
+	base := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	manifests := []Manifest{
+		{Version: 0.0, Timestamp: base, Changes: []FileChange{{Path: "a.md", State: stateAdded}}},
+		{Version: 0.1, Timestamp: base.Add(time.Hour), Changes: []FileChange{{Path: "a.md", State: stateModified}}},
+		{Version: 0.2, Timestamp: base.Add(2 * time.Hour), Changes: []FileChange{{Path: "b.md", State: stateAdded}}},
+		{Version: 0.3, Timestamp: base.Add(72 * time.Hour), Changes: []FileChange{{Path: "a.md", State: stateModified}}},
+	}
+	st, _ := computeFileStats("a.md", manifests)
+	if st.LongestGap != 71*time.Hour || st.GapFrom != 0.1 || st.GapTo != 0.3 {
+		t.Errorf("Unexpected gap: %v (v%.1f → v%.1f)", st.LongestGap, st.GapFrom, st.GapTo)
+	}
+}
+
+func TestExportMetrics(t *testing.T) {
+	setupTestDir(t)
+
+	ts := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	manifests := []Manifest{
+		{Version: 0.0, Timestamp: ts, Changes: []FileChange{{Path: "a.md", State: stateAdded, LinesAdded: 3, Lines: 3}}},
+		{Version: 0.1, Timestamp: ts, Changes: []FileChange{
+			{Path: "a.md", State: stateModified, LinesAdded: 1, LinesRemoved: 1, Lines: 3},
+			{Path: "b.md", State: stateAdded, LinesAdded: 2, Lines: 2},
+		}},
+	}
+
+	if err := exportMetrics("metrics.csv", metricRows(manifests, "")); err != nil {
+		t.Fatalf("exportMetrics failed: %v", err)
+	}
+	f, err := os.Open("metrics.csv")
+	if err != nil {
+		t.Fatalf("Failed to open export: %v", err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("Expected header + 3 rows, got %d", len(records))
+	}
+	if records[2][0] != "0.1" || records[2][2] != "a.md" || records[2][4] != "1" {
+		t.Errorf("Unexpected row: %v", records[2])
+	}
+
+	if err := exportMetrics("metrics.json", metricRows(manifests, "b.md")); err != nil {
+		t.Fatalf("JSON export failed: %v", err)
+	}
+	var rows []MetricRow
+	if err := loadJSON("metrics.json", &rows); err != nil {
+		t.Fatalf("Failed to load JSON export: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Path != "b.md" {
+		t.Errorf("Expected only b.md rows, got %+v", rows)
+	}
+}
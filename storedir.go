@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Store folder ---
+//
+// The store is .gitnot by default. GITNOT_DIR=.history names another folder
+// at the project root, e.g. to keep tools that look for ".git*" names from
+// tripping over it. Once a store exists under another name it is found
+// without the variable: when there is no .gitnot, the root folder holding a
+// store (and no mirror marker) is used. `gitnot new` looks in the new folder
+// instead, so it gets .gitnot unless GITNOT_DIR says otherwise. Plugins get
+// GITNOT_DIR as the store's absolute path, so an absolute path naming a
+// folder at the project root is accepted too.
+
+const storeDirEnv = "GITNOT_DIR"
+
+// storeDirName returns the store folder name GITNOT_DIR gives: a single
+// folder name, or the absolute path of a folder at the project root.
+func storeDirName(name string) (string, error) {
+	if filepath.IsAbs(name) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, name); err == nil {
+				name = rel
+			}
+		}
+	}
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || filepath.IsAbs(name) {
+		return "", fmt.Errorf("%s=%q: want a single folder name such as .history, or the absolute path of a folder at the project root", storeDirEnv, os.Getenv(storeDirEnv))
+	}
+	return name, nil
+}
+
+// isStore reports whether dir looks like a gitnot store: version.txt,
+// config.json and snapshot/. A mirror (see mirror.go) holds the same files
+// but is a copy of some other project's store, never this one's.
+func isStore(dir string) bool {
+	if _, err := repo.FS.Stat(filepath.Join(dir, mirrorMarker)); err == nil {
+		return false
+	}
+	for _, f := range []string{"version.txt", "config.json", "snapshot"} {
+		if _, err := repo.FS.Stat(filepath.Join(dir, f)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// locateStore picks the store folder for this run: GITNOT_DIR, then .gitnot,
+// then a root folder that holds a store under another name.
+func locateStore() error {
+	if env := os.Getenv(storeDirEnv); env != "" {
+		name, err := storeDirName(env)
+		if err != nil {
+			return err
+		}
+		useStoreDir(name)
+		return nil
+	}
+	if _, err := repo.FS.Stat(defaultStoreDir); err == nil {
+		useStoreDir(defaultStoreDir)
+		return nil
+	}
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		return nil
+	}
+	for _, e := range entries {
+		if e.IsDir() && isStore(e.Name()) {
+			useStoreDir(e.Name())
+			return nil
+		}
+	}
+	useStoreDir(defaultStoreDir)
+	return nil
+}
+
+// within reports whether p is dir or inside it, comparing whole path
+// segments: ".gitnothing" is not inside ".gitnot".
+func within(p, dir string) bool {
+	p, dir = filepath.ToSlash(filepath.Clean(p)), filepath.ToSlash(filepath.Clean(dir))
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCustomStoreDir(t *testing.T) {
+	setupTestDir(t)
+	t.Cleanup(func() { useStoreDir(defaultStoreDir) })
+
+	createTestFile(t, "chapter.md", "One\n")
+	createTestFile(t, filepath.Join(".gitnothing", "notes.md"), "Not the store\n")
+	t.Setenv(storeDirEnv, ".history")
+	if err := locateStore(); err != nil {
+		t.Fatal(err)
+	}
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(".history", "version.txt")); err != nil {
+		t.Fatalf("Expected the store in .history: %v", err)
+	}
+	if _, err := os.Stat(defaultStoreDir); err == nil {
+		t.Error("No .gitnot folder should be created")
+	}
+	hashes := loadCommittedHashes()
+	if _, ok := hashes[filepath.Join(".gitnothing", "notes.md")]; !ok {
+		t.Errorf("A folder sharing the store's prefix should be tracked, got %v", hashes)
+	}
+	for rel := range hashes {
+		if within(rel, ".history") {
+			t.Errorf("The store itself should not be tracked: %s", rel)
+		}
+	}
+
+	// found again without the variable
+	os.Unsetenv(storeDirEnv)
+	useStoreDir(defaultStoreDir)
+	if err := locateStore(); err != nil {
+		t.Fatal(err)
+	}
+	if gitnotDir != ".history" || versionFile != ".history/version.txt" {
+		t.Errorf("Expected the .history store to be found, got %s (%s)", gitnotDir, versionFile)
+	}
+
+	t.Setenv(storeDirEnv, "a/b")
+	if err := locateStore(); err == nil {
+		t.Error("Expected an error for a nested store path")
+	}
+
+	// what plugins get: the store's absolute path
+	abs, _ := filepath.Abs(".history")
+	t.Setenv(storeDirEnv, abs)
+	useStoreDir(defaultStoreDir)
+	if err := locateStore(); err != nil || gitnotDir != ".history" {
+		t.Errorf("Expected an absolute path at the root to name the store, got %s, %v", gitnotDir, err)
+	}
+	t.Setenv(storeDirEnv, filepath.Join(abs, "snapshot"))
+	if err := locateStore(); err == nil {
+		t.Error("Expected an error for an absolute path below the root")
+	}
+}
+
+func TestWithin(t *testing.T) {
+	tests := []struct {
+		p, dir string
+		want   bool
+	}{
+		{".gitnot/x", ".gitnot", true},
+		{".gitnot", ".gitnot", true},
+		{"./.gitnot/x", ".gitnot", true},
+		{".gitnothing/x", ".gitnot", false},
+		{"../x", "..", true},
+		{"..notes/x", "..", false},
+	}
+	for _, tt := range tests {
+		if got := within(tt.p, tt.dir); got != tt.want {
+			t.Errorf("within(%q, %q) = %t, want %t", tt.p, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestMirrorIsNotTakenForTheStore(t *testing.T) {
+	setupTestDir(t)
+	t.Cleanup(func() { useStoreDir(defaultStoreDir) })
+
+	createTestFile(t, "p1/a.md", "One\n")
+	os.Chdir("p1")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if _, err := mirrorStore(filepath.Join("..", "backup")); err != nil {
+		t.Fatalf("mirrorStore failed: %v", err)
+	}
+	os.Chdir("..")
+
+	os.Unsetenv(storeDirEnv)
+	if err := locateStore(); err != nil {
+		t.Fatal(err)
+	}
+	if gitnotDir != defaultStoreDir {
+		t.Errorf("A mirror should not be taken for this folder's store, got %s", gitnotDir)
+	}
+
+	// nor does another project's store name carry over into a new project
+	os.Rename(filepath.Join("p1", defaultStoreDir), ".history")
+	if err := locateStore(); err != nil || gitnotDir != ".history" {
+		t.Fatalf("Expected the .history store, got %s, %v", gitnotDir, err)
+	}
+	if err := newProject("fresh", "", ""); err != nil {
+		t.Fatalf("newProject failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("fresh", defaultStoreDir, "version.txt")); err != nil {
+		t.Errorf("Expected the new project's store in %s: %v", defaultStoreDir, err)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Tags ---
+//
+// Tags name versions: `gitnot tag submitted` names the current version, and
+// a tag works wherever a version is expected (`gitnot diff --version
+// submitted`). With "auto_tags": ["daily", "weekly", "monthly"] the first
+// version recorded in each local day, ISO week or month is tagged
+// daily-2024-05-01, weekly-2024-W18 or monthly-2024-05, so "since this
+// morning" or "the end-of-week draft" is one name away. Tags live in
+// .gitnot/tags.json.
+
+const (
+	autoDaily   = "daily"
+	autoWeekly  = "weekly"
+	autoMonthly = "monthly"
+)
+
+var tagNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func loadTags() map[string]float64 {
+	tags := map[string]float64{}
+	_ = loadJSON(tagsFile, &tags)
+	return tags
+}
+
+// tagsOf lists the tags naming v, sorted.
+func tagsOf(tags map[string]float64, v float64) []string {
+	var out []string
+	for name, tv := range tags {
+		if tv == v {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// checkTagName rejects names that could be mistaken for a version number.
+func checkTagName(name string) error {
+	if !tagNameRe.MatchString(name) {
+		return fmt.Errorf("%q is not a valid tag name (letters, digits, '.', '_' and '-')", name)
+	}
+	if _, err := strconv.ParseFloat(strings.TrimPrefix(name, "v"), 64); err == nil {
+		return fmt.Errorf("%q looks like a version number", name)
+	}
+	return nil
+}
+
+// autoTagName is the tag kind gives a version recorded at t.
+func autoTagName(kind string, t time.Time) string {
+	switch kind {
+	case autoDaily:
+		return t.Format("daily-2006-01-02")
+	case autoWeekly:
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("weekly-%d-W%02d", y, w)
+	case autoMonthly:
+		return t.Format("monthly-2006-01")
+	}
+	return ""
+}
+
+// applyAutoTags tags v for every configured period that has no tag yet,
+// which makes v the first version of that period. It returns the new tags.
+func applyAutoTags(cfg Config, v float64, t time.Time) ([]string, error) {
+	if len(cfg.AutoTags) == 0 {
+		return nil, nil
+	}
+	tags := loadTags()
+	var added []string
+	for _, kind := range cfg.AutoTags {
+		name := autoTagName(strings.ToLower(kind), t.Local())
+		if _, ok := tags[name]; name == "" || ok {
+			continue
+		}
+		tags[name] = v
+		added = append(added, name)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	return added, saveJSON(tagsFile, tags)
+}
+
+// recordAutoTags is applyAutoTags for a version just recorded; failing to
+// tag never fails the update.
+func recordAutoTags(cfg Config, v float64, t time.Time) {
+	added, err := applyAutoTags(cfg, v, t)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: Could not save tags: %v\n", err)
+		return
+	}
+	if len(added) > 0 {
+		fmt.Printf("🏷️  Tagged v%.1f %s\n", v, strings.Join(added, ", "))
+	}
+}
+
+func validateAutoTags(kinds []string) []configIssue {
+	var issues []configIssue
+	for _, k := range kinds {
+		if autoTagName(strings.ToLower(k), time.Time{}) == "" {
+			issues = append(issues, configIssue{"auto_tags", fmt.Sprintf("%q is not one of %q, %q, %q", k, autoDaily, autoWeekly, autoMonthly)})
+		}
+	}
+	return issues
+}
+
+func runTag(args []string) error {
+	fs := flag.NewFlagSet("tag", flag.ExitOnError)
+	version := fs.String("version", "", "version to tag (default: current)")
+	remove := fs.Bool("remove", false, "delete the given tags")
+	force := fs.Bool("force", false, "move a tag that already names another version")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	tags := loadTags()
+	if len(rest) == 0 {
+		if *remove {
+			return fmt.Errorf("usage: gitnot tag --remove <name>...")
+		}
+		if len(tags) == 0 {
+			fmt.Println("🏷️  No tags yet")
+			return nil
+		}
+		names := sortedKeys(tags)
+		sort.SliceStable(names, func(i, j int) bool { return tags[names[i]] < tags[names[j]] })
+		for _, name := range names {
+			fmt.Printf("  v%-6.1f %s\n", tags[name], name)
+		}
+		return nil
+	}
+
+	if *remove {
+		for _, name := range rest {
+			if _, ok := tags[name]; !ok {
+				return fmt.Errorf("no tag %q", name)
+			}
+			delete(tags, name)
+		}
+		if err := saveJSON(tagsFile, tags); err != nil {
+			return err
+		}
+		fmt.Printf("🏷️  Removed %s\n", strings.Join(rest, ", "))
+		return nil
+	}
+	v, err := parseVersionArg(*version)
+	if err != nil {
+		return err
+	}
+	if _, err := loadManifest(v); err != nil {
+		return err
+	}
+	for _, name := range rest {
+		if err := checkTagName(name); err != nil {
+			return err
+		}
+		if old, ok := tags[name]; ok && old != v && !*force {
+			return fmt.Errorf("%s already names v%.1f; use --force to move it", name, old)
+		}
+		tags[name] = v
+	}
+	if err := saveJSON(tagsFile, tags); err != nil {
+		return err
+	}
+	fmt.Printf("🏷️  Tagged v%.1f %s\n", v, strings.Join(rest, ", "))
+	return nil
+}
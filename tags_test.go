@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoTagNames(t *testing.T) {
+	at := time.Date(2024, 5, 1, 9, 30, 0, 0, time.Local)
+	for kind, want := range map[string]string{
+		autoDaily:   "daily-2024-05-01",
+		autoWeekly:  "weekly-2024-W18",
+		autoMonthly: "monthly-2024-05",
+		"hourly":    "",
+	} {
+		if got := autoTagName(kind, at); got != want {
+			t.Errorf("autoTagName(%q) = %q, want %q", kind, got, want)
+		}
+	}
+}
+
+func TestAutoTagsMarkFirstVersionOfPeriod(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.md", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := Config{AutoTags: []string{"daily", "monthly"}}
+	morning := time.Date(2024, 5, 1, 9, 0, 0, 0, time.Local)
+	if added, err := applyAutoTags(cfg, 0.1, morning); err != nil || len(added) != 2 {
+		t.Fatalf("Expected a daily and a monthly tag, got %v, %v", added, err)
+	}
+	if added, _ := applyAutoTags(cfg, 0.2, morning.Add(8*time.Hour)); len(added) != 0 {
+		t.Errorf("A later version the same day should not be tagged, got %v", added)
+	}
+	if added, _ := applyAutoTags(cfg, 0.3, morning.Add(24*time.Hour)); len(added) != 1 || added[0] != "daily-2024-05-02" {
+		t.Errorf("Expected only the next day's tag, got %v", added)
+	}
+	tags := loadTags()
+	if tags["daily-2024-05-01"] != 0.1 || tags["monthly-2024-05"] != 0.1 || tags["daily-2024-05-02"] != 0.3 {
+		t.Errorf("Unexpected tags %v", tags)
+	}
+}
+
+func TestTagCommand(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.md", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "a.md", "two\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if err := runTag([]string{"submitted", "--version", "0.0"}); err != nil {
+		t.Fatalf("tag failed: %v", err)
+	}
+	if v, err := parseVersionArg("submitted"); err != nil || v != 0.0 {
+		t.Errorf("Expected the tag to resolve to v0.0, got %v, %v", v, err)
+	}
+	if err := runTag([]string{"submitted"}); err == nil {
+		t.Error("Moving a tag should need --force")
+	}
+	if err := runTag([]string{"submitted", "--force"}); err != nil {
+		t.Errorf("--force should move the tag: %v", err)
+	}
+	if v, _ := parseVersionArg("submitted"); v != 0.1 {
+		t.Errorf("Expected the tag moved to v0.1, got v%.1f", v)
+	}
+	for _, bad := range []string{"1.2", "v3", "has space", "-x"} {
+		if err := checkTagName(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+	if err := runTag([]string{"--remove", "submitted"}); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if _, err := parseVersionArg("submitted"); err == nil {
+		t.Error("A removed tag should no longer resolve")
+	}
+}
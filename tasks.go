@@ -0,0 +1,447 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- Post-update tasks ---
+//
+// Once a version is committed an update may still pack objects, copy them
+// to a storage backend, bring mirrors up to date, publish and run hooks.
+// With "post_update": "background" that work is queued instead, one file per
+// task in .gitnot/tasks/, and a detached `gitnot tasks flush` is started to
+// do it, so the update returns as soon as the version is recorded. "queue"
+// only queues: `gitnot watch` works through the queue after each version it
+// records, and `gitnot tasks flush` does so by hand.
+//
+// Packing and mirroring cover the whole store, so a newer queued one takes
+// the place of an older one; storage, publishing and hooks are per version
+// and run in version order. A task that fails stays queued with its error;
+// the background runner and watch give up on it after taskAttempts tries, a
+// flush by hand always tries again. Packing and mirroring hold the store
+// lock while they run, as `gitnot pack` and `gitnot mirror` do; while an
+// update holds it they wait, in the background for a while, otherwise for
+// the next run.
+
+const (
+	postUpdateInline     = "inline"
+	postUpdateBackground = "background"
+	postUpdateQueue      = "queue"
+
+	taskPack    = "pack"
+	taskStorage = "storage"
+	taskMirrors = "mirrors"
+	taskPublish = "publish"
+	taskHooks   = "hooks"
+
+	taskAttempts = 5
+	taskLockWait = 30 * time.Second // how long the background runner waits for the store
+)
+
+// taskKinds in the order an update runs them inline.
+var taskKinds = []string{taskPack, taskStorage, taskMirrors, taskPublish, taskHooks}
+
+// errStoreBusy leaves a task queued because an update holds the store lock.
+var errStoreBusy = errors.New("the store is locked by an update")
+
+// Task is one queued piece of post-update work.
+type Task struct {
+	Kind     string    `json:"kind"`
+	Version  float64   `json:"version"`
+	Queued   time.Time `json:"queued"`
+	Attempts int       `json:"attempts,omitempty"`
+	Error    string    `json:"error,omitempty"` // from the last attempt
+}
+
+func (c Config) postUpdate() string {
+	if c.PostUpdate == "" {
+		return postUpdateInline
+	}
+	return c.PostUpdate
+}
+
+// file is where t is queued; whole-store tasks share one file per kind.
+func (t Task) file() string {
+	if t.Kind == taskPack || t.Kind == taskMirrors {
+		return filepath.Join(tasksDir, t.Kind+".json")
+	}
+	return filepath.Join(tasksDir, fmt.Sprintf("v%.1f-%s.json", t.Version, t.Kind))
+}
+
+// postUpdateTasks lists the work cfg asks for after version m.
+func postUpdateTasks(cfg Config, m Manifest) []Task {
+	due := map[string]bool{
+		taskPack:    cfg.AutoPack,
+		taskStorage: cfg.Storage != "",
+		taskMirrors: len(cfg.Mirrors) > 0,
+		taskPublish: len(cfg.Publish) > 0,
+		taskHooks:   len(cfg.Hooks) > 0,
+	}
+	var tasks []Task
+	for _, kind := range taskKinds {
+		if due[kind] {
+			tasks = append(tasks, Task{Kind: kind, Version: m.Version, Queued: repo.Now()})
+		}
+	}
+	return tasks
+}
+
+// queuePostUpdate queues the work due after m and, in background mode,
+// starts a runner for it unless the caller runs the queue itself.
+func queuePostUpdate(cfg Config, m Manifest, keep bool) {
+	tasks := postUpdateTasks(cfg, m)
+	if len(tasks) == 0 {
+		return
+	}
+	for _, t := range tasks {
+		if err := saveJSON(t.file(), t); err != nil {
+			fmt.Printf("⚠️  Warning: Could not queue %s: %v\n", t.Kind, err)
+			return
+		}
+	}
+	if cfg.postUpdate() == postUpdateQueue || keep {
+		fmt.Printf("⏳ %d post-update tasks queued (gitnot tasks flush runs them)\n", len(tasks))
+		return
+	}
+	if err := startTaskRunner(); err != nil {
+		fmt.Printf("⚠️  Warning: Could not start the background runner: %v (gitnot tasks flush runs the queue)\n", err)
+		return
+	}
+	fmt.Printf("⏳ %d post-update tasks running in the background (gitnot tasks)\n", len(tasks))
+}
+
+// startTaskRunner starts a detached `gitnot tasks flush --background` in the
+// project folder. Tests replace it.
+var startTaskRunner = func() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, "tasks", "flush", "--background")
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// loadTasks returns the queued tasks in the order they run.
+func loadTasks() ([]Task, error) {
+	entries, err := repo.FS.ReadDir(tasksDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		var t Task
+		if err := loadJSON(filepath.Join(tasksDir, e.Name()), &t); err != nil {
+			fmt.Printf("⚠️  Warning: Skipping unreadable task %s: %v\n", e.Name(), err)
+			continue
+		}
+		tasks = append(tasks, t)
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Version != tasks[j].Version {
+			return tasks[i].Version < tasks[j].Version
+		}
+		return slices.Index(taskKinds, tasks[i].Kind) < slices.Index(taskKinds, tasks[j].Kind)
+	})
+	return tasks, nil
+}
+
+// replaced reports whether a newer task of t's kind took its place in the
+// queue while t ran; it is then left for that one.
+func replaced(t Task) bool {
+	var now Task
+	return loadJSON(t.file(), &now) == nil && !now.Queued.Equal(t.Queued)
+}
+
+// doneTask drops t from the queue.
+func doneTask(t Task) error {
+	if replaced(t) {
+		return nil
+	}
+	err := repo.FS.Remove(t.file())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// failedTask keeps t queued with the error it failed with.
+func failedTask(t Task, err error) error {
+	if replaced(t) {
+		return nil
+	}
+	t.Attempts++
+	t.Error = err.Error()
+	return saveJSON(t.file(), t)
+}
+
+// stillCurrent is m with only the changes whose files still hold what m
+// recorded: publishing later must not send a newer draft under m's name.
+// The version that records the newer content publishes it in turn.
+func stillCurrent(m Manifest) Manifest {
+	var kept []FileChange
+	for _, c := range m.Changes {
+		if c.State == stateDeleted || c.State == stateUntracked || hashFile(c.Path) == m.Files[c.Path] {
+			kept = append(kept, c)
+		}
+	}
+	m.Changes = kept
+	return m
+}
+
+// lockStore takes the store lock, waiting up to wait for an update to let
+// go of it.
+func lockStore(wait time.Duration) (release func(), err error) {
+	deadline := time.Now().Add(wait)
+	for {
+		release, err := acquireLock()
+		if err == nil {
+			return release, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errStoreBusy
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// runTask does the work of t, waiting up to wait for the store lock where
+// it needs it.
+func runTask(cfg Config, t Task, wait time.Duration) error {
+	switch t.Kind {
+	case taskPack, taskMirrors:
+		// the update that started a background runner may still hold it
+		release, err := lockStore(wait)
+		if err != nil {
+			return err
+		}
+		defer release()
+		if t.Kind == taskPack {
+			return maybeAutoPack(cfg)
+		}
+		return runMirrors(cfg.Mirrors)
+	case taskStorage, taskPublish, taskHooks:
+		m, err := loadManifest(t.Version)
+		if err != nil {
+			return err
+		}
+		switch t.Kind {
+		case taskStorage:
+			return storeRemote(cfg.Storage, m)
+		case taskPublish:
+			return runPublish(cfg.Publish, stillCurrent(m))
+		default:
+			return runHooks(cfg.Hooks, m)
+		}
+	}
+	return fmt.Errorf("unknown task %q", t.Kind)
+}
+
+// taskRunnerFile holds the process ID of the runner working the queue.
+func taskRunnerFile() string {
+	return filepath.Join(tasksDir, "running")
+}
+
+// taskRunnerPID returns the process ID of a live runner, or 0.
+func taskRunnerPID() int {
+	b, err := repo.FS.ReadFile(taskRunnerFile())
+	if err != nil {
+		return 0
+	}
+	var pid int
+	if _, err := fmt.Sscan(string(b), &pid); err != nil || !processAlive(pid) {
+		return 0
+	}
+	return pid
+}
+
+// claimTaskRunner makes this process the one working the queue.
+func claimTaskRunner() (release func(), err error) {
+	if err := repo.FS.MkdirAll(tasksDir, 0o755); err != nil {
+		return nil, err
+	}
+	for range 2 {
+		f, err := repo.FS.OpenFile(taskRunnerFile(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			if pid := taskRunnerPID(); pid != 0 {
+				return nil, fmt.Errorf("tasks are already being run (process %d)", pid)
+			}
+			_ = repo.FS.Remove(taskRunnerFile()) // left by a runner that died
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(f, "%d\n", os.Getpid())
+		f.Close()
+		return func() { _ = repo.FS.Remove(taskRunnerFile()) }, nil
+	}
+	return nil, fmt.Errorf("could not claim %s", taskRunnerFile())
+}
+
+// taskRun sums up a flush.
+type taskRun struct {
+	Done, Failed, Waiting int
+}
+
+// flushTasks runs the queue until nothing is left to try, including tasks
+// queued meanwhile. In the background, tasks that failed taskAttempts times
+// are left alone, and a locked store is waited for up to taskLockWait.
+func flushTasks(background bool) (taskRun, error) {
+	var run taskRun
+	release, err := claimTaskRunner()
+	if err != nil {
+		return run, err
+	}
+	defer release()
+	cfg := loadConfig()
+	setRetry(cfg.Retry)
+	setCopy(cfg.Copy)
+	tried := map[string]bool{}
+	for {
+		tasks, err := loadTasks()
+		if err != nil {
+			return run, err
+		}
+		ran := false
+		for _, t := range tasks {
+			key := t.file() + t.Queued.String()
+			if tried[key] || background && t.Attempts >= taskAttempts {
+				continue
+			}
+			tried[key], ran = true, true
+			wait := time.Duration(0)
+			if background {
+				wait = taskLockWait
+			}
+			switch err := runTask(cfg, t, wait); {
+			case errors.Is(err, errStoreBusy):
+				run.Waiting++
+			case err != nil:
+				run.Failed++
+				if err := failedTask(t, err); err != nil {
+					return run, err
+				}
+			default:
+				run.Done++
+				if err := doneTask(t); err != nil {
+					return run, err
+				}
+			}
+		}
+		if !ran {
+			return run, nil
+		}
+	}
+}
+
+func printTasks() error {
+	tasks, err := loadTasks()
+	if err != nil {
+		return err
+	}
+	if pid := taskRunnerPID(); pid != 0 {
+		fmt.Printf("🏃 Running in the background (process %d)\n", pid)
+	}
+	if len(tasks) == 0 {
+		fmt.Println("✅ No tasks queued")
+		return nil
+	}
+	fmt.Printf("⏳ %d tasks queued:\n", len(tasks))
+	for _, t := range tasks {
+		line := fmt.Sprintf("  v%-6.1f %-8s queued %s", t.Version, t.Kind, t.Queued.Local().Format("2006-01-02 15:04"))
+		if t.Attempts > 0 {
+			line += fmt.Sprintf(", failed %d times: %s", t.Attempts, t.Error)
+		}
+		if t.Attempts >= taskAttempts {
+			line += " (no longer retried in the background)"
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func runTasks(args []string) error {
+	fs := flag.NewFlagSet("tasks", flag.ExitOnError)
+	background := fs.Bool("background", false, "run as the background runner an update starts")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	action := "list"
+	if len(rest) > 0 {
+		action = rest[0]
+	}
+	switch action {
+	case "list":
+		return printTasks()
+	case "flush":
+		if *background {
+			// the runner an update starts is background work, paced as
+			// watch is
+			setThrottle(loadConfig().Throttle)
+		}
+		run, err := flushTasks(*background)
+		if err != nil {
+			return err
+		}
+		switch {
+		case run.Done+run.Failed+run.Waiting == 0:
+			fmt.Println("✅ No tasks queued")
+		case run.Done > 0:
+			fmt.Printf("✅ Ran %d tasks\n", run.Done)
+		}
+		if run.Waiting > 0 {
+			fmt.Printf("⏳ %d tasks wait for the update in progress\n", run.Waiting)
+		}
+		if run.Failed > 0 {
+			return fmt.Errorf("%d tasks failed and stay queued (see gitnot tasks)", run.Failed)
+		}
+		return nil
+	case "clear":
+		if pid := taskRunnerPID(); pid != 0 {
+			return fmt.Errorf("tasks are being run (process %d); try again when it is done", pid)
+		}
+		tasks, err := loadTasks()
+		if err != nil {
+			return err
+		}
+		for _, t := range tasks {
+			if err := repo.FS.Remove(t.file()); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		fmt.Printf("🗑  Dropped %d queued tasks\n", len(tasks))
+		return nil
+	}
+	return fmt.Errorf("usage: gitnot tasks [list|flush|clear]")
+}
+
+func validatePostUpdate(c Config) []configIssue {
+	switch c.PostUpdate {
+	case "", postUpdateInline, postUpdateBackground, postUpdateQueue:
+		return nil
+	}
+	return []configIssue{{"post_update", fmt.Sprintf("unknown value %q; use %q, %q or %q", c.PostUpdate, postUpdateInline, postUpdateBackground, postUpdateQueue)}}
+}
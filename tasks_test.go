@@ -0,0 +1,201 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupQueue initializes a project whose post-update work is queued, with a
+// mirror and a publish target.
+func setupQueue(t *testing.T, mode string) (mirror, site string) {
+	t.Helper()
+	setupTestDir(t)
+	mirror = filepath.Join(t.TempDir(), "novel")
+	site = t.TempDir()
+	createTestFile(t, "ch1.md", "first\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.PostUpdate = mode
+	cfg.Mirrors = []string{mirror}
+	cfg.Publish = []PublishTarget{{Match: "*.md", To: site}}
+	saveJSON(configFile, cfg)
+	return mirror, site
+}
+
+func TestQueuedTasksRunOnFlush(t *testing.T) {
+	mirror, site := setupQueue(t, postUpdateQueue)
+	createTestFile(t, "ch1.md", "second\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(mirror, "version.txt")); err == nil {
+		t.Fatal("Expected the mirror to wait for the queue")
+	}
+	createTestFile(t, "ch2.md", "new\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	// one mirror task for the latest version, publishing per version
+	tasks, err := loadTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, task := range tasks {
+		got = append(got, task.file())
+	}
+	want := []string{"v0.1-publish.json", "mirrors.json", "v0.2-publish.json"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v queued, got %v", want, got)
+	}
+	for i := range want {
+		if filepath.Base(got[i]) != want[i] {
+			t.Errorf("Expected %v queued, got %v", want, got)
+			break
+		}
+	}
+	if tasks[1].Version != 0.2 {
+		t.Errorf("Expected the mirror task for v0.2, got v%.1f", tasks[1].Version)
+	}
+
+	run, err := flushTasks(false)
+	if err != nil || run.Done != 3 || run.Failed != 0 {
+		t.Fatalf("Expected 3 tasks done, got %+v, %v", run, err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(mirror, "version.txt")); strings.TrimSpace(string(b)) != "0.2" {
+		t.Errorf("Expected the mirror at v0.2, got %q", b)
+	}
+	for _, f := range []string{"ch1.md", "ch2.md"} {
+		if _, err := os.Stat(filepath.Join(site, f)); err != nil {
+			t.Errorf("Expected %s to be published: %v", f, err)
+		}
+	}
+	if tasks, _ := loadTasks(); len(tasks) != 0 {
+		t.Errorf("Expected an empty queue, got %v", tasks)
+	}
+	if _, err := os.Stat(taskRunnerFile()); err == nil {
+		t.Error("Expected the runner to let go of the queue")
+	}
+}
+
+func TestBackgroundModeStartsRunner(t *testing.T) {
+	setupQueue(t, postUpdateBackground)
+	started := 0
+	defer func(f func() error) { startTaskRunner = f }(startTaskRunner)
+	startTaskRunner = func() error {
+		started++
+		return nil
+	}
+	createTestFile(t, "ch1.md", "second\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if started != 1 {
+		t.Errorf("Expected one runner to be started, got %d", started)
+	}
+	if err := updateGitnotWith(updateOptions{KeepTasks: true}); err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, "ch1.md", "third\n")
+	if err := updateGitnotWith(updateOptions{KeepTasks: true}); err != nil {
+		t.Fatal(err)
+	}
+	if started != 1 {
+		t.Errorf("Expected no runner when the caller keeps the tasks, got %d", started)
+	}
+}
+
+func TestFailedTasksStayQueued(t *testing.T) {
+	setupQueue(t, postUpdateQueue)
+	cfg := loadConfig()
+	cfg.Mirrors = []string{filepath.Join(t.TempDir(), "unplugged", "novel")}
+	cfg.Publish = nil
+	saveJSON(configFile, cfg)
+	createTestFile(t, "ch1.md", "second\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	for i := 1; i <= taskAttempts; i++ {
+		if run, _ := flushTasks(true); run.Failed != 1 {
+			t.Fatalf("Attempt %d: expected the mirror to fail, got %+v", i, run)
+		}
+	}
+	tasks, _ := loadTasks()
+	if len(tasks) != 1 || tasks[0].Attempts != taskAttempts || !strings.Contains(tasks[0].Error, "not updated") {
+		t.Fatalf("Expected the failure to be kept, got %+v", tasks)
+	}
+	if run, _ := flushTasks(true); run.Failed+run.Done != 0 {
+		t.Errorf("Expected the background runner to give up, got %+v", run)
+	}
+	if run, _ := flushTasks(false); run.Failed != 1 {
+		t.Errorf("Expected a flush by hand to try again, got %+v", run)
+	}
+
+	// packing and mirroring wait while an update holds the store
+	release, err := acquireLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, _ := flushTasks(false)
+	release()
+	if run.Waiting != 1 || run.Failed != 0 {
+		t.Errorf("Expected the mirror to wait for the lock, got %+v", run)
+	}
+	if tasks, _ := loadTasks(); tasks[0].Attempts != taskAttempts+1 {
+		t.Errorf("Waiting should not count as an attempt, got %d", tasks[0].Attempts)
+	}
+}
+
+func TestTaskRunnerIsExclusive(t *testing.T) {
+	setupQueue(t, postUpdateQueue)
+	release, err := claimTaskRunner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := flushTasks(false); err == nil {
+		t.Error("Expected a second runner to be refused")
+	}
+	release()
+
+	// a runner that died doesn't block the queue
+	os.WriteFile(taskRunnerFile(), []byte("999999999\n"), 0o644)
+	if _, err := flushTasks(false); err != nil {
+		t.Errorf("Expected a stale runner file to be taken over: %v", err)
+	}
+}
+
+func TestStillCurrent(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.md", "A\n")
+	createTestFile(t, "b.md", "B, edited again\n")
+	m := Manifest{
+		Files: map[string]string{"a.md": contentHash([]byte("A\n")), "b.md": contentHash([]byte("B\n"))},
+		Changes: []FileChange{
+			{Path: "a.md", State: stateModified},
+			{Path: "b.md", State: stateModified},
+			{Path: "c.md", State: stateDeleted},
+		},
+	}
+	var kept []string
+	for _, c := range stillCurrent(m).Changes {
+		kept = append(kept, c.Path)
+	}
+	if strings.Join(kept, " ") != "a.md c.md" {
+		t.Errorf("Expected a.md and c.md to be kept, got %v", kept)
+	}
+}
+
+func TestValidatePostUpdate(t *testing.T) {
+	if issues := validatePostUpdate(Config{PostUpdate: postUpdateBackground}); len(issues) != 0 {
+		t.Errorf("Unexpected issues %v", issues)
+	}
+	if issues := validatePostUpdate(Config{PostUpdate: "later"}); len(issues) != 1 {
+		t.Errorf("Expected an unknown mode to be reported, got %v", issues)
+	}
+}
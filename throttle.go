@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// --- Throttling ---
+//
+// Background work (gitnot watch, the task runner an update starts, and
+// anything they run) can be capped so it doesn't compete with the editor:
+// io_mb_per_sec limits how fast files are read for hashing and copying, and
+// cpu_percent makes each chunk of work followed by a proportional pause.
+// Commands run by hand, `gitnot tasks flush` included, are never throttled.
+
+// ThrottleConfig is the "throttle" config object.
+type ThrottleConfig struct {
+	IOMBPerSec int `json:"io_mb_per_sec,omitempty"`
+	CPUPercent int `json:"cpu_percent,omitempty"` // 1–99; share of one core to use
+}
+
+// throttle paces reads; the zero value doesn't throttle.
+type throttle struct {
+	mu       sync.Mutex
+	rate     float64 // bytes per second, 0 = unlimited
+	cpuShare float64 // 0 < share < 1, 0 = unlimited
+	start    time.Time
+	bytes    int64
+	last     time.Time
+}
+
+var backgroundThrottle throttle
+
+// setThrottle turns pacing on (or off, for the zero config) for the rest of
+// the process.
+func setThrottle(tc ThrottleConfig) {
+	t := &backgroundThrottle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rate, t.cpuShare = 0, 0
+	if tc.IOMBPerSec > 0 {
+		t.rate = float64(tc.IOMBPerSec) * (1 << 20)
+	}
+	if tc.CPUPercent > 0 && tc.CPUPercent < 100 {
+		t.cpuShare = float64(tc.CPUPercent) / 100
+	}
+	t.start, t.bytes, t.last = time.Time{}, 0, time.Time{}
+}
+
+// pause returns how long to wait after n more bytes were read at now.
+func (t *throttle) pause(n int, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rate == 0 && t.cpuShare == 0 {
+		return 0
+	}
+	var wait time.Duration
+	if t.rate > 0 {
+		if t.start.IsZero() || now.Sub(t.start) > time.Second {
+			t.start, t.bytes = now, 0 // pace within short windows so idle time isn't banked
+		}
+		t.bytes += int64(n)
+		due := t.start.Add(time.Duration(float64(t.bytes) / t.rate * float64(time.Second)))
+		wait = due.Sub(now)
+	}
+	if t.cpuShare > 0 && !t.last.IsZero() {
+		worked := now.Sub(t.last)
+		if worked < 100*time.Millisecond { // longer gaps are idle time, not work
+			wait = max(wait, time.Duration(float64(worked)*(1-t.cpuShare)/t.cpuShare))
+		}
+	}
+	t.last = now.Add(max(wait, 0))
+	return wait
+}
+
+func (t *throttle) active() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rate > 0 || t.cpuShare > 0
+}
+
+type throttledReader struct {
+	r io.Reader
+	t *throttle
+}
+
+func (tr throttledReader) Read(p []byte) (int, error) {
+	n, err := tr.r.Read(p)
+	if d := tr.t.pause(n, time.Now()); d > 0 {
+		time.Sleep(d)
+	}
+	return n, err
+}
+
+// throttled wraps r in the background throttle when one is set.
+func throttled(r io.Reader) io.Reader {
+	if !backgroundThrottle.active() {
+		return r
+	}
+	return throttledReader{r, &backgroundThrottle}
+}
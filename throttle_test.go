@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottlePause(t *testing.T) {
+	var th throttle
+	now := time.Now()
+	if d := th.pause(1<<20, now); d != 0 {
+		t.Errorf("Unthrottled reads should not wait, got %v", d)
+	}
+
+	th.rate = 1 << 20 // 1 MB/s
+	th.pause(0, now)
+	if d := th.pause(512<<10, now); d < 490*time.Millisecond || d > 510*time.Millisecond {
+		t.Errorf("Half a MB at 1 MB/s should wait ~0.5s, got %v", d)
+	}
+
+	th = throttle{cpuShare: 0.25}
+	th.pause(8192, now)
+	if d := th.pause(8192, now.Add(10*time.Millisecond)); d != 30*time.Millisecond {
+		t.Errorf("25%% CPU after 10ms of work should rest 30ms, got %v", d)
+	}
+	if d := th.pause(8192, now.Add(time.Second)); d != 0 {
+		t.Errorf("Idle gaps should not count as work, got %v", d)
+	}
+}
+
+func TestSetThrottle(t *testing.T) {
+	t.Cleanup(func() { setThrottle(ThrottleConfig{}) })
+	setThrottle(ThrottleConfig{IOMBPerSec: 5, CPUPercent: 50})
+	if !backgroundThrottle.active() {
+		t.Fatal("Throttle should be active")
+	}
+	if _, ok := throttled(nil).(throttledReader); !ok {
+		t.Error("Reads should be throttled")
+	}
+	setThrottle(ThrottleConfig{})
+	if backgroundThrottle.active() {
+		t.Error("Zero config should turn throttling off")
+	}
+}
+
+func TestThrottleRange(t *testing.T) {
+	for p, ok := range map[int]bool{0: true, 1: true, 99: true, 100: false, -5: false} {
+		cfg := defaultConfig
+		cfg.Throttle.CPUPercent = p
+		var found bool
+		for _, is := range validateConfig(cfg) {
+			found = found || is.Key == "throttle.cpu_percent"
+		}
+		if found == ok {
+			t.Errorf("cpu_percent %d: valid = %v, want %v", p, !found, ok)
+		}
+	}
+}
+
+func TestBackgroundTaskRunnerIsThrottled(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "a.md", "One\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.Throttle = ThrottleConfig{CPUPercent: 30}
+	if err := saveJSON(configFile, cfg); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { setThrottle(ThrottleConfig{}) })
+
+	if err := runTasks([]string{"flush"}); err != nil {
+		t.Fatal(err)
+	}
+	if backgroundThrottle.active() {
+		t.Error("A flush by hand should not be throttled")
+	}
+	if err := runTasks([]string{"flush", "--background"}); err != nil {
+		t.Fatal(err)
+	}
+	if !backgroundThrottle.active() {
+		t.Error("Expected the background runner to be throttled")
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- Today ---
+//
+// `gitnot today` is an end-of-day review: it compares the tree as it stood
+// before the first version recorded today with the latest version and lists
+// each file that changed with its line and word counts, then the words
+// written overall. --since picks another starting day. Changes not recorded
+// yet are only counted, with a hint to record them.
+
+// dayReview is what changed over a span of versions.
+type dayReview struct {
+	Since    time.Time
+	From, To float64 // From is -1 when the span starts before the first version
+	Versions int
+	Changes  []FileChange
+}
+
+// reviewSince compares the last version before since with the latest one.
+// ok is false when nothing was recorded since then.
+func reviewSince(manifests []Manifest, since time.Time) (r dayReview, ok bool, err error) {
+	r = dayReview{Since: since, From: -1}
+	first := sort.Search(len(manifests), func(i int) bool { return !manifests[i].Timestamp.Before(since) })
+	if first == len(manifests) {
+		return r, false, nil
+	}
+	var base Manifest
+	if first > 0 {
+		if base, err = withFiles(manifests[first-1]); err != nil {
+			return r, false, err
+		}
+		r.From = base.Version
+	}
+	last, err := withFiles(manifests[len(manifests)-1])
+	if err != nil {
+		return r, false, err
+	}
+	r.To, r.Versions = last.Version, len(manifests)-first
+
+	added, changed, deleted := diffTrees(base, last)
+	read := func(rel string, m Manifest) (string, error) {
+		if _, ok := m.Files[rel]; !ok {
+			return "", nil
+		}
+		b, err := contentAt(rel, m)
+		return string(b), err
+	}
+	for _, group := range []struct {
+		paths []string
+		state string
+	}{{added, stateAdded}, {changed, stateModified}, {deleted, stateDeleted}} {
+		for _, rel := range group.paths {
+			before, err := read(rel, base)
+			if err != nil {
+				return r, false, err
+			}
+			after, err := read(rel, last)
+			if err != nil {
+				return r, false, err
+			}
+			fc := measureChange(before, after)
+			fc.Path, fc.State = rel, group.state
+			r.Changes = append(r.Changes, fc)
+		}
+	}
+	sort.Slice(r.Changes, func(i, j int) bool { return r.Changes[i].Path < r.Changes[j].Path })
+	return r, true, nil
+}
+
+func (r dayReview) String() string {
+	var b strings.Builder
+	span := fmt.Sprintf("v%.1f", r.To)
+	if r.From >= 0 {
+		span = fmt.Sprintf("v%.1f → v%.1f", r.From, r.To)
+	}
+	fmt.Fprintf(&b, "📅 Since %s: %s (%d versions)\n", r.Since.Format("Mon 2006-01-02 15:04"), span, r.Versions)
+	var added, removed int
+	for _, c := range r.Changes {
+		rel := filepath.ToSlash(c.Path)
+		switch c.State {
+		case stateAdded:
+			fmt.Fprintf(&b, "  + %s (new, %d words)\n", rel, c.WordsAdded)
+		case stateDeleted:
+			fmt.Fprintf(&b, "  - %s (deleted, %d words)\n", rel, c.WordsRemoved)
+		default:
+			fmt.Fprintf(&b, "  ≠ %s (+%d −%d lines, +%d −%d words)\n", rel, c.LinesAdded, c.LinesRemoved, c.WordsAdded, c.WordsRemoved)
+		}
+		added += c.WordsAdded
+		removed += c.WordsRemoved
+	}
+	if len(r.Changes) == 0 {
+		b.WriteString("  (the files ended up as they started)\n")
+	}
+	fmt.Fprintf(&b, "✍️  %d words written, %d removed (net %+d) across %d files\n", added, removed, added-removed, len(r.Changes))
+	return b.String()
+}
+
+// parseSince reads a --since value: a date, "today" or "yesterday", as
+// local midnight.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch s {
+	case "", "today":
+		return midnight, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--since wants a date like 2024-05-01, \"today\" or \"yesterday\", not %q", s)
+	}
+	return t, nil
+}
+
+func runToday(args []string) error {
+	fs := flag.NewFlagSet("today", flag.ExitOnError)
+	since := fs.String("since", "", "start of the review: a date (2024-05-01), today or yesterday")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	start, err := parseSince(*since, repo.Now())
+	if err != nil {
+		return err
+	}
+	manifests, err := loadManifestHeads()
+	if err != nil {
+		return err
+	}
+	r, ok, err := reviewSince(manifests, start)
+	if err != nil {
+		return err
+	}
+	if ok {
+		fmt.Print(r)
+	} else {
+		fmt.Printf("📭 No versions recorded since %s\n", start.Format("Mon 2006-01-02"))
+	}
+	if st, err := pendingStatus(); err == nil {
+		if n := len(st.Added) + len(st.Modified) + len(st.Deleted); n > 0 {
+			fmt.Printf("💡 %d files have changes not recorded yet; run gitnot to include them\n", n)
+		}
+	}
+	return nil
+}
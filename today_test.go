@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReviewSince(t *testing.T) {
+	setupTestDir(t)
+	createTestFile(t, "ch1.md", "It was a dark night.\n")
+	createTestFile(t, "old.md", "scrap this\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "ch1.md", "It was a dark and stormy night.\nRain fell.\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	createTestFile(t, "scene.md", "A new scene begins here.\n")
+	os.Remove("old.md")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	manifests, err := loadManifests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pretend v0.0 was recorded yesterday.
+	manifests[0].Timestamp = manifests[0].Timestamp.Add(-24 * time.Hour)
+	since, _ := parseSince("today", time.Now())
+	r, ok, err := reviewSince(manifests, since)
+	if err != nil || !ok {
+		t.Fatalf("Expected a review, got %v, %v", ok, err)
+	}
+	if r.From != 0.0 || r.To != 0.2 || r.Versions != 2 || len(r.Changes) != 3 {
+		t.Fatalf("Unexpected review %+v", r)
+	}
+	out := r.String()
+	for _, want := range []string{"v0.0 → v0.2", "≠ ch1.md (+2 −1 lines, +9 −5 words)", "+ scene.md (new, 5 words)", "- old.md (deleted, 2 words)", "14 words written, 7 removed (net +7)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+
+	if _, ok, _ := reviewSince(manifests, since.Add(48*time.Hour)); ok {
+		t.Error("Expected nothing recorded in the future")
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 5, 2, 15, 0, 0, 0, time.Local)
+	if got, _ := parseSince("yesterday", now); !got.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("yesterday = %v", got)
+	}
+	if got, _ := parseSince("2024-04-30", now); got.Day() != 30 {
+		t.Errorf("date = %v", got)
+	}
+	if _, err := parseSince("last week", now); err == nil {
+		t.Error("Expected an error for an unknown value")
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+import "syscall"
+
+// transientErrnos are the errors network filesystems report for a read that
+// may well succeed a moment later.
+var transientErrnos = []syscall.Errno{syscall.EIO, syscall.EBUSY, syscall.EAGAIN, syscall.EINTR, syscall.ETIMEDOUT, syscall.ESTALE}
+
+// inUseErrnos is empty: other programs can't lock files against reading here.
+var inUseErrnos []syscall.Errno
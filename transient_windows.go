@@ -0,0 +1,21 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+// transientErrnos are the errors SMB shares and locked files report for a
+// read that may well succeed a moment later.
+var transientErrnos = []syscall.Errno{
+	32,  // ERROR_SHARING_VIOLATION
+	33,  // ERROR_LOCK_VIOLATION
+	59,  // ERROR_UNEXP_NET_ERR
+	64,  // ERROR_NETNAME_DELETED
+	121, // ERROR_SEM_TIMEOUT
+}
+
+// inUseErrnos mean another program has the file open and won't share it.
+var inUseErrnos = []syscall.Errno{
+	32, // ERROR_SHARING_VIOLATION
+	33, // ERROR_LOCK_VIOLATION
+}
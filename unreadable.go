@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// --- Unreadable files ---
+//
+// A working file that can't be read (locked, a dropped network share, a
+// broken disk sector) used to get the stand-in hash "unreadable-<name>",
+// which then looked like a content change, was stored in hashes.json and
+// the manifest, and made the file look unchanged on every later run that
+// failed the same way. hashFile now returns "" for such a file and notes the
+// error. Status and update hold the file at its recorded version instead of
+// comparing against the stand-in (a file never recorded is left out), list
+// it with the reason, and the manifest of a version recorded meanwhile names
+// it under "unreadable" with the error.
+//
+// The same goes for folders the scan can't list: their recorded files are
+// held rather than recorded as deleted. Only a file that is really gone
+// (not found) counts as deleted; "permission denied" is reported on its own
+// line so it isn't mistaken for damage.
+
+var (
+	readErrMu  sync.Mutex
+	readErrors = map[string]error{}
+)
+
+// noteUnreadable remembers why p could not be read.
+func noteUnreadable(p string, err error) {
+	var pe *os.PathError
+	if errors.As(err, &pe) {
+		err = pe.Err
+	}
+	readErrMu.Lock()
+	readErrors[filepath.Clean(p)] = err
+	readErrMu.Unlock()
+}
+
+// takeUnreadable returns the files of current that could not be read, with
+// why, and forgets every noted error. With current nil it returns them all.
+func takeUnreadable(current map[string]string) map[string]error {
+	readErrMu.Lock()
+	defer readErrMu.Unlock()
+	out := map[string]error{}
+	for p, err := range readErrors {
+		if h, ok := current[p]; current == nil || ok && h == "" {
+			out[p] = err
+		}
+	}
+	readErrors = map[string]error{}
+	return out
+}
+
+// unscannedFiles returns the recorded files inside folders the scan could not
+// list, each with the folder's error.
+func unscannedFiles(dirs map[string]error, old map[string]string) map[string]error {
+	out := map[string]error{}
+	for dir, err := range dirs {
+		var pe *fs.PathError
+		if errors.As(err, &pe) {
+			err = pe.Err
+		}
+		for rel := range old {
+			if dir == "." || within(rel, dir) {
+				out[rel] = err
+			}
+		}
+	}
+	return out
+}
+
+// holdUnreadable takes the unreadable files out of files and gives them their
+// recorded hash, so they count as unchanged; ones never recorded are left
+// out. A file that vanished since the scan is simply gone: it is dropped
+// from unreadable and recorded as deleted.
+func holdUnreadable(files []string, current, old map[string]string, unreadable map[string]error) []string {
+	if len(unreadable) == 0 {
+		return files
+	}
+	drop := map[string]bool{}
+	for p, err := range unreadable {
+		drop[p] = true
+		if errors.Is(err, fs.ErrNotExist) {
+			delete(current, p)
+			delete(unreadable, p)
+			continue
+		}
+		if h, ok := old[p]; ok {
+			current[p] = h
+		} else {
+			delete(current, p)
+		}
+	}
+	var keep []string
+	for _, f := range files {
+		if !drop[f] {
+			keep = append(keep, f)
+		}
+	}
+	return keep
+}
+
+// unreadableErrors is how a manifest records unreadable files.
+func unreadableErrors(unreadable map[string]error) map[string]string {
+	if len(unreadable) == 0 {
+		return nil
+	}
+	out := map[string]string{}
+	for p, err := range unreadable {
+		out[p] = err.Error()
+	}
+	return out
+}
+
+// reportUnreadable lists the files that could not be read: those another
+// program had open, those the permissions keep out, then the rest.
+func reportUnreadable(unreadable map[string]error) {
+	var inUse, denied, other []string
+	for p, err := range unreadable {
+		switch {
+		case isInUse(err):
+			inUse = append(inUse, p)
+		case errors.Is(err, fs.ErrPermission):
+			denied = append(denied, p)
+		default:
+			other = append(other, p)
+		}
+	}
+	sort.Strings(inUse)
+	sort.Strings(denied)
+	sort.Strings(other)
+	show := func(ps []string, withErr bool) string {
+		var out []string
+		for _, p := range preview(ps, 3) {
+			s := filepath.ToSlash(p)
+			if withErr {
+				s += " (" + unreadable[p].Error() + ")"
+			}
+			out = append(out, s)
+		}
+		if len(ps) > 3 {
+			out = append(out, fmt.Sprintf("and %d more", len(ps)-3))
+		}
+		return strings.Join(out, ", ")
+	}
+	if len(inUse) > 0 {
+		fmt.Printf("🔒 Skipped, in use by another program (%d): %s (close them, or see locked_files)\n", len(inUse), show(inUse, false))
+	}
+	if len(denied) > 0 {
+		fmt.Printf("🔐 Permission denied (%d files): %s; their last recorded version is kept\n", len(denied), show(denied, false))
+	}
+	if len(other) > 0 {
+		fmt.Printf("⚠️  Could not read (%d): %s; their last recorded version is kept\n", len(other), show(other, true))
+	}
+}
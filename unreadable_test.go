@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestHoldUnreadable(t *testing.T) {
+	old := map[string]string{"report.docx": "aaa", "notes.md": "bbb"}
+	current := map[string]string{"report.docx": "", "notes.md": "ccc", "new.xlsx": ""}
+	unreadable := map[string]error{"report.docx": os.ErrPermission, "new.xlsx": os.ErrPermission}
+	files := holdUnreadable([]string{"new.xlsx", "notes.md", "report.docx"}, current, old, unreadable)
+	if !reflect.DeepEqual(files, []string{"notes.md"}) {
+		t.Errorf("files = %v", files)
+	}
+	if want := map[string]string{"report.docx": "aaa", "notes.md": "ccc"}; !reflect.DeepEqual(current, want) {
+		t.Errorf("current = %v, want %v", current, want)
+	}
+}
+
+func TestUnreadableFileIsHeldAtItsRecordedVersion(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() == 0 {
+		t.Skip("needs file permissions that stop the current user from reading")
+	}
+	setupTestDir(t)
+
+	createTestFile(t, "chapter.md", "One\n")
+	createTestFile(t, "secret.md", "Secret\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "secret.md", "Secret, edited\n")
+	if err := os.Chmod("secret.md", 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod("secret.md", 0o644) })
+
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.0 {
+		t.Errorf("An unreadable file alone should not record a version, got v%.1f", v)
+	}
+	createTestFile(t, "chapter.md", "Two\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	m, err := loadManifest(0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := m.Files["secret.md"]; h == "" || strings.HasPrefix(h, "unreadable") {
+		t.Errorf("secret.md should keep its recorded hash, got %q", h)
+	}
+	if !strings.Contains(m.Unreadable["secret.md"], "permission denied") {
+		t.Errorf("Expected the read error in the manifest, got %v", m.Unreadable)
+	}
+	if len(m.Changes) != 1 {
+		t.Errorf("Only chapter.md changed, got %+v", m.Changes)
+	}
+	snap, _ := os.ReadFile(filepath.Join(snapshotDir, "secret.md"))
+	if string(snap) != "Secret\n" {
+		t.Errorf("The snapshot should keep the last readable content, got %q", snap)
+	}
+}
+
+func TestDeniedFolderIsNotRecordedAsDeleted(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() == 0 {
+		t.Skip("needs file permissions that stop the current user from reading")
+	}
+	setupTestDir(t)
+
+	createTestFile(t, filepath.Join("private", "a.md"), "A\n")
+	createTestFile(t, filepath.Join("private", "b.md"), "B\n")
+	createTestFile(t, "chapter.md", "One\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := os.Chmod("private", 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod("private", 0o755) })
+	createTestFile(t, "chapter.md", "Two\n")
+
+	st, err := pendingStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Deleted) != 0 || !reflect.DeepEqual(st.Modified, []string{"chapter.md"}) {
+		t.Errorf("Expected only chapter.md modified and nothing deleted, got %+v", st)
+	}
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	m, err := loadManifest(0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Changes) != 1 || len(m.Unreadable) != 2 {
+		t.Errorf("Expected only chapter.md changed and two unreadable files, got %+v / %v", m.Changes, m.Unreadable)
+	}
+	if _, ok := m.Files[filepath.Join("private", "a.md")]; !ok {
+		t.Error("private/a.md should still be tracked")
+	}
+}
+
+func TestVanishedFileCountsAsDeleted(t *testing.T) {
+	current := map[string]string{"gone.md": "", "here.md": "abc"}
+	unreadable := map[string]error{"gone.md": os.ErrNotExist}
+	files := holdUnreadable([]string{"gone.md", "here.md"}, current, map[string]string{"gone.md": "old"}, unreadable)
+	if _, ok := current["gone.md"]; ok || len(unreadable) != 0 || !reflect.DeepEqual(files, []string{"here.md"}) {
+		t.Errorf("A missing file should be dropped, got current %v, unreadable %v, files %v", current, unreadable, files)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// --- Untracking ---
+//
+// `gitnot untrack notes/scratch.md` stops tracking a file without touching
+// it. The path (or glob) goes on .gitnot/untracked.txt, which every scan
+// skips. The next update records the file as "untracked" rather than
+// deleted: it leaves the tracked tree and the hashes, nothing is copied to
+// the deleted store, and its changelog moves to .gitnot/untracked/. Earlier
+// versions keep the file, so it can still be restored from them.
+// `gitnot untrack --undo` tracks it again, changelog included.
+
+const stateUntracked = "untracked"
+
+func loadUntrackList() []string {
+	pats, _ := readPatternFile(untrackListFile)
+	return pats
+}
+
+func saveUntrackList(pats []string) error {
+	if len(pats) == 0 {
+		if err := os.Remove(untrackListFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return writeFileAtomic(untrackListFile, []byte(strings.Join(pats, "\n")+"\n"), 0o644)
+}
+
+// splitUntracked separates, from the paths an update found gone, those that
+// are only gone because they were untracked: listed and still on disk.
+func splitUntracked(gone []string) (untracked, deleted []string) {
+	pats := loadUntrackList()
+	for _, rel := range gone {
+		if _, err := os.Stat(longPath(rel)); err == nil && matchesAny(filepath.ToSlash(rel), pats) {
+			untracked = append(untracked, rel)
+		} else {
+			deleted = append(deleted, rel)
+		}
+	}
+	return untracked, deleted
+}
+
+// moveChangelog moves rel's changelog from one folder to another, if it has
+// one.
+func moveChangelog(rel, from, to string) error {
+	src := filepath.Join(from, rel+".log")
+	if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	dst := filepath.Join(to, rel+".log")
+	if err := safeMkdirAllForFile(dst); err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
+// archiveUntracked runs once an update that untracked files is committed.
+func archiveUntracked(files []string) {
+	for _, rel := range files {
+		if err := moveChangelog(rel, changelogDir, untrackedLogDir); err != nil {
+			fmt.Printf("⚠️  Warning: Could not archive the changelog of %s: %v\n", rel, err)
+		}
+	}
+	if len(files) > 0 {
+		fmt.Printf("🚫 Stopped tracking %d files (kept on disk): %s\n", len(files), strings.Join(preview(files, 3), ", "))
+	}
+}
+
+func runUntrack(args []string) error {
+	fs := flag.NewFlagSet("untrack", flag.ExitOnError)
+	undo := fs.Bool("undo", false, "track the given paths again")
+	pathsFrom := fs.String("paths-from", "", "also untrack the paths listed in this file (- for stdin)")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if *pathsFrom != "" {
+		listed, err := readPathList(*pathsFrom)
+		if err != nil {
+			return err
+		}
+		rest = append(rest, listed...)
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	pats := loadUntrackList()
+	if len(rest) == 0 {
+		if *undo {
+			return fmt.Errorf("usage: gitnot untrack --undo <path|glob>...")
+		}
+		if len(pats) == 0 {
+			fmt.Println("🚫 Nothing untracked")
+			return nil
+		}
+		for _, p := range pats {
+			fmt.Printf("  🚫 %s\n", p)
+		}
+		return nil
+	}
+	if !*undo {
+		if err := requirePassphrase("untrack files"); err != nil {
+			return err
+		}
+	}
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	listed := map[string]bool{}
+	for _, p := range pats {
+		listed[p] = true
+	}
+	tracked := loadCommittedHashes()
+	for _, a := range rest {
+		p := filepath.ToSlash(filepath.Clean(a))
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("%q is not a valid glob", a)
+		}
+		if *undo {
+			if !listed[p] {
+				return fmt.Errorf("%s is not untracked", p)
+			}
+			delete(listed, p)
+			continue
+		}
+		n := 0
+		for rel := range tracked {
+			if matchesAny(filepath.ToSlash(rel), []string{p}) {
+				n++
+			}
+		}
+		if n == 0 {
+			return fmt.Errorf("%s matches no tracked file", a)
+		}
+		listed[p] = true
+	}
+	if err := saveUntrackList(sortedKeys(listed)); err != nil {
+		return err
+	}
+
+	if *undo {
+		// Bring archived changelogs back so history continues.
+		for _, a := range rest {
+			p := filepath.ToSlash(filepath.Clean(a))
+			_ = walkStore(untrackedLogDir, func(q string, d os.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return nil
+				}
+				rel, _ := filepath.Rel(untrackedLogDir, strings.TrimSuffix(q, ".log"))
+				if matchesAny(filepath.ToSlash(rel), []string{p}) {
+					if err := moveChangelog(rel, untrackedLogDir, changelogDir); err != nil {
+						fmt.Printf("⚠️  Warning: Could not bring back the changelog of %s: %v\n", rel, err)
+					}
+				}
+				return nil
+			})
+		}
+		fmt.Printf("👀 Tracking %s again; the next update records it\n", strings.Join(rest, ", "))
+		return nil
+	}
+	fmt.Printf("🚫 Untracked %s; the files stay on disk and the next update records the change\n", strings.Join(rest, ", "))
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUntrackKeepsFile(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "chapter1.md", "One\n")
+	createTestFile(t, "scratch.md", "Notes\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "scratch.md", "Notes\nMore\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if err := runUntrack([]string{"missing.md"}); err == nil {
+		t.Error("Expected an error untracking a file that is not tracked")
+	}
+	if err := runUntrack([]string{"scratch.md"}); err != nil {
+		t.Fatalf("untrack failed: %v", err)
+	}
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	if _, err := os.Stat("scratch.md"); err != nil {
+		t.Fatalf("The file itself should stay: %v", err)
+	}
+	m, err := loadManifest(0.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Files["scratch.md"]; ok {
+		t.Error("The untracked file should leave the manifest")
+	}
+	if len(m.Changes) != 1 || m.Changes[0].State != stateUntracked {
+		t.Errorf("Expected one untracked change, got %+v", m.Changes)
+	}
+	if _, err := os.Stat(filepath.Join(deletedDir, "scratch.md")); err == nil {
+		t.Error("An untracked file should not go to the deleted store")
+	}
+	if _, err := os.Stat(filepath.Join(snapshotDir, "scratch.md")); err == nil {
+		t.Error("An untracked file should leave the snapshot")
+	}
+	if _, err := os.Stat(filepath.Join(changelogDir, "scratch.md.log")); err == nil {
+		t.Error("The changelog should be archived")
+	}
+	if _, err := os.Stat(filepath.Join(untrackedLogDir, "scratch.md.log")); err != nil {
+		t.Errorf("Expected the archived changelog: %v", err)
+	}
+	prev, _ := loadManifest(0.1)
+	if b, err := contentAt("scratch.md", prev); err != nil || string(b) != "Notes\nMore\n" {
+		t.Errorf("Earlier versions should still hold the file, got %q, %v", b, err)
+	}
+
+	createTestFile(t, "scratch.md", "Changed after untracking\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if v, _ := readVersion(); v != 0.2 {
+		t.Errorf("Edits to an untracked file should not make a version, at v%.1f", v)
+	}
+
+	if err := runUntrack([]string{"--undo", "scratch.md"}); err != nil {
+		t.Fatalf("untrack --undo failed: %v", err)
+	}
+	if len(loadUntrackList()) != 0 {
+		t.Errorf("Expected an empty list, got %v", loadUntrackList())
+	}
+	if _, err := os.Stat(filepath.Join(changelogDir, "scratch.md.log")); err != nil {
+		t.Errorf("--undo should bring the changelog back: %v", err)
+	}
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if m, _ := loadManifest(0.3); m.Files["scratch.md"] == "" {
+		t.Error("The file should be tracked again")
+	}
+}
+
+func TestUntrackedFileDeletedLater(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "scratch.md", "Notes\n")
+	createTestFile(t, "keep.md", "Keep\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	if err := runUntrack([]string{"scratch.md"}); err != nil {
+		t.Fatalf("untrack failed: %v", err)
+	}
+	os.Remove("scratch.md")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	m, _ := loadManifest(0.1)
+	if len(m.Changes) != 1 || m.Changes[0].State != stateDeleted {
+		t.Errorf("A listed file that is gone from disk is a deletion, got %+v", m.Changes)
+	}
+}
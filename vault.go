@@ -0,0 +1,373 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// --- Vault mode ---
+//
+// A folder with an .obsidian/ directory is treated as an Obsidian vault: the
+// app's own state (.obsidian/, .trash/) is ignored, canvas files are tracked
+// and diffed by structure rather than by line, and renamed notes can have
+// their [[wiki-links]] rewritten. A folder with logseq/config.edn is treated
+// as a Logseq graph: its backups are ignored, .org pages are tracked and the
+// properties Logseq writes on its own are left out of diffs.
+
+// VaultConfig selects vault handling. Mode "" auto-detects, "obsidian" or
+// "logseq" forces one, "none" turns it off.
+type VaultConfig struct {
+	Mode         string `json:"mode,omitempty"`
+	RewriteLinks bool   `json:"rewrite_links,omitempty"` // update [[links]] when a note is renamed
+
+	// IgnoreProperties lists `key:: value` block properties hidden from
+	// changelog diffs (Logseq default: collapsed, id).
+	IgnoreProperties []string `json:"ignore_properties,omitempty"`
+}
+
+const (
+	vaultObsidian = "obsidian"
+	vaultLogseq   = "logseq"
+	vaultNone     = "none"
+)
+
+var (
+	obsidianIgnores    = []string{".obsidian/*", ".trash/*"}
+	obsidianExtensions = []string{".canvas"}
+
+	logseqIgnores    = []string{"logseq/bak/*", "logseq/.recycle/*"}
+	logseqExtensions = []string{".org"}
+	logseqProperties = []string{"collapsed", "id"}
+)
+
+// vaultMode resolves the configured mode against the working tree.
+func (c Config) vaultMode() string {
+	if c.Vault.Mode != "" {
+		return c.Vault.Mode
+	}
+	if fi, err := os.Stat(".obsidian"); err == nil && fi.IsDir() {
+		return vaultObsidian
+	}
+	if _, err := os.Stat(filepath.Join("logseq", "config.edn")); err == nil {
+		return vaultLogseq
+	}
+	return vaultNone
+}
+
+// propertyMask returns a matcher for block-property lines that diffs should
+// ignore, or nil when none are configured.
+func (c Config) propertyMask() func(string) bool {
+	props := c.Vault.IgnoreProperties
+	if len(props) == 0 && c.vaultMode() == vaultLogseq {
+		props = logseqProperties
+	}
+	if len(props) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(props))
+	for i, p := range props {
+		quoted[i] = regexp.QuoteMeta(p)
+	}
+	re := regexp.MustCompile(`^\s*(-\s+)?(` + strings.Join(quoted, "|") + `)::(\s|$)`)
+	return re.MatchString
+}
+
+// --- Renames ---
+
+// detectRenames pairs deleted paths with new paths holding identical
+// content. Each new path is claimed at most once.
+func detectRenames(newFiles, deletedFiles []string, current, old map[string]string) map[string]string {
+	renames := map[string]string{}
+	claimed := map[string]bool{}
+	for _, d := range deletedFiles {
+		for _, n := range newFiles {
+			if !claimed[n] && current[n] == old[d] {
+				renames[d] = n
+				claimed[n] = true
+				break
+			}
+		}
+	}
+	return renames
+}
+
+// --- Wiki-links ---
+
+var wikiLinkRe = regexp.MustCompile(`\[\[([^\]|#^]+)([#^|][^\]]*)?\]\]`)
+
+// linkName is how Obsidian refers to a file: its slash path, without the
+// .md extension for notes.
+func linkName(rel string) string {
+	rel = filepath.ToSlash(rel)
+	if strings.EqualFold(path.Ext(rel), ".md") {
+		rel = strings.TrimSuffix(rel, path.Ext(rel))
+	}
+	return rel
+}
+
+// rewriteWikiLinks points links at oldRel to newRel and returns the new text
+// and how many links changed. Links written as a bare note name stay bare;
+// links written as a path get the new path.
+func rewriteWikiLinks(text, oldRel, newRel string) (string, int) {
+	oldFull, newFull := linkName(oldRel), linkName(newRel)
+	oldBase, newBase := path.Base(oldFull), path.Base(newFull)
+	n := 0
+	out := wikiLinkRe.ReplaceAllStringFunc(text, func(m string) string {
+		sub := wikiLinkRe.FindStringSubmatch(m)
+		target := strings.TrimSpace(sub[1])
+		var repl string
+		switch target {
+		case oldFull:
+			repl = newFull
+		case oldBase:
+			repl = newBase
+		default:
+			return m
+		}
+		n++
+		return "[[" + repl + sub[2] + "]]"
+	})
+	return out, n
+}
+
+// countWikiLinks reports how many links in text point at rel.
+func countWikiLinks(text, rel string) int {
+	_, n := rewriteWikiLinks(text, rel, rel)
+	return n
+}
+
+// applyLinkRewrites updates links to renamed notes across the tracked
+// markdown files and returns the files it changed.
+func applyLinkRewrites(files []string, renames map[string]string) ([]string, error) {
+	var changed []string
+	for _, f := range files {
+		if !strings.EqualFold(filepath.Ext(f), ".md") {
+			continue
+		}
+		b, err := os.ReadFile(longPath(f))
+		if err != nil {
+			continue
+		}
+		text, total := string(b), 0
+		for _, oldRel := range sortedKeys(renames) {
+			var n int
+			text, n = rewriteWikiLinks(text, oldRel, renames[oldRel])
+			total += n
+		}
+		if total == 0 {
+			continue
+		}
+		fi, err := os.Stat(longPath(f))
+		if err != nil {
+			return changed, err
+		}
+		if err := writeFileAtomic(longPath(f), []byte(text), fi.Mode().Perm()); err != nil {
+			return changed, err
+		}
+		changed = append(changed, f)
+	}
+	return changed, nil
+}
+
+// filesLinkingTo lists the notes among files that link to a renamed note.
+func filesLinkingTo(files []string, renames map[string]string) []string {
+	var out []string
+	for _, f := range files {
+		if !strings.EqualFold(filepath.Ext(f), ".md") {
+			continue
+		}
+		b, err := os.ReadFile(longPath(f))
+		if err != nil {
+			continue
+		}
+		for oldRel := range renames {
+			if countWikiLinks(string(b), oldRel) > 0 {
+				out = append(out, f)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// staleLinkCount counts links across tracked markdown files that still point
+// at the old names of renamed notes.
+func staleLinkCount(files []string, renames map[string]string) int {
+	total := 0
+	for _, f := range files {
+		if !strings.EqualFold(filepath.Ext(f), ".md") {
+			continue
+		}
+		b, err := os.ReadFile(longPath(f))
+		if err != nil {
+			continue
+		}
+		for oldRel := range renames {
+			total += countWikiLinks(string(b), oldRel)
+		}
+	}
+	return total
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// --- Structural diffs ---
+
+// structuredFile reports whether rel is diffed as JSON structure in vault mode.
+func structuredFile(rel string) bool {
+	switch strings.ToLower(filepath.Ext(rel)) {
+	case ".canvas", ".json":
+		return true
+	}
+	return false
+}
+
+type jsonDiff struct {
+	added, removed, changed []string
+}
+
+// structuralDiff compares two JSON documents and renders the differences as
+// changelog markdown. ok is false when either side is not valid JSON.
+func structuralDiff(oldB, newB []byte) (md string, ok bool) {
+	var a, b any
+	if json.Unmarshal(oldB, &a) != nil || json.Unmarshal(newB, &b) != nil {
+		return "", false
+	}
+	var d jsonDiff
+	diffJSON("", a, b, &d)
+	if len(d.added)+len(d.removed)+len(d.changed) == 0 {
+		return "📄 File changed (formatting only)\n", true
+	}
+	var sb strings.Builder
+	for _, sec := range []struct {
+		title string
+		lines []string
+	}{{"### ➕ Added\n", d.added}, {"### ➖ Removed\n", d.removed}, {"### ✏️ Changed\n", d.changed}} {
+		if len(sec.lines) == 0 {
+			continue
+		}
+		sb.WriteString(sec.title)
+		for _, l := range sec.lines {
+			sb.WriteString(l)
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), true
+}
+
+func diffJSON(p string, a, b any, d *jsonDiff) {
+	if reflect.DeepEqual(a, b) {
+		return
+	}
+	if am, ok := a.(map[string]any); ok {
+		if bm, ok := b.(map[string]any); ok {
+			diffKeyed(p, ".", am, bm, d)
+			return
+		}
+	}
+	if aa, ok := a.([]any); ok {
+		if ba, ok := b.([]any); ok {
+			if ak, ok := keyedByID(aa); ok {
+				if bk, ok := keyedByID(ba); ok {
+					diffKeyed(p, "id=", ak, bk, d)
+					return
+				}
+			}
+			for i := 0; i < len(aa) || i < len(ba); i++ {
+				ip := fmt.Sprintf("%s[%d]", p, i)
+				switch {
+				case i >= len(aa):
+					d.added = append(d.added, jsonPath(ip)+": "+jsonValue(ba[i]))
+				case i >= len(ba):
+					d.removed = append(d.removed, jsonPath(ip)+": "+jsonValue(aa[i]))
+				default:
+					diffJSON(ip, aa[i], ba[i], d)
+				}
+			}
+			return
+		}
+	}
+	d.changed = append(d.changed, fmt.Sprintf("%s: %s → %s", jsonPath(p), jsonValue(a), jsonValue(b)))
+}
+
+// diffKeyed diffs two keyed collections: object members (sep ".") or array
+// elements identified by their "id" (sep "id=", as canvas nodes and edges are).
+func diffKeyed(p, sep string, a, b map[string]any, d *jsonDiff) {
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	for _, k := range sorted {
+		kp := p + "." + k
+		if sep != "." {
+			kp = fmt.Sprintf("%s[%s%s]", p, sep, k)
+		}
+		av, inA := a[k]
+		bv, inB := b[k]
+		switch {
+		case !inA:
+			d.added = append(d.added, jsonPath(kp)+": "+jsonValue(bv))
+		case !inB:
+			d.removed = append(d.removed, jsonPath(kp)+": "+jsonValue(av))
+		default:
+			diffJSON(kp, av, bv, d)
+		}
+	}
+}
+
+// keyedByID indexes an array of objects by their string "id" field.
+func keyedByID(arr []any) (map[string]any, bool) {
+	out := map[string]any{}
+	for _, e := range arr {
+		m, ok := e.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		id, ok := m["id"].(string)
+		if !ok || out[id] != nil {
+			return nil, false
+		}
+		out[id] = e
+	}
+	return out, true
+}
+
+func jsonPath(p string) string {
+	p = strings.TrimPrefix(p, ".")
+	if p == "" {
+		return "(root)"
+	}
+	return p
+}
+
+// jsonValue renders a value compactly for a changelog line.
+func jsonValue(v any) string {
+	b, _ := json.Marshal(v)
+	if r := []rune(string(b)); len(r) > 80 {
+		return string(r[:77]) + "..."
+	}
+	return string(b)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRewriteWikiLinks(t *testing.T) {
+	text := "See [[Old Note]], [[notes/Old Note#Intro]] and ![[Old Note|alias]]. Not [[Other]]."
+	out, n := rewriteWikiLinks(text, "notes/Old Note.md", "archive/New Note.md")
+	if n != 3 {
+		t.Errorf("Expected 3 links rewritten, got %d", n)
+	}
+	want := "See [[New Note]], [[archive/New Note#Intro]] and ![[New Note|alias]]. Not [[Other]]."
+	if out != want {
+		t.Errorf("Unexpected rewrite:\n got %q\nwant %q", out, want)
+	}
+}
+
+func TestStructuralDiffCanvas(t *testing.T) {
+	oldB := []byte(`{"nodes":[{"id":"a","text":"Intro","x":0},{"id":"b","text":"Gone"}],"edges":[]}`)
+	newB := []byte(`{"nodes":[{"id":"a","text":"Intro","x":40},{"id":"c","text":"New"}],"edges":[]}`)
+	md, ok := structuralDiff(oldB, newB)
+	if !ok {
+		t.Fatal("Expected valid JSON to diff")
+	}
+	for _, want := range []string{`nodes[id=c]: {"id":"c","text":"New"}`, `nodes[id=b]:`, `nodes[id=a].x: 0 → 40`} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected %q in diff:\n%s", want, md)
+		}
+	}
+	if md, _ := structuralDiff([]byte(`{"a":1}`), []byte("{\n  \"a\": 1\n}")); !strings.Contains(md, "formatting only") {
+		t.Errorf("Reformatting should be reported as formatting only, got:\n%s", md)
+	}
+	if _, ok := structuralDiff([]byte("not json"), newB); ok {
+		t.Error("Invalid JSON should fall back to the line diff")
+	}
+}
+
+func TestObsidianVault(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, ".obsidian/app.json", "{}")
+	createTestFile(t, ".trash/old.md", "trashed")
+	createTestFile(t, "board.canvas", `{"nodes":[]}`)
+	createTestFile(t, "Draft.md", "A note")
+	createTestFile(t, "index.md", "Start at [[Draft]]")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+
+	files, _ := getAllTextFiles(".")
+	joined := strings.Join(files, " ")
+	if strings.Contains(joined, ".obsidian") || strings.Contains(joined, ".trash") {
+		t.Errorf("Vault state should be ignored, got %v", files)
+	}
+	if !strings.Contains(joined, "board.canvas") {
+		t.Errorf("Canvas files should be tracked, got %v", files)
+	}
+
+	cfg := loadConfig()
+	cfg.Vault.RewriteLinks = true
+	saveJSON(configFile, cfg)
+	os.Rename("Draft.md", "Chapter One.md")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	b, _ := os.ReadFile("index.md")
+	if string(b) != "Start at [[Chapter One]]" {
+		t.Errorf("Link not rewritten: %q", b)
+	}
+	if err := showStatus(); err != nil {
+		t.Fatalf("showStatus failed: %v", err)
+	}
+	if m, err := loadManifest(0.1); err != nil || m.Files["index.md"] != hashFile("index.md") {
+		t.Error("Rewritten note should be recorded in the same version")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --- Verify ---
+//
+// `gitnot verify` reads back every stored object and snapshot file and
+// checks it against the hash it was recorded under, like scrub, but changes
+// nothing: it reports damage and leaves quarantining and rebuilding to
+// `gitnot scrub`, so it is safe to run at any time, even while an update
+// is running. Both spread the reading over several files at once (--jobs)
+// and show progress on a terminal. --paths limits either one to the content
+// recorded for files under a folder, so checking one part of a huge vault
+// doesn't mean reading all of it. `gitnot verify --signatures` checks
+// signatures instead (see signing.go).
+
+// checkPaths turns --paths arguments into paths relative to the project.
+func checkPaths(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	return parsePathList([]byte(strings.Join(args, "\n")))
+}
+
+// verifyStore checks the stored copies of the content recorded for files
+// under paths (everything when empty) and returns how many it checked and
+// the damaged ones.
+func verifyStore(paths []string, jobs int) (int, []scrubDamage, error) {
+	copies, err := storedCopies(paths)
+	if err != nil {
+		return 0, nil, err
+	}
+	where := recordedAt()
+	var damaged []scrubDamage
+	for i, err := range checkCopies(copies, jobs) {
+		if err == nil {
+			continue
+		}
+		c := copies[i]
+		files := where[c.Hash]
+		if c.Rel != "" {
+			files = []string{c.Rel}
+		}
+		damaged = append(damaged, scrubDamage{Hash: c.Hash, Where: c.Path, Files: files, Reason: err.Error()})
+	}
+	return len(copies), damaged, nil
+}
+
+func verifyContent(paths []string, jobs int) error {
+	checked, damaged, err := verifyStore(paths, jobs)
+	if err != nil {
+		return err
+	}
+	scope := ""
+	if len(paths) > 0 {
+		scope = " under " + strings.Join(slashPaths(paths), ", ")
+	}
+	fmt.Printf("🔎 Checked %d stored copies%s\n", checked, scope)
+	if len(damaged) == 0 {
+		fmt.Println("✅ Everything matches its recorded hash")
+		return nil
+	}
+	fmt.Printf("❌ %d damaged:\n", len(damaged))
+	for _, d := range damaged {
+		fmt.Printf("  • %s (%s)\n", d.Where, d.Reason)
+		if len(d.Files) > 0 {
+			fmt.Printf("    holds %s\n", strings.Join(preview(d.Files, 4), ", "))
+		}
+	}
+	return fmt.Errorf("%d damaged copies; run 'gitnot scrub' to quarantine them and rebuild what can be", len(damaged))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyReportsDamageWithoutTouchingIt(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "draft/ch1.md", "first\n")
+	createTestFile(t, "notes/idea.md", "idea\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	checked, damaged, err := verifyStore(nil, 4)
+	if err != nil || checked != 4 || len(damaged) != 0 {
+		t.Fatalf("Expected 4 intact copies, got %d, %+v, %v", checked, damaged, err)
+	}
+
+	ch1 := contentHash([]byte("first\n"))
+	os.WriteFile(objectPath(ch1), []byte("fir5t\n"), 0o644)
+	snap := filepath.Join(snapshotDir, "notes", "idea.md")
+	os.WriteFile(snap, []byte("rot\n"), 0o644)
+
+	_, damaged, err = verifyStore(nil, 4)
+	if err != nil || len(damaged) != 2 {
+		t.Fatalf("Expected the object and the snapshot file reported, got %+v, %v", damaged, err)
+	}
+	if d := damaged[0]; d.Hash != ch1 || d.Moved || len(d.Files) != 1 || d.Files[0] != "draft/ch1.md@v0.0" {
+		t.Errorf("Unexpected object damage %+v", d)
+	}
+	if b, _ := os.ReadFile(objectPath(ch1)); string(b) != "fir5t\n" {
+		t.Error("verify must not move or repair anything")
+	}
+	if b, _ := os.ReadFile(snap); string(b) != "rot\n" {
+		t.Error("verify must not rebuild snapshot files")
+	}
+
+	// Limited to a folder, only the content recorded under it is read.
+	checked, damaged, err = verifyStore([]string{"draft"}, 2)
+	if err != nil || checked != 2 || len(damaged) != 1 || damaged[0].Hash != ch1 {
+		t.Errorf("Expected only draft/ checked, got %d, %+v, %v", checked, damaged, err)
+	}
+	checked, damaged, err = verifyStore([]string{"elsewhere"}, 2)
+	if err != nil || checked != 0 || len(damaged) != 0 {
+		t.Errorf("Expected nothing checked, got %d, %+v, %v", checked, damaged, err)
+	}
+	if _, err := checkPaths([]string{"../outside"}); err == nil {
+		t.Error("Expected paths outside the project to be refused")
+	}
+}
+
+func TestLimitedScrubIsNotSaved(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a/x.md", "x\n")
+	createTestFile(t, "b/y.md", "y\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	os.WriteFile(filepath.Join(snapshotDir, "b", "y.md"), []byte("rot\n"), 0o644)
+
+	res, err := scrubStore(scrubOptions{Paths: []string{"a"}, Jobs: 2})
+	if err != nil || len(res.Damaged) != 0 || res.Snapshot != 1 {
+		t.Fatalf("Expected a clean scrub of a/, got %+v, %v", res, err)
+	}
+	if _, err := os.Stat(scrubFile); !os.IsNotExist(err) {
+		t.Error("A scrub limited with --paths should not count as the scheduled one")
+	}
+	res, err = scrubStore(scrubOptions{Paths: []string{"b"}})
+	if err != nil || len(res.Rebuilt) != 1 {
+		t.Fatalf("Expected b/y.md rebuilt, got %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"io/fs"
+	"maps"
+	"slices"
+
+	"gitnot/versionfs"
+)
+
+// --- Version file systems ---
+//
+// Repo.VersionFS presents the tree recorded at one version as an io/fs.FS
+// (see package versionfs), reading content from the object store on Open.
+
+// VersionFS returns the tree recorded at version v. The result also
+// implements fs.ReadDirFS.
+func (r Repo) VersionFS(v float64) (fs.FS, error) {
+	m, err := loadManifest(v)
+	if err != nil {
+		return nil, err
+	}
+	return newVersionFS(m), nil
+}
+
+func newVersionFS(m Manifest) *versionfs.FS {
+	return versionfs.New(slices.Collect(maps.Keys(m.Files)), m.Timestamp, func(rel string) ([]byte, error) {
+		return contentAt(rel, m)
+	})
+}
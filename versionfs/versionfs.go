@@ -0,0 +1,183 @@
+// Package versionfs presents the files recorded at one gitnot version as an
+// io/fs.FS, so code that reads files (templates, site generators,
+// fs.WalkDir) can work on any past state without extracting it to disk
+// first. Content is read on Open through the function given to New; folders
+// are derived from the recorded paths.
+package versionfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// ReadFunc returns the content of a recorded file, named as the version
+// lists it.
+type ReadFunc func(name string) ([]byte, error)
+
+// FS is the tree of one version. It implements fs.ReadDirFS.
+type FS struct {
+	read  ReadFunc
+	mod   time.Time
+	files map[string]string   // slash path → name as recorded
+	dirs  map[string][]string // slash dir → sorted child names
+}
+
+// New returns the tree of the given files, which are named with the
+// system's separator as a version records them. Every entry reports modTime,
+// the time the version was recorded.
+func New(files []string, modTime time.Time, read ReadFunc) *FS {
+	vfs := &FS{read: read, mod: modTime, files: map[string]string{}, dirs: map[string][]string{".": nil}}
+	children := map[string]map[string]bool{}
+	for _, rel := range files {
+		p := filepath.ToSlash(rel)
+		vfs.files[p] = rel
+		for p != "." {
+			dir := path.Dir(p)
+			if children[dir] == nil {
+				children[dir] = map[string]bool{}
+			}
+			children[dir][path.Base(p)] = true
+			p = dir
+		}
+	}
+	for dir, names := range children {
+		vfs.dirs[dir] = slices.Sorted(maps.Keys(names))
+	}
+	return vfs
+}
+
+// Open opens a recorded file or one of the folders holding them.
+func (vfs *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if rel, ok := vfs.files[name]; ok {
+		b, err := vfs.read(rel)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &vfsFile{info: vfs.info(name, int64(len(b)), false), r: bytes.NewReader(b)}, nil
+	}
+	if _, ok := vfs.dirs[name]; ok {
+		return &versionDir{vfs: vfs, name: name, info: vfs.info(name, 0, true)}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir lists a folder, sorted by name.
+func (vfs *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	names, ok := vfs.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := make([]fs.DirEntry, len(names))
+	for i, n := range names {
+		p := path.Join(name, n)
+		_, isDir := vfs.dirs[p]
+		entries[i] = versionDirEntry{vfs: vfs, path: p, dir: isDir}
+	}
+	return entries, nil
+}
+
+// versionDirEntry reads a file's content only when Info asks for its size.
+type versionDirEntry struct {
+	vfs  *FS
+	path string
+	dir  bool
+}
+
+func (e versionDirEntry) Name() string { return path.Base(e.path) }
+func (e versionDirEntry) IsDir() bool  { return e.dir }
+
+func (e versionDirEntry) Type() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+func (e versionDirEntry) Info() (fs.FileInfo, error) {
+	if e.dir {
+		return e.vfs.info(e.path, 0, true), nil
+	}
+	return fs.Stat(e.vfs, e.path)
+}
+
+func (vfs *FS) info(name string, size int64, dir bool) versionFileInfo {
+	return versionFileInfo{name: path.Base(name), size: size, dir: dir, mod: vfs.mod}
+}
+
+type versionFileInfo struct {
+	name string
+	size int64
+	dir  bool
+	mod  time.Time
+}
+
+func (fi versionFileInfo) Name() string       { return fi.name }
+func (fi versionFileInfo) Size() int64        { return fi.size }
+func (fi versionFileInfo) ModTime() time.Time { return fi.mod }
+func (fi versionFileInfo) IsDir() bool        { return fi.dir }
+func (fi versionFileInfo) Sys() any           { return nil }
+
+func (fi versionFileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+type vfsFile struct {
+	info versionFileInfo
+	r    *bytes.Reader
+}
+
+func (f *vfsFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *vfsFile) Read(b []byte) (int, error) { return f.r.Read(b) }
+func (f *vfsFile) Close() error               { return nil }
+
+type versionDir struct {
+	vfs     *FS
+	name    string
+	info    versionFileInfo
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *versionDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *versionDir) Close() error               { return nil }
+
+func (d *versionDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *versionDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.vfs.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.read = entries, true
+	}
+	if n <= 0 {
+		out := d.entries
+		d.entries = nil
+		return out, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	k := min(n, len(d.entries))
+	out := d.entries[:k]
+	d.entries = d.entries[k:]
+	return out, nil
+}
+
+var _ fs.ReadDirFS = (*FS)(nil)
@@ -0,0 +1,50 @@
+package versionfs
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	content := map[string]string{
+		filepath.Join("chapters", "one", "ch1.md"): "first\n",
+		filepath.Join("chapters", "ch2.md"):        "new\n",
+		"readme.md":                                "hello\n",
+	}
+	var files []string
+	for rel := range content {
+		files = append(files, rel)
+	}
+	at := time.Date(2024, 3, 9, 14, 30, 0, 0, time.UTC)
+	reads := 0
+	vfs := New(files, at, func(name string) ([]byte, error) {
+		reads++
+		if c, ok := content[name]; ok {
+			return []byte(c), nil
+		}
+		return nil, errors.New("not recorded")
+	})
+
+	if reads != 0 {
+		t.Errorf("Expected nothing read before Open, got %d reads", reads)
+	}
+	if err := fstest.TestFS(vfs, "readme.md", "chapters/ch2.md", "chapters/one/ch1.md"); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fs.ReadFile(vfs, "chapters/one/ch1.md"); err != nil || string(b) != "first\n" {
+		t.Errorf("Expected the recorded content, got %q, %v", b, err)
+	}
+	if fi, err := fs.Stat(vfs, "chapters"); err != nil || !fi.IsDir() || !fi.ModTime().Equal(at) {
+		t.Errorf("Expected a folder stamped %v, got %v, %v", at, fi, err)
+	}
+	if _, err := vfs.Open("missing.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+	if _, err := vfs.Open("../readme.md"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Expected fs.ErrInvalid, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVersionFS(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, filepath.Join("chapters", "one", "ch1.md"), "first\n")
+	createTestFile(t, "readme.md", "hello\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, filepath.Join("chapters", "one", "ch1.md"), "second\n")
+	createTestFile(t, filepath.Join("chapters", "ch2.md"), "new\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	old, err := repo.VersionFS(0.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(old, "readme.md", "chapters/one/ch1.md"); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fs.ReadFile(old, "chapters/one/ch1.md"); err != nil || string(b) != "first\n" {
+		t.Errorf("Expected v0.0 content, got %q, %v", b, err)
+	}
+	if _, err := fs.Stat(old, "chapters/ch2.md"); err == nil {
+		t.Error("chapters/ch2.md did not exist at v0.0")
+	}
+
+	cur, err := repo.VersionFS(0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(cur, "readme.md", "chapters/ch2.md", "chapters/one/ch1.md"); err != nil {
+		t.Fatal(err)
+	}
+	var walked []string
+	err = fs.WalkDir(cur, ".", func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			walked = append(walked, p)
+		}
+		return err
+	})
+	if want := []string{"chapters/ch2.md", "chapters/one/ch1.md", "readme.md"}; err != nil || !slices.Equal(walked, want) {
+		t.Errorf("Expected fs.WalkDir to visit %v, got %v, %v", want, walked, err)
+	}
+	if _, err := repo.VersionFS(0.5); err == nil {
+		t.Error("Expected an error for a version that was never recorded")
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// --- Version IDs ---
+//
+// Version numbers (v0.7) are per store, so two machines recording into a
+// synced folder can both produce a "v0.7". Every manifest therefore also
+// carries a globally unique ID: the recording device plus that device's own
+// sequence number ("3fa91c-12" is the twelfth version recorded on device
+// 3fa91c). The vector clock next to it records the latest sequence number
+// seen from every device, so two histories can later be told apart from a
+// linear one. The device ID is generated once per machine and kept in the
+// user config, outside the shared folder.
+//
+// Each device also notes the last version it recorded in devices/<id>.json.
+// Those files never collide between machines, so after a sync they still
+// show every device's latest version when the sync service kept only one of
+// two manifests both numbered v0.7. An update compares each of them with the
+// clock of the version it builds on: one the clock hasn't seen was recorded
+// on a history that diverged. The update says so and merges that clock into
+// its own, so each divergence is reported once. An update also refuses a
+// version number whose manifest another machine already wrote.
+
+// Device identifies this machine in version IDs.
+type Device struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"` // defaults to the host name
+}
+
+// currentDevice returns this machine's device, creating its ID on first use.
+func currentDevice() Device {
+	uc := loadUserConfig()
+	if uc.Device.ID == "" {
+		b := make([]byte, 3)
+		_, _ = rand.Read(b)
+		uc.Device.ID = hex.EncodeToString(b)
+		if uc.Device.Name == "" {
+			uc.Device.Name, _ = os.Hostname()
+		}
+		if err := saveUserConfig(uc); err != nil {
+			fmt.Printf("⚠️  Warning: Could not save device ID: %v\n", err)
+		}
+	}
+	return uc.Device
+}
+
+// nextVersionID advances prev's clock for device and returns the new ID and
+// clock; prev is not modified.
+func nextVersionID(device string, prev map[string]int) (string, map[string]int) {
+	clock := maps.Clone(prev)
+	if clock == nil {
+		clock = map[string]int{}
+	}
+	clock[device]++
+	return fmt.Sprintf("%s-%d", device, clock[device]), clock
+}
+
+// versionByID finds the version recorded under a version ID.
+func versionByID(id string) (float64, bool) {
+	var v float64
+	found := false
+	_ = eachManifest(func(m Manifest) error {
+		if m.ID == id {
+			v, found = m.Version, true
+			return errStopManifests
+		}
+		return nil
+	})
+	return v, found
+}
+
+// deviceHead is the last version a device recorded, kept in devices/.
+type deviceHead struct {
+	ID      string         `json:"id"`
+	Version float64        `json:"version"`
+	Clock   map[string]int `json:"clock"`
+}
+
+func recordDeviceHead(device string, m Manifest) error {
+	return saveJSON(filepath.Join(devicesDir, device+".json"), deviceHead{ID: m.ID, Version: m.Version, Clock: m.Clock})
+}
+
+// clockCovers reports whether clock a has seen everything clock b has.
+func clockCovers(a, b map[string]int) bool {
+	for d, n := range b {
+		if a[d] < n {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeClocks returns the latest sequence number of each device in a or b.
+func mergeClocks(a, b map[string]int) map[string]int {
+	out := maps.Clone(a)
+	if out == nil {
+		out = map[string]int{}
+	}
+	for d, n := range b {
+		out[d] = max(out[d], n)
+	}
+	return out
+}
+
+// divergedHeads returns the device heads clock, the clock of version prev,
+// hasn't seen: versions recorded on another line of history, sorted by ID.
+// This device's own head past prev is from an update that never committed
+// (the head is noted just before version.txt) and doesn't count.
+func divergedHeads(clock map[string]int, prev float64) []deviceHead {
+	entries, _ := repo.FS.ReadDir(devicesDir)
+	self := currentDevice().ID + ".json"
+	var out []deviceHead
+	for _, e := range entries {
+		var h deviceHead
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") || loadJSON(filepath.Join(devicesDir, e.Name()), &h) != nil {
+			continue
+		}
+		if e.Name() == self && h.Version > prev {
+			continue
+		}
+		if !clockCovers(clock, h.Clock) {
+			out = append(out, h)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// mergeDiverged warns about every device head the clock of version prev
+// hasn't seen and returns that clock with theirs merged in.
+func mergeDiverged(clock map[string]int, prev float64) map[string]int {
+	for _, h := range divergedHeads(clock, prev) {
+		fmt.Printf("⚠️  Warning: %s (recorded as v%.1f) is not in this history: two machines recorded versions "+
+			"without seeing each other's and the sync kept only one. Look for its files in your sync service's conflict copies.\n", h.ID, h.Version)
+		clock = mergeClocks(clock, h.Clock)
+	}
+	return clock
+}
+
+// checkVersionFree fails with errStoreChanged when a manifest for ver
+// already exists: another machine recorded that number and the sync
+// delivered its manifest ahead of its version.txt.
+func checkVersionFree(ver float64) error {
+	var m Manifest
+	if err := loadJSON(manifestPath(ver), &m); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return fmt.Errorf("v%.1f is already recorded (id %q): %w", ver, m.ID, errStoreChanged)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVersionIDs(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "a.md", "two\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+
+	dev := currentDevice().ID
+	if len(dev) != 6 || currentDevice().ID != dev {
+		t.Fatalf("Expected a stable 6-character device ID, got %q", dev)
+	}
+	m, err := loadManifest(0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != dev+"-2" || m.Clock[dev] != 2 {
+		t.Errorf("Expected ID %s-2 and clock 2, got %q %v", dev, m.ID, m.Clock)
+	}
+	if v, err := parseVersionArg(dev + "-1"); err != nil || v != 0.0 {
+		t.Errorf("Expected %s-1 to resolve to v0.0, got v%.1f, %v", dev, v, err)
+	}
+
+	// Another device's versions keep their own sequence.
+	id, clock := nextVersionID("other", m.Clock)
+	if id != "other-1" || clock[dev] != 2 || m.Clock["other"] != 0 {
+		t.Errorf("Unexpected next ID %q, clock %v (previous %v)", id, clock, m.Clock)
+	}
+	if _, err := parseVersionArg("nope-9"); err == nil || !strings.Contains(err.Error(), "invalid version") {
+		t.Errorf("Unknown ID should be rejected, got %v", err)
+	}
+}
+
+func TestDivergedHistoriesAreDetected(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	createTestFile(t, "a.md", "two\n")
+	if err := updateGitnot(); err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	dev := currentDevice().ID
+
+	// Another machine also recorded a v0.1 on top of v0.0, and the sync kept
+	// its manifest over ours.
+	theirs, _ := loadManifest(0.0)
+	theirs.Version = 0.1
+	theirs.ID, theirs.Clock = nextVersionID("other", theirs.Clock)
+	if err := writeManifest(theirs); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordDeviceHead("other", theirs); err != nil {
+		t.Fatal(err)
+	}
+	if got := divergedHeads(theirs.Clock, 0.1); len(got) != 1 || got[0].ID != dev+"-2" {
+		t.Fatalf("Expected this device's v0.1 to be missing from the history, got %+v", got)
+	}
+
+	createTestFile(t, "a.md", "three\n")
+	var err error
+	out := captureStdout(t, func() { err = updateGitnot() })
+	if err != nil {
+		t.Fatalf("updateGitnot failed: %v", err)
+	}
+	if !strings.Contains(string(out), dev+"-2") || !strings.Contains(string(out), "not in this history") {
+		t.Errorf("Expected a warning naming the lost version, got %s", out)
+	}
+	m, _ := loadManifest(0.2)
+	if m.Clock[dev] != 3 || m.Clock["other"] != 1 {
+		t.Errorf("Expected the clocks merged, got %v", m.Clock)
+	}
+	if got := divergedHeads(m.Clock, 0.2); len(got) != 0 {
+		t.Errorf("A merged divergence should not be reported again, got %+v", got)
+	}
+}
+
+func TestUpdateRefusesRecordedVersionNumber(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "one\n")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	// another machine's v0.1 manifest arrives ahead of its version.txt
+	theirs, _ := loadManifest(0.0)
+	theirs.Version = 0.1
+	theirs.ID, theirs.Clock = nextVersionID("other", theirs.Clock)
+	if err := writeManifest(theirs); err != nil {
+		t.Fatal(err)
+	}
+	createTestFile(t, "a.md", "two\n")
+	if err := updateGitnot(); !errors.Is(err, errStoreChanged) {
+		t.Fatalf("Expected errStoreChanged, got %v", err)
+	}
+	if m, _ := loadManifest(0.1); m.ID != "other-1" {
+		t.Errorf("The other machine's manifest must not be overwritten, got %q", m.ID)
+	}
+}
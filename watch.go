@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- Watch ---
+//
+// `gitnot watch` polls the folder and records a version once tracked files
+// have stopped changing for one interval. config.json, .gitnotignore and
+// tracked.txt are reread on every scan, so edits to any of them apply
+// without a restart; the watcher reports which paths the new filters start
+// or stop tracking. Other programs can drive the watcher through its control
+// API, see api.go. With scrub_every_hours set, a quiet watcher also scrubs
+// the store, see scrub.go.
+
+type watcher struct {
+	configSig string
+	filter    scanFilter
+	fileSigs  map[string]string // path → size and mtime, nil before the first poll
+}
+
+type watchEvent struct {
+	Reloaded   bool
+	NowTracked []string
+	NowIgnored []string
+	Changed    bool     // tracked files changed since the last poll
+	Paths      []string // which ones, sorted
+}
+
+// statSig summarizes paths by size and modification time.
+func statSig(paths ...string) string {
+	var b strings.Builder
+	for _, p := range paths {
+		if fi, err := os.Stat(longPath(p)); err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d\n", p, fi.Size(), fi.ModTime().UnixNano())
+		} else {
+			fmt.Fprintf(&b, "%s:-\n", p)
+		}
+	}
+	return b.String()
+}
+
+// fileSigs maps each path to its size and modification time.
+func fileSigs(paths []string) map[string]string {
+	sigs := make(map[string]string, len(paths))
+	for _, p := range paths {
+		sigs[p] = statSig(p)
+	}
+	return sigs
+}
+
+// changedPaths lists the paths whose signature differs between two polls,
+// including ones that appeared or disappeared.
+func changedPaths(before, after map[string]string) []string {
+	var out []string
+	for p, sig := range after {
+		if before[p] != sig {
+			out = append(out, p)
+		}
+	}
+	for p := range before {
+		if _, ok := after[p]; !ok {
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// diffPaths returns what is only in after and what is only in before.
+func diffPaths(before, after []string) (added, removed []string) {
+	in := map[string]bool{}
+	for _, p := range before {
+		in[p] = true
+	}
+	for _, p := range after {
+		if !in[p] {
+			added = append(added, p)
+		}
+		delete(in, p)
+	}
+	for p := range in {
+		removed = append(removed, p)
+	}
+	sort.Strings(removed)
+	return added, removed
+}
+
+// poll rescans the folder. When the config changed, the tree is listed with
+// both the old and the new filters so the report only reflects the reload.
+func (w *watcher) poll() (watchEvent, error) {
+	var ev watchEvent
+	cfgSig := statSig(configFile, ignoreFile, trackedListFile)
+	cfg := loadConfig()
+	if cfgSig != w.configSig {
+		setThrottle(cfg.Throttle)
+	}
+	filter := cfg.scanFilters()
+	files, err := scanTextFiles(".", filter)
+	if err != nil {
+		return ev, err
+	}
+	if w.configSig != "" && cfgSig != w.configSig {
+		ev.Reloaded = true
+		before, err := scanTextFiles(".", w.filter)
+		if err != nil {
+			return ev, err
+		}
+		ev.NowTracked, ev.NowIgnored = diffPaths(before, files)
+	}
+	sigs := fileSigs(files)
+	if w.fileSigs != nil {
+		ev.Paths = changedPaths(w.fileSigs, sigs)
+		ev.Changed = len(ev.Paths) > 0
+	}
+	w.configSig, w.filter, w.fileSigs = cfgSig, filter, sigs
+	return ev, nil
+}
+
+func printReload(ev watchEvent) {
+	fmt.Printf("🔄 Reloaded %s, %s and %s\n", configFile, ignoreFile, trackedListFile)
+	for _, p := range ev.NowTracked {
+		fmt.Printf("  ➕ now tracked: %s\n", p)
+	}
+	for _, p := range ev.NowIgnored {
+		fmt.Printf("  🙈 now ignored: %s\n", p)
+	}
+	if len(ev.NowTracked)+len(ev.NowIgnored) == 0 {
+		fmt.Println("  no change to the tracked files")
+	}
+}
+
+// eventWriter returns a function that writes each event to w as one line of
+// JSON, the same objects the control API pushes to subscribers.
+func eventWriter(w io.Writer) func(streamEvent) {
+	enc := json.NewEncoder(w)
+	return func(ev streamEvent) {
+		if err := enc.Encode(ev); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: Could not write event: %v\n", err)
+		}
+	}
+}
+
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	interval := fs.Duration("interval", 2*time.Second, "how often to scan for changes")
+	events := fs.Bool("events", false, "print a JSON line per change and per new version on stdout (messages go to stderr)")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	w := &watcher{}
+	defer setThrottle(ThrottleConfig{})
+	if _, err := w.poll(); err != nil {
+		return err
+	}
+	api, err := listenAPI()
+	if errors.Is(err, errWatchRunning) {
+		return err
+	}
+	var calls chan apiCall
+	if err != nil {
+		fmt.Printf("⚠️  Warning: Control API unavailable: %v\n", err)
+	} else {
+		defer api.Close()
+		calls = api.calls
+	}
+	emit := api.publish
+	if *events {
+		stdout := os.Stdout
+		os.Stdout = os.Stderr // keep stdout to the events
+		defer func() { os.Stdout = stdout }()
+		write := eventWriter(stdout)
+		emit = func(ev streamEvent) {
+			api.publish(ev)
+			write(ev)
+		}
+	}
+	fmt.Printf("👀 Watching for changes every %s (Ctrl+C to stop)\n", *interval)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	defer signal.Stop(stop)
+	tick := time.NewTicker(*interval)
+	defer tick.Stop()
+	dirty := false
+	// record updates and tells subscribers about the new version, if any.
+	record := func(message string) (float64, error) {
+		dirty = false
+		before, _ := readVersion()
+		err := updateGitnotWith(updateOptions{Message: message, KeepTasks: true})
+		if err == nil && loadConfig().postUpdate() != postUpdateInline {
+			if run, err := flushTasks(true); err != nil {
+				fmt.Printf("⚠️  Warning: Could not run post-update tasks: %v\n", err)
+			} else if run.Failed > 0 {
+				fmt.Printf("⚠️  %d post-update tasks failed and stay queued (see gitnot tasks)\n", run.Failed)
+			}
+		}
+		if _, err := w.poll(); err != nil {
+			fmt.Printf("⚠️  Warning: Could not scan: %v\n", err)
+		}
+		if ev, ok := versionEvent(before); ok {
+			emit(ev)
+			return *ev.Version, err
+		}
+		return before, err
+	}
+	for {
+		select {
+		case <-stop:
+			fmt.Println("👋 Stopped watching")
+			return nil
+		case call := <-calls:
+			call.reply <- answerAPI(call.req, record)
+			continue
+		case <-tick.C:
+		}
+		ev, err := w.poll()
+		if err != nil {
+			fmt.Printf("⚠️  Warning: Could not scan: %v\n", err)
+			continue
+		}
+		if ev.Reloaded {
+			printReload(ev)
+			emit(streamEvent{Event: "reloaded", Time: repo.Now()})
+		}
+		if ev.Changed {
+			emit(streamEvent{Event: "changed", Time: repo.Now(), Paths: slashPaths(ev.Paths)})
+		}
+		if ev.Changed || ev.Reloaded {
+			dirty = true // wait for a quiet interval before recording
+			continue
+		}
+		if dirty {
+			if _, err := record(""); err != nil {
+				fmt.Println("❌", err)
+			}
+		} else if scrubDue(loadConfig(), repo.Now()) {
+			backgroundScrub()
+		}
+	}
+}
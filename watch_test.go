@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcherReloadsFilters(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "a.md", "a")
+	createTestFile(t, "drafts/b.md", "b")
+	createTestFile(t, "notes.org", "c")
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	w := &watcher{}
+	if _, err := w.poll(); err != nil {
+		t.Fatal(err)
+	}
+	ev, _ := w.poll()
+	if ev.Reloaded || ev.Changed {
+		t.Fatalf("Nothing changed, got %+v", ev)
+	}
+
+	createTestFile(t, ignoreFile, "# scratch\ndrafts/*\n")
+	cfg := loadConfig()
+	cfg.Extensions = append(cfg.Extensions, ".org")
+	saveJSON(configFile, cfg)
+	ev, err := w.poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ev.Reloaded {
+		t.Fatal("Config change not noticed")
+	}
+	if !reflect.DeepEqual(ev.NowTracked, []string{"notes.org"}) || !reflect.DeepEqual(ev.NowIgnored, []string{filepath.Join("drafts", "b.md")}) {
+		t.Errorf("Unexpected reload report: %+v", ev)
+	}
+
+	future := time.Now().Add(time.Hour)
+	os.Chtimes("a.md", future, future)
+	if ev, _ := w.poll(); !ev.Changed || ev.Reloaded {
+		t.Errorf("Edit to a tracked file not noticed: %+v", ev)
+	}
+}
+
+func TestWatchEventLines(t *testing.T) {
+	before := map[string]string{"a.md": "1", "b.md": "1", "c.md": "1"}
+	after := map[string]string{"a.md": "1", "b.md": "2", "d.md": "1"}
+	paths := changedPaths(before, after)
+	if !reflect.DeepEqual(paths, []string{"b.md", "c.md", "d.md"}) {
+		t.Errorf("Expected the edited, removed and new paths, got %v", paths)
+	}
+
+	var buf bytes.Buffer
+	write := eventWriter(&buf)
+	v, first := 0.3, 0.0
+	write(streamEvent{Event: "changed", Paths: paths})
+	write(streamEvent{Event: "version", Version: &v, ID: "ab12cd-4"})
+	write(streamEvent{Event: "version", Version: &first})
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected one line per event, got %q", buf.String())
+	}
+	var ev streamEvent
+	if err := json.Unmarshal([]byte(lines[1]), &ev); err != nil || ev.Event != "version" || ev.Version == nil || *ev.Version != 0.3 {
+		t.Errorf("Unexpected event line %q (%v)", lines[1], err)
+	}
+	if strings.Contains(lines[0], `"version"`) || !strings.Contains(lines[2], `"version":0`) {
+		t.Errorf("Expected only version events to carry a version, v0.0 included, got %q", buf.String())
+	}
+}
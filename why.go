@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// --- status --why ---
+//
+// Lists what is in the tree but not tracked, each with the reason: an ignore
+// rule, an extension no rule tracks, a size limit, binary content, the
+// hidden file settings or an online-only placeholder. Ignored folders are
+// reported once instead of file by file.
+
+type untrackedFile struct {
+	Path   string // directories end in "/"
+	Reason string
+}
+
+// looksBinary reports whether a file starts with a NUL byte in its first 8KB,
+// the same heuristic git uses.
+func looksBinary(p string) bool {
+	f, err := os.Open(longPath(p))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	buf := make([]byte, 8192)
+	n, _ := io.ReadFull(f, buf)
+	return bytes.IndexByte(buf[:n], 0) >= 0
+}
+
+func ruleReason(r Rule) string {
+	return fmt.Sprintf("ignored by %q (%s)", r.Match, r.source)
+}
+
+// untrackedReason explains why filter doesn't track p, or returns "" if it does.
+func untrackedReason(filter scanFilter, p string, size int64) string {
+	r, i := filter.ruleFor(p)
+	if hidden := isHidden(filepath.Base(p)) && !filter.listed; hidden && filter.HiddenFiles == hiddenSkip {
+		return `hidden file (hidden_files is "skip")`
+	} else if hidden && filter.HiddenFiles == hiddenTrack && i < 0 && !matchesAny(p, hiddenNoise) {
+		return ""
+	}
+	switch {
+	case i >= 0 && r.ignores():
+		return ruleReason(r)
+	case i >= 0 && !r.fits(size):
+		return fmt.Sprintf("too large (%s, %q allows %s)", formatBytes(size), r.Match, formatBytes(int64(r.MaxSizeKB)<<10))
+	case i >= 0:
+		return ""
+	}
+	ext := filepath.Ext(p)
+	what := fmt.Sprintf("extension %s not tracked", ext)
+	if ext == "" {
+		what = "no extension and no include pattern"
+	}
+	if looksBinary(p) {
+		return "binary, " + what
+	}
+	return what
+}
+
+// ignoredDir returns the rule hiding everything in dir, if one does.
+func ignoredDir(filter scanFilter, dir string) (Rule, bool) {
+	r, i := filter.ruleFor(filepath.Join(dir, "\x00"))
+	return r, i >= 0 && r.ignores()
+}
+
+func whyUntracked(cfg Config) ([]untrackedFile, error) {
+	filter := cfg.scanFilters()
+	var out []untrackedFile
+	err := filepath.WalkDir(".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if isUnderGitnot(p) || d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if p != "." && filter.skipsHiddenDir(p) {
+				out = append(out, untrackedFile{Path: filepath.ToSlash(p) + "/", Reason: `hidden folder (hidden_dirs is "skip")`})
+				return filepath.SkipDir
+			}
+			if p != "." {
+				if r, ok := ignoredDir(filter, p); ok {
+					out = append(out, untrackedFile{Path: filepath.ToSlash(p) + "/", Reason: ruleReason(r)})
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if reason := untrackedReason(filter, p, fi.Size()); reason != "" {
+			out = append(out, untrackedFile{Path: filepath.ToSlash(p), Reason: reason})
+		} else if _, deferred := deferPlaceholders([]string{p}, cfg); len(deferred) > 0 {
+			out = append(out, untrackedFile{Path: filepath.ToSlash(p), Reason: "online-only placeholder (cloud_placeholders is \"skip\")"})
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out, err
+}
+
+func showUntracked() error {
+	files, err := whyUntracked(loadConfig())
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Println("✅ Every file in the tree is tracked")
+		return nil
+	}
+	width := 0
+	for _, f := range files {
+		width = max(width, len(f.Path))
+	}
+	width = min(width, 48)
+	fmt.Printf("🙈 Not tracked (%d):\n", len(files))
+	for _, f := range files {
+		fmt.Printf("  %-*s  %s\n", width, f.Path, f.Reason)
+	}
+	fmt.Println("💡 'gitnot config lint' shows every rule and what it matches")
+	return nil
+}
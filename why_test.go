@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWhyUntracked(t *testing.T) {
+	setupTestDir(t)
+
+	createTestFile(t, "notes.md", "tracked")
+	createTestFile(t, "scratch.tmp", "temp")
+	createTestFile(t, "image.png", "\x89PNG\x00\x00")
+	createTestFile(t, "Makefile", "all:")
+	createTestFile(t, "node_modules/pkg/index.js", "x")
+	createTestFile(t, "node_modules/pkg/readme.md", "x")
+	createTestFile(t, "big.txt", strings.Repeat("x", 4096))
+	if err := initGitnot(); err != nil {
+		t.Fatalf("initGitnot failed: %v", err)
+	}
+	cfg := loadConfig()
+	cfg.Rules = []Rule{
+		{Match: "*.tmp", Action: ruleIgnore},
+		{Match: "node_modules/*", Action: ruleIgnore},
+		{Match: "*.md"},
+		{Match: "*.txt", MaxSizeKB: 1},
+	}
+	saveJSON(configFile, cfg)
+
+	files, err := whyUntracked(loadConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, f := range files {
+		got[f.Path] = f.Reason
+	}
+	want := map[string]string{
+		"Makefile":      "no extension",
+		"big.txt":       "too large",
+		"image.png":     "binary, extension .png not tracked",
+		"node_modules/": `ignored by "node_modules/*" (rules)`,
+		"scratch.tmp":   `ignored by "*.tmp" (rules)`,
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected %d untracked entries, got %v", len(want), got)
+	}
+	for p, reason := range want {
+		if !strings.Contains(got[p], reason) {
+			t.Errorf("%s: expected reason containing %q, got %q", p, reason, got[p])
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// --- XXH64 ---
+//
+// A streaming implementation of the 64-bit xxHash (seed 0), used as the fast
+// pre-filter in fasthash.go. It is not a cryptographic hash and is never
+// recorded in place of one.
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+type xxh64 struct {
+	v     [4]uint64
+	total uint64
+	buf   [32]byte
+	n     int // bytes in buf
+}
+
+func newXXH64() *xxh64 {
+	x := &xxh64{}
+	x.Reset()
+	return x
+}
+
+func (x *xxh64) Reset() {
+	p1 := xxPrime1 // wrapping arithmetic, which constants don't do
+	x.v = [4]uint64{p1 + xxPrime2, xxPrime2, 0, -p1}
+	x.total, x.n = 0, 0
+}
+
+func (x *xxh64) Size() int      { return 8 }
+func (x *xxh64) BlockSize() int { return 32 }
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	return bits.RotateLeft64(acc, 31) * xxPrime1
+}
+
+func xxMerge(acc, v uint64) uint64 {
+	acc ^= xxRound(0, v)
+	return acc*xxPrime1 + xxPrime4
+}
+
+// stripes consumes whole 32-byte stripes of b.
+func (x *xxh64) stripes(b []byte) {
+	for ; len(b) >= 32; b = b[32:] {
+		for i := range x.v {
+			x.v[i] = xxRound(x.v[i], binary.LittleEndian.Uint64(b[8*i:]))
+		}
+	}
+}
+
+func (x *xxh64) Write(b []byte) (int, error) {
+	n := len(b)
+	x.total += uint64(n)
+	if x.n > 0 {
+		c := copy(x.buf[x.n:], b)
+		x.n += c
+		b = b[c:]
+		if x.n < 32 {
+			return n, nil
+		}
+		x.stripes(x.buf[:])
+		x.n = 0
+	}
+	whole := len(b) &^ 31
+	x.stripes(b[:whole])
+	x.n = copy(x.buf[:], b[whole:])
+	return n, nil
+}
+
+func (x *xxh64) Sum64() uint64 {
+	var h uint64
+	if x.total >= 32 {
+		v := x.v
+		h = bits.RotateLeft64(v[0], 1) + bits.RotateLeft64(v[1], 7) + bits.RotateLeft64(v[2], 12) + bits.RotateLeft64(v[3], 18)
+		for _, vi := range v {
+			h = xxMerge(h, vi)
+		}
+	} else {
+		h = xxPrime5
+	}
+	h += x.total
+
+	b := x.buf[:x.n]
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(b))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+func (x *xxh64) Sum(b []byte) []byte {
+	return binary.BigEndian.AppendUint64(b, x.Sum64())
+}